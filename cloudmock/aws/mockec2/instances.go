@@ -18,6 +18,7 @@ package mockec2
 
 import (
 	"context"
+	"slices"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
@@ -25,9 +26,27 @@ import (
 	"k8s.io/klog/v2"
 )
 
+// DescribeInstances returns the Instances, optionally restricted to the requested InstanceIds.
 func (m *MockEC2) DescribeInstances(ctx context.Context, request *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
-	klog.Warningf("MockEc2::DescribeInstances is stub-implemented")
-	return &ec2.DescribeInstancesOutput{}, nil
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if len(request.Filters) != 0 {
+		klog.Warningf("MockEc2::DescribeInstances ignores filters")
+	}
+
+	var instances []ec2types.Instance
+	for id, instance := range m.Instances {
+		if len(request.InstanceIds) != 0 && !slices.Contains(request.InstanceIds, id) {
+			continue
+		}
+		instances = append(instances, *instance)
+	}
+	response := &ec2.DescribeInstancesOutput{}
+	if len(instances) != 0 {
+		response.Reservations = []ec2types.Reservation{{Instances: instances}}
+	}
+	return response, nil
 }
 
 func (m *MockEC2) DescribeInstanceTypes(ctx context.Context, request *ec2.DescribeInstanceTypesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstanceTypesOutput, error) {
@@ -39,6 +39,10 @@ type MockELBV2 struct {
 	listenerCount int
 	LBAttributes  map[string][]elbv2types.LoadBalancerAttribute
 
+	// SSLPolicies, if set, replaces the predefined security policies, mapped to their supported protocols,
+	// e.g. to simulate a region where only some policies are available
+	SSLPolicies map[string][]string
+
 	Tags map[string]elbv2types.TagDescription
 }
 
@@ -49,6 +53,7 @@ type loadBalancer struct {
 type targetGroup struct {
 	description elbv2types.TargetGroup
 	attributes  []elbv2types.TargetGroupAttribute
+	targets     []elbv2types.TargetHealthDescription
 }
 
 type listener struct {
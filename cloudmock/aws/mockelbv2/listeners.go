@@ -51,6 +51,27 @@ func (m *MockELBV2) DescribeListeners(ctx context.Context, request *elbv2.Descri
 	return page, nil
 }
 
+// DescribeListenerCertificates returns the certificates of the listener, the first of which is the default certificate.
+func (m *MockELBV2) DescribeListenerCertificates(ctx context.Context, request *elbv2.DescribeListenerCertificatesInput, optFns ...func(*elbv2.Options)) (*elbv2.DescribeListenerCertificatesOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DescribeListenerCertificates v2 %v", request)
+
+	l, ok := m.Listeners[aws.ToString(request.ListenerArn)]
+	if !ok {
+		return nil, &elbv2types.ListenerNotFoundException{}
+	}
+	output := &elbv2.DescribeListenerCertificatesOutput{}
+	for i, certificate := range l.description.Certificates {
+		output.Certificates = append(output.Certificates, elbv2types.Certificate{
+			CertificateArn: certificate.CertificateArn,
+			IsDefault:      aws.Bool(i == 0),
+		})
+	}
+	return output, nil
+}
+
 func (m *MockELBV2) CreateListener(ctx context.Context, request *elbv2.CreateListenerInput, optFns ...func(*elbv2.Options)) (*elbv2.CreateListenerOutput, error) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -64,6 +85,7 @@ func (m *MockELBV2) CreateListener(ctx context.Context, request *elbv2.CreateLis
 		Certificates:    request.Certificates,
 		Protocol:        request.Protocol,
 		SslPolicy:       request.SslPolicy,
+		AlpnPolicy:      request.AlpnPolicy,
 	}
 
 	lbARN := aws.ToString(request.LoadBalancerArn)
@@ -79,22 +101,38 @@ func (m *MockELBV2) CreateListener(ctx context.Context, request *elbv2.CreateLis
 		m.Listeners = make(map[string]*listener)
 	}
 
-	tgARN := aws.ToString(l.DefaultActions[0].TargetGroupArn)
+	tgARNs := []string{aws.ToString(l.DefaultActions[0].TargetGroupArn)}
+	if forwardConfig := l.DefaultActions[0].ForwardConfig; forwardConfig != nil {
+		for _, tuple := range forwardConfig.TargetGroups {
+			tgARNs = append(tgARNs, aws.ToString(tuple.TargetGroupArn))
+		}
+	}
 
-	if _, ok := m.TargetGroups[tgARN]; ok {
-		found := false
-		for _, lb := range m.TargetGroups[tgARN].description.LoadBalancerArns {
-			if lb == lbARN {
-				found = true
-				break
+	for _, tgARN := range tgARNs {
+		if _, ok := m.TargetGroups[tgARN]; ok {
+			found := false
+			for _, lb := range m.TargetGroups[tgARN].description.LoadBalancerArns {
+				if lb == lbARN {
+					found = true
+					break
+				}
+			}
+			if !found {
+				m.TargetGroups[tgARN].description.LoadBalancerArns = append(m.TargetGroups[tgARN].description.LoadBalancerArns, lbARN)
 			}
-		}
-		if !found {
-			m.TargetGroups[tgARN].description.LoadBalancerArns = append(m.TargetGroups[tgARN].description.LoadBalancerArns, lbARN)
 		}
 	}
 
 	m.Listeners[arn] = &listener{description: l}
+	if len(request.Tags) != 0 {
+		if m.Tags == nil {
+			m.Tags = make(map[string]elbv2types.TagDescription)
+		}
+		m.Tags[arn] = elbv2types.TagDescription{
+			ResourceArn: aws.String(arn),
+			Tags:        request.Tags,
+		}
+	}
 	return &elbv2.CreateListenerOutput{Listeners: []elbv2types.Listener{l}}, nil
 }
 
@@ -109,12 +147,99 @@ func (m *MockELBV2) DeleteListener(ctx context.Context, request *elbv2.DeleteLis
 		return nil, fmt.Errorf("Listener not found %v", lARN)
 	}
 	delete(m.Listeners, lARN)
+	delete(m.Tags, lARN)
 	return nil, nil
 }
 
-func (m *MockELBV2) ModifyListener(request *elbv2.ModifyListenerInput) (*elbv2.ModifyListenerOutput, error) {
+func (m *MockELBV2) ModifyListener(ctx context.Context, request *elbv2.ModifyListenerInput, optFns ...func(*elbv2.Options)) (*elbv2.ModifyListenerOutput, error) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
-	klog.Fatalf("elbv2.ModifyListener() not implemented")
-	return nil, nil
+
+	klog.Infof("ModifyListener v2 %v", request)
+
+	lARN := aws.ToString(request.ListenerArn)
+	l, ok := m.Listeners[lARN]
+	if !ok {
+		return nil, &elbv2types.ListenerNotFoundException{}
+	}
+	if request.Port != nil {
+		l.description.Port = request.Port
+	}
+	if request.Protocol != "" {
+		l.description.Protocol = request.Protocol
+		if request.Protocol != elbv2types.ProtocolEnumTls {
+			// Only TLS listeners have certificates and policies
+			l.description.Certificates = nil
+			l.description.SslPolicy = nil
+			l.description.AlpnPolicy = nil
+		}
+	}
+	if request.Certificates != nil {
+		// Only the default certificate is replaced, the certificates added with AddListenerCertificates are kept
+		certificates := append([]elbv2types.Certificate(nil), request.Certificates...)
+		if len(l.description.Certificates) > 1 {
+			certificates = append(certificates, l.description.Certificates[1:]...)
+		}
+		l.description.Certificates = certificates
+	}
+	if request.SslPolicy != nil {
+		l.description.SslPolicy = request.SslPolicy
+	}
+	if request.AlpnPolicy != nil {
+		l.description.AlpnPolicy = request.AlpnPolicy
+	}
+	if request.DefaultActions != nil {
+		l.description.DefaultActions = request.DefaultActions
+	}
+	return &elbv2.ModifyListenerOutput{Listeners: []elbv2types.Listener{l.description}}, nil
+}
+
+// AddListenerCertificates adds certificates to the listener, after its default certificate.
+func (m *MockELBV2) AddListenerCertificates(ctx context.Context, request *elbv2.AddListenerCertificatesInput, optFns ...func(*elbv2.Options)) (*elbv2.AddListenerCertificatesOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("AddListenerCertificates v2 %v", request)
+
+	l, ok := m.Listeners[aws.ToString(request.ListenerArn)]
+	if !ok {
+		return nil, &elbv2types.ListenerNotFoundException{}
+	}
+	for _, certificate := range request.Certificates {
+		found := false
+		for _, existing := range l.description.Certificates {
+			if aws.ToString(existing.CertificateArn) == aws.ToString(certificate.CertificateArn) {
+				found = true
+			}
+		}
+		if !found {
+			l.description.Certificates = append(l.description.Certificates, elbv2types.Certificate{CertificateArn: certificate.CertificateArn})
+		}
+	}
+	return &elbv2.AddListenerCertificatesOutput{Certificates: request.Certificates}, nil
+}
+
+// RemoveListenerCertificates removes certificates from the listener; the default certificate cannot be removed.
+func (m *MockELBV2) RemoveListenerCertificates(ctx context.Context, request *elbv2.RemoveListenerCertificatesInput, optFns ...func(*elbv2.Options)) (*elbv2.RemoveListenerCertificatesOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("RemoveListenerCertificates v2 %v", request)
+
+	l, ok := m.Listeners[aws.ToString(request.ListenerArn)]
+	if !ok {
+		return nil, &elbv2types.ListenerNotFoundException{}
+	}
+	remove := make(map[string]bool)
+	for _, certificate := range request.Certificates {
+		remove[aws.ToString(certificate.CertificateArn)] = true
+	}
+	var certificates []elbv2types.Certificate
+	for i, certificate := range l.description.Certificates {
+		if i == 0 || !remove[aws.ToString(certificate.CertificateArn)] {
+			certificates = append(certificates, certificate)
+		}
+	}
+	l.description.Certificates = certificates
+	return &elbv2.RemoveListenerCertificatesOutput{}, nil
 }
@@ -78,6 +78,11 @@ func (m *MockELBV2) CreateLoadBalancer(ctx context.Context, request *elbv2.Creat
 		IpAddressType:         request.IpAddressType,
 		DNSName:               aws.String(fmt.Sprintf("%v.amazonaws.com", aws.ToString(request.Name))),
 		CanonicalHostedZoneId: aws.String("HZ123456"),
+		State:                 &elbv2types.LoadBalancerState{Code: elbv2types.LoadBalancerStateEnumActive},
+	}
+	if len(request.SecurityGroups) > 0 {
+		// AWS enforces the inbound rules on PrivateLink traffic by default
+		lb.EnforceSecurityGroupInboundRulesOnPrivateLinkTraffic = aws.String(string(elbv2types.EnforceSecurityGroupInboundRulesOnPrivateLinkTrafficEnumOn))
 	}
 	zones := make([]elbv2types.AvailabilityZone, 0)
 	vpc := "vpc-1"
@@ -90,6 +95,7 @@ func (m *MockELBV2) CreateLoadBalancer(ctx context.Context, request *elbv2.Creat
 		})
 		if err == nil {
 			vpc = *subnetsOutput.Subnets[0].VpcId
+			zones[len(zones)-1].ZoneName = subnetsOutput.Subnets[0].AvailabilityZone
 		}
 	}
 	for _, subnetMapping := range request.SubnetMappings {
@@ -109,6 +115,7 @@ func (m *MockELBV2) CreateLoadBalancer(ctx context.Context, request *elbv2.Creat
 		})
 		if err == nil {
 			vpc = *subnetsOutput.Subnets[0].VpcId
+			zones[len(zones)-1].ZoneName = subnetsOutput.Subnets[0].AvailabilityZone
 		}
 	}
 	lb.AvailabilityZones = zones
@@ -168,9 +175,9 @@ func (m *MockELBV2) ModifyLoadBalancerAttributes(ctx context.Context, request *e
 	if _, ok := m.LBAttributes[arn]; ok {
 		for _, reqAttr := range request.Attributes {
 			found := false
-			for _, lbAttr := range m.LBAttributes[arn] {
+			for i, lbAttr := range m.LBAttributes[arn] {
 				if aws.ToString(reqAttr.Key) == aws.ToString(lbAttr.Key) {
-					lbAttr.Value = reqAttr.Value
+					m.LBAttributes[arn][i].Value = reqAttr.Value
 					found = true
 				}
 			}
@@ -190,14 +197,35 @@ func (m *MockELBV2) SetSecurityGroups(ctx context.Context, request *elbv2.SetSec
 	defer m.mutex.Unlock()
 	arn := aws.ToString(request.LoadBalancerArn)
 	if lb, ok := m.LoadBalancers[arn]; ok {
+		if len(lb.description.SecurityGroups) > 0 && len(request.SecurityGroups) == 0 {
+			return nil, fmt.Errorf("InvalidConfigurationRequest: cannot remove all security groups from load balancer %q", arn)
+		}
 		lb.description.SecurityGroups = request.SecurityGroups
+		if request.EnforceSecurityGroupInboundRulesOnPrivateLinkTraffic != "" {
+			lb.description.EnforceSecurityGroupInboundRulesOnPrivateLinkTraffic = aws.String(string(request.EnforceSecurityGroupInboundRulesOnPrivateLinkTraffic))
+		}
 		return &elbv2.SetSecurityGroupsOutput{
 			SecurityGroupIds: request.SecurityGroups,
+			EnforceSecurityGroupInboundRulesOnPrivateLinkTraffic: elbv2types.EnforceSecurityGroupInboundRulesOnPrivateLinkTrafficEnum(aws.ToString(lb.description.EnforceSecurityGroupInboundRulesOnPrivateLinkTraffic)),
 		}, nil
 	}
 	return nil, fmt.Errorf("LoadBalancerNotFound: %v", aws.ToString(request.LoadBalancerArn))
 }
 
+func (m *MockELBV2) SetIpAddressType(ctx context.Context, request *elbv2.SetIpAddressTypeInput, optFns ...func(*elbv2.Options)) (*elbv2.SetIpAddressTypeOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("SetIpAddressType %v", request)
+
+	arn := aws.ToString(request.LoadBalancerArn)
+	if lb, ok := m.LoadBalancers[arn]; ok {
+		lb.description.IpAddressType = request.IpAddressType
+		return &elbv2.SetIpAddressTypeOutput{IpAddressType: request.IpAddressType}, nil
+	}
+	return nil, fmt.Errorf("LoadBalancerNotFound: %v", arn)
+}
+
 func (m *MockELBV2) SetSubnets(ctx context.Context, request *elbv2.SetSubnetsInput, optFns ...func(*elbv2.Options)) (*elbv2.SetSubnetsOutput, error) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -212,6 +240,11 @@ func (m *MockELBV2) DeleteLoadBalancer(ctx context.Context, request *elbv2.Delet
 	klog.Infof("DeleteLoadBalancer %v", request)
 
 	arn := aws.ToString(request.LoadBalancerArn)
+	for _, attr := range m.LBAttributes[arn] {
+		if aws.ToString(attr.Key) == "deletion_protection.enabled" && aws.ToString(attr.Value) == "true" {
+			return nil, &elbv2types.OperationNotPermittedException{Message: aws.String("Load balancer '" + arn + "' cannot be deleted because deletion protection is enabled")}
+		}
+	}
 	delete(m.LoadBalancers, arn)
 	for listenerARN, listener := range m.Listeners {
 		if aws.ToString(listener.description.LoadBalancerArn) == arn {
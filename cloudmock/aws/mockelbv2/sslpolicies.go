@@ -0,0 +1,69 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mockelbv2
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"k8s.io/klog/v2"
+)
+
+// sslPolicies holds a subset of the predefined AWS security policies, mapped to their supported protocols
+var sslPolicies = map[string][]string{
+	"ELBSecurityPolicy-2016-08":               {"TLSv1", "TLSv1.1", "TLSv1.2"},
+	"ELBSecurityPolicy-TLS-1-1-2017-01":       {"TLSv1.1", "TLSv1.2"},
+	"ELBSecurityPolicy-TLS-1-2-2017-01":       {"TLSv1.2"},
+	"ELBSecurityPolicy-TLS13-1-2-2021-06":     {"TLSv1.2", "TLSv1.3"},
+	"ELBSecurityPolicy-TLS13-1-3-2021-06":     {"TLSv1.3"},
+	"ELBSecurityPolicy-TLS13-1-2-Res-2021-06": {"TLSv1.2", "TLSv1.3"},
+}
+
+func (m *MockELBV2) DescribeSSLPolicies(ctx context.Context, request *elbv2.DescribeSSLPoliciesInput, optFns ...func(*elbv2.Options)) (*elbv2.DescribeSSLPoliciesOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DescribeSSLPolicies v2 %v", request)
+
+	policies := sslPolicies
+	if m.SSLPolicies != nil {
+		policies = m.SSLPolicies
+	}
+
+	names := request.Names
+	if len(names) == 0 {
+		for name := range policies {
+			names = append(names, name)
+		}
+	}
+
+	output := &elbv2.DescribeSSLPoliciesOutput{}
+	for _, name := range names {
+		protocols, ok := policies[name]
+		if !ok {
+			return nil, &elbv2types.SSLPolicyNotFoundException{Message: aws.String(fmt.Sprintf("SSL policy %q not found", name))}
+		}
+		output.SslPolicies = append(output.SslPolicies, elbv2types.SslPolicy{
+			Name:         aws.String(name),
+			SslProtocols: protocols,
+		})
+	}
+	return output, nil
+}
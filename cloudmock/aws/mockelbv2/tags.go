@@ -36,30 +36,60 @@ func (m *MockELBV2) AddTags(ctx context.Context, request *elbv2.AddTagsInput, op
 	}
 
 	for _, arn := range request.ResourceArns {
-		if t, ok := m.Tags[arn]; ok {
-			for _, reqTag := range request.Tags {
-				found := false
-				for _, tag := range t.Tags {
-					if aws.ToString(reqTag.Key) == aws.ToString(tag.Key) {
-						tag.Value = reqTag.Value
-					}
-				}
-				if !found {
-					tags := m.Tags[arn]
-					tags.Tags = append(m.Tags[arn].Tags, reqTag)
+		tagDescription, ok := m.Tags[arn]
+		if !ok {
+			tagDescription = elbv2types.TagDescription{
+				ResourceArn: aws.String(arn),
+			}
+		}
+		for _, reqTag := range request.Tags {
+			found := false
+			for i, tag := range tagDescription.Tags {
+				if aws.ToString(reqTag.Key) == aws.ToString(tag.Key) {
+					tagDescription.Tags[i].Value = reqTag.Value
+					found = true
 				}
 			}
-		} else {
-			m.Tags[arn] = elbv2types.TagDescription{
-				ResourceArn: aws.String(arn),
-				Tags:        request.Tags,
+			if !found {
+				tagDescription.Tags = append(tagDescription.Tags, reqTag)
 			}
 		}
+		m.Tags[arn] = tagDescription
 	}
 
 	return &elbv2.AddTagsOutput{}, nil
 }
 
+func (m *MockELBV2) RemoveTags(ctx context.Context, request *elbv2.RemoveTagsInput, optFns ...func(*elbv2.Options)) (*elbv2.RemoveTagsOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("RemoveTags v2 %v", request)
+
+	for _, arn := range request.ResourceArns {
+		tagDescription, ok := m.Tags[arn]
+		if !ok {
+			continue
+		}
+		var tags []elbv2types.Tag
+		for _, tag := range tagDescription.Tags {
+			remove := false
+			for _, key := range request.TagKeys {
+				if aws.ToString(tag.Key) == key {
+					remove = true
+				}
+			}
+			if !remove {
+				tags = append(tags, tag)
+			}
+		}
+		tagDescription.Tags = tags
+		m.Tags[arn] = tagDescription
+	}
+
+	return &elbv2.RemoveTagsOutput{}, nil
+}
+
 func (m *MockELBV2) DescribeTags(ctx context.Context, request *elbv2.DescribeTagsInput, optFns ...func(*elbv2.Options)) (*elbv2.DescribeTagsOutput, error) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
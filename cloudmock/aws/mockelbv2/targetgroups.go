@@ -50,8 +50,10 @@ func (m *MockELBV2) DescribeTargetGroups(ctx context.Context, request *elbv2.Des
 				}
 			}
 		} else if request.LoadBalancerArn != nil {
-			if len(tg.description.LoadBalancerArns) > 0 && tg.description.LoadBalancerArns[0] == aws.ToString(request.LoadBalancerArn) {
-				match = true
+			for _, lbARN := range tg.description.LoadBalancerArns {
+				if lbARN == aws.ToString(request.LoadBalancerArn) {
+					match = true
+				}
 			}
 		} else if len(request.Names) > 0 {
 			for _, name := range request.Names {
@@ -68,7 +70,7 @@ func (m *MockELBV2) DescribeTargetGroups(ctx context.Context, request *elbv2.Des
 		}
 	}
 
-	if len(tgs) == 0 && len(request.TargetGroupArns) > 0 || request.LoadBalancerArn != nil {
+	if len(tgs) == 0 && len(request.TargetGroupArns) > 0 {
 		return nil, &elbv2types.TargetGroupNotFoundException{}
 	}
 
@@ -84,12 +86,25 @@ func (m *MockELBV2) CreateTargetGroup(ctx context.Context, request *elbv2.Create
 	klog.Infof("CreateTargetGroup %v", request)
 
 	tg := elbv2types.TargetGroup{
-		TargetGroupName:         request.Name,
-		Port:                    request.Port,
-		Protocol:                request.Protocol,
-		VpcId:                   request.VpcId,
-		HealthyThresholdCount:   request.HealthyThresholdCount,
-		UnhealthyThresholdCount: request.UnhealthyThresholdCount,
+		TargetGroupName:            request.Name,
+		Port:                       request.Port,
+		Protocol:                   request.Protocol,
+		TargetType:                 request.TargetType,
+		IpAddressType:              request.IpAddressType,
+		VpcId:                      request.VpcId,
+		HealthCheckIntervalSeconds: request.HealthCheckIntervalSeconds,
+		HealthyThresholdCount:      request.HealthyThresholdCount,
+		UnhealthyThresholdCount:    request.UnhealthyThresholdCount,
+		HealthCheckProtocol:        request.HealthCheckProtocol,
+		HealthCheckPath:            request.HealthCheckPath,
+		HealthCheckPort:            request.HealthCheckPort,
+		HealthCheckTimeoutSeconds:  request.HealthCheckTimeoutSeconds,
+		Matcher:                    request.Matcher,
+		ProtocolVersion:            request.ProtocolVersion,
+	}
+
+	if tg.IpAddressType == "" {
+		tg.IpAddressType = elbv2types.TargetGroupIpAddressTypeEnumIpv4
 	}
 
 	m.tgCount++
@@ -122,6 +137,44 @@ func (m *MockELBV2) DeleteTargetGroup(ctx context.Context, request *elbv2.Delete
 	return &elbv2.DeleteTargetGroupOutput{}, nil
 }
 
+func (m *MockELBV2) ModifyTargetGroup(ctx context.Context, request *elbv2.ModifyTargetGroupInput, optFns ...func(*elbv2.Options)) (*elbv2.ModifyTargetGroupOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("ModifyTargetGroup %v", request)
+
+	arn := aws.ToString(request.TargetGroupArn)
+	tg, ok := m.TargetGroups[arn]
+	if !ok {
+		return nil, &elbv2types.TargetGroupNotFoundException{}
+	}
+	if request.HealthCheckIntervalSeconds != nil {
+		tg.description.HealthCheckIntervalSeconds = request.HealthCheckIntervalSeconds
+	}
+	if request.HealthyThresholdCount != nil {
+		tg.description.HealthyThresholdCount = request.HealthyThresholdCount
+	}
+	if request.UnhealthyThresholdCount != nil {
+		tg.description.UnhealthyThresholdCount = request.UnhealthyThresholdCount
+	}
+	if request.HealthCheckProtocol != "" {
+		tg.description.HealthCheckProtocol = request.HealthCheckProtocol
+	}
+	if request.HealthCheckPath != nil {
+		tg.description.HealthCheckPath = request.HealthCheckPath
+	}
+	if request.HealthCheckPort != nil {
+		tg.description.HealthCheckPort = request.HealthCheckPort
+	}
+	if request.HealthCheckTimeoutSeconds != nil {
+		tg.description.HealthCheckTimeoutSeconds = request.HealthCheckTimeoutSeconds
+	}
+	if request.Matcher != nil {
+		tg.description.Matcher = request.Matcher
+	}
+	return &elbv2.ModifyTargetGroupOutput{TargetGroups: []elbv2types.TargetGroup{tg.description}}, nil
+}
+
 func (m *MockELBV2) DescribeTargetGroupAttributes(ctx context.Context, request *elbv2.DescribeTargetGroupAttributesInput, optFns ...func(*elbv2.Options)) (*elbv2.DescribeTargetGroupAttributesOutput, error) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -139,6 +192,111 @@ func (m *MockELBV2) ModifyTargetGroupAttributes(ctx context.Context, request *el
 	klog.Infof("ModifyTargetGroupAttributes %v", request)
 
 	arn := aws.ToString(request.TargetGroupArn)
-	m.TargetGroups[arn].attributes = request.Attributes
-	return &elbv2.ModifyTargetGroupAttributesOutput{Attributes: request.Attributes}, nil
+	tg := m.TargetGroups[arn]
+	// Attributes that are not in the request are left unchanged
+	for _, attribute := range request.Attributes {
+		found := false
+		for i := range tg.attributes {
+			if aws.ToString(tg.attributes[i].Key) == aws.ToString(attribute.Key) {
+				tg.attributes[i].Value = attribute.Value
+				found = true
+			}
+		}
+		if !found {
+			tg.attributes = append(tg.attributes, attribute)
+		}
+	}
+	return &elbv2.ModifyTargetGroupAttributesOutput{Attributes: tg.attributes}, nil
+}
+
+// RegisterTargets registers the targets with the target group; the mock reports registered targets as healthy.
+func (m *MockELBV2) RegisterTargets(ctx context.Context, request *elbv2.RegisterTargetsInput, optFns ...func(*elbv2.Options)) (*elbv2.RegisterTargetsOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("RegisterTargets %v", request)
+
+	tg, ok := m.TargetGroups[aws.ToString(request.TargetGroupArn)]
+	if !ok {
+		return nil, &elbv2types.TargetGroupNotFoundException{}
+	}
+	for _, target := range request.Targets {
+		// Registering a target again is a no-op
+		registered := false
+		for _, existing := range tg.targets {
+			if aws.ToString(existing.Target.Id) == aws.ToString(target.Id) && aws.ToInt32(existing.Target.Port) == aws.ToInt32(target.Port) {
+				registered = true
+			}
+		}
+		if registered {
+			continue
+		}
+		tg.targets = append(tg.targets, elbv2types.TargetHealthDescription{
+			Target:       &elbv2types.TargetDescription{Id: target.Id, Port: target.Port, AvailabilityZone: target.AvailabilityZone},
+			TargetHealth: &elbv2types.TargetHealth{State: elbv2types.TargetHealthStateEnumHealthy},
+		})
+	}
+	return &elbv2.RegisterTargetsOutput{}, nil
+}
+
+// DeregisterTargets deregisters the targets from the target group; the mock removes them immediately, without draining.
+func (m *MockELBV2) DeregisterTargets(ctx context.Context, request *elbv2.DeregisterTargetsInput, optFns ...func(*elbv2.Options)) (*elbv2.DeregisterTargetsOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DeregisterTargets %v", request)
+
+	tg, ok := m.TargetGroups[aws.ToString(request.TargetGroupArn)]
+	if !ok {
+		return nil, &elbv2types.TargetGroupNotFoundException{}
+	}
+	var targets []elbv2types.TargetHealthDescription
+	for _, existing := range tg.targets {
+		deregistered := false
+		for _, target := range request.Targets {
+			if aws.ToString(existing.Target.Id) != aws.ToString(target.Id) {
+				continue
+			}
+			if target.Port != nil && aws.ToInt32(existing.Target.Port) != aws.ToInt32(target.Port) {
+				continue
+			}
+			deregistered = true
+		}
+		if !deregistered {
+			targets = append(targets, existing)
+		}
+	}
+	tg.targets = targets
+	return &elbv2.DeregisterTargetsOutput{}, nil
+}
+
+func (m *MockELBV2) DescribeTargetHealth(ctx context.Context, request *elbv2.DescribeTargetHealthInput, optFns ...func(*elbv2.Options)) (*elbv2.DescribeTargetHealthOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DescribeTargetHealth %v", request)
+
+	tg, ok := m.TargetGroups[aws.ToString(request.TargetGroupArn)]
+	if !ok {
+		return nil, &elbv2types.TargetGroupNotFoundException{}
+	}
+	return &elbv2.DescribeTargetHealthOutput{TargetHealthDescriptions: tg.targets}, nil
+}
+
+// SetTargetHealth sets the health reported for a registered target, e.g. to simulate failing health checks.
+func (m *MockELBV2) SetTargetHealth(targetGroupArn string, id string, health elbv2types.TargetHealth) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	tg, ok := m.TargetGroups[targetGroupArn]
+	if !ok {
+		return &elbv2types.TargetGroupNotFoundException{}
+	}
+	for i := range tg.targets {
+		if aws.ToString(tg.targets[i].Target.Id) == id {
+			tg.targets[i].TargetHealth = &health
+			return nil
+		}
+	}
+	return fmt.Errorf("target %q not registered with target group %q", id, targetGroupArn)
 }
@@ -36,6 +36,8 @@ type MockIAM struct {
 	OIDCProviders    map[string]*iam.GetOpenIDConnectProviderOutput
 	RolePolicies     []*rolePolicy
 	AttachedPolicies map[string][]iamtypes.AttachedPolicy
+	// ServerCertificates are the server certificates, by name
+	ServerCertificates map[string]*iamtypes.ServerCertificate
 }
 
 var _ awsinterfaces.IAMAPI = &MockIAM{}
@@ -120,7 +120,7 @@ func RunReplace(ctx context.Context, f *util.Factory, out io.Writer, c *ReplaceO
 					if err != nil {
 						return err
 					}
-					status, err := cloud.FindClusterStatus(v)
+					status, err := cloud.FindClusterStatus(ctx, v)
 					if err != nil {
 						return err
 					}
@@ -62,6 +62,7 @@ type UpdateClusterOptions struct {
 	Yes                bool
 	Target             string
 	OutDir             string
+	TerraformImport    bool
 	SSHPublicKey       string
 	RunTasksOptions    fi.RunTasksOptions
 	AllowKopsDowngrade bool
@@ -122,6 +123,7 @@ func NewCmdUpdateCluster(f *util.Factory, out io.Writer) *cobra.Command {
 	cmd.Flags().BoolVarP(&options.Yes, "yes", "y", options.Yes, "Create cloud resources, without --yes update is in dry run mode")
 	cmd.Flags().StringVar(&options.Target, "target", options.Target, "Target - direct, terraform")
 	cmd.RegisterFlagCompletionFunc("target", completeUpdateClusterTarget(f, options))
+	cmd.Flags().BoolVar(&options.TerraformImport, "terraform-import", options.TerraformImport, "Add import blocks for existing load balancers, listeners and target groups to the terraform output (requires terraform 1.5)")
 	cmd.Flags().StringVar(&options.SSHPublicKey, "ssh-public-key", options.SSHPublicKey, "SSH public key to use (deprecated: use kops create secret instead)")
 	cmd.Flags().StringVar(&options.OutDir, "out", options.OutDir, "Path to write any local output")
 	cmd.MarkFlagDirname("out")
@@ -298,6 +300,7 @@ func RunUpdateCluster(ctx context.Context, f *util.Factory, out io.Writer, c *Up
 		AllowKopsDowngrade: c.AllowKopsDowngrade,
 		RunTasksOptions:    &c.RunTasksOptions,
 		OutDir:             c.OutDir,
+		TerraformImport:    c.TerraformImport,
 		Phase:              phase,
 		TargetName:         targetName,
 		LifecycleOverrides: lifecycleOverrideMap,
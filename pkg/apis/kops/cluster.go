@@ -223,6 +223,9 @@ type AWSSpec struct {
 	// Manager to assign to each ELB provisioned for a Service, instead of creating
 	// one per ELB.
 	ElbSecurityGroup *string `json:"elbSecurityGroup,omitempty"`
+	// LoadBalancerTargetType is the target type ("instance" or "ip") used by load balancer
+	// target groups that do not set one explicitly.
+	LoadBalancerTargetType *string `json:"loadBalancerTargetType,omitempty"`
 
 	// Spotinst cloud-config specs
 	SpotinstProduct     *string `json:"spotinstProduct,omitempty"`
@@ -553,6 +556,11 @@ type LoadBalancerAccessSpec struct {
 	SSLPolicy *string `json:"sslPolicy,omitempty"`
 	// CrossZoneLoadBalancing allows you to enable the cross zone load balancing
 	CrossZoneLoadBalancing *bool `json:"crossZoneLoadBalancing,omitempty"`
+	// DeregistrationDelaySeconds is how long the target groups of a network load balancer keep serving the connections
+	// of a deregistered control plane node, between 0 and 3600 seconds. It can only be set with a network load balancer.
+	// It defaults to the shutdownGracePeriod of controlPlaneKubelet, then to the shutdownGracePeriod of kubelet,
+	// rounded up to whole seconds and capped at 3600, and to 30 seconds if neither is set.
+	DeregistrationDelaySeconds *int32 `json:"deregistrationDelaySeconds,omitempty"`
 	// Subnets allows you to specify the subnets that must be used for the load balancer
 	Subnets []LoadBalancerSubnetSpec `json:"subnets,omitempty"`
 	// AccessLog is the configuration of access logs.
@@ -19,6 +19,11 @@ package kops
 type ClusterStatus struct {
 	// EtcdClusters stores the status for each cluster
 	EtcdClusters []EtcdClusterStatus `json:"etcdClusters,omitempty"`
+	// LoadBalancers stores the status for each API load balancer
+	LoadBalancers []LoadBalancerStatus `json:"loadBalancers,omitempty"`
+	// Warnings describe the parts of the status that could not be discovered, e.g. because an API call was throttled;
+	// the rest of the status is still reported
+	Warnings []string `json:"warnings,omitempty"`
 }
 
 // EtcdClusterStatus represents the status of etcd: because etcd only allows limited reconfiguration, we have to block changes once etcd has been initialized.
@@ -27,6 +32,8 @@ type EtcdClusterStatus struct {
 	Name string `json:"name,omitempty"`
 	// EtcdMember stores the configurations for each member of the cluster (including the data volume)
 	Members []*EtcdMemberStatus `json:"etcdMembers,omitempty"`
+	// Quorum is whether a majority of the members of the cluster are healthy; nil if the health of the members is unknown
+	Quorum *bool `json:"quorum,omitempty"`
 }
 
 type EtcdMemberStatus struct {
@@ -35,4 +42,93 @@ type EtcdMemberStatus struct {
 
 	// VolumeID is the id of the cloud volume (e.g. the AWS volume id)
 	VolumeID string `json:"volumeID,omitempty"`
+
+	// InstanceID is the id of the instance the volume is attached to, if any
+	InstanceID string `json:"instanceID,omitempty"`
+
+	// AvailabilityZone is the availability zone of the volume
+	AvailabilityZone string `json:"availabilityZone,omitempty"`
+
+	// Healthy is a best-effort signal of the health of the member (e.g. whether its volume is attached to a running instance);
+	// nil if unknown
+	Healthy *bool `json:"healthy,omitempty"`
+}
+
+// LoadBalancerStatus represents the status of a load balancer serving the cluster API.
+type LoadBalancerStatus struct {
+	// Name is the name of the load balancer (the value of the Name tag on AWS)
+	Name string `json:"name,omitempty"`
+	// ARN is the Amazon Resource Name of the load balancer
+	ARN string `json:"arn,omitempty"`
+	// DNSName is the DNS name of the load balancer
+	DNSName string `json:"dnsName,omitempty"`
+	// CanonicalHostedZoneID is the ID of the Route 53 hosted zone of the load balancer, for DNS alias records
+	CanonicalHostedZoneID string `json:"canonicalHostedZoneID,omitempty"`
+	// State is the provisioning state of the load balancer (e.g. active, provisioning or failed)
+	State string `json:"state,omitempty"`
+	// AvailabilityZones are the availability zones the load balancer is enabled in
+	AvailabilityZones []string `json:"availabilityZones,omitempty"`
+	// SubnetIDs are the IDs of the subnets the load balancer is attached to, in the order of AvailabilityZones
+	SubnetIDs []string `json:"subnetIDs,omitempty"`
+	// IPAddressType is the type of IP addresses the load balancer uses (e.g. ipv4 or dualstack)
+	IPAddressType string `json:"ipAddressType,omitempty"`
+	// SecurityGroups are the IDs of the security groups attached to the load balancer
+	SecurityGroups []string `json:"securityGroups,omitempty"`
+	// Listeners stores the status for each listener on the load balancer
+	Listeners []ListenerStatus `json:"listeners,omitempty"`
+	// TargetGroups stores the targets registered with each target group the listeners forward to
+	TargetGroups []TargetGroupStatus `json:"targetGroups,omitempty"`
+}
+
+// TargetGroupStatus represents the targets registered with a target group of a load balancer.
+type TargetGroupStatus struct {
+	// Name is the name of the target group
+	Name string `json:"name,omitempty"`
+	// ARN is the Amazon Resource Name of the target group
+	ARN string `json:"arn,omitempty"`
+	// HealthyTargets is the number of targets passing health checks
+	HealthyTargets int32 `json:"healthyTargets,omitempty"`
+	// UnhealthyTargets is the number of targets failing health checks, or whose health is unavailable
+	UnhealthyTargets int32 `json:"unhealthyTargets,omitempty"`
+	// DrainingTargets is the number of targets being deregistered
+	DrainingTargets int32 `json:"drainingTargets,omitempty"`
+	// Targets stores the status for each registered target
+	Targets []TargetStatus `json:"targets,omitempty"`
+}
+
+// TargetStatus represents a target registered with a target group, and its health.
+type TargetStatus struct {
+	// ID is the ID of the target, typically an instance ID
+	ID string `json:"id,omitempty"`
+	// State is the health of the target (e.g. healthy, unhealthy or initial)
+	State string `json:"state,omitempty"`
+	// EtcdMembers are the etcd members (as <etcd cluster>/<member>) whose volumes are attached to the target instance
+	EtcdMembers []string `json:"etcdMembers,omitempty"`
+}
+
+// ListenerStatus represents the effective configuration of a load balancer listener.
+type ListenerStatus struct {
+	// ARNs are the Amazon Resource Names of the listener. A TCP and a UDP listener reported together
+	// as a TCP_UDP listener have one ARN each.
+	ARNs []string `json:"arns,omitempty"`
+	// Port is the port the listener accepts connections on
+	Port int32 `json:"port,omitempty"`
+	// Protocol is the protocol of the listener (e.g. TCP or TLS)
+	Protocol string `json:"protocol,omitempty"`
+	// SSLPolicy is the security policy used to negotiate TLS connections, for TLS listeners
+	SSLPolicy string `json:"sslPolicy,omitempty"`
+	// CertificateARN is the ARN of the default certificate of the listener, for TLS listeners
+	CertificateARN string `json:"certificateARN,omitempty"`
+	// CertificateNotAfter is when the certificate of the listener expires, in RFC3339 format.
+	// It changes when the certificate is renewed in place, and is only reported for IAM server certificates.
+	CertificateNotAfter string `json:"certificateNotAfter,omitempty"`
+	// MinimumTLSVersion is the lowest TLS protocol version accepted by the SSLPolicy (e.g. TLSv1.2).
+	// Clients that only support older versions will fail the TLS handshake.
+	MinimumTLSVersion string `json:"minimumTLSVersion,omitempty"`
+	// CertificateRotatedAt is when kops last rotated the certificate of the listener, in RFC3339 format.
+	// For a while after a rotation, clients cannot resume TLS sessions established with the previous certificate,
+	// and perform full handshakes instead.
+	CertificateRotatedAt string `json:"certificateRotatedAt,omitempty"`
+	// CorrelationID is the stable identifier kops tags the listener with, to attribute access log records to the listener
+	CorrelationID string `json:"correlationID,omitempty"`
 }
@@ -958,6 +958,10 @@ type CloudConfiguration struct {
 	// one per ELB (AWS only).
 	// +k8s:conversion-gen=false
 	ElbSecurityGroup *string `json:"elbSecurityGroup,omitempty"`
+	// LoadBalancerTargetType is the target type ("instance" or "ip") used by load balancer
+	// target groups that do not set one explicitly (AWS only).
+	// +k8s:conversion-gen=false
+	LoadBalancerTargetType *string `json:"loadBalancerTargetType,omitempty"`
 	// VSphereUsername is unused.
 	// +k8s:conversion-gen=false
 	VSphereUsername *string `json:"vSphereUsername,omitempty"`
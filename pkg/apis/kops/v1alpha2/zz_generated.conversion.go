@@ -2184,6 +2184,7 @@ func autoConvert_v1alpha2_CloudConfiguration_To_kops_CloudConfiguration(in *Clou
 	// INFO: in.GCEServiceAccount opted out of conversion generation
 	// INFO: in.DisableSecurityGroupIngress opted out of conversion generation
 	// INFO: in.ElbSecurityGroup opted out of conversion generation
+	// INFO: in.LoadBalancerTargetType opted out of conversion generation
 	// INFO: in.VSphereUsername opted out of conversion generation
 	// INFO: in.VSpherePassword opted out of conversion generation
 	// INFO: in.VSphereServer opted out of conversion generation
@@ -5746,6 +5747,7 @@ func autoConvert_v1alpha2_LoadBalancerAccessSpec_To_kops_LoadBalancerAccessSpec(
 	out.SSLCertificate = in.SSLCertificate
 	out.SSLPolicy = in.SSLPolicy
 	out.CrossZoneLoadBalancing = in.CrossZoneLoadBalancing
+	out.DeregistrationDelaySeconds = in.DeregistrationDelaySeconds
 	if in.Subnets != nil {
 		in, out := &in.Subnets, &out.Subnets
 		*out = make([]kops.LoadBalancerSubnetSpec, len(*in))
@@ -5784,6 +5786,7 @@ func autoConvert_kops_LoadBalancerAccessSpec_To_v1alpha2_LoadBalancerAccessSpec(
 	out.SSLCertificate = in.SSLCertificate
 	out.SSLPolicy = in.SSLPolicy
 	out.CrossZoneLoadBalancing = in.CrossZoneLoadBalancing
+	out.DeregistrationDelaySeconds = in.DeregistrationDelaySeconds
 	if in.Subnets != nil {
 		in, out := &in.Subnets, &out.Subnets
 		*out = make([]LoadBalancerSubnetSpec, len(*in))
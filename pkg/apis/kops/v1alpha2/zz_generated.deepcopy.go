@@ -746,6 +746,11 @@ func (in *CloudConfiguration) DeepCopyInto(out *CloudConfiguration) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.LoadBalancerTargetType != nil {
+		in, out := &in.LoadBalancerTargetType, &out.LoadBalancerTargetType
+		*out = new(string)
+		**out = **in
+	}
 	if in.VSphereUsername != nil {
 		in, out := &in.VSphereUsername, &out.VSphereUsername
 		*out = new(string)
@@ -4360,6 +4365,11 @@ func (in *LoadBalancerAccessSpec) DeepCopyInto(out *LoadBalancerAccessSpec) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.DeregistrationDelaySeconds != nil {
+		in, out := &in.DeregistrationDelaySeconds, &out.DeregistrationDelaySeconds
+		*out = new(int32)
+		**out = **in
+	}
 	if in.Subnets != nil {
 		in, out := &in.Subnets, &out.Subnets
 		*out = make([]LoadBalancerSubnetSpec, len(*in))
@@ -193,6 +193,11 @@ func (in *AWSSpec) DeepCopyInto(out *AWSSpec) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.LoadBalancerTargetType != nil {
+		in, out := &in.LoadBalancerTargetType, &out.LoadBalancerTargetType
+		*out = new(string)
+		**out = **in
+	}
 	if in.SpotinstProduct != nil {
 		in, out := &in.SpotinstProduct, &out.SpotinstProduct
 		*out = new(string)
@@ -4334,6 +4339,11 @@ func (in *LoadBalancerAccessSpec) DeepCopyInto(out *LoadBalancerAccessSpec) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.DeregistrationDelaySeconds != nil {
+		in, out := &in.DeregistrationDelaySeconds, &out.DeregistrationDelaySeconds
+		*out = new(int32)
+		**out = **in
+	}
 	if in.Subnets != nil {
 		in, out := &in.Subnets, &out.Subnets
 		*out = make([]LoadBalancerSubnetSpec, len(*in))
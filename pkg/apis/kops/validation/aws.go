@@ -52,10 +52,20 @@ func awsValidateCluster(c *kops.Cluster, strict bool) field.ErrorList {
 			allErrs = append(allErrs, field.Forbidden(lbPath.Child("sslCertificate"), "sslCertificate requires a network load balancer. See https://github.com/kubernetes/kops/blob/master/permalinks/acm_nlb.md"))
 		}
 		allErrs = append(allErrs, awsValidateSSLPolicy(lbPath.Child("sslPolicy"), lbSpec)...)
+		if lbSpec.DeregistrationDelaySeconds != nil {
+			fieldPath := lbPath.Child("deregistrationDelaySeconds")
+			if lbSpec.Class != kops.LoadBalancerClassNetwork {
+				allErrs = append(allErrs, field.Forbidden(fieldPath, "deregistrationDelaySeconds should be specified with Network Load Balancer"))
+			}
+			if delay := *lbSpec.DeregistrationDelaySeconds; delay < 0 || delay > 3600 {
+				allErrs = append(allErrs, field.Invalid(fieldPath, delay, "must be between 0 and 3600"))
+			}
+		}
 		allErrs = append(allErrs, awsValidateLoadBalancerSubnets(lbPath.Child("subnets"), c.Spec)...)
 	}
 
 	allErrs = append(allErrs, awsValidateEBSCSIDriver(c)...)
+	allErrs = append(allErrs, awsValidateLoadBalancerTargetType(c)...)
 
 	if c.Spec.Authentication != nil && c.Spec.Authentication.AWS != nil {
 		allErrs = append(allErrs, awsValidateIAMAuthenticator(field.NewPath("spec", "authentication", "aws"), c.Spec.Authentication.AWS)...)
@@ -74,6 +84,26 @@ func awsValidateEBSCSIDriver(cluster *kops.Cluster) (allErrs field.ErrorList) {
 	return allErrs
 }
 
+// awsValidateLoadBalancerTargetType checks the cluster-wide default target type.
+// Target groups can only default to the target types every network load balancer
+// target group supports; alb targets have to be set on an individual target group.
+// The target groups of the API and bastion load balancers are attached to autoscaling groups,
+// which only register instances, so ip cannot be used alongside them.
+func awsValidateLoadBalancerTargetType(cluster *kops.Cluster) field.ErrorList {
+	fldPath := field.NewPath("spec", "cloudProvider", "aws", "loadBalancerTargetType")
+	targetType := cluster.Spec.CloudProvider.AWS.LoadBalancerTargetType
+	allErrs := IsValidValue(fldPath, targetType, []string{"instance", "ip"})
+	if fi.ValueOf(targetType) == "ip" {
+		if cluster.Spec.API.LoadBalancer != nil && cluster.Spec.API.LoadBalancer.Class == kops.LoadBalancerClassNetwork {
+			allErrs = append(allErrs, field.Forbidden(fldPath, "target type ip cannot be used with a network load balancer for the API, whose target groups register instances"))
+		}
+		if cluster.Spec.Networking.Topology != nil && cluster.Spec.Networking.Topology.Bastion != nil {
+			allErrs = append(allErrs, field.Forbidden(fldPath, "target type ip cannot be used with a bastion, whose load balancer target group registers instances"))
+		}
+	}
+	return allErrs
+}
+
 func awsValidateInstanceGroup(ig *kops.InstanceGroup, cloud awsup.AWSCloud) field.ErrorList {
 	allErrs := field.ErrorList{}
 
@@ -85,6 +85,66 @@ func TestAWSValidateEBSCSIDriver(t *testing.T) {
 	}
 }
 
+func TestAWSValidateLoadBalancerTargetType(t *testing.T) {
+	grid := []struct {
+		TargetType     *string
+		API            kops.APISpec
+		Topology       *kops.TopologySpec
+		ExpectedErrors []string
+	}{
+		{
+			TargetType: nil,
+		},
+		{
+			TargetType: fi.PtrTo("instance"),
+		},
+		{
+			TargetType: fi.PtrTo("ip"),
+		},
+		{
+			TargetType:     fi.PtrTo("alb"),
+			ExpectedErrors: []string{"Unsupported value::spec.cloudProvider.aws.loadBalancerTargetType"},
+		},
+		{
+			TargetType: fi.PtrTo("instance"),
+			API:        kops.APISpec{LoadBalancer: &kops.LoadBalancerAccessSpec{Class: kops.LoadBalancerClassNetwork}},
+			Topology:   &kops.TopologySpec{Bastion: &kops.BastionSpec{}},
+		},
+		{
+			TargetType: fi.PtrTo("ip"),
+			API:        kops.APISpec{LoadBalancer: &kops.LoadBalancerAccessSpec{Class: kops.LoadBalancerClassClassic}},
+		},
+		{
+			TargetType:     fi.PtrTo("ip"),
+			API:            kops.APISpec{LoadBalancer: &kops.LoadBalancerAccessSpec{Class: kops.LoadBalancerClassNetwork}},
+			ExpectedErrors: []string{"Forbidden::spec.cloudProvider.aws.loadBalancerTargetType"},
+		},
+		{
+			TargetType:     fi.PtrTo("ip"),
+			Topology:       &kops.TopologySpec{Bastion: &kops.BastionSpec{}},
+			ExpectedErrors: []string{"Forbidden::spec.cloudProvider.aws.loadBalancerTargetType"},
+		},
+	}
+	for _, g := range grid {
+		cluster := &kops.Cluster{
+			Spec: kops.ClusterSpec{
+				API: g.API,
+				CloudProvider: kops.CloudProviderSpec{
+					AWS: &kops.AWSSpec{
+						LoadBalancerTargetType: g.TargetType,
+					},
+				},
+				Networking: kops.NetworkingSpec{
+					Topology: g.Topology,
+				},
+			},
+		}
+		errs := awsValidateLoadBalancerTargetType(cluster)
+
+		testErrors(t, g.TargetType, errs, g.ExpectedErrors)
+	}
+}
+
 func TestValidateInstanceGroupSpec(t *testing.T) {
 	grid := []struct {
 		Input          kops.InstanceGroupSpec
@@ -607,6 +667,39 @@ func TestLoadBalancerSubnets(t *testing.T) {
 	}
 }
 
+func TestLoadBalancerDeregistrationDelay(t *testing.T) {
+	tests := []struct {
+		class    kops.LoadBalancerClass
+		delay    int32
+		expected []string
+	}{
+		{class: kops.LoadBalancerClassNetwork, delay: 0},
+		{class: kops.LoadBalancerClassNetwork, delay: 3600},
+		{class: kops.LoadBalancerClassNetwork, delay: -1, expected: []string{"Invalid value::spec.api.loadBalancer.deregistrationDelaySeconds"}},
+		{class: kops.LoadBalancerClassNetwork, delay: 3601, expected: []string{"Invalid value::spec.api.loadBalancer.deregistrationDelaySeconds"}},
+		{class: kops.LoadBalancerClassClassic, delay: 60, expected: []string{"Forbidden::spec.api.loadBalancer.deregistrationDelaySeconds"}},
+	}
+
+	for _, test := range tests {
+		cluster := kops.Cluster{
+			Spec: kops.ClusterSpec{
+				API: kops.APISpec{
+					LoadBalancer: &kops.LoadBalancerAccessSpec{
+						Class:                      test.class,
+						Type:                       kops.LoadBalancerTypePublic,
+						DeregistrationDelaySeconds: fi.PtrTo(test.delay),
+					},
+				},
+				CloudProvider: kops.CloudProviderSpec{
+					AWS: &kops.AWSSpec{},
+				},
+			},
+		}
+		errs := awsValidateCluster(&cluster, true)
+		testErrors(t, test, errs, test.expected)
+	}
+}
+
 func TestAWSAuthentication(t *testing.T) {
 	tests := []struct {
 		backendMode      string
@@ -110,7 +110,7 @@ func RunToolboxEnroll(ctx context.Context, f commandutils.Factory, out io.Writer
 	wellKnownAddresses := make(model.WellKnownAddresses)
 
 	{
-		ingresses, err := cloud.GetApiIngressStatus(cluster)
+		ingresses, err := cloud.GetApiIngressStatus(ctx, cluster)
 		if err != nil {
 			return fmt.Errorf("error getting ingress status: %v", err)
 		}
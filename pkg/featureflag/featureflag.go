@@ -98,6 +98,9 @@ var (
 	Metal = new("Metal", Bool(false))
 	// AWSSingleNodesInstanceGroup enables the creation of a single node instance group instead of one per availability zone.
 	AWSSingleNodesInstanceGroup = new("AWSSingleNodesInstanceGroup", Bool(false))
+	// PruneLoadBalancerListeners deletes the listeners of the API load balancer that are owned by the cluster
+	// but no longer in the cluster spec.
+	PruneLoadBalancerListeners = new("PruneLoadBalancerListeners", Bool(false))
 )
 
 // FeatureFlag defines a feature flag
@@ -20,8 +20,10 @@ import (
 	"context"
 	"crypto/x509/pkix"
 	"fmt"
+	"net"
 	"os/user"
 	"sort"
+	"strconv"
 	"time"
 
 	"k8s.io/klog/v2"
@@ -33,6 +35,14 @@ import (
 
 const DefaultKubecfgAdminLifetime = 18 * time.Hour
 
+// ingressTarget returns the address of an API ingress point, including its port unless it is the default HTTPS port.
+func ingressTarget(host string, port int32) string {
+	if port == 0 || port == 443 {
+		return host
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(port)))
+}
+
 func BuildKubecfg(ctx context.Context, cluster *kops.Cluster, keyStore fi.KeystoreReader, secretStore fi.SecretStore, cloud fi.Cloud, admin time.Duration, configUser string, internal bool, kopsStateStore string, useKopsAuthenticationPlugin bool) (*KubeconfigBuilder, error) {
 	clusterName := cluster.ObjectMeta.Name
 
@@ -49,7 +59,7 @@ func BuildKubecfg(ctx context.Context, cluster *kops.Cluster, keyStore fi.Keysto
 		// If a load balancer exists we use it, except for when an SSL certificate is set.
 		// This should avoid a lot of pain with DNS pre-creation.
 		if cluster.Spec.API.LoadBalancer != nil && (cluster.Spec.API.LoadBalancer.SSLCertificate == "" || admin != 0) {
-			ingresses, err := cloud.GetApiIngressStatus(cluster)
+			ingresses, err := cloud.GetApiIngressStatus(ctx, cluster)
 			if err != nil {
 				return nil, fmt.Errorf("error getting ingress status: %v", err)
 			}
@@ -62,10 +72,10 @@ func BuildKubecfg(ctx context.Context, cluster *kops.Cluster, keyStore fi.Keysto
 						continue
 					}
 					if ingress.Hostname != "" {
-						targets = append(targets, ingress.Hostname)
+						targets = append(targets, ingressTarget(ingress.Hostname, ingress.Port))
 					}
 					if ingress.IP != "" {
-						targets = append(targets, ingress.IP)
+						targets = append(targets, ingressTarget(ingress.IP, ingress.Port))
 					}
 				}
 				if len(targets) > 0 {
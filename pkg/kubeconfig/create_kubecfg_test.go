@@ -46,7 +46,7 @@ type fakeStatusCloud struct {
 
 var _ fi.Cloud = &fakeStatusCloud{}
 
-func (f fakeStatusCloud) GetApiIngressStatus(cluster *kops.Cluster) ([]fi.ApiIngressStatus, error) {
+func (f fakeStatusCloud) GetApiIngressStatus(ctx context.Context, cluster *kops.Cluster) ([]fi.ApiIngressStatus, error) {
 	return f.GetApiIngressStatusFn(cluster)
 }
 
@@ -86,7 +86,7 @@ func (f fakeStatusCloud) Region() string {
 	panic("not implemented")
 }
 
-func (f fakeStatusCloud) FindClusterStatus(cluster *kops.Cluster) (*kops.ClusterStatus, error) {
+func (f fakeStatusCloud) FindClusterStatus(ctx context.Context, cluster *kops.Cluster) (*kops.ClusterStatus, error) {
 	panic("not implemented")
 }
 
@@ -175,6 +175,17 @@ func TestBuildKubecfg(t *testing.T) {
 		},
 	}
 
+	customPortStatus := fakeStatusCloud{
+		GetApiIngressStatusFn: func(cluster *kops.Cluster) ([]fi.ApiIngressStatus, error) {
+			return []fi.ApiIngressStatus{
+				{
+					Hostname: "elbHostName",
+					Port:     6443,
+				},
+			}, nil
+		},
+	}
+
 	tests := []struct {
 		name           string
 		args           args
@@ -296,6 +307,21 @@ func TestBuildKubecfg(t *testing.T) {
 			},
 			wantClientCert: false,
 		},
+		{
+			name: "Test Kube Config Data For Gossip cluster with custom API port",
+			args: args{
+				cluster: gossipCluster,
+				status:  customPortStatus,
+			},
+			want: &KubeconfigBuilder{
+				Context:       "testgossipcluster.k8s.local",
+				Server:        "https://elbHostName:6443",
+				TLSServerName: "api.internal.testgossipcluster.k8s.local",
+				CACerts:       []byte(nextCertificate + certData),
+				User:          "testgossipcluster.k8s.local",
+			},
+			wantClientCert: false,
+		},
 		{
 			name: "Public DNS with kops auth plugin",
 			args: args{
@@ -19,12 +19,14 @@ package awsmodel
 import (
 	"fmt"
 	"sort"
+	"strconv"
 	"time"
 
 	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/featureflag"
 	"k8s.io/kops/pkg/wellknownports"
 	"k8s.io/kops/pkg/wellknownservices"
 	"k8s.io/kops/upup/pkg/fi"
@@ -34,6 +36,34 @@ import (
 // LoadBalancerDefaultIdleTimeout is the default idle time for the ELB
 const LoadBalancerDefaultIdleTimeout = 5 * time.Minute
 
+// defaultAPIDeregistrationDelaySeconds is the deregistration delay of the API target groups
+// when the control plane does not shut down gracefully.
+const defaultAPIDeregistrationDelaySeconds = 30
+
+// maxDeregistrationDelaySeconds is the longest deregistration delay accepted by AWS.
+const maxDeregistrationDelaySeconds = 3600
+
+// apiDeregistrationDelaySeconds returns the deregistration delay of the target groups of the API network load balancer:
+// the delay set in the cluster spec, otherwise the graceful shutdown period of the control plane kubelet,
+// so that connections are not cut while a control plane node is drained during a rolling update.
+func apiDeregistrationDelaySeconds(cluster *kops.Cluster) int32 {
+	if lbSpec := cluster.Spec.API.LoadBalancer; lbSpec != nil && lbSpec.DeregistrationDelaySeconds != nil {
+		return *lbSpec.DeregistrationDelaySeconds
+	}
+	var shutdownGracePeriod time.Duration
+	for _, kubelet := range []*kops.KubeletConfigSpec{cluster.Spec.ControlPlaneKubelet, cluster.Spec.Kubelet} {
+		if kubelet != nil && kubelet.ShutdownGracePeriod != nil {
+			shutdownGracePeriod = kubelet.ShutdownGracePeriod.Duration
+			break
+		}
+	}
+	seconds := int32((shutdownGracePeriod + time.Second - 1) / time.Second)
+	if seconds <= 0 {
+		return defaultAPIDeregistrationDelaySeconds
+	}
+	return min(seconds, maxDeregistrationDelaySeconds)
+}
+
 // APILoadBalancerBuilder builds a LoadBalancer for accessing the API
 type APILoadBalancerBuilder struct {
 	*AWSModelContext
@@ -135,54 +165,21 @@ func (b *APILoadBalancerBuilder) Build(c *fi.CloudupModelBuilderContext) error {
 		}
 		var nlbListeners []*awstasks.NetworkLoadBalancerListener
 
-		if lbSpec.SSLCertificate == "" {
-			listener443 := &awstasks.NetworkLoadBalancerListener{
-				Name:                fi.PtrTo(b.NLBListenerName("api", 443)),
-				Lifecycle:           b.Lifecycle,
-				NetworkLoadBalancer: b.LinkToNLB("api"),
-				Port:                443,
-				TargetGroup:         b.LinkToTargetGroup("tcp"),
-			}
-			nlbListeners = append(nlbListeners, listener443)
-		} else {
-			// When using a custom certificate, we create a secondary listener on 8443, which does _not_ use the custom certificate.
-			// This is because client certificates cannot be used in conjunction with custom certificates on NLBs.
-			listener8443 := &awstasks.NetworkLoadBalancerListener{
-				Name:                fi.PtrTo(b.NLBListenerName("api", 8443)),
-				Lifecycle:           b.Lifecycle,
-				NetworkLoadBalancer: b.LinkToNLB("api"),
-				Port:                8443,
-				TargetGroup:         b.LinkToTargetGroup("tcp"),
-			}
-			nlbListeners = append(nlbListeners, listener8443)
-
-			// The primary listener _does_ use the custom certificate.
-			listeners["443"].SSLCertificateID = lbSpec.SSLCertificate
-			listener443 := &awstasks.NetworkLoadBalancerListener{
-				Name:                fi.PtrTo(b.NLBListenerName("api", 443)),
+		for _, spec := range APILoadBalancerListeners(b.Cluster) {
+			nlbListeners = append(nlbListeners, &awstasks.NetworkLoadBalancerListener{
+				Name:                fi.PtrTo(b.NLBListenerName("api", spec.Port)),
 				Lifecycle:           b.Lifecycle,
 				NetworkLoadBalancer: b.LinkToNLB("api"),
-				Port:                443,
-				TargetGroup:         b.LinkToTargetGroup("tls"),
-				SSLCertificateID:    lbSpec.SSLCertificate,
-			}
-			if lbSpec.SSLPolicy != nil {
-				listener443.SSLPolicy = *lbSpec.SSLPolicy
-			} else {
-				listener443.SSLPolicy = "ELBSecurityPolicy-2016-08" // The AWS default
-			}
-			nlbListeners = append(nlbListeners, listener443)
+				Port:                spec.Port,
+				Protocol:            spec.Protocol,
+				TargetGroup:         b.LinkToTargetGroup(spec.TargetGroup),
+				SSLCertificateID:    spec.SSLCertificateID,
+				SSLPolicy:           spec.SSLPolicy,
+			})
 		}
-
-		if b.Cluster.UsesNoneDNS() {
-			nlbListener := &awstasks.NetworkLoadBalancerListener{
-				Name:                fi.PtrTo(b.NLBListenerName("api", wellknownports.KopsControllerPort)),
-				Lifecycle:           b.Lifecycle,
-				NetworkLoadBalancer: b.LinkToNLB("api"),
-				Port:                wellknownports.KopsControllerPort,
-				TargetGroup:         b.LinkToTargetGroup("kops-controller"),
-			}
-			nlbListeners = append(nlbListeners, nlbListener)
+		if lbSpec.SSLCertificate != "" {
+			// The classic load balancer terminates TLS with the custom certificate on 443
+			listeners["443"].SSLCertificateID = lbSpec.SSLCertificate
 		}
 
 		if lbSpec.SecurityGroupOverride != nil {
@@ -210,6 +207,7 @@ func (b *APILoadBalancerBuilder) Build(c *fi.CloudupModelBuilderContext) error {
 			WellKnownServices: []wellknownservices.WellKnownService{wellknownservices.KubeAPIServer},
 			VPC:               b.LinkToVPC(),
 			Type:              elbv2types.LoadBalancerTypeEnumNetwork,
+			PruneListeners:    featureflag.PruneLoadBalancerListeners.Enabled(),
 		}
 
 		// Wait for all load balancer components to be created (including network interfaces needed for NoneDNS).
@@ -300,9 +298,11 @@ func (b *APILoadBalancerBuilder) Build(c *fi.CloudupModelBuilderContext) error {
 		} else if b.APILoadBalancerClass() == kops.LoadBalancerClassNetwork {
 			groupAttrs := map[string]string{
 				awstasks.TargetGroupAttributeDeregistrationDelayConnectionTerminationEnabled: "true",
-				awstasks.TargetGroupAttributeDeregistrationDelayTimeoutSeconds:               "30",
+				awstasks.TargetGroupAttributeDeregistrationDelayTimeoutSeconds:               strconv.Itoa(int(apiDeregistrationDelaySeconds(b.Cluster))),
 			}
 
+			// The target groups are attached to the control plane autoscaling groups, which only register instances,
+			// so they set their target type rather than inheriting the cluster-wide default
 			{
 				groupName := b.NLBTargetGroupName("tcp")
 				groupTags := b.CloudTags(groupName, false)
@@ -315,6 +315,7 @@ func (b *APILoadBalancerBuilder) Build(c *fi.CloudupModelBuilderContext) error {
 					Lifecycle:          b.Lifecycle,
 					VPC:                b.LinkToVPC(),
 					Tags:               groupTags,
+					TargetType:         elbv2types.TargetTypeEnumInstance,
 					Protocol:           elbv2types.ProtocolEnumTcp,
 					Port:               fi.PtrTo(int32(443)),
 					Attributes:         groupAttrs,
@@ -339,6 +340,7 @@ func (b *APILoadBalancerBuilder) Build(c *fi.CloudupModelBuilderContext) error {
 					Lifecycle:          b.Lifecycle,
 					VPC:                b.LinkToVPC(),
 					Tags:               groupTags,
+					TargetType:         elbv2types.TargetTypeEnumInstance,
 					Protocol:           elbv2types.ProtocolEnumTcp,
 					Port:               fi.PtrTo(int32(wellknownports.KopsControllerPort)),
 					Attributes:         groupAttrs,
@@ -363,6 +365,7 @@ func (b *APILoadBalancerBuilder) Build(c *fi.CloudupModelBuilderContext) error {
 					Lifecycle:          b.Lifecycle,
 					VPC:                b.LinkToVPC(),
 					Tags:               tlsGroupTags,
+					TargetType:         elbv2types.TargetTypeEnumInstance,
 					Protocol:           elbv2types.ProtocolEnumTls,
 					Port:               fi.PtrTo(int32(443)),
 					Attributes:         groupAttrs,
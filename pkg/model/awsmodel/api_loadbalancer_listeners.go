@@ -0,0 +1,90 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awsmodel
+
+import (
+	"sort"
+
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/wellknownports"
+)
+
+// APILoadBalancerListenerSpec is a listener of the API network load balancer, as derived from the cluster spec.
+type APILoadBalancerListenerSpec struct {
+	Port     int
+	Protocol elbv2types.ProtocolEnum
+	// TargetGroup is the prefix of the name of the target group the listener forwards to, e.g. "tcp".
+	TargetGroup string
+	// SSLCertificateID and SSLPolicy are set on TLS listeners.
+	SSLCertificateID string
+	SSLPolicy        string
+}
+
+// defaultAPILoadBalancerSSLPolicy is the policy of the TLS listener of the API when the cluster spec does not set one.
+const defaultAPILoadBalancerSSLPolicy = "ELBSecurityPolicy-2016-08" // The AWS default
+
+// APILoadBalancerListeners returns the listeners of the API network load balancer of the cluster, in order of port.
+//
+// The API is served on 443, passing TLS through to the API servers. With a custom certificate, 443 terminates TLS
+// with that certificate instead, and 8443 passes TLS through, because client certificates cannot be used
+// in conjunction with custom certificates on NLBs. kops-controller is served on its own port when the cluster
+// does not use DNS.
+func APILoadBalancerListeners(cluster *kops.Cluster) []APILoadBalancerListenerSpec {
+	lbSpec := cluster.Spec.API.LoadBalancer
+	if lbSpec == nil {
+		return nil
+	}
+
+	var listeners []APILoadBalancerListenerSpec
+	if lbSpec.SSLCertificate == "" {
+		listeners = append(listeners, APILoadBalancerListenerSpec{
+			Port:        443,
+			Protocol:    elbv2types.ProtocolEnumTcp,
+			TargetGroup: "tcp",
+		})
+	} else {
+		sslPolicy := defaultAPILoadBalancerSSLPolicy
+		if lbSpec.SSLPolicy != nil {
+			sslPolicy = *lbSpec.SSLPolicy
+		}
+		listeners = append(listeners, APILoadBalancerListenerSpec{
+			Port:             443,
+			Protocol:         elbv2types.ProtocolEnumTls,
+			TargetGroup:      "tls",
+			SSLCertificateID: lbSpec.SSLCertificate,
+			SSLPolicy:        sslPolicy,
+		}, APILoadBalancerListenerSpec{
+			Port:        8443,
+			Protocol:    elbv2types.ProtocolEnumTcp,
+			TargetGroup: "tcp",
+		})
+	}
+
+	if cluster.UsesNoneDNS() {
+		listeners = append(listeners, APILoadBalancerListenerSpec{
+			Port:        wellknownports.KopsControllerPort,
+			Protocol:    elbv2types.ProtocolEnumTcp,
+			TargetGroup: "kops-controller",
+		})
+	}
+
+	sort.Slice(listeners, func(i, j int) bool {
+		return listeners[i].Port < listeners[j].Port
+	})
+	return listeners
+}
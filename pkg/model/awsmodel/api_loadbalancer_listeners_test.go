@@ -0,0 +1,89 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awsmodel
+
+import (
+	"reflect"
+	"testing"
+
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/upup/pkg/fi"
+)
+
+func TestAPILoadBalancerListeners(t *testing.T) {
+	certificate := "arn:aws:acm:us-east-1:000000000000:certificate/1"
+
+	grid := []struct {
+		name         string
+		loadBalancer *kops.LoadBalancerAccessSpec
+		noneDNS      bool
+		expected     []APILoadBalancerListenerSpec
+	}{
+		{
+			name: "no load balancer",
+		},
+		{
+			name:         "plain 443",
+			loadBalancer: &kops.LoadBalancerAccessSpec{Class: kops.LoadBalancerClassNetwork},
+			expected: []APILoadBalancerListenerSpec{
+				{Port: 443, Protocol: elbv2types.ProtocolEnumTcp, TargetGroup: "tcp"},
+			},
+		},
+		{
+			name:         "tls 443 and 8443",
+			loadBalancer: &kops.LoadBalancerAccessSpec{Class: kops.LoadBalancerClassNetwork, SSLCertificate: certificate},
+			expected: []APILoadBalancerListenerSpec{
+				{Port: 443, Protocol: elbv2types.ProtocolEnumTls, TargetGroup: "tls", SSLCertificateID: certificate, SSLPolicy: "ELBSecurityPolicy-2016-08"},
+				{Port: 8443, Protocol: elbv2types.ProtocolEnumTcp, TargetGroup: "tcp"},
+			},
+		},
+		{
+			name: "tls with policy",
+			loadBalancer: &kops.LoadBalancerAccessSpec{
+				Class:          kops.LoadBalancerClassNetwork,
+				SSLCertificate: certificate,
+				SSLPolicy:      fi.PtrTo("ELBSecurityPolicy-TLS13-1-2-2021-06"),
+			},
+			expected: []APILoadBalancerListenerSpec{
+				{Port: 443, Protocol: elbv2types.ProtocolEnumTls, TargetGroup: "tls", SSLCertificateID: certificate, SSLPolicy: "ELBSecurityPolicy-TLS13-1-2-2021-06"},
+				{Port: 8443, Protocol: elbv2types.ProtocolEnumTcp, TargetGroup: "tcp"},
+			},
+		},
+		{
+			name:         "kops-controller without DNS",
+			loadBalancer: &kops.LoadBalancerAccessSpec{Class: kops.LoadBalancerClassNetwork},
+			noneDNS:      true,
+			expected: []APILoadBalancerListenerSpec{
+				{Port: 443, Protocol: elbv2types.ProtocolEnumTcp, TargetGroup: "tcp"},
+				{Port: 3988, Protocol: elbv2types.ProtocolEnumTcp, TargetGroup: "kops-controller"},
+			},
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			cluster := &kops.Cluster{}
+			cluster.Spec.API.LoadBalancer = g.loadBalancer
+			if g.noneDNS {
+				cluster.Spec.Networking.Topology = &kops.TopologySpec{DNS: kops.DNSTypeNone}
+			}
+			if actual := APILoadBalancerListeners(cluster); !reflect.DeepEqual(actual, g.expected) {
+				t.Errorf("expected listeners %+v, got %+v", g.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awsmodel
+
+import (
+	"testing"
+	"time"
+
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/model"
+	"k8s.io/kops/pkg/model/iam"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awstasks"
+)
+
+func TestAPIDeregistrationDelaySeconds(t *testing.T) {
+	grid := []struct {
+		name                            string
+		kubeletShutdownGracePeriod      *time.Duration
+		controlPlaneShutdownGracePeriod *time.Duration
+		deregistrationDelaySeconds      *int32
+		expected                        int32
+	}{
+		{
+			name:     "no graceful shutdown",
+			expected: 30,
+		},
+		{
+			name:                       "graceful shutdown disabled",
+			kubeletShutdownGracePeriod: fi.PtrTo(time.Duration(0)),
+			expected:                   30,
+		},
+		{
+			name:                       "kubelet graceful shutdown",
+			kubeletShutdownGracePeriod: fi.PtrTo(90 * time.Second),
+			expected:                   90,
+		},
+		{
+			name:                            "control plane graceful shutdown",
+			kubeletShutdownGracePeriod:      fi.PtrTo(90 * time.Second),
+			controlPlaneShutdownGracePeriod: fi.PtrTo(120500 * time.Millisecond),
+			expected:                        121,
+		},
+		{
+			name:                       "graceful shutdown longer than allowed",
+			kubeletShutdownGracePeriod: fi.PtrTo(2 * time.Hour),
+			expected:                   3600,
+		},
+		{
+			name:                       "explicit delay",
+			kubeletShutdownGracePeriod: fi.PtrTo(90 * time.Second),
+			deregistrationDelaySeconds: fi.PtrTo(int32(0)),
+			expected:                   0,
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			cluster := &kops.Cluster{}
+			cluster.Spec.API.LoadBalancer = &kops.LoadBalancerAccessSpec{
+				Class:                      kops.LoadBalancerClassNetwork,
+				DeregistrationDelaySeconds: g.deregistrationDelaySeconds,
+			}
+			if g.kubeletShutdownGracePeriod != nil {
+				cluster.Spec.Kubelet = &kops.KubeletConfigSpec{ShutdownGracePeriod: &metav1.Duration{Duration: *g.kubeletShutdownGracePeriod}}
+			}
+			if g.controlPlaneShutdownGracePeriod != nil {
+				cluster.Spec.ControlPlaneKubelet = &kops.KubeletConfigSpec{ShutdownGracePeriod: &metav1.Duration{Duration: *g.controlPlaneShutdownGracePeriod}}
+			}
+			if actual := apiDeregistrationDelaySeconds(cluster); actual != g.expected {
+				t.Errorf("expected a deregistration delay of %d seconds, got %d", g.expected, actual)
+			}
+		})
+	}
+}
+
+func TestAPILoadBalancerTargetGroupsRegisterInstances(t *testing.T) {
+	cluster := buildMinimalCluster()
+	cluster.Spec.API.LoadBalancer = &kops.LoadBalancerAccessSpec{
+		Type:           kops.LoadBalancerTypePublic,
+		Class:          kops.LoadBalancerClassNetwork,
+		SSLCertificate: "arn:aws:acm:us-test-1:000000000000:certificate/1",
+	}
+	cluster.Spec.Networking.Topology = &kops.TopologySpec{DNS: kops.DNSTypeNone}
+	// The autoscaling groups can only register instances, whatever the cluster-wide default
+	cluster.Spec.CloudProvider.AWS.LoadBalancerTargetType = fi.PtrTo("ip")
+
+	b := &APILoadBalancerBuilder{
+		AWSModelContext: &AWSModelContext{
+			KopsModelContext: &model.KopsModelContext{
+				IAMModelContext: iam.IAMModelContext{Cluster: cluster},
+			},
+		},
+		Lifecycle:         fi.LifecycleSync,
+		SecurityLifecycle: fi.LifecycleSync,
+	}
+	c := &fi.CloudupModelBuilderContext{
+		Tasks: make(map[string]fi.CloudupTask),
+	}
+	if err := b.Build(c); err != nil {
+		t.Fatalf("error from Build: %v", err)
+	}
+
+	found := 0
+	for name, task := range c.Tasks {
+		targetGroup, ok := task.(*awstasks.TargetGroup)
+		if !ok {
+			continue
+		}
+		found++
+		if targetGroup.TargetType != elbv2types.TargetTypeEnumInstance {
+			t.Errorf("expected target group %q to register instances, got target type %q", name, targetGroup.TargetType)
+		}
+	}
+	if found != 3 {
+		t.Errorf("expected the tcp, tls and kops-controller target groups, found %d target groups", found)
+	}
+}
@@ -347,13 +347,9 @@ func (b *BastionModelBuilder) Build(c *fi.CloudupModelBuilderContext) error {
 			SecurityGroups: []*awstasks.SecurityGroup{
 				b.LinkToELBSecurityGroup("bastion"),
 			},
-			Tags:          tags,
-			VPC:           b.LinkToVPC(),
-			Type:          elbv2types.LoadBalancerTypeEnumNetwork,
-			IpAddressType: elbv2types.IpAddressTypeIpv4,
-		}
-		if useIPv6ForBastion(b) {
-			nlb.IpAddressType = elbv2types.IpAddressTypeDualstack
+			Tags: tags,
+			VPC:  b.LinkToVPC(),
+			Type: elbv2types.LoadBalancerTypeEnumNetwork,
 		}
 		// Set the NLB Scheme according to load balancer Type
 		switch bastionLoadBalancerType {
@@ -376,11 +372,13 @@ func (b *BastionModelBuilder) Build(c *fi.CloudupModelBuilderContext) error {
 			awstasks.TargetGroupAttributeDeregistrationDelayTimeoutSeconds:               "30",
 		}
 
+		// The bastion autoscaling groups only register instances, whatever the cluster-wide default target type
 		tg := &awstasks.TargetGroup{
 			Name:               fi.PtrTo(sshGroupName),
 			Lifecycle:          b.Lifecycle,
 			VPC:                b.LinkToVPC(),
 			Tags:               sshGroupTags,
+			TargetType:         elbv2types.TargetTypeEnumInstance,
 			Protocol:           elbv2types.ProtocolEnumTcp,
 			Port:               fi.PtrTo(int32(22)),
 			Attributes:         groupAttrs,
@@ -428,22 +426,6 @@ func (b *BastionModelBuilder) Build(c *fi.CloudupModelBuilderContext) error {
 	return nil
 }
 
-func useIPv6ForBastion(b *BastionModelBuilder) bool {
-	for _, ig := range b.InstanceGroups {
-		for _, igSubnetName := range ig.Spec.Subnets {
-			for _, clusterSubnet := range b.Cluster.Spec.Networking.Subnets {
-				if igSubnetName != clusterSubnet.Name {
-					continue
-				}
-				if clusterSubnet.IPv6CIDR != "" {
-					return true
-				}
-			}
-		}
-	}
-	return false
-}
-
 // Choose between subnets in a zone.
 // We have already applied the rules to match internal subnets to internal NLBs and vice-versa for public-facing NLBs.
 // For internal NLBs: we prefer the control-plane subnets
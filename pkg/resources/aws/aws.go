@@ -53,6 +53,7 @@ const (
 	TypeEventBridgeRule         = "eventbridge-rule"
 	TypeLoadBalancer            = "load-balancer"
 	TypeTargetGroup             = "target-group"
+	TypeLoadBalancerListener    = "load-balancer-listener"
 )
 
 type listFn func(fi.Cloud, string, string) ([]*resources.Resource, error)
@@ -82,6 +83,7 @@ func ListResourcesAWS(cloud awsup.AWSCloud, clusterInfo resources.ClusterInfo) (
 		// ELBs
 		ListELBs,
 		ListELBV2s,
+		ListOrphanedELBV2Listeners,
 		ListTargetGroups,
 		// IAM
 		ListIAMInstanceProfiles,
@@ -1463,11 +1465,24 @@ func DeleteELBV2(cloud fi.Cloud, r *resources.Resource) error {
 	c := cloud.(awsup.AWSCloud)
 	id := r.ID
 
+	protected, err := awsup.FindProtectedELBV2Listeners(ctx, c, id)
+	if err != nil {
+		return err
+	}
+	if len(protected) != 0 {
+		return fmt.Errorf("refusing to delete V2 LoadBalancer %q with protected listeners %v; delete the listeners manually", id, protected)
+	}
+
+	// Deletion protection guards against accidental deletion, not against deleting the cluster
+	if err := awsup.DisableELBV2DeletionProtection(ctx, c, id); err != nil {
+		return err
+	}
+
 	klog.V(2).Infof("Deleting ELBV2 %q", id)
 	request := &elbv2.DeleteLoadBalancerInput{
 		LoadBalancerArn: aws.String(id),
 	}
-	_, err := c.ELBV2().DeleteLoadBalancer(ctx, request)
+	_, err = c.ELBV2().DeleteLoadBalancer(ctx, request)
 	if err != nil {
 		if IsDependencyViolation(err) {
 			return err
@@ -1631,6 +1646,86 @@ func ListELBV2s(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resourc
 	return resourceTrackers, nil
 }
 
+// ListOrphanedELBV2Listeners finds the listeners tagged for the cluster on load balancers that are not tagged for the cluster.
+// These listeners are not removed along with the cluster's load balancers, and would otherwise be left behind,
+// keeping the cluster's target groups in use.
+func ListOrphanedELBV2Listeners(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource, error) {
+	ctx := context.TODO()
+	c := cloud.(awsup.AWSCloud)
+	tags := c.Tags()
+
+	if len(tags) == 0 {
+		// Without cluster tags every listener would match
+		return nil, nil
+	}
+
+	loadBalancers, err := awsup.ListAllELBV2LoadBalancers(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	var resourceTrackers []*resources.Resource
+	for _, loadBalancer := range loadBalancers {
+		if matchesElbV2Tags(tags, loadBalancer.Tags) {
+			// The listeners are deleted along with the load balancer
+			continue
+		}
+
+		listeners, err := awsup.ListELBV2ListenersWithTags(ctx, c, loadBalancer.ARN())
+		if err != nil {
+			return nil, err
+		}
+		for _, listener := range listeners {
+			if !matchesElbV2Tags(tags, listener.Tags) {
+				continue
+			}
+			if _, found := awsup.FindELBV2Tag(listener.Tags, awsup.KopsProtectedTag); found {
+				klog.Infof("ignoring protected listener %q", listener.ARN())
+				continue
+			}
+
+			resourceTracker := &resources.Resource{
+				Name:    fmt.Sprintf("%s:%d", aws.ToString(loadBalancer.LoadBalancer.LoadBalancerName), aws.ToInt32(listener.Listener.Port)),
+				ID:      listener.ARN(),
+				Type:    TypeLoadBalancerListener,
+				Deleter: DeleteELBV2Listener,
+				Obj:     listener.Listener,
+			}
+
+			var blocks []string
+			for _, action := range listener.Listener.DefaultActions {
+				if action.TargetGroupArn != nil {
+					blocks = append(blocks, TypeTargetGroup+":"+aws.ToString(action.TargetGroupArn))
+				} else if action.ForwardConfig != nil {
+					for _, tuple := range action.ForwardConfig.TargetGroups {
+						blocks = append(blocks, TypeTargetGroup+":"+aws.ToString(tuple.TargetGroupArn))
+					}
+				}
+			}
+			resourceTracker.Blocks = blocks
+
+			resourceTrackers = append(resourceTrackers, resourceTracker)
+		}
+	}
+
+	return resourceTrackers, nil
+}
+
+func DeleteELBV2Listener(cloud fi.Cloud, r *resources.Resource) error {
+	ctx := context.TODO()
+	c := cloud.(awsup.AWSCloud)
+	id := r.ID
+
+	klog.V(2).Infof("Deleting ELBV2 listener %q", id)
+	_, err := c.ELBV2().DeleteListener(ctx, &elbv2.DeleteListenerInput{
+		ListenerArn: aws.String(id),
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting V2 LoadBalancer listener %q: %v", id, err)
+	}
+	return nil
+}
+
 func DumpTargetGroup(op *resources.DumpOperation, r *resources.Resource) error {
 	data := make(map[string]interface{})
 	data["id"] = r.ID
@@ -1672,7 +1767,7 @@ func listMatchingTargetGroups(cloud fi.Cloud) ([]*awsup.TargetGroupInfo, error)
 
 	klog.V(2).Infof("Listing all TargetGroups")
 
-	targetGroups, err := awsup.ListELBV2TargetGroups(ctx, c)
+	targetGroups, err := awsup.ListELBV2TargetGroups(ctx, c, "", "")
 	if err != nil {
 		return nil, err
 	}
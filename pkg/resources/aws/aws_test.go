@@ -26,8 +26,11 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	elbtypes "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing/types"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
 	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
 	"k8s.io/kops/cloudmock/aws/mockec2"
+	"k8s.io/kops/cloudmock/aws/mockelbv2"
 	"k8s.io/kops/cloudmock/aws/mockiam"
 	"k8s.io/kops/pkg/resources"
 	"k8s.io/kops/upup/pkg/fi"
@@ -402,3 +405,156 @@ func TestMatchesElbTags(t *testing.T) {
 		}
 	}
 }
+
+func TestDeleteELBV2WithProtectedListener(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	c := &mockelbv2.MockELBV2{}
+	cloud.MockELBV2 = c
+
+	lb, err := c.CreateLoadBalancer(ctx, &elbv2.CreateLoadBalancerInput{
+		Name: aws.String("api-example-com"),
+		Type: elbv2types.LoadBalancerTypeEnumNetwork,
+	})
+	if err != nil {
+		t.Fatalf("error creating load balancer: %v", err)
+	}
+	lbARN := aws.ToString(lb.LoadBalancers[0].LoadBalancerArn)
+
+	listener, err := c.CreateListener(ctx, &elbv2.CreateListenerInput{
+		LoadBalancerArn: aws.String(lbARN),
+		Port:            aws.Int32(443),
+		Protocol:        elbv2types.ProtocolEnumTcp,
+		DefaultActions:  []elbv2types.Action{{Type: elbv2types.ActionTypeEnumForward, TargetGroupArn: aws.String("tg")}},
+		Tags:            []elbv2types.Tag{{Key: aws.String(awsup.KopsProtectedTag), Value: aws.String("true")}},
+	})
+	if err != nil {
+		t.Fatalf("error creating listener: %v", err)
+	}
+
+	r := &resources.Resource{ID: lbARN, Type: TypeLoadBalancer}
+	if err := DeleteELBV2(cloud, r); err == nil {
+		t.Fatalf("expected error deleting load balancer with protected listener")
+	}
+	if len(c.LoadBalancers) != 1 {
+		t.Fatalf("expected load balancer to be kept, found %d", len(c.LoadBalancers))
+	}
+
+	// Once the protected listener is removed manually, the load balancer can be deleted
+	if _, err := c.DeleteListener(ctx, &elbv2.DeleteListenerInput{ListenerArn: listener.Listeners[0].ListenerArn}); err != nil {
+		t.Fatalf("error deleting listener: %v", err)
+	}
+	if err := DeleteELBV2(cloud, r); err != nil {
+		t.Fatalf("unexpected error deleting load balancer: %v", err)
+	}
+	if len(c.LoadBalancers) != 0 {
+		t.Errorf("expected load balancer to be deleted, found %d", len(c.LoadBalancers))
+	}
+}
+
+func TestDeleteELBV2WithDeletionProtection(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	c := &mockelbv2.MockELBV2{}
+	cloud.MockELBV2 = c
+
+	lb, err := c.CreateLoadBalancer(ctx, &elbv2.CreateLoadBalancerInput{
+		Name: aws.String("api-example-com"),
+		Type: elbv2types.LoadBalancerTypeEnumNetwork,
+	})
+	if err != nil {
+		t.Fatalf("error creating load balancer: %v", err)
+	}
+	lbARN := aws.ToString(lb.LoadBalancers[0].LoadBalancerArn)
+
+	if _, err := c.ModifyLoadBalancerAttributes(ctx, &elbv2.ModifyLoadBalancerAttributesInput{
+		LoadBalancerArn: aws.String(lbARN),
+		Attributes:      []elbv2types.LoadBalancerAttribute{{Key: aws.String("deletion_protection.enabled"), Value: aws.String("true")}},
+	}); err != nil {
+		t.Fatalf("error enabling deletion protection: %v", err)
+	}
+	if _, err := c.DeleteLoadBalancer(ctx, &elbv2.DeleteLoadBalancerInput{LoadBalancerArn: aws.String(lbARN)}); err == nil {
+		t.Fatalf("expected deletion protection to prevent deleting the load balancer")
+	}
+
+	r := &resources.Resource{ID: lbARN, Type: TypeLoadBalancer}
+	if err := DeleteELBV2(cloud, r); err != nil {
+		t.Fatalf("unexpected error deleting load balancer: %v", err)
+	}
+	if len(c.LoadBalancers) != 0 {
+		t.Errorf("expected load balancer to be deleted, found %d", len(c.LoadBalancers))
+	}
+}
+
+func TestListOrphanedELBV2Listeners(t *testing.T) {
+	ctx := context.TODO()
+
+	mockCloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	c := &mockelbv2.MockELBV2{}
+	mockCloud.MockELBV2 = c
+	clusterTags := map[string]string{awsup.TagClusterName: "me.example.com"}
+	cloud := mockCloud.WithTags(clusterTags)
+
+	createLoadBalancer := func(name string, tags map[string]string) string {
+		lb, err := c.CreateLoadBalancer(ctx, &elbv2.CreateLoadBalancerInput{
+			Name: aws.String(name),
+			Type: elbv2types.LoadBalancerTypeEnumNetwork,
+			Tags: awsup.ELBv2Tags(tags),
+		})
+		if err != nil {
+			t.Fatalf("error creating load balancer: %v", err)
+		}
+		return aws.ToString(lb.LoadBalancers[0].LoadBalancerArn)
+	}
+	createListener := func(lbARN string, port int32, tags map[string]string) string {
+		listener, err := c.CreateListener(ctx, &elbv2.CreateListenerInput{
+			LoadBalancerArn: aws.String(lbARN),
+			Port:            aws.Int32(port),
+			Protocol:        elbv2types.ProtocolEnumTcp,
+			DefaultActions:  []elbv2types.Action{{Type: elbv2types.ActionTypeEnumForward, TargetGroupArn: aws.String("tg-" + lbARN)}},
+			Tags:            awsup.ELBv2Tags(tags),
+		})
+		if err != nil {
+			t.Fatalf("error creating listener: %v", err)
+		}
+		return aws.ToString(listener.Listeners[0].ListenerArn)
+	}
+
+	// The cluster's own load balancer: its listeners are deleted along with it
+	clusterLB := createLoadBalancer("api-me-example-com", clusterTags)
+	createListener(clusterLB, 443, clusterTags)
+
+	// A load balancer shared with something else, with listeners added for the cluster and for another cluster
+	sharedLB := createLoadBalancer("shared", map[string]string{"Name": "shared"})
+	orphaned := createListener(sharedLB, 443, clusterTags)
+	createListener(sharedLB, 8443, map[string]string{awsup.TagClusterName: "other.example.com"})
+	createListener(sharedLB, 9443, nil)
+	createListener(sharedLB, 10443, map[string]string{awsup.TagClusterName: "me.example.com", awsup.KopsProtectedTag: "true"})
+
+	resourceTrackers, err := ListOrphanedELBV2Listeners(cloud, "", "me.example.com")
+	if err != nil {
+		t.Fatalf("unexpected error listing listeners: %v", err)
+	}
+	if len(resourceTrackers) != 1 {
+		t.Fatalf("expected exactly one orphaned listener, found %v", resourceTrackers)
+	}
+	r := resourceTrackers[0]
+	if r.ID != orphaned || r.Type != TypeLoadBalancerListener {
+		t.Errorf("unexpected orphaned listener %s:%s", r.Type, r.ID)
+	}
+	if expected := []string{TypeTargetGroup + ":tg-" + sharedLB}; !reflect.DeepEqual(r.Blocks, expected) {
+		t.Errorf("unexpected blocks: expected %v, got %v", expected, r.Blocks)
+	}
+
+	if err := r.Deleter(cloud, r); err != nil {
+		t.Fatalf("unexpected error deleting listener: %v", err)
+	}
+	if _, found := c.Listeners[orphaned]; found {
+		t.Errorf("expected orphaned listener to be deleted")
+	}
+	if len(c.Listeners) != 4 {
+		t.Errorf("expected the other listeners to be kept, found %d", len(c.Listeners))
+	}
+}
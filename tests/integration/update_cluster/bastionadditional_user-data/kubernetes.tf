@@ -792,6 +792,10 @@ resource "aws_lb_listener" "bastion-bastionuserdata-example-com-22" {
   load_balancer_arn = aws_lb.bastion-bastionuserdata-example-com.id
   port              = 22
   protocol          = "TCP"
+  tags = {
+    "KubernetesCluster"                                 = "bastionuserdata.example.com"
+    "kubernetes.io/cluster/bastionuserdata.example.com" = "owned"
+  }
 }
 
 resource "aws_lb_target_group" "bastion-bastionuserdata-e-4grhsv" {
@@ -811,7 +815,8 @@ resource "aws_lb_target_group" "bastion-bastionuserdata-e-4grhsv" {
     "Name"                                              = "bastion-bastionuserdata-e-4grhsv"
     "kubernetes.io/cluster/bastionuserdata.example.com" = "owned"
   }
-  vpc_id = aws_vpc.bastionuserdata-example-com.id
+  target_type = "instance"
+  vpc_id      = aws_vpc.bastionuserdata-example-com.id
 }
 
 resource "aws_nat_gateway" "us-test-1a-bastionuserdata-example-com" {
@@ -662,6 +662,10 @@ resource "aws_lb_listener" "api-complex-example-com-443" {
   port              = 443
   protocol          = "TLS"
   ssl_policy        = "ELBSecurityPolicy-2016-08"
+  tags = {
+    "KubernetesCluster"                         = "complex.example.com"
+    "kubernetes.io/cluster/complex.example.com" = "owned"
+  }
 }
 
 resource "aws_lb_listener" "api-complex-example-com-8443" {
@@ -672,6 +676,10 @@ resource "aws_lb_listener" "api-complex-example-com-8443" {
   load_balancer_arn = aws_lb.api-complex-example-com.id
   port              = 8443
   protocol          = "TCP"
+  tags = {
+    "KubernetesCluster"                         = "complex.example.com"
+    "kubernetes.io/cluster/complex.example.com" = "owned"
+  }
 }
 
 resource "aws_lb_target_group" "tcp-complex-example-com-vpjolq" {
@@ -693,7 +701,8 @@ resource "aws_lb_target_group" "tcp-complex-example-com-vpjolq" {
     "foo/bar"                                   = "fib+baz"
     "kubernetes.io/cluster/complex.example.com" = "owned"
   }
-  vpc_id = aws_vpc.complex-example-com.id
+  target_type = "instance"
+  vpc_id      = aws_vpc.complex-example-com.id
 }
 
 resource "aws_lb_target_group" "tls-complex-example-com-5nursn" {
@@ -715,7 +724,8 @@ resource "aws_lb_target_group" "tls-complex-example-com-5nursn" {
     "foo/bar"                                   = "fib+baz"
     "kubernetes.io/cluster/complex.example.com" = "owned"
   }
-  vpc_id = aws_vpc.complex-example-com.id
+  target_type = "instance"
+  vpc_id      = aws_vpc.complex-example-com.id
 }
 
 resource "aws_route" "route-0-0-0-0--0" {
@@ -591,6 +591,10 @@ resource "aws_lb_listener" "api-minimal-example-com-3988" {
   load_balancer_arn = aws_lb.api-minimal-example-com.id
   port              = 3988
   protocol          = "TCP"
+  tags = {
+    "KubernetesCluster"                         = "minimal.example.com"
+    "kubernetes.io/cluster/minimal.example.com" = "owned"
+  }
 }
 
 resource "aws_lb_listener" "api-minimal-example-com-443" {
@@ -601,6 +605,10 @@ resource "aws_lb_listener" "api-minimal-example-com-443" {
   load_balancer_arn = aws_lb.api-minimal-example-com.id
   port              = 443
   protocol          = "TCP"
+  tags = {
+    "KubernetesCluster"                         = "minimal.example.com"
+    "kubernetes.io/cluster/minimal.example.com" = "owned"
+  }
 }
 
 resource "aws_lb_target_group" "kops-controller-minimal-e-uvauf3" {
@@ -620,7 +628,8 @@ resource "aws_lb_target_group" "kops-controller-minimal-e-uvauf3" {
     "Name"                                      = "kops-controller-minimal-e-uvauf3"
     "kubernetes.io/cluster/minimal.example.com" = "owned"
   }
-  vpc_id = aws_vpc.minimal-example-com.id
+  target_type = "instance"
+  vpc_id      = aws_vpc.minimal-example-com.id
 }
 
 resource "aws_lb_target_group" "tcp-minimal-example-com-5905t8" {
@@ -640,7 +649,8 @@ resource "aws_lb_target_group" "tcp-minimal-example-com-5905t8" {
     "Name"                                      = "tcp-minimal-example-com-5905t8"
     "kubernetes.io/cluster/minimal.example.com" = "owned"
   }
-  vpc_id = aws_vpc.minimal-example-com.id
+  target_type = "instance"
+  vpc_id      = aws_vpc.minimal-example-com.id
 }
 
 resource "aws_route" "route-0-0-0-0--0" {
@@ -641,6 +641,10 @@ resource "aws_lb_listener" "api-minimal-ipv6-example-com-443" {
   load_balancer_arn = aws_lb.api-minimal-ipv6-example-com.id
   port              = 443
   protocol          = "TCP"
+  tags = {
+    "KubernetesCluster"                              = "minimal-ipv6.example.com"
+    "kubernetes.io/cluster/minimal-ipv6.example.com" = "owned"
+  }
 }
 
 resource "aws_lb_target_group" "tcp-minimal-ipv6-example--bne5ih" {
@@ -660,7 +664,8 @@ resource "aws_lb_target_group" "tcp-minimal-ipv6-example--bne5ih" {
     "Name"                                           = "tcp-minimal-ipv6-example--bne5ih"
     "kubernetes.io/cluster/minimal-ipv6.example.com" = "owned"
   }
-  vpc_id = aws_vpc.minimal-ipv6-example-com.id
+  target_type = "instance"
+  vpc_id      = aws_vpc.minimal-ipv6-example-com.id
 }
 
 resource "aws_nat_gateway" "us-test-1a-minimal-ipv6-example-com" {
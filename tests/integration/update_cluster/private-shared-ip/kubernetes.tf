@@ -773,6 +773,10 @@ resource "aws_lb_listener" "bastion-private-shared-ip-example-com-22" {
   load_balancer_arn = aws_lb.bastion-private-shared-ip-example-com.id
   port              = 22
   protocol          = "TCP"
+  tags = {
+    "KubernetesCluster"                                   = "private-shared-ip.example.com"
+    "kubernetes.io/cluster/private-shared-ip.example.com" = "owned"
+  }
 }
 
 resource "aws_lb_target_group" "bastion-private-shared-ip-eepmph" {
@@ -792,7 +796,8 @@ resource "aws_lb_target_group" "bastion-private-shared-ip-eepmph" {
     "Name"                                                = "bastion-private-shared-ip-eepmph"
     "kubernetes.io/cluster/private-shared-ip.example.com" = "owned"
   }
-  vpc_id = "vpc-12345678"
+  target_type = "instance"
+  vpc_id      = "vpc-12345678"
 }
 
 resource "aws_nat_gateway" "us-test-1a-private-shared-ip-example-com" {
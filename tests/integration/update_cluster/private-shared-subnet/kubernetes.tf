@@ -768,6 +768,10 @@ resource "aws_lb_listener" "bastion-private-shared-subnet-example-com-22" {
   load_balancer_arn = aws_lb.bastion-private-shared-subnet-example-com.id
   port              = 22
   protocol          = "TCP"
+  tags = {
+    "KubernetesCluster"                                       = "private-shared-subnet.example.com"
+    "kubernetes.io/cluster/private-shared-subnet.example.com" = "owned"
+  }
 }
 
 resource "aws_lb_target_group" "bastion-private-shared-su-5ol32q" {
@@ -787,7 +791,8 @@ resource "aws_lb_target_group" "bastion-private-shared-su-5ol32q" {
     "Name"                                                    = "bastion-private-shared-su-5ol32q"
     "kubernetes.io/cluster/private-shared-subnet.example.com" = "owned"
   }
-  vpc_id = "vpc-12345678"
+  target_type = "instance"
+  vpc_id      = "vpc-12345678"
 }
 
 resource "aws_route53_record" "api-private-shared-subnet-example-com" {
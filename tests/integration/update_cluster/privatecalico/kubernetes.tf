@@ -787,6 +787,10 @@ resource "aws_lb_listener" "bastion-privatecalico-example-com-22" {
   load_balancer_arn = aws_lb.bastion-privatecalico-example-com.id
   port              = 22
   protocol          = "TCP"
+  tags = {
+    "KubernetesCluster"                               = "privatecalico.example.com"
+    "kubernetes.io/cluster/privatecalico.example.com" = "owned"
+  }
 }
 
 resource "aws_lb_target_group" "bastion-privatecalico-exa-hocohm" {
@@ -806,7 +810,8 @@ resource "aws_lb_target_group" "bastion-privatecalico-exa-hocohm" {
     "Name"                                            = "bastion-privatecalico-exa-hocohm"
     "kubernetes.io/cluster/privatecalico.example.com" = "owned"
   }
-  vpc_id = aws_vpc.privatecalico-example-com.id
+  target_type = "instance"
+  vpc_id      = aws_vpc.privatecalico-example-com.id
 }
 
 resource "aws_nat_gateway" "us-test-1a-privatecalico-example-com" {
@@ -791,6 +791,10 @@ resource "aws_lb_listener" "bastion-privatecanal-example-com-22" {
   load_balancer_arn = aws_lb.bastion-privatecanal-example-com.id
   port              = 22
   protocol          = "TCP"
+  tags = {
+    "KubernetesCluster"                              = "privatecanal.example.com"
+    "kubernetes.io/cluster/privatecanal.example.com" = "owned"
+  }
 }
 
 resource "aws_lb_target_group" "bastion-privatecanal-exam-hmhsp5" {
@@ -810,7 +814,8 @@ resource "aws_lb_target_group" "bastion-privatecanal-exam-hmhsp5" {
     "Name"                                           = "bastion-privatecanal-exam-hmhsp5"
     "kubernetes.io/cluster/privatecanal.example.com" = "owned"
   }
-  vpc_id = aws_vpc.privatecanal-example-com.id
+  target_type = "instance"
+  vpc_id      = aws_vpc.privatecanal-example-com.id
 }
 
 resource "aws_nat_gateway" "us-test-1a-privatecanal-example-com" {
@@ -791,6 +791,10 @@ resource "aws_lb_listener" "bastion-privatecilium-example-com-22" {
   load_balancer_arn = aws_lb.bastion-privatecilium-example-com.id
   port              = 22
   protocol          = "TCP"
+  tags = {
+    "KubernetesCluster"                               = "privatecilium.example.com"
+    "kubernetes.io/cluster/privatecilium.example.com" = "owned"
+  }
 }
 
 resource "aws_lb_target_group" "bastion-privatecilium-exa-l2ms01" {
@@ -810,7 +814,8 @@ resource "aws_lb_target_group" "bastion-privatecilium-exa-l2ms01" {
     "Name"                                            = "bastion-privatecilium-exa-l2ms01"
     "kubernetes.io/cluster/privatecilium.example.com" = "owned"
   }
-  vpc_id = aws_vpc.privatecilium-example-com.id
+  target_type = "instance"
+  vpc_id      = aws_vpc.privatecilium-example-com.id
 }
 
 resource "aws_nat_gateway" "us-test-1a-privatecilium-example-com" {
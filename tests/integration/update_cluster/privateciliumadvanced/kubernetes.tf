@@ -808,6 +808,10 @@ resource "aws_lb_listener" "bastion-privateciliumadvanced-example-com-22" {
   load_balancer_arn = aws_lb.bastion-privateciliumadvanced-example-com.id
   port              = 22
   protocol          = "TCP"
+  tags = {
+    "KubernetesCluster"                                       = "privateciliumadvanced.example.com"
+    "kubernetes.io/cluster/privateciliumadvanced.example.com" = "owned"
+  }
 }
 
 resource "aws_lb_target_group" "bastion-privateciliumadva-0jni40" {
@@ -827,7 +831,8 @@ resource "aws_lb_target_group" "bastion-privateciliumadva-0jni40" {
     "Name"                                                    = "bastion-privateciliumadva-0jni40"
     "kubernetes.io/cluster/privateciliumadvanced.example.com" = "owned"
   }
-  vpc_id = aws_vpc.privateciliumadvanced-example-com.id
+  target_type = "instance"
+  vpc_id      = aws_vpc.privateciliumadvanced-example-com.id
 }
 
 resource "aws_nat_gateway" "us-test-1a-privateciliumadvanced-example-com" {
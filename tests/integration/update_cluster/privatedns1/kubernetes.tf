@@ -873,6 +873,10 @@ resource "aws_lb_listener" "bastion-privatedns1-example-com-22" {
   load_balancer_arn = aws_lb.bastion-privatedns1-example-com.id
   port              = 22
   protocol          = "TCP"
+  tags = {
+    "KubernetesCluster"                             = "privatedns1.example.com"
+    "kubernetes.io/cluster/privatedns1.example.com" = "owned"
+  }
 }
 
 resource "aws_lb_target_group" "bastion-privatedns1-examp-mbgbef" {
@@ -894,7 +898,8 @@ resource "aws_lb_target_group" "bastion-privatedns1-examp-mbgbef" {
     "foo/bar"                                       = "fib+baz"
     "kubernetes.io/cluster/privatedns1.example.com" = "owned"
   }
-  vpc_id = aws_vpc.privatedns1-example-com.id
+  target_type = "instance"
+  vpc_id      = aws_vpc.privatedns1-example-com.id
 }
 
 resource "aws_nat_gateway" "us-test-1a-privatedns1-example-com" {
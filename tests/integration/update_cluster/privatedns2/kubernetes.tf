@@ -782,6 +782,10 @@ resource "aws_lb_listener" "bastion-privatedns2-example-com-22" {
   load_balancer_arn = aws_lb.bastion-privatedns2-example-com.id
   port              = 22
   protocol          = "TCP"
+  tags = {
+    "KubernetesCluster"                             = "privatedns2.example.com"
+    "kubernetes.io/cluster/privatedns2.example.com" = "owned"
+  }
 }
 
 resource "aws_lb_target_group" "bastion-privatedns2-examp-e704o2" {
@@ -801,7 +805,8 @@ resource "aws_lb_target_group" "bastion-privatedns2-examp-e704o2" {
     "Name"                                          = "bastion-privatedns2-examp-e704o2"
     "kubernetes.io/cluster/privatedns2.example.com" = "owned"
   }
-  vpc_id = "vpc-12345678"
+  target_type = "instance"
+  vpc_id      = "vpc-12345678"
 }
 
 resource "aws_nat_gateway" "us-test-1a-privatedns2-example-com" {
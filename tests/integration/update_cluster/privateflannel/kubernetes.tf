@@ -791,6 +791,10 @@ resource "aws_lb_listener" "bastion-privateflannel-example-com-22" {
   load_balancer_arn = aws_lb.bastion-privateflannel-example-com.id
   port              = 22
   protocol          = "TCP"
+  tags = {
+    "KubernetesCluster"                                = "privateflannel.example.com"
+    "kubernetes.io/cluster/privateflannel.example.com" = "owned"
+  }
 }
 
 resource "aws_lb_target_group" "bastion-privateflannel-ex-753531" {
@@ -810,7 +814,8 @@ resource "aws_lb_target_group" "bastion-privateflannel-ex-753531" {
     "Name"                                             = "bastion-privateflannel-ex-753531"
     "kubernetes.io/cluster/privateflannel.example.com" = "owned"
   }
-  vpc_id = aws_vpc.privateflannel-example-com.id
+  target_type = "instance"
+  vpc_id      = aws_vpc.privateflannel-example-com.id
 }
 
 resource "aws_nat_gateway" "us-test-1a-privateflannel-example-com" {
@@ -800,6 +800,10 @@ resource "aws_lb_listener" "bastion-privatekopeio-example-com-22" {
   load_balancer_arn = aws_lb.bastion-privatekopeio-example-com.id
   port              = 22
   protocol          = "TCP"
+  tags = {
+    "KubernetesCluster"                               = "privatekopeio.example.com"
+    "kubernetes.io/cluster/privatekopeio.example.com" = "owned"
+  }
 }
 
 resource "aws_lb_target_group" "bastion-privatekopeio-exa-d8ef8e" {
@@ -819,7 +823,8 @@ resource "aws_lb_target_group" "bastion-privatekopeio-exa-d8ef8e" {
     "Name"                                            = "bastion-privatekopeio-exa-d8ef8e"
     "kubernetes.io/cluster/privatekopeio.example.com" = "owned"
   }
-  vpc_id = aws_vpc.privatekopeio-example-com.id
+  target_type = "instance"
+  vpc_id      = aws_vpc.privatekopeio-example-com.id
 }
 
 resource "aws_route" "route-0-0-0-0--0" {
@@ -776,6 +776,10 @@ resource "aws_lb_listener" "bastion-unmanaged-example-com-22" {
   load_balancer_arn = aws_lb.bastion-unmanaged-example-com.id
   port              = 22
   protocol          = "TCP"
+  tags = {
+    "KubernetesCluster"                           = "unmanaged.example.com"
+    "kubernetes.io/cluster/unmanaged.example.com" = "owned"
+  }
 }
 
 resource "aws_lb_target_group" "bastion-unmanaged-example-d7bn3d" {
@@ -795,7 +799,8 @@ resource "aws_lb_target_group" "bastion-unmanaged-example-d7bn3d" {
     "Name"                                        = "bastion-unmanaged-example-d7bn3d"
     "kubernetes.io/cluster/unmanaged.example.com" = "owned"
   }
-  vpc_id = "vpc-12345678"
+  target_type = "instance"
+  vpc_id      = "vpc-12345678"
 }
 
 resource "aws_route53_record" "api-unmanaged-example-com" {
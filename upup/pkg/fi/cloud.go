@@ -17,6 +17,9 @@ limitations under the License.
 package fi
 
 import (
+	"context"
+	"sort"
+
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/kops/dnsprovider/pkg/dnsprovider"
 	"k8s.io/kops/pkg/apis/kops"
@@ -50,9 +53,14 @@ type Cloud interface {
 	// If the region concept does not apply, returns "".
 	Region() string
 
+	StatusStore
+}
+
+// StatusStore discovers the status of a cluster from the cloud.
+type StatusStore interface {
 	// FindClusterStatus discovers the status of the cluster, by inspecting the cloud objects
-	FindClusterStatus(cluster *kops.Cluster) (*kops.ClusterStatus, error)
-	GetApiIngressStatus(cluster *kops.Cluster) ([]ApiIngressStatus, error)
+	FindClusterStatus(ctx context.Context, cluster *kops.Cluster) (*kops.ClusterStatus, error)
+	GetApiIngressStatus(ctx context.Context, cluster *kops.Cluster) ([]ApiIngressStatus, error)
 }
 
 type VPCInfo struct {
@@ -84,4 +92,51 @@ type ApiIngressStatus struct {
 	// (typically AWS load-balancers)
 	// +optional
 	Hostname string `json:"hostname,omitempty" protobuf:"bytes,2,opt,name=hostname"`
+
+	// Port is the port the API is served on, if known; clients should assume 443 otherwise.
+	// When the load balancer also has listeners for other services, or several listeners for the API,
+	// it is the lowest port of a listener forwarding to the API servers.
+	// +optional
+	Port int32 `json:"port,omitempty" protobuf:"varint,3,opt,name=port"`
+
+	// IPFamilies are the address families ("ipv4", "ipv6") that Hostname resolves to, if known
+	// (typically dualstack AWS load-balancers), so that DNS records of the matching types can be created.
+	// +optional
+	IPFamilies []string `json:"ipFamilies,omitempty"`
+
+	// LoadBalancerName is the name of the load balancer serving the ingress point, if known
+	// (typically AWS load-balancers), e.g. so that tooling can look up its listeners.
+	// +optional
+	LoadBalancerName string `json:"loadBalancerName,omitempty" protobuf:"bytes,4,opt,name=loadBalancerName"`
+
+	// LoadBalancerARN is the Amazon Resource Name of the load balancer serving the ingress point,
+	// for AWS load-balancers that have one (not classic load-balancers).
+	// +optional
+	LoadBalancerARN string `json:"loadBalancerARN,omitempty" protobuf:"bytes,5,opt,name=loadBalancerARN"`
+}
+
+// UniqueApiIngressStatus removes the ingress points with the same IP and Hostname as an earlier one,
+// and sorts the rest by Hostname then IP, so that the ingress points of a cluster are reported in a stable order.
+func UniqueApiIngressStatus(ingresses []ApiIngressStatus) []ApiIngressStatus {
+	type key struct {
+		ip       string
+		hostname string
+	}
+	seen := make(map[key]bool)
+	var unique []ApiIngressStatus
+	for _, ingress := range ingresses {
+		k := key{ip: ingress.IP, hostname: ingress.Hostname}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		unique = append(unique, ingress)
+	}
+	sort.SliceStable(unique, func(i, j int) bool {
+		if unique[i].Hostname != unique[j].Hostname {
+			return unique[i].Hostname < unique[j].Hostname
+		}
+		return unique[i].IP < unique[j].IP
+	})
+	return unique
 }
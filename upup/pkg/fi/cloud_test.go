@@ -0,0 +1,54 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUniqueApiIngressStatus(t *testing.T) {
+	ingresses := []ApiIngressStatus{
+		{IP: "192.0.2.20"},
+		{Hostname: "api-b.elb.amazonaws.com"},
+		{IP: "192.0.2.10", InternalEndpoint: true},
+		{Hostname: "api-a.elb.amazonaws.com", IPFamilies: []string{"ipv4", "ipv6"}},
+		{IP: "192.0.2.20"},
+		{Hostname: "api-a.elb.amazonaws.com", IPFamilies: []string{"ipv4", "ipv6"}},
+		{IP: "192.0.2.10", InternalEndpoint: true},
+	}
+	expected := []ApiIngressStatus{
+		{IP: "192.0.2.10", InternalEndpoint: true},
+		{IP: "192.0.2.20"},
+		{Hostname: "api-a.elb.amazonaws.com", IPFamilies: []string{"ipv4", "ipv6"}},
+		{Hostname: "api-b.elb.amazonaws.com"},
+	}
+
+	actual := UniqueApiIngressStatus(ingresses)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected ingress status:\nexpected %+v\ngot      %+v", expected, actual)
+	}
+
+	// The order of the ingress points reported by the cloud doesn't matter
+	for i, j := 0, len(ingresses)-1; i < j; i, j = i+1, j-1 {
+		ingresses[i], ingresses[j] = ingresses[j], ingresses[i]
+	}
+	actual = UniqueApiIngressStatus(ingresses)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected ingress status for reversed input:\nexpected %+v\ngot      %+v", expected, actual)
+	}
+}
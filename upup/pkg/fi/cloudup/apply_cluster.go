@@ -99,6 +99,9 @@ type ApplyClusterCmd struct {
 	// OutDir is a local directory in which we place output, can cache files etc
 	OutDir string
 
+	// TerraformImport adds import blocks for the existing cloud resources to the terraform output.
+	TerraformImport bool
+
 	Clientset simple.Clientset
 
 	// DryRun is true if this is only a dry run
@@ -705,7 +708,7 @@ func (c *ApplyClusterCmd) Run(ctx context.Context) (*ApplyResults, error) {
 		case kops.CloudProviderGCE:
 			target = gce.NewGCEAPITarget(cloud.(gce.GCECloud))
 		case kops.CloudProviderAWS:
-			target = awsup.NewAWSAPITarget(cloud.(awsup.AWSCloud))
+			target = awsup.NewAWSAPITarget(ctx, cloud.(awsup.AWSCloud))
 		case kops.CloudProviderDO:
 			target = do.NewDOAPITarget(cloud.(do.DOCloud))
 		case kops.CloudProviderHetzner:
@@ -723,6 +726,7 @@ func (c *ApplyClusterCmd) Run(ctx context.Context) (*ApplyResults, error) {
 	case TargetTerraform:
 		outDir := c.OutDir
 		tf := terraform.NewTerraformTarget(cloud, project, outDir, cluster.Spec.Target)
+		tf.EmitImportBlocks = c.TerraformImport
 
 		// We include a few "util" variables in the TF output
 		if err := tf.AddOutputVariable("region", terraformWriter.LiteralFromStringValue(cloud.Region())); err != nil {
@@ -0,0 +1,78 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awstasks
+
+import (
+	"time"
+
+	"k8s.io/klog/v2"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+// reconcileTimingLogLevel is the klog verbosity at which the reconcile timings of the load balancer tasks are logged
+const reconcileTimingLogLevel = 4
+
+// ReconcileTiming describes a single reconcile of a load balancer task
+type ReconcileTiming struct {
+	// Task is the type of the task, e.g. NetworkLoadBalancer
+	Task string
+	// Name is the name of the task
+	Name string
+	// Duration is the time taken by the reconcile, including Find and the render
+	Duration time.Duration
+	// APICalls is the number of AWS API calls made by the reconcile; it is only counted for the real AWS clients
+	APICalls int64
+	// Err is the error returned by the reconcile, if any
+	Err error
+}
+
+// ReconcileTimingSink, if set, receives the timing of every reconcile of the NetworkLoadBalancer,
+// NetworkLoadBalancerListener and TargetGroup tasks, for example to export them as metrics.
+// Tasks run in parallel, so it must be safe for concurrent use.
+var ReconcileTimingSink func(ReconcileTiming)
+
+// runTimed runs the reconcile of a load balancer task, timing it when the timings are logged or a sink is set.
+func runTimed(c *fi.CloudupContext, task string, name string, run func(c *fi.CloudupContext) error) error {
+	sink := ReconcileTimingSink
+	if sink == nil && !klog.V(reconcileTimingLogLevel).Enabled() {
+		return run(c)
+	}
+
+	counter := &awsup.APICallCounter{}
+	ctx := awsup.WithAPICallCounter(c.Context(), counter)
+	timed := c.WithContext(ctx)
+	if t, ok := c.Target.(*awsup.AWSAPITarget); ok {
+		timed.Target = t.WithContext(ctx)
+	}
+
+	start := time.Now()
+	err := run(timed)
+	timing := ReconcileTiming{
+		Task:     task,
+		Name:     name,
+		Duration: time.Since(start),
+		APICalls: counter.Calls(),
+		Err:      err,
+	}
+
+	klog.V(reconcileTimingLogLevel).Infof("reconciled %s %q in %v with %d AWS API calls", timing.Task, timing.Name, timing.Duration, timing.APICalls)
+	if sink != nil {
+		sink(timing)
+	}
+	return err
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awstasks
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"k8s.io/kops/cloudmock/aws/mockec2"
+	"k8s.io/kops/cloudmock/aws/mockelbv2"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+// apiCallCountingELBV2 counts the DescribeLoadBalancers calls made by Find, and the Create calls made by RenderAWS,
+// as the middleware of the real AWS clients would
+type apiCallCountingELBV2 struct {
+	*mockelbv2.MockELBV2
+}
+
+func (m *apiCallCountingELBV2) DescribeLoadBalancers(ctx context.Context, request *elbv2.DescribeLoadBalancersInput, optFns ...func(*elbv2.Options)) (*elbv2.DescribeLoadBalancersOutput, error) {
+	awsup.CountAPICall(ctx)
+	return m.MockELBV2.DescribeLoadBalancers(ctx, request, optFns...)
+}
+
+func (m *apiCallCountingELBV2) CreateTargetGroup(ctx context.Context, request *elbv2.CreateTargetGroupInput, optFns ...func(*elbv2.Options)) (*elbv2.CreateTargetGroupOutput, error) {
+	awsup.CountAPICall(ctx)
+	return m.MockELBV2.CreateTargetGroup(ctx, request, optFns...)
+}
+
+func (m *apiCallCountingELBV2) CreateListener(ctx context.Context, request *elbv2.CreateListenerInput, optFns ...func(*elbv2.Options)) (*elbv2.CreateListenerOutput, error) {
+	awsup.CountAPICall(ctx)
+	return m.MockELBV2.CreateListener(ctx, request, optFns...)
+}
+
+func TestReconcileTimingSink(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	cloud.MockELBV2 = &apiCallCountingELBV2{MockELBV2: &mockelbv2.MockELBV2{EC2: ec2Client}}
+
+	allTasks := buildNLBTasks()
+	nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+	tg1 := &TargetGroup{
+		Name:               s("tg1"),
+		Lifecycle:          fi.LifecycleSync,
+		VPC:                nlb1.VPC,
+		Tags:               map[string]string{"Name": "tg1"},
+		Protocol:           elbv2types.ProtocolEnumTcp,
+		Port:               fi.PtrTo(int32(443)),
+		Interval:           fi.PtrTo(int32(10)),
+		HealthyThreshold:   fi.PtrTo(int32(2)),
+		UnhealthyThreshold: fi.PtrTo(int32(2)),
+	}
+	allTasks["tg1"] = tg1
+	allTasks["listener1"] = &NetworkLoadBalancerListener{
+		Name:                s("listener1"),
+		Lifecycle:           fi.LifecycleSync,
+		NetworkLoadBalancer: nlb1,
+		Port:                443,
+		TargetGroup:         tg1,
+	}
+
+	var mutex sync.Mutex
+	timings := make(map[string]ReconcileTiming)
+	ReconcileTimingSink = func(timing ReconcileTiming) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		timings[timing.Task] = timing
+	}
+	defer func() { ReconcileTimingSink = nil }()
+
+	runNLBTasks(t, cloud, allTasks)
+
+	expected := map[string]string{
+		"NetworkLoadBalancer":         "nlb1",
+		"NetworkLoadBalancerListener": "listener1",
+		"TargetGroup":                 "tg1",
+	}
+	if len(timings) != len(expected) {
+		t.Fatalf("expected timings for %v, got %v", expected, timings)
+	}
+	for task, name := range expected {
+		timing := timings[task]
+		if timing.Name != name {
+			t.Errorf("expected %s timing for %q, got %q", task, name, timing.Name)
+		}
+		if timing.Duration <= 0 {
+			t.Errorf("expected a nonzero duration for %s, got %v", task, timing.Duration)
+		}
+		if timing.Err != nil {
+			t.Errorf("unexpected error for %s: %v", task, timing.Err)
+		}
+	}
+	if calls := timings["NetworkLoadBalancer"].APICalls; calls == 0 {
+		t.Errorf("expected the DescribeLoadBalancers calls of the NetworkLoadBalancer to be counted")
+	}
+	// The target group and the listener are only counted when RenderAWS uses the context of the task
+	if calls := timings["TargetGroup"].APICalls; calls == 0 {
+		t.Errorf("expected the CreateTargetGroup call of the TargetGroup to be counted")
+	}
+	if calls := timings["NetworkLoadBalancerListener"].APICalls; calls == 0 {
+		t.Errorf("expected the CreateListener call of the NetworkLoadBalancerListener to be counted")
+	}
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awstasks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+const (
+	// DefaultTargetHealthyTimeout is the default time to wait for a target group to report healthy targets
+	DefaultTargetHealthyTimeout = 5 * time.Minute
+	// DefaultTargetHealthyPollInterval is the default interval between target group health checks
+	DefaultTargetHealthyPollInterval = 15 * time.Second
+	// DefaultCertificateRotationSettleTime is the default time to wait after rotating the certificate of listeners
+	DefaultCertificateRotationSettleTime = 10 * time.Second
+	// DefaultThrottleRetryBaseDelay is the default delay before retrying a throttled ELBV2 request for the first time
+	DefaultThrottleRetryBaseDelay = time.Second
+	// DefaultThrottleRetryAttempts is the default maximum number of attempts of a throttled ELBV2 request
+	DefaultThrottleRetryAttempts = 5
+	// DefaultLoadBalancerActiveTimeout is the default time to wait for a load balancer to be active
+	DefaultLoadBalancerActiveTimeout = 10 * time.Minute
+)
+
+// maxThrottleRetryDelay caps the delay between the attempts of a throttled ELBV2 request.
+const maxThrottleRetryDelay = 30 * time.Second
+
+// ELBV2WaitConfig configures how long the ELBV2 tasks wait for load balancer resources to reach a desired state,
+// and how often they poll while waiting. Fields left unset use the defaults.
+type ELBV2WaitConfig struct {
+	// TargetHealthyTimeout is how long to wait for a target group to report healthy targets
+	TargetHealthyTimeout time.Duration
+	// TargetHealthyPollInterval is how often to check the health of a target group while waiting
+	TargetHealthyPollInterval time.Duration
+	// CertificateRotationSettleTime is how long to wait after rotating the certificate of listeners,
+	// for clients resuming TLS sessions established with the old certificate to fall back to full handshakes
+	CertificateRotationSettleTime time.Duration
+	// ThrottleRetryBaseDelay is the delay before retrying a throttled ELBV2 request for the first time.
+	// The delay doubles, with jitter, on every further retry.
+	ThrottleRetryBaseDelay time.Duration
+	// ThrottleRetryAttempts is the maximum number of attempts of a throttled ELBV2 request
+	ThrottleRetryAttempts int
+	// WaitForLoadBalancerActive makes listeners wait for their load balancer to be active before they are created,
+	// because CreateListener can fail on a load balancer that is still provisioning
+	WaitForLoadBalancerActive bool
+	// LoadBalancerActiveTimeout is how long to wait for a load balancer to be active
+	LoadBalancerActiveTimeout time.Duration
+}
+
+func (_ *ELBV2WaitConfig) GetDependencies(tasks map[string]fi.CloudupTask) []fi.CloudupTask {
+	return nil
+}
+
+// targetHealthyTimeout returns the configured TargetHealthyTimeout, or the default.
+func (c *ELBV2WaitConfig) targetHealthyTimeout() time.Duration {
+	if c == nil || c.TargetHealthyTimeout <= 0 {
+		return DefaultTargetHealthyTimeout
+	}
+	return c.TargetHealthyTimeout
+}
+
+// certificateRotationSettleTime returns the configured CertificateRotationSettleTime, or the default.
+func (c *ELBV2WaitConfig) certificateRotationSettleTime() time.Duration {
+	if c == nil || c.CertificateRotationSettleTime <= 0 {
+		return DefaultCertificateRotationSettleTime
+	}
+	return c.CertificateRotationSettleTime
+}
+
+// loadBalancerActiveTimeout returns the configured LoadBalancerActiveTimeout, or the default.
+func (c *ELBV2WaitConfig) loadBalancerActiveTimeout() time.Duration {
+	if c == nil || c.LoadBalancerActiveTimeout <= 0 {
+		return DefaultLoadBalancerActiveTimeout
+	}
+	return c.LoadBalancerActiveTimeout
+}
+
+// targetHealthyPollInterval returns the configured TargetHealthyPollInterval, or the default.
+// The interval is capped at the timeout, so short timeouts are still honored.
+func (c *ELBV2WaitConfig) targetHealthyPollInterval() time.Duration {
+	interval := DefaultTargetHealthyPollInterval
+	if c != nil && c.TargetHealthyPollInterval > 0 {
+		interval = c.TargetHealthyPollInterval
+	}
+	if timeout := c.targetHealthyTimeout(); interval > timeout {
+		interval = timeout
+	}
+	return interval
+}
+
+// waitForTargetGroupHealthy waits until all targets registered with the target group are healthy.
+func waitForTargetGroupHealthy(ctx context.Context, cloud awsup.AWSCloud, targetGroup *TargetGroup, config *ELBV2WaitConfig) error {
+	timeout := config.targetHealthyTimeout()
+	interval := config.targetHealthyPollInterval()
+
+	klog.V(2).Infof("Waiting up to %v for target group %q to become healthy", timeout, fi.ValueOf(targetGroup.Name))
+	waiter := elbv2.NewTargetInServiceWaiter(cloud.ELBV2(), func(o *elbv2.TargetInServiceWaiterOptions) {
+		o.MinDelay = interval
+		o.MaxDelay = interval
+	})
+	if err := waiter.Wait(ctx, &elbv2.DescribeTargetHealthInput{TargetGroupArn: targetGroup.ARN}, timeout); err != nil {
+		return fmt.Errorf("waiting for target group %q to become healthy: %w", fi.ValueOf(targetGroup.Name), err)
+	}
+	return nil
+}
+
+// throttleRetryBackoff returns the backoff between the attempts of a throttled ELBV2 request.
+func (c *ELBV2WaitConfig) throttleRetryBackoff() wait.Backoff {
+	backoff := wait.Backoff{
+		Duration: DefaultThrottleRetryBaseDelay,
+		Factor:   2,
+		Jitter:   0.5,
+		Steps:    DefaultThrottleRetryAttempts,
+		Cap:      maxThrottleRetryDelay,
+	}
+	if c != nil && c.ThrottleRetryBaseDelay > 0 {
+		backoff.Duration = c.ThrottleRetryBaseDelay
+	}
+	if c != nil && c.ThrottleRetryAttempts > 0 {
+		backoff.Steps = c.ThrottleRetryAttempts
+	}
+	return backoff
+}
+
+// isELBV2ThrottlingError returns true if the request was rejected because of the API rate limits.
+func isELBV2ThrottlingError(err error) bool {
+	switch awsup.AWSErrorCode(err) {
+	case "Throttling", "ThrottlingException", "RequestLimitExceeded":
+		return true
+	}
+	return false
+}
+
+// retryOnThrottling calls fn until it succeeds, fails with an error other than throttling,
+// or has been throttled for the configured number of attempts, waiting with exponential backoff between attempts.
+func retryOnThrottling(ctx context.Context, config *ELBV2WaitConfig, description string, fn func() error) error {
+	backoff := config.throttleRetryBackoff()
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !isELBV2ThrottlingError(err) || backoff.Steps <= 1 {
+			return err
+		}
+		delay := backoff.Step()
+		klog.Warningf("%s was throttled (attempt %d), retrying in %v: %v", description, attempt, delay, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
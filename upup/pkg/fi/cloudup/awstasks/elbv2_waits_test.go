@@ -0,0 +1,139 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awstasks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"k8s.io/kops/cloudmock/aws/mockelbv2"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+func TestELBV2WaitConfigDefaults(t *testing.T) {
+	grid := []struct {
+		name             string
+		config           *ELBV2WaitConfig
+		expectedTimeout  time.Duration
+		expectedInterval time.Duration
+		expectedSettle   time.Duration
+	}{
+		{
+			name:             "nil config",
+			expectedTimeout:  DefaultTargetHealthyTimeout,
+			expectedInterval: DefaultTargetHealthyPollInterval,
+			expectedSettle:   DefaultCertificateRotationSettleTime,
+		},
+		{
+			name:             "empty config",
+			config:           &ELBV2WaitConfig{},
+			expectedTimeout:  DefaultTargetHealthyTimeout,
+			expectedInterval: DefaultTargetHealthyPollInterval,
+			expectedSettle:   DefaultCertificateRotationSettleTime,
+		},
+		{
+			name:             "configured",
+			config:           &ELBV2WaitConfig{TargetHealthyTimeout: 20 * time.Minute, TargetHealthyPollInterval: time.Minute, CertificateRotationSettleTime: time.Minute},
+			expectedTimeout:  20 * time.Minute,
+			expectedInterval: time.Minute,
+			expectedSettle:   time.Minute,
+		},
+		{
+			name:             "interval capped at timeout",
+			config:           &ELBV2WaitConfig{TargetHealthyTimeout: 5 * time.Second},
+			expectedTimeout:  5 * time.Second,
+			expectedInterval: 5 * time.Second,
+			expectedSettle:   DefaultCertificateRotationSettleTime,
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			if actual := g.config.targetHealthyTimeout(); actual != g.expectedTimeout {
+				t.Errorf("unexpected timeout: expected %v, got %v", g.expectedTimeout, actual)
+			}
+			if actual := g.config.targetHealthyPollInterval(); actual != g.expectedInterval {
+				t.Errorf("unexpected poll interval: expected %v, got %v", g.expectedInterval, actual)
+			}
+			if actual := g.config.certificateRotationSettleTime(); actual != g.expectedSettle {
+				t.Errorf("unexpected certificate rotation settle time: expected %v, got %v", g.expectedSettle, actual)
+			}
+		})
+	}
+}
+
+func TestELBV2WaitConfigLoadBalancerActiveTimeout(t *testing.T) {
+	for _, g := range []struct {
+		config   *ELBV2WaitConfig
+		expected time.Duration
+	}{
+		{config: nil, expected: DefaultLoadBalancerActiveTimeout},
+		{config: &ELBV2WaitConfig{WaitForLoadBalancerActive: true}, expected: DefaultLoadBalancerActiveTimeout},
+		{config: &ELBV2WaitConfig{WaitForLoadBalancerActive: true, LoadBalancerActiveTimeout: time.Minute}, expected: time.Minute},
+	} {
+		if actual := g.config.loadBalancerActiveTimeout(); actual != g.expected {
+			t.Errorf("unexpected load balancer active timeout for %+v: expected %v, got %v", g.config, g.expected, actual)
+		}
+	}
+}
+
+func TestWaitForTargetGroupHealthyHonorsTimeout(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	c := &mockelbv2.MockELBV2{}
+	cloud.MockELBV2 = c
+
+	response, err := c.CreateTargetGroup(ctx, &elbv2.CreateTargetGroupInput{
+		Name:     s("tg1"),
+		Protocol: elbv2types.ProtocolEnumTcp,
+		Port:     fi.PtrTo(int32(443)),
+	})
+	if err != nil {
+		t.Fatalf("error creating target group: %v", err)
+	}
+	tg1 := &TargetGroup{Name: s("tg1"), ARN: response.TargetGroups[0].TargetGroupArn}
+
+	config := &ELBV2WaitConfig{
+		TargetHealthyTimeout:      200 * time.Millisecond,
+		TargetHealthyPollInterval: 10 * time.Millisecond,
+	}
+
+	// The target group has no healthy targets, so we should give up after the configured timeout
+	start := time.Now()
+	err = waitForTargetGroupHealthy(ctx, cloud, tg1, config)
+	elapsed := time.Since(start)
+	if err == nil {
+		t.Fatalf("expected timeout waiting for target group with no targets")
+	}
+	if elapsed > 5*time.Second {
+		t.Errorf("wait took %v, longer than the configured timeout of %v", elapsed, config.TargetHealthyTimeout)
+	}
+
+	if _, err := c.RegisterTargets(ctx, &elbv2.RegisterTargetsInput{
+		TargetGroupArn: tg1.ARN,
+		Targets:        []elbv2types.TargetDescription{{Id: s("i-12345678")}},
+	}); err != nil {
+		t.Fatalf("error registering targets: %v", err)
+	}
+	if err := waitForTargetGroupHealthy(ctx, cloud, tg1, config); err != nil {
+		t.Errorf("unexpected error waiting for healthy target group: %v", err)
+	}
+}
@@ -19,12 +19,14 @@ package awstasks
 import (
 	"context"
 	"fmt"
+	"maps"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	elb "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing"
 	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
 	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
@@ -57,25 +59,62 @@ type NetworkLoadBalancer struct {
 	// This enables migration from CLB -> NLB
 	CLBName *string
 
+	// NameTagOverride, if set, replaces the Name tag used to find the NLB, and is used as the base of its LoadBalancerName,
+	// for organisations that require a naming scheme of their own. The ownership tags are unchanged.
+	NameTagOverride string
+
 	DNSName      *string
 	HostedZoneId *string
 
 	SubnetMappings []*SubnetMapping
 	SecurityGroups []*SecurityGroup
 
+	// EnforceSecurityGroupInboundRulesOnPrivateLinkTraffic is whether the inbound rules of the SecurityGroups
+	// apply to traffic arriving through PrivateLink (VPC endpoints); on or off. AWS turns it on by default.
+	// It can only be set when the load balancer has security groups.
+	EnforceSecurityGroupInboundRulesOnPrivateLinkTraffic *string
+
 	Scheme elbv2types.LoadBalancerSchemeEnum
 
 	CrossZoneLoadBalancing *bool
 
+	// DeletionProtection prevents the load balancer from being deleted outside of kops;
+	// kops turns it off when it deletes the load balancer itself.
+	DeletionProtection *bool
+
+	// ClientRoutingPolicy is how the DNS name of the load balancer favours the zone of the client; one of
+	// any_availability_zone, availability_zone_affinity or partial_availability_zone_affinity.
+	// If not set, AWS uses any_availability_zone.
+	ClientRoutingPolicy *string
+
+	// IpAddressType is ipv4, dualstack or dualstack-without-public-ipv4 (internet-facing only);
+	// if not set, it is dualstack when all the subnets have IPv6 CIDRs, and ipv4 otherwise.
 	IpAddressType elbv2types.IpAddressType
 
 	Tags map[string]string
 
+	// PropagateTags copies the Tags of the load balancer onto its listeners and target groups, e.g. for cost allocation.
+	// Tags set on a listener or target group take precedence over the propagated tags.
+	PropagateTags bool
+
+	// SSLPolicy is the default security policy of the TLS listeners of the load balancer,
+	// used by the listeners that do not set their own SSLPolicy.
+	SSLPolicy string
+
+	// PruneListeners deletes the listeners of the load balancer that are owned by the cluster
+	// but have no NetworkLoadBalancerListener task, e.g. when a port was removed from the cluster spec.
+	// Listeners without the cluster tags, or tagged as protected, are never pruned.
+	PruneListeners bool
+
 	Type elbv2types.LoadBalancerTypeEnum
 
 	VPC       *VPC
 	AccessLog *NetworkLoadBalancerAccessLog
 
+	// ExportWithID, if set, exposes the DNS name of the NLB as the <ExportWithID>_nlb_dns_name output,
+	// e.g. for DNS delegation outside of kops. Only supported by terraform currently.
+	ExportWithID *string
+
 	// WellKnownServices indicates which services are supported by this resource.
 	// This field is internal and is not rendered to the cloud.
 	WellKnownServices []wellknownservices.WellKnownService
@@ -86,6 +125,20 @@ type NetworkLoadBalancer struct {
 	// After this is found/created, we store the ARN
 	loadBalancerArn string
 
+	// listeners caches the listeners of the load balancer once it is found or created,
+	// so that the listener tasks describe them once per run rather than once per listener.
+	listeners *elbv2ListenerCache
+
+	// certificates caches the validation of the certificates of the listeners, which often share a certificate,
+	// for the whole run, whether or not the load balancer exists yet.
+	certificates *elbv2CertificateCache
+
+	// sslPolicies caches the SSL policies available in the region, for the whole run.
+	sslPolicies *elbv2SSLPolicyCache
+
+	// subnetZones caches the availability zones of the subnets that don't set AvailabilityZone, keyed by subnet ID.
+	subnetZones map[string]string
+
 	// After this is found/created, we store the revision
 	revision string
 
@@ -165,6 +218,22 @@ func (e *NetworkLoadBalancer) getDNSName() *string {
 	return e.DNSName
 }
 
+// nameTag returns the value of the Name tag used to find the NLB.
+func (e *NetworkLoadBalancer) nameTag() string {
+	if e.NameTagOverride != "" {
+		return e.NameTagOverride
+	}
+	return fi.ValueOf(e.Name)
+}
+
+// loadBalancerBaseName returns the base of the LoadBalancerName of the NLB.
+func (e *NetworkLoadBalancer) loadBalancerBaseName() string {
+	if e.NameTagOverride != "" {
+		return e.NameTagOverride
+	}
+	return fi.ValueOf(e.LoadBalancerBaseName)
+}
+
 func (e *NetworkLoadBalancer) getHostedZoneId() *string {
 	return e.HostedZoneId
 }
@@ -178,14 +247,14 @@ func (e *NetworkLoadBalancer) Find(c *fi.CloudupContext) (*NetworkLoadBalancer,
 		return nil, err
 	}
 
-	latest := awsup.FindLatestELBV2ByNameTag(allLoadBalancers, fi.ValueOf(e.Name))
+	latest := awsup.FindLatestELBV2ByNameTag(allLoadBalancers, e.nameTag())
 	if err != nil {
 		return nil, err
 	}
 
 	// Stash deletions for later
 	for _, lb := range allLoadBalancers {
-		if lb.NameTag() != fi.ValueOf(e.Name) {
+		if lb.NameTag() != e.nameTag() {
 			continue
 		}
 		if latest != nil && latest.ARN() == lb.ARN() {
@@ -208,6 +277,8 @@ func (e *NetworkLoadBalancer) Find(c *fi.CloudupContext) (*NetworkLoadBalancer,
 	actual := &NetworkLoadBalancer{}
 	actual.Name = e.Name
 	actual.CLBName = e.CLBName
+	actual.NameTagOverride = e.NameTagOverride
+	actual.SSLPolicy = e.SSLPolicy
 	actual.DNSName = lb.DNSName
 	actual.HostedZoneId = lb.CanonicalHostedZoneId // CanonicalHostedZoneNameID
 	actual.Scheme = lb.Scheme
@@ -229,7 +300,7 @@ func (e *NetworkLoadBalancer) Find(c *fi.CloudupContext) (*NetworkLoadBalancer,
 
 	for _, az := range lb.AvailabilityZones {
 		sm := &SubnetMapping{
-			Subnet: &Subnet{ID: az.SubnetId},
+			Subnet: &Subnet{ID: az.SubnetId, AvailabilityZone: az.ZoneName},
 		}
 		for _, a := range az.LoadBalancerAddresses {
 			if a.PrivateIPv4Address != nil {
@@ -251,6 +322,7 @@ func (e *NetworkLoadBalancer) Find(c *fi.CloudupContext) (*NetworkLoadBalancer,
 	for _, sg := range lb.SecurityGroups {
 		actual.SecurityGroups = append(actual.SecurityGroups, &SecurityGroup{ID: aws.String(sg)})
 	}
+	actual.EnforceSecurityGroupInboundRulesOnPrivateLinkTraffic = lb.EnforceSecurityGroupInboundRulesOnPrivateLinkTraffic
 
 	{
 		lbAttributes, err := findNetworkLoadBalancerAttributes(ctx, cloud, loadBalancerArn)
@@ -270,6 +342,14 @@ func (e *NetworkLoadBalancer) Find(c *fi.CloudupContext) (*NetworkLoadBalancer,
 					return nil, err
 				}
 				actual.CrossZoneLoadBalancing = fi.PtrTo(b)
+			case "deletion_protection.enabled":
+				b, err := strconv.ParseBool(*value)
+				if err != nil {
+					return nil, err
+				}
+				actual.DeletionProtection = fi.PtrTo(b)
+			case "dns_record.client_routing_policy":
+				actual.ClientRoutingPolicy = value
 			case "access_logs.s3.enabled":
 				b, err := strconv.ParseBool(*value)
 				if err != nil {
@@ -319,10 +399,14 @@ func (e *NetworkLoadBalancer) Find(c *fi.CloudupContext) (*NetworkLoadBalancer,
 	actual.WellKnownServices = e.WellKnownServices
 	actual.Lifecycle = e.Lifecycle
 	actual.LoadBalancerBaseName = e.LoadBalancerBaseName
+	actual.PropagateTags = e.PropagateTags
+	actual.PruneListeners = e.PruneListeners
+	actual.ExportWithID = e.ExportWithID
 
 	// Store state for other tasks
 	e.loadBalancerArn = aws.ToString(lb.LoadBalancerArn)
 	actual.loadBalancerArn = e.loadBalancerArn
+	e.listeners = &elbv2ListenerCache{}
 	e.revision, _ = latest.GetTag(awsup.KopsResourceRevisionTag)
 	actual.revision = e.revision
 
@@ -363,7 +447,7 @@ func (e *NetworkLoadBalancer) FindAddresses(c *fi.CloudupContext) ([]string, err
 			return nil, err
 		}
 
-		lb := awsup.FindLatestELBV2ByNameTag(allLoadBalancers, fi.ValueOf(e.Name))
+		lb := awsup.FindLatestELBV2ByNameTag(allLoadBalancers, e.nameTag())
 
 		if lb != nil {
 			if fi.ValueOf(lb.LoadBalancer.DNSName) != "" {
@@ -390,22 +474,166 @@ func (e *NetworkLoadBalancer) FindAddresses(c *fi.CloudupContext) ([]string, err
 }
 
 func (e *NetworkLoadBalancer) Run(c *fi.CloudupContext) error {
+	return runTimed(c, "NetworkLoadBalancer", fi.ValueOf(e.Name), e.run)
+}
+
+func (e *NetworkLoadBalancer) run(c *fi.CloudupContext) error {
+	if t, ok := c.Target.(*terraform.TerraformTarget); ok && t.EmitImportBlocks {
+		if err := e.addTerraformImport(c, t); err != nil {
+			return err
+		}
+	}
 	return fi.CloudupDefaultDeltaRunMethod(e, c)
 }
 
+// addTerraformImport adds an import block for the existing load balancer, if any.
+// The terraform target does not call Find, so the ARN is also stored here for the imports of the listeners.
+func (e *NetworkLoadBalancer) addTerraformImport(c *fi.CloudupContext, t *terraform.TerraformTarget) error {
+	allLoadBalancers, err := awsup.ListELBV2LoadBalancers(c.Context(), awsup.GetCloud(c))
+	if err != nil {
+		return err
+	}
+	latest := awsup.FindLatestELBV2ByNameTag(allLoadBalancers, e.nameTag())
+	if latest == nil {
+		return nil
+	}
+	e.loadBalancerArn = latest.ARN()
+	t.AddImport("aws_lb", e.TerraformName(), e.loadBalancerArn)
+	return nil
+}
+
+// propagateTagsTo returns tags merged with the Tags of the load balancer if PropagateTags is set, otherwise tags unchanged.
+// The values already in tags win, so that the Name and ownership tags of a child are kept; keys in skip are not propagated.
+func (e *NetworkLoadBalancer) propagateTagsTo(tags map[string]string, skip map[string]string) map[string]string {
+	if e == nil || !e.PropagateTags || len(e.Tags) == 0 {
+		return tags
+	}
+	merged := make(map[string]string, len(e.Tags)+len(tags))
+	for k, v := range e.Tags {
+		if _, found := skip[k]; found {
+			continue
+		}
+		merged[k] = v
+	}
+	maps.Copy(merged, tags)
+	return merged
+}
+
 func (e *NetworkLoadBalancer) Normalize(c *fi.CloudupContext) error {
 	// We need to sort our arrays consistently, so we don't get spurious changes
 	sort.Stable(OrderSubnetMappingsByName(e.SubnetMappings))
 
-	e.IpAddressType = elbv2types.IpAddressTypeDualstack
+	// The listeners are normalized after the load balancer has run
+	if e.certificates == nil {
+		e.certificates = &elbv2CertificateCache{}
+	}
+	if e.sslPolicies == nil {
+		e.sslPolicies = &elbv2SSLPolicyCache{}
+	}
+
+	if e.NameTagOverride != "" && e.Tags["Name"] != e.NameTagOverride {
+		tags := make(map[string]string, len(e.Tags)+1)
+		for k, v := range e.Tags {
+			tags[k] = v
+		}
+		tags["Name"] = e.NameTagOverride
+		e.Tags = tags
+	}
+
+	if err := e.validateSubnetZones(c); err != nil {
+		return err
+	}
+
+	// Serve IPv6 by default when all the subnets of the load balancer have IPv6 CIDRs
+	ipv6Subnets := true
 	for _, subnet := range e.SubnetMappings {
 		for _, clusterSubnet := range c.T.Cluster.Spec.Networking.Subnets {
 			if clusterSubnet.Name == fi.ValueOf(subnet.Subnet.ShortName) && clusterSubnet.IPv6CIDR == "" {
-				e.IpAddressType = elbv2types.IpAddressTypeIpv4
+				ipv6Subnets = false
 			}
 		}
 	}
 
+	switch e.IpAddressType {
+	case "":
+		if ipv6Subnets {
+			e.IpAddressType = elbv2types.IpAddressTypeDualstack
+		} else {
+			e.IpAddressType = elbv2types.IpAddressTypeIpv4
+		}
+	case elbv2types.IpAddressTypeIpv4:
+	case elbv2types.IpAddressTypeDualstack, elbv2types.IpAddressTypeDualstackWithoutPublicIpv4:
+		if !ipv6Subnets {
+			return fmt.Errorf("network load balancer %q with IP address type %q requires IPv6 CIDRs on all its subnets", fi.ValueOf(e.Name), e.IpAddressType)
+		}
+		// Only internet-facing load balancers have public IPv4 addresses to go without
+		if e.IpAddressType == elbv2types.IpAddressTypeDualstackWithoutPublicIpv4 && e.Scheme == elbv2types.LoadBalancerSchemeEnumInternal {
+			return fmt.Errorf("network load balancer %q with IP address type %q must be internet-facing", fi.ValueOf(e.Name), e.IpAddressType)
+		}
+	default:
+		return fmt.Errorf("unsupported IP address type %q for network load balancer %q", e.IpAddressType, fi.ValueOf(e.Name))
+	}
+
+	return nil
+}
+
+// validateSubnetZones checks that the subnets of the load balancer are in distinct availability zones,
+// as AWS only allows one subnet per zone and otherwise rejects the creation with a confusing error.
+// The zones of subnets that don't set AvailabilityZone are described once, and cached.
+func (e *NetworkLoadBalancer) validateSubnetZones(c *fi.CloudupContext) error {
+	var unknown []string
+	for _, subnetMapping := range e.SubnetMappings {
+		subnet := subnetMapping.Subnet
+		if subnet == nil || subnet.AvailabilityZone != nil || subnet.ID == nil {
+			continue
+		}
+		if _, found := e.subnetZones[*subnet.ID]; !found {
+			unknown = append(unknown, *subnet.ID)
+		}
+	}
+	if len(unknown) != 0 {
+		response, err := awsup.GetCloud(c).EC2().DescribeSubnets(c.Context(), &ec2.DescribeSubnetsInput{
+			SubnetIds: unknown,
+		})
+		if err != nil {
+			return fmt.Errorf("describing the subnets of network load balancer %q: %w", fi.ValueOf(e.Name), err)
+		}
+		if e.subnetZones == nil {
+			e.subnetZones = make(map[string]string)
+		}
+		for _, subnet := range response.Subnets {
+			e.subnetZones[aws.ToString(subnet.SubnetId)] = aws.ToString(subnet.AvailabilityZone)
+		}
+	}
+
+	var zones []string
+	subnetsByZone := make(map[string][]string)
+	for _, subnetMapping := range e.SubnetMappings {
+		subnet := subnetMapping.Subnet
+		if subnet == nil {
+			continue
+		}
+		zone := fi.ValueOf(subnet.AvailabilityZone)
+		if zone == "" && subnet.ID != nil {
+			zone = e.subnetZones[*subnet.ID]
+		}
+		if zone == "" {
+			continue
+		}
+		if subnetsByZone[zone] == nil {
+			zones = append(zones, zone)
+		}
+		name := fi.ValueOf(subnet.Name)
+		if name == "" {
+			name = fi.ValueOf(subnet.ID)
+		}
+		subnetsByZone[zone] = append(subnetsByZone[zone], strconv.Quote(name))
+	}
+	for _, zone := range zones {
+		if subnets := subnetsByZone[zone]; len(subnets) > 1 {
+			return fmt.Errorf("network load balancer %q can only have one subnet per availability zone, but subnets %s are all in zone %q", fi.ValueOf(e.Name), strings.Join(subnets, ", "), zone)
+		}
+	}
 	return nil
 }
 
@@ -423,18 +651,19 @@ func (*NetworkLoadBalancer) CheckChanges(a, e, changes *NetworkLoadBalancer) err
 				return fi.RequiredField("CrossZoneLoadBalancing")
 			}
 		}
+	} else {
+		// AWS cannot change the scheme of an existing NLB, and kops does not replace it automatically,
+		// because the DNS name of the new NLB is different
+		if a.Scheme != "" && e.Scheme != "" && a.Scheme != e.Scheme {
+			return fmt.Errorf("the scheme of network load balancer %q cannot be changed from %q to %q; the load balancer must be recreated: delete it, e.g. with the AWS console, then update the cluster again to create it with the new scheme (its DNS name will change)", fi.ValueOf(e.Name), a.Scheme, e.Scheme)
+		}
 
-		if e.AccessLog != nil {
-			if e.AccessLog.Enabled == nil {
-				return fi.RequiredField("Accesslog.Enabled")
-			}
-			if *e.AccessLog.Enabled {
-				if e.AccessLog.S3BucketName == nil {
-					return fi.RequiredField("Accesslog.S3Bucket")
-				}
-			}
+		// The reverse of adding security groups to an NLB created without them (see Find):
+		// AWS does not allow removing all the security groups of an NLB that was created with them.
+		if len(a.SecurityGroups) > 0 && len(e.SecurityGroups) == 0 {
+			return fmt.Errorf("network load balancer %q was created with security groups, which cannot all be removed; at least one security group is required", fi.ValueOf(e.Name))
 		}
-	} else {
+
 		if len(changes.SubnetMappings) > 0 {
 			expectedSubnets := make(map[string]*string)
 			for _, s := range e.SubnetMappings {
@@ -452,17 +681,55 @@ func (*NetworkLoadBalancer) CheckChanges(a, e, changes *NetworkLoadBalancer) err
 				if !ok {
 					return fmt.Errorf("network load balancers do not support detaching subnets")
 				}
-				if fi.ValueOf(eIP) != fi.ValueOf(s.PrivateIPv4Address) || fi.ValueOf(eIP) != fi.ValueOf(s.AllocationID) {
+				// Only one of the two is set, depending on the scheme
+				if fi.ValueOf(eIP) != fi.ValueOf(s.PrivateIPv4Address) && fi.ValueOf(eIP) != fi.ValueOf(s.AllocationID) {
 					return fmt.Errorf("network load balancers do not support modifying address settings")
 				}
 			}
 		}
 	}
+
+	for _, subnetMapping := range e.SubnetMappings {
+		if err := subnetMapping.validate(e.Scheme); err != nil {
+			return fmt.Errorf("network load balancer %q: %w", fi.ValueOf(e.Name), err)
+		}
+	}
+
+	if e.ClientRoutingPolicy != nil {
+		switch fi.ValueOf(e.ClientRoutingPolicy) {
+		case "any_availability_zone", "availability_zone_affinity", "partial_availability_zone_affinity":
+		default:
+			return fmt.Errorf("unsupported client routing policy %q for network load balancer %q", fi.ValueOf(e.ClientRoutingPolicy), fi.ValueOf(e.Name))
+		}
+	}
+
+	if e.EnforceSecurityGroupInboundRulesOnPrivateLinkTraffic != nil {
+		switch elbv2types.EnforceSecurityGroupInboundRulesOnPrivateLinkTrafficEnum(fi.ValueOf(e.EnforceSecurityGroupInboundRulesOnPrivateLinkTraffic)) {
+		case elbv2types.EnforceSecurityGroupInboundRulesOnPrivateLinkTrafficEnumOn, elbv2types.EnforceSecurityGroupInboundRulesOnPrivateLinkTrafficEnumOff:
+		default:
+			return fmt.Errorf("unsupported value %q for EnforceSecurityGroupInboundRulesOnPrivateLinkTraffic of network load balancer %q, expected on or off", fi.ValueOf(e.EnforceSecurityGroupInboundRulesOnPrivateLinkTraffic), fi.ValueOf(e.Name))
+		}
+		if len(e.SecurityGroups) == 0 {
+			return fmt.Errorf("network load balancer %q can only set EnforceSecurityGroupInboundRulesOnPrivateLinkTraffic with security groups", fi.ValueOf(e.Name))
+		}
+	}
+
+	// Access logging can also be enabled on an existing NLB
+	if e.AccessLog != nil {
+		if e.AccessLog.Enabled == nil {
+			return fi.RequiredField("Accesslog.Enabled")
+		}
+		if *e.AccessLog.Enabled {
+			if fi.ValueOf(e.AccessLog.S3BucketName) == "" {
+				return fi.RequiredField("Accesslog.S3Bucket")
+			}
+		}
+	}
 	return nil
 }
 
 func (_ *NetworkLoadBalancer) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *NetworkLoadBalancer) error {
-	ctx := context.TODO()
+	ctx := t.Context()
 
 	loadBalancerArn := ""
 
@@ -480,9 +747,9 @@ func (_ *NetworkLoadBalancer) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *Ne
 	}
 
 	if a == nil {
-		loadBalancerName := fi.ValueOf(e.LoadBalancerBaseName)
+		loadBalancerName := e.loadBalancerBaseName()
 		if revision != "" {
-			s := fi.ValueOf(e.LoadBalancerBaseName) + "-" + revision
+			s := e.loadBalancerBaseName() + "-" + revision
 
 			// We always compute the hash and add it, lest we trick users into assuming that we never do this
 			opt := truncate.TruncateStringOptions{
@@ -529,9 +796,17 @@ func (_ *NetworkLoadBalancer) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *Ne
 			e.VPC = &VPC{ID: lb.VpcId}
 			loadBalancerArn = aws.ToString(lb.LoadBalancerArn)
 			e.loadBalancerArn = loadBalancerArn
+			e.listeners = &elbv2ListenerCache{}
 			e.revision = revision
 		}
 
+		// CreateLoadBalancer always enforces the inbound rules on PrivateLink traffic
+		if e.EnforceSecurityGroupInboundRulesOnPrivateLinkTraffic != nil {
+			if err := e.setSecurityGroups(ctx, t.Cloud, loadBalancerArn); err != nil {
+				return err
+			}
+		}
+
 		if e.waitForLoadBalancerReady {
 			klog.Infof("Waiting for load balancer %q to be created...", loadBalancerName)
 			request := &elbv2.DescribeLoadBalancersInput{
@@ -595,17 +870,9 @@ func (_ *NetworkLoadBalancer) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *Ne
 			}
 		}
 
-		if changes.SecurityGroups != nil {
-			request := &elbv2.SetSecurityGroupsInput{
-				LoadBalancerArn: &loadBalancerArn,
-			}
-			for _, sg := range e.SecurityGroups {
-				request.SecurityGroups = append(request.SecurityGroups, aws.ToString(sg.ID))
-			}
-
-			klog.V(2).Infof("Updating Load Balancer Security Groups")
-			if _, err := t.Cloud.ELBV2().SetSecurityGroups(ctx, request); err != nil {
-				return fmt.Errorf("Error updating security groups on Load Balancer: %v", err)
+		if changes.SecurityGroups != nil || changes.EnforceSecurityGroupInboundRulesOnPrivateLinkTraffic != nil {
+			if err := e.setSecurityGroups(ctx, t.Cloud, loadBalancerArn); err != nil {
+				return err
 			}
 		}
 
@@ -625,6 +892,25 @@ func (_ *NetworkLoadBalancer) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *Ne
 	return nil
 }
 
+// setSecurityGroups sets the security groups of the load balancer, and whether they apply to PrivateLink traffic.
+func (e *NetworkLoadBalancer) setSecurityGroups(ctx context.Context, cloud awsup.AWSCloud, loadBalancerArn string) error {
+	request := &elbv2.SetSecurityGroupsInput{
+		LoadBalancerArn: &loadBalancerArn,
+	}
+	for _, sg := range e.SecurityGroups {
+		request.SecurityGroups = append(request.SecurityGroups, aws.ToString(sg.ID))
+	}
+	if e.EnforceSecurityGroupInboundRulesOnPrivateLinkTraffic != nil {
+		request.EnforceSecurityGroupInboundRulesOnPrivateLinkTraffic = elbv2types.EnforceSecurityGroupInboundRulesOnPrivateLinkTrafficEnum(fi.ValueOf(e.EnforceSecurityGroupInboundRulesOnPrivateLinkTraffic))
+	}
+
+	klog.V(2).Infof("Updating Load Balancer Security Groups")
+	if _, err := cloud.ELBV2().SetSecurityGroups(ctx, request); err != nil {
+		return fmt.Errorf("Error updating security groups on Load Balancer: %v", err)
+	}
+	return nil
+}
+
 type terraformNetworkLoadBalancer struct {
 	Name                   string                                      `cty:"name"`
 	Internal               bool                                        `cty:"internal"`
@@ -633,8 +919,12 @@ type terraformNetworkLoadBalancer struct {
 	SecurityGroups         []*terraformWriter.Literal                  `cty:"security_groups"`
 	SubnetMappings         []terraformNetworkLoadBalancerSubnetMapping `cty:"subnet_mapping"`
 	CrossZoneLoadBalancing bool                                        `cty:"enable_cross_zone_load_balancing"`
+	DeletionProtection     *bool                                       `cty:"enable_deletion_protection"`
+	ClientRoutingPolicy    *string                                     `cty:"dns_record_client_routing_policy"`
 	AccessLog              *terraformNetworkLoadBalancerAccessLog      `cty:"access_logs"`
 
+	EnforceSecurityGroupInboundRulesOnPrivateLinkTraffic *string `cty:"enforce_security_group_inbound_rules_on_private_link_traffic"`
+
 	Tags map[string]string `cty:"tags"`
 }
 
@@ -646,13 +936,16 @@ type terraformNetworkLoadBalancerSubnetMapping struct {
 
 func (_ *NetworkLoadBalancer) RenderTerraform(t *terraform.TerraformTarget, a, e, changes *NetworkLoadBalancer) error {
 	nlbTF := &terraformNetworkLoadBalancer{
-		Name:                   *e.LoadBalancerBaseName,
+		Name:                   e.loadBalancerBaseName(),
 		Internal:               e.Scheme == elbv2types.LoadBalancerSchemeEnumInternal,
 		Type:                   elbv2types.LoadBalancerTypeEnumNetwork,
 		Tags:                   e.Tags,
 		CrossZoneLoadBalancing: fi.ValueOf(e.CrossZoneLoadBalancing),
+		DeletionProtection:     e.DeletionProtection,
+		ClientRoutingPolicy:    e.ClientRoutingPolicy,
+		EnforceSecurityGroupInboundRulesOnPrivateLinkTraffic: e.EnforceSecurityGroupInboundRulesOnPrivateLinkTraffic,
 	}
-	if e.IpAddressType == elbv2types.IpAddressTypeDualstack {
+	if e.IpAddressType != "" && e.IpAddressType != elbv2types.IpAddressTypeIpv4 {
 		nlbTF.IPAddressType = &e.IpAddressType
 	}
 
@@ -677,6 +970,12 @@ func (_ *NetworkLoadBalancer) RenderTerraform(t *terraform.TerraformTarget, a, e
 		}
 	}
 
+	if fi.ValueOf(e.ExportWithID) != "" {
+		if err := t.AddOutputVariable(*e.ExportWithID+"_nlb_dns_name", e.TerraformLink("dns_name")); err != nil {
+			return err
+		}
+	}
+
 	err := t.RenderResource("aws_lb", e.TerraformName(), nlbTF)
 	if err != nil {
 		return err
@@ -698,12 +997,21 @@ func (e *NetworkLoadBalancer) TerraformLink(params ...string) *terraformWriter.L
 	return terraformWriter.LiteralProperty("aws_lb", e.TerraformName(), prop)
 }
 
-// FindDeletions schedules deletion of the corresponding legacy classic load balancer when it no longer has targets.
+// FindDeletions schedules deletion of the corresponding legacy classic load balancer when it no longer has targets,
+// and of the orphaned listeners if PruneListeners is set.
 func (e *NetworkLoadBalancer) FindDeletions(context *fi.CloudupContext) ([]fi.CloudupDeletion, error) {
 	var deletions []fi.CloudupDeletion
 
 	deletions = append(deletions, e.deletions...)
 
+	if e.PruneListeners && e.loadBalancerArn != "" {
+		orphaned, err := e.findOrphanedListeners(context)
+		if err != nil {
+			return nil, err
+		}
+		deletions = append(deletions, orphaned...)
+	}
+
 	if e.CLBName != nil {
 		cloud := context.T.Cloud.(awsup.AWSCloud)
 
@@ -721,6 +1029,102 @@ func (e *NetworkLoadBalancer) FindDeletions(context *fi.CloudupContext) ([]fi.Cl
 	return deletions, nil
 }
 
+// findOrphanedListeners returns deletions for the listeners of the load balancer that are owned by the cluster
+// but do not belong to any NetworkLoadBalancerListener task of this load balancer.
+func (e *NetworkLoadBalancer) findOrphanedListeners(c *fi.CloudupContext) ([]fi.CloudupDeletion, error) {
+	ctx := c.Context()
+	cloud := awsup.GetCloud(c)
+
+	// The temporary port of a listener is in use while the listener is swapped
+	desiredPorts := make(map[int32]bool)
+	adoptedARNs := make(map[string]bool)
+	for _, task := range c.AllTasks() {
+		listener, ok := task.(*NetworkLoadBalancerListener)
+		if !ok || listener.NetworkLoadBalancer != e {
+			continue
+		}
+		desiredPorts[int32(listener.Port)] = true
+		if listener.TemporaryPort != 0 {
+			desiredPorts[int32(listener.TemporaryPort)] = true
+		}
+		if listener.AdoptListenerARN != "" {
+			adoptedARNs[listener.AdoptListenerARN] = true
+		}
+	}
+
+	listeners, err := awsup.ListELBV2ListenersWithTags(ctx, cloud, e.loadBalancerArn)
+	if err != nil {
+		return nil, err
+	}
+
+	var deletions []fi.CloudupDeletion
+	for _, listener := range listeners {
+		if desiredPorts[aws.ToInt32(listener.Listener.Port)] || adoptedARNs[listener.ARN()] {
+			continue
+		}
+		if !awsup.MatchesElbV2Tags(cloud.Tags(), listener.Tags) {
+			klog.V(2).Infof("not pruning listener %q of load balancer %q, which is not owned by the cluster", listener.ARN(), e.loadBalancerArn)
+			continue
+		}
+		if _, found := awsup.FindELBV2Tag(listener.Tags, awsup.KopsProtectedTag); found {
+			klog.Warningf("not pruning protected listener %q of load balancer %q; delete it manually", listener.ARN(), e.loadBalancerArn)
+			continue
+		}
+		deletions = append(deletions, &deleteNLBListener{
+			listener:  listener.Listener,
+			listeners: e.listeners,
+		})
+	}
+	return deletions, nil
+}
+
+// deleteNLBListener tracks a listener of a NLB that is no longer in the cluster spec, that we're going to delete.
+// It implements fi.CloudupDeletion
+type deleteNLBListener struct {
+	listener elbv2types.Listener
+
+	// listeners is the listener cache of the load balancer, invalidated once the listener is deleted
+	listeners *elbv2ListenerCache
+}
+
+var _ fi.CloudupDeletion = &deleteNLBListener{}
+
+func (d *deleteNLBListener) Delete(t fi.CloudupTarget) error {
+	awsTarget, ok := t.(*awsup.AWSAPITarget)
+	if !ok {
+		return fmt.Errorf("unexpected target type for deletion: %T", t)
+	}
+
+	klog.V(2).Infof("deleting listener %q (port %d)", d.Item(), aws.ToInt32(d.listener.Port))
+	if _, err := awsTarget.Cloud.ELBV2().DeleteListener(awsTarget.Context(), &elbv2.DeleteListenerInput{
+		ListenerArn: d.listener.ListenerArn,
+	}); err != nil {
+		return fmt.Errorf("deleting listener %q (port %d, protocol %s) of load balancer %q: %w", d.Item(), aws.ToInt32(d.listener.Port), d.listener.Protocol, aws.ToString(d.listener.LoadBalancerArn), err)
+	}
+	d.listeners.invalidate()
+
+	return nil
+}
+
+// String returns a string representation of the task
+func (d *deleteNLBListener) String() string {
+	return d.TaskName() + "-" + d.Item()
+}
+
+// TaskName returns the task name
+func (d *deleteNLBListener) TaskName() string {
+	return "NetworkLoadBalancerListener"
+}
+
+// Item returns the listener ARN
+func (d *deleteNLBListener) Item() string {
+	return aws.ToString(d.listener.ListenerArn)
+}
+
+func (d *deleteNLBListener) DeferDeletion() bool {
+	return true
+}
+
 type deleteClassicLoadBalancer struct {
 	// LoadBalancerName is the name in ELB, possibly different from our name
 	// (ELB is restricted as to names, so we have limited choices!)
@@ -779,6 +1183,20 @@ func (d *deleteNLB) Delete(t fi.CloudupTarget) error {
 	}
 
 	arn := d.obj.ARN()
+
+	protected, err := awsup.FindProtectedELBV2Listeners(ctx, awsTarget.Cloud, arn)
+	if err != nil {
+		return err
+	}
+	if len(protected) != 0 {
+		return fmt.Errorf("refusing to delete load balancer %q with protected listeners %v; delete the listeners manually", arn, protected)
+	}
+
+	// A previous revision keeps the deletion protection of the current one
+	if err := awsup.DisableELBV2DeletionProtection(ctx, awsTarget.Cloud, arn); err != nil {
+		return err
+	}
+
 	klog.V(2).Infof("deleting load balancer %q", arn)
 	if _, err := awsTarget.Cloud.ELBV2().DeleteLoadBalancer(ctx, &elbv2.DeleteLoadBalancerInput{
 		LoadBalancerArn: &arn,
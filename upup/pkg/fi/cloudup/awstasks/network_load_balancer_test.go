@@ -0,0 +1,1178 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awstasks
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"k8s.io/kops/cloudmock/aws/mockec2"
+	"k8s.io/kops/cloudmock/aws/mockelbv2"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+func TestNetworkLoadBalancerCheckChangesSecurityGroups(t *testing.T) {
+	sg1 := &SecurityGroup{Name: s("sg1"), ID: s("sg-1")}
+	sg2 := &SecurityGroup{Name: s("sg2"), ID: s("sg-2")}
+
+	grid := []struct {
+		name        string
+		actual      []*SecurityGroup
+		expected    []*SecurityGroup
+		expectError bool
+	}{
+		{name: "without security groups"},
+		{name: "unchanged", actual: []*SecurityGroup{sg1}, expected: []*SecurityGroup{sg1}},
+		{name: "replaced", actual: []*SecurityGroup{sg1}, expected: []*SecurityGroup{sg2}},
+		{name: "one removed", actual: []*SecurityGroup{sg1, sg2}, expected: []*SecurityGroup{sg2}},
+		{name: "all removed", actual: []*SecurityGroup{sg1, sg2}, expectError: true},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			a := &NetworkLoadBalancer{Name: s("nlb"), SecurityGroups: g.actual}
+			e := &NetworkLoadBalancer{Name: s("nlb"), SecurityGroups: g.expected}
+			err := (&NetworkLoadBalancer{}).CheckChanges(a, e, &NetworkLoadBalancer{})
+			if g.expectError && err == nil {
+				t.Errorf("expected error, got none")
+			}
+			if !g.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestNetworkLoadBalancerCheckChangesPrivateLinkEnforcement(t *testing.T) {
+	sg1 := &SecurityGroup{Name: s("sg1"), ID: s("sg-1")}
+
+	grid := []struct {
+		name           string
+		securityGroups []*SecurityGroup
+		enforce        *string
+		expectError    bool
+	}{
+		{name: "unset"},
+		{name: "on", securityGroups: []*SecurityGroup{sg1}, enforce: s("on")},
+		{name: "off", securityGroups: []*SecurityGroup{sg1}, enforce: s("off")},
+		{name: "invalid", securityGroups: []*SecurityGroup{sg1}, enforce: s("disabled"), expectError: true},
+		{name: "without security groups", enforce: s("off"), expectError: true},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			a := &NetworkLoadBalancer{Name: s("nlb"), SecurityGroups: g.securityGroups}
+			e := &NetworkLoadBalancer{Name: s("nlb"), SecurityGroups: g.securityGroups, EnforceSecurityGroupInboundRulesOnPrivateLinkTraffic: g.enforce}
+			err := (&NetworkLoadBalancer{}).CheckChanges(a, e, &NetworkLoadBalancer{})
+			if g.expectError && err == nil {
+				t.Errorf("expected error, got none")
+			}
+			if !g.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestNetworkLoadBalancerCheckChangesScheme(t *testing.T) {
+	internal := elbv2types.LoadBalancerSchemeEnumInternal
+	internetFacing := elbv2types.LoadBalancerSchemeEnumInternetFacing
+
+	grid := []struct {
+		name        string
+		actual      elbv2types.LoadBalancerSchemeEnum
+		expected    elbv2types.LoadBalancerSchemeEnum
+		expectError bool
+	}{
+		{name: "unchanged internal", actual: internal, expected: internal},
+		{name: "unchanged internet-facing", actual: internetFacing, expected: internetFacing},
+		{name: "internal to internet-facing", actual: internal, expected: internetFacing, expectError: true},
+		{name: "internet-facing to internal", actual: internetFacing, expected: internal, expectError: true},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			a := &NetworkLoadBalancer{Name: s("nlb"), Scheme: g.actual}
+			e := &NetworkLoadBalancer{Name: s("nlb"), Scheme: g.expected}
+			changes := &NetworkLoadBalancer{}
+			if g.actual != g.expected {
+				changes.Scheme = g.expected
+			}
+			err := (&NetworkLoadBalancer{}).CheckChanges(a, e, changes)
+			if g.expectError {
+				if err == nil {
+					t.Fatalf("expected error, got none")
+				}
+				if !strings.Contains(err.Error(), "must be recreated") {
+					t.Errorf("expected the error to explain that the load balancer must be recreated, got %v", err)
+				}
+			}
+			if !g.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestNetworkLoadBalancerCheckChangesAccessLog(t *testing.T) {
+	grid := []struct {
+		name        string
+		accessLog   *NetworkLoadBalancerAccessLog
+		expectError bool
+	}{
+		{name: "unset"},
+		{name: "disabled", accessLog: &NetworkLoadBalancerAccessLog{Enabled: fi.PtrTo(false)}},
+		{name: "enabled", accessLog: &NetworkLoadBalancerAccessLog{Enabled: fi.PtrTo(true), S3BucketName: s("logs"), S3BucketPrefix: s("nlb")}},
+		{name: "enabled without bucket", accessLog: &NetworkLoadBalancerAccessLog{Enabled: fi.PtrTo(true)}, expectError: true},
+		{name: "enabled with empty bucket", accessLog: &NetworkLoadBalancerAccessLog{Enabled: fi.PtrTo(true), S3BucketName: s("")}, expectError: true},
+		{name: "missing enabled", accessLog: &NetworkLoadBalancerAccessLog{S3BucketName: s("logs")}, expectError: true},
+	}
+	for _, g := range grid {
+		for _, exists := range []bool{false, true} {
+			name := g.name + " on create"
+			if exists {
+				name = g.name + " on update"
+			}
+			t.Run(name, func(t *testing.T) {
+				e := &NetworkLoadBalancer{
+					Name:           s("nlb"),
+					SubnetMappings: []*SubnetMapping{{Subnet: &Subnet{Name: s("subnet1")}}},
+					AccessLog:      g.accessLog,
+				}
+				var a *NetworkLoadBalancer
+				if exists {
+					a = &NetworkLoadBalancer{Name: s("nlb")}
+				}
+				err := (&NetworkLoadBalancer{}).CheckChanges(a, e, &NetworkLoadBalancer{AccessLog: g.accessLog})
+				if g.expectError && err == nil {
+					t.Errorf("expected error, got none")
+				}
+				if !g.expectError && err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			})
+		}
+	}
+}
+
+func TestNetworkLoadBalancerDeletionProtection(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = c
+
+	deletionProtection := func(t *testing.T, loadBalancerArn string) string {
+		response, err := c.DescribeLoadBalancerAttributes(ctx, &elbv2.DescribeLoadBalancerAttributesInput{LoadBalancerArn: aws.String(loadBalancerArn)})
+		if err != nil {
+			t.Fatalf("error describing load balancer attributes: %v", err)
+		}
+		for _, attribute := range response.Attributes {
+			if aws.ToString(attribute.Key) == "deletion_protection.enabled" {
+				return aws.ToString(attribute.Value)
+			}
+		}
+		return ""
+	}
+
+	var loadBalancerArn string
+	for _, enabled := range []bool{true, false, true} {
+		allTasks := buildNLBTasks()
+		allTasks["nlb1"].(*NetworkLoadBalancer).DeletionProtection = fi.PtrTo(enabled)
+		runNLBTasks(t, cloud, allTasks)
+
+		arn := allTasks["nlb1"].(*NetworkLoadBalancer).loadBalancerArn
+		if loadBalancerArn != "" && arn != loadBalancerArn {
+			t.Fatalf("expected deletion protection to be changed in place, load balancer changed from %q to %q", loadBalancerArn, arn)
+		}
+		loadBalancerArn = arn
+		if actual := deletionProtection(t, loadBalancerArn); actual != strconv.FormatBool(enabled) {
+			t.Errorf("expected deletion protection %v, got %q", enabled, actual)
+		}
+
+		allTasks = buildNLBTasks()
+		allTasks["nlb1"].(*NetworkLoadBalancer).DeletionProtection = fi.PtrTo(enabled)
+		checkNLBNoChanges(t, ctx, cloud, allTasks)
+	}
+
+	// kops deletes the previous revisions of the load balancer even if they are protected
+	loadBalancers, err := awsup.ListELBV2LoadBalancers(ctx, cloud)
+	if err != nil {
+		t.Fatalf("error listing load balancers: %v", err)
+	}
+	if len(loadBalancers) != 1 {
+		t.Fatalf("expected 1 load balancer, got %d", len(loadBalancers))
+	}
+	if err := buildDeleteNLB(loadBalancers[0]).Delete(&awsup.AWSAPITarget{Cloud: cloud}); err != nil {
+		t.Fatalf("unexpected error deleting load balancer: %v", err)
+	}
+	if len(c.LoadBalancers) != 0 {
+		t.Errorf("expected load balancer to be deleted, found %d", len(c.LoadBalancers))
+	}
+}
+
+func TestNetworkLoadBalancerPropagateTags(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = c
+	clusterTags := cloud.Tags()
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func() map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+		nlb1.PropagateTags = true
+		nlb1.Tags = map[string]string{"Name": "nlb1", "cost-center": "1", "team": "a"}
+		for k, v := range clusterTags {
+			nlb1.Tags[k] = v
+		}
+
+		tg1 := &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                nlb1.VPC,
+			Tags:               map[string]string{"Name": "tg1", "team": "b"},
+			Protocol:           elbv2types.ProtocolEnumTcp,
+			Port:               fi.PtrTo(int32(443)),
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+		}
+		tg1.CreateNewRevisionsWith(nlb1)
+		allTasks["tg1"] = tg1
+		allTasks["listener1"] = &NetworkLoadBalancerListener{
+			Name:                s("listener1"),
+			Lifecycle:           fi.LifecycleSync,
+			NetworkLoadBalancer: nlb1,
+			Port:                443,
+			TargetGroup:         tg1,
+		}
+		return allTasks
+	}
+
+	resourceTags := func(t *testing.T, arn string) map[string]string {
+		response, err := c.DescribeTags(ctx, &elbv2.DescribeTagsInput{ResourceArns: []string{arn}})
+		if err != nil {
+			t.Fatalf("error describing tags: %v", err)
+		}
+		tags := make(map[string]string)
+		for _, tagDescription := range response.TagDescriptions {
+			for _, tag := range tagDescription.Tags {
+				tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+			}
+		}
+		return tags
+	}
+
+	allTasks := buildTasks()
+	runNLBTasks(t, cloud, allTasks)
+
+	tgTags := resourceTags(t, fi.ValueOf(allTasks["tg1"].(*TargetGroup).ARN))
+	if tgTags["Name"] != "tg1" || tgTags["team"] != "b" || tgTags["cost-center"] != "1" {
+		t.Errorf("expected the target group to keep its own tags and gain cost-center=1, got %v", tgTags)
+	}
+	listenerTags := resourceTags(t, allTasks["listener1"].(*NetworkLoadBalancerListener).listenerArn)
+	if listenerTags["Name"] != "nlb1" || listenerTags["team"] != "a" || listenerTags["cost-center"] != "1" {
+		t.Errorf("expected the listener to have the tags of the load balancer, got %v", listenerTags)
+	}
+	for k, v := range clusterTags {
+		if tgTags[k] != v || listenerTags[k] != v {
+			t.Errorf("expected cluster tag %s=%s to be kept, got %v and %v", k, v, tgTags, listenerTags)
+		}
+	}
+
+	checkNLBNoChanges(t, ctx, cloud, buildTasks())
+}
+
+func TestNetworkLoadBalancerIPAddressType(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(ipAddressType elbv2types.IpAddressType) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+		nlb1.Scheme = elbv2types.LoadBalancerSchemeEnumInternetFacing
+		nlb1.IpAddressType = ipAddressType
+		return allTasks
+	}
+
+	var loadBalancerArn string
+	for _, ipAddressType := range []elbv2types.IpAddressType{
+		elbv2types.IpAddressTypeIpv4,
+		elbv2types.IpAddressTypeDualstack,
+		elbv2types.IpAddressTypeDualstackWithoutPublicIpv4,
+	} {
+		allTasks := buildTasks(ipAddressType)
+		runNLBTasks(t, cloud, allTasks)
+
+		arn := allTasks["nlb1"].(*NetworkLoadBalancer).loadBalancerArn
+		if loadBalancerArn != "" && arn != loadBalancerArn {
+			t.Fatalf("expected IP address type to be changed in place, load balancer changed from %q to %q", loadBalancerArn, arn)
+		}
+		loadBalancerArn = arn
+		response, err := c.DescribeLoadBalancers(ctx, &elbv2.DescribeLoadBalancersInput{LoadBalancerArns: []string{loadBalancerArn}})
+		if err != nil {
+			t.Fatalf("error describing load balancer: %v", err)
+		}
+		if actual := response.LoadBalancers[0].IpAddressType; actual != ipAddressType {
+			t.Errorf("expected IP address type %q, got %q", ipAddressType, actual)
+		}
+
+		checkNLBNoChanges(t, ctx, cloud, buildTasks(ipAddressType))
+	}
+}
+
+func TestNetworkLoadBalancerNormalizeIPAddressType(t *testing.T) {
+	ctx := context.TODO()
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+
+	cluster := &kops.Cluster{}
+	cluster.Spec.Networking.Subnets = []kops.ClusterSubnetSpec{
+		{Name: "dualstack", IPv6CIDR: "2001:db8::/64"},
+		{Name: "ipv4"},
+	}
+
+	grid := []struct {
+		name          string
+		subnet        string
+		scheme        elbv2types.LoadBalancerSchemeEnum
+		ipAddressType elbv2types.IpAddressType
+		expected      elbv2types.IpAddressType
+		expectError   bool
+	}{
+		{name: "default with ipv6 subnets", subnet: "dualstack", expected: elbv2types.IpAddressTypeDualstack},
+		{name: "default with ipv4 subnets", subnet: "ipv4", expected: elbv2types.IpAddressTypeIpv4},
+		{name: "ipv4 with ipv6 subnets", subnet: "dualstack", ipAddressType: elbv2types.IpAddressTypeIpv4, expected: elbv2types.IpAddressTypeIpv4},
+		{name: "dualstack with ipv4 subnets", subnet: "ipv4", ipAddressType: elbv2types.IpAddressTypeDualstack, expectError: true},
+		{name: "without public ipv4", subnet: "dualstack", scheme: elbv2types.LoadBalancerSchemeEnumInternetFacing, ipAddressType: elbv2types.IpAddressTypeDualstackWithoutPublicIpv4, expected: elbv2types.IpAddressTypeDualstackWithoutPublicIpv4},
+		{name: "internal without public ipv4", subnet: "dualstack", scheme: elbv2types.LoadBalancerSchemeEnumInternal, ipAddressType: elbv2types.IpAddressTypeDualstackWithoutPublicIpv4, expectError: true},
+		{name: "unsupported", subnet: "dualstack", ipAddressType: "ipv6", expectError: true},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			c, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, &awsup.AWSAPITarget{Cloud: cloud}, cluster, cloud, nil, nil, nil, nil)
+			if err != nil {
+				t.Fatalf("error building context: %v", err)
+			}
+			nlb := &NetworkLoadBalancer{
+				Name:           s("nlb"),
+				Scheme:         g.scheme,
+				IpAddressType:  g.ipAddressType,
+				SubnetMappings: []*SubnetMapping{{Subnet: &Subnet{Name: s(g.subnet + ".example.com"), ShortName: s(g.subnet)}}},
+			}
+			err = nlb.Normalize(c)
+			if g.expectError {
+				if err == nil {
+					t.Errorf("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if nlb.IpAddressType != g.expected {
+				t.Errorf("expected IP address type %q, got %q", g.expected, nlb.IpAddressType)
+			}
+		})
+	}
+}
+
+// describeSubnetsCountingEC2 counts the DescribeSubnets calls
+type describeSubnetsCountingEC2 struct {
+	*mockec2.MockEC2
+
+	describeSubnetsCalls int
+}
+
+func (m *describeSubnetsCountingEC2) DescribeSubnets(ctx context.Context, request *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
+	m.describeSubnetsCalls++
+	return m.MockEC2.DescribeSubnets(ctx, request, optFns...)
+}
+
+func TestNetworkLoadBalancerNormalizeSubnetZones(t *testing.T) {
+	ctx := context.TODO()
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &describeSubnetsCountingEC2{MockEC2: &mockec2.MockEC2{}}
+	cloud.MockEC2 = ec2Client
+
+	// Shared subnets only have an ID, so their zones are described
+	for _, subnet := range []struct{ id, zone string }{
+		{"subnet-shared-a", "us-east-1a"},
+		{"subnet-shared-a2", "us-east-1a"},
+		{"subnet-shared-b", "us-east-1b"},
+	} {
+		if _, err := ec2Client.CreateSubnetWithId(&ec2.CreateSubnetInput{
+			VpcId:            s("vpc-1"),
+			CidrBlock:        s("10.0.0.0/24"),
+			AvailabilityZone: s(subnet.zone),
+		}, subnet.id); err != nil {
+			t.Fatalf("error creating subnet: %v", err)
+		}
+	}
+
+	grid := []struct {
+		name          string
+		subnets       []*Subnet
+		expectedError string
+	}{
+		{
+			name: "distinct zones",
+			subnets: []*Subnet{
+				{Name: s("us-east-1a.example.com"), AvailabilityZone: s("us-east-1a")},
+				{Name: s("us-east-1b.example.com"), AvailabilityZone: s("us-east-1b")},
+			},
+		},
+		{
+			name: "duplicate zone",
+			subnets: []*Subnet{
+				{Name: s("us-east-1a.example.com"), AvailabilityZone: s("us-east-1a")},
+				{Name: s("utility-us-east-1a.example.com"), AvailabilityZone: s("us-east-1a")},
+				{Name: s("us-east-1b.example.com"), AvailabilityZone: s("us-east-1b")},
+			},
+			expectedError: `subnets "us-east-1a.example.com", "utility-us-east-1a.example.com" are all in zone "us-east-1a"`,
+		},
+		{
+			name: "shared subnets in distinct zones",
+			subnets: []*Subnet{
+				{ID: s("subnet-shared-a")},
+				{ID: s("subnet-shared-b")},
+			},
+		},
+		{
+			name: "shared subnet in the zone of another subnet",
+			subnets: []*Subnet{
+				{Name: s("us-east-1a.example.com"), AvailabilityZone: s("us-east-1a")},
+				{ID: s("subnet-shared-a2")},
+			},
+			expectedError: `subnets "subnet-shared-a2", "us-east-1a.example.com" are all in zone "us-east-1a"`,
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			c, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, &awsup.AWSAPITarget{Cloud: cloud}, &kops.Cluster{}, cloud, nil, nil, nil, nil)
+			if err != nil {
+				t.Fatalf("error building context: %v", err)
+			}
+			nlb := &NetworkLoadBalancer{
+				Name: s("nlb"),
+			}
+			for _, subnet := range g.subnets {
+				nlb.SubnetMappings = append(nlb.SubnetMappings, &SubnetMapping{Subnet: subnet})
+			}
+			err = nlb.Normalize(c)
+			if g.expectedError == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), g.expectedError) {
+				t.Errorf("expected error containing %q, got %v", g.expectedError, err)
+			}
+		})
+	}
+
+	// The zones of the shared subnets are described in a single call, and only once
+	ec2Client.describeSubnetsCalls = 0
+	nlb := &NetworkLoadBalancer{
+		Name: s("nlb"),
+		SubnetMappings: []*SubnetMapping{
+			{Subnet: &Subnet{ID: s("subnet-shared-a")}},
+			{Subnet: &Subnet{ID: s("subnet-shared-b")}},
+		},
+	}
+	c, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, &awsup.AWSAPITarget{Cloud: cloud}, &kops.Cluster{}, cloud, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := nlb.Normalize(c); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if ec2Client.describeSubnetsCalls != 1 {
+		t.Errorf("expected a single DescribeSubnets call, got %d", ec2Client.describeSubnetsCalls)
+	}
+}
+
+func TestNetworkLoadBalancerClientRoutingPolicy(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(policy *string) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		allTasks["nlb1"].(*NetworkLoadBalancer).ClientRoutingPolicy = policy
+		return allTasks
+	}
+
+	var loadBalancerArn string
+	for _, policy := range []*string{nil, s("availability_zone_affinity"), s("partial_availability_zone_affinity"), s("any_availability_zone")} {
+		allTasks := buildTasks(policy)
+		runNLBTasks(t, cloud, allTasks)
+
+		arn := allTasks["nlb1"].(*NetworkLoadBalancer).loadBalancerArn
+		if loadBalancerArn != "" && arn != loadBalancerArn {
+			t.Fatalf("expected client routing policy to be changed in place, load balancer changed from %q to %q", loadBalancerArn, arn)
+		}
+		loadBalancerArn = arn
+
+		response, err := c.DescribeLoadBalancerAttributes(ctx, &elbv2.DescribeLoadBalancerAttributesInput{LoadBalancerArn: aws.String(loadBalancerArn)})
+		if err != nil {
+			t.Fatalf("error describing load balancer attributes: %v", err)
+		}
+		actual := ""
+		for _, attribute := range response.Attributes {
+			if aws.ToString(attribute.Key) == "dns_record.client_routing_policy" {
+				actual = aws.ToString(attribute.Value)
+			}
+		}
+		if actual != fi.ValueOf(policy) {
+			t.Errorf("expected client routing policy %q, got %q", fi.ValueOf(policy), actual)
+		}
+
+		checkNLBNoChanges(t, ctx, cloud, buildTasks(policy))
+	}
+
+	nlb := &NetworkLoadBalancer{Name: s("nlb1"), ClientRoutingPolicy: s("same_availability_zone")}
+	if err := (&NetworkLoadBalancer{}).CheckChanges(&NetworkLoadBalancer{Name: s("nlb1")}, nlb, nlb); err == nil {
+		t.Errorf("expected error for an unsupported client routing policy")
+	}
+}
+
+func TestNetworkLoadBalancerCheckChangesSubnetMappings(t *testing.T) {
+	subnet1 := &Subnet{Name: s("subnet1"), ID: s("subnet-1")}
+	subnet2 := &Subnet{Name: s("subnet2"), ID: s("subnet-2")}
+
+	grid := []struct {
+		name        string
+		scheme      elbv2types.LoadBalancerSchemeEnum
+		actual      []*SubnetMapping
+		expected    []*SubnetMapping
+		expectError bool
+	}{
+		{
+			name:     "elastic ip",
+			scheme:   elbv2types.LoadBalancerSchemeEnumInternetFacing,
+			expected: []*SubnetMapping{{Subnet: subnet1, AllocationID: s("eipalloc-1")}},
+		},
+		{
+			name:        "elastic ip on internal",
+			scheme:      elbv2types.LoadBalancerSchemeEnumInternal,
+			expected:    []*SubnetMapping{{Subnet: subnet1, AllocationID: s("eipalloc-1")}},
+			expectError: true,
+		},
+		{
+			name:     "private address",
+			scheme:   elbv2types.LoadBalancerSchemeEnumInternal,
+			expected: []*SubnetMapping{{Subnet: subnet1, PrivateIPv4Address: s("10.0.0.10")}},
+		},
+		{
+			name:        "private address on internet-facing",
+			scheme:      elbv2types.LoadBalancerSchemeEnumInternetFacing,
+			expected:    []*SubnetMapping{{Subnet: subnet1, PrivateIPv4Address: s("10.0.0.10")}},
+			expectError: true,
+		},
+		{
+			name:        "invalid private address",
+			scheme:      elbv2types.LoadBalancerSchemeEnumInternal,
+			expected:    []*SubnetMapping{{Subnet: subnet1, PrivateIPv4Address: s("2001:db8::10")}},
+			expectError: true,
+		},
+		{
+			name:     "subnet added with elastic ips",
+			scheme:   elbv2types.LoadBalancerSchemeEnumInternetFacing,
+			actual:   []*SubnetMapping{{Subnet: subnet1, AllocationID: s("eipalloc-1")}},
+			expected: []*SubnetMapping{{Subnet: subnet1, AllocationID: s("eipalloc-1")}, {Subnet: subnet2, AllocationID: s("eipalloc-2")}},
+		},
+		{
+			name:        "elastic ip changed",
+			scheme:      elbv2types.LoadBalancerSchemeEnumInternetFacing,
+			actual:      []*SubnetMapping{{Subnet: subnet1, AllocationID: s("eipalloc-1")}},
+			expected:    []*SubnetMapping{{Subnet: subnet1, AllocationID: s("eipalloc-2")}},
+			expectError: true,
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			e := &NetworkLoadBalancer{Name: s("nlb"), Scheme: g.scheme, SubnetMappings: g.expected}
+			var a *NetworkLoadBalancer
+			changes := e
+			if g.actual != nil {
+				a = &NetworkLoadBalancer{Name: s("nlb"), Scheme: g.scheme, SubnetMappings: g.actual}
+				changes = &NetworkLoadBalancer{SubnetMappings: g.expected}
+			}
+			err := (&NetworkLoadBalancer{}).CheckChanges(a, e, changes)
+			if g.expectError && err == nil {
+				t.Errorf("expected error, got none")
+			}
+			if !g.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestNetworkLoadBalancerPrivateLinkEnforcement(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(enforce string) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+		sg1 := &SecurityGroup{
+			Name:        s("sg1"),
+			Lifecycle:   fi.LifecycleSync,
+			VPC:         nlb1.VPC,
+			Description: s("nlb1"),
+			Tags:        map[string]string{"Name": "sg1"},
+		}
+		allTasks["sg1"] = sg1
+		nlb1.SecurityGroups = []*SecurityGroup{sg1}
+		nlb1.EnforceSecurityGroupInboundRulesOnPrivateLinkTraffic = s(enforce)
+		return allTasks
+	}
+
+	var loadBalancerArn string
+	for _, enforce := range []string{"off", "on"} {
+		allTasks := buildTasks(enforce)
+		runNLBTasks(t, cloud, allTasks)
+
+		arn := allTasks["nlb1"].(*NetworkLoadBalancer).loadBalancerArn
+		if loadBalancerArn != "" && arn != loadBalancerArn {
+			t.Fatalf("expected the setting to be changed in place, load balancer changed from %q to %q", loadBalancerArn, arn)
+		}
+		loadBalancerArn = arn
+
+		loadBalancers, err := awsup.ListELBV2LoadBalancers(ctx, cloud)
+		if err != nil {
+			t.Fatalf("error listing load balancers: %v", err)
+		}
+		if len(loadBalancers) != 1 {
+			t.Fatalf("expected a single load balancer, got %d", len(loadBalancers))
+		}
+		if actual := aws.ToString(loadBalancers[0].LoadBalancer.EnforceSecurityGroupInboundRulesOnPrivateLinkTraffic); actual != enforce {
+			t.Errorf("expected enforcement of inbound rules on PrivateLink traffic %q, got %q", enforce, actual)
+		}
+
+		checkNLBNoChanges(t, ctx, cloud, buildTasks(enforce))
+	}
+}
+
+func TestNetworkLoadBalancerTerraform(t *testing.T) {
+	cases := []*renderTest{
+		{
+			Resource: &NetworkLoadBalancer{
+				Name:                 s("nlb1"),
+				LoadBalancerBaseName: s("nlb1"),
+				Scheme:               elbv2types.LoadBalancerSchemeEnumInternetFacing,
+				IpAddressType:        elbv2types.IpAddressTypeDualstackWithoutPublicIpv4,
+				SubnetMappings:       []*SubnetMapping{{Subnet: &Subnet{Name: s("subnet1")}}},
+				DeletionProtection:   fi.PtrTo(true),
+				ClientRoutingPolicy:  s("availability_zone_affinity"),
+				Tags:                 map[string]string{"Name": "nlb1"},
+			},
+			Expected: `provider "aws" {
+  region = "eu-west-2"
+}
+
+resource "aws_lb" "nlb1" {
+  dns_record_client_routing_policy = "availability_zone_affinity"
+  enable_cross_zone_load_balancing = false
+  enable_deletion_protection       = true
+  internal                         = false
+  ip_address_type                  = "dualstack-without-public-ipv4"
+  load_balancer_type               = "network"
+  name                             = "nlb1"
+  subnet_mapping {
+    subnet_id = aws_subnet.subnet1.id
+  }
+  tags = {
+    "Name" = "nlb1"
+  }
+}
+
+terraform {
+  required_version = ">= 0.15.0"
+  required_providers {
+    aws = {
+      "source"  = "hashicorp/aws"
+      "version" = ">= 5.0.0"
+    }
+  }
+}
+`,
+		},
+		{
+			Resource: &NetworkLoadBalancer{
+				Name:                 s("nlb1"),
+				LoadBalancerBaseName: s("nlb1"),
+				Scheme:               elbv2types.LoadBalancerSchemeEnumInternal,
+				SubnetMappings:       []*SubnetMapping{{Subnet: &Subnet{Name: s("subnet1")}}},
+				SecurityGroups:       []*SecurityGroup{{Name: s("sg1")}},
+				Tags:                 map[string]string{"Name": "nlb1"},
+
+				EnforceSecurityGroupInboundRulesOnPrivateLinkTraffic: s("off"),
+			},
+			Expected: `provider "aws" {
+  region = "eu-west-2"
+}
+
+resource "aws_lb" "nlb1" {
+  enable_cross_zone_load_balancing                             = false
+  enforce_security_group_inbound_rules_on_private_link_traffic = "off"
+  internal                                                     = true
+  load_balancer_type                                           = "network"
+  name                                                         = "nlb1"
+  security_groups                                              = [aws_security_group.sg1.id]
+  subnet_mapping {
+    subnet_id = aws_subnet.subnet1.id
+  }
+  tags = {
+    "Name" = "nlb1"
+  }
+}
+
+terraform {
+  required_version = ">= 0.15.0"
+  required_providers {
+    aws = {
+      "source"  = "hashicorp/aws"
+      "version" = ">= 5.0.0"
+    }
+  }
+}
+`,
+		},
+		{
+			Resource: &NetworkLoadBalancer{
+				Name:                 s("nlb1"),
+				LoadBalancerBaseName: s("nlb1"),
+				Scheme:               elbv2types.LoadBalancerSchemeEnumInternal,
+				SubnetMappings:       []*SubnetMapping{{Subnet: &Subnet{Name: s("subnet1")}, PrivateIPv4Address: s("172.20.1.10")}},
+				Tags:                 map[string]string{"Name": "nlb1"},
+			},
+			Expected: `provider "aws" {
+  region = "eu-west-2"
+}
+
+resource "aws_lb" "nlb1" {
+  enable_cross_zone_load_balancing = false
+  internal                         = true
+  load_balancer_type               = "network"
+  name                             = "nlb1"
+  subnet_mapping {
+    private_ipv4_address = "172.20.1.10"
+    subnet_id            = aws_subnet.subnet1.id
+  }
+  tags = {
+    "Name" = "nlb1"
+  }
+}
+
+terraform {
+  required_version = ">= 0.15.0"
+  required_providers {
+    aws = {
+      "source"  = "hashicorp/aws"
+      "version" = ">= 5.0.0"
+    }
+  }
+}
+`,
+		},
+	}
+
+	doRenderTests(t, "RenderTerraform", cases)
+}
+
+func TestNetworkLoadBalancerPrivateIPv4Address(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func() map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+		nlb1.SubnetMappings[0].PrivateIPv4Address = s("172.20.1.10")
+		return allTasks
+	}
+
+	allTasks := buildTasks()
+	runNLBTasks(t, cloud, allTasks)
+
+	loadBalancers, err := awsup.ListELBV2LoadBalancers(ctx, cloud)
+	if err != nil {
+		t.Fatalf("error listing load balancers: %v", err)
+	}
+	if len(loadBalancers) != 1 {
+		t.Fatalf("expected a single load balancer, got %d", len(loadBalancers))
+	}
+	var addresses []string
+	for _, az := range loadBalancers[0].LoadBalancer.AvailabilityZones {
+		for _, address := range az.LoadBalancerAddresses {
+			addresses = append(addresses, fi.ValueOf(address.PrivateIPv4Address))
+		}
+	}
+	if expected := []string{"172.20.1.10"}; !slices.Equal(addresses, expected) {
+		t.Errorf("expected private addresses %v, got %v", expected, addresses)
+	}
+
+	// Find reads back the private address, so there are no changes
+	checkNLBNoChanges(t, ctx, cloud, buildTasks())
+}
+
+func TestNetworkLoadBalancerExportDNSNameTerraform(t *testing.T) {
+	cases := []*renderTest{
+		{
+			Resource: &NetworkLoadBalancer{
+				Name:                 s("api.cluster.example.com"),
+				LoadBalancerBaseName: s("api"),
+				Scheme:               elbv2types.LoadBalancerSchemeEnumInternetFacing,
+				SubnetMappings:       []*SubnetMapping{{Subnet: &Subnet{Name: s("subnet1")}}},
+				ExportWithID:         s("cluster"),
+			},
+			Expected: `locals {
+  cluster_nlb_dns_name = aws_lb.api-cluster-example-com.dns_name
+}
+
+output "cluster_nlb_dns_name" {
+  value = aws_lb.api-cluster-example-com.dns_name
+}
+
+provider "aws" {
+  region = "eu-west-2"
+}
+
+resource "aws_lb" "api-cluster-example-com" {
+  enable_cross_zone_load_balancing = false
+  internal                         = false
+  load_balancer_type               = "network"
+  name                             = "api"
+  subnet_mapping {
+    subnet_id = aws_subnet.subnet1.id
+  }
+}
+
+terraform {
+  required_version = ">= 0.15.0"
+  required_providers {
+    aws = {
+      "source"  = "hashicorp/aws"
+      "version" = ">= 5.0.0"
+    }
+  }
+}
+`,
+		},
+	}
+
+	doRenderTests(t, "RenderTerraform", cases)
+}
+
+func TestNetworkLoadBalancerPruneListeners(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(pruneListeners bool, ports ...int) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+		nlb1.PruneListeners = pruneListeners
+
+		tg1 := &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                nlb1.VPC,
+			Tags:               map[string]string{"Name": "tg1"},
+			Protocol:           elbv2types.ProtocolEnumTcp,
+			Port:               fi.PtrTo(int32(443)),
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+		}
+		allTasks["tg1"] = tg1
+		for _, port := range ports {
+			name := "listener-" + strconv.Itoa(port)
+			allTasks[name] = &NetworkLoadBalancerListener{
+				Name:                s(name),
+				Lifecycle:           fi.LifecycleSync,
+				NetworkLoadBalancer: nlb1,
+				Port:                port,
+				TargetGroup:         tg1,
+				Protected:           port == 10443,
+			}
+		}
+		return allTasks
+	}
+
+	listenerPorts := func(t *testing.T, loadBalancerArn string) []int32 {
+		listeners, err := awsup.ListELBV2Listeners(ctx, cloud, loadBalancerArn)
+		if err != nil {
+			t.Fatalf("error listing listeners: %v", err)
+		}
+		var ports []int32
+		for _, listener := range listeners {
+			ports = append(ports, aws.ToInt32(listener.Port))
+		}
+		slices.Sort(ports)
+		return ports
+	}
+
+	allTasks := buildTasks(false, 443, 8443, 10443)
+	runNLBTasks(t, cloud, allTasks)
+	loadBalancerArn := allTasks["nlb1"].(*NetworkLoadBalancer).loadBalancerArn
+
+	// The mock cloud has no cluster tags, so we tag the listeners of the cluster ourselves
+	clusterTags := map[string]string{"KubernetesCluster": "abc.example.com"}
+	for _, port := range []int{8443, 10443} {
+		listenerArn := allTasks["listener-"+strconv.Itoa(port)].(*NetworkLoadBalancerListener).listenerArn
+		if _, err := c.AddTags(ctx, &elbv2.AddTagsInput{ResourceArns: []string{listenerArn}, Tags: awsup.ELBv2Tags(clusterTags)}); err != nil {
+			t.Fatalf("error tagging listener: %v", err)
+		}
+	}
+	// A listener created outside of kops, without the cluster tags
+	if _, err := c.CreateListener(ctx, &elbv2.CreateListenerInput{
+		LoadBalancerArn: aws.String(loadBalancerArn),
+		Port:            aws.Int32(9443),
+		Protocol:        elbv2types.ProtocolEnumTcp,
+		DefaultActions: []elbv2types.Action{
+			{Type: elbv2types.ActionTypeEnumForward, TargetGroupArn: allTasks["tg1"].(*TargetGroup).ARN},
+		},
+	}); err != nil {
+		t.Fatalf("error creating listener: %v", err)
+	}
+
+	// Without opting in, the listeners removed from the spec are kept
+	runNLBTasks(t, cloud, buildTasks(false, 443))
+	if ports, expected := listenerPorts(t, loadBalancerArn), []int32{443, 8443, 9443, 10443}; !slices.Equal(ports, expected) {
+		t.Errorf("expected listeners on ports %v without pruning, got %v", expected, ports)
+	}
+
+	// Only the listener owned by the cluster and not protected is pruned
+	clusterCloud := cloud.WithTags(clusterTags)
+	target := &awsup.AWSAPITarget{Cloud: clusterCloud}
+	allTasks = buildTasks(true, 443)
+	nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+	nlb1.loadBalancerArn = loadBalancerArn
+	cloudupContext, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, target, &kops.Cluster{}, clusterCloud, nil, nil, nil, allTasks)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+	deletions, err := nlb1.FindDeletions(cloudupContext)
+	if err != nil {
+		t.Fatalf("error finding deletions: %v", err)
+	}
+	if len(deletions) != 1 {
+		t.Fatalf("expected 1 listener to prune, got %v", deletions)
+	}
+	if err := deletions[0].Delete(target); err != nil {
+		t.Fatalf("error pruning listener: %v", err)
+	}
+	if ports, expected := listenerPorts(t, loadBalancerArn), []int32{443, 9443, 10443}; !slices.Equal(ports, expected) {
+		t.Errorf("expected listeners on ports %v after pruning, got %v", expected, ports)
+	}
+}
+
+func TestNetworkLoadBalancerCrossZoneDrift(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func() map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		allTasks["nlb1"].(*NetworkLoadBalancer).CrossZoneLoadBalancing = fi.PtrTo(true)
+		return allTasks
+	}
+
+	crossZoneEnabled := func(t *testing.T, loadBalancerArn string) string {
+		response, err := c.DescribeLoadBalancerAttributes(ctx, &elbv2.DescribeLoadBalancerAttributesInput{LoadBalancerArn: aws.String(loadBalancerArn)})
+		if err != nil {
+			t.Fatalf("error describing load balancer attributes: %v", err)
+		}
+		for _, attribute := range response.Attributes {
+			if aws.ToString(attribute.Key) == "load_balancing.cross_zone.enabled" {
+				return aws.ToString(attribute.Value)
+			}
+		}
+		return ""
+	}
+
+	allTasks := buildTasks()
+	runNLBTasks(t, cloud, allTasks)
+	loadBalancerArn := allTasks["nlb1"].(*NetworkLoadBalancer).loadBalancerArn
+	checkNLBNoChanges(t, ctx, cloud, buildTasks())
+
+	// Cross-zone load balancing is turned off outside of kops
+	if _, err := c.ModifyLoadBalancerAttributes(ctx, &elbv2.ModifyLoadBalancerAttributesInput{
+		LoadBalancerArn: aws.String(loadBalancerArn),
+		Attributes: []elbv2types.LoadBalancerAttribute{
+			{Key: aws.String("load_balancing.cross_zone.enabled"), Value: aws.String("false")},
+		},
+	}); err != nil {
+		t.Fatalf("error modifying load balancer attributes: %v", err)
+	}
+
+	allTasks = buildTasks()
+	cloudupContext, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, &awsup.AWSAPITarget{Cloud: cloud}, &kops.Cluster{}, cloud, nil, nil, nil, allTasks)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+	e := allTasks["nlb1"].(*NetworkLoadBalancer)
+	actual, err := e.Find(cloudupContext)
+	if err != nil {
+		t.Fatalf("error finding load balancer: %v", err)
+	}
+	changes := &NetworkLoadBalancer{}
+	if !fi.BuildChanges(actual, e, changes) || !fi.ValueOf(changes.CrossZoneLoadBalancing) {
+		t.Errorf("expected the cross-zone change to be detected, got changes %v", fi.DebugAsJsonString(changes))
+	}
+
+	runNLBTasks(t, cloud, buildTasks())
+	if actual := crossZoneEnabled(t, loadBalancerArn); actual != "true" {
+		t.Errorf("expected cross-zone load balancing to be enabled again, got %q", actual)
+	}
+	checkNLBNoChanges(t, ctx, cloud, buildTasks())
+}
+
+func TestNetworkLoadBalancerNameTagOverride(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func() map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+		nlb1.NameTagOverride = "corp-prod-api"
+
+		tg1 := &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                nlb1.VPC,
+			Tags:               map[string]string{"Name": "tg1"},
+			Protocol:           elbv2types.ProtocolEnumTcp,
+			Port:               fi.PtrTo(int32(443)),
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+		}
+		allTasks["tg1"] = tg1
+		allTasks["listener1"] = &NetworkLoadBalancerListener{
+			Name:                s("listener1"),
+			Lifecycle:           fi.LifecycleSync,
+			NetworkLoadBalancer: nlb1,
+			Port:                443,
+			TargetGroup:         tg1,
+		}
+		return allTasks
+	}
+
+	allTasks := buildTasks()
+	runNLBTasks(t, cloud, allTasks)
+	loadBalancerArn := allTasks["nlb1"].(*NetworkLoadBalancer).loadBalancerArn
+
+	loadBalancers, err := awsup.ListELBV2LoadBalancers(ctx, cloud)
+	if err != nil {
+		t.Fatalf("error listing load balancers: %v", err)
+	}
+	if len(loadBalancers) != 1 {
+		t.Fatalf("expected a single load balancer, got %d", len(loadBalancers))
+	}
+	if nameTag := loadBalancers[0].NameTag(); nameTag != "corp-prod-api" {
+		t.Errorf("expected Name tag %q, got %q", "corp-prod-api", nameTag)
+	}
+	if name := aws.ToString(loadBalancers[0].LoadBalancer.LoadBalancerName); !strings.HasPrefix(name, "corp-prod-api") {
+		t.Errorf("expected the load balancer name to start with %q, got %q", "corp-prod-api", name)
+	}
+
+	// The load balancer and its listener are found again using the custom Name tag
+	allTasks = buildTasks()
+	cloudupContext, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, &awsup.AWSAPITarget{Cloud: cloud}, &kops.Cluster{}, cloud, nil, nil, nil, allTasks)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+	nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+	if err := nlb1.Normalize(cloudupContext); err != nil {
+		t.Fatalf("error normalizing load balancer: %v", err)
+	}
+	actual, err := nlb1.Find(cloudupContext)
+	if err != nil {
+		t.Fatalf("error finding load balancer: %v", err)
+	}
+	if actual == nil || actual.loadBalancerArn != loadBalancerArn {
+		t.Fatalf("expected to find load balancer %q, got %v", loadBalancerArn, actual)
+	}
+	if len(nlb1.deletions) != 0 {
+		t.Errorf("expected no load balancers to be deleted, got %d", len(nlb1.deletions))
+	}
+	actualListener, err := allTasks["listener1"].(*NetworkLoadBalancerListener).Find(cloudupContext)
+	if err != nil {
+		t.Fatalf("error finding listener: %v", err)
+	}
+	if actualListener == nil {
+		t.Fatalf("expected to find the listener of load balancer %q", loadBalancerArn)
+	}
+
+	checkNLBNoChanges(t, ctx, cloud, buildTasks())
+}
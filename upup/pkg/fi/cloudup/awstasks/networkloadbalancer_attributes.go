@@ -71,7 +71,7 @@ func findNetworkLoadBalancerAttributes(ctx context.Context, cloud awsup.AWSCloud
 func (_ *NetworkLoadBalancer) modifyLoadBalancerAttributes(t *awsup.AWSAPITarget, a, e, changes *NetworkLoadBalancer, loadBalancerArn string) error {
 	ctx := context.TODO()
 
-	if changes.CrossZoneLoadBalancing == nil && changes.AccessLog == nil {
+	if changes.CrossZoneLoadBalancing == nil && changes.AccessLog == nil && changes.DeletionProtection == nil && changes.ClientRoutingPolicy == nil {
 		klog.V(4).Infof("No LoadBalancerAttribute changes; skipping update")
 		return nil
 	}
@@ -91,6 +91,22 @@ func (_ *NetworkLoadBalancer) modifyLoadBalancerAttributes(t *awsup.AWSAPITarget
 	}
 	attributes = append(attributes, attribute)
 
+	if e.DeletionProtection != nil {
+		attr := elbv2types.LoadBalancerAttribute{
+			Key:   aws.String("deletion_protection.enabled"),
+			Value: aws.String(strconv.FormatBool(aws.ToBool(e.DeletionProtection))),
+		}
+		attributes = append(attributes, attr)
+	}
+
+	if e.ClientRoutingPolicy != nil {
+		attr := elbv2types.LoadBalancerAttribute{
+			Key:   aws.String("dns_record.client_routing_policy"),
+			Value: e.ClientRoutingPolicy,
+		}
+		attributes = append(attributes, attr)
+	}
+
 	if e.AccessLog != nil {
 		attr := elbv2types.LoadBalancerAttribute{
 			Key:   aws.String("access_logs.s3.enabled"),
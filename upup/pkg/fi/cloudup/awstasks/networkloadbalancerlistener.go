@@ -18,7 +18,14 @@ package awstasks
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
@@ -39,12 +46,70 @@ type NetworkLoadBalancerListener struct {
 
 	NetworkLoadBalancer *NetworkLoadBalancer
 
-	Port             int
-	TargetGroup      *TargetGroup
+	Port int
+	// Protocol is the protocol of the listener: TCP, UDP, TCP_UDP or TLS.
+	// It defaults to TLS if SSLCertificateID is set, and to TCP otherwise.
+	// The protocols of the target groups must be compatible, see listenerTargetGroupProtocols;
+	// in particular a TCP listener passes TLS through to a TLS target group without terminating it.
+	Protocol    elbv2types.ProtocolEnum
+	TargetGroup *TargetGroup
+	// SSLCertificateID is the default certificate of a TLS listener, served to clients whose SNI hostname
+	// matches none of the AdditionalCertificates. Changing it swaps the default in place.
 	SSLCertificateID string
-	SSLPolicy        string
+	// AdditionalCertificates are served alongside the default SSLCertificateID using SNI,
+	// e.g. so that each API hostname presents its own certificate.
+	AdditionalCertificates []string
+	// SSLPolicy is the security policy of a TLS listener.
+	// It defaults to the SSLPolicy of the NetworkLoadBalancer, or to awsup.DefaultNetworkLoadBalancerSSLPolicy,
+	// if SSLCertificateID is set.
+	SSLPolicy string
+	// FallbackSSLCertificateID, if set, is used when the SSLCertificateID certificate no longer exists
+	// (e.g. it was deleted from ACM). By default, creating the listener fails instead.
+	FallbackSSLCertificateID string
+	// SSLPolicyAutoSelect replaces an SSLPolicy that is not available in the region with the closest available policy,
+	// instead of failing.
+	SSLPolicyAutoSelect bool
+	// ALPNPolicy is the Application-Layer Protocol Negotiation policy of a TLS listener, e.g. HTTP2Preferred.
+	// It is changed without recreating the listener.
+	ALPNPolicy string
+
+	// ForwardTargetGroups, if set, splits the traffic between several target groups by weight,
+	// and is used instead of TargetGroup. Weights are relative: [3, 7] and [30, 70] are the same split.
+	ForwardTargetGroups []*TargetGroupWeight
+	// StickinessEnabled keeps a client on the same target group of ForwardTargetGroups
+	// for StickinessDurationSeconds.
+	StickinessEnabled         bool
+	StickinessDurationSeconds *int
+
+	// TemporaryPort, if set, avoids an outage when a change requires the listener to be recreated.
+	// The new configuration is first created on TemporaryPort; once the target group reports healthy targets,
+	// the existing listener is deleted and the temporary listener is moved to Port.
+	TemporaryPort int
+
+	// WaitConfig configures the timeouts used while waiting for ELBV2 resources, such as when swapping listeners.
+	WaitConfig *ELBV2WaitConfig
+
+	// Protected prevents kops from ever deleting the listener, whether to recreate it for required changes
+	// or when the load balancer is deleted. Changes that require recreating a protected listener fail,
+	// and the listener must be deleted manually.
+	Protected bool
+
+	// Tags are applied to the listener in addition to the cluster tags, e.g. for cost allocation.
+	Tags map[string]string
+
+	// CorrelationID is a stable identifier of the listener, applied as a tag so that access log records can be
+	// attributed to the listener across reconciles and recreations. It defaults to the Name of the task.
+	CorrelationID string
+
+	// AdoptListenerARN, if set, binds the task to this existing listener instead of the listener on Port,
+	// e.g. when bringing a load balancer created outside of kops under management.
+	// The adopted listener is only ever modified in place, never deleted and recreated.
+	AdoptListenerARN string
 
 	listenerArn string
+
+	// otherListenerPorts holds the ports of the other listener tasks of the load balancer, to the name of the task.
+	otherListenerPorts map[int]string
 }
 
 var _ fi.CompareWithID = &NetworkLoadBalancerListener{}
@@ -63,6 +128,8 @@ func (e *NetworkLoadBalancerListener) Find(c *fi.CloudupContext) (*NetworkLoadBa
 		return nil, fi.RequiredField("NetworkLoadBalancer")
 	}
 
+	// The load balancer has not been created yet, e.g. when planning a new cluster,
+	// so the listener is created once the load balancer task has run
 	loadBalancerArn := e.NetworkLoadBalancer.loadBalancerArn
 	if loadBalancerArn == "" {
 		return nil, nil
@@ -73,51 +140,117 @@ func (e *NetworkLoadBalancerListener) Find(c *fi.CloudupContext) (*NetworkLoadBa
 		request := &elbv2.DescribeListenersInput{
 			LoadBalancerArn: &loadBalancerArn,
 		}
-		// TODO: Move to lbInfo?
-		var allListeners []elbv2types.Listener
-		paginator := elbv2.NewDescribeListenersPaginator(cloud.ELBV2(), request)
-		for paginator.HasMorePages() {
-			page, err := paginator.NextPage(ctx)
-			if err != nil {
-				return nil, fmt.Errorf("error querying for NLB listeners :%v", err)
-			}
-			allListeners = append(allListeners, page.Listeners...)
+		allListeners, err := e.NetworkLoadBalancer.listeners.list(func() ([]elbv2types.Listener, error) {
+			var listeners []elbv2types.Listener
+			// Many tasks query ELBV2 at once during large updates, so throttled requests are retried rather than failing the update
+			err := retryOnThrottling(ctx, e.WaitConfig, fmt.Sprintf("listing listeners of load balancer %q", loadBalancerArn), func() error {
+				listeners = nil
+				paginator := elbv2.NewDescribeListenersPaginator(cloud.ELBV2(), request)
+				for paginator.HasMorePages() {
+					page, err := paginator.NextPage(ctx)
+					if err != nil {
+						return err
+					}
+					listeners = append(listeners, page.Listeners...)
+				}
+				return nil
+			})
+			return listeners, err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error querying for NLB listeners :%w", err)
 		}
 
-		var matches []elbv2types.Listener
-		for _, listener := range allListeners {
-			if aws.ToInt32(listener.Port) == int32(e.Port) {
-				matches = append(matches, listener)
+		if e.AdoptListenerARN != "" {
+			for i := range allListeners {
+				if aws.ToString(allListeners[i].ListenerArn) == e.AdoptListenerARN {
+					l = &allListeners[i]
+				}
 			}
+			if l == nil {
+				return nil, fmt.Errorf("listener %q to adopt was not found on load balancer %q", e.AdoptListenerARN, loadBalancerArn)
+			}
+		} else {
+			var matches []elbv2types.Listener
+			for _, listener := range allListeners {
+				if aws.ToInt32(listener.Port) == int32(e.Port) {
+					matches = append(matches, listener)
+				}
+			}
+			if len(matches) == 0 {
+				return nil, nil
+			}
+			l = e.selectListener(matches)
 		}
-		if len(matches) == 0 {
-			return nil, nil
-		}
-		if len(matches) > 1 {
-			return nil, fmt.Errorf("found multiple listeners matching %+v", e)
-		}
-		l = &matches[0]
 	}
 
 	actual := &NetworkLoadBalancerListener{}
 	actual.listenerArn = aws.ToString(l.ListenerArn)
 
+	{
+		response, err := cloud.ELBV2().DescribeTags(ctx, &elbv2.DescribeTagsInput{
+			ResourceArns: []string{actual.listenerArn},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("describing tags for listener %q: %w", actual.listenerArn, err)
+		}
+		clusterTags := cloud.Tags()
+		for _, tagDescription := range response.TagDescriptions {
+			for _, tag := range tagDescription.Tags {
+				k := aws.ToString(tag.Key)
+				if k == awsup.KopsProtectedTag {
+					actual.Protected = true
+					continue
+				}
+				if k == awsup.KopsListenerCorrelationIDTag {
+					actual.CorrelationID = aws.ToString(tag.Value)
+					continue
+				}
+				if _, found := clusterTags[k]; found || k == awsup.KopsCertificateRotatedTag {
+					continue
+				}
+				if actual.Tags == nil {
+					actual.Tags = make(map[string]string)
+				}
+				actual.Tags[k] = aws.ToString(tag.Value)
+			}
+		}
+	}
+
 	actual.Port = int(aws.ToInt32(l.Port))
+	actual.Protocol = l.Protocol
 	if len(l.Certificates) != 0 {
-		actual.SSLCertificateID = aws.ToString(l.Certificates[0].CertificateArn) // What if there is more then one certificate, can we just grab the default certificate? we don't set it as default, we only set the one.
+		certificates, err := awsup.ListELBV2ListenerCertificates(ctx, cloud, actual.listenerArn)
+		if err != nil {
+			if awsup.AWSErrorCode(err) != "AccessDenied" {
+				return nil, err
+			}
+			// Some restricted roles can describe listeners but not their certificates
+			klog.Warningf("not permitted to describe certificates of listener %q, using the listener's default certificate: %v", actual.listenerArn, err)
+			actual.SSLCertificateID = findDefaultCertificateARN(l.Certificates)
+			// The additional certificates are not known, so they are assumed to be up to date
+			actual.AdditionalCertificates = e.AdditionalCertificates
+		} else {
+			actual.SSLCertificateID = findDefaultCertificateARN(certificates)
+			actual.AdditionalCertificates = findAdditionalCertificateARNs(certificates, actual.SSLCertificateID, e.AdditionalCertificates)
+		}
+		if e.FallbackSSLCertificateID != "" && actual.SSLCertificateID == e.FallbackSSLCertificateID && e.SSLCertificateID != e.FallbackSSLCertificateID {
+			// Don't recreate the listener on every update while the certificate is still missing
+			klog.Warningf("listener %q is using the fallback certificate %q instead of %q", actual.listenerArn, e.FallbackSSLCertificateID, e.SSLCertificateID)
+			actual.SSLCertificateID = e.SSLCertificateID
+		}
 		if l.SslPolicy != nil {
 			actual.SSLPolicy = aws.ToString(l.SslPolicy)
 		}
+		if len(l.AlpnPolicy) != 0 && l.AlpnPolicy[0] != alpnPolicyNone {
+			actual.ALPNPolicy = l.AlpnPolicy[0]
+		}
 	}
 
-	// This will need to be rearranged when we recognized multiple listeners and target groups per NLB
-	if len(l.DefaultActions) > 0 {
-		targetGroupARN := l.DefaultActions[0].TargetGroupArn
-		if targetGroupARN != nil {
-			actual.TargetGroup = &TargetGroup{
-				ARN: targetGroupARN,
-			}
-		}
+	if action := findForwardAction(l.DefaultActions); action != nil {
+		actual.setForwardAction(action, e)
+	} else if len(l.DefaultActions) > 0 {
+		klog.Warningf("listener %q has no forward default action; found %d other actions", actual.listenerArn, len(l.DefaultActions))
 	}
 
 	_ = actual.Normalize(c)
@@ -126,26 +259,542 @@ func (e *NetworkLoadBalancerListener) Find(c *fi.CloudupContext) (*NetworkLoadBa
 	// Avoid spurious changes
 	actual.Name = e.Name
 	actual.NetworkLoadBalancer = e.NetworkLoadBalancer
+	actual.FallbackSSLCertificateID = e.FallbackSSLCertificateID
+	actual.SSLPolicyAutoSelect = e.SSLPolicyAutoSelect
+	actual.TemporaryPort = e.TemporaryPort
+	actual.WaitConfig = e.WaitConfig
+	actual.AdoptListenerARN = e.AdoptListenerARN
 
 	klog.V(4).Infof("Found NLB listener %+v", actual)
 
 	return actual, nil
 }
 
+// findDefaultCertificateARN returns the default certificate among the certificates of a listener.
+// The certificates listed by DescribeListeners are not flagged as default, in which case the first one is the default.
+func findDefaultCertificateARN(certificates []elbv2types.Certificate) string {
+	for _, certificate := range certificates {
+		if aws.ToBool(certificate.IsDefault) {
+			return aws.ToString(certificate.CertificateArn)
+		}
+	}
+	if len(certificates) != 0 {
+		return aws.ToString(certificates[0].CertificateArn)
+	}
+	return ""
+}
+
+// findAdditionalCertificateARNs returns the certificates of a listener other than the default certificate.
+// The certificates in desired come first and in the same order, so that an unchanged set is not reported as a change.
+func findAdditionalCertificateARNs(certificates []elbv2types.Certificate, defaultCertificateARN string, desired []string) []string {
+	found := make(map[string]bool)
+	for _, certificate := range certificates {
+		if arn := aws.ToString(certificate.CertificateArn); arn != defaultCertificateARN {
+			found[arn] = true
+		}
+	}
+
+	var additional []string
+	for _, arn := range desired {
+		if found[arn] {
+			additional = append(additional, arn)
+			delete(found, arn)
+		}
+	}
+	for _, certificate := range certificates {
+		if arn := aws.ToString(certificate.CertificateArn); found[arn] {
+			additional = append(additional, arn)
+			delete(found, arn)
+		}
+	}
+	return additional
+}
+
+// resolveSSLPolicy checks that SSLPolicy is available in the region, as not all policies are available in all regions.
+// If it is not, SSLPolicy is replaced by the closest available policy when SSLPolicyAutoSelect is set,
+// otherwise an error suggests the closest available policies.
+// The policies of the region are described once per run for all the listeners of the load balancer.
+func (e *NetworkLoadBalancerListener) resolveSSLPolicy(ctx context.Context, cloud awsup.AWSCloud) error {
+	policies, err := e.NetworkLoadBalancer.sslPolicies.list(func() ([]elbv2types.SslPolicy, error) {
+		return awsup.ListELBV2SSLPolicies(ctx, cloud)
+	})
+	if err != nil {
+		return err
+	}
+	for _, policy := range policies {
+		if aws.ToString(policy.Name) == e.SSLPolicy {
+			return nil
+		}
+	}
+
+	alternatives := awsup.ClosestELBV2SSLPolicies(e.SSLPolicy, policies)
+	if len(alternatives) == 0 {
+		return fmt.Errorf("SSL policy %q is not available in region %q, and no policy with an equivalent minimum TLS version is available", e.SSLPolicy, cloud.Region())
+	}
+	// Without knowing the minimum TLS version of the requested policy, we could pick a weaker policy
+	if e.SSLPolicyAutoSelect && awsup.SSLPolicyNameMinimumTLSVersion(e.SSLPolicy) != "" {
+		klog.Warningf("SSL policy %q is not available in region %q, using %q instead", e.SSLPolicy, cloud.Region(), alternatives[0])
+		e.SSLPolicy = alternatives[0]
+		return nil
+	}
+	return fmt.Errorf("SSL policy %q is not available in region %q; the closest available policies are: %s", e.SSLPolicy, cloud.Region(), strings.Join(alternatives[:min(3, len(alternatives))], ", "))
+}
+
+// elbv2ListenerCache holds the listeners of a load balancer, shared by the listener tasks of the load balancer.
+// It is invalidated whenever a listener task changes the listeners.
+type elbv2ListenerCache struct {
+	mutex     sync.Mutex
+	loaded    bool
+	listeners []elbv2types.Listener
+}
+
+// list returns the cached listeners, calling fetch on first use or after the cache was invalidated.
+// A nil cache always calls fetch.
+func (c *elbv2ListenerCache) list(fetch func() ([]elbv2types.Listener, error)) ([]elbv2types.Listener, error) {
+	if c == nil {
+		return fetch()
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.loaded {
+		listeners, err := fetch()
+		if err != nil {
+			return nil, err
+		}
+		c.listeners = listeners
+		c.loaded = true
+	}
+	return c.listeners, nil
+}
+
+// invalidate discards the cached listeners, so that they are described again on next use.
+func (c *elbv2ListenerCache) invalidate() {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.loaded = false
+	c.listeners = nil
+}
+
+// elbv2CertificateCache holds the outcome of validating certificates, shared by the listener tasks of a load balancer.
+// It is safe for concurrent use; the lock is held while validating, so that each certificate is validated once.
+type elbv2CertificateCache struct {
+	mutex   sync.Mutex
+	results map[string]error
+}
+
+// validate returns the cached outcome of validating the certificate, calling validate on first use.
+// An outcome is not cached if the context is done, as the validation was then likely interrupted.
+// A nil cache always calls validate.
+func (c *elbv2CertificateCache) validate(ctx context.Context, certificateARN string, validate func() error) error {
+	if c == nil {
+		return validate()
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if err, found := c.results[certificateARN]; found {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	err := validate()
+	if ctx.Err() != nil {
+		return err
+	}
+	if c.results == nil {
+		c.results = make(map[string]error)
+	}
+	c.results[certificateARN] = err
+	return err
+}
+
+// elbv2SSLPolicyCache holds the SSL policies available in the region, shared by the listener tasks of a load balancer.
+// It is safe for concurrent use; the lock is held while describing the policies, so that they are described once.
+type elbv2SSLPolicyCache struct {
+	mutex    sync.Mutex
+	loaded   bool
+	policies []elbv2types.SslPolicy
+}
+
+// list returns the cached policies, calling fetch on first use. Errors are not cached.
+// A nil cache always calls fetch.
+func (c *elbv2SSLPolicyCache) list(fetch func() ([]elbv2types.SslPolicy, error)) ([]elbv2types.SslPolicy, error) {
+	if c == nil {
+		return fetch()
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.loaded {
+		policies, err := fetch()
+		if err != nil {
+			return nil, err
+		}
+		c.policies = policies
+		c.loaded = true
+	}
+	return c.policies, nil
+}
+
+// selectListener picks the listener to manage among the listeners on the port of the task.
+// There should only be one, but an interrupted update can leave several behind; rather than failing,
+// the listener forwarding to the desired target groups is preferred, and then the listener with the lowest ARN.
+func (e *NetworkLoadBalancerListener) selectListener(matches []elbv2types.Listener) *elbv2types.Listener {
+	if len(matches) == 1 {
+		return &matches[0]
+	}
+
+	targetGroupARNs := make(map[string]bool)
+	if e.TargetGroup != nil && e.TargetGroup.ARN != nil {
+		targetGroupARNs[aws.ToString(e.TargetGroup.ARN)] = true
+	}
+	for _, w := range e.ForwardTargetGroups {
+		if w.TargetGroup != nil && w.TargetGroup.ARN != nil {
+			targetGroupARNs[aws.ToString(w.TargetGroup.ARN)] = true
+		}
+	}
+
+	var candidates []elbv2types.Listener
+	for _, listener := range matches {
+		action := findForwardAction(listener.DefaultActions)
+		if action == nil {
+			continue
+		}
+		forwards := targetGroupARNs[aws.ToString(action.TargetGroupArn)]
+		if action.ForwardConfig != nil {
+			for _, tuple := range action.ForwardConfig.TargetGroups {
+				forwards = forwards || targetGroupARNs[aws.ToString(tuple.TargetGroupArn)]
+			}
+		}
+		if forwards {
+			candidates = append(candidates, listener)
+		}
+	}
+	if len(candidates) == 1 {
+		klog.Warningf("found %d listeners on port %d, using %q which forwards to the target group of %q", len(matches), e.Port, aws.ToString(candidates[0].ListenerArn), fi.ValueOf(e.Name))
+		return &candidates[0]
+	}
+	if len(candidates) == 0 {
+		candidates = matches
+	}
+
+	selected := slices.MinFunc(candidates, func(a, b elbv2types.Listener) int {
+		return strings.Compare(aws.ToString(a.ListenerArn), aws.ToString(b.ListenerArn))
+	})
+	klog.Warningf("found %d listeners on port %d for %q, using %q", len(matches), e.Port, fi.ValueOf(e.Name), aws.ToString(selected.ListenerArn))
+	return &selected
+}
+
+// findForwardAction returns the forward action among the default actions, if any.
+// The forward action is not necessarily the first action, e.g. when it follows an authenticate action.
+func findForwardAction(actions []elbv2types.Action) *elbv2types.Action {
+	for i := range actions {
+		if actions[i].Type == elbv2types.ActionTypeEnumForward {
+			return &actions[i]
+		}
+	}
+	return nil
+}
+
+// setForwardAction sets the target groups and stickiness from a forward action.
+// A weighted forward is decoded into ForwardTargetGroups, following the order of desired and reusing its target groups,
+// so that an unchanged weighted forward is not reported as a change; likewise for an unchanged TargetGroup.
+func (e *NetworkLoadBalancerListener) setForwardAction(action *elbv2types.Action, desired *NetworkLoadBalancerListener) {
+	var tuples []elbv2types.TargetGroupTuple
+	if action.ForwardConfig != nil {
+		tuples = action.ForwardConfig.TargetGroups
+		if stickiness := action.ForwardConfig.TargetGroupStickinessConfig; stickiness != nil && aws.ToBool(stickiness.Enabled) {
+			e.StickinessEnabled = true
+			if stickiness.DurationSeconds != nil {
+				e.StickinessDurationSeconds = fi.PtrTo(int(aws.ToInt32(stickiness.DurationSeconds)))
+			}
+		}
+	}
+
+	if len(tuples) > 1 || (len(tuples) == 1 && len(desired.ForwardTargetGroups) != 0) {
+		weights := make(map[string]int32)
+		for _, tuple := range tuples {
+			weights[aws.ToString(tuple.TargetGroupArn)] = aws.ToInt32(tuple.Weight)
+		}
+		for _, w := range desired.ForwardTargetGroups {
+			if w.TargetGroup == nil || w.TargetGroup.ARN == nil {
+				continue
+			}
+			arn := aws.ToString(w.TargetGroup.ARN)
+			if weight, found := weights[arn]; found {
+				e.ForwardTargetGroups = append(e.ForwardTargetGroups, &TargetGroupWeight{TargetGroup: w.TargetGroup, Weight: int(weight)})
+				delete(weights, arn)
+			}
+		}
+		for _, tuple := range tuples {
+			arn := aws.ToString(tuple.TargetGroupArn)
+			if weight, found := weights[arn]; found {
+				e.ForwardTargetGroups = append(e.ForwardTargetGroups, &TargetGroupWeight{TargetGroup: &TargetGroup{ARN: tuple.TargetGroupArn}, Weight: int(weight)})
+			}
+		}
+		// Weights are relative, so proportional weights are not reported as a change
+		if sameWeightedSplit(e.ForwardTargetGroups, desired.ForwardTargetGroups) {
+			for i, w := range e.ForwardTargetGroups {
+				w.Weight = desired.ForwardTargetGroups[i].Weight
+			}
+		}
+		return
+	}
+
+	targetGroupArn := action.TargetGroupArn
+	if targetGroupArn == nil && len(tuples) == 1 {
+		targetGroupArn = tuples[0].TargetGroupArn
+	}
+	if targetGroupArn == nil {
+		return
+	}
+	// The desired target group is reused when its ARN is known and matches,
+	// so that both sides describe the target group the same way
+	if desired.TargetGroup != nil && desired.TargetGroup.ARN != nil && aws.ToString(desired.TargetGroup.ARN) == aws.ToString(targetGroupArn) {
+		e.TargetGroup = desired.TargetGroup
+	} else {
+		e.TargetGroup = &TargetGroup{ARN: targetGroupArn}
+	}
+}
+
+// forwardedTargetGroups returns the target groups that receive traffic from the listener.
+func (e *NetworkLoadBalancerListener) forwardedTargetGroups() []*TargetGroup {
+	if len(e.ForwardTargetGroups) == 0 {
+		if e.TargetGroup == nil {
+			return nil
+		}
+		return []*TargetGroup{e.TargetGroup}
+	}
+	var targetGroups []*TargetGroup
+	for _, w := range e.ForwardTargetGroups {
+		targetGroups = append(targetGroups, w.TargetGroup)
+	}
+	return targetGroups
+}
+
 func (e *NetworkLoadBalancerListener) Run(c *fi.CloudupContext) error {
+	return runTimed(c, "NetworkLoadBalancerListener", fi.ValueOf(e.Name), e.run)
+}
+
+func (e *NetworkLoadBalancerListener) run(c *fi.CloudupContext) error {
+	if t, ok := c.Target.(*terraform.TerraformTarget); ok && t.EmitImportBlocks {
+		// The terraform target does not call Find, so the existing listener is looked up here
+		actual, err := e.Find(c)
+		if err != nil {
+			return err
+		}
+		if actual != nil {
+			t.AddImport("aws_lb_listener", e.TerraformName(), actual.listenerArn)
+		}
+	}
 	return fi.CloudupDefaultDeltaRunMethod(e, c)
 }
 
 func (e *NetworkLoadBalancerListener) Normalize(c *fi.CloudupContext) error {
+	e.Protocol = e.protocol()
+	if e.CorrelationID == "" {
+		e.CorrelationID = fi.ValueOf(e.Name)
+	}
+	// The weights are validated before planning, as AWS accepts weights that would surprise users
+	if err := e.validateWeights(); err != nil {
+		return err
+	}
+	if e.NetworkLoadBalancer != nil {
+		e.otherListenerPorts = make(map[int]string)
+		for _, task := range c.AllTasks() {
+			listener, ok := task.(*NetworkLoadBalancerListener)
+			if !ok || listener == e || listener.NetworkLoadBalancer != e.NetworkLoadBalancer {
+				continue
+			}
+			e.otherListenerPorts[listener.Port] = fi.ValueOf(listener.Name)
+		}
+	}
+	if e.NetworkLoadBalancer != nil && e.NetworkLoadBalancer.PropagateTags {
+		// The cluster tags are always set on the listener, and are not part of its Tags
+		cloud := c.T.Cloud.(awsup.AWSCloud)
+		e.Tags = e.NetworkLoadBalancer.propagateTagsTo(e.Tags, cloud.Tags())
+	}
+	if e.SSLCertificateID != "" && e.SSLPolicy == "" {
+		if e.NetworkLoadBalancer != nil && e.NetworkLoadBalancer.SSLPolicy != "" {
+			e.SSLPolicy = e.NetworkLoadBalancer.SSLPolicy
+		} else {
+			e.SSLPolicy = awsup.DefaultNetworkLoadBalancerSSLPolicy
+		}
+	}
+	// Reject unknown policies before looking up their availability in the region
+	if e.SSLPolicy != "" && !slices.Contains(awsup.NetworkLoadBalancerSSLPolicies, e.SSLPolicy) {
+		return fmt.Errorf("listener %q has unknown SSL policy %q, did you mean %q?", fi.ValueOf(e.Name), e.SSLPolicy, awsup.ClosestNetworkLoadBalancerSSLPolicy(e.SSLPolicy))
+	}
+	if e.SSLCertificateID != "" && e.SSLPolicy != "" && e.NetworkLoadBalancer != nil {
+		if err := e.resolveSSLPolicy(c.Context(), c.T.Cloud.(awsup.AWSCloud)); err != nil {
+			return fmt.Errorf("listener %q: %w", fi.ValueOf(e.Name), err)
+		}
+	}
+	if e.SSLCertificateID != "" && e.NetworkLoadBalancer != nil {
+		// Reject unusable certificates early, rather than with an opaque error from CreateListener
+		cloud := c.T.Cloud.(awsup.AWSCloud)
+		err := e.NetworkLoadBalancer.certificates.validate(c.Context(), e.SSLCertificateID, func() error {
+			return awsup.ValidateListenerCertificate(c.Context(), cloud, e.SSLCertificateID)
+		})
+		if errors.Is(err, awsup.ErrCertificateNotFound) && e.FallbackSSLCertificateID != "" {
+			klog.Warningf("listener %q: %v, the fallback certificate %q will be used if the listener is created", fi.ValueOf(e.Name), err, e.FallbackSSLCertificateID)
+		} else if err != nil {
+			return fmt.Errorf("listener %q: %w", fi.ValueOf(e.Name), err)
+		}
+	}
 	return nil
 }
 
+// validateWeights checks the weights of ForwardTargetGroups.
+func (e *NetworkLoadBalancerListener) validateWeights() error {
+	if len(e.ForwardTargetGroups) == 0 {
+		return nil
+	}
+	totalWeight := 0
+	for _, w := range e.ForwardTargetGroups {
+		if w.TargetGroup == nil {
+			return fmt.Errorf("listener %q has a weighted forward without a target group", fi.ValueOf(e.Name))
+		}
+		if w.Weight < 0 || w.Weight > maxTargetGroupWeight {
+			return fmt.Errorf("listener %q has invalid weight %d for target group %q, must be between 0 and %d", fi.ValueOf(e.Name), w.Weight, fi.ValueOf(w.TargetGroup.Name), maxTargetGroupWeight)
+		}
+		totalWeight += w.Weight
+	}
+	// AWS accepts all-zero weights, but the listener would then drop all traffic
+	if totalWeight <= 0 {
+		return fmt.Errorf("listener %q must give a positive weight to at least one target group", fi.ValueOf(e.Name))
+	}
+	return nil
+}
+
+// protocol returns the Protocol of the listener, defaulting to TLS if a certificate is set and to TCP otherwise.
+func (e *NetworkLoadBalancerListener) protocol() elbv2types.ProtocolEnum {
+	if e.Protocol != "" {
+		return e.Protocol
+	}
+	if e.SSLCertificateID != "" {
+		return elbv2types.ProtocolEnumTls
+	}
+	return elbv2types.ProtocolEnumTcp
+}
+
+// alpnPolicyNone is the ALPN policy of a TLS listener without ALPN.
+const alpnPolicyNone = "None"
+
+// validALPNPolicies are the ALPN policies supported by TLS listeners.
+var validALPNPolicies = []string{"HTTP1Only", "HTTP2Only", "HTTP2Optional", "HTTP2Preferred", alpnPolicyNone}
+
+// listenerTargetGroupProtocols are the protocols of the target groups a listener can forward to, by listener protocol.
+// A TCP listener forwarding to a TLS target group is a TLS passthrough: the load balancer does not terminate TLS,
+// and the targets present their own certificates.
+var listenerTargetGroupProtocols = map[elbv2types.ProtocolEnum][]elbv2types.ProtocolEnum{
+	elbv2types.ProtocolEnumTcp:    {elbv2types.ProtocolEnumTcp, elbv2types.ProtocolEnumTcpUdp, elbv2types.ProtocolEnumTls},
+	elbv2types.ProtocolEnumTls:    {elbv2types.ProtocolEnumTcp, elbv2types.ProtocolEnumTls},
+	elbv2types.ProtocolEnumUdp:    {elbv2types.ProtocolEnumUdp, elbv2types.ProtocolEnumTcpUdp},
+	elbv2types.ProtocolEnumTcpUdp: {elbv2types.ProtocolEnumTcpUdp},
+}
+
 func (*NetworkLoadBalancerListener) CheckChanges(a, e, changes *NetworkLoadBalancerListener) error {
+	if e.Port < 1 || e.Port > 65535 {
+		return fmt.Errorf("listener %q has invalid Port %d, must be between 1 and 65535", fi.ValueOf(e.Name), e.Port)
+	}
+	switch e.protocol() {
+	case elbv2types.ProtocolEnumTls:
+		if e.SSLCertificateID == "" {
+			return fmt.Errorf("listener %q must set a certificate to use protocol %q", fi.ValueOf(e.Name), e.protocol())
+		}
+	case elbv2types.ProtocolEnumTcp, elbv2types.ProtocolEnumUdp, elbv2types.ProtocolEnumTcpUdp:
+		if e.SSLCertificateID != "" {
+			return fmt.Errorf("listener %q can only use a certificate with protocol %q, not %q", fi.ValueOf(e.Name), elbv2types.ProtocolEnumTls, e.protocol())
+		}
+	default:
+		return fmt.Errorf("listener %q has unsupported protocol %q", fi.ValueOf(e.Name), e.protocol())
+	}
+	if len(e.AdditionalCertificates) != 0 {
+		if e.SSLCertificateID == "" {
+			return fmt.Errorf("listener %q can only set additional certificates with TLS", fi.ValueOf(e.Name))
+		}
+		seen := map[string]bool{e.SSLCertificateID: true}
+		for _, arn := range e.AdditionalCertificates {
+			if seen[arn] {
+				return fmt.Errorf("listener %q has certificate %q more than once", fi.ValueOf(e.Name), arn)
+			}
+			seen[arn] = true
+		}
+	}
+	if e.ALPNPolicy != "" {
+		if e.SSLCertificateID == "" {
+			return fmt.Errorf("listener %q can only set an ALPN policy with TLS", fi.ValueOf(e.Name))
+		}
+		if !slices.Contains(validALPNPolicies, e.ALPNPolicy) {
+			return fmt.Errorf("listener %q has unsupported ALPN policy %q, expected one of %s", fi.ValueOf(e.Name), e.ALPNPolicy, strings.Join(validALPNPolicies, ", "))
+		}
+	}
+	if e.TemporaryPort != 0 {
+		if e.TemporaryPort < 1 || e.TemporaryPort > 65535 {
+			return fmt.Errorf("listener %q has invalid TemporaryPort %d", fi.ValueOf(e.Name), e.TemporaryPort)
+		}
+		if e.TemporaryPort == e.Port {
+			return fmt.Errorf("listener %q TemporaryPort must be different from Port %d", fi.ValueOf(e.Name), e.Port)
+		}
+		// The swap would otherwise delete or fail on the listener of the other task
+		if other, found := e.otherListenerPorts[e.TemporaryPort]; found {
+			return fmt.Errorf("listener %q TemporaryPort %d is the Port of listener %q", fi.ValueOf(e.Name), e.TemporaryPort, other)
+		}
+	}
+	if len(e.ForwardTargetGroups) != 0 {
+		if e.TargetGroup != nil {
+			return fmt.Errorf("listener %q cannot set both TargetGroup and ForwardTargetGroups", fi.ValueOf(e.Name))
+		}
+		if err := e.validateWeights(); err != nil {
+			return err
+		}
+		if e.StickinessDurationSeconds != nil {
+			// The duration is only read back from AWS when stickiness is enabled
+			if !e.StickinessEnabled {
+				return fmt.Errorf("listener %q sets StickinessDurationSeconds without StickinessEnabled", fi.ValueOf(e.Name))
+			}
+			if *e.StickinessDurationSeconds < 1 || *e.StickinessDurationSeconds > maxForwardStickinessDurationSeconds {
+				return fmt.Errorf("listener %q has invalid StickinessDurationSeconds %d, must be between 1 and %d", fi.ValueOf(e.Name), *e.StickinessDurationSeconds, maxForwardStickinessDurationSeconds)
+			}
+		}
+	} else if e.StickinessEnabled || e.StickinessDurationSeconds != nil {
+		return fmt.Errorf("listener %q can only use stickiness with ForwardTargetGroups", fi.ValueOf(e.Name))
+	}
+	if e.NetworkLoadBalancer != nil && e.NetworkLoadBalancer.VPC != nil {
+		// AWS rejects forwarding to a target group in another VPC, typically after copying a listener between clusters
+		for _, targetGroup := range e.forwardedTargetGroups() {
+			if targetGroup.VPC != nil && !sameVPC(targetGroup.VPC, e.NetworkLoadBalancer.VPC) {
+				return fmt.Errorf("listener %q forwards to target group %q in VPC %q, but load balancer %q is in VPC %q; the target group must be in the same VPC as the load balancer",
+					fi.ValueOf(e.Name), fi.ValueOf(targetGroup.Name), vpcDisplayName(targetGroup.VPC), fi.ValueOf(e.NetworkLoadBalancer.Name), vpcDisplayName(e.NetworkLoadBalancer.VPC))
+			}
+		}
+	}
+	for _, targetGroup := range e.forwardedTargetGroups() {
+		// The target group is a separate task; its protocol is only compared, never changed to match the listener
+		if targetGroup == nil || targetGroup.Protocol == "" {
+			continue
+		}
+		if allowed := listenerTargetGroupProtocols[e.protocol()]; !slices.Contains(allowed, targetGroup.Protocol) {
+			return fmt.Errorf("listener %q with protocol %s cannot forward to target group %q with protocol %s, expected one of %v", fi.ValueOf(e.Name), e.protocol(), fi.ValueOf(targetGroup.Name), targetGroup.Protocol, allowed)
+		}
+	}
+	if e.TargetGroup != nil && e.TargetGroup.TargetType == elbv2types.TargetTypeEnumAlb {
+		// Forwarding to an Application Load Balancer is only supported by TCP listeners on Network Load Balancers
+		if e.NetworkLoadBalancer != nil && e.NetworkLoadBalancer.Type != "" && e.NetworkLoadBalancer.Type != elbv2types.LoadBalancerTypeEnumNetwork {
+			return fmt.Errorf("listener %q can only forward to target group %q with target type %q from a network load balancer", fi.ValueOf(e.Name), fi.ValueOf(e.TargetGroup.Name), e.TargetGroup.TargetType)
+		}
+		if e.protocol() != elbv2types.ProtocolEnumTcp {
+			return fmt.Errorf("listener %q must use TCP to forward to target group %q with target type %q", fi.ValueOf(e.Name), fi.ValueOf(e.TargetGroup.Name), e.TargetGroup.TargetType)
+		}
+	}
 	return nil
 }
 
 func (*NetworkLoadBalancerListener) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *NetworkLoadBalancerListener) error {
-	ctx := context.TODO()
+	ctx := t.Context()
 
 	if e.NetworkLoadBalancer == nil {
 		return fi.RequiredField("NetworkLoadBalancer")
@@ -154,99 +803,568 @@ func (*NetworkLoadBalancerListener) RenderAWS(t *awsup.AWSAPITarget, a, e, chang
 	if loadBalancerArn == "" {
 		return fmt.Errorf("load balancer not yet created (arn not set)")
 	}
+	// Any change to the listeners makes the cached listeners stale
+	defer e.NetworkLoadBalancer.listeners.invalidate()
+
+	if a != nil && (e.AdoptListenerARN != "" || !listenerRequiresRecreate(a, e, changes)) {
+		actualAdditionalCertificates := a.AdditionalCertificates
+		if listenerRemovesTLS(a, e) {
+			// The additional certificates are removed while the listener still uses TLS
+			if err := updateAdditionalCertificates(ctx, t.Cloud, a.listenerArn, a.AdditionalCertificates, nil); err != nil {
+				return err
+			}
+			actualAdditionalCertificates = nil
+		}
+		sslPolicyChanged := e.SSLPolicy != "" && a.SSLPolicy != e.SSLPolicy
+		if changes.SSLCertificateID != "" && a.SSLCertificateID != "" {
+			// Listeners sharing the certificate are rotated together, so that none is left on the old certificate.
+			// A new SSL policy is set in the same request as the certificate, as the new certificate may require it.
+			var policyChange *awsup.ELBV2ListenerSSLPolicyChange
+			if sslPolicyChanged {
+				klog.V(2).Infof("Updating SSL policy of listener %q to %q", a.listenerArn, e.SSLPolicy)
+				policyChange = &awsup.ELBV2ListenerSSLPolicyChange{
+					ListenerARN: a.listenerArn,
+					OldPolicy:   a.SSLPolicy,
+					NewPolicy:   e.SSLPolicy,
+				}
+				sslPolicyChanged = false
+			}
+			if err := awsup.RotateELBV2ListenerCertificate(ctx, t.Cloud, loadBalancerArn, a.SSLCertificateID, e.SSLCertificateID, policyChange, e.WaitConfig.certificateRotationSettleTime()); err != nil {
+				return err
+			}
+		}
+		// The other changes are applied together, without interrupting the connections of the listener
+		request := &elbv2.ModifyListenerInput{
+			ListenerArn: &a.listenerArn,
+		}
+		modified := false
+		if changes.Protocol != "" {
+			// Switching to TCP drops the default certificate, SSL policy and ALPN policy of the listener.
+			// Other protocol changes are only applied in place to adopted listeners.
+			klog.V(2).Infof("Updating protocol of listener %q from %q to %q", a.listenerArn, a.Protocol, e.protocol())
+			request.Protocol = e.protocol()
+			modified = true
+		}
+		if changes.Port != 0 {
+			// Only adopted listeners change port in place
+			klog.V(2).Infof("Updating port of listener %q from %d to %d", a.listenerArn, a.Port, e.Port)
+			request.Port = aws.Int32(int32(e.Port))
+			modified = true
+		}
+		if changes.SSLCertificateID != "" && a.SSLCertificateID == "" {
+			// Only adopted listeners switch to TLS in place
+			klog.V(2).Infof("Updating default certificate of listener %q to %q", a.listenerArn, e.SSLCertificateID)
+			request.Certificates = []elbv2types.Certificate{{CertificateArn: aws.String(e.SSLCertificateID)}}
+			modified = true
+		}
+		if sslPolicyChanged {
+			klog.V(2).Infof("Updating SSL policy of listener %q to %q", a.listenerArn, e.SSLPolicy)
+			request.SslPolicy = aws.String(e.SSLPolicy)
+			modified = true
+		}
+		if changes.TargetGroup != nil || changes.ForwardTargetGroups != nil || a.StickinessEnabled != e.StickinessEnabled || fi.ValueOf(a.StickinessDurationSeconds) != fi.ValueOf(e.StickinessDurationSeconds) {
+			// The weights may have been changed outside of kops, e.g. by a manual rebalancing
+			action, err := e.buildForwardAction()
+			if err != nil {
+				return err
+			}
+			klog.V(2).Infof("Updating forward action of listener %q", a.listenerArn)
+			request.DefaultActions = []elbv2types.Action{*action}
+			modified = true
+		}
+		if a.ALPNPolicy != e.ALPNPolicy && !listenerRemovesTLS(a, e) {
+			alpnPolicy := e.ALPNPolicy
+			if alpnPolicy == "" {
+				alpnPolicy = alpnPolicyNone
+			}
+			klog.V(2).Infof("Updating ALPN policy of listener %q to %q", a.listenerArn, alpnPolicy)
+			request.AlpnPolicy = []string{alpnPolicy}
+			modified = true
+		}
+		if modified {
+			if _, err := t.Cloud.ELBV2().ModifyListener(ctx, request); err != nil {
+				return fmt.Errorf("updating listener %q: %w", a.listenerArn, err)
+			}
+		}
+		if err := updateAdditionalCertificates(ctx, t.Cloud, a.listenerArn, actualAdditionalCertificates, e.AdditionalCertificates); err != nil {
+			return err
+		}
+		if !maps.Equal(a.Tags, e.Tags) || a.CorrelationID != e.CorrelationID {
+			if err := e.updateTags(ctx, t, a.listenerArn, a.Tags); err != nil {
+				return err
+			}
+		}
+		if a.Protected != e.Protected {
+			return e.updateProtection(ctx, t, a.listenerArn)
+		}
+		return nil
+	}
+
+	if a != nil && (a.Protected || e.Protected) {
+		return fmt.Errorf("listener %q on port %d is protected, refusing to delete it to apply required changes (%+v); delete the listener manually, or remove the protection first", a.listenerArn, a.Port, changes)
+	}
+
+	if a != nil {
+		if err := verifyLoadBalancerOwnership(ctx, t, a.listenerArn, loadBalancerArn); err != nil {
+			return err
+		}
+	}
+
+	if a != nil && e.TemporaryPort != 0 {
+		return e.swapListener(ctx, t, a, loadBalancerArn)
+	}
 
 	if a != nil {
 		// TODO: Can we do better here?
 		klog.Warningf("deleting ELB listener %q for required changes (%+v)", a.listenerArn, changes)
 
 		// delete the listener before recreating it
-		_, err := t.Cloud.ELBV2().DeleteListener(ctx, &elbv2.DeleteListenerInput{
-			ListenerArn: &a.listenerArn,
-		})
-		if err != nil {
-			return fmt.Errorf("error deleting load balancer listener with arn=%q: %w", e.listenerArn, err)
+		if err := a.deleteListener(ctx, t.Cloud, loadBalancerArn); err != nil {
+			return err
 		}
 		a = nil
 	}
 
 	if a == nil {
+		request, err := e.buildCreateListenerInput(loadBalancerArn, e.Port, e.listenerTags(t.Cloud.Tags()))
+		if err != nil {
+			return err
+		}
+
+		klog.V(2).Infof("Creating Listener for NLB with port %v", e.Port)
+		response, err := e.createListener(ctx, t.Cloud, request)
+		if err != nil {
+			return fmt.Errorf("creating listener for NLB on port %v: %w", e.Port, err)
+		}
+		e.listenerArn = aws.ToString(response.Listeners[0].ListenerArn)
+
+		if err := updateAdditionalCertificates(ctx, t.Cloud, e.listenerArn, nil, e.AdditionalCertificates); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteListener deletes the actual listener a, naming the listener, its load balancer, port and protocol on failure.
+func (a *NetworkLoadBalancerListener) deleteListener(ctx context.Context, cloud awsup.AWSCloud, loadBalancerArn string) error {
+	if _, err := cloud.ELBV2().DeleteListener(ctx, &elbv2.DeleteListenerInput{ListenerArn: &a.listenerArn}); err != nil {
+		return fmt.Errorf("deleting listener %q (port %d, protocol %s) of load balancer %q: %w", a.listenerArn, a.Port, a.Protocol, loadBalancerArn, err)
+	}
+	return nil
+}
+
+// verifyLoadBalancerOwnership checks that the load balancer carries the tags of this cluster before one of its listeners is deleted,
+// so that a listener of a load balancer owned by another cluster (or not managed by kops) is never removed.
+func verifyLoadBalancerOwnership(ctx context.Context, t *awsup.AWSAPITarget, listenerArn string, loadBalancerArn string) error {
+	response, err := t.Cloud.ELBV2().DescribeTags(ctx, &elbv2.DescribeTagsInput{
+		ResourceArns: []string{loadBalancerArn},
+	})
+	if err != nil {
+		return fmt.Errorf("refusing to delete listener %q: describing tags of load balancer %q: %w", listenerArn, loadBalancerArn, err)
+	}
+	for _, tagDescription := range response.TagDescriptions {
+		if aws.ToString(tagDescription.ResourceArn) == loadBalancerArn && awsup.MatchesElbV2Tags(t.Cloud.Tags(), tagDescription.Tags) {
+			return nil
+		}
+	}
+	return fmt.Errorf("refusing to delete listener %q: load balancer %q does not have the tags of this cluster", listenerArn, loadBalancerArn)
+}
+
+// createListener creates the listener, handling a SSLCertificateID that no longer exists:
+// the listener is created with the FallbackSSLCertificateID if set, otherwise the error names the missing certificate.
+func (e *NetworkLoadBalancerListener) createListener(ctx context.Context, cloud awsup.AWSCloud, request *elbv2.CreateListenerInput) (*elbv2.CreateListenerOutput, error) {
+	if e.WaitConfig != nil && e.WaitConfig.WaitForLoadBalancerActive {
+		if err := awsup.WaitForLoadBalancerActive(ctx, cloud, aws.ToString(request.LoadBalancerArn), e.WaitConfig.loadBalancerActiveTimeout()); err != nil {
+			return nil, err
+		}
+	}
+
+	response, err := cloud.ELBV2().CreateListener(ctx, request)
+	if err == nil || e.SSLCertificateID == "" || awsup.AWSErrorCode(err) != "CertificateNotFound" {
+		return response, err
+	}
+	if e.FallbackSSLCertificateID == "" {
+		return nil, fmt.Errorf("certificate %q of listener %q was not found, it may have been deleted from ACM; update the certificate, or set a fallback certificate: %w", e.SSLCertificateID, fi.ValueOf(e.Name), err)
+	}
+
+	klog.Warningf("certificate %q of listener %q was not found, using the fallback certificate %q", e.SSLCertificateID, fi.ValueOf(e.Name), e.FallbackSSLCertificateID)
+	request.Certificates = []elbv2types.Certificate{{CertificateArn: aws.String(e.FallbackSSLCertificateID)}}
+	return cloud.ELBV2().CreateListener(ctx, request)
+}
+
+// sameVPC returns true if both tasks refer to the same VPC, comparing by ID when both IDs are known.
+func sameVPC(a, b *VPC) bool {
+	if a == b {
+		return true
+	}
+	if a.ID != nil && b.ID != nil {
+		return *a.ID == *b.ID
+	}
+	return fi.ValueOf(a.Name) == fi.ValueOf(b.Name)
+}
+
+// vpcDisplayName returns the ID of the VPC if known, otherwise its name.
+func vpcDisplayName(vpc *VPC) string {
+	if vpc.ID != nil {
+		return *vpc.ID
+	}
+	return fi.ValueOf(vpc.Name)
+}
+
+// updateAdditionalCertificates adds and removes the additional certificates of the listener to match desired.
+// The default certificate is set on the listener itself, and is not affected.
+func updateAdditionalCertificates(ctx context.Context, cloud awsup.AWSCloud, listenerArn string, actual, desired []string) error {
+	var added, removed []elbv2types.Certificate
+	for _, arn := range desired {
+		if !slices.Contains(actual, arn) {
+			added = append(added, elbv2types.Certificate{CertificateArn: aws.String(arn)})
+		}
+	}
+	for _, arn := range actual {
+		if !slices.Contains(desired, arn) {
+			removed = append(removed, elbv2types.Certificate{CertificateArn: aws.String(arn)})
+		}
+	}
+
+	if len(added) != 0 {
+		klog.V(2).Infof("Adding %d certificates to listener %q", len(added), listenerArn)
+		if _, err := cloud.ELBV2().AddListenerCertificates(ctx, &elbv2.AddListenerCertificatesInput{
+			ListenerArn:  aws.String(listenerArn),
+			Certificates: added,
+		}); err != nil {
+			return fmt.Errorf("adding certificates to listener %q: %w", listenerArn, err)
+		}
+	}
+	if len(removed) != 0 {
+		klog.V(2).Infof("Removing %d certificates from listener %q", len(removed), listenerArn)
+		if _, err := cloud.ELBV2().RemoveListenerCertificates(ctx, &elbv2.RemoveListenerCertificatesInput{
+			ListenerArn:  aws.String(listenerArn),
+			Certificates: removed,
+		}); err != nil {
+			return fmt.Errorf("removing certificates from listener %q: %w", listenerArn, err)
+		}
+	}
+	return nil
+}
+
+// listenerRequiresRecreate returns true if the changes can only be applied by recreating the listener.
+// Mutual TLS authentication is not modelled, as only Application Load Balancer listeners support it;
+// if it is added, changes to the trust store or its ignore-expiry flag should be applied with ModifyListener.
+// The certificate, SSL policy and target groups are changed in place; only a different port or protocol
+// requires a new listener, including a TCP listener that must be recreated to use TLS.
+// A TLS listener whose certificate is removed is downgraded to TCP in place.
+func listenerRequiresRecreate(a, e, changes *NetworkLoadBalancerListener) bool {
+	addsTLS := changes.SSLCertificateID != "" && a.SSLCertificateID == ""
+	return changes.Port != 0 || (changes.Protocol != "" && !listenerRemovesTLS(a, e)) || addsTLS
+}
+
+// listenerRemovesTLS returns true if the actual listener uses TLS, and the desired listener is plain TCP without a certificate.
+func listenerRemovesTLS(a, e *NetworkLoadBalancerListener) bool {
+	return a.Protocol == elbv2types.ProtocolEnumTls && a.SSLCertificateID != "" && e.SSLCertificateID == "" && e.protocol() == elbv2types.ProtocolEnumTcp
+}
+
+// updateTags applies the Tags of the task to an existing listener, removing the tags that are no longer wanted.
+// The cluster tags and the tags used internally by kops are left untouched.
+func (e *NetworkLoadBalancerListener) updateTags(ctx context.Context, t *awsup.AWSAPITarget, listenerArn string, actualTags map[string]string) error {
+	if err := t.AddELBV2Tags(listenerArn, e.listenerTags(t.Cloud.Tags())); err != nil {
+		return err
+	}
+	var removed []string
+	for k := range actualTags {
+		if _, found := e.Tags[k]; !found {
+			removed = append(removed, k)
+		}
+	}
+	if len(removed) != 0 {
+		slices.Sort(removed)
+		klog.V(2).Infof("Removing tags %v from listener %q", removed, listenerArn)
+		if _, err := t.Cloud.ELBV2().RemoveTags(ctx, &elbv2.RemoveTagsInput{
+			ResourceArns: []string{listenerArn},
+			TagKeys:      removed,
+		}); err != nil {
+			return fmt.Errorf("removing tags from listener %q: %w", listenerArn, err)
+		}
+	}
+	return nil
+}
+
+// updateProtection adds or removes the protection tag on an existing listener.
+func (e *NetworkLoadBalancerListener) updateProtection(ctx context.Context, t *awsup.AWSAPITarget, listenerArn string) error {
+	if e.Protected {
+		klog.V(2).Infof("Protecting listener %q", listenerArn)
+		if _, err := t.Cloud.ELBV2().AddTags(ctx, &elbv2.AddTagsInput{
+			ResourceArns: []string{listenerArn},
+			Tags:         awsup.ELBv2Tags(map[string]string{awsup.KopsProtectedTag: "true"}),
+		}); err != nil {
+			return fmt.Errorf("protecting listener %q: %w", listenerArn, err)
+		}
+	} else {
+		klog.V(2).Infof("Removing protection from listener %q", listenerArn)
+		if _, err := t.Cloud.ELBV2().RemoveTags(ctx, &elbv2.RemoveTagsInput{
+			ResourceArns: []string{listenerArn},
+			TagKeys:      []string{awsup.KopsProtectedTag},
+		}); err != nil {
+			return fmt.Errorf("removing protection from listener %q: %w", listenerArn, err)
+		}
+	}
+	return nil
+}
+
+// listenerTags returns the tags to apply to the listener: the Tags of the task, with the cluster tags taking precedence,
+// the protection tag if the listener is Protected, and the CorrelationID.
+func (e *NetworkLoadBalancerListener) listenerTags(clusterTags map[string]string) map[string]string {
+	tags := make(map[string]string)
+	for k, v := range e.Tags {
+		tags[k] = v
+	}
+	for k, v := range clusterTags {
+		tags[k] = v
+	}
+	if e.Protected {
+		tags[awsup.KopsProtectedTag] = "true"
+	}
+	if e.CorrelationID != "" {
+		tags[awsup.KopsListenerCorrelationIDTag] = e.CorrelationID
+	}
+	return tags
+}
+
+// buildCreateListenerInput builds the request to create a listener with the desired configuration on the given port.
+// The listener is tagged with the cluster tags, so that it can be found if it outlives the cluster.
+func (e *NetworkLoadBalancerListener) buildCreateListenerInput(loadBalancerArn string, port int, tags map[string]string) (*elbv2.CreateListenerInput, error) {
+	action, err := e.buildForwardAction()
+	if err != nil {
+		return nil, err
+	}
+	request := &elbv2.CreateListenerInput{
+		DefaultActions:  []elbv2types.Action{*action},
+		LoadBalancerArn: aws.String(loadBalancerArn),
+		Port:            aws.Int32(int32(port)),
+	}
+
+	if e.SSLCertificateID != "" {
+		request.Certificates = []elbv2types.Certificate{}
+		request.Certificates = append(request.Certificates, elbv2types.Certificate{
+			CertificateArn: aws.String(e.SSLCertificateID),
+		})
+		if e.SSLPolicy != "" {
+			request.SslPolicy = aws.String(e.SSLPolicy)
+		}
+		if e.ALPNPolicy != "" {
+			request.AlpnPolicy = []string{e.ALPNPolicy}
+		}
+	}
+	request.Protocol = e.protocol()
+	if len(tags) != 0 {
+		request.Tags = awsup.ELBv2Tags(tags)
+	}
+	return request, nil
+}
+
+// buildForwardAction builds the default forward action, to either TargetGroup or the weighted ForwardTargetGroups.
+// Only forward actions are supported: fixed-response actions, e.g. for a maintenance listener returning 503,
+// are limited to the HTTP/HTTPS listeners of Application Load Balancers, so every listener needs a target group.
+func (e *NetworkLoadBalancerListener) buildForwardAction() (*elbv2types.Action, error) {
+	if len(e.ForwardTargetGroups) == 0 {
 		if e.TargetGroup == nil {
-			return fi.RequiredField("TargetGroup")
+			return nil, fi.RequiredField("TargetGroup")
 		}
 		targetGroupARN := fi.ValueOf(e.TargetGroup.ARN)
 		if targetGroupARN == "" {
-			return fmt.Errorf("target group not yet created (arn not set)")
+			return nil, fmt.Errorf("target group not yet created (arn not set)")
 		}
-		request := &elbv2.CreateListenerInput{
-			DefaultActions: []elbv2types.Action{
-				{
-					TargetGroupArn: aws.String(targetGroupARN),
-					Type:           elbv2types.ActionTypeEnumForward,
-				},
-			},
-			LoadBalancerArn: aws.String(loadBalancerArn),
-			Port:            aws.Int32(int32(e.Port)),
+		return &elbv2types.Action{
+			TargetGroupArn: aws.String(targetGroupARN),
+			Type:           elbv2types.ActionTypeEnumForward,
+		}, nil
+	}
+
+	forwardConfig := &elbv2types.ForwardActionConfig{}
+	for _, w := range e.ForwardTargetGroups {
+		targetGroupARN := fi.ValueOf(w.TargetGroup.ARN)
+		if targetGroupARN == "" {
+			return nil, fmt.Errorf("target group %q not yet created (arn not set)", fi.ValueOf(w.TargetGroup.Name))
+		}
+		forwardConfig.TargetGroups = append(forwardConfig.TargetGroups, elbv2types.TargetGroupTuple{
+			TargetGroupArn: aws.String(targetGroupARN),
+			Weight:         aws.Int32(int32(w.Weight)),
+		})
+	}
+	if e.StickinessEnabled {
+		forwardConfig.TargetGroupStickinessConfig = &elbv2types.TargetGroupStickinessConfig{
+			Enabled: aws.Bool(true),
+		}
+		if e.StickinessDurationSeconds != nil {
+			forwardConfig.TargetGroupStickinessConfig.DurationSeconds = aws.Int32(int32(*e.StickinessDurationSeconds))
 		}
+	}
+	return &elbv2types.Action{
+		ForwardConfig: forwardConfig,
+		Type:          elbv2types.ActionTypeEnumForward,
+	}, nil
+}
 
-		if e.SSLCertificateID != "" {
-			request.Certificates = []elbv2types.Certificate{}
-			request.Certificates = append(request.Certificates, elbv2types.Certificate{
-				CertificateArn: aws.String(e.SSLCertificateID),
-			})
-			request.Protocol = elbv2types.ProtocolEnumTls
-			if e.SSLPolicy != "" {
-				request.SslPolicy = aws.String(e.SSLPolicy)
-			}
-		} else {
-			request.Protocol = elbv2types.ProtocolEnumTcp
+// swapListener replaces the existing listener without taking the port out of service until the new configuration is healthy.
+// A listener with the new configuration is created on the TemporaryPort, and once the target group is healthy
+// the existing listener is deleted and the temporary listener is moved to the listener Port.
+func (e *NetworkLoadBalancerListener) swapListener(ctx context.Context, t *awsup.AWSAPITarget, a *NetworkLoadBalancerListener, loadBalancerArn string) error {
+	listeners, err := awsup.ListELBV2ListenersWithTags(ctx, t.Cloud, loadBalancerArn)
+	if err != nil {
+		return err
+	}
+	for _, listener := range listeners {
+		if aws.ToInt32(listener.Listener.Port) != int32(e.TemporaryPort) {
+			continue
+		}
+		// Clean up any temporary listener left behind by an interrupted swap, but never a listener
+		// of another cluster or a protected one that merely happens to be on the temporary port
+		if !e.isLeftoverTemporaryListener(listener, t.Cloud.Tags()) {
+			return fmt.Errorf("temporary port %d of listener %q is in use by listener %q, which was not created by kops for this listener", e.TemporaryPort, fi.ValueOf(e.Name), listener.ARN())
 		}
+		klog.Warningf("deleting leftover temporary listener %q on port %d", listener.ARN(), e.TemporaryPort)
+		if _, err := t.Cloud.ELBV2().DeleteListener(ctx, &elbv2.DeleteListenerInput{ListenerArn: listener.Listener.ListenerArn}); err != nil {
+			return fmt.Errorf("deleting temporary listener on port %d: %w", e.TemporaryPort, err)
+		}
+	}
 
-		klog.V(2).Infof("Creating Listener for NLB with port %v", e.Port)
-		_, err := t.Cloud.ELBV2().CreateListener(ctx, request)
-		if err != nil {
-			return fmt.Errorf("creating listener for NLB on port %v: %w", e.Port, err)
+	request, err := e.buildCreateListenerInput(loadBalancerArn, e.TemporaryPort, e.listenerTags(t.Cloud.Tags()))
+	if err != nil {
+		return err
+	}
+	klog.V(2).Infof("Creating temporary Listener for NLB with port %v", e.TemporaryPort)
+	response, err := e.createListener(ctx, t.Cloud, request)
+	if err != nil {
+		return fmt.Errorf("creating temporary listener for NLB on port %v: %w", e.TemporaryPort, err)
+	}
+	temporaryListenerArn := response.Listeners[0].ListenerArn
+	if err := updateAdditionalCertificates(ctx, t.Cloud, aws.ToString(temporaryListenerArn), nil, e.AdditionalCertificates); err != nil {
+		return err
+	}
+
+	for _, targetGroup := range e.forwardedTargetGroups() {
+		if err := waitForTargetGroupHealthy(ctx, t.Cloud, targetGroup, e.WaitConfig); err != nil {
+			// Leave the existing listener in service
+			if _, deleteErr := t.Cloud.ELBV2().DeleteListener(ctx, &elbv2.DeleteListenerInput{ListenerArn: temporaryListenerArn}); deleteErr != nil {
+				klog.Warningf("failed to delete temporary listener %q: %v", aws.ToString(temporaryListenerArn), deleteErr)
+			}
+			return err
 		}
 	}
 
+	klog.Warningf("deleting ELB listener %q to swap in temporary listener %q", a.listenerArn, aws.ToString(temporaryListenerArn))
+	if err := a.deleteListener(ctx, t.Cloud, loadBalancerArn); err != nil {
+		return err
+	}
+
+	klog.V(2).Infof("Moving temporary Listener for NLB from port %v to port %v", e.TemporaryPort, e.Port)
+	if _, err := t.Cloud.ELBV2().ModifyListener(ctx, &elbv2.ModifyListenerInput{
+		ListenerArn: temporaryListenerArn,
+		Port:        aws.Int32(int32(e.Port)),
+	}); err != nil {
+		return fmt.Errorf("moving temporary listener to port %v: %w", e.Port, err)
+	}
+	e.listenerArn = aws.ToString(temporaryListenerArn)
+
 	return nil
 }
 
+// isLeftoverTemporaryListener returns true if the listener on the temporary port was left behind by an interrupted swap
+// of this listener, i.e. it carries the correlation ID of this listener.
+// Without a correlation ID, a listener with the cluster tags is a leftover unless it is protected
+// or carries the correlation ID of another listener.
+func (e *NetworkLoadBalancerListener) isLeftoverTemporaryListener(listener *awsup.ListenerInfo, clusterTags map[string]string) bool {
+	correlationID, found := awsup.FindELBV2Tag(listener.Tags, awsup.KopsListenerCorrelationIDTag)
+	if e.CorrelationID != "" {
+		return correlationID == e.CorrelationID
+	}
+	if found {
+		return false
+	}
+	return awsup.MatchesElbV2TagsExcluding(clusterTags, map[string]string{awsup.KopsProtectedTag: ""}, listener.Tags)
+}
+
 type terraformNetworkLoadBalancerListener struct {
 	LoadBalancer   *terraformWriter.Literal                     `cty:"load_balancer_arn"`
 	Port           int64                                        `cty:"port"`
 	Protocol       elbv2types.ProtocolEnum                      `cty:"protocol"`
 	CertificateARN *string                                      `cty:"certificate_arn"`
 	SSLPolicy      *string                                      `cty:"ssl_policy"`
+	ALPNPolicy     *string                                      `cty:"alpn_policy"`
 	DefaultAction  []terraformNetworkLoadBalancerListenerAction `cty:"default_action"`
+	Tags           map[string]string                            `cty:"tags"`
+}
+
+type terraformNetworkLoadBalancerListenerCertificate struct {
+	ListenerARN    *terraformWriter.Literal `cty:"listener_arn"`
+	CertificateARN *string                  `cty:"certificate_arn"`
 }
 
 type terraformNetworkLoadBalancerListenerAction struct {
-	Type           elbv2types.ActionTypeEnum `cty:"type"`
-	TargetGroupARN *terraformWriter.Literal  `cty:"target_group_arn"`
+	Type           elbv2types.ActionTypeEnum                    `cty:"type"`
+	TargetGroupARN *terraformWriter.Literal                     `cty:"target_group_arn"`
+	Forward        *terraformNetworkLoadBalancerListenerForward `cty:"forward"`
+}
+
+type terraformNetworkLoadBalancerListenerForward struct {
+	TargetGroups []terraformNetworkLoadBalancerListenerForwardTargetGroup `cty:"target_group"`
+	Stickiness   *terraformNetworkLoadBalancerListenerForwardStickiness   `cty:"stickiness"`
+}
+
+type terraformNetworkLoadBalancerListenerForwardTargetGroup struct {
+	ARN    *terraformWriter.Literal `cty:"arn"`
+	Weight int64                    `cty:"weight"`
+}
+
+type terraformNetworkLoadBalancerListenerForwardStickiness struct {
+	Enabled  bool   `cty:"enabled"`
+	Duration *int64 `cty:"duration"`
 }
 
 func (_ *NetworkLoadBalancerListener) RenderTerraform(t *terraform.TerraformTarget, a, e, changes *NetworkLoadBalancerListener) error {
-	if e.TargetGroup == nil {
-		return fi.RequiredField("TargetGroup")
+	action := terraformNetworkLoadBalancerListenerAction{
+		Type: elbv2types.ActionTypeEnumForward,
+	}
+	if len(e.ForwardTargetGroups) == 0 {
+		if e.TargetGroup == nil {
+			return fi.RequiredField("TargetGroup")
+		}
+		action.TargetGroupARN = e.TargetGroup.TerraformLink()
+	} else {
+		action.Forward = &terraformNetworkLoadBalancerListenerForward{}
+		for _, w := range e.ForwardTargetGroups {
+			action.Forward.TargetGroups = append(action.Forward.TargetGroups, terraformNetworkLoadBalancerListenerForwardTargetGroup{
+				ARN:    w.TargetGroup.TerraformLink(),
+				Weight: int64(w.Weight),
+			})
+		}
+		if e.StickinessEnabled {
+			action.Forward.Stickiness = &terraformNetworkLoadBalancerListenerForwardStickiness{
+				Enabled: true,
+			}
+			if e.StickinessDurationSeconds != nil {
+				action.Forward.Stickiness.Duration = fi.PtrTo(int64(*e.StickinessDurationSeconds))
+			}
+		}
 	}
 	listenerTF := &terraformNetworkLoadBalancerListener{
-		LoadBalancer: e.NetworkLoadBalancer.TerraformLink(),
-		Port:         int64(e.Port),
-		DefaultAction: []terraformNetworkLoadBalancerListenerAction{
-			{
-				Type:           elbv2types.ActionTypeEnumForward,
-				TargetGroupARN: e.TargetGroup.TerraformLink(),
-			},
-		},
+		LoadBalancer:  e.NetworkLoadBalancer.TerraformLink(),
+		Port:          int64(e.Port),
+		DefaultAction: []terraformNetworkLoadBalancerListenerAction{action},
 	}
 	if e.SSLCertificateID != "" {
 		listenerTF.CertificateARN = &e.SSLCertificateID
-		listenerTF.Protocol = elbv2types.ProtocolEnumTls
 		if e.SSLPolicy != "" {
 			listenerTF.SSLPolicy = &e.SSLPolicy
 		}
-	} else {
-		listenerTF.Protocol = elbv2types.ProtocolEnumTcp
+		if e.ALPNPolicy != "" {
+			listenerTF.ALPNPolicy = &e.ALPNPolicy
+		}
+	}
+	listenerTF.Protocol = e.protocol()
+	// The same tags as RenderAWS, so that listeners managed by terraform can be attributed to the cluster
+	if tags := e.listenerTags(t.Cloud.(awsup.AWSCloud).Tags()); len(tags) != 0 {
+		listenerTF.Tags = tags
 	}
 
 	err := t.RenderResource("aws_lb_listener", e.TerraformName(), listenerTF)
@@ -254,9 +1372,43 @@ func (_ *NetworkLoadBalancerListener) RenderTerraform(t *terraform.TerraformTarg
 		return err
 	}
 
+	// The default certificate is set on the listener, terraform models each additional certificate as a separate resource
+	names := listenerCertificateTerraformNames(e.AdditionalCertificates)
+	for _, arn := range e.AdditionalCertificates {
+		certificateTF := &terraformNetworkLoadBalancerListenerCertificate{
+			ListenerARN:    terraformWriter.LiteralProperty("aws_lb_listener", e.TerraformName(), "arn"),
+			CertificateARN: fi.PtrTo(arn),
+		}
+		if err := t.RenderResource("aws_lb_listener_certificate", e.TerraformName()+"-"+names[arn], certificateTF); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// listenerCertificateTerraformNames returns the suffix of the terraform name of each additional certificate.
+// Certificates are named after their ID rather than their position, so that removing a certificate doesn't replace the others;
+// certificates sharing an ID, e.g. IAM server certificates with the same name under different paths, also get a hash of their ARN.
+func listenerCertificateTerraformNames(arns []string) map[string]string {
+	ids := make(map[string]string, len(arns))
+	count := make(map[string]int)
+	for _, arn := range arns {
+		id := arn[strings.LastIndex(arn, "/")+1:]
+		ids[arn] = id
+		count[id]++
+	}
+	names := make(map[string]string, len(arns))
+	for arn, id := range ids {
+		if count[id] > 1 {
+			hash := sha256.Sum256([]byte(arn))
+			id += "-" + hex.EncodeToString(hash[:])[:8]
+		}
+		names[arn] = id
+	}
+	return names
+}
+
 func (e *NetworkLoadBalancerListener) TerraformName() string {
 	tfName := fmt.Sprintf("%v-%v", e.NetworkLoadBalancer.TerraformName(), e.Port)
 	return tfName
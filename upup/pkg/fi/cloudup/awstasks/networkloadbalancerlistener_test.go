@@ -0,0 +1,4393 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awstasks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/smithy-go"
+	"k8s.io/kops/cloudmock/aws/mockec2"
+	"k8s.io/kops/cloudmock/aws/mockelbv2"
+	"k8s.io/kops/cloudmock/aws/mockiam"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/assets"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+	"k8s.io/kops/upup/pkg/fi/cloudup/terraform"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+// buildNLBTasks builds the tasks for a network load balancer in a new VPC and subnet,
+// to which tests can attach target groups and listeners.
+func buildNLBTasks() map[string]fi.CloudupTask {
+	vpc1 := &VPC{
+		Name:      s("vpc1"),
+		Lifecycle: fi.LifecycleSync,
+		CIDR:      s("172.20.0.0/16"),
+		Tags:      map[string]string{"Name": "vpc1"},
+	}
+	subnet1 := &Subnet{
+		Name:                s("subnet1"),
+		Lifecycle:           fi.LifecycleSync,
+		VPC:                 vpc1,
+		CIDR:                s("172.20.1.0/24"),
+		ResourceBasedNaming: fi.PtrTo(true),
+		Tags:                map[string]string{"Name": "subnet1"},
+	}
+	nlb1 := &NetworkLoadBalancer{
+		Name:                 s("nlb1"),
+		Lifecycle:            fi.LifecycleSync,
+		LoadBalancerBaseName: s("nlb1"),
+		VPC:                  vpc1,
+		SubnetMappings:       []*SubnetMapping{{Subnet: subnet1}},
+		Scheme:               elbv2types.LoadBalancerSchemeEnumInternal,
+		Type:                 elbv2types.LoadBalancerTypeEnumNetwork,
+		Tags:                 map[string]string{"Name": "nlb1"},
+	}
+
+	return map[string]fi.CloudupTask{
+		"vpc1":    vpc1,
+		"subnet1": subnet1,
+		"nlb1":    nlb1,
+	}
+}
+
+// runNLBTasks runs the tasks like runTasks, but with a cluster, as the NetworkLoadBalancer task reads the cluster subnets.
+func runNLBTasks(t *testing.T, cloud awsup.AWSCloud, allTasks map[string]fi.CloudupTask) {
+	t.Helper()
+	ctx := context.TODO()
+
+	target := &awsup.AWSAPITarget{
+		Cloud: cloud,
+	}
+
+	context, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, target, &kops.Cluster{}, cloud, nil, nil, nil, allTasks)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+
+	if err := context.RunTasks(testRunTasksOptions); err != nil {
+		t.Fatalf("unexpected error during Run: %v", err)
+	}
+}
+
+// checkNLBNoChanges checks that the tasks have no changes like checkNoChanges, but with a cluster, as for runNLBTasks.
+func checkNLBNoChanges(t *testing.T, ctx context.Context, cloud fi.Cloud, allTasks map[string]fi.CloudupTask) {
+	t.Helper()
+	cluster := &kops.Cluster{
+		Spec: kops.ClusterSpec{
+			KubernetesVersion: "v1.9.0",
+		},
+	}
+	assetBuilder := assets.NewAssetBuilder(vfs.Context, cluster.Spec.Assets, cluster.Spec.KubernetesVersion, false)
+	target := fi.NewCloudupDryRunTarget(assetBuilder, os.Stderr)
+	context, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, target, cluster, cloud, nil, nil, nil, allTasks)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+
+	if err := context.RunTasks(testRunTasksOptions); err != nil {
+		t.Fatalf("unexpected error during Run: %v", err)
+	}
+
+	if target.HasChanges() {
+		var b bytes.Buffer
+		if err := target.PrintReport(allTasks, &b); err != nil {
+			t.Fatalf("error building report: %v", err)
+		}
+		t.Fatalf("Target had changes after executing: %v", b.String())
+	}
+}
+
+func TestNetworkLoadBalancerListenerForwardToALB(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func() map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		tg1 := &TargetGroup{
+			Name:                s("tg1"),
+			Lifecycle:           fi.LifecycleSync,
+			VPC:                 nlb1.VPC,
+			Tags:                map[string]string{"Name": "tg1"},
+			Protocol:            elbv2types.ProtocolEnumTcp,
+			Port:                fi.PtrTo(int32(443)),
+			TargetType:          elbv2types.TargetTypeEnumAlb,
+			Interval:            fi.PtrTo(int32(10)),
+			HealthyThreshold:    fi.PtrTo(int32(2)),
+			UnhealthyThreshold:  fi.PtrTo(int32(2)),
+			HealthCheckProtocol: elbv2types.ProtocolEnumHttps,
+		}
+		listener1 := &NetworkLoadBalancerListener{
+			Name:                s("listener1"),
+			Lifecycle:           fi.LifecycleSync,
+			NetworkLoadBalancer: nlb1,
+			Port:                443,
+			TargetGroup:         tg1,
+		}
+		allTasks["tg1"] = tg1
+		allTasks["listener1"] = listener1
+		return allTasks
+	}
+
+	{
+		allTasks := buildTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+		tg1 := allTasks["tg1"].(*TargetGroup)
+
+		runNLBTasks(t, cloud, allTasks)
+
+		response, err := c.DescribeTargetGroups(ctx, &elbv2.DescribeTargetGroupsInput{})
+		if err != nil {
+			t.Fatalf("error describing target groups: %v", err)
+		}
+		if len(response.TargetGroups) != 1 {
+			t.Fatalf("expected exactly one target group, found %v", response.TargetGroups)
+		}
+		if response.TargetGroups[0].TargetType != elbv2types.TargetTypeEnumAlb {
+			t.Errorf("unexpected target type %q", response.TargetGroups[0].TargetType)
+		}
+
+		listeners, err := c.DescribeListeners(ctx, &elbv2.DescribeListenersInput{
+			LoadBalancerArn: fi.PtrTo(nlb1.loadBalancerArn),
+		})
+		if err != nil {
+			t.Fatalf("error describing listeners: %v", err)
+		}
+		if len(listeners.Listeners) != 1 {
+			t.Fatalf("expected exactly one listener, found %v", listeners.Listeners)
+		}
+		l := listeners.Listeners[0]
+		if l.Protocol != elbv2types.ProtocolEnumTcp {
+			t.Errorf("unexpected listener protocol %q", l.Protocol)
+		}
+		action := l.DefaultActions[0]
+		if action.Type != elbv2types.ActionTypeEnumForward || fi.ValueOf(action.TargetGroupArn) != fi.ValueOf(tg1.ARN) {
+			t.Errorf("unexpected listener default action %+v", action)
+		}
+	}
+
+	{
+		allTasks := buildTasks()
+		checkNLBNoChanges(t, ctx, cloud, allTasks)
+	}
+}
+
+func TestNetworkLoadBalancerListenerCheckChangesALBTarget(t *testing.T) {
+	tg := &TargetGroup{
+		Name:       s("tg"),
+		Protocol:   elbv2types.ProtocolEnumTcp,
+		TargetType: elbv2types.TargetTypeEnumAlb,
+	}
+
+	grid := []struct {
+		name        string
+		listener    *NetworkLoadBalancerListener
+		expectError bool
+	}{
+		{
+			name: "tcp listener on nlb",
+			listener: &NetworkLoadBalancerListener{
+				Name:                s("listener"),
+				NetworkLoadBalancer: &NetworkLoadBalancer{Type: elbv2types.LoadBalancerTypeEnumNetwork},
+				Port:                443,
+				TargetGroup:         tg,
+			},
+		},
+		{
+			name: "tls listener on nlb",
+			listener: &NetworkLoadBalancerListener{
+				Name:                s("listener"),
+				NetworkLoadBalancer: &NetworkLoadBalancer{Type: elbv2types.LoadBalancerTypeEnumNetwork},
+				Port:                443,
+				TargetGroup:         tg,
+				SSLCertificateID:    "arn:aws-test:acm:us-test-1:000000000000:certificate/1",
+			},
+			expectError: true,
+		},
+		{
+			name: "tcp listener on gateway load balancer",
+			listener: &NetworkLoadBalancerListener{
+				Name:                s("listener"),
+				NetworkLoadBalancer: &NetworkLoadBalancer{Type: elbv2types.LoadBalancerTypeEnumGateway},
+				Port:                443,
+				TargetGroup:         tg,
+			},
+			expectError: true,
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			err := (&NetworkLoadBalancerListener{}).CheckChanges(nil, g.listener, g.listener)
+			if g.expectError && err == nil {
+				t.Errorf("expected error, got none")
+			}
+			if !g.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestNetworkLoadBalancerListenerCheckChangesTargetGroupVPC(t *testing.T) {
+	vpc1 := &VPC{Name: s("vpc1"), ID: s("vpc-1")}
+	nlb := &NetworkLoadBalancer{Name: s("nlb1"), VPC: vpc1}
+
+	grid := []struct {
+		name        string
+		targetGroup *TargetGroup
+		expectError bool
+	}{
+		{
+			name:        "same vpc task",
+			targetGroup: &TargetGroup{Name: s("tg1"), VPC: vpc1},
+		},
+		{
+			name:        "same vpc id",
+			targetGroup: &TargetGroup{Name: s("tg1"), VPC: &VPC{Name: s("shared-vpc"), ID: s("vpc-1")}},
+		},
+		{
+			name:        "other vpc",
+			targetGroup: &TargetGroup{Name: s("tg1"), VPC: &VPC{Name: s("vpc2"), ID: s("vpc-2")}},
+			expectError: true,
+		},
+		{
+			name:        "shared target group without vpc",
+			targetGroup: &TargetGroup{Name: s("tg1"), Shared: fi.PtrTo(true)},
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			listener := &NetworkLoadBalancerListener{
+				Name:                s("listener"),
+				NetworkLoadBalancer: nlb,
+				Port:                443,
+				TargetGroup:         g.targetGroup,
+			}
+			err := (&NetworkLoadBalancerListener{}).CheckChanges(nil, listener, listener)
+			if g.expectError {
+				if err == nil {
+					t.Fatalf("expected error, got none")
+				}
+				if !strings.Contains(err.Error(), "vpc-2") || !strings.Contains(err.Error(), "vpc-1") {
+					t.Errorf("expected error to name both VPCs, got %v", err)
+				}
+			}
+			if !g.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestNetworkLoadBalancerListenerSwap(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = c
+
+	waitConfig := &ELBV2WaitConfig{
+		TargetHealthyTimeout:      time.Second,
+		TargetHealthyPollInterval: 10 * time.Millisecond,
+	}
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(sslCertificateID string) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		tg1 := &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                nlb1.VPC,
+			Tags:               map[string]string{"Name": "tg1"},
+			Protocol:           elbv2types.ProtocolEnumTcp,
+			Port:               fi.PtrTo(int32(443)),
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+		}
+		listener1 := &NetworkLoadBalancerListener{
+			Name:                s("listener1"),
+			Lifecycle:           fi.LifecycleSync,
+			NetworkLoadBalancer: nlb1,
+			Port:                443,
+			TargetGroup:         tg1,
+			SSLCertificateID:    sslCertificateID,
+			TemporaryPort:       8444,
+			WaitConfig:          waitConfig,
+		}
+		allTasks["tg1"] = tg1
+		allTasks["listener1"] = listener1
+		return allTasks
+	}
+
+	describeListeners := func(loadBalancerArn string) []elbv2types.Listener {
+		t.Helper()
+		response, err := c.DescribeListeners(ctx, &elbv2.DescribeListenersInput{
+			LoadBalancerArn: fi.PtrTo(loadBalancerArn),
+		})
+		if err != nil {
+			t.Fatalf("error describing listeners: %v", err)
+		}
+		return response.Listeners
+	}
+
+	var loadBalancerArn, originalListenerArn string
+	{
+		allTasks := buildTasks("")
+		runNLBTasks(t, cloud, allTasks)
+
+		loadBalancerArn = allTasks["nlb1"].(*NetworkLoadBalancer).loadBalancerArn
+		tg1 := allTasks["tg1"].(*TargetGroup)
+		if _, err := c.RegisterTargets(ctx, &elbv2.RegisterTargetsInput{
+			TargetGroupArn: tg1.ARN,
+			Targets:        []elbv2types.TargetDescription{{Id: s("i-12345678")}},
+		}); err != nil {
+			t.Fatalf("error registering targets: %v", err)
+		}
+
+		listeners := describeListeners(loadBalancerArn)
+		if len(listeners) != 1 {
+			t.Fatalf("expected exactly one listener, found %v", listeners)
+		}
+		originalListenerArn = fi.ValueOf(listeners[0].ListenerArn)
+	}
+
+	// Switching to TLS requires recreating the listener, which should swap in a listener created on the temporary port
+	var swappedListenerArn string
+	{
+		allTasks := buildTasks("arn:aws:acm:us-east-1:000000000000:certificate/1")
+		runNLBTasks(t, cloud, allTasks)
+
+		listeners := describeListeners(loadBalancerArn)
+		if len(listeners) != 1 {
+			t.Fatalf("expected exactly one listener after swap, found %v", listeners)
+		}
+		l := listeners[0]
+		swappedListenerArn = fi.ValueOf(l.ListenerArn)
+		if swappedListenerArn == originalListenerArn {
+			t.Errorf("expected listener to be replaced, still have %q", originalListenerArn)
+		}
+		if fi.ValueOf(l.Port) != 443 {
+			t.Errorf("expected swapped listener on port 443, got %d", fi.ValueOf(l.Port))
+		}
+		if l.Protocol != elbv2types.ProtocolEnumTls {
+			t.Errorf("expected swapped listener to use TLS, got %q", l.Protocol)
+		}
+	}
+
+	{
+		allTasks := buildTasks("arn:aws:acm:us-east-1:000000000000:certificate/1")
+		checkNLBNoChanges(t, ctx, cloud, allTasks)
+	}
+
+	// If the target group never becomes healthy, the existing listener must stay in service
+	{
+		response, err := c.CreateTargetGroup(ctx, &elbv2.CreateTargetGroupInput{
+			Name:     s("tg2"),
+			Protocol: elbv2types.ProtocolEnumTcp,
+			Port:     fi.PtrTo(int32(443)),
+		})
+		if err != nil {
+			t.Fatalf("error creating target group: %v", err)
+		}
+
+		a := &NetworkLoadBalancerListener{listenerArn: swappedListenerArn}
+		e := &NetworkLoadBalancerListener{
+			Name:          s("listener1"),
+			Port:          443,
+			TargetGroup:   &TargetGroup{Name: s("tg2"), ARN: response.TargetGroups[0].TargetGroupArn},
+			TemporaryPort: 8444,
+			WaitConfig:    waitConfig,
+		}
+		err = e.swapListener(ctx, &awsup.AWSAPITarget{Cloud: cloud}, a, loadBalancerArn)
+		if err == nil {
+			t.Fatalf("expected error swapping to unhealthy target group")
+		}
+
+		listeners := describeListeners(loadBalancerArn)
+		if len(listeners) != 1 || fi.ValueOf(listeners[0].ListenerArn) != swappedListenerArn {
+			t.Errorf("expected only the existing listener %q to remain, found %v", swappedListenerArn, listeners)
+		}
+	}
+}
+
+func TestNetworkLoadBalancerListenerSwapLeftoverTemporaryListener(t *testing.T) {
+	ctx := context.TODO()
+
+	clusterTags := map[string]string{"KubernetesCluster": "example.com"}
+	waitConfig := &ELBV2WaitConfig{
+		TargetHealthyTimeout:      time.Second,
+		TargetHealthyPollInterval: 10 * time.Millisecond,
+	}
+
+	grid := []struct {
+		name          string
+		correlationID string
+		leftoverTags  map[string]string
+		expectDeleted bool
+	}{
+		{
+			name:          "same correlation ID",
+			correlationID: "api-443",
+			leftoverTags:  map[string]string{awsup.KopsListenerCorrelationIDTag: "api-443", awsup.KopsProtectedTag: "true"},
+			expectDeleted: true,
+		},
+		{
+			name:          "cluster tags without correlation ID",
+			correlationID: "api-443",
+			leftoverTags:  map[string]string{"KubernetesCluster": "example.com"},
+		},
+		{
+			name:          "cluster tags of another listener",
+			correlationID: "api-443",
+			leftoverTags:  map[string]string{"KubernetesCluster": "example.com", awsup.KopsListenerCorrelationIDTag: "api-8444"},
+		},
+		{
+			name:          "another listener",
+			correlationID: "api-443",
+			leftoverTags:  map[string]string{awsup.KopsListenerCorrelationIDTag: "api-8443"},
+		},
+		{
+			name:          "no correlation ID, cluster tags",
+			leftoverTags:  map[string]string{"KubernetesCluster": "example.com"},
+			expectDeleted: true,
+		},
+		{
+			name:         "no correlation ID, cluster tags of another listener",
+			leftoverTags: map[string]string{"KubernetesCluster": "example.com", awsup.KopsListenerCorrelationIDTag: "api-8444"},
+		},
+		{
+			name:         "no correlation ID, protected",
+			leftoverTags: map[string]string{"KubernetesCluster": "example.com", awsup.KopsProtectedTag: "true"},
+		},
+		{
+			name:         "no correlation ID, another cluster",
+			leftoverTags: map[string]string{"KubernetesCluster": "other.example.com"},
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			mockCloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+			ec2Client := &mockec2.MockEC2{}
+			mockCloud.MockEC2 = ec2Client
+			c := &mockelbv2.MockELBV2{EC2: ec2Client}
+			mockCloud.MockELBV2 = c
+			cloud := mockCloud.WithTags(clusterTags)
+
+			lb, err := c.CreateLoadBalancer(ctx, &elbv2.CreateLoadBalancerInput{
+				Name: s("nlb1"),
+				Type: elbv2types.LoadBalancerTypeEnumNetwork,
+			})
+			if err != nil {
+				t.Fatalf("error creating load balancer: %v", err)
+			}
+			loadBalancerArn := fi.ValueOf(lb.LoadBalancers[0].LoadBalancerArn)
+			tg, err := c.CreateTargetGroup(ctx, &elbv2.CreateTargetGroupInput{
+				Name:     s("tg1"),
+				Protocol: elbv2types.ProtocolEnumTcp,
+				Port:     fi.PtrTo(int32(443)),
+			})
+			if err != nil {
+				t.Fatalf("error creating target group: %v", err)
+			}
+			targetGroupArn := tg.TargetGroups[0].TargetGroupArn
+			if _, err := c.RegisterTargets(ctx, &elbv2.RegisterTargetsInput{
+				TargetGroupArn: targetGroupArn,
+				Targets:        []elbv2types.TargetDescription{{Id: s("i-12345678")}},
+			}); err != nil {
+				t.Fatalf("error registering targets: %v", err)
+			}
+
+			createListener := func(port int32, tags map[string]string) string {
+				t.Helper()
+				response, err := c.CreateListener(ctx, &elbv2.CreateListenerInput{
+					LoadBalancerArn: fi.PtrTo(loadBalancerArn),
+					Port:            fi.PtrTo(port),
+					Protocol:        elbv2types.ProtocolEnumTcp,
+					DefaultActions:  []elbv2types.Action{{Type: elbv2types.ActionTypeEnumForward, TargetGroupArn: targetGroupArn}},
+					Tags:            awsup.ELBv2Tags(tags),
+				})
+				if err != nil {
+					t.Fatalf("error creating listener: %v", err)
+				}
+				return fi.ValueOf(response.Listeners[0].ListenerArn)
+			}
+			listenerArn := createListener(443, clusterTags)
+			leftoverArn := createListener(8444, g.leftoverTags)
+
+			a := &NetworkLoadBalancerListener{listenerArn: listenerArn}
+			e := &NetworkLoadBalancerListener{
+				Name:             s("listener1"),
+				Port:             443,
+				TargetGroup:      &TargetGroup{Name: s("tg1"), ARN: targetGroupArn},
+				SSLCertificateID: "arn:aws:acm:us-east-1:000000000000:certificate/1",
+				TemporaryPort:    8444,
+				CorrelationID:    g.correlationID,
+				WaitConfig:       waitConfig,
+			}
+			err = e.swapListener(ctx, &awsup.AWSAPITarget{Cloud: cloud}, a, loadBalancerArn)
+
+			response, describeErr := c.DescribeListeners(ctx, &elbv2.DescribeListenersInput{LoadBalancerArn: fi.PtrTo(loadBalancerArn)})
+			if describeErr != nil {
+				t.Fatalf("error describing listeners: %v", describeErr)
+			}
+			found := false
+			for _, listener := range response.Listeners {
+				if fi.ValueOf(listener.ListenerArn) == leftoverArn {
+					found = true
+				}
+			}
+
+			if g.expectDeleted {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if found {
+					t.Errorf("expected leftover temporary listener %q to be deleted", leftoverArn)
+				}
+			} else {
+				if err == nil || !strings.Contains(err.Error(), leftoverArn) {
+					t.Errorf("expected an error naming listener %q, got %v", leftoverArn, err)
+				}
+				if !found {
+					t.Errorf("expected listener %q on the temporary port to be kept", leftoverArn)
+				}
+			}
+		})
+	}
+}
+
+func TestNetworkLoadBalancerListenerFindMultipleDefaultActions(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	c := &mockelbv2.MockELBV2{}
+	cloud.MockELBV2 = c
+
+	lb, err := c.CreateLoadBalancer(ctx, &elbv2.CreateLoadBalancerInput{
+		Name: s("nlb1"),
+		Type: elbv2types.LoadBalancerTypeEnumNetwork,
+	})
+	if err != nil {
+		t.Fatalf("error creating load balancer: %v", err)
+	}
+	loadBalancerArn := fi.ValueOf(lb.LoadBalancers[0].LoadBalancerArn)
+
+	listeners := map[int32][]elbv2types.Action{
+		// An authenticate-then-forward chain
+		443: {
+			{Type: elbv2types.ActionTypeEnumAuthenticateOidc, Order: fi.PtrTo(int32(1))},
+			{Type: elbv2types.ActionTypeEnumForward, Order: fi.PtrTo(int32(2)), TargetGroupArn: s("tg-443")},
+		},
+		// A forward expressed only through a ForwardConfig
+		8443: {
+			{
+				Type: elbv2types.ActionTypeEnumForward,
+				ForwardConfig: &elbv2types.ForwardActionConfig{
+					TargetGroups: []elbv2types.TargetGroupTuple{{TargetGroupArn: s("tg-8443")}},
+				},
+			},
+		},
+		// No forward action at all
+		9443: {
+			{Type: elbv2types.ActionTypeEnumFixedResponse},
+		},
+	}
+	for port, actions := range listeners {
+		if _, err := c.CreateListener(ctx, &elbv2.CreateListenerInput{
+			LoadBalancerArn: &loadBalancerArn,
+			Port:            fi.PtrTo(port),
+			Protocol:        elbv2types.ProtocolEnumTcp,
+			DefaultActions:  actions,
+		}); err != nil {
+			t.Fatalf("error creating listener: %v", err)
+		}
+	}
+
+	cloudupContext, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, &awsup.AWSAPITarget{Cloud: cloud}, &kops.Cluster{}, cloud, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+
+	grid := []struct {
+		port             int
+		expectedTGArn    string
+		expectedNoTarget bool
+	}{
+		{port: 443, expectedTGArn: "tg-443"},
+		{port: 8443, expectedTGArn: "tg-8443"},
+		{port: 9443, expectedNoTarget: true},
+	}
+	for _, g := range grid {
+		e := &NetworkLoadBalancerListener{
+			Name:                s("listener"),
+			NetworkLoadBalancer: &NetworkLoadBalancer{loadBalancerArn: loadBalancerArn},
+			Port:                g.port,
+		}
+		actual, err := e.Find(cloudupContext)
+		if err != nil {
+			t.Fatalf("unexpected error finding listener on port %d: %v", g.port, err)
+		}
+		if actual == nil {
+			t.Fatalf("listener on port %d not found", g.port)
+		}
+		if g.expectedNoTarget {
+			if actual.TargetGroup != nil {
+				t.Errorf("expected no target group for listener on port %d, got %v", g.port, fi.ValueOf(actual.TargetGroup.ARN))
+			}
+			continue
+		}
+		if actual.TargetGroup == nil || fi.ValueOf(actual.TargetGroup.ARN) != g.expectedTGArn {
+			t.Errorf("unexpected target group for listener on port %d: %+v", g.port, actual.TargetGroup)
+		}
+	}
+}
+
+func TestNetworkLoadBalancerListenerFindDuplicatePort(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	c := &mockelbv2.MockELBV2{}
+	cloud.MockELBV2 = c
+
+	lb, err := c.CreateLoadBalancer(ctx, &elbv2.CreateLoadBalancerInput{
+		Name: s("nlb1"),
+		Type: elbv2types.LoadBalancerTypeEnumNetwork,
+	})
+	if err != nil {
+		t.Fatalf("error creating load balancer: %v", err)
+	}
+	loadBalancerArn := fi.ValueOf(lb.LoadBalancers[0].LoadBalancerArn)
+
+	// Two listeners on the same port, as left behind by an interrupted update
+	listenerArns := make(map[string]string)
+	for _, targetGroupArn := range []string{"tg-a", "tg-b"} {
+		response, err := c.CreateListener(ctx, &elbv2.CreateListenerInput{
+			LoadBalancerArn: &loadBalancerArn,
+			Port:            fi.PtrTo(int32(443)),
+			Protocol:        elbv2types.ProtocolEnumTcp,
+			DefaultActions:  []elbv2types.Action{{Type: elbv2types.ActionTypeEnumForward, TargetGroupArn: s(targetGroupArn)}},
+		})
+		if err != nil {
+			t.Fatalf("error creating listener: %v", err)
+		}
+		listenerArns[targetGroupArn] = fi.ValueOf(response.Listeners[0].ListenerArn)
+	}
+
+	cloudupContext, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, &awsup.AWSAPITarget{Cloud: cloud}, &kops.Cluster{}, cloud, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+
+	grid := []struct {
+		name                string
+		targetGroupArn      string
+		expectedListenerArn string
+	}{
+		{name: "forwards to first", targetGroupArn: "tg-a", expectedListenerArn: listenerArns["tg-a"]},
+		{name: "forwards to second", targetGroupArn: "tg-b", expectedListenerArn: listenerArns["tg-b"]},
+		{name: "forwards to neither", targetGroupArn: "tg-c", expectedListenerArn: min(listenerArns["tg-a"], listenerArns["tg-b"])},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			e := &NetworkLoadBalancerListener{
+				Name:                s("listener"),
+				NetworkLoadBalancer: &NetworkLoadBalancer{loadBalancerArn: loadBalancerArn},
+				Port:                443,
+				TargetGroup:         &TargetGroup{Name: s(g.targetGroupArn), ARN: s(g.targetGroupArn)},
+			}
+			actual, err := e.Find(cloudupContext)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual == nil {
+				t.Fatalf("listener not found")
+			}
+			if actual.listenerArn != g.expectedListenerArn {
+				t.Errorf("expected listener %q, got %q", g.expectedListenerArn, actual.listenerArn)
+			}
+		})
+	}
+}
+
+// throttlingELBV2 throttles the first DescribeListeners requests.
+type throttlingELBV2 struct {
+	*mockelbv2.MockELBV2
+
+	throttles             int
+	describeListenerCalls int
+}
+
+func (m *throttlingELBV2) DescribeListeners(ctx context.Context, request *elbv2.DescribeListenersInput, optFns ...func(*elbv2.Options)) (*elbv2.DescribeListenersOutput, error) {
+	m.describeListenerCalls++
+	if m.describeListenerCalls <= m.throttles {
+		return nil, &smithy.GenericAPIError{Code: "Throttling", Message: "Rate exceeded"}
+	}
+	return m.MockELBV2.DescribeListeners(ctx, request, optFns...)
+}
+
+func TestNetworkLoadBalancerListenerFindThrottled(t *testing.T) {
+	ctx := context.TODO()
+
+	grid := []struct {
+		name        string
+		attempts    int
+		expectError bool
+	}{
+		{name: "retried until success", attempts: 3},
+		{name: "too many throttles", attempts: 2, expectError: true},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+			c := &throttlingELBV2{MockELBV2: &mockelbv2.MockELBV2{}, throttles: 2}
+			cloud.MockELBV2 = c
+
+			lb, err := c.CreateLoadBalancer(ctx, &elbv2.CreateLoadBalancerInput{
+				Name: s("nlb1"),
+				Type: elbv2types.LoadBalancerTypeEnumNetwork,
+			})
+			if err != nil {
+				t.Fatalf("error creating load balancer: %v", err)
+			}
+			loadBalancerArn := fi.ValueOf(lb.LoadBalancers[0].LoadBalancerArn)
+			if _, err := c.CreateListener(ctx, &elbv2.CreateListenerInput{
+				LoadBalancerArn: &loadBalancerArn,
+				Port:            fi.PtrTo(int32(443)),
+				Protocol:        elbv2types.ProtocolEnumTcp,
+				DefaultActions:  []elbv2types.Action{{Type: elbv2types.ActionTypeEnumForward, TargetGroupArn: s("tg-443")}},
+			}); err != nil {
+				t.Fatalf("error creating listener: %v", err)
+			}
+
+			cloudupContext, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, &awsup.AWSAPITarget{Cloud: cloud}, &kops.Cluster{}, cloud, nil, nil, nil, nil)
+			if err != nil {
+				t.Fatalf("error building context: %v", err)
+			}
+
+			e := &NetworkLoadBalancerListener{
+				Name:                s("listener"),
+				NetworkLoadBalancer: &NetworkLoadBalancer{loadBalancerArn: loadBalancerArn},
+				Port:                443,
+				WaitConfig: &ELBV2WaitConfig{
+					ThrottleRetryBaseDelay: time.Millisecond,
+					ThrottleRetryAttempts:  g.attempts,
+				},
+			}
+			actual, err := e.Find(cloudupContext)
+			if g.expectError {
+				if err == nil {
+					t.Fatalf("expected an error after %d attempts", g.attempts)
+				}
+				if c.describeListenerCalls != g.attempts {
+					t.Errorf("expected %d DescribeListeners calls, got %d", g.attempts, c.describeListenerCalls)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual == nil {
+				t.Fatalf("listener not found")
+			}
+			if c.describeListenerCalls != 3 {
+				t.Errorf("expected 3 DescribeListeners calls, got %d", c.describeListenerCalls)
+			}
+		})
+	}
+}
+
+func TestNetworkLoadBalancerListenerProtected(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func() map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		tg1 := &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                nlb1.VPC,
+			Tags:               map[string]string{"Name": "tg1"},
+			Protocol:           elbv2types.ProtocolEnumTcp,
+			Port:               fi.PtrTo(int32(443)),
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+		}
+		listener1 := &NetworkLoadBalancerListener{
+			Name:                s("listener1"),
+			Lifecycle:           fi.LifecycleSync,
+			NetworkLoadBalancer: nlb1,
+			Port:                443,
+			TargetGroup:         tg1,
+			Protected:           true,
+		}
+		allTasks["tg1"] = tg1
+		allTasks["listener1"] = listener1
+		return allTasks
+	}
+
+	allTasks := buildTasks()
+	runNLBTasks(t, cloud, allTasks)
+	checkNLBNoChanges(t, ctx, cloud, buildTasks())
+
+	nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+	protected, err := awsup.FindProtectedELBV2Listeners(ctx, cloud, nlb1.loadBalancerArn)
+	if err != nil {
+		t.Fatalf("error finding protected listeners: %v", err)
+	}
+	if len(protected) != 1 {
+		t.Fatalf("expected one protected listener, found %v", protected)
+	}
+
+	cloudupContext, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, &awsup.AWSAPITarget{Cloud: cloud}, &kops.Cluster{}, cloud, nil, nil, nil, allTasks)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+
+	// Switching to TLS requires recreating the listener, which must be refused, with or without a temporary port
+	for _, temporaryPort := range []int{0, 8444} {
+		e := allTasks["listener1"].(*NetworkLoadBalancerListener)
+		e.SSLCertificateID = "arn:aws-test:acm:us-test-1:000000000000:certificate/1"
+		e.TemporaryPort = temporaryPort
+
+		a, err := e.Find(cloudupContext)
+		if err != nil {
+			t.Fatalf("error finding listener: %v", err)
+		}
+		if a == nil || !a.Protected {
+			t.Fatalf("expected to find protected listener, got %+v", a)
+		}
+		changes := &NetworkLoadBalancerListener{}
+		fi.BuildChanges(a, e, changes)
+
+		err = e.RenderAWS(&awsup.AWSAPITarget{Cloud: cloud}, a, e, changes)
+		if err == nil {
+			t.Errorf("expected error recreating protected listener (temporary port %d)", temporaryPort)
+		}
+
+		listeners, err := awsup.ListELBV2Listeners(ctx, cloud, nlb1.loadBalancerArn)
+		if err != nil {
+			t.Fatalf("error listing listeners: %v", err)
+		}
+		if len(listeners) != 1 || fi.ValueOf(listeners[0].ListenerArn) != protected[0] {
+			t.Errorf("expected protected listener %q to be kept, found %v", protected[0], listeners)
+		}
+	}
+
+	// Deleting the load balancer, as when cleaning up old revisions, must also be refused
+	loadBalancers, err := awsup.ListELBV2LoadBalancers(ctx, cloud)
+	if err != nil {
+		t.Fatalf("error listing load balancers: %v", err)
+	}
+	if len(loadBalancers) != 1 {
+		t.Fatalf("expected one load balancer, found %d", len(loadBalancers))
+	}
+	if err := buildDeleteNLB(loadBalancers[0]).Delete(&awsup.AWSAPITarget{Cloud: cloud}); err == nil {
+		t.Errorf("expected error deleting load balancer with protected listener")
+	}
+	if len(c.LoadBalancers) != 1 {
+		t.Errorf("expected load balancer to be kept, found %d", len(c.LoadBalancers))
+	}
+
+	// Removing the protection is applied in-place
+	{
+		e := allTasks["listener1"].(*NetworkLoadBalancerListener)
+		e.SSLCertificateID = ""
+		e.TemporaryPort = 0
+		e.Protected = false
+
+		a, err := e.Find(cloudupContext)
+		if err != nil {
+			t.Fatalf("error finding listener: %v", err)
+		}
+		changes := &NetworkLoadBalancerListener{}
+		fi.BuildChanges(a, e, changes)
+		if err := e.RenderAWS(&awsup.AWSAPITarget{Cloud: cloud}, a, e, changes); err != nil {
+			t.Fatalf("unexpected error removing protection: %v", err)
+		}
+
+		protected, err := awsup.FindProtectedELBV2Listeners(ctx, cloud, nlb1.loadBalancerArn)
+		if err != nil {
+			t.Fatalf("error finding protected listeners: %v", err)
+		}
+		if len(protected) != 0 {
+			t.Errorf("expected no protected listeners, found %v", protected)
+		}
+	}
+}
+
+func TestNetworkLoadBalancerListenerProtectedCertificateChange(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &countingELBV2{MockELBV2: &mockelbv2.MockELBV2{EC2: ec2Client}}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(certificateID string) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		tg1 := &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                nlb1.VPC,
+			Tags:               map[string]string{"Name": "tg1"},
+			Protocol:           elbv2types.ProtocolEnumTcp,
+			Port:               fi.PtrTo(int32(443)),
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+		}
+		listener1 := &NetworkLoadBalancerListener{
+			Name:                s("listener1"),
+			Lifecycle:           fi.LifecycleSync,
+			NetworkLoadBalancer: nlb1,
+			Port:                443,
+			TargetGroup:         tg1,
+			SSLCertificateID:    certificateID,
+			Protected:           true,
+			WaitConfig:          &ELBV2WaitConfig{CertificateRotationSettleTime: time.Millisecond},
+		}
+		allTasks["tg1"] = tg1
+		allTasks["listener1"] = listener1
+		return allTasks
+	}
+
+	allTasks := buildTasks("arn:aws:acm:us-east-1:000000000000:certificate/1")
+	runNLBTasks(t, cloud, allTasks)
+	listenerArn := allTasks["listener1"].(*NetworkLoadBalancerListener).listenerArn
+
+	// Changing the certificate is applied in place, only recording the rotation in the tags of the listener
+	c.modifyListenerCalls = 0
+	c.addTagsRequests = nil
+	c.removeTagsRequests = nil
+	runNLBTasks(t, cloud, buildTasks("arn:aws:acm:us-east-1:000000000000:certificate/2"))
+
+	if c.modifyListenerCalls != 1 {
+		t.Errorf("expected the certificate to be changed with one ModifyListener call, got %d", c.modifyListenerCalls)
+	}
+	for _, request := range c.addTagsRequests {
+		if !slices.Contains(request.ResourceArns, listenerArn) {
+			continue
+		}
+		for _, tag := range request.Tags {
+			if k := fi.ValueOf(tag.Key); k != awsup.KopsCertificateRotatedTag {
+				t.Errorf("expected no tags other than %q to be added to listener %q, got %q", awsup.KopsCertificateRotatedTag, listenerArn, k)
+			}
+		}
+	}
+	for _, request := range c.removeTagsRequests {
+		if slices.Contains(request.ResourceArns, listenerArn) {
+			t.Errorf("expected no tags to be removed from listener %q, got %v", listenerArn, request.TagKeys)
+		}
+	}
+
+	protected, err := awsup.FindProtectedELBV2Listeners(ctx, cloud, allTasks["nlb1"].(*NetworkLoadBalancer).loadBalancerArn)
+	if err != nil {
+		t.Fatalf("error finding protected listeners: %v", err)
+	}
+	if len(protected) != 1 || protected[0] != listenerArn {
+		t.Errorf("expected listener %q to stay protected, found %v", listenerArn, protected)
+	}
+}
+
+func TestNetworkLoadBalancerListenerOwnership(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &countingELBV2{MockELBV2: &mockelbv2.MockELBV2{EC2: ec2Client}}
+	cloud.MockELBV2 = c
+
+	allTasks := buildNLBTasks()
+	nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+	tg1 := &TargetGroup{
+		Name:               s("tg1"),
+		Lifecycle:          fi.LifecycleSync,
+		VPC:                nlb1.VPC,
+		Tags:               map[string]string{"Name": "tg1"},
+		Protocol:           elbv2types.ProtocolEnumTcp,
+		Port:               fi.PtrTo(int32(443)),
+		Interval:           fi.PtrTo(int32(10)),
+		HealthyThreshold:   fi.PtrTo(int32(2)),
+		UnhealthyThreshold: fi.PtrTo(int32(2)),
+	}
+	allTasks["tg1"] = tg1
+	allTasks["listener1"] = &NetworkLoadBalancerListener{
+		Name:                s("listener1"),
+		Lifecycle:           fi.LifecycleSync,
+		NetworkLoadBalancer: nlb1,
+		Port:                443,
+		TargetGroup:         tg1,
+	}
+	runNLBTasks(t, cloud, allTasks)
+
+	// The load balancer was created without the tags of the other cluster
+	otherCluster := cloud.WithTags(map[string]string{"KubernetesCluster": "other.example.com"})
+	cloudupContext, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, &awsup.AWSAPITarget{Cloud: otherCluster}, &kops.Cluster{}, otherCluster, nil, nil, nil, allTasks)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+
+	// Switching to TLS requires recreating the listener, which must be refused, with or without a temporary port
+	for _, temporaryPort := range []int{0, 8444} {
+		e := allTasks["listener1"].(*NetworkLoadBalancerListener)
+		e.SSLCertificateID = "arn:aws-test:acm:us-east-1:000000000000:certificate/1"
+		e.TemporaryPort = temporaryPort
+
+		a, err := e.Find(cloudupContext)
+		if err != nil {
+			t.Fatalf("error finding listener: %v", err)
+		}
+		if a == nil {
+			t.Fatalf("expected to find listener")
+		}
+		changes := &NetworkLoadBalancerListener{}
+		fi.BuildChanges(a, e, changes)
+
+		err = e.RenderAWS(&awsup.AWSAPITarget{Cloud: otherCluster}, a, e, changes)
+		if err == nil || !strings.Contains(err.Error(), "does not have the tags of this cluster") {
+			t.Errorf("expected ownership error recreating listener (temporary port %d), got %v", temporaryPort, err)
+		}
+		if c.deleteListenerCalls != 0 || c.createListenerCalls != 1 {
+			t.Errorf("expected listener to be kept (temporary port %d), got %d deletes and %d creates", temporaryPort, c.deleteListenerCalls, c.createListenerCalls)
+		}
+	}
+}
+
+// failingDeleteELBV2 fails every DeleteListener call.
+type failingDeleteELBV2 struct {
+	*mockelbv2.MockELBV2
+}
+
+func (m *failingDeleteELBV2) DeleteListener(ctx context.Context, request *elbv2.DeleteListenerInput, optFns ...func(*elbv2.Options)) (*elbv2.DeleteListenerOutput, error) {
+	return nil, &smithy.GenericAPIError{Code: "OperationNotPermitted", Message: "listener is in use"}
+}
+
+func TestNetworkLoadBalancerListenerDeleteError(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = c
+
+	allTasks := buildNLBTasks()
+	nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+	tg1 := &TargetGroup{
+		Name:               s("tg1"),
+		Lifecycle:          fi.LifecycleSync,
+		VPC:                nlb1.VPC,
+		Tags:               map[string]string{"Name": "tg1"},
+		Protocol:           elbv2types.ProtocolEnumTcp,
+		Port:               fi.PtrTo(int32(443)),
+		Interval:           fi.PtrTo(int32(10)),
+		HealthyThreshold:   fi.PtrTo(int32(2)),
+		UnhealthyThreshold: fi.PtrTo(int32(2)),
+	}
+	allTasks["tg1"] = tg1
+	allTasks["listener1"] = &NetworkLoadBalancerListener{
+		Name:                s("listener1"),
+		Lifecycle:           fi.LifecycleSync,
+		NetworkLoadBalancer: nlb1,
+		Port:                443,
+		TargetGroup:         tg1,
+	}
+	runNLBTasks(t, cloud, allTasks)
+
+	cloud.MockELBV2 = &failingDeleteELBV2{MockELBV2: c}
+	cloudupContext, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, &awsup.AWSAPITarget{Cloud: cloud}, &kops.Cluster{}, cloud, nil, nil, nil, allTasks)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+
+	e := allTasks["listener1"].(*NetworkLoadBalancerListener)
+	e.SSLCertificateID = "arn:aws-test:acm:us-east-1:000000000000:certificate/1"
+
+	a, err := e.Find(cloudupContext)
+	if err != nil {
+		t.Fatalf("error finding listener: %v", err)
+	}
+	if a == nil || a.listenerArn == "" {
+		t.Fatalf("expected to find listener, got %+v", a)
+	}
+	changes := &NetworkLoadBalancerListener{}
+	fi.BuildChanges(a, e, changes)
+
+	err = e.RenderAWS(&awsup.AWSAPITarget{Cloud: cloud}, a, e, changes)
+	if err == nil {
+		t.Fatalf("expected error deleting listener")
+	}
+	for _, expected := range []string{a.listenerArn, nlb1.loadBalancerArn, "port 443", "protocol TCP", "listener is in use"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error to contain %q, got %q", expected, err)
+		}
+	}
+}
+
+// blockingELBV2 blocks CreateListener until the context of the call is done.
+type blockingELBV2 struct {
+	*mockelbv2.MockELBV2
+
+	started chan struct{}
+}
+
+func (m *blockingELBV2) CreateListener(ctx context.Context, request *elbv2.CreateListenerInput, optFns ...func(*elbv2.Options)) (*elbv2.CreateListenerOutput, error) {
+	close(m.started)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestNetworkLoadBalancerListenerRenderCancelled(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = c
+
+	allTasks := buildNLBTasks()
+	nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+	tg1 := &TargetGroup{
+		Name:               s("tg1"),
+		Lifecycle:          fi.LifecycleSync,
+		VPC:                nlb1.VPC,
+		Tags:               map[string]string{"Name": "tg1"},
+		Protocol:           elbv2types.ProtocolEnumTcp,
+		Port:               fi.PtrTo(int32(443)),
+		Interval:           fi.PtrTo(int32(10)),
+		HealthyThreshold:   fi.PtrTo(int32(2)),
+		UnhealthyThreshold: fi.PtrTo(int32(2)),
+	}
+	allTasks["tg1"] = tg1
+	runNLBTasks(t, cloud, allTasks)
+
+	blocking := &blockingELBV2{MockELBV2: c, started: make(chan struct{})}
+	cloud.MockELBV2 = blocking
+
+	e := &NetworkLoadBalancerListener{
+		Name:                s("listener1"),
+		Lifecycle:           fi.LifecycleSync,
+		NetworkLoadBalancer: nlb1,
+		Port:                443,
+		TargetGroup:         tg1,
+	}
+
+	updateCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, 1)
+	go func() {
+		errs <- e.RenderAWS(awsup.NewAWSAPITarget(updateCtx, cloud), nil, e, &NetworkLoadBalancerListener{})
+	}()
+
+	<-blocking.started
+	cancel()
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected render to be cancelled, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("render was not aborted when the context was cancelled")
+	}
+	if len(c.Listeners) != 0 {
+		t.Errorf("expected no listeners, found %d", len(c.Listeners))
+	}
+}
+
+func TestNetworkLoadBalancerListenerRenderAWS(t *testing.T) {
+	ctx := context.TODO()
+
+	grid := []struct {
+		name string
+		// existing creates a listener on port 443 before rendering
+		existing bool
+		// build modifies the desired listener
+		build func(e *NetworkLoadBalancerListener)
+
+		expectedError   string
+		expectedCreates int
+		expectedDeletes int
+		expectedModify  int
+	}{
+		{
+			name:            "create",
+			expectedCreates: 1,
+		},
+		{
+			name:     "recreate",
+			existing: true,
+			build: func(e *NetworkLoadBalancerListener) {
+				e.SSLCertificateID = "arn:aws-test:acm:us-east-1:000000000000:certificate/1"
+			},
+			expectedCreates: 1,
+			expectedDeletes: 1,
+		},
+		{
+			name:     "update tags",
+			existing: true,
+			build: func(e *NetworkLoadBalancerListener) {
+				e.Tags = map[string]string{"team": "networking"}
+			},
+		},
+		{
+			name: "missing load balancer",
+			build: func(e *NetworkLoadBalancerListener) {
+				e.NetworkLoadBalancer = nil
+			},
+			expectedError: "NetworkLoadBalancer",
+		},
+		{
+			name: "load balancer not created",
+			build: func(e *NetworkLoadBalancerListener) {
+				e.NetworkLoadBalancer = &NetworkLoadBalancer{Name: s("nlb2")}
+			},
+			expectedError: "load balancer not yet created",
+		},
+	}
+
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+			ec2Client := &mockec2.MockEC2{}
+			cloud.MockEC2 = ec2Client
+			mock := &mockelbv2.MockELBV2{EC2: ec2Client}
+			cloud.MockELBV2 = mock
+
+			allTasks := buildNLBTasks()
+			nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+			tg1 := &TargetGroup{
+				Name:               s("tg1"),
+				Lifecycle:          fi.LifecycleSync,
+				VPC:                nlb1.VPC,
+				Tags:               map[string]string{"Name": "tg1"},
+				Protocol:           elbv2types.ProtocolEnumTcp,
+				Port:               fi.PtrTo(int32(443)),
+				Interval:           fi.PtrTo(int32(10)),
+				HealthyThreshold:   fi.PtrTo(int32(2)),
+				UnhealthyThreshold: fi.PtrTo(int32(2)),
+			}
+			allTasks["tg1"] = tg1
+			buildListener := func() *NetworkLoadBalancerListener {
+				return &NetworkLoadBalancerListener{
+					Name:                s("listener1"),
+					Lifecycle:           fi.LifecycleSync,
+					NetworkLoadBalancer: nlb1,
+					Port:                443,
+					TargetGroup:         tg1,
+				}
+			}
+			if g.existing {
+				allTasks["listener1"] = buildListener()
+			}
+			runNLBTasks(t, cloud, allTasks)
+
+			c := &countingELBV2{MockELBV2: mock}
+			cloud.MockELBV2 = c
+			cloudupContext, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, &awsup.AWSAPITarget{Cloud: cloud}, &kops.Cluster{}, cloud, nil, nil, nil, allTasks)
+			if err != nil {
+				t.Fatalf("error building context: %v", err)
+			}
+
+			e := buildListener()
+			var a *NetworkLoadBalancerListener
+			if g.existing {
+				a, err = e.Find(cloudupContext)
+				if err != nil {
+					t.Fatalf("error finding listener: %v", err)
+				}
+				if a == nil {
+					t.Fatalf("expected to find listener")
+				}
+			}
+			if g.build != nil {
+				g.build(e)
+			}
+			changes := &NetworkLoadBalancerListener{}
+			if a != nil {
+				fi.BuildChanges(a, e, changes)
+			}
+
+			err = e.RenderAWS(&awsup.AWSAPITarget{Cloud: cloud}, a, e, changes)
+			if g.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), g.expectedError) {
+					t.Fatalf("expected error containing %q, got %v", g.expectedError, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if c.createListenerCalls != g.expectedCreates {
+				t.Errorf("expected %d CreateListener calls, got %d", g.expectedCreates, c.createListenerCalls)
+			}
+			if c.deleteListenerCalls != g.expectedDeletes {
+				t.Errorf("expected %d DeleteListener calls, got %d", g.expectedDeletes, c.deleteListenerCalls)
+			}
+			if c.modifyListenerCalls != g.expectedModify {
+				t.Errorf("expected %d ModifyListener calls, got %d", g.expectedModify, c.modifyListenerCalls)
+			}
+			if g.expectedError == "" {
+				if e.listenerArn == "" && a == nil {
+					t.Errorf("expected listener ARN to be set")
+				}
+				listeners, err := awsup.ListELBV2Listeners(ctx, cloud, nlb1.loadBalancerArn)
+				if err != nil {
+					t.Fatalf("error listing listeners: %v", err)
+				}
+				if len(listeners) != 1 || listeners[0].Protocol != e.protocol() {
+					t.Errorf("expected one %s listener, found %+v", e.protocol(), listeners)
+				}
+			}
+		})
+	}
+}
+
+func TestNetworkLoadBalancerListenerAdopt(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	mock := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = mock
+
+	certificate := "arn:aws-test:acm:us-east-1:000000000000:certificate/1"
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(adoptListenerARN string) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+		tg1 := &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                nlb1.VPC,
+			Tags:               map[string]string{"Name": "tg1"},
+			Protocol:           elbv2types.ProtocolEnumTcp,
+			Port:               fi.PtrTo(int32(443)),
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+		}
+		allTasks["tg1"] = tg1
+		if adoptListenerARN != "" {
+			allTasks["listener1"] = &NetworkLoadBalancerListener{
+				Name:                s("listener1"),
+				Lifecycle:           fi.LifecycleSync,
+				NetworkLoadBalancer: nlb1,
+				Port:                443,
+				TargetGroup:         tg1,
+				SSLCertificateID:    certificate,
+				SSLPolicy:           "ELBSecurityPolicy-TLS13-1-2-2021-06",
+				AdoptListenerARN:    adoptListenerARN,
+			}
+		}
+		return allTasks
+	}
+
+	allTasks := buildTasks("")
+	runNLBTasks(t, cloud, allTasks)
+	nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+	tg1 := allTasks["tg1"].(*TargetGroup)
+
+	// The listener was created by hand, on another port and with an older SSL policy
+	response, err := mock.CreateListener(ctx, &elbv2.CreateListenerInput{
+		LoadBalancerArn: &nlb1.loadBalancerArn,
+		Port:            fi.PtrTo(int32(8443)),
+		Protocol:        elbv2types.ProtocolEnumTls,
+		SslPolicy:       s("ELBSecurityPolicy-TLS13-1-0-2021-06"),
+		Certificates:    []elbv2types.Certificate{{CertificateArn: s(certificate)}},
+		DefaultActions:  []elbv2types.Action{{Type: elbv2types.ActionTypeEnumForward, TargetGroupArn: tg1.ARN}},
+	})
+	if err != nil {
+		t.Fatalf("error creating listener: %v", err)
+	}
+	listenerArn := fi.ValueOf(response.Listeners[0].ListenerArn)
+
+	c := &countingELBV2{MockELBV2: mock}
+	cloud.MockELBV2 = c
+	runNLBTasks(t, cloud, buildTasks(listenerArn))
+
+	if c.createListenerCalls != 0 || c.deleteListenerCalls != 0 {
+		t.Errorf("expected the listener to be adopted in place, got %d creates and %d deletes", c.createListenerCalls, c.deleteListenerCalls)
+	}
+	if c.modifyListenerCalls != 1 {
+		t.Errorf("expected 1 ModifyListener call, got %d", c.modifyListenerCalls)
+	}
+	listeners, err := awsup.ListELBV2Listeners(ctx, cloud, nlb1.loadBalancerArn)
+	if err != nil {
+		t.Fatalf("error listing listeners: %v", err)
+	}
+	if len(listeners) != 1 {
+		t.Fatalf("expected one listener, found %d", len(listeners))
+	}
+	listener := listeners[0]
+	if fi.ValueOf(listener.ListenerArn) != listenerArn || fi.ValueOf(listener.Port) != 443 || fi.ValueOf(listener.SslPolicy) != "ELBSecurityPolicy-TLS13-1-2-2021-06" {
+		t.Errorf("expected listener %q to be reconciled to port 443 and the new SSL policy, got %+v", listenerArn, listener)
+	}
+
+	checkNLBNoChanges(t, ctx, cloud, buildTasks(listenerArn))
+
+	// Adopting a listener that does not exist fails rather than creating a listener
+	allTasks = buildTasks(listenerArn + "-missing")
+	cloudupContext, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, &awsup.AWSAPITarget{Cloud: cloud}, &kops.Cluster{}, cloud, nil, nil, nil, allTasks)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+	if err := cloudupContext.RunTasks(testRunTasksOptions); err == nil || !strings.Contains(err.Error(), "to adopt was not found") {
+		t.Errorf("expected error adopting missing listener, got %v", err)
+	}
+}
+
+func TestNetworkLoadBalancerListenerWeightedForward(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func() map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		listener1 := &NetworkLoadBalancerListener{
+			Name:                      s("listener1"),
+			Lifecycle:                 fi.LifecycleSync,
+			NetworkLoadBalancer:       nlb1,
+			Port:                      443,
+			StickinessEnabled:         true,
+			StickinessDurationSeconds: fi.PtrTo(300),
+		}
+		for _, name := range []string{"tg-blue", "tg-green"} {
+			tg := &TargetGroup{
+				Name:               s(name),
+				Lifecycle:          fi.LifecycleSync,
+				VPC:                nlb1.VPC,
+				Tags:               map[string]string{"Name": name},
+				Protocol:           elbv2types.ProtocolEnumTcp,
+				Port:               fi.PtrTo(int32(443)),
+				Interval:           fi.PtrTo(int32(10)),
+				HealthyThreshold:   fi.PtrTo(int32(2)),
+				UnhealthyThreshold: fi.PtrTo(int32(2)),
+			}
+			allTasks[name] = tg
+		}
+		listener1.ForwardTargetGroups = []*TargetGroupWeight{
+			{TargetGroup: allTasks["tg-blue"].(*TargetGroup), Weight: 80},
+			{TargetGroup: allTasks["tg-green"].(*TargetGroup), Weight: 20},
+		}
+		allTasks["listener1"] = listener1
+		return allTasks
+	}
+
+	{
+		allTasks := buildTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+		blue := allTasks["tg-blue"].(*TargetGroup)
+		green := allTasks["tg-green"].(*TargetGroup)
+
+		runNLBTasks(t, cloud, allTasks)
+
+		listeners, err := c.DescribeListeners(ctx, &elbv2.DescribeListenersInput{
+			LoadBalancerArn: fi.PtrTo(nlb1.loadBalancerArn),
+		})
+		if err != nil {
+			t.Fatalf("error describing listeners: %v", err)
+		}
+		if len(listeners.Listeners) != 1 {
+			t.Fatalf("expected exactly one listener, found %v", listeners.Listeners)
+		}
+		forwardConfig := listeners.Listeners[0].DefaultActions[0].ForwardConfig
+		if forwardConfig == nil || len(forwardConfig.TargetGroups) != 2 {
+			t.Fatalf("unexpected forward config %+v", forwardConfig)
+		}
+		for i, expected := range []struct {
+			arn    string
+			weight int32
+		}{
+			{arn: fi.ValueOf(blue.ARN), weight: 80},
+			{arn: fi.ValueOf(green.ARN), weight: 20},
+		} {
+			tuple := forwardConfig.TargetGroups[i]
+			if fi.ValueOf(tuple.TargetGroupArn) != expected.arn || fi.ValueOf(tuple.Weight) != expected.weight {
+				t.Errorf("unexpected target group %d: %q with weight %d", i, fi.ValueOf(tuple.TargetGroupArn), fi.ValueOf(tuple.Weight))
+			}
+		}
+		stickiness := forwardConfig.TargetGroupStickinessConfig
+		if stickiness == nil || !fi.ValueOf(stickiness.Enabled) || fi.ValueOf(stickiness.DurationSeconds) != 300 {
+			t.Errorf("unexpected stickiness config %+v", stickiness)
+		}
+	}
+
+	{
+		allTasks := buildTasks()
+		checkNLBNoChanges(t, ctx, cloud, allTasks)
+	}
+}
+
+func TestNetworkLoadBalancerListenerProportionalWeights(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &countingELBV2{MockELBV2: &mockelbv2.MockELBV2{EC2: ec2Client}}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(weights ...int) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		listener1 := &NetworkLoadBalancerListener{
+			Name:                s("listener1"),
+			Lifecycle:           fi.LifecycleSync,
+			NetworkLoadBalancer: nlb1,
+			Port:                443,
+		}
+		for i, name := range []string{"tg-blue", "tg-green"} {
+			tg := &TargetGroup{
+				Name:               s(name),
+				Lifecycle:          fi.LifecycleSync,
+				VPC:                nlb1.VPC,
+				Tags:               map[string]string{"Name": name},
+				Protocol:           elbv2types.ProtocolEnumTcp,
+				Port:               fi.PtrTo(int32(443)),
+				Interval:           fi.PtrTo(int32(10)),
+				HealthyThreshold:   fi.PtrTo(int32(2)),
+				UnhealthyThreshold: fi.PtrTo(int32(2)),
+			}
+			allTasks[name] = tg
+			listener1.ForwardTargetGroups = append(listener1.ForwardTargetGroups, &TargetGroupWeight{TargetGroup: tg, Weight: weights[i]})
+		}
+		allTasks["listener1"] = listener1
+		return allTasks
+	}
+
+	allTasks := buildTasks(30, 70)
+	runNLBTasks(t, cloud, allTasks)
+	listenerArn := allTasks["listener1"].(*NetworkLoadBalancerListener).listenerArn
+
+	// The same split with smaller weights is not a change
+	c.modifyListenerCalls = 0
+	checkNLBNoChanges(t, ctx, cloud, buildTasks(3, 7))
+	runNLBTasks(t, cloud, buildTasks(3, 7))
+	if c.modifyListenerCalls != 0 {
+		t.Errorf("expected proportional weights not to modify the listener, got %d ModifyListener calls", c.modifyListenerCalls)
+	}
+	listeners, err := c.DescribeListeners(ctx, &elbv2.DescribeListenersInput{ListenerArns: []string{listenerArn}})
+	if err != nil {
+		t.Fatalf("error describing listeners: %v", err)
+	}
+	var weights []int32
+	for _, tuple := range listeners.Listeners[0].DefaultActions[0].ForwardConfig.TargetGroups {
+		weights = append(weights, fi.ValueOf(tuple.Weight))
+	}
+	if expected := []int32{30, 70}; !reflect.DeepEqual(weights, expected) {
+		t.Errorf("expected weights %v, got %v", expected, weights)
+	}
+
+	// A different split is a change
+	runNLBTasks(t, cloud, buildTasks(1, 1))
+	if c.modifyListenerCalls != 1 {
+		t.Errorf("expected a different split to modify the listener once, got %d ModifyListener calls", c.modifyListenerCalls)
+	}
+	checkNLBNoChanges(t, ctx, cloud, buildTasks(5, 5))
+
+	// All-zero weights would drop all traffic
+	zero := buildTasks(0, 0)
+	if err := zero["listener1"].(*NetworkLoadBalancerListener).Normalize(nil); err == nil || !strings.Contains(err.Error(), "positive weight") {
+		t.Errorf("expected all-zero weights to be rejected, got %v", err)
+	}
+}
+
+func TestNetworkLoadBalancerListenerWeightedForwardTerraform(t *testing.T) {
+	nlb1 := &NetworkLoadBalancer{
+		Name:                 s("nlb1"),
+		LoadBalancerBaseName: s("nlb1"),
+	}
+	cases := []*renderTest{
+		{
+			Resource: &NetworkLoadBalancerListener{
+				Name:                nlb1.Name,
+				NetworkLoadBalancer: nlb1,
+				Port:                443,
+				ForwardTargetGroups: []*TargetGroupWeight{
+					{TargetGroup: &TargetGroup{Name: s("tg-blue")}, Weight: 80},
+					{TargetGroup: &TargetGroup{Name: s("tg-green")}, Weight: 20},
+				},
+				StickinessEnabled:         true,
+				StickinessDurationSeconds: fi.PtrTo(300),
+			},
+			Expected: `provider "aws" {
+  region = "eu-west-2"
+}
+
+resource "aws_lb_listener" "nlb1-443" {
+  default_action {
+    forward {
+      stickiness {
+        duration = 300
+        enabled  = true
+      }
+      target_group {
+        arn    = aws_lb_target_group.tg-blue.id
+        weight = 80
+      }
+      target_group {
+        arn    = aws_lb_target_group.tg-green.id
+        weight = 20
+      }
+    }
+    type = "forward"
+  }
+  load_balancer_arn = aws_lb.nlb1.id
+  port              = 443
+  protocol          = "TCP"
+}
+
+terraform {
+  required_version = ">= 0.15.0"
+  required_providers {
+    aws = {
+      "source"  = "hashicorp/aws"
+      "version" = ">= 5.0.0"
+    }
+  }
+}
+`,
+		},
+	}
+
+	doRenderTests(t, "RenderTerraform", cases)
+}
+
+func TestNetworkLoadBalancerListenerRegionLimitedSSLPolicy(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-gov-west-1", "a")
+	c := &mockelbv2.MockELBV2{
+		// Only some of the predefined policies are available in the region
+		SSLPolicies: map[string][]string{
+			"ELBSecurityPolicy-2016-08":           {"TLSv1", "TLSv1.1", "TLSv1.2"},
+			"ELBSecurityPolicy-TLS-1-2-2017-01":   {"TLSv1.2"},
+			"ELBSecurityPolicy-TLS13-1-2-2021-06": {"TLSv1.2", "TLSv1.3"},
+		},
+	}
+	cloud.MockELBV2 = c
+
+	cloudupContext, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, &awsup.AWSAPITarget{Cloud: cloud}, &kops.Cluster{}, cloud, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+
+	grid := []struct {
+		name           string
+		sslPolicy      string
+		autoSelect     bool
+		expectedPolicy string
+		expectedError  string
+	}{
+		{
+			name:           "available",
+			sslPolicy:      "ELBSecurityPolicy-TLS-1-2-2017-01",
+			expectedPolicy: "ELBSecurityPolicy-TLS-1-2-2017-01",
+		},
+		{
+			name:          "unavailable",
+			sslPolicy:     "ELBSecurityPolicy-TLS13-1-2-Res-2021-06",
+			expectedError: `listener "listener": SSL policy "ELBSecurityPolicy-TLS13-1-2-Res-2021-06" is not available in region "us-gov-west-1"; the closest available policies are: ELBSecurityPolicy-TLS13-1-2-2021-06, ELBSecurityPolicy-TLS-1-2-2017-01`,
+		},
+		{
+			name:           "unavailable with auto-selection",
+			sslPolicy:      "ELBSecurityPolicy-TLS13-1-2-Res-2021-06",
+			autoSelect:     true,
+			expectedPolicy: "ELBSecurityPolicy-TLS13-1-2-2021-06",
+		},
+		{
+			name:          "no equivalent policy",
+			sslPolicy:     "ELBSecurityPolicy-TLS13-1-3-2021-06",
+			autoSelect:    true,
+			expectedError: `listener "listener": SSL policy "ELBSecurityPolicy-TLS13-1-3-2021-06" is not available in region "us-gov-west-1", and no policy with an equivalent minimum TLS version is available`,
+		},
+		{
+			name:          "unknown policy is not auto-selected",
+			sslPolicy:     "my-custom-policy",
+			autoSelect:    true,
+			expectedError: `listener "listener" has unknown SSL policy "my-custom-policy", did you mean "ELBSecurityPolicy-2016-08"?`,
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			e := &NetworkLoadBalancerListener{
+				Name:                s("listener"),
+				NetworkLoadBalancer: &NetworkLoadBalancer{},
+				Port:                443,
+				SSLCertificateID:    "arn:aws-us-gov:acm:us-gov-west-1:123456789012:certificate/1",
+				SSLPolicy:           g.sslPolicy,
+				SSLPolicyAutoSelect: g.autoSelect,
+			}
+			err := e.Normalize(cloudupContext)
+			if g.expectedError != "" {
+				if err == nil || err.Error() != g.expectedError {
+					t.Fatalf("expected error %q, got %v", g.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if e.SSLPolicy != g.expectedPolicy {
+				t.Errorf("expected SSL policy %q, got %q", g.expectedPolicy, e.SSLPolicy)
+			}
+		})
+	}
+}
+
+// sslPolicyCountingELBV2 counts the DescribeSSLPolicies calls.
+type sslPolicyCountingELBV2 struct {
+	*mockelbv2.MockELBV2
+
+	mutex                    sync.Mutex
+	describeSSLPoliciesCalls int
+}
+
+func (m *sslPolicyCountingELBV2) DescribeSSLPolicies(ctx context.Context, request *elbv2.DescribeSSLPoliciesInput, optFns ...func(*elbv2.Options)) (*elbv2.DescribeSSLPoliciesOutput, error) {
+	m.mutex.Lock()
+	m.describeSSLPoliciesCalls++
+	m.mutex.Unlock()
+	return m.MockELBV2.DescribeSSLPolicies(ctx, request, optFns...)
+}
+
+func TestNetworkLoadBalancerListenerSSLPoliciesDescribedOnce(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-test-1", "a")
+	c := &sslPolicyCountingELBV2{MockELBV2: &mockelbv2.MockELBV2{}}
+	cloud.MockELBV2 = c
+
+	cloudupContext, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, &awsup.AWSAPITarget{Cloud: cloud}, &kops.Cluster{}, cloud, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+
+	nlb := &NetworkLoadBalancer{Name: s("nlb1")}
+	if err := nlb.Normalize(cloudupContext); err != nil {
+		t.Fatalf("unexpected error normalizing load balancer: %v", err)
+	}
+	var listeners []*NetworkLoadBalancerListener
+	for _, port := range []int{443, 8443} {
+		listener := &NetworkLoadBalancerListener{
+			Name:                s(fmt.Sprintf("listener-%d", port)),
+			NetworkLoadBalancer: nlb,
+			Port:                port,
+			SSLCertificateID:    "arn:aws-test:acm:us-test-1:000000000000:certificate/1",
+			SSLPolicy:           "ELBSecurityPolicy-TLS13-1-2-2021-06",
+		}
+		if err := listener.Normalize(cloudupContext); err != nil {
+			t.Fatalf("unexpected error normalizing listener: %v", err)
+		}
+		listeners = append(listeners, listener)
+	}
+	if c.describeSSLPoliciesCalls != 1 {
+		t.Errorf("expected the SSL policies to be described once, got %d calls", c.describeSSLPoliciesCalls)
+	}
+
+	// Find only reads the state of the listener
+	for _, listener := range listeners {
+		if _, err := listener.Find(cloudupContext); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if c.describeSSLPoliciesCalls != 1 {
+		t.Errorf("expected Find not to describe the SSL policies, got %d calls", c.describeSSLPoliciesCalls)
+	}
+}
+
+// countingELBV2 counts the calls that describe or change listeners and target groups.
+// It can wrap the ELBV2 mock of any task test.
+type countingELBV2 struct {
+	*mockelbv2.MockELBV2
+
+	describeListenersCalls int
+	createListenerCalls    int
+	deleteListenerCalls    int
+	modifyListenerCalls    int
+	modifyTargetGroupCalls int
+	createTargetGroupCalls int
+	deleteTargetGroupCalls int
+
+	modifyListenerRequests              []*elbv2.ModifyListenerInput
+	modifyTargetGroupAttributesRequests []*elbv2.ModifyTargetGroupAttributesInput
+	addTagsRequests                     []*elbv2.AddTagsInput
+	removeTagsRequests                  []*elbv2.RemoveTagsInput
+}
+
+func (m *countingELBV2) DescribeListeners(ctx context.Context, request *elbv2.DescribeListenersInput, optFns ...func(*elbv2.Options)) (*elbv2.DescribeListenersOutput, error) {
+	m.describeListenersCalls++
+	return m.MockELBV2.DescribeListeners(ctx, request, optFns...)
+}
+
+func (m *countingELBV2) CreateListener(ctx context.Context, request *elbv2.CreateListenerInput, optFns ...func(*elbv2.Options)) (*elbv2.CreateListenerOutput, error) {
+	m.createListenerCalls++
+	return m.MockELBV2.CreateListener(ctx, request, optFns...)
+}
+
+func (m *countingELBV2) DeleteListener(ctx context.Context, request *elbv2.DeleteListenerInput, optFns ...func(*elbv2.Options)) (*elbv2.DeleteListenerOutput, error) {
+	m.deleteListenerCalls++
+	return m.MockELBV2.DeleteListener(ctx, request, optFns...)
+}
+
+func (m *countingELBV2) ModifyListener(ctx context.Context, request *elbv2.ModifyListenerInput, optFns ...func(*elbv2.Options)) (*elbv2.ModifyListenerOutput, error) {
+	m.modifyListenerCalls++
+	m.modifyListenerRequests = append(m.modifyListenerRequests, request)
+	return m.MockELBV2.ModifyListener(ctx, request, optFns...)
+}
+
+func (m *countingELBV2) ModifyTargetGroup(ctx context.Context, request *elbv2.ModifyTargetGroupInput, optFns ...func(*elbv2.Options)) (*elbv2.ModifyTargetGroupOutput, error) {
+	m.modifyTargetGroupCalls++
+	return m.MockELBV2.ModifyTargetGroup(ctx, request, optFns...)
+}
+
+func (m *countingELBV2) CreateTargetGroup(ctx context.Context, request *elbv2.CreateTargetGroupInput, optFns ...func(*elbv2.Options)) (*elbv2.CreateTargetGroupOutput, error) {
+	m.createTargetGroupCalls++
+	return m.MockELBV2.CreateTargetGroup(ctx, request, optFns...)
+}
+
+func (m *countingELBV2) DeleteTargetGroup(ctx context.Context, request *elbv2.DeleteTargetGroupInput, optFns ...func(*elbv2.Options)) (*elbv2.DeleteTargetGroupOutput, error) {
+	m.deleteTargetGroupCalls++
+	return m.MockELBV2.DeleteTargetGroup(ctx, request, optFns...)
+}
+
+func (m *countingELBV2) ModifyTargetGroupAttributes(ctx context.Context, request *elbv2.ModifyTargetGroupAttributesInput, optFns ...func(*elbv2.Options)) (*elbv2.ModifyTargetGroupAttributesOutput, error) {
+	m.modifyTargetGroupAttributesRequests = append(m.modifyTargetGroupAttributesRequests, request)
+	return m.MockELBV2.ModifyTargetGroupAttributes(ctx, request, optFns...)
+}
+
+func (m *countingELBV2) AddTags(ctx context.Context, request *elbv2.AddTagsInput, optFns ...func(*elbv2.Options)) (*elbv2.AddTagsOutput, error) {
+	m.addTagsRequests = append(m.addTagsRequests, request)
+	return m.MockELBV2.AddTags(ctx, request, optFns...)
+}
+
+func (m *countingELBV2) RemoveTags(ctx context.Context, request *elbv2.RemoveTagsInput, optFns ...func(*elbv2.Options)) (*elbv2.RemoveTagsOutput, error) {
+	m.removeTagsRequests = append(m.removeTagsRequests, request)
+	return m.MockELBV2.RemoveTags(ctx, request, optFns...)
+}
+
+func TestNetworkLoadBalancerListenerReconcileWeights(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &countingELBV2{MockELBV2: &mockelbv2.MockELBV2{EC2: ec2Client}}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func() map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		listener1 := &NetworkLoadBalancerListener{
+			Name:                s("listener1"),
+			Lifecycle:           fi.LifecycleSync,
+			NetworkLoadBalancer: nlb1,
+			Port:                443,
+		}
+		for _, name := range []string{"tg-blue", "tg-green"} {
+			allTasks[name] = &TargetGroup{
+				Name:               s(name),
+				Lifecycle:          fi.LifecycleSync,
+				VPC:                nlb1.VPC,
+				Tags:               map[string]string{"Name": name},
+				Protocol:           elbv2types.ProtocolEnumTcp,
+				Port:               fi.PtrTo(int32(443)),
+				Interval:           fi.PtrTo(int32(10)),
+				HealthyThreshold:   fi.PtrTo(int32(2)),
+				UnhealthyThreshold: fi.PtrTo(int32(2)),
+			}
+		}
+		listener1.ForwardTargetGroups = []*TargetGroupWeight{
+			{TargetGroup: allTasks["tg-blue"].(*TargetGroup), Weight: 90},
+			{TargetGroup: allTasks["tg-green"].(*TargetGroup), Weight: 10},
+		}
+		allTasks["listener1"] = listener1
+		return allTasks
+	}
+
+	var listenerArn string
+	{
+		allTasks := buildTasks()
+		runNLBTasks(t, cloud, allTasks)
+		listenerArn = allTasks["listener1"].(*NetworkLoadBalancerListener).listenerArn
+	}
+
+	// Someone rebalances the traffic outside of kops
+	{
+		listeners, err := c.DescribeListeners(ctx, &elbv2.DescribeListenersInput{ListenerArns: []string{listenerArn}})
+		if err != nil {
+			t.Fatalf("error describing listeners: %v", err)
+		}
+		action := listeners.Listeners[0].DefaultActions[0]
+		forwardConfig := *action.ForwardConfig
+		forwardConfig.TargetGroups = []elbv2types.TargetGroupTuple{
+			{TargetGroupArn: action.ForwardConfig.TargetGroups[0].TargetGroupArn, Weight: fi.PtrTo(int32(50))},
+			{TargetGroupArn: action.ForwardConfig.TargetGroups[1].TargetGroupArn, Weight: fi.PtrTo(int32(50))},
+		}
+		action.ForwardConfig = &forwardConfig
+		if _, err := c.MockELBV2.ModifyListener(ctx, &elbv2.ModifyListenerInput{
+			ListenerArn:    &listenerArn,
+			DefaultActions: []elbv2types.Action{action},
+		}); err != nil {
+			t.Fatalf("error modifying listener: %v", err)
+		}
+	}
+
+	{
+		c.createListenerCalls, c.deleteListenerCalls, c.modifyListenerCalls = 0, 0, 0
+
+		allTasks := buildTasks()
+		runNLBTasks(t, cloud, allTasks)
+
+		if c.modifyListenerCalls != 1 {
+			t.Errorf("expected a single ModifyListener call, got %d", c.modifyListenerCalls)
+		}
+		if c.createListenerCalls != 0 || c.deleteListenerCalls != 0 {
+			t.Errorf("expected the listener not to be recreated, got %d creations and %d deletions", c.createListenerCalls, c.deleteListenerCalls)
+		}
+
+		listeners, err := c.DescribeListeners(ctx, &elbv2.DescribeListenersInput{ListenerArns: []string{listenerArn}})
+		if err != nil {
+			t.Fatalf("error describing listeners: %v", err)
+		}
+		if len(listeners.Listeners) != 1 {
+			t.Fatalf("expected listener %q to be kept, found %v", listenerArn, listeners.Listeners)
+		}
+		var weights []int32
+		for _, tuple := range listeners.Listeners[0].DefaultActions[0].ForwardConfig.TargetGroups {
+			weights = append(weights, fi.ValueOf(tuple.Weight))
+		}
+		if len(weights) != 2 || weights[0] != 90 || weights[1] != 10 {
+			t.Errorf("expected weights to be reconciled to [90 10], got %v", weights)
+		}
+	}
+
+	{
+		allTasks := buildTasks()
+		checkNLBNoChanges(t, ctx, cloud, allTasks)
+	}
+}
+
+// restrictedELBV2 denies access to DescribeListenerCertificates, like some restricted IAM roles.
+type restrictedELBV2 struct {
+	*mockelbv2.MockELBV2
+}
+
+func (m *restrictedELBV2) DescribeListenerCertificates(ctx context.Context, request *elbv2.DescribeListenerCertificatesInput, optFns ...func(*elbv2.Options)) (*elbv2.DescribeListenerCertificatesOutput, error) {
+	return nil, &smithy.GenericAPIError{Code: "AccessDenied", Message: "not authorized to perform: elasticloadbalancing:DescribeListenerCertificates"}
+}
+
+func TestNetworkLoadBalancerListenerFindCertificatesAccessDenied(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	c := &mockelbv2.MockELBV2{}
+
+	lb, err := c.CreateLoadBalancer(ctx, &elbv2.CreateLoadBalancerInput{
+		Name: s("nlb1"),
+		Type: elbv2types.LoadBalancerTypeEnumNetwork,
+	})
+	if err != nil {
+		t.Fatalf("error creating load balancer: %v", err)
+	}
+	loadBalancerArn := fi.ValueOf(lb.LoadBalancers[0].LoadBalancerArn)
+
+	if _, err := c.CreateListener(ctx, &elbv2.CreateListenerInput{
+		LoadBalancerArn: &loadBalancerArn,
+		Port:            fi.PtrTo(int32(443)),
+		Protocol:        elbv2types.ProtocolEnumTls,
+		Certificates:    []elbv2types.Certificate{{CertificateArn: s("arn:aws:acm:us-east-1:123456789012:certificate/1")}},
+		SslPolicy:       s("ELBSecurityPolicy-2016-08"),
+		DefaultActions:  []elbv2types.Action{{Type: elbv2types.ActionTypeEnumForward, TargetGroupArn: s("tg-443")}},
+	}); err != nil {
+		t.Fatalf("error creating listener: %v", err)
+	}
+
+	for _, restricted := range []bool{false, true} {
+		if restricted {
+			cloud.MockELBV2 = &restrictedELBV2{MockELBV2: c}
+		} else {
+			cloud.MockELBV2 = c
+		}
+
+		cloudupContext, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, &awsup.AWSAPITarget{Cloud: cloud}, &kops.Cluster{}, cloud, nil, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("error building context: %v", err)
+		}
+
+		e := &NetworkLoadBalancerListener{
+			Name:                s("listener"),
+			NetworkLoadBalancer: &NetworkLoadBalancer{loadBalancerArn: loadBalancerArn},
+			Port:                443,
+		}
+		actual, err := e.Find(cloudupContext)
+		if err != nil {
+			t.Fatalf("unexpected error finding listener (restricted=%v): %v", restricted, err)
+		}
+		if actual == nil {
+			t.Fatalf("listener not found (restricted=%v)", restricted)
+		}
+		if actual.SSLCertificateID != "arn:aws:acm:us-east-1:123456789012:certificate/1" {
+			t.Errorf("unexpected certificate %q (restricted=%v)", actual.SSLCertificateID, restricted)
+		}
+		if actual.SSLPolicy != "ELBSecurityPolicy-2016-08" {
+			t.Errorf("unexpected SSL policy %q (restricted=%v)", actual.SSLPolicy, restricted)
+		}
+	}
+}
+
+// missingCertificateELBV2 rejects listeners using a certificate that has been deleted from ACM.
+type missingCertificateELBV2 struct {
+	*mockelbv2.MockELBV2
+
+	missingCertificateARN string
+}
+
+func (m *missingCertificateELBV2) CreateListener(ctx context.Context, request *elbv2.CreateListenerInput, optFns ...func(*elbv2.Options)) (*elbv2.CreateListenerOutput, error) {
+	for _, certificate := range request.Certificates {
+		if fi.ValueOf(certificate.CertificateArn) == m.missingCertificateARN {
+			return nil, &elbv2types.CertificateNotFoundException{Message: s("Certificate '" + m.missingCertificateARN + "' not found")}
+		}
+	}
+	return m.MockELBV2.CreateListener(ctx, request, optFns...)
+}
+
+func TestNetworkLoadBalancerListenerMissingCertificate(t *testing.T) {
+	ctx := context.TODO()
+
+	missingCertificateARN := "arn:aws:acm:us-east-1:000000000000:certificate/deleted"
+	fallbackCertificateARN := "arn:aws:acm:us-east-1:000000000000:certificate/fallback"
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &missingCertificateELBV2{MockELBV2: &mockelbv2.MockELBV2{EC2: ec2Client}, missingCertificateARN: missingCertificateARN}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(fallback string) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		tg1 := &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                nlb1.VPC,
+			Tags:               map[string]string{"Name": "tg1"},
+			Protocol:           elbv2types.ProtocolEnumTcp,
+			Port:               fi.PtrTo(int32(443)),
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+		}
+		allTasks["tg1"] = tg1
+		allTasks["listener1"] = &NetworkLoadBalancerListener{
+			Name:                     s("listener1"),
+			Lifecycle:                fi.LifecycleSync,
+			NetworkLoadBalancer:      nlb1,
+			Port:                     443,
+			TargetGroup:              tg1,
+			SSLCertificateID:         missingCertificateARN,
+			FallbackSSLCertificateID: fallback,
+		}
+		return allTasks
+	}
+
+	// Without a fallback certificate, the error names the missing certificate
+	{
+		listener := buildTasks("")["listener1"].(*NetworkLoadBalancerListener)
+		request := &elbv2.CreateListenerInput{
+			Certificates: []elbv2types.Certificate{{CertificateArn: s(missingCertificateARN)}},
+		}
+		_, err := listener.createListener(ctx, cloud, request)
+		if err == nil {
+			t.Fatalf("expected an error creating a listener with a missing certificate")
+		}
+		if !strings.Contains(err.Error(), missingCertificateARN) {
+			t.Errorf("expected the error to name the missing certificate, got %v", err)
+		}
+	}
+
+	// With a fallback certificate, the listener is created with it
+	{
+		allTasks := buildTasks(fallbackCertificateARN)
+		runNLBTasks(t, cloud, allTasks)
+
+		listenerArn := allTasks["listener1"].(*NetworkLoadBalancerListener).listenerArn
+		listeners, err := c.DescribeListeners(ctx, &elbv2.DescribeListenersInput{ListenerArns: []string{listenerArn}})
+		if err != nil {
+			t.Fatalf("error describing listeners: %v", err)
+		}
+		if len(listeners.Listeners) != 1 {
+			t.Fatalf("expected listener %q, found %v", listenerArn, listeners.Listeners)
+		}
+		certificates := listeners.Listeners[0].Certificates
+		if len(certificates) != 1 || fi.ValueOf(certificates[0].CertificateArn) != fallbackCertificateARN {
+			t.Errorf("expected the listener to use the fallback certificate, got %+v", certificates)
+		}
+	}
+
+	// The listener is not recreated while the certificate is missing
+	{
+		allTasks := buildTasks(fallbackCertificateARN)
+		checkNLBNoChanges(t, ctx, cloud, allTasks)
+	}
+}
+
+func TestNetworkLoadBalancerListenerRotateSharedCertificate(t *testing.T) {
+	ctx := context.TODO()
+
+	oldCertificateARN := "arn:aws:acm:us-east-1:000000000000:certificate/old"
+	newCertificateARN := "arn:aws:acm:us-east-1:000000000000:certificate/new"
+	ports := []int{443, 8443, 9443}
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &countingELBV2{MockELBV2: &mockelbv2.MockELBV2{EC2: ec2Client}}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(certificateARN string) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		tg1 := &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                nlb1.VPC,
+			Tags:               map[string]string{"Name": "tg1"},
+			Protocol:           elbv2types.ProtocolEnumTcp,
+			Port:               fi.PtrTo(int32(443)),
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+		}
+		allTasks["tg1"] = tg1
+		for _, port := range ports {
+			name := fmt.Sprintf("listener-%d", port)
+			allTasks[name] = &NetworkLoadBalancerListener{
+				Name:                s(name),
+				Lifecycle:           fi.LifecycleSync,
+				NetworkLoadBalancer: nlb1,
+				Port:                port,
+				TargetGroup:         tg1,
+				SSLCertificateID:    certificateARN,
+				WaitConfig:          &ELBV2WaitConfig{CertificateRotationSettleTime: time.Millisecond},
+			}
+		}
+		return allTasks
+	}
+
+	var listenerArns []string
+	{
+		allTasks := buildTasks(oldCertificateARN)
+		runNLBTasks(t, cloud, allTasks)
+		for _, port := range ports {
+			listenerArns = append(listenerArns, allTasks[fmt.Sprintf("listener-%d", port)].(*NetworkLoadBalancerListener).listenerArn)
+		}
+	}
+
+	{
+		c.createListenerCalls, c.deleteListenerCalls, c.modifyListenerCalls = 0, 0, 0
+
+		allTasks := buildTasks(newCertificateARN)
+		runNLBTasks(t, cloud, allTasks)
+
+		if c.createListenerCalls != 0 || c.deleteListenerCalls != 0 {
+			t.Errorf("expected the listeners not to be recreated, got %d creations and %d deletions", c.createListenerCalls, c.deleteListenerCalls)
+		}
+		if c.modifyListenerCalls != len(ports) {
+			t.Errorf("expected a single ModifyListener call per listener, got %d", c.modifyListenerCalls)
+		}
+
+		listeners, err := c.DescribeListeners(ctx, &elbv2.DescribeListenersInput{ListenerArns: listenerArns})
+		if err != nil {
+			t.Fatalf("error describing listeners: %v", err)
+		}
+		if len(listeners.Listeners) != len(ports) {
+			t.Fatalf("expected listeners %v to be kept, found %v", listenerArns, listeners.Listeners)
+		}
+		for _, listener := range listeners.Listeners {
+			if certificateARN := fi.ValueOf(listener.Certificates[0].CertificateArn); certificateARN != newCertificateARN {
+				t.Errorf("expected listener on port %d to use certificate %q, got %q", fi.ValueOf(listener.Port), newCertificateARN, certificateARN)
+			}
+		}
+	}
+
+	{
+		allTasks := buildTasks(newCertificateARN)
+		checkNLBNoChanges(t, ctx, cloud, allTasks)
+	}
+}
+
+func TestNetworkLoadBalancerListenerTargetGroupHealthCheckChange(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &countingELBV2{MockELBV2: &mockelbv2.MockELBV2{EC2: ec2Client}}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(interval int32) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		tg1 := &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                nlb1.VPC,
+			Tags:               map[string]string{"Name": "tg1"},
+			Protocol:           elbv2types.ProtocolEnumTcp,
+			Port:               fi.PtrTo(int32(443)),
+			Interval:           fi.PtrTo(interval),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+		}
+		allTasks["tg1"] = tg1
+		allTasks["listener1"] = &NetworkLoadBalancerListener{
+			Name:                s("listener1"),
+			Lifecycle:           fi.LifecycleSync,
+			NetworkLoadBalancer: nlb1,
+			Port:                443,
+			TargetGroup:         tg1,
+		}
+		return allTasks
+	}
+
+	var targetGroupArn string
+	{
+		allTasks := buildTasks(10)
+		runNLBTasks(t, cloud, allTasks)
+		targetGroupArn = fi.ValueOf(allTasks["tg1"].(*TargetGroup).ARN)
+	}
+
+	{
+		c.createListenerCalls, c.deleteListenerCalls, c.modifyListenerCalls, c.modifyTargetGroupCalls = 0, 0, 0, 0
+
+		allTasks := buildTasks(30)
+		runNLBTasks(t, cloud, allTasks)
+
+		if c.modifyTargetGroupCalls != 1 {
+			t.Errorf("expected a single ModifyTargetGroup call, got %d", c.modifyTargetGroupCalls)
+		}
+		if c.createListenerCalls != 0 || c.deleteListenerCalls != 0 || c.modifyListenerCalls != 0 {
+			t.Errorf("expected the listener to be untouched, got %d creations, %d deletions and %d modifications", c.createListenerCalls, c.deleteListenerCalls, c.modifyListenerCalls)
+		}
+
+		targetGroups, err := c.DescribeTargetGroups(ctx, &elbv2.DescribeTargetGroupsInput{TargetGroupArns: []string{targetGroupArn}})
+		if err != nil {
+			t.Fatalf("error describing target groups: %v", err)
+		}
+		if interval := fi.ValueOf(targetGroups.TargetGroups[0].HealthCheckIntervalSeconds); interval != 30 {
+			t.Errorf("expected health check interval 30, got %d", interval)
+		}
+	}
+
+	{
+		allTasks := buildTasks(30)
+		checkNLBNoChanges(t, ctx, cloud, allTasks)
+	}
+}
+
+func TestNetworkLoadBalancerListenerALPNPolicyChange(t *testing.T) {
+	ctx := context.TODO()
+
+	certificateARN := "arn:aws:acm:us-east-1:000000000000:certificate/tls"
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &countingELBV2{MockELBV2: &mockelbv2.MockELBV2{EC2: ec2Client}}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(alpnPolicy string) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		tg1 := &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                nlb1.VPC,
+			Tags:               map[string]string{"Name": "tg1"},
+			Protocol:           elbv2types.ProtocolEnumTcp,
+			Port:               fi.PtrTo(int32(443)),
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+		}
+		allTasks["tg1"] = tg1
+		allTasks["listener1"] = &NetworkLoadBalancerListener{
+			Name:                s("listener1"),
+			Lifecycle:           fi.LifecycleSync,
+			NetworkLoadBalancer: nlb1,
+			Port:                443,
+			TargetGroup:         tg1,
+			SSLCertificateID:    certificateARN,
+			ALPNPolicy:          alpnPolicy,
+		}
+		return allTasks
+	}
+
+	var listenerArn string
+	{
+		allTasks := buildTasks("HTTP1Only")
+		runNLBTasks(t, cloud, allTasks)
+		listenerArn = allTasks["listener1"].(*NetworkLoadBalancerListener).listenerArn
+	}
+
+	{
+		c.createListenerCalls, c.deleteListenerCalls, c.modifyListenerCalls = 0, 0, 0
+
+		allTasks := buildTasks("HTTP2Preferred")
+		runNLBTasks(t, cloud, allTasks)
+
+		if c.createListenerCalls != 0 || c.deleteListenerCalls != 0 {
+			t.Errorf("expected the listener not to be recreated, got %d creations and %d deletions", c.createListenerCalls, c.deleteListenerCalls)
+		}
+		if c.modifyListenerCalls != 1 {
+			t.Errorf("expected a single ModifyListener call, got %d", c.modifyListenerCalls)
+		}
+
+		listeners, err := c.DescribeListeners(ctx, &elbv2.DescribeListenersInput{ListenerArns: []string{listenerArn}})
+		if err != nil {
+			t.Fatalf("error describing listeners: %v", err)
+		}
+		if alpnPolicy := listeners.Listeners[0].AlpnPolicy; !reflect.DeepEqual(alpnPolicy, []string{"HTTP2Preferred"}) {
+			t.Errorf("expected ALPN policy HTTP2Preferred, got %v", alpnPolicy)
+		}
+	}
+
+	{
+		allTasks := buildTasks("HTTP2Preferred")
+		checkNLBNoChanges(t, ctx, cloud, allTasks)
+	}
+}
+
+func TestNetworkLoadBalancerListenerTags(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &countingELBV2{MockELBV2: &mockelbv2.MockELBV2{EC2: ec2Client}}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(tags map[string]string) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		tg1 := &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                nlb1.VPC,
+			Tags:               map[string]string{"Name": "tg1"},
+			Protocol:           elbv2types.ProtocolEnumTcp,
+			Port:               fi.PtrTo(int32(443)),
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+		}
+		allTasks["tg1"] = tg1
+		allTasks["listener1"] = &NetworkLoadBalancerListener{
+			Name:                s("listener1"),
+			Lifecycle:           fi.LifecycleSync,
+			NetworkLoadBalancer: nlb1,
+			Port:                443,
+			TargetGroup:         tg1,
+			Protected:           true,
+			Tags:                tags,
+		}
+		return allTasks
+	}
+
+	listenerTags := func(listenerArn string) map[string]string {
+		response, err := c.DescribeTags(ctx, &elbv2.DescribeTagsInput{ResourceArns: []string{listenerArn}})
+		if err != nil {
+			t.Fatalf("error describing tags: %v", err)
+		}
+		tags := make(map[string]string)
+		for _, tagDescription := range response.TagDescriptions {
+			for _, tag := range tagDescription.Tags {
+				tags[fi.ValueOf(tag.Key)] = fi.ValueOf(tag.Value)
+			}
+		}
+		return tags
+	}
+
+	var listenerArn string
+	{
+		allTasks := buildTasks(map[string]string{"team": "a", "cost-center": "1"})
+		runNLBTasks(t, cloud, allTasks)
+		listenerArn = allTasks["listener1"].(*NetworkLoadBalancerListener).listenerArn
+
+		tags := listenerTags(listenerArn)
+		if tags["team"] != "a" || tags["cost-center"] != "1" {
+			t.Errorf("expected the listener to be created with its tags, got %v", tags)
+		}
+	}
+
+	{
+		c.createListenerCalls, c.deleteListenerCalls = 0, 0
+
+		allTasks := buildTasks(map[string]string{"team": "b"})
+		runNLBTasks(t, cloud, allTasks)
+
+		if c.createListenerCalls != 0 || c.deleteListenerCalls != 0 {
+			t.Errorf("expected the listener not to be recreated, got %d creations and %d deletions", c.createListenerCalls, c.deleteListenerCalls)
+		}
+		tags := listenerTags(listenerArn)
+		if tags["team"] != "b" {
+			t.Errorf("expected tag team=b, got %v", tags)
+		}
+		if _, found := tags["cost-center"]; found {
+			t.Errorf("expected tag cost-center to be removed, got %v", tags)
+		}
+		if tags[awsup.KopsProtectedTag] != "true" {
+			t.Errorf("expected the protection tag to be kept, got %v", tags)
+		}
+		for k, v := range cloud.Tags() {
+			if tags[k] != v {
+				t.Errorf("expected cluster tag %s=%s to be kept, got %v", k, v, tags)
+			}
+		}
+	}
+
+	{
+		allTasks := buildTasks(map[string]string{"team": "b"})
+		checkNLBNoChanges(t, ctx, cloud, allTasks)
+	}
+}
+
+func TestNetworkLoadBalancerListenerTagsTerraform(t *testing.T) {
+	nlb1 := &NetworkLoadBalancer{
+		Name:                 s("nlb1"),
+		LoadBalancerBaseName: s("nlb1"),
+	}
+	cases := []*renderTest{
+		{
+			Resource: &NetworkLoadBalancerListener{
+				Name:                nlb1.Name,
+				NetworkLoadBalancer: nlb1,
+				Port:                443,
+				TargetGroup:         &TargetGroup{Name: s("tg1")},
+				Tags:                map[string]string{"team": "a"},
+			},
+			Expected: `provider "aws" {
+  region = "eu-west-2"
+}
+
+resource "aws_lb_listener" "nlb1-443" {
+  default_action {
+    target_group_arn = aws_lb_target_group.tg1.id
+    type             = "forward"
+  }
+  load_balancer_arn = aws_lb.nlb1.id
+  port              = 443
+  protocol          = "TCP"
+  tags = {
+    "team" = "a"
+  }
+}
+
+terraform {
+  required_version = ">= 0.15.0"
+  required_providers {
+    aws = {
+      "source"  = "hashicorp/aws"
+      "version" = ">= 5.0.0"
+    }
+  }
+}
+`,
+		},
+	}
+
+	doRenderTests(t, "RenderTerraform", cases)
+}
+
+func TestNetworkLoadBalancerListenerAdditionalCertificates(t *testing.T) {
+	ctx := context.TODO()
+
+	defaultCertificateARN := "arn:aws:acm:us-east-1:000000000000:certificate/default"
+	apiCertificateARN := "arn:aws:acm:us-east-1:000000000000:certificate/api"
+	internalCertificateARN := "arn:aws:acm:us-east-1:000000000000:certificate/internal"
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &countingELBV2{MockELBV2: &mockelbv2.MockELBV2{EC2: ec2Client}}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(additionalCertificates []string) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		tg1 := &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                nlb1.VPC,
+			Tags:               map[string]string{"Name": "tg1"},
+			Protocol:           elbv2types.ProtocolEnumTcp,
+			Port:               fi.PtrTo(int32(443)),
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+		}
+		allTasks["tg1"] = tg1
+		allTasks["listener1"] = &NetworkLoadBalancerListener{
+			Name:                   s("listener1"),
+			Lifecycle:              fi.LifecycleSync,
+			NetworkLoadBalancer:    nlb1,
+			Port:                   443,
+			TargetGroup:            tg1,
+			SSLCertificateID:       defaultCertificateARN,
+			AdditionalCertificates: additionalCertificates,
+		}
+		return allTasks
+	}
+
+	var listenerArn string
+	checkCertificates := func(expected []string) {
+		t.Helper()
+		certificates, err := awsup.ListELBV2ListenerCertificates(ctx, cloud, listenerArn)
+		if err != nil {
+			t.Fatalf("error listing listener certificates: %v", err)
+		}
+		var actual []string
+		for _, certificate := range certificates {
+			actual = append(actual, fi.ValueOf(certificate.CertificateArn))
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("expected certificates %v, got %v", expected, actual)
+		}
+	}
+
+	{
+		allTasks := buildTasks([]string{apiCertificateARN, internalCertificateARN})
+		runNLBTasks(t, cloud, allTasks)
+		listenerArn = allTasks["listener1"].(*NetworkLoadBalancerListener).listenerArn
+		checkCertificates([]string{defaultCertificateARN, apiCertificateARN, internalCertificateARN})
+	}
+
+	{
+		allTasks := buildTasks([]string{apiCertificateARN, internalCertificateARN})
+		checkNLBNoChanges(t, ctx, cloud, allTasks)
+	}
+
+	for _, additionalCertificates := range [][]string{{internalCertificateARN}, nil} {
+		c.createListenerCalls, c.deleteListenerCalls = 0, 0
+
+		allTasks := buildTasks(additionalCertificates)
+		runNLBTasks(t, cloud, allTasks)
+
+		if c.createListenerCalls != 0 || c.deleteListenerCalls != 0 {
+			t.Errorf("expected the listener not to be recreated, got %d creations and %d deletions", c.createListenerCalls, c.deleteListenerCalls)
+		}
+		checkCertificates(append([]string{defaultCertificateARN}, additionalCertificates...))
+
+		allTasks = buildTasks(additionalCertificates)
+		checkNLBNoChanges(t, ctx, cloud, allTasks)
+	}
+}
+
+func TestNetworkLoadBalancerListenerReassignDefaultCertificate(t *testing.T) {
+	ctx := context.TODO()
+
+	wildcardCertificateARN := "arn:aws:acm:us-east-1:000000000000:certificate/wildcard"
+	apiCertificateARN := "arn:aws:acm:us-east-1:000000000000:certificate/api"
+	internalCertificateARN := "arn:aws:acm:us-east-1:000000000000:certificate/internal"
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &countingELBV2{MockELBV2: &mockelbv2.MockELBV2{EC2: ec2Client}}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(defaultCertificate string, additionalCertificates []string) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		tg1 := &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                nlb1.VPC,
+			Tags:               map[string]string{"Name": "tg1"},
+			Protocol:           elbv2types.ProtocolEnumTcp,
+			Port:               fi.PtrTo(int32(443)),
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+		}
+		allTasks["tg1"] = tg1
+		allTasks["listener1"] = &NetworkLoadBalancerListener{
+			Name:                   s("listener1"),
+			Lifecycle:              fi.LifecycleSync,
+			NetworkLoadBalancer:    nlb1,
+			Port:                   443,
+			TargetGroup:            tg1,
+			SSLCertificateID:       defaultCertificate,
+			AdditionalCertificates: additionalCertificates,
+			WaitConfig:             &ELBV2WaitConfig{CertificateRotationSettleTime: time.Millisecond},
+		}
+		return allTasks
+	}
+
+	allTasks := buildTasks(wildcardCertificateARN, []string{apiCertificateARN, internalCertificateARN})
+	runNLBTasks(t, cloud, allTasks)
+	listenerArn := allTasks["listener1"].(*NetworkLoadBalancerListener).listenerArn
+
+	// The api certificate becomes the default, and the wildcard certificate is only served through SNI
+	c.createListenerCalls, c.deleteListenerCalls = 0, 0
+	runNLBTasks(t, cloud, buildTasks(apiCertificateARN, []string{wildcardCertificateARN, internalCertificateARN}))
+
+	if c.createListenerCalls != 0 || c.deleteListenerCalls != 0 {
+		t.Errorf("expected the listener not to be recreated, got %d creations and %d deletions", c.createListenerCalls, c.deleteListenerCalls)
+	}
+	certificates, err := awsup.ListELBV2ListenerCertificates(ctx, cloud, listenerArn)
+	if err != nil {
+		t.Fatalf("error listing listener certificates: %v", err)
+	}
+	if defaultCertificate := findDefaultCertificateARN(certificates); defaultCertificate != apiCertificateARN {
+		t.Errorf("expected default certificate %q, got %q", apiCertificateARN, defaultCertificate)
+	}
+	additional := findAdditionalCertificateARNs(certificates, apiCertificateARN, nil)
+	sort.Strings(additional)
+	if expected := []string{internalCertificateARN, wildcardCertificateARN}; !reflect.DeepEqual(additional, expected) {
+		t.Errorf("expected additional certificates %v, got %v", expected, additional)
+	}
+
+	checkNLBNoChanges(t, ctx, cloud, buildTasks(apiCertificateARN, []string{wildcardCertificateARN, internalCertificateARN}))
+}
+
+func TestNetworkLoadBalancerListenerRotateCertificateWithSSLPolicy(t *testing.T) {
+	ctx := context.TODO()
+
+	oldCertificateARN := "arn:aws:acm:us-east-1:000000000000:certificate/old"
+	newCertificateARN := "arn:aws:acm:us-east-1:000000000000:certificate/new"
+	oldPolicy := "ELBSecurityPolicy-TLS13-1-2-2021-06"
+	newPolicy := "ELBSecurityPolicy-TLS13-1-3-2021-06"
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &countingELBV2{MockELBV2: &mockelbv2.MockELBV2{EC2: ec2Client}}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(certificate string, sslPolicy string) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		tg1 := &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                nlb1.VPC,
+			Tags:               map[string]string{"Name": "tg1"},
+			Protocol:           elbv2types.ProtocolEnumTcp,
+			Port:               fi.PtrTo(int32(443)),
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+		}
+		allTasks["tg1"] = tg1
+		allTasks["listener1"] = &NetworkLoadBalancerListener{
+			Name:                s("listener1"),
+			Lifecycle:           fi.LifecycleSync,
+			NetworkLoadBalancer: nlb1,
+			Port:                443,
+			TargetGroup:         tg1,
+			SSLCertificateID:    certificate,
+			SSLPolicy:           sslPolicy,
+			WaitConfig:          &ELBV2WaitConfig{CertificateRotationSettleTime: time.Millisecond},
+		}
+		return allTasks
+	}
+
+	allTasks := buildTasks(oldCertificateARN, oldPolicy)
+	runNLBTasks(t, cloud, allTasks)
+	listenerArn := allTasks["listener1"].(*NetworkLoadBalancerListener).listenerArn
+
+	c.modifyListenerCalls, c.modifyListenerRequests = 0, nil
+	c.createListenerCalls, c.deleteListenerCalls = 0, 0
+	runNLBTasks(t, cloud, buildTasks(newCertificateARN, newPolicy))
+
+	if c.createListenerCalls != 0 || c.deleteListenerCalls != 0 {
+		t.Errorf("expected the listener not to be recreated, got %d creations and %d deletions", c.createListenerCalls, c.deleteListenerCalls)
+	}
+	if c.modifyListenerCalls != 1 {
+		t.Fatalf("expected the certificate and SSL policy to be changed in 1 request, got %d", c.modifyListenerCalls)
+	}
+	request := c.modifyListenerRequests[0]
+	if fi.ValueOf(request.ListenerArn) != listenerArn {
+		t.Errorf("expected listener %q to be modified, got %q", listenerArn, fi.ValueOf(request.ListenerArn))
+	}
+	if len(request.Certificates) != 1 || fi.ValueOf(request.Certificates[0].CertificateArn) != newCertificateARN {
+		t.Errorf("expected certificate %q in the request, got %v", newCertificateARN, request.Certificates)
+	}
+	if fi.ValueOf(request.SslPolicy) != newPolicy {
+		t.Errorf("expected SSL policy %q in the request, got %q", newPolicy, fi.ValueOf(request.SslPolicy))
+	}
+
+	checkNLBNoChanges(t, ctx, cloud, buildTasks(newCertificateARN, newPolicy))
+}
+
+// reversedCertificatesELBV2 lists the certificates of listeners in reverse order, so that the default certificate is not first.
+type reversedCertificatesELBV2 struct {
+	*mockelbv2.MockELBV2
+}
+
+func (m *reversedCertificatesELBV2) DescribeListenerCertificates(ctx context.Context, request *elbv2.DescribeListenerCertificatesInput, optFns ...func(*elbv2.Options)) (*elbv2.DescribeListenerCertificatesOutput, error) {
+	response, err := m.MockELBV2.DescribeListenerCertificates(ctx, request, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(response.Certificates)
+	return response, nil
+}
+
+func TestNetworkLoadBalancerListenerFindDefaultCertificateNotFirst(t *testing.T) {
+	ctx := context.TODO()
+
+	defaultCertificateARN := "arn:aws:acm:us-east-1:000000000000:certificate/default"
+	apiCertificateARN := "arn:aws:acm:us-east-1:000000000000:certificate/api"
+	internalCertificateARN := "arn:aws:acm:us-east-1:000000000000:certificate/internal"
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &reversedCertificatesELBV2{MockELBV2: &mockelbv2.MockELBV2{EC2: ec2Client}}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func() map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		tg1 := &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                nlb1.VPC,
+			Tags:               map[string]string{"Name": "tg1"},
+			Protocol:           elbv2types.ProtocolEnumTcp,
+			Port:               fi.PtrTo(int32(443)),
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+		}
+		allTasks["tg1"] = tg1
+		allTasks["listener1"] = &NetworkLoadBalancerListener{
+			Name:                   s("listener1"),
+			Lifecycle:              fi.LifecycleSync,
+			NetworkLoadBalancer:    nlb1,
+			Port:                   443,
+			TargetGroup:            tg1,
+			SSLCertificateID:       defaultCertificateARN,
+			AdditionalCertificates: []string{apiCertificateARN, internalCertificateARN},
+		}
+		return allTasks
+	}
+
+	allTasks := buildTasks()
+	runNLBTasks(t, cloud, allTasks)
+	listenerArn := allTasks["listener1"].(*NetworkLoadBalancerListener).listenerArn
+
+	certificates, err := awsup.ListELBV2ListenerCertificates(ctx, cloud, listenerArn)
+	if err != nil {
+		t.Fatalf("error listing listener certificates: %v", err)
+	}
+	if len(certificates) == 0 || fi.ValueOf(certificates[0].CertificateArn) == defaultCertificateARN {
+		t.Fatalf("expected the default certificate not to be listed first, got %v", certificates)
+	}
+
+	cloudupContext, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, &awsup.AWSAPITarget{Cloud: cloud}, &kops.Cluster{}, cloud, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+	e := buildTasks()["listener1"].(*NetworkLoadBalancerListener)
+	e.NetworkLoadBalancer.loadBalancerArn = allTasks["nlb1"].(*NetworkLoadBalancer).loadBalancerArn
+	actual, err := e.Find(cloudupContext)
+	if err != nil {
+		t.Fatalf("error finding listener: %v", err)
+	}
+	if actual == nil {
+		t.Fatalf("listener not found")
+	}
+	if actual.SSLCertificateID != defaultCertificateARN {
+		t.Errorf("expected default certificate %q, got %q", defaultCertificateARN, actual.SSLCertificateID)
+	}
+	if expected := []string{apiCertificateARN, internalCertificateARN}; !reflect.DeepEqual(actual.AdditionalCertificates, expected) {
+		t.Errorf("expected additional certificates %v, got %v", expected, actual.AdditionalCertificates)
+	}
+
+	checkNLBNoChanges(t, ctx, cloud, buildTasks())
+}
+
+func TestNetworkLoadBalancerListenerClusterTagsTerraform(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("eu-west-2", "abc").WithTags(map[string]string{
+		"KubernetesCluster":                 "example.com",
+		"kubernetes.io/cluster/example.com": "owned",
+	})
+	outDir := t.TempDir()
+	target := terraform.NewTerraformTarget(cloud, "test", outDir, nil)
+
+	nlb1 := &NetworkLoadBalancer{
+		Name:                 s("nlb1"),
+		LoadBalancerBaseName: s("nlb1"),
+	}
+	listener := &NetworkLoadBalancerListener{
+		Name:                nlb1.Name,
+		NetworkLoadBalancer: nlb1,
+		Port:                443,
+		TargetGroup:         &TargetGroup{Name: s("tg1")},
+		Tags:                map[string]string{"team": "a"},
+		CorrelationID:       "api.example.com-443",
+		Protected:           true,
+	}
+	if err := listener.RenderTerraform(target, nil, listener, listener); err != nil {
+		t.Fatalf("unexpected error rendering: %v", err)
+	}
+	if err := target.Finish(map[string]fi.CloudupTask{}); err != nil {
+		t.Fatalf("error finishing terraform target: %v", err)
+	}
+	b, err := os.ReadFile(filepath.Join(outDir, "kubernetes.tf"))
+	if err != nil {
+		t.Fatalf("error reading terraform output: %v", err)
+	}
+
+	expected := `  tags = {
+    "KubernetesCluster"                   = "example.com"
+    "kops.k8s.io/listener-correlation-id" = "api.example.com-443"
+    "kops.k8s.io/protected"               = "true"
+    "kubernetes.io/cluster/example.com"   = "owned"
+    "team"                                = "a"
+  }
+`
+	if !strings.Contains(string(b), expected) {
+		t.Errorf("expected the listener to be rendered with tags\n%s\ngot\n%s", expected, string(b))
+	}
+}
+
+func TestNetworkLoadBalancerListenerAdditionalCertificatesTerraform(t *testing.T) {
+	nlb1 := &NetworkLoadBalancer{
+		Name:                 s("nlb1"),
+		LoadBalancerBaseName: s("nlb1"),
+	}
+	cases := []*renderTest{
+		{
+			Resource: &NetworkLoadBalancerListener{
+				Name:                   nlb1.Name,
+				NetworkLoadBalancer:    nlb1,
+				Port:                   443,
+				TargetGroup:            &TargetGroup{Name: s("tg1")},
+				SSLCertificateID:       "arn:aws:acm:us-east-1:000000000000:certificate/default",
+				AdditionalCertificates: []string{"arn:aws:acm:us-east-1:000000000000:certificate/api"},
+			},
+			Expected: `provider "aws" {
+  region = "eu-west-2"
+}
+
+resource "aws_lb_listener" "nlb1-443" {
+  certificate_arn = "arn:aws:acm:us-east-1:000000000000:certificate/default"
+  default_action {
+    target_group_arn = aws_lb_target_group.tg1.id
+    type             = "forward"
+  }
+  load_balancer_arn = aws_lb.nlb1.id
+  port              = 443
+  protocol          = "TLS"
+}
+
+resource "aws_lb_listener_certificate" "nlb1-443-api" {
+  certificate_arn = "arn:aws:acm:us-east-1:000000000000:certificate/api"
+  listener_arn    = aws_lb_listener.nlb1-443.arn
+}
+
+terraform {
+  required_version = ">= 0.15.0"
+  required_providers {
+    aws = {
+      "source"  = "hashicorp/aws"
+      "version" = ">= 5.0.0"
+    }
+  }
+}
+`,
+		},
+	}
+
+	doRenderTests(t, "RenderTerraform", cases)
+}
+
+func TestNetworkLoadBalancerListenerAdditionalCertificatesTerraformNames(t *testing.T) {
+	nlb1 := &NetworkLoadBalancer{
+		Name:                 s("nlb1"),
+		LoadBalancerBaseName: s("nlb1"),
+	}
+	cases := []*renderTest{
+		{
+			Resource: &NetworkLoadBalancerListener{
+				Name:                nlb1.Name,
+				NetworkLoadBalancer: nlb1,
+				Port:                443,
+				TargetGroup:         &TargetGroup{Name: s("tg1")},
+				SSLCertificateID:    "arn:aws:acm:us-east-1:000000000000:certificate/default",
+				AdditionalCertificates: []string{
+					"arn:aws:iam::000000000000:server-certificate/internal/api",
+					"arn:aws:iam::000000000000:server-certificate/external/api",
+					"arn:aws:acm:us-east-1:000000000000:certificate/other",
+				},
+			},
+			Expected: `provider "aws" {
+  region = "eu-west-2"
+}
+
+resource "aws_lb_listener" "nlb1-443" {
+  certificate_arn = "arn:aws:acm:us-east-1:000000000000:certificate/default"
+  default_action {
+    target_group_arn = aws_lb_target_group.tg1.id
+    type             = "forward"
+  }
+  load_balancer_arn = aws_lb.nlb1.id
+  port              = 443
+  protocol          = "TLS"
+}
+
+resource "aws_lb_listener_certificate" "nlb1-443-api-14f4d8a0" {
+  certificate_arn = "arn:aws:iam::000000000000:server-certificate/internal/api"
+  listener_arn    = aws_lb_listener.nlb1-443.arn
+}
+
+resource "aws_lb_listener_certificate" "nlb1-443-api-d1b8b6e8" {
+  certificate_arn = "arn:aws:iam::000000000000:server-certificate/external/api"
+  listener_arn    = aws_lb_listener.nlb1-443.arn
+}
+
+resource "aws_lb_listener_certificate" "nlb1-443-other" {
+  certificate_arn = "arn:aws:acm:us-east-1:000000000000:certificate/other"
+  listener_arn    = aws_lb_listener.nlb1-443.arn
+}
+
+terraform {
+  required_version = ">= 0.15.0"
+  required_providers {
+    aws = {
+      "source"  = "hashicorp/aws"
+      "version" = ">= 5.0.0"
+    }
+  }
+}
+`,
+		},
+	}
+
+	doRenderTests(t, "RenderTerraform", cases)
+}
+
+func TestNetworkLoadBalancerListenerCheckChangesALPNPolicy(t *testing.T) {
+	grid := []struct {
+		name             string
+		sslCertificateID string
+		alpnPolicy       string
+		expectError      bool
+	}{
+		{
+			name:             "tls without alpn",
+			sslCertificateID: "arn:aws-test:acm:us-test-1:000000000000:certificate/1",
+		},
+		{
+			name:             "tls with alpn",
+			sslCertificateID: "arn:aws-test:acm:us-test-1:000000000000:certificate/1",
+			alpnPolicy:       "HTTP2Preferred",
+		},
+		{
+			name:             "tls with none",
+			sslCertificateID: "arn:aws-test:acm:us-test-1:000000000000:certificate/1",
+			alpnPolicy:       "None",
+		},
+		{
+			name:             "unknown alpn policy",
+			sslCertificateID: "arn:aws-test:acm:us-test-1:000000000000:certificate/1",
+			alpnPolicy:       "HTTP3Only",
+			expectError:      true,
+		},
+		{
+			name:        "tcp with alpn",
+			alpnPolicy:  "HTTP2Only",
+			expectError: true,
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			listener := &NetworkLoadBalancerListener{
+				Name:                s("listener"),
+				NetworkLoadBalancer: &NetworkLoadBalancer{},
+				Port:                443,
+				TargetGroup:         &TargetGroup{Name: s("tg")},
+				SSLCertificateID:    g.sslCertificateID,
+				ALPNPolicy:          g.alpnPolicy,
+			}
+			err := (&NetworkLoadBalancerListener{}).CheckChanges(nil, listener, listener)
+			if g.expectError && err == nil {
+				t.Errorf("expected error, got none")
+			}
+			if !g.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestNetworkLoadBalancerListenerALPNPolicyTerraform(t *testing.T) {
+	nlb1 := &NetworkLoadBalancer{
+		Name:                 s("nlb1"),
+		LoadBalancerBaseName: s("nlb1"),
+	}
+	cases := []*renderTest{
+		{
+			Resource: &NetworkLoadBalancerListener{
+				Name:                nlb1.Name,
+				NetworkLoadBalancer: nlb1,
+				Port:                443,
+				TargetGroup:         &TargetGroup{Name: s("tg1")},
+				SSLCertificateID:    "arn:aws:acm:us-east-1:000000000000:certificate/default",
+				ALPNPolicy:          "HTTP2Preferred",
+			},
+			Expected: `provider "aws" {
+  region = "eu-west-2"
+}
+
+resource "aws_lb_listener" "nlb1-443" {
+  alpn_policy     = "HTTP2Preferred"
+  certificate_arn = "arn:aws:acm:us-east-1:000000000000:certificate/default"
+  default_action {
+    target_group_arn = aws_lb_target_group.tg1.id
+    type             = "forward"
+  }
+  load_balancer_arn = aws_lb.nlb1.id
+  port              = 443
+  protocol          = "TLS"
+}
+
+terraform {
+  required_version = ">= 0.15.0"
+  required_providers {
+    aws = {
+      "source"  = "hashicorp/aws"
+      "version" = ">= 5.0.0"
+    }
+  }
+}
+`,
+		},
+	}
+
+	doRenderTests(t, "RenderTerraform", cases)
+}
+
+func TestNetworkLoadBalancerListenerCheckChangesProtocol(t *testing.T) {
+	certificateARN := "arn:aws-test:acm:us-test-1:000000000000:certificate/1"
+
+	grid := []struct {
+		name             string
+		protocol         elbv2types.ProtocolEnum
+		sslCertificateID string
+		expectError      bool
+	}{
+		{name: "default tcp"},
+		{name: "default tls", sslCertificateID: certificateARN},
+		{name: "udp", protocol: elbv2types.ProtocolEnumUdp},
+		{name: "tcp_udp", protocol: elbv2types.ProtocolEnumTcpUdp},
+		{name: "tls", protocol: elbv2types.ProtocolEnumTls, sslCertificateID: certificateARN},
+		{name: "tls without certificate", protocol: elbv2types.ProtocolEnumTls, expectError: true},
+		{name: "udp with certificate", protocol: elbv2types.ProtocolEnumUdp, sslCertificateID: certificateARN, expectError: true},
+		{name: "tcp with certificate", protocol: elbv2types.ProtocolEnumTcp, sslCertificateID: certificateARN, expectError: true},
+		{name: "http", protocol: elbv2types.ProtocolEnumHttp, expectError: true},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			listener := &NetworkLoadBalancerListener{
+				Name:                s("listener"),
+				NetworkLoadBalancer: &NetworkLoadBalancer{},
+				Port:                53,
+				Protocol:            g.protocol,
+				TargetGroup:         &TargetGroup{Name: s("tg")},
+				SSLCertificateID:    g.sslCertificateID,
+			}
+			err := (&NetworkLoadBalancerListener{}).CheckChanges(nil, listener, listener)
+			if g.expectError && err == nil {
+				t.Errorf("expected error, got none")
+			}
+			if !g.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestNetworkLoadBalancerListenerCheckChangesTargetGroupProtocol(t *testing.T) {
+	certificateARN := "arn:aws-test:acm:us-test-1:000000000000:certificate/1"
+
+	grid := []struct {
+		name                string
+		protocol            elbv2types.ProtocolEnum
+		targetGroupProtocol elbv2types.ProtocolEnum
+		expectError         bool
+	}{
+		{name: "tcp to tcp", protocol: elbv2types.ProtocolEnumTcp, targetGroupProtocol: elbv2types.ProtocolEnumTcp},
+		{name: "tls passthrough", protocol: elbv2types.ProtocolEnumTcp, targetGroupProtocol: elbv2types.ProtocolEnumTls},
+		{name: "tls to tcp", protocol: elbv2types.ProtocolEnumTls, targetGroupProtocol: elbv2types.ProtocolEnumTcp},
+		{name: "tls to tls", protocol: elbv2types.ProtocolEnumTls, targetGroupProtocol: elbv2types.ProtocolEnumTls},
+		{name: "udp to tcp_udp", protocol: elbv2types.ProtocolEnumUdp, targetGroupProtocol: elbv2types.ProtocolEnumTcpUdp},
+		{name: "unknown target group protocol", protocol: elbv2types.ProtocolEnumUdp},
+		{name: "udp to tcp", protocol: elbv2types.ProtocolEnumUdp, targetGroupProtocol: elbv2types.ProtocolEnumTcp, expectError: true},
+		{name: "tcp to udp", protocol: elbv2types.ProtocolEnumTcp, targetGroupProtocol: elbv2types.ProtocolEnumUdp, expectError: true},
+		{name: "tls to tcp_udp", protocol: elbv2types.ProtocolEnumTls, targetGroupProtocol: elbv2types.ProtocolEnumTcpUdp, expectError: true},
+		{name: "tcp_udp to tcp", protocol: elbv2types.ProtocolEnumTcpUdp, targetGroupProtocol: elbv2types.ProtocolEnumTcp, expectError: true},
+		// HTTP/2 and gRPC target groups are only reachable through an Application Load Balancer
+		{name: "tls to https", protocol: elbv2types.ProtocolEnumTls, targetGroupProtocol: elbv2types.ProtocolEnumHttps, expectError: true},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			listener := &NetworkLoadBalancerListener{
+				Name:                s("listener"),
+				NetworkLoadBalancer: &NetworkLoadBalancer{},
+				Port:                443,
+				Protocol:            g.protocol,
+				TargetGroup:         &TargetGroup{Name: s("tg"), Protocol: g.targetGroupProtocol},
+			}
+			if g.protocol == elbv2types.ProtocolEnumTls {
+				listener.SSLCertificateID = certificateARN
+			}
+			err := (&NetworkLoadBalancerListener{}).CheckChanges(nil, listener, listener)
+			if g.expectError && err == nil {
+				t.Errorf("expected error, got none")
+			}
+			if !g.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestNetworkLoadBalancerListenerTLSPassthrough(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func() map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+		tg1 := &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                nlb1.VPC,
+			Tags:               map[string]string{"Name": "tg1"},
+			Protocol:           elbv2types.ProtocolEnumTls,
+			Port:               fi.PtrTo(int32(443)),
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+		}
+		allTasks["tg1"] = tg1
+		allTasks["listener1"] = &NetworkLoadBalancerListener{
+			Name:                s("listener1"),
+			Lifecycle:           fi.LifecycleSync,
+			NetworkLoadBalancer: nlb1,
+			Port:                443,
+			Protocol:            elbv2types.ProtocolEnumTcp,
+			TargetGroup:         tg1,
+		}
+		return allTasks
+	}
+
+	allTasks := buildTasks()
+	runNLBTasks(t, cloud, allTasks)
+
+	listeners, err := awsup.ListELBV2Listeners(ctx, cloud, allTasks["nlb1"].(*NetworkLoadBalancer).loadBalancerArn)
+	if err != nil {
+		t.Fatalf("error listing listeners: %v", err)
+	}
+	if len(listeners) != 1 {
+		t.Fatalf("expected 1 listener, got %d", len(listeners))
+	}
+	if listeners[0].Protocol != elbv2types.ProtocolEnumTcp || len(listeners[0].Certificates) != 0 || listeners[0].SslPolicy != nil {
+		t.Errorf("expected a TCP listener without certificates, got %+v", listeners[0])
+	}
+
+	checkNLBNoChanges(t, ctx, cloud, buildTasks())
+}
+
+func TestNetworkLoadBalancerListenerCheckChangesPort(t *testing.T) {
+	grid := []struct {
+		port          int
+		expectedError string
+	}{
+		{port: 0, expectedError: `listener "listener" has invalid Port 0, must be between 1 and 65535`},
+		{port: -1, expectedError: `listener "listener" has invalid Port -1, must be between 1 and 65535`},
+		{port: 1},
+		{port: 443},
+		{port: 65535},
+		{port: 65536, expectedError: `listener "listener" has invalid Port 65536, must be between 1 and 65535`},
+	}
+	for _, g := range grid {
+		t.Run(fmt.Sprintf("%d", g.port), func(t *testing.T) {
+			listener := &NetworkLoadBalancerListener{
+				Name:                s("listener"),
+				NetworkLoadBalancer: &NetworkLoadBalancer{},
+				Port:                g.port,
+				TargetGroup:         &TargetGroup{Name: s("tg")},
+			}
+			err := (&NetworkLoadBalancerListener{}).CheckChanges(nil, listener, listener)
+			if g.expectedError == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || err.Error() != g.expectedError {
+				t.Errorf("expected error %q, got %v", g.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestNetworkLoadBalancerListenerCheckChangesTemporaryPort(t *testing.T) {
+	ctx := context.TODO()
+
+	grid := []struct {
+		name          string
+		temporaryPort int
+		expectedError string
+	}{
+		{name: "unused port", temporaryPort: 8444},
+		{name: "own port", temporaryPort: 443, expectedError: `listener "api" TemporaryPort must be different from Port 443`},
+		{name: "port of another listener", temporaryPort: 8443, expectedError: `listener "api" TemporaryPort 8443 is the Port of listener "kops-controller"`},
+		{name: "port of a listener of another load balancer", temporaryPort: 9443},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+			cloud.MockELBV2 = &mockelbv2.MockELBV2{}
+
+			nlb := &NetworkLoadBalancer{Name: s("nlb1")}
+			listener := &NetworkLoadBalancerListener{
+				Name:                s("api"),
+				NetworkLoadBalancer: nlb,
+				Port:                443,
+				TargetGroup:         &TargetGroup{Name: s("tg")},
+				TemporaryPort:       g.temporaryPort,
+			}
+			tasks := map[string]fi.CloudupTask{
+				"api": listener,
+				"kops-controller": &NetworkLoadBalancerListener{
+					Name:                s("kops-controller"),
+					NetworkLoadBalancer: nlb,
+					Port:                8443,
+					TargetGroup:         &TargetGroup{Name: s("tg")},
+				},
+				"other": &NetworkLoadBalancerListener{
+					Name:                s("other"),
+					NetworkLoadBalancer: &NetworkLoadBalancer{Name: s("nlb2")},
+					Port:                9443,
+					TargetGroup:         &TargetGroup{Name: s("tg")},
+				},
+			}
+			cloudupContext, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, &awsup.AWSAPITarget{Cloud: cloud}, &kops.Cluster{}, cloud, nil, nil, nil, tasks)
+			if err != nil {
+				t.Fatalf("error building context: %v", err)
+			}
+			if err := listener.Normalize(cloudupContext); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			err = (&NetworkLoadBalancerListener{}).CheckChanges(nil, listener, listener)
+			if g.expectedError == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || err.Error() != g.expectedError {
+				t.Errorf("expected error %q, got %v", g.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestNetworkLoadBalancerListenerNormalizeSSLPolicy(t *testing.T) {
+	grid := []struct {
+		name             string
+		sslCertificateID string
+		sslPolicy        string
+		expectedPolicy   string
+		expectedError    string
+	}{
+		{name: "no certificate"},
+		{
+			name:             "default policy",
+			sslCertificateID: "arn:aws-test:acm:us-test-1:000000000000:certificate/1",
+			expectedPolicy:   awsup.DefaultNetworkLoadBalancerSSLPolicy,
+		},
+		{
+			name:             "explicit policy",
+			sslCertificateID: "arn:aws-test:acm:us-test-1:000000000000:certificate/1",
+			sslPolicy:        "ELBSecurityPolicy-TLS13-1-3-2021-06",
+			expectedPolicy:   "ELBSecurityPolicy-TLS13-1-3-2021-06",
+		},
+		{
+			name:             "mistyped policy",
+			sslCertificateID: "arn:aws-test:acm:us-test-1:000000000000:certificate/1",
+			sslPolicy:        "ELBSecurityPolicy-TLS13-1-2-2021-07",
+			expectedError:    `listener "listener" has unknown SSL policy "ELBSecurityPolicy-TLS13-1-2-2021-07", did you mean "ELBSecurityPolicy-TLS13-1-2-2021-06"?`,
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			listener := &NetworkLoadBalancerListener{
+				Name:             s("listener"),
+				Port:             443,
+				SSLCertificateID: g.sslCertificateID,
+				SSLPolicy:        g.sslPolicy,
+			}
+			err := listener.Normalize(nil)
+			if g.expectedError == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				if listener.SSLPolicy != g.expectedPolicy {
+					t.Errorf("expected SSL policy %q, got %q", g.expectedPolicy, listener.SSLPolicy)
+				}
+			} else if err == nil || err.Error() != g.expectedError {
+				t.Errorf("expected error %q, got %v", g.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestNetworkLoadBalancerListenerInheritSSLPolicy(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	cloud.MockELBV2 = &mockelbv2.MockELBV2{}
+	cloudupContext, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, &awsup.AWSAPITarget{Cloud: cloud}, &kops.Cluster{}, cloud, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+
+	grid := []struct {
+		name             string
+		nlbSSLPolicy     string
+		sslCertificateID string
+		sslPolicy        string
+		expectedPolicy   string
+	}{
+		{
+			name:             "inherited by TLS listener",
+			nlbSSLPolicy:     "ELBSecurityPolicy-TLS13-1-3-2021-06",
+			sslCertificateID: "arn:aws:acm:us-east-1:000000000000:certificate/1",
+			expectedPolicy:   "ELBSecurityPolicy-TLS13-1-3-2021-06",
+		},
+		{
+			name:             "listener policy wins",
+			nlbSSLPolicy:     "ELBSecurityPolicy-TLS13-1-3-2021-06",
+			sslCertificateID: "arn:aws:acm:us-east-1:000000000000:certificate/1",
+			sslPolicy:        "ELBSecurityPolicy-TLS13-1-2-2021-06",
+			expectedPolicy:   "ELBSecurityPolicy-TLS13-1-2-2021-06",
+		},
+		{
+			name:         "not inherited by TCP listener",
+			nlbSSLPolicy: "ELBSecurityPolicy-TLS13-1-3-2021-06",
+		},
+		{
+			name:             "default without load balancer policy",
+			sslCertificateID: "arn:aws:acm:us-east-1:000000000000:certificate/1",
+			expectedPolicy:   awsup.DefaultNetworkLoadBalancerSSLPolicy,
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			listener := &NetworkLoadBalancerListener{
+				Name:                s("listener"),
+				NetworkLoadBalancer: &NetworkLoadBalancer{Name: s("nlb1"), SSLPolicy: g.nlbSSLPolicy, certificates: &elbv2CertificateCache{}},
+				Port:                443,
+				SSLCertificateID:    g.sslCertificateID,
+				SSLPolicy:           g.sslPolicy,
+			}
+			if err := listener.Normalize(cloudupContext); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if listener.SSLPolicy != g.expectedPolicy {
+				t.Errorf("expected SSL policy %q, got %q", g.expectedPolicy, listener.SSLPolicy)
+			}
+		})
+	}
+}
+
+func TestNetworkLoadBalancerListenerUDP(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &countingELBV2{MockELBV2: &mockelbv2.MockELBV2{EC2: ec2Client}}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(protocol elbv2types.ProtocolEnum) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		tg1 := &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                nlb1.VPC,
+			Tags:               map[string]string{"Name": "tg1"},
+			Protocol:           elbv2types.ProtocolEnumTcpUdp,
+			Port:               fi.PtrTo(int32(53)),
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+		}
+		allTasks["tg1"] = tg1
+		allTasks["listener1"] = &NetworkLoadBalancerListener{
+			Name:                s("listener1"),
+			Lifecycle:           fi.LifecycleSync,
+			NetworkLoadBalancer: nlb1,
+			Port:                53,
+			Protocol:            protocol,
+			TargetGroup:         tg1,
+		}
+		return allTasks
+	}
+
+	for _, protocol := range []elbv2types.ProtocolEnum{elbv2types.ProtocolEnumUdp, elbv2types.ProtocolEnumTcpUdp} {
+		c.createListenerCalls, c.deleteListenerCalls = 0, 0
+
+		allTasks := buildTasks(protocol)
+		runNLBTasks(t, cloud, allTasks)
+		listenerArn := allTasks["listener1"].(*NetworkLoadBalancerListener).listenerArn
+
+		if c.createListenerCalls != 1 {
+			t.Errorf("expected the listener to be created with protocol %q, got %d creations", protocol, c.createListenerCalls)
+		}
+		listeners, err := c.DescribeListeners(ctx, &elbv2.DescribeListenersInput{ListenerArns: []string{listenerArn}})
+		if err != nil {
+			t.Fatalf("error describing listeners: %v", err)
+		}
+		if actual := listeners.Listeners[0].Protocol; actual != protocol {
+			t.Errorf("expected protocol %q, got %q", protocol, actual)
+		}
+
+		allTasks = buildTasks(protocol)
+		checkNLBNoChanges(t, ctx, cloud, allTasks)
+	}
+}
+
+func TestNetworkLoadBalancerListenerModifyInPlace(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &countingELBV2{MockELBV2: &mockelbv2.MockELBV2{EC2: ec2Client}}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(sslPolicy string, targetGroup string) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		for _, name := range []string{"tg1", "tg2"} {
+			allTasks[name] = &TargetGroup{
+				Name:               s(name),
+				Lifecycle:          fi.LifecycleSync,
+				VPC:                nlb1.VPC,
+				Tags:               map[string]string{"Name": name},
+				Protocol:           elbv2types.ProtocolEnumTcp,
+				Port:               fi.PtrTo(int32(443)),
+				Interval:           fi.PtrTo(int32(10)),
+				HealthyThreshold:   fi.PtrTo(int32(2)),
+				UnhealthyThreshold: fi.PtrTo(int32(2)),
+			}
+		}
+		allTasks["listener1"] = &NetworkLoadBalancerListener{
+			Name:                s("listener1"),
+			Lifecycle:           fi.LifecycleSync,
+			NetworkLoadBalancer: nlb1,
+			Port:                443,
+			TargetGroup:         allTasks[targetGroup].(*TargetGroup),
+			SSLCertificateID:    "arn:aws:acm:us-east-1:000000000000:certificate/tls",
+			SSLPolicy:           sslPolicy,
+		}
+		return allTasks
+	}
+
+	var listenerArn string
+	{
+		allTasks := buildTasks("ELBSecurityPolicy-TLS13-1-2-2021-06", "tg1")
+		runNLBTasks(t, cloud, allTasks)
+		listenerArn = allTasks["listener1"].(*NetworkLoadBalancerListener).listenerArn
+	}
+
+	grid := []struct {
+		name        string
+		sslPolicy   string
+		targetGroup string
+	}{
+		{name: "ssl policy", sslPolicy: "ELBSecurityPolicy-TLS13-1-3-2021-06", targetGroup: "tg1"},
+		{name: "target group", sslPolicy: "ELBSecurityPolicy-TLS13-1-3-2021-06", targetGroup: "tg2"},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			c.createListenerCalls, c.deleteListenerCalls, c.modifyListenerCalls = 0, 0, 0
+
+			allTasks := buildTasks(g.sslPolicy, g.targetGroup)
+			runNLBTasks(t, cloud, allTasks)
+
+			if c.createListenerCalls != 0 || c.deleteListenerCalls != 0 {
+				t.Errorf("expected the listener not to be recreated, got %d creations and %d deletions", c.createListenerCalls, c.deleteListenerCalls)
+			}
+			if c.modifyListenerCalls != 1 {
+				t.Errorf("expected a single ModifyListener call, got %d", c.modifyListenerCalls)
+			}
+
+			listeners, err := c.DescribeListeners(ctx, &elbv2.DescribeListenersInput{ListenerArns: []string{listenerArn}})
+			if err != nil {
+				t.Fatalf("error describing listeners: %v", err)
+			}
+			listener := listeners.Listeners[0]
+			if sslPolicy := fi.ValueOf(listener.SslPolicy); sslPolicy != g.sslPolicy {
+				t.Errorf("expected SSL policy %q, got %q", g.sslPolicy, sslPolicy)
+			}
+			targetGroupARN := fi.ValueOf(allTasks[g.targetGroup].(*TargetGroup).ARN)
+			if actual := fi.ValueOf(listener.DefaultActions[0].TargetGroupArn); actual != targetGroupARN {
+				t.Errorf("expected listener to forward to %q, got %q", targetGroupARN, actual)
+			}
+
+			allTasks = buildTasks(g.sslPolicy, g.targetGroup)
+			checkNLBNoChanges(t, ctx, cloud, allTasks)
+		})
+	}
+}
+
+func TestNetworkLoadBalancerListenerFindTargetGroup(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	cloud.MockELBV2 = &mockelbv2.MockELBV2{EC2: ec2Client}
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func() map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+		tg1 := &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                nlb1.VPC,
+			Tags:               map[string]string{"Name": "tg1"},
+			Protocol:           elbv2types.ProtocolEnumTcp,
+			Port:               fi.PtrTo(int32(443)),
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+		}
+		allTasks["tg1"] = tg1
+		allTasks["listener1"] = &NetworkLoadBalancerListener{
+			Name:                s("listener1"),
+			Lifecycle:           fi.LifecycleSync,
+			NetworkLoadBalancer: nlb1,
+			Port:                443,
+			TargetGroup:         tg1,
+		}
+		return allTasks
+	}
+
+	allTasks := buildTasks()
+	runNLBTasks(t, cloud, allTasks)
+	targetGroupARN := allTasks["tg1"].(*TargetGroup).ARN
+
+	for _, g := range []struct {
+		name          string
+		arn           *string
+		expectChanges bool
+	}{
+		{name: "known ARN", arn: targetGroupARN},
+		{name: "unknown ARN", expectChanges: true},
+	} {
+		t.Run(g.name, func(t *testing.T) {
+			allTasks := buildTasks()
+			cloudupContext, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, &awsup.AWSAPITarget{Cloud: cloud}, &kops.Cluster{}, cloud, nil, nil, nil, allTasks)
+			if err != nil {
+				t.Fatalf("error building context: %v", err)
+			}
+			if _, err := allTasks["nlb1"].(*NetworkLoadBalancer).Find(cloudupContext); err != nil {
+				t.Fatalf("error finding load balancer: %v", err)
+			}
+
+			// The desired target group is a fully populated task, the actual one is only known by its ARN
+			e := allTasks["listener1"].(*NetworkLoadBalancerListener)
+			e.TargetGroup.ARN = g.arn
+			actual, err := e.Find(cloudupContext)
+			if err != nil {
+				t.Fatalf("error finding listener: %v", err)
+			}
+			changes := &NetworkLoadBalancerListener{}
+			fi.BuildChanges(actual, e, changes)
+			if changed := changes.TargetGroup != nil; changed != g.expectChanges {
+				t.Errorf("expected target group changed to be %v, got changes %v", g.expectChanges, fi.DebugAsJsonString(changes))
+			}
+			if !g.expectChanges && actual.TargetGroup != e.TargetGroup {
+				t.Errorf("expected the desired target group to be reused, got %v", fi.DebugAsJsonString(actual.TargetGroup))
+			}
+		})
+	}
+}
+
+func TestNetworkLoadBalancerListenerRecreatedTargetGroup(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &countingELBV2{MockELBV2: &mockelbv2.MockELBV2{EC2: ec2Client}}
+	cloud.MockELBV2 = c
+
+	certificate := "arn:aws:acm:us-east-1:000000000000:certificate/tls"
+	sslPolicy := "ELBSecurityPolicy-TLS13-1-3-2021-06"
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func() map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+		tg1 := &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                nlb1.VPC,
+			Tags:               map[string]string{"Name": "tg1"},
+			Protocol:           elbv2types.ProtocolEnumTcp,
+			Port:               fi.PtrTo(int32(443)),
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+		}
+		allTasks["tg1"] = tg1
+		allTasks["listener1"] = &NetworkLoadBalancerListener{
+			Name:                s("listener1"),
+			Lifecycle:           fi.LifecycleSync,
+			NetworkLoadBalancer: nlb1,
+			Port:                443,
+			TargetGroup:         tg1,
+			SSLCertificateID:    certificate,
+			SSLPolicy:           sslPolicy,
+		}
+		return allTasks
+	}
+
+	allTasks := buildTasks()
+	runNLBTasks(t, cloud, allTasks)
+	listenerArn := allTasks["listener1"].(*NetworkLoadBalancerListener).listenerArn
+	oldTargetGroupARN := fi.ValueOf(allTasks["tg1"].(*TargetGroup).ARN)
+
+	// The target group is deleted outside of kops, so it is recreated with a new ARN
+	if _, err := c.MockELBV2.DeleteTargetGroup(ctx, &elbv2.DeleteTargetGroupInput{TargetGroupArn: s(oldTargetGroupARN)}); err != nil {
+		t.Fatalf("error deleting target group: %v", err)
+	}
+
+	c.createListenerCalls, c.deleteListenerCalls, c.modifyListenerCalls = 0, 0, 0
+	allTasks = buildTasks()
+	runNLBTasks(t, cloud, allTasks)
+
+	newTargetGroupARN := fi.ValueOf(allTasks["tg1"].(*TargetGroup).ARN)
+	if newTargetGroupARN == oldTargetGroupARN {
+		t.Fatalf("expected the target group to be recreated with a new ARN")
+	}
+	if c.createListenerCalls != 0 || c.deleteListenerCalls != 0 {
+		t.Errorf("expected the listener not to be recreated, got %d creations and %d deletions", c.createListenerCalls, c.deleteListenerCalls)
+	}
+	if c.modifyListenerCalls != 1 {
+		t.Errorf("expected a single ModifyListener call, got %d", c.modifyListenerCalls)
+	}
+
+	listeners, err := c.DescribeListeners(ctx, &elbv2.DescribeListenersInput{ListenerArns: []string{listenerArn}})
+	if err != nil {
+		t.Fatalf("error describing listeners: %v", err)
+	}
+	listener := listeners.Listeners[0]
+	if actual := fi.ValueOf(listener.DefaultActions[0].TargetGroupArn); actual != newTargetGroupARN {
+		t.Errorf("expected listener to forward to %q, got %q", newTargetGroupARN, actual)
+	}
+	if actual := fi.ValueOf(listener.SslPolicy); actual != sslPolicy {
+		t.Errorf("expected SSL policy %q to be kept, got %q", sslPolicy, actual)
+	}
+	if len(listener.Certificates) != 1 || fi.ValueOf(listener.Certificates[0].CertificateArn) != certificate {
+		t.Errorf("expected certificate %q to be kept, got %+v", certificate, listener.Certificates)
+	}
+
+	checkNLBNoChanges(t, ctx, cloud, buildTasks())
+}
+func TestNetworkLoadBalancerListenerDowngradeTLS(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &countingELBV2{MockELBV2: &mockelbv2.MockELBV2{EC2: ec2Client}}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(tls bool) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		allTasks["tg1"] = &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                nlb1.VPC,
+			Tags:               map[string]string{"Name": "tg1"},
+			Protocol:           elbv2types.ProtocolEnumTcp,
+			Port:               fi.PtrTo(int32(443)),
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+		}
+		listener := &NetworkLoadBalancerListener{
+			Name:                s("listener1"),
+			Lifecycle:           fi.LifecycleSync,
+			NetworkLoadBalancer: nlb1,
+			Port:                443,
+			TargetGroup:         allTasks["tg1"].(*TargetGroup),
+		}
+		if tls {
+			listener.SSLCertificateID = "arn:aws:acm:us-east-1:000000000000:certificate/tls"
+			listener.AdditionalCertificates = []string{"arn:aws:acm:us-east-1:000000000000:certificate/extra"}
+			listener.SSLPolicy = "ELBSecurityPolicy-TLS13-1-2-2021-06"
+			listener.ALPNPolicy = "HTTP2Preferred"
+		}
+		allTasks["listener1"] = listener
+		return allTasks
+	}
+
+	var listenerArn string
+	{
+		allTasks := buildTasks(true)
+		runNLBTasks(t, cloud, allTasks)
+		listenerArn = allTasks["listener1"].(*NetworkLoadBalancerListener).listenerArn
+	}
+
+	c.createListenerCalls, c.deleteListenerCalls, c.modifyListenerCalls = 0, 0, 0
+	runNLBTasks(t, cloud, buildTasks(false))
+
+	if c.createListenerCalls != 0 || c.deleteListenerCalls != 0 {
+		t.Errorf("expected the listener not to be recreated, got %d creations and %d deletions", c.createListenerCalls, c.deleteListenerCalls)
+	}
+	if c.modifyListenerCalls != 1 {
+		t.Errorf("expected a single ModifyListener call, got %d", c.modifyListenerCalls)
+	}
+
+	listeners, err := c.DescribeListeners(ctx, &elbv2.DescribeListenersInput{ListenerArns: []string{listenerArn}})
+	if err != nil {
+		t.Fatalf("error describing listeners: %v", err)
+	}
+	listener := listeners.Listeners[0]
+	if listener.Protocol != elbv2types.ProtocolEnumTcp {
+		t.Errorf("expected protocol %q, got %q", elbv2types.ProtocolEnumTcp, listener.Protocol)
+	}
+	if len(listener.Certificates) != 0 {
+		t.Errorf("expected no certificates, got %v", listener.Certificates)
+	}
+	if listener.SslPolicy != nil {
+		t.Errorf("expected no SSL policy, got %q", fi.ValueOf(listener.SslPolicy))
+	}
+	if len(listener.AlpnPolicy) != 0 {
+		t.Errorf("expected no ALPN policy, got %v", listener.AlpnPolicy)
+	}
+
+	checkNLBNoChanges(t, ctx, cloud, buildTasks(false))
+}
+
+func TestNetworkLoadBalancerListenerCheckChangesWeights(t *testing.T) {
+	grid := []struct {
+		name        string
+		weights     []int
+		expectError bool
+	}{
+		{name: "single", weights: []int{1}},
+		{name: "split", weights: []int{90, 10}},
+		{name: "drained", weights: []int{100, 0}},
+		{name: "maximum", weights: []int{999, 999}},
+		{name: "all zero", weights: []int{0, 0}, expectError: true},
+		{name: "negative", weights: []int{10, -1}, expectError: true},
+		{name: "too large", weights: []int{1000}, expectError: true},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			listener := &NetworkLoadBalancerListener{
+				Name:                s("listener"),
+				NetworkLoadBalancer: &NetworkLoadBalancer{},
+				Port:                443,
+			}
+			for i, weight := range g.weights {
+				listener.ForwardTargetGroups = append(listener.ForwardTargetGroups, &TargetGroupWeight{
+					TargetGroup: &TargetGroup{Name: s(fmt.Sprintf("tg%d", i))},
+					Weight:      weight,
+				})
+			}
+			err := (&NetworkLoadBalancerListener{}).CheckChanges(nil, listener, listener)
+			if g.expectError && err == nil {
+				t.Errorf("expected error, got none")
+			}
+			if !g.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestNetworkLoadBalancerListenerCheckChangesStickiness(t *testing.T) {
+	grid := []struct {
+		name        string
+		enabled     bool
+		duration    *int
+		forward     bool
+		expectError bool
+	}{
+		{name: "enabled without duration", enabled: true, forward: true},
+		{name: "minimum", enabled: true, duration: fi.PtrTo(1), forward: true},
+		{name: "maximum", enabled: true, duration: fi.PtrTo(604800), forward: true},
+		{name: "zero", enabled: true, duration: fi.PtrTo(0), forward: true, expectError: true},
+		{name: "too long", enabled: true, duration: fi.PtrTo(604801), forward: true, expectError: true},
+		{name: "duration without enabled", duration: fi.PtrTo(300), forward: true, expectError: true},
+		{name: "without weighted forward", enabled: true, duration: fi.PtrTo(300), expectError: true},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			listener := &NetworkLoadBalancerListener{
+				Name:                      s("listener"),
+				NetworkLoadBalancer:       &NetworkLoadBalancer{},
+				Port:                      443,
+				StickinessEnabled:         g.enabled,
+				StickinessDurationSeconds: g.duration,
+			}
+			if g.forward {
+				listener.ForwardTargetGroups = []*TargetGroupWeight{
+					{TargetGroup: &TargetGroup{Name: s("tg0")}, Weight: 90},
+					{TargetGroup: &TargetGroup{Name: s("tg1")}, Weight: 10},
+				}
+			} else {
+				listener.TargetGroup = &TargetGroup{Name: s("tg0")}
+			}
+			err := (&NetworkLoadBalancerListener{}).CheckChanges(nil, listener, listener)
+			if g.expectError && err == nil {
+				t.Errorf("expected error, got none")
+			}
+			if !g.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestNetworkLoadBalancerListenerFindCachesListeners(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	c := &throttlingELBV2{MockELBV2: &mockelbv2.MockELBV2{}}
+	cloud.MockELBV2 = c
+
+	lb, err := c.CreateLoadBalancer(ctx, &elbv2.CreateLoadBalancerInput{
+		Name: s("nlb1"),
+		Type: elbv2types.LoadBalancerTypeEnumNetwork,
+	})
+	if err != nil {
+		t.Fatalf("error creating load balancer: %v", err)
+	}
+	loadBalancerArn := fi.ValueOf(lb.LoadBalancers[0].LoadBalancerArn)
+	ports := []int{80, 443, 8443}
+	for _, port := range ports {
+		if _, err := c.CreateListener(ctx, &elbv2.CreateListenerInput{
+			LoadBalancerArn: &loadBalancerArn,
+			Port:            fi.PtrTo(int32(port)),
+			Protocol:        elbv2types.ProtocolEnumTcp,
+			DefaultActions:  []elbv2types.Action{{Type: elbv2types.ActionTypeEnumForward, TargetGroupArn: s(fmt.Sprintf("tg-%d", port))}},
+		}); err != nil {
+			t.Fatalf("error creating listener: %v", err)
+		}
+	}
+
+	cloudupContext, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, &awsup.AWSAPITarget{Cloud: cloud}, &kops.Cluster{}, cloud, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+
+	nlb := &NetworkLoadBalancer{loadBalancerArn: loadBalancerArn, listeners: &elbv2ListenerCache{}}
+	for _, port := range ports {
+		e := &NetworkLoadBalancerListener{
+			Name:                s(fmt.Sprintf("listener-%d", port)),
+			NetworkLoadBalancer: nlb,
+			Port:                port,
+		}
+		actual, err := e.Find(cloudupContext)
+		if err != nil {
+			t.Fatalf("unexpected error finding listener %d: %v", port, err)
+		}
+		if actual == nil {
+			t.Fatalf("listener %d not found", port)
+		}
+	}
+	if c.describeListenerCalls != 1 {
+		t.Errorf("expected 1 DescribeListeners call for %d listeners, got %d", len(ports), c.describeListenerCalls)
+	}
+
+	nlb.listeners.invalidate()
+	e := &NetworkLoadBalancerListener{
+		Name:                s("listener-80"),
+		NetworkLoadBalancer: nlb,
+		Port:                80,
+	}
+	if _, err := e.Find(cloudupContext); err != nil {
+		t.Fatalf("unexpected error finding listener: %v", err)
+	}
+	if c.describeListenerCalls != 2 {
+		t.Errorf("expected listeners to be described again after invalidation, got %d calls", c.describeListenerCalls)
+	}
+}
+
+// countingIAM counts the GetServerCertificate calls.
+type countingIAM struct {
+	*mockiam.MockIAM
+
+	getServerCertificateCalls int
+}
+
+func (m *countingIAM) GetServerCertificate(ctx context.Context, request *iam.GetServerCertificateInput, optFns ...func(*iam.Options)) (*iam.GetServerCertificateOutput, error) {
+	m.getServerCertificateCalls++
+	return m.MockIAM.GetServerCertificate(ctx, request, optFns...)
+}
+
+func TestNetworkLoadBalancerListenerNormalizeCertificate(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	iamClient := &countingIAM{MockIAM: &mockiam.MockIAM{
+		ServerCertificates: map[string]*iamtypes.ServerCertificate{"api": {}},
+	}}
+	cloud.MockIAM = iamClient
+	cloud.MockELBV2 = &mockelbv2.MockELBV2{}
+
+	cloudupContext, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, &awsup.AWSAPITarget{Cloud: cloud}, &kops.Cluster{}, cloud, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+
+	grid := []struct {
+		name             string
+		certificateARN   string
+		fallbackARN      string
+		expectedError    string
+		expectedIAMCalls int
+	}{
+		{name: "acm", certificateARN: "arn:aws:acm:us-east-1:000000000000:certificate/1"},
+		{
+			name:           "acm in another region",
+			certificateARN: "arn:aws:acm:eu-west-1:000000000000:certificate/1",
+			expectedError:  `listener "listener-443": certificate "arn:aws:acm:eu-west-1:000000000000:certificate/1" is in region "eu-west-1", but ACM certificates can only be used by load balancers in the same region ("us-east-1")`,
+		},
+		{name: "iam", certificateARN: "arn:aws:iam::000000000000:server-certificate/api", expectedIAMCalls: 1},
+		{
+			name:           "iam in another partition",
+			certificateARN: "arn:aws-cn:iam::000000000000:server-certificate/api",
+			expectedError:  `listener "listener-443": certificate "arn:aws-cn:iam::000000000000:server-certificate/api" is in partition "aws-cn", but load balancers in region "us-east-1" can only use certificates in partition "aws"`,
+		},
+		{
+			name:             "iam missing",
+			certificateARN:   "arn:aws:iam::000000000000:server-certificate/deleted",
+			expectedError:    `listener "listener-443": IAM server certificate "arn:aws:iam::000000000000:server-certificate/deleted": certificate not found`,
+			expectedIAMCalls: 1,
+		},
+		{
+			name:             "iam missing with fallback",
+			certificateARN:   "arn:aws:iam::000000000000:server-certificate/deleted",
+			fallbackARN:      "arn:aws:iam::000000000000:server-certificate/api",
+			expectedIAMCalls: 1,
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			iamClient.getServerCertificateCalls = 0
+
+			// The listeners of a load balancer share the validation of their certificate
+			nlb := &NetworkLoadBalancer{Name: s("nlb1"), certificates: &elbv2CertificateCache{}}
+			for _, port := range []int{443, 8443} {
+				listener := &NetworkLoadBalancerListener{
+					Name:                     s(fmt.Sprintf("listener-%d", port)),
+					NetworkLoadBalancer:      nlb,
+					Port:                     port,
+					SSLCertificateID:         g.certificateARN,
+					FallbackSSLCertificateID: g.fallbackARN,
+				}
+				err := listener.Normalize(cloudupContext)
+				if g.expectedError == "" {
+					if err != nil {
+						t.Fatalf("unexpected error: %v", err)
+					}
+				} else if port == 443 && (err == nil || err.Error() != g.expectedError) {
+					t.Fatalf("expected error %q, got %v", g.expectedError, err)
+				}
+			}
+			if iamClient.getServerCertificateCalls != g.expectedIAMCalls {
+				t.Errorf("expected %d GetServerCertificate calls, got %d", g.expectedIAMCalls, iamClient.getServerCertificateCalls)
+			}
+		})
+	}
+}
+
+func TestNetworkLoadBalancerListenerSharedCertificate(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	iamClient := &countingIAM{MockIAM: &mockiam.MockIAM{
+		ServerCertificates: map[string]*iamtypes.ServerCertificate{"api": {}},
+	}}
+	cloud.MockIAM = iamClient
+	cloud.MockELBV2 = &mockelbv2.MockELBV2{}
+	certificateARN := "arn:aws:iam::000000000000:server-certificate/api"
+
+	cloudupContext, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, &awsup.AWSAPITarget{Cloud: cloud}, &kops.Cluster{}, cloud, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+
+	// The load balancer does not exist yet, as when creating a cluster
+	nlb := &NetworkLoadBalancer{Name: s("nlb1")}
+	if err := nlb.Normalize(cloudupContext); err != nil {
+		t.Fatalf("unexpected error normalizing load balancer: %v", err)
+	}
+
+	// A cancelled validation is not cached
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	cancelledContext, err := fi.NewCloudupContext(cancelledCtx, fi.DeletionProcessingModeDeleteIncludingDeferred, &awsup.AWSAPITarget{Cloud: cloud}, &kops.Cluster{}, cloud, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+	cancelled := &NetworkLoadBalancerListener{Name: s("listener-443"), NetworkLoadBalancer: nlb, Port: 443, SSLCertificateID: certificateARN}
+	if err := cancelled.Normalize(cancelledContext); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	// The listener tasks run concurrently
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i, port := range []int{443, 8443, 9443} {
+		listener := &NetworkLoadBalancerListener{
+			Name:                s(fmt.Sprintf("listener-%d", port)),
+			NetworkLoadBalancer: nlb,
+			Port:                port,
+			SSLCertificateID:    certificateARN,
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = listener.Normalize(cloudupContext)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if iamClient.getServerCertificateCalls != 1 {
+		t.Errorf("expected the shared certificate to be looked up once, got %d GetServerCertificate calls", iamClient.getServerCertificateCalls)
+	}
+}
+
+func TestNetworkLoadBalancerListenerTerraformImport(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func() map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		tg1 := &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                nlb1.VPC,
+			Tags:               map[string]string{"Name": "tg1"},
+			Protocol:           elbv2types.ProtocolEnumTcp,
+			Port:               fi.PtrTo(int32(443)),
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+		}
+		allTasks["tg1"] = tg1
+		allTasks["listener1"] = &NetworkLoadBalancerListener{
+			Name:                s("listener1"),
+			Lifecycle:           fi.LifecycleSync,
+			NetworkLoadBalancer: nlb1,
+			Port:                443,
+			TargetGroup:         tg1,
+		}
+		return allTasks
+	}
+
+	allTasks := buildTasks()
+	runNLBTasks(t, cloud, allTasks)
+	loadBalancerArn := allTasks["nlb1"].(*NetworkLoadBalancer).loadBalancerArn
+	targetGroupArn := fi.ValueOf(allTasks["tg1"].(*TargetGroup).ARN)
+	listenerArn := allTasks["listener1"].(*NetworkLoadBalancerListener).listenerArn
+
+	renderTerraform := func(t *testing.T, emitImportBlocks bool) string {
+		outDir := t.TempDir()
+		target := terraform.NewTerraformTarget(cloud, "", outDir, nil)
+		target.EmitImportBlocks = emitImportBlocks
+
+		allTasks := buildTasks()
+		context, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeIgnore, target, &kops.Cluster{}, cloud, nil, nil, nil, allTasks)
+		if err != nil {
+			t.Fatalf("error building context: %v", err)
+		}
+		if err := context.RunTasks(testRunTasksOptions); err != nil {
+			t.Fatalf("unexpected error during Run: %v", err)
+		}
+		if err := target.Finish(allTasks); err != nil {
+			t.Fatalf("error finishing terraform target: %v", err)
+		}
+		b, err := os.ReadFile(filepath.Join(outDir, "kubernetes.tf"))
+		if err != nil {
+			t.Fatalf("error reading terraform output: %v", err)
+		}
+		return string(b)
+	}
+
+	t.Run("with import blocks", func(t *testing.T) {
+		hcl := renderTerraform(t, true)
+		for _, expected := range []string{
+			fmt.Sprintf("import {\n  to = aws_lb.nlb1\n  id = %q\n}\n", loadBalancerArn),
+			`resource "aws_lb_listener" "nlb1-443" {`,
+			fmt.Sprintf("import {\n  to = aws_lb_listener.nlb1-443\n  id = %q\n}\n", listenerArn),
+			fmt.Sprintf("import {\n  to = aws_lb_target_group.tg1\n  id = %q\n}\n", targetGroupArn),
+			`required_version = ">= 1.5.0"`,
+		} {
+			if !strings.Contains(hcl, expected) {
+				t.Errorf("expected terraform output to contain %q, got:\n%s", expected, hcl)
+			}
+		}
+	})
+
+	t.Run("without import blocks", func(t *testing.T) {
+		hcl := renderTerraform(t, false)
+		if strings.Contains(hcl, "import {") {
+			t.Errorf("expected no import blocks, got:\n%s", hcl)
+		}
+	})
+}
+
+func TestNetworkLoadBalancerListenerFindWithoutLoadBalancer(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	c := &countingELBV2{MockELBV2: &mockelbv2.MockELBV2{}}
+	cloud.MockELBV2 = c
+
+	cloudupContext, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, &awsup.AWSAPITarget{Cloud: cloud}, &kops.Cluster{}, cloud, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+
+	// The load balancer task is present but has not created the load balancer yet
+	e := &NetworkLoadBalancerListener{
+		Name:                s("listener"),
+		NetworkLoadBalancer: &NetworkLoadBalancer{Name: s("nlb1")},
+		Port:                443,
+	}
+	actual, err := e.Find(cloudupContext)
+	if err != nil {
+		t.Fatalf("unexpected error finding listener of a load balancer not yet created: %v", err)
+	}
+	if actual != nil {
+		t.Errorf("expected no listener for a load balancer not yet created, got %+v", actual)
+	}
+	if c.describeListenersCalls != 0 {
+		t.Errorf("expected no DescribeListeners calls for a load balancer not yet created, got %d", c.describeListenersCalls)
+	}
+
+	// No load balancer is configured at all
+	e = &NetworkLoadBalancerListener{
+		Name: s("listener"),
+		Port: 443,
+	}
+	if _, err := e.Find(cloudupContext); err == nil || !strings.Contains(err.Error(), "NetworkLoadBalancer") {
+		t.Errorf("expected an error for a listener without a load balancer, got %v", err)
+	}
+}
+
+func TestNetworkLoadBalancerListenerCorrelationID(t *testing.T) {
+	ctx := context.TODO()
+
+	certificateARN := "arn:aws:acm:us-east-1:000000000000:certificate/api"
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &countingELBV2{MockELBV2: &mockelbv2.MockELBV2{EC2: ec2Client}}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(certificate string) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		tg1 := &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                nlb1.VPC,
+			Tags:               map[string]string{"Name": "tg1"},
+			Protocol:           elbv2types.ProtocolEnumTcp,
+			Port:               fi.PtrTo(int32(443)),
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+		}
+		allTasks["tg1"] = tg1
+		allTasks["listener1"] = &NetworkLoadBalancerListener{
+			Name:                s("listener1"),
+			Lifecycle:           fi.LifecycleSync,
+			NetworkLoadBalancer: nlb1,
+			Port:                443,
+			TargetGroup:         tg1,
+			SSLCertificateID:    certificate,
+		}
+		return allTasks
+	}
+
+	correlationID := func(listenerArn string) string {
+		response, err := c.DescribeTags(ctx, &elbv2.DescribeTagsInput{ResourceArns: []string{listenerArn}})
+		if err != nil {
+			t.Fatalf("error describing tags: %v", err)
+		}
+		value, _ := awsup.FindELBV2Tag(response.TagDescriptions[0].Tags, awsup.KopsListenerCorrelationIDTag)
+		return value
+	}
+
+	allTasks := buildTasks("")
+	runNLBTasks(t, cloud, allTasks)
+	listenerArn := allTasks["listener1"].(*NetworkLoadBalancerListener).listenerArn
+	if id := correlationID(listenerArn); id != "listener1" {
+		t.Errorf("expected correlation ID %q, got %q", "listener1", id)
+	}
+	checkNLBNoChanges(t, ctx, cloud, buildTasks(""))
+
+	// A missing tag is restored in place
+	if _, err := c.RemoveTags(ctx, &elbv2.RemoveTagsInput{ResourceArns: []string{listenerArn}, TagKeys: []string{awsup.KopsListenerCorrelationIDTag}}); err != nil {
+		t.Fatalf("error removing tags: %v", err)
+	}
+	c.createListenerCalls, c.deleteListenerCalls = 0, 0
+	runNLBTasks(t, cloud, buildTasks(""))
+	if c.createListenerCalls != 0 || c.deleteListenerCalls != 0 {
+		t.Errorf("expected the listener not to be recreated, got %d creations and %d deletions", c.createListenerCalls, c.deleteListenerCalls)
+	}
+	if id := correlationID(listenerArn); id != "listener1" {
+		t.Errorf("expected correlation ID %q to be restored, got %q", "listener1", id)
+	}
+
+	// The identifier survives the listener being recreated
+	allTasks = buildTasks(certificateARN)
+	runNLBTasks(t, cloud, allTasks)
+	recreatedArn := allTasks["listener1"].(*NetworkLoadBalancerListener).listenerArn
+	if recreatedArn == listenerArn {
+		t.Fatalf("expected the listener to be recreated to use TLS")
+	}
+	if id := correlationID(recreatedArn); id != "listener1" {
+		t.Errorf("expected correlation ID %q on the recreated listener, got %q", "listener1", id)
+	}
+	checkNLBNoChanges(t, ctx, cloud, buildTasks(certificateARN))
+}
@@ -17,6 +17,10 @@ limitations under the License.
 package awstasks
 
 import (
+	"fmt"
+	"net"
+
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
 	"k8s.io/klog/v2"
 	"k8s.io/kops/upup/pkg/fi"
 )
@@ -30,6 +34,31 @@ type SubnetMapping struct {
 	AllocationID *string
 }
 
+// validate checks the addresses of the subnet mapping against the scheme of the NLB:
+// an Elastic IP (AllocationID) gives an internet-facing NLB a stable public address,
+// while PrivateIPv4Address fixes the address of an internal NLB.
+func (s *SubnetMapping) validate(scheme elbv2types.LoadBalancerSchemeEnum) error {
+	subnetID := fi.ValueOf(s.Subnet.ID)
+	internal := scheme == elbv2types.LoadBalancerSchemeEnumInternal
+	if s.AllocationID != nil {
+		if fi.ValueOf(s.AllocationID) == "" {
+			return fmt.Errorf("subnet mapping for subnet %q has an empty AllocationID", subnetID)
+		}
+		if internal {
+			return fmt.Errorf("subnet mapping for subnet %q cannot set an AllocationID on an internal load balancer", subnetID)
+		}
+	}
+	if s.PrivateIPv4Address != nil {
+		if ip := net.ParseIP(fi.ValueOf(s.PrivateIPv4Address)); ip == nil || ip.To4() == nil {
+			return fmt.Errorf("subnet mapping for subnet %q has invalid PrivateIPv4Address %q", subnetID, fi.ValueOf(s.PrivateIPv4Address))
+		}
+		if !internal {
+			return fmt.Errorf("subnet mapping for subnet %q can only set a PrivateIPv4Address on an internal load balancer", subnetID)
+		}
+	}
+	return nil
+}
+
 // OrderSubnetsById implements sort.Interface for []Subnet, based on ID
 type OrderSubnetMappingsByID []*SubnetMapping
 
@@ -0,0 +1,67 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awstasks
+
+import (
+	"k8s.io/kops/upup/pkg/fi"
+)
+
+// maxTargetGroupWeight is the largest weight AWS accepts for a target group of a forward action.
+const maxTargetGroupWeight = 999
+
+// maxForwardStickinessDurationSeconds is the longest stickiness duration AWS accepts for a forward action (7 days).
+const maxForwardStickinessDurationSeconds = 604800
+
+// TargetGroupWeight is a target group that receives a share of the traffic of a weighted forward action.
+type TargetGroupWeight struct {
+	TargetGroup *TargetGroup
+	Weight      int
+}
+
+var _ fi.CloudupHasDependencies = &TargetGroupWeight{}
+
+func (w *TargetGroupWeight) GetDependencies(tasks map[string]fi.CloudupTask) []fi.CloudupTask {
+	if w.TargetGroup == nil {
+		return nil
+	}
+	return []fi.CloudupTask{w.TargetGroup}
+}
+
+// sameWeightedSplit returns true if a and b forward to the same target groups, in the same order,
+// with proportional weights, e.g. [30, 70] and [3, 7], so that they split the traffic the same way.
+func sameWeightedSplit(a, b []*TargetGroupWeight) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	totalA, totalB := 0, 0
+	for i := range a {
+		if a[i].TargetGroup != b[i].TargetGroup {
+			return false
+		}
+		totalA += a[i].Weight
+		totalB += b[i].Weight
+	}
+	if totalA <= 0 || totalB <= 0 {
+		return false
+	}
+	for i := range a {
+		if a[i].Weight*totalB != b[i].Weight*totalA {
+			return false
+		}
+	}
+	return true
+}
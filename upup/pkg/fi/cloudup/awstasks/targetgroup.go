@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
@@ -37,21 +38,99 @@ const (
 	// TargetGroupAttributeDeregistrationDelayConnectionTerminationEnabled indicates whether
 	//the load balancer terminates connections at the end of the deregistration timeout.
 	// https://docs.aws.amazon.com/elasticloadbalancing/latest/network/load-balancer-target-groups.html#deregistration-delay
-	TargetGroupAttributeDeregistrationDelayConnectionTerminationEnabled = "deregistration_delay.connection_termination.enabled"
+	TargetGroupAttributeDeregistrationDelayConnectionTerminationEnabled = awsup.TargetGroupAttributeDeregistrationDelayConnectionTerminationEnabled
 	// TargetGroupAttributeDeregistrationDelayTimeoutSeconds is the amount of time for Elastic Load Balancing
 	// to wait before changing the state of a deregistering target from draining to unused.
 	// https://docs.aws.amazon.com/elasticloadbalancing/latest/network/load-balancer-target-groups.html#deregistration-delay
-	TargetGroupAttributeDeregistrationDelayTimeoutSeconds = "deregistration_delay.timeout_seconds"
+	TargetGroupAttributeDeregistrationDelayTimeoutSeconds = awsup.TargetGroupAttributeDeregistrationDelayTimeoutSeconds
+	// TargetGroupAttributeProxyProtocolV2Enabled indicates whether PROXY protocol v2 is enabled.
+	// https://docs.aws.amazon.com/elasticloadbalancing/latest/network/load-balancer-target-groups.html#proxy-protocol
+	TargetGroupAttributeProxyProtocolV2Enabled = awsup.TargetGroupAttributeProxyProtocolV2Enabled
+	// TargetGroupAttributeStickinessEnabled indicates whether sticky sessions are enabled.
+	TargetGroupAttributeStickinessEnabled = awsup.TargetGroupAttributeStickinessEnabled
+	// TargetGroupAttributeStickinessType is the type of sticky sessions.
+	// https://docs.aws.amazon.com/elasticloadbalancing/latest/network/load-balancer-target-groups.html#sticky-sessions
+	TargetGroupAttributeStickinessType = awsup.TargetGroupAttributeStickinessType
+	// TargetGroupAttributeLoadBalancingCrossZoneEnabled indicates whether cross-zone load balancing is enabled for the target group,
+	// overriding the setting of the load balancer.
+	// https://docs.aws.amazon.com/elasticloadbalancing/latest/network/load-balancer-target-groups.html#cross_zone_load_balancing
+	TargetGroupAttributeLoadBalancingCrossZoneEnabled = awsup.TargetGroupAttributeLoadBalancingCrossZoneEnabled
+	// TargetGroupAttributePreserveClientIPEnabled indicates whether the targets see the client IP as the source of the connections.
+	// https://docs.aws.amazon.com/elasticloadbalancing/latest/network/load-balancer-target-groups.html#client-ip-preservation
+	TargetGroupAttributePreserveClientIPEnabled = awsup.TargetGroupAttributePreserveClientIPEnabled
+	// TargetGroupAttributeUnhealthyConnectionTerminationEnabled indicates whether the load balancer terminates
+	// the connections to unhealthy targets.
+	// https://docs.aws.amazon.com/elasticloadbalancing/latest/network/load-balancer-target-groups.html#unhealthy-target-connection-termination
+	TargetGroupAttributeUnhealthyConnectionTerminationEnabled = awsup.TargetGroupAttributeUnhealthyConnectionTerminationEnabled
+	// TargetGroupAttributeUnhealthyDrainingIntervalSeconds is the amount of time for Elastic Load Balancing
+	// to wait before changing the state of an unhealthy target from draining to unhealthy.
+	// https://docs.aws.amazon.com/elasticloadbalancing/latest/network/load-balancer-target-groups.html#unhealthy-target-connection-termination
+	TargetGroupAttributeUnhealthyDrainingIntervalSeconds = awsup.TargetGroupAttributeUnhealthyDrainingIntervalSeconds
+)
+
+// TargetGroupCrossZoneUseLoadBalancerConfiguration makes a target group follow the cross-zone setting of its load balancer.
+const TargetGroupCrossZoneUseLoadBalancerConfiguration = "use_load_balancer_configuration"
+
+// Stickiness types of target groups.
+const (
+	// TargetGroupStickinessTypeSourceIP binds clients to targets by their source IP, and is the only type supported by NLBs.
+	TargetGroupStickinessTypeSourceIP = "source_ip"
+	// TargetGroupStickinessTypeLBCookie binds clients to targets with a cookie generated by an ALB.
+	TargetGroupStickinessTypeLBCookie = "lb_cookie"
+	// TargetGroupStickinessTypeAppCookie binds clients to targets with a cookie generated by the application behind an ALB.
+	TargetGroupStickinessTypeAppCookie = "app_cookie"
+)
+
+// Bounds enforced by AWS on the health check settings of target groups.
+const (
+	minHealthCheckInterval   = 5
+	maxHealthCheckInterval   = 300
+	minHealthCheckThreshold  = 2
+	maxHealthCheckThreshold  = 10
+	maxHealthCheckPathLength = 1024
+	minHealthCheckTimeout    = 2
+	maxHealthCheckTimeout    = 120
+)
+
+// maxDeregistrationDelaySeconds is the longest deregistration delay accepted by AWS.
+const maxDeregistrationDelaySeconds = 3600
+
+// maxUnhealthyDrainingIntervalSeconds is the longest draining interval of unhealthy targets accepted by AWS.
+const maxUnhealthyDrainingIntervalSeconds = 360000
+
+const (
+	// defaultHTTPHealthCheckMatcher is the HTTP code of a successful HTTP/HTTPS health check, if HealthCheckMatcher is not set.
+	defaultHTTPHealthCheckMatcher = "200"
+	// defaultGRPCHealthCheckMatcher is the gRPC code of a successful health check of a GRPC target group, if HealthCheckMatcher is not set.
+	defaultGRPCHealthCheckMatcher = "0"
+	// maxTargetGroupNameLength is the maximum length of the name of a target group in AWS.
+	maxTargetGroupNameLength = 32
 )
 
 // +kops:fitask
 type TargetGroup struct {
-	Name      *string
-	Lifecycle fi.Lifecycle
-	VPC       *VPC
-	Tags      map[string]string
-	Port      *int32
-	Protocol  elbv2types.ProtocolEnum
+	// Name is the value of the Name tag, by which the Target Group is discovered.
+	Name *string
+	// TargetGroupName is the name of the Target Group in AWS, which must be unique within the region
+	// and at most 32 characters long. It defaults to Name.
+	// Changing it does not rename an existing Target Group.
+	TargetGroupName *string
+	Lifecycle       fi.Lifecycle
+	VPC             *VPC
+	// Tags are the tags applied to the Target Group, in addition to the cluster ownership tags.
+	// The Name tag, the cluster ownership tags and the kops revision tag are reserved and cannot be overridden.
+	// Other tags on the Target Group are left alone, unless their keys are managed by kops (see isKopsManagedTargetGroupTag).
+	Tags     map[string]string
+	Port     *int32
+	Protocol elbv2types.ProtocolEnum
+
+	// TargetType is the type of targets registered with the Target Group, defaulting to instance.
+	// The alb type forwards traffic from a Network Load Balancer to an Application Load Balancer.
+	TargetType elbv2types.TargetTypeEnum
+
+	// IPAddressType is the type of the addresses of the targets, ipv4 (the default) or ipv6.
+	// IPv6 target groups must use the ip target type, in a VPC with an IPv6 CIDR.
+	IPAddressType elbv2types.TargetGroupIpAddressTypeEnum
 
 	// networkLoadBalancer, if set, will create a new Target Group for each revision of the Network Load Balancer
 	networkLoadBalancer *NetworkLoadBalancer
@@ -64,10 +143,71 @@ type TargetGroup struct {
 
 	Attributes map[string]string
 
+	// ProxyProtocolV2 sends the PROXY protocol v2 header to the targets, so that they can see the original client address.
+	// The targets must then expect the header on every connection, including health checks.
+	ProxyProtocolV2 *bool
+
+	// PreserveClientIP makes the client IP the source of the connections to the targets; otherwise the source is
+	// a private IP of the load balancer. AWS enables it by default for instance targets and for UDP/TCP_UDP target groups,
+	// where it cannot be disabled. Disabling it is compatible with ProxyProtocolV2, which still passes the client address
+	// in the PROXY header; enabling both is allowed but redundant.
+	PreserveClientIP *bool
+
+	// Stickiness, if set, configures the sticky sessions of the target group.
+	Stickiness *TargetGroupStickiness
+
+	// ConnectionTermination closes the connections to a deregistered target at the end of the deregistration delay,
+	// which is needed for UDP and TCP_UDP flows that would otherwise keep reaching the target.
+	ConnectionTermination *bool
+	// DeregistrationDelaySeconds is how long to wait before a deregistering target stops draining, between 0 and 3600.
+	DeregistrationDelaySeconds *int
+	// FastTeardown, if set, stops draining deregistered targets: it defaults DeregistrationDelaySeconds to 0
+	// and ConnectionTermination to true, e.g. for short-lived clusters that are deleted as soon as they are tested.
+	FastTeardown *bool
+
+	// UnhealthyConnectionTermination closes the connections to a target when it becomes unhealthy, which AWS does by default.
+	UnhealthyConnectionTermination *bool
+	// UnhealthyDrainingIntervalSeconds is how long an unhealthy target keeps its existing connections, between 0 and 360000,
+	// so that flapping targets are not cut off immediately. It requires UnhealthyConnectionTermination to be false.
+	UnhealthyDrainingIntervalSeconds *int
+
+	// CrossZoneLoadBalancing overrides the cross-zone load balancing setting of the load balancer for this target group:
+	// "true", "false", or "use_load_balancer_configuration".
+	CrossZoneLoadBalancing *string
+
 	Interval           *int32
 	HealthyThreshold   *int32
 	UnhealthyThreshold *int32
 
+	// HealthCheckProtocol is the protocol used for health checks, which may differ from the traffic Protocol
+	// (for example a TCP health check on an HTTP target group).
+	// If not set, AWS uses TCP for TCP/TLS/UDP target groups and the traffic protocol for HTTP/HTTPS target groups.
+	HealthCheckProtocol elbv2types.ProtocolEnum
+	// HealthCheckPath is the destination for HTTP/HTTPS health checks.
+	HealthCheckPath *string
+	// HealthCheckMatcher is the set of HTTP codes (e.g. "200-399") for a successful HTTP/HTTPS health check,
+	// or the set of gRPC codes (e.g. "0-99") when ProtocolVersion is GRPC.
+	// It defaults to 200, or to 0 when ProtocolVersion is GRPC.
+	HealthCheckMatcher *string
+	// HealthCheckPort is the port used for health checks, either a port number or "traffic-port" (the default)
+	// for the port on which each target receives traffic.
+	HealthCheckPort *string
+	// HealthCheckTimeout is the number of seconds without a response after which a health check fails.
+	HealthCheckTimeout *int32
+
+	// ProtocolVersion is the protocol version of HTTP/HTTPS target groups: HTTP1, HTTP2 or GRPC.
+	// For GRPC, HealthCheckPath is the gRPC service and method (e.g. "/package.Service/Check").
+	ProtocolVersion *string
+
+	// ExportWithID, if set, exposes the ARN of the Target Group as the <ExportWithID>_target_group_arn output,
+	// so that additional targets can be attached outside of kops. Only supported by terraform currently.
+	ExportWithID *string
+
+	// AdoptARN, if set, binds the task to this existing target group instead of the target group with a matching Name tag,
+	// e.g. when bringing a load balancer created outside of kops under management.
+	// The adopted target group must be in the VPC of the cluster, and is only ever modified in place, never recreated.
+	AdoptARN string
+
 	info     *awsup.TargetGroupInfo
 	revision string
 
@@ -75,6 +215,13 @@ type TargetGroup struct {
 	deletions []fi.CloudupDeletion
 }
 
+// TargetGroupStickiness configures the sticky sessions of a target group.
+type TargetGroupStickiness struct {
+	Enabled bool
+	// Type is the type of sticky sessions; Network Load Balancer target groups only support source_ip.
+	Type string
+}
+
 // CreateNewRevisionsWith will create new revisions of the TargetGroup when the given networkLoadBalancer has a new revision.
 // This works around the fact that TargetGroups can only be attached to a single NetworkLoadBalancer.
 func (e *TargetGroup) CreateNewRevisionsWith(nlb *NetworkLoadBalancer) {
@@ -101,10 +248,27 @@ func (e *TargetGroup) CompareWithID() *string {
 	return e.Name
 }
 
-func (e *TargetGroup) findLatestTargetGroupByName(ctx context.Context, cloud awsup.AWSCloud) (*awsup.TargetGroupInfo, error) {
-	name := fi.ValueOf(e.Name)
+// awsTargetGroupName returns the name of the Target Group in AWS, before any revision suffix.
+func (e *TargetGroup) awsTargetGroupName() string {
+	if e.TargetGroupName != nil {
+		return *e.TargetGroupName
+	}
+	return fi.ValueOf(e.Name)
+}
+
+// matchesTargetGroup returns true if the target group is a version of this Target Group.
+// Target groups are matched by their Name tag; the AWS name is only used for target groups without a Name tag,
+// which might predate tagging, so that a cluster never matches the target group of another cluster whose
+// (possibly truncated) AWS name happens to equal its Name tag.
+func (e *TargetGroup) matchesTargetGroup(targetGroup *awsup.TargetGroupInfo) bool {
+	if nameTag := targetGroup.NameTag(); nameTag != "" {
+		return nameTag == fi.ValueOf(e.Name)
+	}
+	return aws.ToString(targetGroup.TargetGroup.TargetGroupName) == e.awsTargetGroupName()
+}
 
-	targetGroups, err := awsup.ListELBV2TargetGroups(ctx, cloud)
+func (e *TargetGroup) findLatestTargetGroupByName(ctx context.Context, cloud awsup.AWSCloud) (*awsup.TargetGroupInfo, error) {
+	targetGroups, err := awsup.ListELBV2TargetGroups(ctx, cloud, "", "")
 	if err != nil {
 		return nil, err
 	}
@@ -112,8 +276,7 @@ func (e *TargetGroup) findLatestTargetGroupByName(ctx context.Context, cloud aws
 	var latest *awsup.TargetGroupInfo
 	var latestRevision int
 	for _, targetGroup := range targetGroups {
-		// We accept the name tag _or_ the TargetGroupName itself, to allow matching groups that might predate tagging.
-		if aws.ToString(targetGroup.TargetGroup.TargetGroupName) != name && targetGroup.NameTag() != name {
+		if !e.matchesTargetGroup(targetGroup) {
 			continue
 		}
 		revisionTag, _ := targetGroup.GetTag(awsup.KopsResourceRevisionTag)
@@ -130,7 +293,8 @@ func (e *TargetGroup) findLatestTargetGroupByName(ctx context.Context, cloud aws
 			revision = n
 		}
 
-		if latest == nil || revision > latestRevision {
+		// Among target groups of the same revision, prefer one in our VPC; one in another VPC will be recreated.
+		if latest == nil || revision > latestRevision || (revision == latestRevision && e.inVPC(targetGroup) && !e.inVPC(latest)) {
 			latestRevision = revision
 			latest = targetGroup
 		}
@@ -152,7 +316,7 @@ func (e *TargetGroup) findLatestTargetGroupByName(ctx context.Context, cloud aws
 
 	// Record deletions for later
 	for _, targetGroup := range targetGroups {
-		if aws.ToString(targetGroup.TargetGroup.TargetGroupName) != name && targetGroup.NameTag() != name {
+		if !e.matchesTargetGroup(targetGroup) {
 			continue
 		}
 		if latest != nil && latest.ARN == targetGroup.ARN {
@@ -165,9 +329,17 @@ func (e *TargetGroup) findLatestTargetGroupByName(ctx context.Context, cloud aws
 	return latest, nil
 }
 
-func (e *TargetGroup) findTargetGroupByARN(ctx context.Context, cloud awsup.AWSCloud) (*awsup.TargetGroupInfo, error) {
+// inVPC returns true if the target group is in the VPC of this task, or if that VPC is not yet known.
+func (e *TargetGroup) inVPC(targetGroup *awsup.TargetGroupInfo) bool {
+	if e.VPC == nil || e.VPC.ID == nil {
+		return true
+	}
+	return aws.ToString(targetGroup.TargetGroup.VpcId) == aws.ToString(e.VPC.ID)
+}
+
+func (e *TargetGroup) findTargetGroupByARN(ctx context.Context, cloud awsup.AWSCloud, arn string) (*awsup.TargetGroupInfo, error) {
 	request := &elbv2.DescribeTargetGroupsInput{}
-	request.TargetGroupArns = []string{aws.ToString(e.ARN)}
+	request.TargetGroupArns = []string{arn}
 
 	var targetGroups []elbv2types.TargetGroup
 	paginator := elbv2.NewDescribeTargetGroupsPaginator(cloud.ELBV2(), request)
@@ -180,7 +352,7 @@ func (e *TargetGroup) findTargetGroupByARN(ctx context.Context, cloud awsup.AWSC
 					return nil, nil
 				}
 			}
-			return nil, fmt.Errorf("error describing targetgroup %s: %w", *e.ARN, err)
+			return nil, fmt.Errorf("error describing targetgroup %s: %w", arn, err)
 		}
 		targetGroups = append(targetGroups, page.TargetGroups...)
 	}
@@ -216,14 +388,26 @@ func (e *TargetGroup) Find(c *fi.CloudupContext) (*TargetGroup, error) {
 
 	var targetGroupInfo *awsup.TargetGroupInfo
 
-	if e.ARN == nil {
+	if e.AdoptARN != "" {
+		tgi, err := e.findTargetGroupByARN(ctx, cloud, e.AdoptARN)
+		if err != nil {
+			return nil, err
+		}
+		if tgi == nil {
+			return nil, fmt.Errorf("target group %q to adopt was not found", e.AdoptARN)
+		}
+		if !e.inVPC(tgi) {
+			return nil, fmt.Errorf("target group %q to adopt is in VPC %q, not in VPC %q of the cluster", e.AdoptARN, aws.ToString(tgi.TargetGroup.VpcId), fi.ValueOf(e.VPC.ID))
+		}
+		targetGroupInfo = tgi
+	} else if e.ARN == nil {
 		tgi, err := e.findLatestTargetGroupByName(ctx, cloud)
 		if err != nil {
 			return nil, err
 		}
 		targetGroupInfo = tgi
 	} else {
-		tgi, err := e.findTargetGroupByARN(ctx, cloud)
+		tgi, err := e.findTargetGroupByARN(ctx, cloud, aws.ToString(e.ARN))
 		if err != nil {
 			return nil, err
 		}
@@ -236,23 +420,63 @@ func (e *TargetGroup) Find(c *fi.CloudupContext) (*TargetGroup, error) {
 
 	tg := targetGroupInfo.TargetGroup
 
+	// A target group cannot move between VPCs, so one in another VPC (e.g. after a VPC migration) is replaced,
+	// and deleted once the listeners have been pointed at its replacement.
+	if !fi.ValueOf(e.Shared) && !e.inVPC(targetGroupInfo) {
+		e.deletions = append(e.deletions, buildDeleteTargetGroup(targetGroupInfo))
+	}
+
 	actual := &TargetGroup{
-		Name:               tg.TargetGroupName,
-		Port:               tg.Port,
-		Protocol:           tg.Protocol,
-		ARN:                tg.TargetGroupArn,
-		Interval:           tg.HealthCheckIntervalSeconds,
-		HealthyThreshold:   tg.HealthyThresholdCount,
-		UnhealthyThreshold: tg.UnhealthyThresholdCount,
-		VPC:                &VPC{ID: tg.VpcId},
+		Name:                tg.TargetGroupName,
+		Port:                tg.Port,
+		Protocol:            tg.Protocol,
+		TargetType:          tg.TargetType,
+		IPAddressType:       tg.IpAddressType,
+		ARN:                 tg.TargetGroupArn,
+		Interval:            tg.HealthCheckIntervalSeconds,
+		HealthyThreshold:    tg.HealthyThresholdCount,
+		UnhealthyThreshold:  tg.UnhealthyThresholdCount,
+		HealthCheckProtocol: tg.HealthCheckProtocol,
+		HealthCheckPort:     tg.HealthCheckPort,
+		HealthCheckTimeout:  tg.HealthCheckTimeoutSeconds,
+		ProtocolVersion:     tg.ProtocolVersion,
+		VPC:                 &VPC{ID: tg.VpcId},
+	}
+	if isHTTPHealthCheck(actual.healthCheckProtocol()) {
+		actual.HealthCheckPath = tg.HealthCheckPath
+		if tg.Matcher != nil {
+			if actual.isGRPC() {
+				actual.HealthCheckMatcher = tg.Matcher.GrpcCode
+			} else {
+				actual.HealthCheckMatcher = tg.Matcher.HttpCode
+			}
+		}
 	}
 	actual.info = targetGroupInfo
 	e.info = targetGroupInfo
 	actual.revision, _ = targetGroupInfo.GetTag(awsup.KopsResourceRevisionTag)
 	e.revision = actual.revision
 
-	// Interval cannot be changed after TargetGroup creation
-	e.Interval = actual.Interval
+	if e.TargetType == "" {
+		e.TargetType = actual.TargetType
+	}
+	if e.IPAddressType == "" {
+		e.IPAddressType = actual.IPAddressType
+	}
+	if e.ProtocolVersion == nil {
+		e.ProtocolVersion = actual.ProtocolVersion
+	}
+
+	// Health check settings left unset are defaulted by AWS
+	if e.HealthCheckProtocol == "" {
+		e.HealthCheckProtocol = actual.HealthCheckProtocol
+	}
+	if e.HealthCheckPath == nil {
+		e.HealthCheckPath = actual.HealthCheckPath
+	}
+	if e.HealthCheckMatcher == nil {
+		e.HealthCheckMatcher = actual.HealthCheckMatcher
+	}
 
 	e.ARN = tg.TargetGroupArn
 	tags := make(map[string]string)
@@ -263,16 +487,46 @@ func (e *TargetGroup) Find(c *fi.CloudupContext) (*TargetGroup, error) {
 			actual.revision = v
 			continue
 		}
+		// Tags with the aws: prefix are managed by AWS, and can neither be set nor removed
+		if strings.HasPrefix(k, "aws:") {
+			continue
+		}
 		tags[k] = v
 	}
 	actual.Tags = tags
 
+	// Compare against the tags we will actually apply, so reserved keys don't cause spurious changes
+	if !fi.ValueOf(e.Shared) {
+		e.Tags = e.mergedTags(cloud.Tags())
+
+		// Tags added outside of kops, e.g. by tag policies or for cost allocation, are not ours to remove
+		for k := range actual.Tags {
+			if _, found := e.Tags[k]; !found && !isKopsManagedTargetGroupTag(k) {
+				delete(actual.Tags, k)
+			}
+		}
+	}
+
 	attrResp, err := cloud.ELBV2().DescribeTargetGroupAttributes(ctx, &elbv2.DescribeTargetGroupAttributesInput{
 		TargetGroupArn: tg.TargetGroupArn,
 	})
 	if err != nil {
 		return nil, err
 	}
+	parsed := awsup.UnmarshalTargetGroupAttributes(attrResp.Attributes)
+	actual.ProxyProtocolV2 = parsed.ProxyProtocolV2
+	if parsed.StickinessEnabled != nil || parsed.StickinessType != nil {
+		actual.Stickiness = &TargetGroupStickiness{
+			Enabled: fi.ValueOf(parsed.StickinessEnabled),
+			Type:    fi.ValueOf(parsed.StickinessType),
+		}
+	}
+	actual.CrossZoneLoadBalancing = parsed.CrossZoneLoadBalancing
+	actual.PreserveClientIP = parsed.PreserveClientIP
+	actual.ConnectionTermination = parsed.DeregistrationDelayConnectionTermination
+	actual.DeregistrationDelaySeconds = parsed.DeregistrationDelayTimeoutSeconds
+	actual.UnhealthyConnectionTermination = parsed.UnhealthyConnectionTermination
+	actual.UnhealthyDrainingIntervalSeconds = parsed.UnhealthyDrainingIntervalSeconds
 	attributes := make(map[string]string)
 	for _, attr := range attrResp.Attributes {
 		if _, ok := e.Attributes[fi.ValueOf(attr.Key)]; ok {
@@ -286,15 +540,69 @@ func (e *TargetGroup) Find(c *fi.CloudupContext) (*TargetGroup, error) {
 	// Prevent spurious changes
 	actual.Lifecycle = e.Lifecycle
 	actual.Shared = e.Shared
+	actual.ExportWithID = e.ExportWithID
+	actual.AdoptARN = e.AdoptARN
+	actual.FastTeardown = e.FastTeardown
 
 	if e.Name != nil {
 		actual.Name = e.Name
 	}
+	// The AWS name may carry a revision suffix, and cannot be changed in place
+	actual.TargetGroupName = e.TargetGroupName
 
 	return actual, nil
 }
 
+var _ fi.CloudupTaskNormalize = &TargetGroup{}
+
+// Normalize defaults the matcher of HTTP/HTTPS health checks according to the ProtocolVersion,
+// rather than relying on the AWS default, and the deregistration settings of FastTeardown.
+func (e *TargetGroup) Normalize(c *fi.CloudupContext) error {
+	if fi.ValueOf(e.Shared) {
+		return nil
+	}
+	e.Tags = e.networkLoadBalancer.propagateTagsTo(e.Tags, nil)
+	// Target groups that don't set a target type inherit the cluster-wide default, if any.
+	if e.TargetType == "" && c != nil && c.T.Cluster != nil && c.T.Cluster.Spec.CloudProvider.AWS != nil {
+		e.TargetType = elbv2types.TargetTypeEnum(fi.ValueOf(c.T.Cluster.Spec.CloudProvider.AWS.LoadBalancerTargetType))
+	}
+	if fi.ValueOf(e.FastTeardown) {
+		if e.DeregistrationDelaySeconds == nil {
+			e.DeregistrationDelaySeconds = fi.PtrTo(0)
+		}
+		if e.ConnectionTermination == nil {
+			e.ConnectionTermination = fi.PtrTo(true)
+		}
+	}
+	if e.HealthCheckMatcher == nil && isHTTPHealthCheck(e.healthCheckProtocol()) {
+		if e.isGRPC() {
+			e.HealthCheckMatcher = fi.PtrTo(defaultGRPCHealthCheckMatcher)
+		} else {
+			e.HealthCheckMatcher = fi.PtrTo(defaultHTTPHealthCheckMatcher)
+		}
+	}
+	return nil
+}
+
 func (e *TargetGroup) Run(c *fi.CloudupContext) error {
+	return runTimed(c, "TargetGroup", fi.ValueOf(e.Name), e.run)
+}
+
+func (e *TargetGroup) run(c *fi.CloudupContext) error {
+	if t, ok := c.Target.(*terraform.TerraformTarget); ok && t.EmitImportBlocks && !fi.ValueOf(e.Shared) {
+		if e.AdoptARN != "" {
+			t.AddImport("aws_lb_target_group", fi.ValueOf(e.Name), e.AdoptARN)
+			return fi.CloudupDefaultDeltaRunMethod(e, c)
+		}
+		// The terraform target does not call Find, so the existing target group is looked up here
+		targetGroupInfo, err := e.findLatestTargetGroupByName(c.Context(), awsup.GetCloud(c))
+		if err != nil {
+			return err
+		}
+		if targetGroupInfo != nil {
+			t.AddImport("aws_lb_target_group", fi.ValueOf(e.Name), targetGroupInfo.ARN)
+		}
+	}
 	return fi.CloudupDefaultDeltaRunMethod(e, c)
 }
 
@@ -306,20 +614,353 @@ func (_ *TargetGroup) ShouldCreate(a, e, changes *TargetGroup) (bool, error) {
 }
 
 func (s *TargetGroup) CheckChanges(a, e, changes *TargetGroup) error {
+	if a != nil && changes.TargetType != "" {
+		return fi.CannotChangeField("TargetType")
+	}
+	if a != nil && changes.ProtocolVersion != nil {
+		return fi.CannotChangeField("ProtocolVersion")
+	}
+	if a != nil && changes.IPAddressType != "" {
+		return fi.CannotChangeField("IPAddressType")
+	}
+	// ProxyProtocolV2 can be changed in place, but the targets must already expect the PROXY protocol header
+	// (or tolerate its absence, when disabling it), otherwise they will reject the connections.
+
+	if !fi.ValueOf(e.Shared) {
+		if err := e.validateTargetGroupName(); err != nil {
+			return err
+		}
+	}
+
+	if e.AdoptARN != "" && fi.ValueOf(e.Shared) {
+		return fmt.Errorf("target group %q cannot both be shared and adopt %q", fi.ValueOf(e.Name), e.AdoptARN)
+	}
+	if e.AdoptARN != "" && targetGroupRequiresRecreate(a, changes) {
+		return fmt.Errorf("adopted target group %q cannot be recreated", e.AdoptARN)
+	}
+
+	if targetGroupRequiresRecreate(a, changes) {
+		klog.Infof("target group %q will be recreated to move it from VPC %q to VPC %q", fi.ValueOf(e.Name), fi.ValueOf(a.VPC.ID), fi.ValueOf(e.VPC.ID))
+	}
+
+	switch e.TargetType {
+	case "", elbv2types.TargetTypeEnumInstance:
+	case elbv2types.TargetTypeEnumIp:
+		// AWS can only resolve IP targets within a VPC
+		if e.VPC == nil && !fi.ValueOf(e.Shared) {
+			return fmt.Errorf("target group %q with target type %q must specify a VPC", fi.ValueOf(e.Name), e.TargetType)
+		}
+	case elbv2types.TargetTypeEnumAlb:
+		// An Application Load Balancer can only be the target of a TCP target group on a Network Load Balancer
+		if e.Protocol != elbv2types.ProtocolEnumTcp {
+			return fmt.Errorf("target group %q with target type %q must use protocol %q, not %q", fi.ValueOf(e.Name), e.TargetType, elbv2types.ProtocolEnumTcp, e.Protocol)
+		}
+		if !isHTTPHealthCheck(e.healthCheckProtocol()) {
+			return fmt.Errorf("target group %q with target type %q must use HTTP or HTTPS health checks", fi.ValueOf(e.Name), e.TargetType)
+		}
+	default:
+		return fmt.Errorf("unsupported target type %q for target group %q", e.TargetType, fi.ValueOf(e.Name))
+	}
+
+	switch e.IPAddressType {
+	case "", elbv2types.TargetGroupIpAddressTypeEnumIpv4:
+	case elbv2types.TargetGroupIpAddressTypeEnumIpv6:
+		if e.TargetType != elbv2types.TargetTypeEnumIp {
+			return fmt.Errorf("target group %q with IP address type %q must use target type %q, not %q", fi.ValueOf(e.Name), e.IPAddressType, elbv2types.TargetTypeEnumIp, e.TargetType)
+		}
+		if e.VPC != nil && !fi.ValueOf(e.VPC.AmazonIPv6) && e.VPC.IPv6CIDR == nil {
+			return fmt.Errorf("target group %q with IP address type %q requires an IPv6 CIDR on its VPC", fi.ValueOf(e.Name), e.IPAddressType)
+		}
+		// The health checks of an IPv6 target group are sent to the IPv6 addresses of its targets, which AWS does
+		// not support for TCP_UDP target groups
+		if e.Protocol == elbv2types.ProtocolEnumTcpUdp {
+			return fmt.Errorf("target group %q with IP address type %q cannot use protocol %s", fi.ValueOf(e.Name), e.IPAddressType, e.Protocol)
+		}
+	default:
+		return fmt.Errorf("unsupported IP address type %q for target group %q", e.IPAddressType, fi.ValueOf(e.Name))
+	}
+
+	if err := e.validateHealthCheckBounds(); err != nil {
+		return err
+	}
+
+	if err := e.validateStickiness(); err != nil {
+		return err
+	}
+
+	if e.PreserveClientIP != nil && !*e.PreserveClientIP {
+		switch e.Protocol {
+		case elbv2types.ProtocolEnumUdp, elbv2types.ProtocolEnumTcpUdp:
+			return fmt.Errorf("client IP preservation cannot be disabled for target group %q with protocol %s", fi.ValueOf(e.Name), e.Protocol)
+		}
+		if !fi.ValueOf(e.ProxyProtocolV2) {
+			klog.Warningf("target group %q disables client IP preservation without PROXY protocol v2, so its targets cannot see the client addresses", fi.ValueOf(e.Name))
+		}
+	}
+
+	if e.DeregistrationDelaySeconds != nil && (*e.DeregistrationDelaySeconds < 0 || *e.DeregistrationDelaySeconds > maxDeregistrationDelaySeconds) {
+		return fmt.Errorf("deregistration delay of target group %q must be between 0 and %d seconds, got %d", fi.ValueOf(e.Name), maxDeregistrationDelaySeconds, *e.DeregistrationDelaySeconds)
+	}
+
+	if fi.ValueOf(e.FastTeardown) {
+		if fi.ValueOf(e.DeregistrationDelaySeconds) != 0 {
+			return fmt.Errorf("target group %q with FastTeardown cannot have a deregistration delay of %d seconds", fi.ValueOf(e.Name), *e.DeregistrationDelaySeconds)
+		}
+		if e.ConnectionTermination != nil && !*e.ConnectionTermination {
+			return fmt.Errorf("target group %q with FastTeardown must terminate the connections of deregistered targets", fi.ValueOf(e.Name))
+		}
+	}
+
+	if e.UnhealthyDrainingIntervalSeconds != nil {
+		if *e.UnhealthyDrainingIntervalSeconds < 0 || *e.UnhealthyDrainingIntervalSeconds > maxUnhealthyDrainingIntervalSeconds {
+			return fmt.Errorf("unhealthy draining interval of target group %q must be between 0 and %d seconds, got %d", fi.ValueOf(e.Name), maxUnhealthyDrainingIntervalSeconds, *e.UnhealthyDrainingIntervalSeconds)
+		}
+		// AWS terminates the connections to unhealthy targets by default
+		if e.UnhealthyConnectionTermination == nil || *e.UnhealthyConnectionTermination {
+			return fmt.Errorf("unhealthy draining interval of target group %q requires UnhealthyConnectionTermination to be false", fi.ValueOf(e.Name))
+		}
+	}
+
+	switch fi.ValueOf(e.CrossZoneLoadBalancing) {
+	case "", "true", "false", TargetGroupCrossZoneUseLoadBalancerConfiguration:
+	default:
+		return fmt.Errorf("unsupported cross-zone load balancing %q for target group %q, expected true, false or %s", fi.ValueOf(e.CrossZoneLoadBalancing), fi.ValueOf(e.Name), TargetGroupCrossZoneUseLoadBalancerConfiguration)
+	}
+
+	healthCheckProtocol := e.healthCheckProtocol()
+	switch healthCheckProtocol {
+	case elbv2types.ProtocolEnumTcp, elbv2types.ProtocolEnumHttp, elbv2types.ProtocolEnumHttps:
+	default:
+		return fmt.Errorf("unsupported health check protocol %q for target group %q", healthCheckProtocol, fi.ValueOf(e.Name))
+	}
+
+	if !isHTTPHealthCheck(healthCheckProtocol) {
+		if e.HealthCheckPath != nil {
+			return fmt.Errorf("HealthCheckPath cannot be set for target group %q with %s health checks", fi.ValueOf(e.Name), healthCheckProtocol)
+		}
+		if e.HealthCheckMatcher != nil {
+			return fmt.Errorf("HealthCheckMatcher cannot be set for target group %q with %s health checks", fi.ValueOf(e.Name), healthCheckProtocol)
+		}
+	}
+
+	if e.ProtocolVersion != nil {
+		switch fi.ValueOf(e.ProtocolVersion) {
+		case "HTTP1", "HTTP2", "GRPC":
+		default:
+			return fmt.Errorf("unsupported protocol version %q for target group %q", fi.ValueOf(e.ProtocolVersion), fi.ValueOf(e.Name))
+		}
+		if !isHTTPHealthCheck(e.Protocol) {
+			return fmt.Errorf("ProtocolVersion can only be set for HTTP or HTTPS target groups, not target group %q with protocol %s", fi.ValueOf(e.Name), e.Protocol)
+		}
+	}
+
+	if e.isGRPC() {
+		if !isHTTPHealthCheck(healthCheckProtocol) {
+			return fmt.Errorf("target group %q with protocol version GRPC must use HTTP or HTTPS health checks", fi.ValueOf(e.Name))
+		}
+		if e.HealthCheckPath != nil && !strings.HasPrefix(fi.ValueOf(e.HealthCheckPath), "/") {
+			return fmt.Errorf("gRPC HealthCheckPath %q for target group %q must be of the form /package.Service/Method", fi.ValueOf(e.HealthCheckPath), fi.ValueOf(e.Name))
+		}
+		if e.HealthCheckMatcher != nil {
+			if err := validateGRPCMatcher(fi.ValueOf(e.HealthCheckMatcher)); err != nil {
+				return fmt.Errorf("invalid HealthCheckMatcher for target group %q: %w", fi.ValueOf(e.Name), err)
+			}
+		}
+	} else if e.HealthCheckMatcher != nil {
+		if err := validateHTTPMatcher(fi.ValueOf(e.HealthCheckMatcher)); err != nil {
+			return fmt.Errorf("invalid HealthCheckMatcher for target group %q: %w", fi.ValueOf(e.Name), err)
+		}
+	}
 	return nil
 }
 
-func (_ *TargetGroup) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *TargetGroup) error {
-	ctx := context.TODO()
-	shared := fi.ValueOf(e.Shared)
-	if shared {
+// targetGroupRequiresRecreate returns true if the changes cannot be applied to the existing target group,
+// which must be replaced by a new one instead; currently that is the case when the VPC changes.
+func targetGroupRequiresRecreate(a, changes *TargetGroup) bool {
+	return a != nil && changes.VPC != nil
+}
+
+// validateHealthCheckBounds checks the health check settings against the bounds enforced by AWS,
+// so that out-of-range values fail before any API call rather than part way through an update.
+func (e *TargetGroup) validateHealthCheckBounds() error {
+	if e.Interval != nil && (*e.Interval < minHealthCheckInterval || *e.Interval > maxHealthCheckInterval) {
+		return fmt.Errorf("health check interval %d for target group %q must be between %d and %d seconds", *e.Interval, fi.ValueOf(e.Name), minHealthCheckInterval, maxHealthCheckInterval)
+	}
+	if e.HealthyThreshold != nil && (*e.HealthyThreshold < minHealthCheckThreshold || *e.HealthyThreshold > maxHealthCheckThreshold) {
+		return fmt.Errorf("healthy threshold %d for target group %q must be between %d and %d", *e.HealthyThreshold, fi.ValueOf(e.Name), minHealthCheckThreshold, maxHealthCheckThreshold)
+	}
+	if e.UnhealthyThreshold != nil && (*e.UnhealthyThreshold < minHealthCheckThreshold || *e.UnhealthyThreshold > maxHealthCheckThreshold) {
+		return fmt.Errorf("unhealthy threshold %d for target group %q must be between %d and %d", *e.UnhealthyThreshold, fi.ValueOf(e.Name), minHealthCheckThreshold, maxHealthCheckThreshold)
+	}
+	if e.HealthCheckPath != nil && (len(*e.HealthCheckPath) == 0 || len(*e.HealthCheckPath) > maxHealthCheckPathLength) {
+		return fmt.Errorf("HealthCheckPath for target group %q must be between 1 and %d characters", fi.ValueOf(e.Name), maxHealthCheckPathLength)
+	}
+	if e.HealthCheckTimeout != nil && (*e.HealthCheckTimeout < minHealthCheckTimeout || *e.HealthCheckTimeout > maxHealthCheckTimeout) {
+		return fmt.Errorf("health check timeout %d for target group %q must be between %d and %d seconds", *e.HealthCheckTimeout, fi.ValueOf(e.Name), minHealthCheckTimeout, maxHealthCheckTimeout)
+	}
+	if port := fi.ValueOf(e.HealthCheckPort); e.HealthCheckPort != nil && port != "traffic-port" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("HealthCheckPort %q for target group %q must be a port number or %q", port, fi.ValueOf(e.Name), "traffic-port")
+		}
+	}
+	return nil
+}
+
+// validateTargetGroupName checks that the AWS name of the target group is valid: at most 32 alphanumeric characters
+// or hyphens, not beginning or ending with a hyphen.
+func (e *TargetGroup) validateTargetGroupName() error {
+	name := e.awsTargetGroupName()
+	if name == "" {
+		return nil
+	}
+	if len(name) > maxTargetGroupNameLength {
+		return fmt.Errorf("name %q of target group %q must be at most %d characters", name, fi.ValueOf(e.Name), maxTargetGroupNameLength)
+	}
+	if strings.HasPrefix(name, "-") || strings.HasSuffix(name, "-") {
+		return fmt.Errorf("name %q of target group %q must not begin or end with a hyphen", name, fi.ValueOf(e.Name))
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+			return fmt.Errorf("name %q of target group %q must only contain alphanumeric characters and hyphens", name, fi.ValueOf(e.Name))
+		}
+	}
+	return nil
+}
+
+// validateStickiness checks that the stickiness type is supported by the load balancer the target group is used with:
+// source_ip for the TCP and UDP target groups of NLBs, and cookies for the HTTP and HTTPS target groups of ALBs.
+func (e *TargetGroup) validateStickiness() error {
+	if e.Stickiness == nil {
+		return nil
+	}
+	switch e.Stickiness.Type {
+	case TargetGroupStickinessTypeSourceIP:
+		switch e.Protocol {
+		case elbv2types.ProtocolEnumTcp, elbv2types.ProtocolEnumUdp, elbv2types.ProtocolEnumTcpUdp:
+		default:
+			return fmt.Errorf("stickiness type %q for target group %q is only supported with TCP, UDP or TCP_UDP target groups, not %s", e.Stickiness.Type, fi.ValueOf(e.Name), e.Protocol)
+		}
+	case TargetGroupStickinessTypeLBCookie, TargetGroupStickinessTypeAppCookie:
+		if !isHTTPHealthCheck(e.Protocol) {
+			return fmt.Errorf("stickiness type %q for target group %q is only supported with HTTP or HTTPS target groups, use %q instead", e.Stickiness.Type, fi.ValueOf(e.Name), TargetGroupStickinessTypeSourceIP)
+		}
+	case "":
+		return fi.RequiredField("Stickiness.Type")
+	default:
+		return fmt.Errorf("unsupported stickiness type %q for target group %q", e.Stickiness.Type, fi.ValueOf(e.Name))
+	}
+	return nil
+}
+
+// isGRPC returns true if the target group uses the GRPC protocol version.
+func (e *TargetGroup) isGRPC() bool {
+	return fi.ValueOf(e.ProtocolVersion) == "GRPC"
+}
+
+// healthCheckMatcher returns the matcher for successful health checks, using gRPC codes for GRPC target groups.
+func (e *TargetGroup) healthCheckMatcher() *elbv2types.Matcher {
+	if e.HealthCheckMatcher == nil {
 		return nil
 	}
+	if e.isGRPC() {
+		return &elbv2types.Matcher{GrpcCode: e.HealthCheckMatcher}
+	}
+	return &elbv2types.Matcher{HttpCode: e.HealthCheckMatcher}
+}
+
+// validateGRPCMatcher checks that the matcher is a list of gRPC codes (0-99), either single values or ranges,
+// e.g. "0", "0,12" or "0-99".
+func validateGRPCMatcher(matcher string) error {
+	for _, part := range strings.Split(matcher, ",") {
+		for _, code := range strings.SplitN(part, "-", 2) {
+			n, err := strconv.Atoi(code)
+			if err != nil || n < 0 || n > 99 {
+				return fmt.Errorf("gRPC matcher %q must contain codes between 0 and 99", matcher)
+			}
+		}
+	}
+	return nil
+}
+
+// validateHTTPMatcher checks that the matcher is a list of HTTP codes (200-599), either single values or ranges,
+// e.g. "200", "200,202" or "200-399".
+func validateHTTPMatcher(matcher string) error {
+	for _, part := range strings.Split(matcher, ",") {
+		for _, code := range strings.SplitN(part, "-", 2) {
+			n, err := strconv.Atoi(code)
+			if err != nil || n < 200 || n > 599 {
+				return fmt.Errorf("HTTP matcher %q must contain codes between 200 and 599", matcher)
+			}
+		}
+	}
+	return nil
+}
+
+// healthCheckProtocol returns the protocol used for health checks, applying the AWS default when HealthCheckProtocol is not set.
+func (e *TargetGroup) healthCheckProtocol() elbv2types.ProtocolEnum {
+	if e.HealthCheckProtocol != "" {
+		return e.HealthCheckProtocol
+	}
+	if isHTTPHealthCheck(e.Protocol) {
+		return e.Protocol
+	}
+	return elbv2types.ProtocolEnumTcp
+}
+
+// mergedTags returns the tags to apply to the Target Group: the user-specified tags,
+// with the Name tag and the cluster ownership tags taking precedence.
+func (e *TargetGroup) mergedTags(clusterTags map[string]string) map[string]string {
+	reserved := make(map[string]string)
+	for k, v := range clusterTags {
+		reserved[k] = v
+	}
+	if e.Name != nil {
+		reserved["Name"] = *e.Name
+	}
 
 	tags := make(map[string]string)
 	for k, v := range e.Tags {
+		if isReservedTargetGroupTag(k, reserved) {
+			if v != reserved[k] {
+				klog.Warningf("ignoring reserved tag %q=%q on target group %q", k, v, fi.ValueOf(e.Name))
+			}
+			continue
+		}
+		tags[k] = v
+	}
+	for k, v := range reserved {
 		tags[k] = v
 	}
+	return tags
+}
+
+// isReservedTargetGroupTag returns true if the tag key is managed by kops or AWS, and so cannot be set by users.
+func isReservedTargetGroupTag(key string, reserved map[string]string) bool {
+	if key == "Name" || key == awsup.KopsResourceRevisionTag || strings.HasPrefix(key, "aws:") {
+		return true
+	}
+	_, found := reserved[key]
+	return found
+}
+
+// isKopsManagedTargetGroupTag returns true if the tag key can only have been set by kops or for the cluster,
+// so that it is removed from the Target Group once it is no longer expected.
+func isKopsManagedTargetGroupTag(key string) bool {
+	return key == "Name" || key == awsup.TagClusterName || strings.HasPrefix(key, awsup.TagNameClusterOwnershipPrefix) || strings.HasPrefix(key, "kops.k8s.io/")
+}
+
+func isHTTPHealthCheck(protocol elbv2types.ProtocolEnum) bool {
+	return protocol == elbv2types.ProtocolEnumHttp || protocol == elbv2types.ProtocolEnumHttps
+}
+
+func (_ *TargetGroup) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *TargetGroup) error {
+	ctx := t.Context()
+	shared := fi.ValueOf(e.Shared)
+	if shared {
+		return nil
+	}
+
+	tags := e.mergedTags(t.Cloud.Tags())
 	if a != nil {
 		if a.revision != "" {
 			tags[awsup.KopsResourceRevisionTag] = a.revision
@@ -340,13 +981,36 @@ func (_ *TargetGroup) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *TargetGrou
 	// to registered targets using the port and protocol that you specified for the target group. You can override this port
 	// when you register each target with the target group.
 
-	if a == nil {
-		createTargetGroupName := *e.Name
-		if tags[awsup.KopsResourceRevisionTag] != "" {
-			s := *e.Name + tags[awsup.KopsResourceRevisionTag]
+	recreate := targetGroupRequiresRecreate(a, changes)
+	if a == nil || recreate {
+		// A previous attempt may have created the target group and then failed, e.g. while setting its attributes,
+		// in which case we adopt the existing target group rather than creating a duplicate.
+		existing, err := awsup.FindTargetGroupByNameTag(ctx, t.Cloud, fi.ValueOf(e.Name), tags[awsup.KopsResourceRevisionTag], fi.ValueOf(e.VPC.ID))
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			klog.Infof("Found existing target group %q with Name tag %q, adopting it", existing.ARN, fi.ValueOf(e.Name))
+			if err := ModifyTargetGroupAttributes(ctx, t.Cloud, existing.TargetGroup.TargetGroupArn, e.targetGroupAttributes()); err != nil {
+				return err
+			}
+			e.ARN = existing.TargetGroup.TargetGroupArn
+			e.info = existing
+			return nil
+		}
+
+		// Target group names are unique within the region, so a replacement in a new VPC needs a name of its own
+		suffix := tags[awsup.KopsResourceRevisionTag]
+		if recreate {
+			suffix += fi.ValueOf(e.VPC.ID)
+		}
+
+		createTargetGroupName := e.awsTargetGroupName()
+		if suffix != "" {
+			s := createTargetGroupName + suffix
 			// We always compute the hash and add it, lest we trick users into assuming that we never do this
 			opt := truncate.TruncateStringOptions{
-				MaxLength:     32,
+				MaxLength:     maxTargetGroupNameLength,
 				AlwaysAddHash: true,
 				HashLength:    6,
 			}
@@ -357,12 +1021,20 @@ func (_ *TargetGroup) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *TargetGrou
 			Name:                       &createTargetGroupName,
 			Port:                       e.Port,
 			Protocol:                   e.Protocol,
+			TargetType:                 e.TargetType,
+			IpAddressType:              e.IPAddressType,
 			VpcId:                      e.VPC.ID,
 			HealthCheckIntervalSeconds: e.Interval,
 			HealthyThresholdCount:      e.HealthyThreshold,
 			UnhealthyThresholdCount:    e.UnhealthyThreshold,
+			HealthCheckProtocol:        e.HealthCheckProtocol,
+			HealthCheckPath:            e.HealthCheckPath,
+			HealthCheckPort:            e.HealthCheckPort,
+			HealthCheckTimeoutSeconds:  e.HealthCheckTimeout,
+			ProtocolVersion:            e.ProtocolVersion,
 			Tags:                       awsup.ELBv2Tags(tags),
 		}
+		request.Matcher = e.healthCheckMatcher()
 
 		klog.V(2).Infof("Creating Target Group for NLB")
 		response, err := t.Cloud.ELBV2().CreateTargetGroup(ctx, request)
@@ -370,39 +1042,102 @@ func (_ *TargetGroup) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *TargetGrou
 			return fmt.Errorf("creating NLB target group: %w", err)
 		}
 
-		if err := ModifyTargetGroupAttributes(ctx, t.Cloud, response.TargetGroups[0].TargetGroupArn, e.Attributes); err != nil {
+		if err := ModifyTargetGroupAttributes(ctx, t.Cloud, response.TargetGroups[0].TargetGroupArn, e.targetGroupAttributes()); err != nil {
 			return err
 		}
 
 		// Avoid spurious changes
 		e.ARN = response.TargetGroups[0].TargetGroupArn
+		if recreate {
+			e.info = nil
+		}
 
 		// TODO: Set revision or info?
 	} else {
 		if a.ARN != nil {
-			if err := t.AddELBV2Tags(fi.ValueOf(a.ARN), e.Tags); err != nil {
+			if err := t.AddELBV2Tags(fi.ValueOf(a.ARN), tags); err != nil {
 				return err
 			}
-			if err := ModifyTargetGroupAttributes(ctx, t.Cloud, a.ARN, e.Attributes); err != nil {
+			// Find only reports the tags that are expected or managed by kops, so the others are kops tags that are no longer expected
+			staleTags := make(map[string]string)
+			for k, v := range a.Tags {
+				if _, found := tags[k]; !found {
+					staleTags[k] = v
+				}
+			}
+			if len(staleTags) != 0 {
+				klog.V(2).Infof("Removing tags %v from Target Group %q", staleTags, fi.ValueOf(a.ARN))
+				if err := t.Cloud.RemoveELBV2Tags(fi.ValueOf(a.ARN), staleTags); err != nil {
+					return fmt.Errorf("removing tags from target group %q: %w", fi.ValueOf(a.ARN), err)
+				}
+			}
+			// Only the attributes that differ are sent, so that other attributes changed outside of kops are not overwritten
+			if err := ModifyTargetGroupAttributes(ctx, t.Cloud, a.ARN, changedTargetGroupAttributes(a.targetGroupAttributes(), e.targetGroupAttributes())); err != nil {
 				return err
 			}
+			// Health check changes are applied in place, so the listeners forwarding to the target group are left untouched
+			if changes.Interval != nil || changes.HealthyThreshold != nil || changes.UnhealthyThreshold != nil || changes.HealthCheckProtocol != "" || changes.HealthCheckPath != nil || changes.HealthCheckMatcher != nil || changes.HealthCheckPort != nil || changes.HealthCheckTimeout != nil {
+				request := &elbv2.ModifyTargetGroupInput{
+					TargetGroupArn:             a.ARN,
+					HealthCheckIntervalSeconds: e.Interval,
+					HealthyThresholdCount:      e.HealthyThreshold,
+					UnhealthyThresholdCount:    e.UnhealthyThreshold,
+					HealthCheckProtocol:        e.HealthCheckProtocol,
+					HealthCheckPath:            e.HealthCheckPath,
+					HealthCheckPort:            e.HealthCheckPort,
+					HealthCheckTimeoutSeconds:  e.HealthCheckTimeout,
+				}
+				request.Matcher = e.healthCheckMatcher()
+
+				klog.V(2).Infof("Modifying Target Group health check for %q", fi.ValueOf(a.ARN))
+				if _, err := t.Cloud.ELBV2().ModifyTargetGroup(ctx, request); err != nil {
+					return fmt.Errorf("modifying target group health check: %w", err)
+				}
+			}
 		}
 	}
 	return nil
 }
 
+// targetGroupAttributes returns the attributes of the target group, including those set through dedicated fields.
+func (e *TargetGroup) targetGroupAttributes() map[string]string {
+	attributes := &awsup.TargetGroupAttributes{
+		Other:                                    e.Attributes,
+		DeregistrationDelayConnectionTermination: e.ConnectionTermination,
+		DeregistrationDelayTimeoutSeconds:        e.DeregistrationDelaySeconds,
+		ProxyProtocolV2:                          e.ProxyProtocolV2,
+		CrossZoneLoadBalancing:                   e.CrossZoneLoadBalancing,
+		PreserveClientIP:                         e.PreserveClientIP,
+		UnhealthyConnectionTermination:           e.UnhealthyConnectionTermination,
+		UnhealthyDrainingIntervalSeconds:         e.UnhealthyDrainingIntervalSeconds,
+	}
+	if e.Stickiness != nil {
+		attributes.StickinessEnabled = fi.PtrTo(e.Stickiness.Enabled)
+		attributes.StickinessType = fi.PtrTo(e.Stickiness.Type)
+	}
+	return attributes.Map()
+}
+
+// changedTargetGroupAttributes returns the desired attributes whose values differ from the actual attributes.
+func changedTargetGroupAttributes(actual, desired map[string]string) map[string]string {
+	changed := make(map[string]string)
+	for k, v := range desired {
+		if current, found := actual[k]; !found || current != v {
+			changed[k] = v
+		}
+	}
+	return changed
+}
+
 func ModifyTargetGroupAttributes(ctx context.Context, cloud awsup.AWSCloud, arn *string, attributes map[string]string) error {
+	if len(attributes) == 0 {
+		return nil
+	}
 	klog.V(2).Infof("Modifying Target Group attributes for NLB")
 	attrReq := &elbv2.ModifyTargetGroupAttributesInput{
-		Attributes:     []elbv2types.TargetGroupAttribute{},
+		Attributes:     awsup.MarshalTargetGroupAttributes(attributes),
 		TargetGroupArn: arn,
 	}
-	for k, v := range attributes {
-		attrReq.Attributes = append(attrReq.Attributes, elbv2types.TargetGroupAttribute{
-			Key:   fi.PtrTo(k),
-			Value: fi.PtrTo(v),
-		})
-	}
 	if _, err := cloud.ELBV2().ModifyTargetGroupAttributes(ctx, attrReq); err != nil {
 		return fmt.Errorf("error modifying target group attributes for NLB : %v", err)
 	}
@@ -422,11 +1157,29 @@ type terraformTargetGroup struct {
 	Name                  string                          `cty:"name"`
 	Port                  int32                           `cty:"port"`
 	Protocol              elbv2types.ProtocolEnum         `cty:"protocol"`
+	TargetType            *string                         `cty:"target_type"`
+	IPAddressType         *string                         `cty:"ip_address_type"`
+	ProtocolVersion       *string                         `cty:"protocol_version"`
 	VPCID                 *terraformWriter.Literal        `cty:"vpc_id"`
 	ConnectionTermination string                          `cty:"connection_termination"`
 	DeregistrationDelay   string                          `cty:"deregistration_delay"`
+	ProxyProtocolV2       *bool                           `cty:"proxy_protocol_v2"`
+	Stickiness            *terraformTargetGroupStickiness `cty:"stickiness"`
+	CrossZoneEnabled      *string                         `cty:"load_balancing_cross_zone_enabled"`
+	PreserveClientIP      *string                         `cty:"preserve_client_ip"`
 	Tags                  map[string]string               `cty:"tags"`
 	HealthCheck           terraformTargetGroupHealthCheck `cty:"health_check"`
+	TargetHealthState     *terraformTargetHealthState     `cty:"target_health_state"`
+}
+
+type terraformTargetHealthState struct {
+	EnableUnhealthyConnectionTermination *bool `cty:"enable_unhealthy_connection_termination"`
+	UnhealthyDrainingInterval            *int  `cty:"unhealthy_draining_interval"`
+}
+
+type terraformTargetGroupStickiness struct {
+	Enabled bool   `cty:"enabled"`
+	Type    string `cty:"type"`
 }
 
 type terraformTargetGroupHealthCheck struct {
@@ -434,6 +1187,10 @@ type terraformTargetGroupHealthCheck struct {
 	HealthyThreshold   int32                   `cty:"healthy_threshold"`
 	UnhealthyThreshold int32                   `cty:"unhealthy_threshold"`
 	Protocol           elbv2types.ProtocolEnum `cty:"protocol"`
+	Path               *string                 `cty:"path"`
+	Matcher            *string                 `cty:"matcher"`
+	Port               *string                 `cty:"port"`
+	Timeout            *int32                  `cty:"timeout"`
 }
 
 func (_ *TargetGroup) RenderTerraform(t *terraform.TerraformTarget, a, e, changes *TargetGroup) error {
@@ -447,20 +1204,48 @@ func (_ *TargetGroup) RenderTerraform(t *terraform.TerraformTarget, a, e, change
 	}
 
 	tf := &terraformTargetGroup{
-		Name:     *e.Name,
-		Port:     *e.Port,
-		Protocol: e.Protocol,
-		VPCID:    e.VPC.TerraformLink(),
-		Tags:     e.Tags,
+		Name:             e.awsTargetGroupName(),
+		Port:             *e.Port,
+		Protocol:         e.Protocol,
+		ProtocolVersion:  e.ProtocolVersion,
+		ProxyProtocolV2:  e.ProxyProtocolV2,
+		CrossZoneEnabled: e.CrossZoneLoadBalancing,
+		VPCID:            e.VPC.TerraformLink(),
+		Tags:             e.mergedTags(t.Cloud.(awsup.AWSCloud).Tags()),
 		HealthCheck: terraformTargetGroupHealthCheck{
 			Interval:           *e.Interval,
 			HealthyThreshold:   *e.HealthyThreshold,
 			UnhealthyThreshold: *e.UnhealthyThreshold,
-			Protocol:           elbv2types.ProtocolEnumTcp,
+			Protocol:           e.healthCheckProtocol(),
+			Path:               e.HealthCheckPath,
+			Matcher:            e.HealthCheckMatcher,
+			Port:               e.HealthCheckPort,
+			Timeout:            e.HealthCheckTimeout,
 		},
 	}
+	if e.TargetType != "" {
+		tf.TargetType = fi.PtrTo(string(e.TargetType))
+	}
+	if e.IPAddressType != "" {
+		tf.IPAddressType = fi.PtrTo(string(e.IPAddressType))
+	}
+	if e.PreserveClientIP != nil {
+		tf.PreserveClientIP = fi.PtrTo(strconv.FormatBool(*e.PreserveClientIP))
+	}
+	if e.Stickiness != nil {
+		tf.Stickiness = &terraformTargetGroupStickiness{
+			Enabled: e.Stickiness.Enabled,
+			Type:    e.Stickiness.Type,
+		}
+	}
+	if e.UnhealthyConnectionTermination != nil || e.UnhealthyDrainingIntervalSeconds != nil {
+		tf.TargetHealthState = &terraformTargetHealthState{
+			EnableUnhealthyConnectionTermination: e.UnhealthyConnectionTermination,
+			UnhealthyDrainingInterval:            e.UnhealthyDrainingIntervalSeconds,
+		}
+	}
 
-	for attr, val := range e.Attributes {
+	for attr, val := range e.targetGroupAttributes() {
 		if attr == TargetGroupAttributeDeregistrationDelayConnectionTerminationEnabled {
 			tf.ConnectionTermination = val
 		}
@@ -469,6 +1254,12 @@ func (_ *TargetGroup) RenderTerraform(t *terraform.TerraformTarget, a, e, change
 		}
 	}
 
+	if fi.ValueOf(e.ExportWithID) != "" {
+		if err := t.AddOutputVariable(*e.ExportWithID+"_target_group_arn", terraformWriter.LiteralProperty("aws_lb_target_group", *e.Name, "arn")); err != nil {
+			return err
+		}
+	}
+
 	return t.RenderResource("aws_lb_target_group", *e.Name, tf)
 }
 
@@ -0,0 +1,2518 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awstasks
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"k8s.io/kops/cloudmock/aws/mockec2"
+	"k8s.io/kops/cloudmock/aws/mockelbv2"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+func TestTargetGroupCheckChangesHealthCheck(t *testing.T) {
+	grid := []struct {
+		name        string
+		targetGroup *TargetGroup
+		expectError bool
+	}{
+		{
+			name: "tcp health check on tcp target group",
+			targetGroup: &TargetGroup{
+				Name:     s("tg"),
+				Protocol: elbv2types.ProtocolEnumTcp,
+			},
+		},
+		{
+			name: "tcp health check on http target group",
+			targetGroup: &TargetGroup{
+				Name:                s("tg"),
+				Protocol:            elbv2types.ProtocolEnumHttp,
+				HealthCheckProtocol: elbv2types.ProtocolEnumTcp,
+			},
+		},
+		{
+			name: "http health check with path and matcher",
+			targetGroup: &TargetGroup{
+				Name:                s("tg"),
+				Protocol:            elbv2types.ProtocolEnumHttp,
+				HealthCheckProtocol: elbv2types.ProtocolEnumHttp,
+				HealthCheckPath:     s("/healthz"),
+				HealthCheckMatcher:  s("200"),
+			},
+		},
+		{
+			name: "defaulted http health check with path",
+			targetGroup: &TargetGroup{
+				Name:            s("tg"),
+				Protocol:        elbv2types.ProtocolEnumHttps,
+				HealthCheckPath: s("/healthz"),
+			},
+		},
+		{
+			name: "tcp health check with path",
+			targetGroup: &TargetGroup{
+				Name:                s("tg"),
+				Protocol:            elbv2types.ProtocolEnumHttp,
+				HealthCheckProtocol: elbv2types.ProtocolEnumTcp,
+				HealthCheckPath:     s("/healthz"),
+			},
+			expectError: true,
+		},
+		{
+			name: "tcp health check with matcher",
+			targetGroup: &TargetGroup{
+				Name:                s("tg"),
+				Protocol:            elbv2types.ProtocolEnumHttp,
+				HealthCheckProtocol: elbv2types.ProtocolEnumTcp,
+				HealthCheckMatcher:  s("200"),
+			},
+			expectError: true,
+		},
+		{
+			name: "defaulted tcp health check with path",
+			targetGroup: &TargetGroup{
+				Name:            s("tg"),
+				Protocol:        elbv2types.ProtocolEnumTls,
+				HealthCheckPath: s("/healthz"),
+			},
+			expectError: true,
+		},
+		{
+			name: "http health check with invalid matcher",
+			targetGroup: &TargetGroup{
+				Name:                s("tg"),
+				Protocol:            elbv2types.ProtocolEnumHttp,
+				HealthCheckProtocol: elbv2types.ProtocolEnumHttp,
+				HealthCheckMatcher:  s("100-199"),
+			},
+			expectError: true,
+		},
+		{
+			name: "https health check on another port of a tls target group",
+			targetGroup: &TargetGroup{
+				Name:                s("tg"),
+				Protocol:            elbv2types.ProtocolEnumTls,
+				Port:                fi.PtrTo(int32(443)),
+				HealthCheckProtocol: elbv2types.ProtocolEnumHttps,
+				HealthCheckPort:     s("8443"),
+				HealthCheckPath:     s("/readyz"),
+			},
+		},
+		{
+			name: "tls health check",
+			targetGroup: &TargetGroup{
+				Name:                s("tg"),
+				Protocol:            elbv2types.ProtocolEnumTls,
+				HealthCheckProtocol: elbv2types.ProtocolEnumTls,
+			},
+			expectError: true,
+		},
+		{
+			name: "udp health check",
+			targetGroup: &TargetGroup{
+				Name:                s("tg"),
+				Protocol:            elbv2types.ProtocolEnumUdp,
+				HealthCheckProtocol: elbv2types.ProtocolEnumUdp,
+			},
+			expectError: true,
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			err := (&TargetGroup{}).CheckChanges(nil, g.targetGroup, g.targetGroup)
+			if g.expectError && err == nil {
+				t.Errorf("expected error, got none")
+			}
+			if !g.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestTargetGroupCheckChangesHealthCheckBounds(t *testing.T) {
+	grid := []struct {
+		name               string
+		interval           int32
+		healthyThreshold   int32
+		unhealthyThreshold int32
+		path               *string
+		timeout            *int32
+		port               *string
+		expectError        bool
+	}{
+		{name: "minimum values", interval: 5, healthyThreshold: 2, unhealthyThreshold: 2},
+		{name: "maximum values", interval: 300, healthyThreshold: 10, unhealthyThreshold: 10},
+		{name: "interval too short", interval: 4, healthyThreshold: 2, unhealthyThreshold: 2, expectError: true},
+		{name: "interval too long", interval: 301, healthyThreshold: 2, unhealthyThreshold: 2, expectError: true},
+		{name: "healthy threshold too low", interval: 10, healthyThreshold: 1, unhealthyThreshold: 2, expectError: true},
+		{name: "healthy threshold too high", interval: 10, healthyThreshold: 11, unhealthyThreshold: 2, expectError: true},
+		{name: "unhealthy threshold too low", interval: 10, healthyThreshold: 2, unhealthyThreshold: 1, expectError: true},
+		{name: "unhealthy threshold too high", interval: 10, healthyThreshold: 2, unhealthyThreshold: 11, expectError: true},
+		{name: "maximum path length", interval: 10, healthyThreshold: 2, unhealthyThreshold: 2, path: s("/" + strings.Repeat("a", 1023))},
+		{name: "path too long", interval: 10, healthyThreshold: 2, unhealthyThreshold: 2, path: s("/" + strings.Repeat("a", 1024)), expectError: true},
+		{name: "empty path", interval: 10, healthyThreshold: 2, unhealthyThreshold: 2, path: s(""), expectError: true},
+		{name: "timeout bounds", interval: 10, healthyThreshold: 2, unhealthyThreshold: 2, timeout: fi.PtrTo(int32(2))},
+		{name: "timeout too short", interval: 10, healthyThreshold: 2, unhealthyThreshold: 2, timeout: fi.PtrTo(int32(1)), expectError: true},
+		{name: "timeout too long", interval: 10, healthyThreshold: 2, unhealthyThreshold: 2, timeout: fi.PtrTo(int32(121)), expectError: true},
+		{name: "traffic port", interval: 10, healthyThreshold: 2, unhealthyThreshold: 2, port: s("traffic-port")},
+		{name: "port number", interval: 10, healthyThreshold: 2, unhealthyThreshold: 2, port: s("8443")},
+		{name: "invalid port", interval: 10, healthyThreshold: 2, unhealthyThreshold: 2, port: s("65536"), expectError: true},
+		{name: "port name", interval: 10, healthyThreshold: 2, unhealthyThreshold: 2, port: s("https"), expectError: true},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			targetGroup := &TargetGroup{
+				Name:               s("tg"),
+				Protocol:           elbv2types.ProtocolEnumHttp,
+				Interval:           fi.PtrTo(g.interval),
+				HealthyThreshold:   fi.PtrTo(g.healthyThreshold),
+				UnhealthyThreshold: fi.PtrTo(g.unhealthyThreshold),
+				HealthCheckPath:    g.path,
+				HealthCheckTimeout: g.timeout,
+				HealthCheckPort:    g.port,
+			}
+			err := (&TargetGroup{}).CheckChanges(nil, targetGroup, targetGroup)
+			if g.expectError && err == nil {
+				t.Errorf("expected error, got none")
+			}
+			if !g.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestTargetGroupHealthCheckPortAndTimeout(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(port string, timeout int32) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		allTasks["tg1"] = &TargetGroup{
+			Name:                s("tg1"),
+			Lifecycle:           fi.LifecycleSync,
+			VPC:                 nlb1.VPC,
+			Tags:                map[string]string{"Name": "tg1"},
+			Protocol:            elbv2types.ProtocolEnumTcp,
+			Port:                fi.PtrTo(int32(443)),
+			Interval:            fi.PtrTo(int32(10)),
+			HealthyThreshold:    fi.PtrTo(int32(2)),
+			UnhealthyThreshold:  fi.PtrTo(int32(2)),
+			HealthCheckProtocol: elbv2types.ProtocolEnumHttps,
+			HealthCheckPath:     s("/readyz"),
+			HealthCheckPort:     s(port),
+			HealthCheckTimeout:  fi.PtrTo(timeout),
+		}
+		return allTasks
+	}
+
+	for _, g := range []struct {
+		port    string
+		timeout int32
+	}{
+		{port: "traffic-port", timeout: 5},
+		{port: "8443", timeout: 10},
+	} {
+		allTasks := buildTasks(g.port, g.timeout)
+		runNLBTasks(t, cloud, allTasks)
+
+		response, err := c.DescribeTargetGroups(ctx, &elbv2.DescribeTargetGroupsInput{
+			TargetGroupArns: []string{fi.ValueOf(allTasks["tg1"].(*TargetGroup).ARN)},
+		})
+		if err != nil {
+			t.Fatalf("error describing target group: %v", err)
+		}
+		tg := response.TargetGroups[0]
+		if port := fi.ValueOf(tg.HealthCheckPort); port != g.port {
+			t.Errorf("expected health check port %q, got %q", g.port, port)
+		}
+		if timeout := fi.ValueOf(tg.HealthCheckTimeoutSeconds); timeout != g.timeout {
+			t.Errorf("expected health check timeout %d, got %d", g.timeout, timeout)
+		}
+
+		allTasks = buildTasks(g.port, g.timeout)
+		checkNLBNoChanges(t, ctx, cloud, allTasks)
+	}
+}
+
+func TestTargetGroupHTTPSHealthCheckOnTLS(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func() map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		// The traffic is passed through on 443, the health of the API is checked on its HTTPS port
+		allTasks["tg1"] = &TargetGroup{
+			Name:                s("tg1"),
+			Lifecycle:           fi.LifecycleSync,
+			VPC:                 nlb1.VPC,
+			Tags:                map[string]string{"Name": "tg1"},
+			Protocol:            elbv2types.ProtocolEnumTls,
+			Port:                fi.PtrTo(int32(443)),
+			Interval:            fi.PtrTo(int32(10)),
+			HealthyThreshold:    fi.PtrTo(int32(2)),
+			UnhealthyThreshold:  fi.PtrTo(int32(2)),
+			HealthCheckProtocol: elbv2types.ProtocolEnumHttps,
+			HealthCheckPort:     s("3990"),
+			HealthCheckPath:     s("/healthz"),
+		}
+		return allTasks
+	}
+
+	allTasks := buildTasks()
+	runNLBTasks(t, cloud, allTasks)
+
+	response, err := c.DescribeTargetGroups(ctx, &elbv2.DescribeTargetGroupsInput{
+		TargetGroupArns: []string{fi.ValueOf(allTasks["tg1"].(*TargetGroup).ARN)},
+	})
+	if err != nil {
+		t.Fatalf("error describing target group: %v", err)
+	}
+	tg := response.TargetGroups[0]
+	if tg.Protocol != elbv2types.ProtocolEnumTls || fi.ValueOf(tg.Port) != 443 {
+		t.Errorf("expected traffic on TLS port 443, got %s port %d", tg.Protocol, fi.ValueOf(tg.Port))
+	}
+	if tg.HealthCheckProtocol != elbv2types.ProtocolEnumHttps || fi.ValueOf(tg.HealthCheckPort) != "3990" || fi.ValueOf(tg.HealthCheckPath) != "/healthz" {
+		t.Errorf("expected HTTPS health checks of /healthz on port 3990, got %s port %q path %q", tg.HealthCheckProtocol, fi.ValueOf(tg.HealthCheckPort), fi.ValueOf(tg.HealthCheckPath))
+	}
+
+	checkNLBNoChanges(t, ctx, cloud, buildTasks())
+}
+
+func TestTargetGroupTCPHealthCheckOnHTTP(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	cloud.MockEC2 = &mockec2.MockEC2{}
+	c := &mockelbv2.MockELBV2{}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func() map[string]fi.CloudupTask {
+		vpc1 := &VPC{
+			Name:      s("vpc1"),
+			Lifecycle: fi.LifecycleSync,
+			CIDR:      s("172.21.0.0/16"),
+			Tags:      map[string]string{"Name": "vpc1"},
+		}
+		tg1 := &TargetGroup{
+			Name:                s("tg1"),
+			Lifecycle:           fi.LifecycleSync,
+			VPC:                 vpc1,
+			Tags:                map[string]string{"Name": "tg1"},
+			Protocol:            elbv2types.ProtocolEnumHttp,
+			Port:                fi.PtrTo(int32(80)),
+			Interval:            fi.PtrTo(int32(10)),
+			HealthyThreshold:    fi.PtrTo(int32(2)),
+			UnhealthyThreshold:  fi.PtrTo(int32(2)),
+			HealthCheckProtocol: elbv2types.ProtocolEnumTcp,
+		}
+		return map[string]fi.CloudupTask{
+			"vpc1": vpc1,
+			"tg1":  tg1,
+		}
+	}
+
+	{
+		allTasks := buildTasks()
+		tg1 := allTasks["tg1"].(*TargetGroup)
+
+		runNLBTasks(t, cloud, allTasks)
+
+		if fi.ValueOf(tg1.ARN) == "" {
+			t.Fatalf("ARN not set after create")
+		}
+		if len(c.TargetGroups) != 1 {
+			t.Fatalf("Expected exactly one TargetGroup; found %v", c.TargetGroups)
+		}
+
+		response, err := c.DescribeTargetGroups(ctx, &elbv2.DescribeTargetGroupsInput{})
+		if err != nil {
+			t.Fatalf("error describing target groups: %v", err)
+		}
+		actual := response.TargetGroups[0]
+		if actual.Protocol != elbv2types.ProtocolEnumHttp {
+			t.Errorf("unexpected protocol %q", actual.Protocol)
+		}
+		if actual.HealthCheckProtocol != elbv2types.ProtocolEnumTcp {
+			t.Errorf("unexpected health check protocol %q", actual.HealthCheckProtocol)
+		}
+		if actual.HealthCheckPath != nil || actual.Matcher != nil {
+			t.Errorf("unexpected path/matcher on TCP health check: %v %v", actual.HealthCheckPath, actual.Matcher)
+		}
+	}
+
+	{
+		allTasks := buildTasks()
+		checkNLBNoChanges(t, ctx, cloud, allTasks)
+	}
+}
+
+func TestTargetGroupMergedTags(t *testing.T) {
+	clusterTags := map[string]string{
+		"KubernetesCluster":                 "example.com",
+		"kubernetes.io/cluster/example.com": "owned",
+	}
+
+	tg := &TargetGroup{
+		Name: s("tg1"),
+		Tags: map[string]string{
+			"Name":                              "other",
+			"KubernetesCluster":                 "other.example.com",
+			"kubernetes.io/cluster/example.com": "shared",
+			awsup.KopsResourceRevisionTag:       "20240101",
+			"aws:cloudformation:stack-name":     "stack",
+			"team":                              "networking",
+		},
+	}
+
+	actual := tg.mergedTags(clusterTags)
+	expected := map[string]string{
+		"Name":                              "tg1",
+		"KubernetesCluster":                 "example.com",
+		"kubernetes.io/cluster/example.com": "owned",
+		"team":                              "networking",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected tags: expected %v, got %v", expected, actual)
+	}
+}
+
+func TestTargetGroupRemovedTags(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(tags map[string]string) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		allTasks["tg1"] = &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                nlb1.VPC,
+			Tags:               tags,
+			Protocol:           elbv2types.ProtocolEnumTcp,
+			Port:               fi.PtrTo(int32(443)),
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+		}
+		return allTasks
+	}
+
+	allTasks := buildTasks(map[string]string{"Name": "tg1", "team": "networking", "kops.k8s.io/instance-group": "nodes"})
+	runNLBTasks(t, cloud, allTasks)
+	arn := fi.ValueOf(allTasks["tg1"].(*TargetGroup).ARN)
+
+	// A tag added outside of kops, one managed by AWS, and the ownership tag of a renamed cluster
+	if _, err := c.AddTags(ctx, &elbv2.AddTagsInput{
+		ResourceArns: []string{arn},
+		Tags: []elbv2types.Tag{
+			{Key: s("cost-center"), Value: s("1234")},
+			{Key: s("aws:cloudformation:stack-name"), Value: s("stack")},
+			{Key: s("kubernetes.io/cluster/old.example.com"), Value: s("owned")},
+		},
+	}); err != nil {
+		t.Fatalf("error adding tags: %v", err)
+	}
+
+	runNLBTasks(t, cloud, buildTasks(map[string]string{"Name": "tg1", "team": "networking"}))
+
+	response, err := c.DescribeTags(ctx, &elbv2.DescribeTagsInput{ResourceArns: []string{arn}})
+	if err != nil {
+		t.Fatalf("error describing tags: %v", err)
+	}
+	actual := make(map[string]string)
+	for _, tag := range response.TagDescriptions[0].Tags {
+		actual[fi.ValueOf(tag.Key)] = fi.ValueOf(tag.Value)
+	}
+	for _, k := range []string{"kops.k8s.io/instance-group", "kubernetes.io/cluster/old.example.com"} {
+		if _, found := actual[k]; found {
+			t.Errorf("expected tag %q to be removed, got %v", k, actual)
+		}
+	}
+	for _, k := range []string{"Name", "team", "cost-center", "aws:cloudformation:stack-name"} {
+		if _, found := actual[k]; !found {
+			t.Errorf("expected tag %q to be kept, got %v", k, actual)
+		}
+	}
+
+	checkNLBNoChanges(t, ctx, cloud, buildTasks(map[string]string{"Name": "tg1", "team": "networking"}))
+}
+
+func TestTargetGroupCheckChangesGRPC(t *testing.T) {
+	grid := []struct {
+		name        string
+		targetGroup *TargetGroup
+		expectError bool
+	}{
+		{
+			name: "grpc health check with path and matcher",
+			targetGroup: &TargetGroup{
+				Name:               s("tg"),
+				Protocol:           elbv2types.ProtocolEnumHttps,
+				ProtocolVersion:    s("GRPC"),
+				HealthCheckPath:    s("/grpc.health.v1.Health/Check"),
+				HealthCheckMatcher: s("0-99"),
+			},
+		},
+		{
+			name: "grpc health check with list of codes",
+			targetGroup: &TargetGroup{
+				Name:               s("tg"),
+				Protocol:           elbv2types.ProtocolEnumHttp,
+				ProtocolVersion:    s("GRPC"),
+				HealthCheckMatcher: s("0,12"),
+			},
+		},
+		{
+			name: "grpc health check with http matcher",
+			targetGroup: &TargetGroup{
+				Name:               s("tg"),
+				Protocol:           elbv2types.ProtocolEnumHttps,
+				ProtocolVersion:    s("GRPC"),
+				HealthCheckMatcher: s("200-399"),
+			},
+			expectError: true,
+		},
+		{
+			name: "grpc health check with relative path",
+			targetGroup: &TargetGroup{
+				Name:            s("tg"),
+				Protocol:        elbv2types.ProtocolEnumHttps,
+				ProtocolVersion: s("GRPC"),
+				HealthCheckPath: s("grpc.health.v1.Health/Check"),
+			},
+			expectError: true,
+		},
+		{
+			name: "grpc with tcp health check",
+			targetGroup: &TargetGroup{
+				Name:                s("tg"),
+				Protocol:            elbv2types.ProtocolEnumHttps,
+				ProtocolVersion:     s("GRPC"),
+				HealthCheckProtocol: elbv2types.ProtocolEnumTcp,
+			},
+			expectError: true,
+		},
+		{
+			name: "grpc on tcp target group",
+			targetGroup: &TargetGroup{
+				Name:            s("tg"),
+				Protocol:        elbv2types.ProtocolEnumTcp,
+				ProtocolVersion: s("GRPC"),
+			},
+			expectError: true,
+		},
+		{
+			name: "unknown protocol version",
+			targetGroup: &TargetGroup{
+				Name:            s("tg"),
+				Protocol:        elbv2types.ProtocolEnumHttps,
+				ProtocolVersion: s("HTTP3"),
+			},
+			expectError: true,
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			err := (&TargetGroup{}).CheckChanges(nil, g.targetGroup, g.targetGroup)
+			if g.expectError && err == nil {
+				t.Errorf("expected error, got none")
+			}
+			if !g.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestTargetGroupGRPCHealthCheck(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	cloud.MockEC2 = &mockec2.MockEC2{}
+	c := &mockelbv2.MockELBV2{}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func() map[string]fi.CloudupTask {
+		vpc1 := &VPC{
+			Name:      s("vpc1"),
+			Lifecycle: fi.LifecycleSync,
+			CIDR:      s("172.21.0.0/16"),
+			Tags:      map[string]string{"Name": "vpc1"},
+		}
+		tg1 := &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                vpc1,
+			Tags:               map[string]string{"Name": "tg1"},
+			Protocol:           elbv2types.ProtocolEnumHttps,
+			ProtocolVersion:    s("GRPC"),
+			Port:               fi.PtrTo(int32(443)),
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+			HealthCheckPath:    s("/grpc.health.v1.Health/Check"),
+			HealthCheckMatcher: s("0"),
+		}
+		return map[string]fi.CloudupTask{
+			"vpc1": vpc1,
+			"tg1":  tg1,
+		}
+	}
+
+	{
+		allTasks := buildTasks()
+		runNLBTasks(t, cloud, allTasks)
+
+		response, err := c.DescribeTargetGroups(ctx, &elbv2.DescribeTargetGroupsInput{})
+		if err != nil {
+			t.Fatalf("error describing target groups: %v", err)
+		}
+		actual := response.TargetGroups[0]
+		if fi.ValueOf(actual.ProtocolVersion) != "GRPC" {
+			t.Errorf("unexpected protocol version %q", fi.ValueOf(actual.ProtocolVersion))
+		}
+		if actual.Matcher == nil || fi.ValueOf(actual.Matcher.GrpcCode) != "0" || actual.Matcher.HttpCode != nil {
+			t.Errorf("unexpected matcher %+v", actual.Matcher)
+		}
+		if fi.ValueOf(actual.HealthCheckPath) != "/grpc.health.v1.Health/Check" {
+			t.Errorf("unexpected health check path %q", fi.ValueOf(actual.HealthCheckPath))
+		}
+	}
+
+	{
+		allTasks := buildTasks()
+		checkNLBNoChanges(t, ctx, cloud, allTasks)
+	}
+}
+
+func TestTargetGroupGRPCTerraform(t *testing.T) {
+	cases := []*renderTest{
+		{
+			Resource: &TargetGroup{
+				Name:               s("tg1"),
+				VPC:                &VPC{Name: s("vpc1"), ID: s("vpc-1234")},
+				Tags:               map[string]string{"Name": "tg1"},
+				Protocol:           elbv2types.ProtocolEnumHttps,
+				ProtocolVersion:    s("GRPC"),
+				Port:               fi.PtrTo(int32(443)),
+				Interval:           fi.PtrTo(int32(10)),
+				HealthyThreshold:   fi.PtrTo(int32(2)),
+				UnhealthyThreshold: fi.PtrTo(int32(2)),
+				HealthCheckPath:    s("/grpc.health.v1.Health/Check"),
+				HealthCheckMatcher: s("0-99"),
+			},
+			Expected: `provider "aws" {
+  region = "eu-west-2"
+}
+
+resource "aws_lb_target_group" "tg1" {
+  connection_termination = ""
+  deregistration_delay   = ""
+  health_check {
+    healthy_threshold   = 2
+    interval            = 10
+    matcher             = "0-99"
+    path                = "/grpc.health.v1.Health/Check"
+    protocol            = "HTTPS"
+    unhealthy_threshold = 2
+  }
+  name             = "tg1"
+  port             = 443
+  protocol         = "HTTPS"
+  protocol_version = "GRPC"
+  tags = {
+    "Name" = "tg1"
+  }
+  vpc_id = aws_vpc.vpc1.id
+}
+
+terraform {
+  required_version = ">= 0.15.0"
+  required_providers {
+    aws = {
+      "source"  = "hashicorp/aws"
+      "version" = ">= 5.0.0"
+    }
+  }
+}
+`,
+		},
+	}
+
+	doRenderTests(t, "RenderTerraform", cases)
+}
+
+func TestTargetGroupExportTerraform(t *testing.T) {
+	cases := []*renderTest{
+		{
+			Resource: &TargetGroup{
+				Name:               s("tg1"),
+				VPC:                &VPC{Name: s("vpc1"), ID: s("vpc-1234")},
+				Tags:               map[string]string{"Name": "tg1"},
+				Protocol:           elbv2types.ProtocolEnumTcp,
+				Port:               fi.PtrTo(int32(443)),
+				Interval:           fi.PtrTo(int32(10)),
+				HealthyThreshold:   fi.PtrTo(int32(2)),
+				UnhealthyThreshold: fi.PtrTo(int32(2)),
+				ExportWithID:       s("api_tcp"),
+			},
+			Expected: `locals {
+  api_tcp_target_group_arn = aws_lb_target_group.tg1.arn
+}
+
+output "api_tcp_target_group_arn" {
+  value = aws_lb_target_group.tg1.arn
+}
+
+provider "aws" {
+  region = "eu-west-2"
+}
+
+resource "aws_lb_target_group" "tg1" {
+  connection_termination = ""
+  deregistration_delay   = ""
+  health_check {
+    healthy_threshold   = 2
+    interval            = 10
+    protocol            = "TCP"
+    unhealthy_threshold = 2
+  }
+  name     = "tg1"
+  port     = 443
+  protocol = "TCP"
+  tags = {
+    "Name" = "tg1"
+  }
+  vpc_id = aws_vpc.vpc1.id
+}
+
+terraform {
+  required_version = ">= 0.15.0"
+  required_providers {
+    aws = {
+      "source"  = "hashicorp/aws"
+      "version" = ">= 5.0.0"
+    }
+  }
+}
+`,
+		},
+	}
+
+	doRenderTests(t, "RenderTerraform", cases)
+}
+
+func TestTargetGroupRetriedCreateAdoptsExisting(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	c := &mockelbv2.MockELBV2{}
+	cloud.MockELBV2 = c
+
+	// A previous attempt created the target group, but failed before the task completed
+	created, err := c.CreateTargetGroup(ctx, &elbv2.CreateTargetGroupInput{
+		Name:     s("tg1"),
+		Port:     fi.PtrTo(int32(443)),
+		Protocol: elbv2types.ProtocolEnumTcp,
+		VpcId:    s("vpc-1234"),
+		Tags:     awsup.ELBv2Tags(map[string]string{"Name": "tg1"}),
+	})
+	if err != nil {
+		t.Fatalf("error creating target group: %v", err)
+	}
+	existingARN := fi.ValueOf(created.TargetGroups[0].TargetGroupArn)
+
+	e := &TargetGroup{
+		Name:               s("tg1"),
+		Lifecycle:          fi.LifecycleSync,
+		VPC:                &VPC{ID: s("vpc-1234")},
+		Tags:               map[string]string{"Name": "tg1"},
+		Protocol:           elbv2types.ProtocolEnumTcp,
+		Port:               fi.PtrTo(int32(443)),
+		Interval:           fi.PtrTo(int32(10)),
+		HealthyThreshold:   fi.PtrTo(int32(2)),
+		UnhealthyThreshold: fi.PtrTo(int32(2)),
+	}
+	if err := e.RenderAWS(&awsup.AWSAPITarget{Cloud: cloud}, nil, e, e); err != nil {
+		t.Fatalf("unexpected error rendering target group: %v", err)
+	}
+
+	if len(c.TargetGroups) != 1 {
+		t.Fatalf("expected the existing target group to be adopted, found %d target groups", len(c.TargetGroups))
+	}
+	if fi.ValueOf(e.ARN) != existingARN {
+		t.Errorf("expected target group ARN %q, got %q", existingARN, fi.ValueOf(e.ARN))
+	}
+
+	// A target group with a different name is still created
+	e2 := &TargetGroup{
+		Name:     s("tg2"),
+		VPC:      &VPC{ID: s("vpc-1234")},
+		Tags:     map[string]string{"Name": "tg2"},
+		Protocol: elbv2types.ProtocolEnumTcp,
+		Port:     fi.PtrTo(int32(443)),
+	}
+	if err := e2.RenderAWS(&awsup.AWSAPITarget{Cloud: cloud}, nil, e2, e2); err != nil {
+		t.Fatalf("unexpected error rendering target group: %v", err)
+	}
+	if len(c.TargetGroups) != 2 {
+		t.Errorf("expected a new target group to be created, found %d target groups", len(c.TargetGroups))
+	}
+}
+
+func TestTargetGroupNormalizeHealthCheckMatcher(t *testing.T) {
+	grid := []struct {
+		name        string
+		targetGroup *TargetGroup
+		expected    *string
+	}{
+		{
+			name: "grpc",
+			targetGroup: &TargetGroup{
+				Protocol:        elbv2types.ProtocolEnumHttps,
+				ProtocolVersion: s("GRPC"),
+			},
+			expected: s("0"),
+		},
+		{
+			name: "http",
+			targetGroup: &TargetGroup{
+				Protocol:            elbv2types.ProtocolEnumTcp,
+				HealthCheckProtocol: elbv2types.ProtocolEnumHttp,
+			},
+			expected: s("200"),
+		},
+		{
+			name: "https with protocol version",
+			targetGroup: &TargetGroup{
+				Protocol:        elbv2types.ProtocolEnumHttps,
+				ProtocolVersion: s("HTTP2"),
+			},
+			expected: s("200"),
+		},
+		{
+			name: "explicit matcher",
+			targetGroup: &TargetGroup{
+				Protocol:           elbv2types.ProtocolEnumHttps,
+				ProtocolVersion:    s("GRPC"),
+				HealthCheckMatcher: s("0-99"),
+			},
+			expected: s("0-99"),
+		},
+		{
+			name: "tcp",
+			targetGroup: &TargetGroup{
+				Protocol: elbv2types.ProtocolEnumTcp,
+			},
+		},
+		{
+			name: "shared",
+			targetGroup: &TargetGroup{
+				Protocol: elbv2types.ProtocolEnumHttp,
+				Shared:   fi.PtrTo(true),
+			},
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			if err := g.targetGroup.Normalize(nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(g.targetGroup.HealthCheckMatcher, g.expected) {
+				t.Errorf("expected matcher %v, got %v", fi.ValueOf(g.expected), fi.ValueOf(g.targetGroup.HealthCheckMatcher))
+			}
+		})
+	}
+}
+
+func TestTargetGroupNormalizeDefaultTargetType(t *testing.T) {
+	ctx := context.TODO()
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "a")
+
+	grid := []struct {
+		name          string
+		clusterTarget *string
+		targetGroup   *TargetGroup
+		expected      elbv2types.TargetTypeEnum
+	}{
+		{
+			name:        "no default",
+			targetGroup: &TargetGroup{},
+		},
+		{
+			name:          "inherits default",
+			clusterTarget: s("ip"),
+			targetGroup:   &TargetGroup{},
+			expected:      elbv2types.TargetTypeEnumIp,
+		},
+		{
+			name:          "explicit overrides default",
+			clusterTarget: s("ip"),
+			targetGroup:   &TargetGroup{TargetType: elbv2types.TargetTypeEnumInstance},
+			expected:      elbv2types.TargetTypeEnumInstance,
+		},
+		{
+			name:          "shared ignores default",
+			clusterTarget: s("ip"),
+			targetGroup:   &TargetGroup{Shared: fi.PtrTo(true)},
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			cluster := &kops.Cluster{}
+			cluster.Spec.CloudProvider.AWS = &kops.AWSSpec{LoadBalancerTargetType: g.clusterTarget}
+			c, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, &awsup.AWSAPITarget{Cloud: cloud}, cluster, cloud, nil, nil, nil, nil)
+			if err != nil {
+				t.Fatalf("error building context: %v", err)
+			}
+			if err := g.targetGroup.Normalize(c); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if g.targetGroup.TargetType != g.expected {
+				t.Errorf("expected target type %q, got %q", g.expected, g.targetGroup.TargetType)
+			}
+		})
+	}
+}
+
+func TestTargetGroupVPCChangeRequiresRecreate(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	c := &mockelbv2.MockELBV2{}
+	cloud.MockELBV2 = c
+
+	// The target group was created in the VPC the cluster used before a VPC migration
+	created, err := c.CreateTargetGroup(ctx, &elbv2.CreateTargetGroupInput{
+		Name:     s("tg1"),
+		Port:     fi.PtrTo(int32(443)),
+		Protocol: elbv2types.ProtocolEnumTcp,
+		VpcId:    s("vpc-old"),
+		Tags:     awsup.ELBv2Tags(map[string]string{"Name": "tg1"}),
+	})
+	if err != nil {
+		t.Fatalf("error creating target group: %v", err)
+	}
+	oldARN := fi.ValueOf(created.TargetGroups[0].TargetGroupArn)
+
+	e := &TargetGroup{
+		Name:      s("tg1"),
+		Lifecycle: fi.LifecycleSync,
+		VPC:       &VPC{ID: s("vpc-new")},
+		Tags:      map[string]string{"Name": "tg1"},
+		Protocol:  elbv2types.ProtocolEnumTcp,
+		Port:      fi.PtrTo(int32(443)),
+	}
+
+	context, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, &awsup.AWSAPITarget{Cloud: cloud}, &kops.Cluster{}, cloud, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+	a, err := e.Find(context)
+	if err != nil {
+		t.Fatalf("unexpected error finding target group: %v", err)
+	}
+	if a == nil {
+		t.Fatalf("expected to find the target group in the old VPC")
+	}
+	changes := &TargetGroup{}
+	fi.BuildChanges(a, e, changes)
+	if !targetGroupRequiresRecreate(a, changes) {
+		t.Fatalf("expected a VPC change to require recreating the target group")
+	}
+	if err := e.CheckChanges(a, e, changes); err != nil {
+		t.Fatalf("unexpected error checking changes: %v", err)
+	}
+	if len(e.deletions) != 1 {
+		t.Fatalf("expected the target group in the old VPC to be deleted, found %d deletions", len(e.deletions))
+	}
+
+	if err := e.RenderAWS(&awsup.AWSAPITarget{Cloud: cloud}, a, e, changes); err != nil {
+		t.Fatalf("unexpected error rendering target group: %v", err)
+	}
+	if fi.ValueOf(e.ARN) == oldARN {
+		t.Fatalf("expected a new target group to be created")
+	}
+	described, err := c.DescribeTargetGroups(ctx, &elbv2.DescribeTargetGroupsInput{TargetGroupArns: []string{fi.ValueOf(e.ARN)}})
+	if err != nil {
+		t.Fatalf("error describing target group: %v", err)
+	}
+	tg := described.TargetGroups[0]
+	if vpcID := fi.ValueOf(tg.VpcId); vpcID != "vpc-new" {
+		t.Errorf("expected the new target group in VPC %q, got %q", "vpc-new", vpcID)
+	}
+	if name := fi.ValueOf(tg.TargetGroupName); name == "tg1" {
+		t.Errorf("expected the new target group to have a name distinct from the old one")
+	}
+}
+
+func TestTargetGroupNameAndNameTag(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	c := &mockelbv2.MockELBV2{}
+	cloud.MockELBV2 = c
+
+	// The target group of the cluster dev.example.com has an AWS name equal to the Name tag of
+	// the target group of the cluster dev.example.org
+	_, err := c.CreateTargetGroup(ctx, &elbv2.CreateTargetGroupInput{
+		Name:     s("tcp-dev-example"),
+		Port:     fi.PtrTo(int32(443)),
+		Protocol: elbv2types.ProtocolEnumTcp,
+		VpcId:    s("vpc-1"),
+		Tags:     awsup.ELBv2Tags(map[string]string{"Name": "tcp-dev-example-com"}),
+	})
+	if err != nil {
+		t.Fatalf("error creating target group: %v", err)
+	}
+
+	context, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, &awsup.AWSAPITarget{Cloud: cloud}, &kops.Cluster{}, cloud, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+
+	other := &TargetGroup{
+		Name:      s("tcp-dev-example"),
+		Lifecycle: fi.LifecycleSync,
+		VPC:       &VPC{ID: s("vpc-1")},
+		Protocol:  elbv2types.ProtocolEnumTcp,
+		Port:      fi.PtrTo(int32(443)),
+	}
+	if a, err := other.Find(context); err != nil {
+		t.Fatalf("unexpected error finding target group: %v", err)
+	} else if a != nil {
+		t.Fatalf("expected not to find the target group of another cluster, found %q", fi.ValueOf(a.ARN))
+	}
+
+	e := &TargetGroup{
+		Name:            s("tcp-dev-example-com"),
+		TargetGroupName: s("tcp-dev-example"),
+		Lifecycle:       fi.LifecycleSync,
+		VPC:             &VPC{ID: s("vpc-1")},
+		Protocol:        elbv2types.ProtocolEnumTcp,
+		Port:            fi.PtrTo(int32(443)),
+	}
+	a, err := e.Find(context)
+	if err != nil {
+		t.Fatalf("unexpected error finding target group: %v", err)
+	}
+	if a == nil {
+		t.Fatalf("expected to find the target group by its Name tag")
+	}
+	changes := &TargetGroup{}
+	fi.BuildChanges(a, e, changes)
+	if changes.Name != nil || changes.TargetGroupName != nil {
+		t.Errorf("unexpected changes to the names of the target group: %+v", changes)
+	}
+
+	// The other cluster creates a target group of its own, with its own AWS name
+	other.TargetGroupName = s("tcp-dev-example-org")
+	if err := other.RenderAWS(&awsup.AWSAPITarget{Cloud: cloud}, nil, other, other); err != nil {
+		t.Fatalf("unexpected error rendering target group: %v", err)
+	}
+	described, err := c.DescribeTargetGroups(ctx, &elbv2.DescribeTargetGroupsInput{TargetGroupArns: []string{fi.ValueOf(other.ARN)}})
+	if err != nil {
+		t.Fatalf("error describing target group: %v", err)
+	}
+	if name := fi.ValueOf(described.TargetGroups[0].TargetGroupName); name != "tcp-dev-example-org" {
+		t.Errorf("expected the target group to be named %q, got %q", "tcp-dev-example-org", name)
+	}
+	if found, err := e.Find(context); err != nil {
+		t.Fatalf("unexpected error finding target group: %v", err)
+	} else if fi.ValueOf(found.ARN) != fi.ValueOf(a.ARN) {
+		t.Errorf("expected to find target group %q, found %q", fi.ValueOf(a.ARN), fi.ValueOf(found.ARN))
+	}
+}
+
+func TestTargetGroupCheckChangesTargetGroupName(t *testing.T) {
+	grid := []struct {
+		name            string
+		targetGroupName *string
+		expectedError   string
+	}{
+		{name: "tcp-api-example-com"},
+		{name: "tcp-api-example-com", targetGroupName: s("tcp-api")},
+		{name: "tcp-api-a-very-long-cluster-name-example-com", targetGroupName: s("tcp-api-a-very-long-cluster-name")},
+		{name: "tcp-api-a-very-long-cluster-name-example-com", expectedError: "must be at most 32 characters"},
+		{name: "tcp-api", targetGroupName: s("tcp-api-a-very-long-cluster-name-example-com"), expectedError: "must be at most 32 characters"},
+		{name: "tcp-api", targetGroupName: s("tcp.api"), expectedError: "must only contain alphanumeric characters and hyphens"},
+		{name: "tcp-api", targetGroupName: s("-tcp-api"), expectedError: "must not begin or end with a hyphen"},
+	}
+	for _, g := range grid {
+		tg := &TargetGroup{
+			Name:            s(g.name),
+			TargetGroupName: g.targetGroupName,
+			Protocol:        elbv2types.ProtocolEnumTcp,
+			Port:            fi.PtrTo(int32(443)),
+		}
+		err := tg.CheckChanges(nil, tg, tg)
+		if g.expectedError == "" {
+			if err != nil {
+				t.Errorf("%s/%s: unexpected error: %v", g.name, fi.ValueOf(g.targetGroupName), err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), g.expectedError) {
+			t.Errorf("%s/%s: expected error containing %q, got %v", g.name, fi.ValueOf(g.targetGroupName), g.expectedError, err)
+		}
+	}
+}
+
+func TestTargetGroupAttributesDiff(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &countingELBV2{MockELBV2: &mockelbv2.MockELBV2{EC2: ec2Client}}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(deregistrationDelay string) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		allTasks["tg1"] = &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                nlb1.VPC,
+			Tags:               map[string]string{"Name": "tg1"},
+			Protocol:           elbv2types.ProtocolEnumTcp,
+			Port:               fi.PtrTo(int32(443)),
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+			Attributes: map[string]string{
+				TargetGroupAttributeDeregistrationDelayConnectionTerminationEnabled: "true",
+				TargetGroupAttributeDeregistrationDelayTimeoutSeconds:               deregistrationDelay,
+			},
+		}
+		return allTasks
+	}
+
+	var targetGroupArn string
+	{
+		allTasks := buildTasks("300")
+		runNLBTasks(t, cloud, allTasks)
+		targetGroupArn = fi.ValueOf(allTasks["tg1"].(*TargetGroup).ARN)
+	}
+
+	{
+		c.modifyTargetGroupAttributesRequests = nil
+
+		allTasks := buildTasks("30")
+		runNLBTasks(t, cloud, allTasks)
+
+		if len(c.modifyTargetGroupAttributesRequests) != 1 {
+			t.Fatalf("expected a single ModifyTargetGroupAttributes call, got %d", len(c.modifyTargetGroupAttributesRequests))
+		}
+		expected := []elbv2types.TargetGroupAttribute{
+			{Key: s(TargetGroupAttributeDeregistrationDelayTimeoutSeconds), Value: s("30")},
+		}
+		if actual := c.modifyTargetGroupAttributesRequests[0].Attributes; !reflect.DeepEqual(actual, expected) {
+			t.Errorf("expected only the changed attribute to be sent, got %v", actual)
+		}
+
+		attributes, err := c.DescribeTargetGroupAttributes(ctx, &elbv2.DescribeTargetGroupAttributesInput{TargetGroupArn: &targetGroupArn})
+		if err != nil {
+			t.Fatalf("error describing target group attributes: %v", err)
+		}
+		for _, attribute := range attributes.Attributes {
+			if fi.ValueOf(attribute.Key) == TargetGroupAttributeDeregistrationDelayConnectionTerminationEnabled && fi.ValueOf(attribute.Value) != "true" {
+				t.Errorf("expected unchanged attribute %q to be kept, got %q", fi.ValueOf(attribute.Key), fi.ValueOf(attribute.Value))
+			}
+		}
+	}
+
+	{
+		allTasks := buildTasks("30")
+		checkNLBNoChanges(t, ctx, cloud, allTasks)
+	}
+}
+
+func TestTargetGroupProxyProtocolV2(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(proxyProtocolV2 *bool) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		allTasks["tg1"] = &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                nlb1.VPC,
+			Tags:               map[string]string{"Name": "tg1"},
+			Protocol:           elbv2types.ProtocolEnumTcp,
+			Port:               fi.PtrTo(int32(443)),
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+			ProxyProtocolV2:    proxyProtocolV2,
+		}
+		return allTasks
+	}
+
+	proxyProtocolV2Attribute := func(t *testing.T, targetGroupArn *string) string {
+		attributes, err := c.DescribeTargetGroupAttributes(ctx, &elbv2.DescribeTargetGroupAttributesInput{TargetGroupArn: targetGroupArn})
+		if err != nil {
+			t.Fatalf("error describing target group attributes: %v", err)
+		}
+		for _, attribute := range attributes.Attributes {
+			if fi.ValueOf(attribute.Key) == TargetGroupAttributeProxyProtocolV2Enabled {
+				return fi.ValueOf(attribute.Value)
+			}
+		}
+		return ""
+	}
+
+	for _, enabled := range []bool{true, false} {
+		allTasks := buildTasks(fi.PtrTo(enabled))
+		runNLBTasks(t, cloud, allTasks)
+
+		expected := strconv.FormatBool(enabled)
+		if actual := proxyProtocolV2Attribute(t, allTasks["tg1"].(*TargetGroup).ARN); actual != expected {
+			t.Errorf("expected %s to be %q, got %q", TargetGroupAttributeProxyProtocolV2Enabled, expected, actual)
+		}
+
+		allTasks = buildTasks(fi.PtrTo(enabled))
+		checkNLBNoChanges(t, ctx, cloud, allTasks)
+	}
+
+	{
+		// Leaving the field unset doesn't reset the attribute
+		allTasks := buildTasks(nil)
+		checkNLBNoChanges(t, ctx, cloud, allTasks)
+	}
+}
+
+func TestTargetGroupProxyProtocolV2Terraform(t *testing.T) {
+	cases := []*renderTest{
+		{
+			Resource: &TargetGroup{
+				Name:               s("tg1"),
+				VPC:                &VPC{Name: s("vpc1"), ID: s("vpc-1234")},
+				Tags:               map[string]string{"Name": "tg1"},
+				Protocol:           elbv2types.ProtocolEnumTcp,
+				Port:               fi.PtrTo(int32(443)),
+				Interval:           fi.PtrTo(int32(10)),
+				HealthyThreshold:   fi.PtrTo(int32(2)),
+				UnhealthyThreshold: fi.PtrTo(int32(2)),
+				ProxyProtocolV2:    fi.PtrTo(true),
+			},
+			Expected: `provider "aws" {
+  region = "eu-west-2"
+}
+
+resource "aws_lb_target_group" "tg1" {
+  connection_termination = ""
+  deregistration_delay   = ""
+  health_check {
+    healthy_threshold   = 2
+    interval            = 10
+    protocol            = "TCP"
+    unhealthy_threshold = 2
+  }
+  name              = "tg1"
+  port              = 443
+  protocol          = "TCP"
+  proxy_protocol_v2 = true
+  tags = {
+    "Name" = "tg1"
+  }
+  vpc_id = aws_vpc.vpc1.id
+}
+
+terraform {
+  required_version = ">= 0.15.0"
+  required_providers {
+    aws = {
+      "source"  = "hashicorp/aws"
+      "version" = ">= 5.0.0"
+    }
+  }
+}
+`,
+		},
+	}
+
+	doRenderTests(t, "RenderTerraform", cases)
+}
+
+func TestTargetGroupCrossZoneLoadBalancing(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &countingELBV2{MockELBV2: &mockelbv2.MockELBV2{EC2: ec2Client}}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(crossZone string) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		allTasks["tg1"] = &TargetGroup{
+			Name:                   s("tg1"),
+			Lifecycle:              fi.LifecycleSync,
+			VPC:                    nlb1.VPC,
+			Tags:                   map[string]string{"Name": "tg1"},
+			Protocol:               elbv2types.ProtocolEnumTcp,
+			Port:                   fi.PtrTo(int32(443)),
+			Interval:               fi.PtrTo(int32(10)),
+			HealthyThreshold:       fi.PtrTo(int32(2)),
+			UnhealthyThreshold:     fi.PtrTo(int32(2)),
+			CrossZoneLoadBalancing: s(crossZone),
+		}
+		return allTasks
+	}
+
+	{
+		allTasks := buildTasks(TargetGroupCrossZoneUseLoadBalancerConfiguration)
+		runNLBTasks(t, cloud, allTasks)
+	}
+
+	{
+		c.modifyTargetGroupAttributesRequests = nil
+
+		allTasks := buildTasks("false")
+		runNLBTasks(t, cloud, allTasks)
+
+		if len(c.modifyTargetGroupAttributesRequests) != 1 {
+			t.Fatalf("expected a single ModifyTargetGroupAttributes call, got %d", len(c.modifyTargetGroupAttributesRequests))
+		}
+		attributes := c.modifyTargetGroupAttributesRequests[0].Attributes
+		if len(attributes) != 1 || fi.ValueOf(attributes[0].Key) != TargetGroupAttributeLoadBalancingCrossZoneEnabled || fi.ValueOf(attributes[0].Value) != "false" {
+			t.Errorf("expected only %s=false to be modified, got %+v", TargetGroupAttributeLoadBalancingCrossZoneEnabled, attributes)
+		}
+	}
+
+	{
+		allTasks := buildTasks("false")
+		checkNLBNoChanges(t, ctx, cloud, allTasks)
+	}
+}
+
+func TestTargetGroupCheckChangesCrossZoneLoadBalancing(t *testing.T) {
+	for _, crossZone := range []string{"true", "false", TargetGroupCrossZoneUseLoadBalancerConfiguration, "maybe"} {
+		tg := &TargetGroup{
+			Name:                   s("tg1"),
+			Protocol:               elbv2types.ProtocolEnumTcp,
+			Port:                   fi.PtrTo(int32(443)),
+			Interval:               fi.PtrTo(int32(10)),
+			HealthyThreshold:       fi.PtrTo(int32(2)),
+			UnhealthyThreshold:     fi.PtrTo(int32(2)),
+			CrossZoneLoadBalancing: s(crossZone),
+		}
+		err := (&TargetGroup{}).CheckChanges(nil, tg, tg)
+		if expectError := crossZone == "maybe"; expectError != (err != nil) {
+			t.Errorf("cross-zone load balancing %q: unexpected error %v", crossZone, err)
+		}
+	}
+}
+
+func TestTargetGroupCrossZoneLoadBalancingTerraform(t *testing.T) {
+	cases := []*renderTest{
+		{
+			Resource: &TargetGroup{
+				Name:                   s("tg1"),
+				VPC:                    &VPC{Name: s("vpc1"), ID: s("vpc-1234")},
+				Tags:                   map[string]string{"Name": "tg1"},
+				Protocol:               elbv2types.ProtocolEnumTcp,
+				Port:                   fi.PtrTo(int32(443)),
+				Interval:               fi.PtrTo(int32(10)),
+				HealthyThreshold:       fi.PtrTo(int32(2)),
+				UnhealthyThreshold:     fi.PtrTo(int32(2)),
+				CrossZoneLoadBalancing: s("false"),
+			},
+			Expected: `provider "aws" {
+  region = "eu-west-2"
+}
+
+resource "aws_lb_target_group" "tg1" {
+  connection_termination = ""
+  deregistration_delay   = ""
+  health_check {
+    healthy_threshold   = 2
+    interval            = 10
+    protocol            = "TCP"
+    unhealthy_threshold = 2
+  }
+  load_balancing_cross_zone_enabled = "false"
+  name                              = "tg1"
+  port                              = 443
+  protocol                          = "TCP"
+  tags = {
+    "Name" = "tg1"
+  }
+  vpc_id = aws_vpc.vpc1.id
+}
+
+terraform {
+  required_version = ">= 0.15.0"
+  required_providers {
+    aws = {
+      "source"  = "hashicorp/aws"
+      "version" = ">= 5.0.0"
+    }
+  }
+}
+`,
+		},
+	}
+
+	doRenderTests(t, "RenderTerraform", cases)
+}
+
+func TestTargetGroupDeregistration(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(connectionTermination bool, deregistrationDelaySeconds int) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		allTasks["tg1"] = &TargetGroup{
+			Name:                       s("tg1"),
+			Lifecycle:                  fi.LifecycleSync,
+			VPC:                        nlb1.VPC,
+			Tags:                       map[string]string{"Name": "tg1"},
+			Protocol:                   elbv2types.ProtocolEnumUdp,
+			Port:                       fi.PtrTo(int32(53)),
+			Interval:                   fi.PtrTo(int32(10)),
+			HealthyThreshold:           fi.PtrTo(int32(2)),
+			UnhealthyThreshold:         fi.PtrTo(int32(2)),
+			HealthCheckProtocol:        elbv2types.ProtocolEnumTcp,
+			ConnectionTermination:      fi.PtrTo(connectionTermination),
+			DeregistrationDelaySeconds: fi.PtrTo(deregistrationDelaySeconds),
+		}
+		return allTasks
+	}
+
+	targetGroupAttributes := func(t *testing.T, targetGroupArn *string) map[string]string {
+		response, err := c.DescribeTargetGroupAttributes(ctx, &elbv2.DescribeTargetGroupAttributesInput{TargetGroupArn: targetGroupArn})
+		if err != nil {
+			t.Fatalf("error describing target group attributes: %v", err)
+		}
+		attributes := make(map[string]string)
+		for _, attribute := range response.Attributes {
+			attributes[fi.ValueOf(attribute.Key)] = fi.ValueOf(attribute.Value)
+		}
+		return attributes
+	}
+
+	grid := []struct {
+		connectionTermination      bool
+		deregistrationDelaySeconds int
+	}{
+		{connectionTermination: true, deregistrationDelaySeconds: 0},
+		{connectionTermination: false, deregistrationDelaySeconds: 300},
+	}
+	for _, g := range grid {
+		allTasks := buildTasks(g.connectionTermination, g.deregistrationDelaySeconds)
+		runNLBTasks(t, cloud, allTasks)
+
+		attributes := targetGroupAttributes(t, allTasks["tg1"].(*TargetGroup).ARN)
+		if actual, expected := attributes[TargetGroupAttributeDeregistrationDelayConnectionTerminationEnabled], strconv.FormatBool(g.connectionTermination); actual != expected {
+			t.Errorf("expected %s to be %q, got %q", TargetGroupAttributeDeregistrationDelayConnectionTerminationEnabled, expected, actual)
+		}
+		if actual, expected := attributes[TargetGroupAttributeDeregistrationDelayTimeoutSeconds], strconv.Itoa(g.deregistrationDelaySeconds); actual != expected {
+			t.Errorf("expected %s to be %q, got %q", TargetGroupAttributeDeregistrationDelayTimeoutSeconds, expected, actual)
+		}
+
+		allTasks = buildTasks(g.connectionTermination, g.deregistrationDelaySeconds)
+		checkNLBNoChanges(t, ctx, cloud, allTasks)
+	}
+}
+
+func TestTargetGroupDeregistrationDelayInPlace(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &countingELBV2{MockELBV2: &mockelbv2.MockELBV2{EC2: ec2Client}}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(deregistrationDelaySeconds int) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		allTasks["tg1"] = &TargetGroup{
+			Name:                       s("tg1"),
+			Lifecycle:                  fi.LifecycleSync,
+			VPC:                        nlb1.VPC,
+			Tags:                       map[string]string{"Name": "tg1"},
+			Protocol:                   elbv2types.ProtocolEnumTcp,
+			Port:                       fi.PtrTo(int32(443)),
+			Interval:                   fi.PtrTo(int32(10)),
+			HealthyThreshold:           fi.PtrTo(int32(2)),
+			UnhealthyThreshold:         fi.PtrTo(int32(2)),
+			DeregistrationDelaySeconds: fi.PtrTo(deregistrationDelaySeconds),
+		}
+		return allTasks
+	}
+
+	allTasks := buildTasks(300)
+	runNLBTasks(t, cloud, allTasks)
+	targetGroupArn := fi.ValueOf(allTasks["tg1"].(*TargetGroup).ARN)
+
+	c.createTargetGroupCalls = 0
+	c.deleteTargetGroupCalls = 0
+	c.modifyTargetGroupAttributesRequests = nil
+
+	allTasks = buildTasks(30)
+	runNLBTasks(t, cloud, allTasks)
+
+	if c.createTargetGroupCalls != 0 || c.deleteTargetGroupCalls != 0 {
+		t.Errorf("expected the target group to be modified in place, got %d creates and %d deletes", c.createTargetGroupCalls, c.deleteTargetGroupCalls)
+	}
+	if arn := fi.ValueOf(allTasks["tg1"].(*TargetGroup).ARN); arn != targetGroupArn {
+		t.Errorf("expected target group %q to be kept, got %q", targetGroupArn, arn)
+	}
+	if len(c.modifyTargetGroupAttributesRequests) != 1 {
+		t.Fatalf("expected a single ModifyTargetGroupAttributes call, got %d", len(c.modifyTargetGroupAttributesRequests))
+	}
+	expected := []elbv2types.TargetGroupAttribute{
+		{Key: s(TargetGroupAttributeDeregistrationDelayTimeoutSeconds), Value: s("30")},
+	}
+	if actual := c.modifyTargetGroupAttributesRequests[0].Attributes; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected only the deregistration delay to be sent, got %v", actual)
+	}
+
+	checkNLBNoChanges(t, ctx, cloud, buildTasks(30))
+}
+
+func TestTargetGroupAdoptARN(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &countingELBV2{MockELBV2: &mockelbv2.MockELBV2{EC2: ec2Client}}
+	cloud.MockELBV2 = c
+
+	allTasks := buildNLBTasks()
+	runNLBTasks(t, cloud, allTasks)
+	vpcID := allTasks["vpc1"].(*VPC).ID
+
+	// The target group was built by hand, with other health check settings and without the Name tag
+	created, err := c.CreateTargetGroup(ctx, &elbv2.CreateTargetGroupInput{
+		Name:                       s("handmade"),
+		Port:                       fi.PtrTo(int32(443)),
+		Protocol:                   elbv2types.ProtocolEnumTcp,
+		VpcId:                      vpcID,
+		HealthCheckIntervalSeconds: fi.PtrTo(int32(30)),
+		HealthyThresholdCount:      fi.PtrTo(int32(3)),
+		UnhealthyThresholdCount:    fi.PtrTo(int32(3)),
+	})
+	if err != nil {
+		t.Fatalf("error creating target group: %v", err)
+	}
+	adoptARN := fi.ValueOf(created.TargetGroups[0].TargetGroupArn)
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(adoptARN string) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		allTasks["tg1"] = &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                nlb1.VPC,
+			Tags:               map[string]string{"Name": "tg1"},
+			Protocol:           elbv2types.ProtocolEnumTcp,
+			Port:               fi.PtrTo(int32(443)),
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+			AdoptARN:           adoptARN,
+		}
+		return allTasks
+	}
+
+	c.createTargetGroupCalls = 0
+	allTasks = buildTasks(adoptARN)
+	runNLBTasks(t, cloud, allTasks)
+
+	if c.createTargetGroupCalls != 0 || c.deleteTargetGroupCalls != 0 {
+		t.Errorf("expected the target group to be adopted, got %d creates and %d deletes", c.createTargetGroupCalls, c.deleteTargetGroupCalls)
+	}
+	if c.modifyTargetGroupCalls != 1 {
+		t.Errorf("expected a single ModifyTargetGroup call, got %d", c.modifyTargetGroupCalls)
+	}
+	if arn := fi.ValueOf(allTasks["tg1"].(*TargetGroup).ARN); arn != adoptARN {
+		t.Errorf("expected target group %q to be adopted, got %q", adoptARN, arn)
+	}
+	described, err := c.DescribeTargetGroups(ctx, &elbv2.DescribeTargetGroupsInput{TargetGroupArns: []string{adoptARN}})
+	if err != nil {
+		t.Fatalf("error describing target group: %v", err)
+	}
+	tg := described.TargetGroups[0]
+	if fi.ValueOf(tg.HealthCheckIntervalSeconds) != 10 || fi.ValueOf(tg.HealthyThresholdCount) != 2 || fi.ValueOf(tg.UnhealthyThresholdCount) != 2 {
+		t.Errorf("expected the health check to be reconciled, got interval %d and thresholds %d/%d", fi.ValueOf(tg.HealthCheckIntervalSeconds), fi.ValueOf(tg.HealthyThresholdCount), fi.ValueOf(tg.UnhealthyThresholdCount))
+	}
+
+	checkNLBNoChanges(t, ctx, cloud, buildTasks(adoptARN))
+
+	// A target group in another VPC cannot be adopted
+	other, err := c.CreateTargetGroup(ctx, &elbv2.CreateTargetGroupInput{
+		Name:     s("other"),
+		Port:     fi.PtrTo(int32(443)),
+		Protocol: elbv2types.ProtocolEnumTcp,
+		VpcId:    s("vpc-other"),
+	})
+	if err != nil {
+		t.Fatalf("error creating target group: %v", err)
+	}
+	e := &TargetGroup{
+		Name:     s("tg1"),
+		VPC:      &VPC{ID: vpcID},
+		AdoptARN: fi.ValueOf(other.TargetGroups[0].TargetGroupArn),
+	}
+	target := &awsup.AWSAPITarget{Cloud: cloud}
+	cloudupContext, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, target, &kops.Cluster{}, cloud, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+	if _, err := e.Find(cloudupContext); err == nil || !strings.Contains(err.Error(), "vpc-other") {
+		t.Errorf("expected an error adopting a target group in another VPC, got %v", err)
+	}
+}
+
+func TestTargetGroupCheckChangesDeregistrationDelay(t *testing.T) {
+	grid := map[int]bool{
+		-1:   true,
+		0:    false,
+		300:  false,
+		3600: false,
+		3601: true,
+	}
+	for delay, expectError := range grid {
+		tg := &TargetGroup{
+			Name:                       s("tg1"),
+			Protocol:                   elbv2types.ProtocolEnumTcp,
+			Port:                       fi.PtrTo(int32(443)),
+			Interval:                   fi.PtrTo(int32(10)),
+			HealthyThreshold:           fi.PtrTo(int32(2)),
+			UnhealthyThreshold:         fi.PtrTo(int32(2)),
+			DeregistrationDelaySeconds: fi.PtrTo(delay),
+		}
+		err := (&TargetGroup{}).CheckChanges(nil, tg, tg)
+		if expectError != (err != nil) {
+			t.Errorf("deregistration delay %d: unexpected error %v", delay, err)
+		}
+	}
+}
+
+func TestTargetGroupFastTeardown(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func() map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		allTasks["tg1"] = &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                nlb1.VPC,
+			Tags:               map[string]string{"Name": "tg1"},
+			Protocol:           elbv2types.ProtocolEnumTcp,
+			Port:               fi.PtrTo(int32(443)),
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+			FastTeardown:       fi.PtrTo(true),
+		}
+		return allTasks
+	}
+
+	allTasks := buildTasks()
+	runNLBTasks(t, cloud, allTasks)
+
+	response, err := c.DescribeTargetGroupAttributes(ctx, &elbv2.DescribeTargetGroupAttributesInput{TargetGroupArn: allTasks["tg1"].(*TargetGroup).ARN})
+	if err != nil {
+		t.Fatalf("error describing target group attributes: %v", err)
+	}
+	attributes := make(map[string]string)
+	for _, attribute := range response.Attributes {
+		attributes[fi.ValueOf(attribute.Key)] = fi.ValueOf(attribute.Value)
+	}
+	expected := map[string]string{
+		TargetGroupAttributeDeregistrationDelayTimeoutSeconds:               "0",
+		TargetGroupAttributeDeregistrationDelayConnectionTerminationEnabled: "true",
+	}
+	for k, v := range expected {
+		if attributes[k] != v {
+			t.Errorf("expected %s to be %q, got %q", k, v, attributes[k])
+		}
+	}
+
+	checkNLBNoChanges(t, ctx, cloud, buildTasks())
+}
+
+func TestTargetGroupCheckChangesFastTeardown(t *testing.T) {
+	grid := []struct {
+		name                       string
+		deregistrationDelaySeconds *int
+		connectionTermination      *bool
+		expectError                bool
+	}{
+		{name: "preset", deregistrationDelaySeconds: fi.PtrTo(0), connectionTermination: fi.PtrTo(true)},
+		{name: "draining", deregistrationDelaySeconds: fi.PtrTo(300), connectionTermination: fi.PtrTo(true), expectError: true},
+		{name: "connections kept", deregistrationDelaySeconds: fi.PtrTo(0), connectionTermination: fi.PtrTo(false), expectError: true},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			tg := &TargetGroup{
+				Name:                       s("tg1"),
+				Protocol:                   elbv2types.ProtocolEnumTcp,
+				Port:                       fi.PtrTo(int32(443)),
+				Interval:                   fi.PtrTo(int32(10)),
+				HealthyThreshold:           fi.PtrTo(int32(2)),
+				UnhealthyThreshold:         fi.PtrTo(int32(2)),
+				FastTeardown:               fi.PtrTo(true),
+				DeregistrationDelaySeconds: g.deregistrationDelaySeconds,
+				ConnectionTermination:      g.connectionTermination,
+			}
+			err := (&TargetGroup{}).CheckChanges(nil, tg, tg)
+			if g.expectError && err == nil {
+				t.Errorf("expected error, got none")
+			}
+			if !g.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestTargetGroupDeregistrationTerraform(t *testing.T) {
+	cases := []*renderTest{
+		{
+			Resource: &TargetGroup{
+				Name:                       s("tg1"),
+				VPC:                        &VPC{Name: s("vpc1"), ID: s("vpc-1234")},
+				Tags:                       map[string]string{"Name": "tg1"},
+				Protocol:                   elbv2types.ProtocolEnumTcp,
+				Port:                       fi.PtrTo(int32(443)),
+				Interval:                   fi.PtrTo(int32(10)),
+				HealthyThreshold:           fi.PtrTo(int32(2)),
+				UnhealthyThreshold:         fi.PtrTo(int32(2)),
+				ConnectionTermination:      fi.PtrTo(true),
+				DeregistrationDelaySeconds: fi.PtrTo(0),
+			},
+			Expected: `provider "aws" {
+  region = "eu-west-2"
+}
+
+resource "aws_lb_target_group" "tg1" {
+  connection_termination = "true"
+  deregistration_delay   = "0"
+  health_check {
+    healthy_threshold   = 2
+    interval            = 10
+    protocol            = "TCP"
+    unhealthy_threshold = 2
+  }
+  name     = "tg1"
+  port     = 443
+  protocol = "TCP"
+  tags = {
+    "Name" = "tg1"
+  }
+  vpc_id = aws_vpc.vpc1.id
+}
+
+terraform {
+  required_version = ">= 0.15.0"
+  required_providers {
+    aws = {
+      "source"  = "hashicorp/aws"
+      "version" = ">= 5.0.0"
+    }
+  }
+}
+`,
+		},
+	}
+
+	doRenderTests(t, "RenderTerraform", cases)
+}
+
+func TestTargetGroupUnhealthyDraining(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(drainingIntervalSeconds int) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		allTasks["tg1"] = &TargetGroup{
+			Name:                             s("tg1"),
+			Lifecycle:                        fi.LifecycleSync,
+			VPC:                              nlb1.VPC,
+			Tags:                             map[string]string{"Name": "tg1"},
+			Protocol:                         elbv2types.ProtocolEnumTcp,
+			Port:                             fi.PtrTo(int32(443)),
+			Interval:                         fi.PtrTo(int32(10)),
+			HealthyThreshold:                 fi.PtrTo(int32(2)),
+			UnhealthyThreshold:               fi.PtrTo(int32(2)),
+			UnhealthyConnectionTermination:   fi.PtrTo(false),
+			UnhealthyDrainingIntervalSeconds: fi.PtrTo(drainingIntervalSeconds),
+		}
+		return allTasks
+	}
+
+	for _, drainingIntervalSeconds := range []int{300, 3600} {
+		allTasks := buildTasks(drainingIntervalSeconds)
+		runNLBTasks(t, cloud, allTasks)
+
+		response, err := c.DescribeTargetGroupAttributes(ctx, &elbv2.DescribeTargetGroupAttributesInput{TargetGroupArn: allTasks["tg1"].(*TargetGroup).ARN})
+		if err != nil {
+			t.Fatalf("error describing target group attributes: %v", err)
+		}
+		attributes := make(map[string]string)
+		for _, attribute := range response.Attributes {
+			attributes[fi.ValueOf(attribute.Key)] = fi.ValueOf(attribute.Value)
+		}
+		if actual, expected := attributes[TargetGroupAttributeUnhealthyConnectionTerminationEnabled], "false"; actual != expected {
+			t.Errorf("expected %s to be %q, got %q", TargetGroupAttributeUnhealthyConnectionTerminationEnabled, expected, actual)
+		}
+		if actual, expected := attributes[TargetGroupAttributeUnhealthyDrainingIntervalSeconds], strconv.Itoa(drainingIntervalSeconds); actual != expected {
+			t.Errorf("expected %s to be %q, got %q", TargetGroupAttributeUnhealthyDrainingIntervalSeconds, expected, actual)
+		}
+
+		allTasks = buildTasks(drainingIntervalSeconds)
+		checkNLBNoChanges(t, ctx, cloud, allTasks)
+	}
+}
+
+func TestTargetGroupCheckChangesUnhealthyDrainingInterval(t *testing.T) {
+	grid := []struct {
+		connectionTermination *bool
+		interval              int
+		expectError           bool
+	}{
+		{connectionTermination: fi.PtrTo(false), interval: -1, expectError: true},
+		{connectionTermination: fi.PtrTo(false), interval: 0, expectError: false},
+		{connectionTermination: fi.PtrTo(false), interval: 360000, expectError: false},
+		{connectionTermination: fi.PtrTo(false), interval: 360001, expectError: true},
+		{connectionTermination: fi.PtrTo(true), interval: 300, expectError: true},
+		{connectionTermination: nil, interval: 300, expectError: true},
+	}
+	for _, g := range grid {
+		tg := &TargetGroup{
+			Name:                             s("tg1"),
+			Protocol:                         elbv2types.ProtocolEnumTcp,
+			Port:                             fi.PtrTo(int32(443)),
+			Interval:                         fi.PtrTo(int32(10)),
+			HealthyThreshold:                 fi.PtrTo(int32(2)),
+			UnhealthyThreshold:               fi.PtrTo(int32(2)),
+			UnhealthyConnectionTermination:   g.connectionTermination,
+			UnhealthyDrainingIntervalSeconds: fi.PtrTo(g.interval),
+		}
+		err := (&TargetGroup{}).CheckChanges(nil, tg, tg)
+		if g.expectError != (err != nil) {
+			t.Errorf("unhealthy draining interval %d with connection termination %v: unexpected error %v", g.interval, fi.DebugAsJsonString(g.connectionTermination), err)
+		}
+	}
+}
+
+func TestTargetGroupUnhealthyDrainingTerraform(t *testing.T) {
+	cases := []*renderTest{
+		{
+			Resource: &TargetGroup{
+				Name:                             s("tg1"),
+				VPC:                              &VPC{Name: s("vpc1"), ID: s("vpc-1234")},
+				Tags:                             map[string]string{"Name": "tg1"},
+				Protocol:                         elbv2types.ProtocolEnumTcp,
+				Port:                             fi.PtrTo(int32(443)),
+				Interval:                         fi.PtrTo(int32(10)),
+				HealthyThreshold:                 fi.PtrTo(int32(2)),
+				UnhealthyThreshold:               fi.PtrTo(int32(2)),
+				UnhealthyConnectionTermination:   fi.PtrTo(false),
+				UnhealthyDrainingIntervalSeconds: fi.PtrTo(300),
+			},
+			Expected: `provider "aws" {
+  region = "eu-west-2"
+}
+
+resource "aws_lb_target_group" "tg1" {
+  connection_termination = ""
+  deregistration_delay   = ""
+  health_check {
+    healthy_threshold   = 2
+    interval            = 10
+    protocol            = "TCP"
+    unhealthy_threshold = 2
+  }
+  name     = "tg1"
+  port     = 443
+  protocol = "TCP"
+  tags = {
+    "Name" = "tg1"
+  }
+  target_health_state {
+    enable_unhealthy_connection_termination = false
+    unhealthy_draining_interval             = 300
+  }
+  vpc_id = aws_vpc.vpc1.id
+}
+
+terraform {
+  required_version = ">= 0.15.0"
+  required_providers {
+    aws = {
+      "source"  = "hashicorp/aws"
+      "version" = ">= 5.0.0"
+    }
+  }
+}
+`,
+		},
+	}
+
+	doRenderTests(t, "RenderTerraform", cases)
+}
+
+func TestTargetGroupPreserveClientIPDrift(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func() map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		allTasks["tg1"] = &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                nlb1.VPC,
+			Tags:               map[string]string{"Name": "tg1"},
+			Protocol:           elbv2types.ProtocolEnumTcp,
+			Port:               fi.PtrTo(int32(443)),
+			TargetType:         elbv2types.TargetTypeEnumIp,
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+			ProxyProtocolV2:    fi.PtrTo(true),
+			PreserveClientIP:   fi.PtrTo(false),
+		}
+		return allTasks
+	}
+
+	preserveClientIPAttribute := func(t *testing.T, targetGroupArn *string) string {
+		attributes, err := c.DescribeTargetGroupAttributes(ctx, &elbv2.DescribeTargetGroupAttributesInput{TargetGroupArn: targetGroupArn})
+		if err != nil {
+			t.Fatalf("error describing target group attributes: %v", err)
+		}
+		for _, attribute := range attributes.Attributes {
+			if fi.ValueOf(attribute.Key) == TargetGroupAttributePreserveClientIPEnabled {
+				return fi.ValueOf(attribute.Value)
+			}
+		}
+		return ""
+	}
+
+	var targetGroupArn *string
+	{
+		allTasks := buildTasks()
+		runNLBTasks(t, cloud, allTasks)
+		targetGroupArn = allTasks["tg1"].(*TargetGroup).ARN
+		if actual := preserveClientIPAttribute(t, targetGroupArn); actual != "false" {
+			t.Errorf("expected %s to be %q, got %q", TargetGroupAttributePreserveClientIPEnabled, "false", actual)
+		}
+	}
+
+	// Re-enable client IP preservation outside of kops
+	if _, err := c.ModifyTargetGroupAttributes(ctx, &elbv2.ModifyTargetGroupAttributesInput{
+		TargetGroupArn: targetGroupArn,
+		Attributes:     []elbv2types.TargetGroupAttribute{{Key: s(TargetGroupAttributePreserveClientIPEnabled), Value: s("true")}},
+	}); err != nil {
+		t.Fatalf("error modifying target group attributes: %v", err)
+	}
+
+	{
+		allTasks := buildTasks()
+		runNLBTasks(t, cloud, allTasks)
+		if actual := preserveClientIPAttribute(t, targetGroupArn); actual != "false" {
+			t.Errorf("expected the drift of %s to be reverted, got %q", TargetGroupAttributePreserveClientIPEnabled, actual)
+		}
+	}
+
+	{
+		allTasks := buildTasks()
+		checkNLBNoChanges(t, ctx, cloud, allTasks)
+	}
+}
+
+func TestTargetGroupCheckChangesPreserveClientIP(t *testing.T) {
+	grid := []struct {
+		protocol         elbv2types.ProtocolEnum
+		preserveClientIP bool
+		expectError      bool
+	}{
+		{protocol: elbv2types.ProtocolEnumTcp, preserveClientIP: false},
+		{protocol: elbv2types.ProtocolEnumTcp, preserveClientIP: true},
+		{protocol: elbv2types.ProtocolEnumUdp, preserveClientIP: true},
+		{protocol: elbv2types.ProtocolEnumUdp, preserveClientIP: false, expectError: true},
+		{protocol: elbv2types.ProtocolEnumTcpUdp, preserveClientIP: false, expectError: true},
+	}
+	for _, g := range grid {
+		tg := &TargetGroup{
+			Name:                s("tg1"),
+			Protocol:            g.protocol,
+			Port:                fi.PtrTo(int32(53)),
+			Interval:            fi.PtrTo(int32(10)),
+			HealthyThreshold:    fi.PtrTo(int32(2)),
+			UnhealthyThreshold:  fi.PtrTo(int32(2)),
+			HealthCheckProtocol: elbv2types.ProtocolEnumTcp,
+			PreserveClientIP:    fi.PtrTo(g.preserveClientIP),
+		}
+		err := (&TargetGroup{}).CheckChanges(nil, tg, tg)
+		if g.expectError != (err != nil) {
+			t.Errorf("protocol %s with client IP preservation %v: unexpected error %v", g.protocol, g.preserveClientIP, err)
+		}
+	}
+}
+
+func TestTargetGroupPreserveClientIPTerraform(t *testing.T) {
+	cases := []*renderTest{
+		{
+			Resource: &TargetGroup{
+				Name:               s("tg1"),
+				VPC:                &VPC{Name: s("vpc1"), ID: s("vpc-1234")},
+				Tags:               map[string]string{"Name": "tg1"},
+				Protocol:           elbv2types.ProtocolEnumTcp,
+				Port:               fi.PtrTo(int32(443)),
+				Interval:           fi.PtrTo(int32(10)),
+				HealthyThreshold:   fi.PtrTo(int32(2)),
+				UnhealthyThreshold: fi.PtrTo(int32(2)),
+				PreserveClientIP:   fi.PtrTo(false),
+			},
+			Expected: `provider "aws" {
+  region = "eu-west-2"
+}
+
+resource "aws_lb_target_group" "tg1" {
+  connection_termination = ""
+  deregistration_delay   = ""
+  health_check {
+    healthy_threshold   = 2
+    interval            = 10
+    protocol            = "TCP"
+    unhealthy_threshold = 2
+  }
+  name               = "tg1"
+  port               = 443
+  preserve_client_ip = "false"
+  protocol           = "TCP"
+  tags = {
+    "Name" = "tg1"
+  }
+  vpc_id = aws_vpc.vpc1.id
+}
+
+terraform {
+  required_version = ">= 0.15.0"
+  required_providers {
+    aws = {
+      "source"  = "hashicorp/aws"
+      "version" = ">= 5.0.0"
+    }
+  }
+}
+`,
+		},
+	}
+
+	doRenderTests(t, "RenderTerraform", cases)
+}
+
+func TestTargetGroupCheckChangesStickiness(t *testing.T) {
+	grid := []struct {
+		name        string
+		protocol    elbv2types.ProtocolEnum
+		stickiness  *TargetGroupStickiness
+		expectError bool
+	}{
+		{name: "no stickiness", protocol: elbv2types.ProtocolEnumTcp},
+		{name: "tcp source_ip", protocol: elbv2types.ProtocolEnumTcp, stickiness: &TargetGroupStickiness{Enabled: true, Type: TargetGroupStickinessTypeSourceIP}},
+		{name: "udp source_ip", protocol: elbv2types.ProtocolEnumUdp, stickiness: &TargetGroupStickiness{Enabled: true, Type: TargetGroupStickinessTypeSourceIP}},
+		{name: "disabled", protocol: elbv2types.ProtocolEnumTcp, stickiness: &TargetGroupStickiness{Type: TargetGroupStickinessTypeSourceIP}},
+		{name: "tls source_ip", protocol: elbv2types.ProtocolEnumTls, stickiness: &TargetGroupStickiness{Enabled: true, Type: TargetGroupStickinessTypeSourceIP}, expectError: true},
+		{name: "tcp lb_cookie", protocol: elbv2types.ProtocolEnumTcp, stickiness: &TargetGroupStickiness{Enabled: true, Type: TargetGroupStickinessTypeLBCookie}, expectError: true},
+		{name: "https lb_cookie", protocol: elbv2types.ProtocolEnumHttps, stickiness: &TargetGroupStickiness{Enabled: true, Type: TargetGroupStickinessTypeLBCookie}},
+		{name: "https source_ip", protocol: elbv2types.ProtocolEnumHttps, stickiness: &TargetGroupStickiness{Enabled: true, Type: TargetGroupStickinessTypeSourceIP}, expectError: true},
+		{name: "missing type", protocol: elbv2types.ProtocolEnumTcp, stickiness: &TargetGroupStickiness{Enabled: true}, expectError: true},
+		{name: "unknown type", protocol: elbv2types.ProtocolEnumTcp, stickiness: &TargetGroupStickiness{Enabled: true, Type: "session"}, expectError: true},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			tg := &TargetGroup{
+				Name:       s("tg1"),
+				Protocol:   g.protocol,
+				Stickiness: g.stickiness,
+			}
+			err := (&TargetGroup{}).CheckChanges(nil, tg, tg)
+			if g.expectError && err == nil {
+				t.Errorf("expected error, got none")
+			}
+			if !g.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestTargetGroupStickiness(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(enabled bool) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		allTasks["tg1"] = &TargetGroup{
+			Name:                s("tg1"),
+			Lifecycle:           fi.LifecycleSync,
+			VPC:                 nlb1.VPC,
+			Tags:                map[string]string{"Name": "tg1"},
+			Protocol:            elbv2types.ProtocolEnumUdp,
+			Port:                fi.PtrTo(int32(53)),
+			Interval:            fi.PtrTo(int32(10)),
+			HealthyThreshold:    fi.PtrTo(int32(2)),
+			UnhealthyThreshold:  fi.PtrTo(int32(2)),
+			HealthCheckProtocol: elbv2types.ProtocolEnumTcp,
+			Stickiness:          &TargetGroupStickiness{Enabled: enabled, Type: TargetGroupStickinessTypeSourceIP},
+		}
+		return allTasks
+	}
+
+	for _, enabled := range []bool{true, false} {
+		allTasks := buildTasks(enabled)
+		runNLBTasks(t, cloud, allTasks)
+
+		attributes, err := c.DescribeTargetGroupAttributes(ctx, &elbv2.DescribeTargetGroupAttributesInput{TargetGroupArn: allTasks["tg1"].(*TargetGroup).ARN})
+		if err != nil {
+			t.Fatalf("error describing target group attributes: %v", err)
+		}
+		actual := make(map[string]string)
+		for _, attribute := range attributes.Attributes {
+			actual[fi.ValueOf(attribute.Key)] = fi.ValueOf(attribute.Value)
+		}
+		if actual[TargetGroupAttributeStickinessEnabled] != strconv.FormatBool(enabled) || actual[TargetGroupAttributeStickinessType] != TargetGroupStickinessTypeSourceIP {
+			t.Errorf("unexpected stickiness attributes %v", actual)
+		}
+
+		allTasks = buildTasks(enabled)
+		checkNLBNoChanges(t, ctx, cloud, allTasks)
+	}
+}
+
+func TestTargetGroupStickinessTerraform(t *testing.T) {
+	cases := []*renderTest{
+		{
+			Resource: &TargetGroup{
+				Name:               s("tg1"),
+				VPC:                &VPC{Name: s("vpc1"), ID: s("vpc-1234")},
+				Tags:               map[string]string{"Name": "tg1"},
+				Protocol:           elbv2types.ProtocolEnumTcp,
+				Port:               fi.PtrTo(int32(443)),
+				Interval:           fi.PtrTo(int32(10)),
+				HealthyThreshold:   fi.PtrTo(int32(2)),
+				UnhealthyThreshold: fi.PtrTo(int32(2)),
+				Stickiness:         &TargetGroupStickiness{Enabled: true, Type: TargetGroupStickinessTypeSourceIP},
+			},
+			Expected: `provider "aws" {
+  region = "eu-west-2"
+}
+
+resource "aws_lb_target_group" "tg1" {
+  connection_termination = ""
+  deregistration_delay   = ""
+  health_check {
+    healthy_threshold   = 2
+    interval            = 10
+    protocol            = "TCP"
+    unhealthy_threshold = 2
+  }
+  name     = "tg1"
+  port     = 443
+  protocol = "TCP"
+  stickiness {
+    enabled = true
+    type    = "source_ip"
+  }
+  tags = {
+    "Name" = "tg1"
+  }
+  vpc_id = aws_vpc.vpc1.id
+}
+
+terraform {
+  required_version = ">= 0.15.0"
+  required_providers {
+    aws = {
+      "source"  = "hashicorp/aws"
+      "version" = ">= 5.0.0"
+    }
+  }
+}
+`,
+		},
+	}
+
+	doRenderTests(t, "RenderTerraform", cases)
+}
+
+func TestTargetGroupCheckChangesTargetType(t *testing.T) {
+	grid := []struct {
+		name        string
+		targetType  elbv2types.TargetTypeEnum
+		vpc         *VPC
+		shared      bool
+		expectError bool
+	}{
+		{name: "default"},
+		{name: "instance", targetType: elbv2types.TargetTypeEnumInstance},
+		{name: "ip", targetType: elbv2types.TargetTypeEnumIp, vpc: &VPC{Name: s("vpc1")}},
+		{name: "ip without vpc", targetType: elbv2types.TargetTypeEnumIp, expectError: true},
+		{name: "shared ip without vpc", targetType: elbv2types.TargetTypeEnumIp, shared: true},
+		{name: "lambda", targetType: elbv2types.TargetTypeEnumLambda, vpc: &VPC{Name: s("vpc1")}, expectError: true},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			targetGroup := &TargetGroup{
+				Name:               s("tg"),
+				Protocol:           elbv2types.ProtocolEnumTcp,
+				TargetType:         g.targetType,
+				VPC:                g.vpc,
+				Shared:             fi.PtrTo(g.shared),
+				Interval:           fi.PtrTo(int32(10)),
+				HealthyThreshold:   fi.PtrTo(int32(2)),
+				UnhealthyThreshold: fi.PtrTo(int32(2)),
+			}
+			err := (&TargetGroup{}).CheckChanges(nil, targetGroup, targetGroup)
+			if g.expectError && err == nil {
+				t.Errorf("expected error, got none")
+			}
+			if !g.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestTargetGroupCheckChangesIPAddressType(t *testing.T) {
+	dualstackVPC := &VPC{Name: s("vpc1"), AmazonIPv6: fi.PtrTo(true)}
+	grid := []struct {
+		name                string
+		ipAddressType       elbv2types.TargetGroupIpAddressTypeEnum
+		targetType          elbv2types.TargetTypeEnum
+		protocol            elbv2types.ProtocolEnum
+		healthCheckProtocol elbv2types.ProtocolEnum
+		vpc                 *VPC
+		expectError         bool
+	}{
+		{name: "default", targetType: elbv2types.TargetTypeEnumIp, vpc: dualstackVPC},
+		{name: "ipv4", ipAddressType: elbv2types.TargetGroupIpAddressTypeEnumIpv4, targetType: elbv2types.TargetTypeEnumInstance, vpc: dualstackVPC},
+		{name: "ipv6", ipAddressType: elbv2types.TargetGroupIpAddressTypeEnumIpv6, targetType: elbv2types.TargetTypeEnumIp, vpc: dualstackVPC},
+		{name: "ipv6 with a shared VPC CIDR", ipAddressType: elbv2types.TargetGroupIpAddressTypeEnumIpv6, targetType: elbv2types.TargetTypeEnumIp, vpc: &VPC{Name: s("vpc1"), IPv6CIDR: s("2001:db8::/56")}},
+		{name: "ipv6 with instance targets", ipAddressType: elbv2types.TargetGroupIpAddressTypeEnumIpv6, targetType: elbv2types.TargetTypeEnumInstance, vpc: dualstackVPC, expectError: true},
+		{name: "ipv6 in an ipv4 VPC", ipAddressType: elbv2types.TargetGroupIpAddressTypeEnumIpv6, targetType: elbv2types.TargetTypeEnumIp, vpc: &VPC{Name: s("vpc1")}, expectError: true},
+		{name: "ipv6 with https health checks", ipAddressType: elbv2types.TargetGroupIpAddressTypeEnumIpv6, targetType: elbv2types.TargetTypeEnumIp, healthCheckProtocol: elbv2types.ProtocolEnumHttps, vpc: dualstackVPC},
+		{name: "ipv6 with tcp_udp", ipAddressType: elbv2types.TargetGroupIpAddressTypeEnumIpv6, targetType: elbv2types.TargetTypeEnumIp, protocol: elbv2types.ProtocolEnumTcpUdp, vpc: dualstackVPC, expectError: true},
+		{name: "unsupported", ipAddressType: "dualstack", targetType: elbv2types.TargetTypeEnumIp, vpc: dualstackVPC, expectError: true},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			protocol := g.protocol
+			if protocol == "" {
+				protocol = elbv2types.ProtocolEnumTcp
+			}
+			targetGroup := &TargetGroup{
+				Name:                s("tg"),
+				Protocol:            protocol,
+				TargetType:          g.targetType,
+				IPAddressType:       g.ipAddressType,
+				VPC:                 g.vpc,
+				Interval:            fi.PtrTo(int32(10)),
+				HealthyThreshold:    fi.PtrTo(int32(2)),
+				UnhealthyThreshold:  fi.PtrTo(int32(2)),
+				HealthCheckProtocol: g.healthCheckProtocol,
+			}
+			err := (&TargetGroup{}).CheckChanges(nil, targetGroup, targetGroup)
+			if g.expectError && err == nil {
+				t.Errorf("expected error, got none")
+			}
+			if !g.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+
+	// The IP address type of an existing target group cannot be changed
+	a := &TargetGroup{Name: s("tg"), IPAddressType: elbv2types.TargetGroupIpAddressTypeEnumIpv4}
+	e := &TargetGroup{Name: s("tg"), TargetType: elbv2types.TargetTypeEnumIp, VPC: dualstackVPC, IPAddressType: elbv2types.TargetGroupIpAddressTypeEnumIpv6}
+	if err := (&TargetGroup{}).CheckChanges(a, e, &TargetGroup{IPAddressType: e.IPAddressType}); err == nil {
+		t.Errorf("expected an error changing the IP address type")
+	}
+}
+
+func TestTargetGroupIPv6(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func() map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		vpc1 := allTasks["vpc1"].(*VPC)
+		vpc1.AmazonIPv6 = fi.PtrTo(true)
+		allTasks["AmazonIPv6"] = &VPCAmazonIPv6CIDRBlock{
+			Name:      s("AmazonIPv6"),
+			Lifecycle: fi.LifecycleSync,
+			VPC:       vpc1,
+			Shared:    fi.PtrTo(false),
+		}
+
+		allTasks["tg1"] = &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                vpc1,
+			Tags:               map[string]string{"Name": "tg1"},
+			Protocol:           elbv2types.ProtocolEnumTcp,
+			Port:               fi.PtrTo(int32(443)),
+			TargetType:         elbv2types.TargetTypeEnumIp,
+			IPAddressType:      elbv2types.TargetGroupIpAddressTypeEnumIpv6,
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+		}
+		return allTasks
+	}
+
+	allTasks := buildTasks()
+	runNLBTasks(t, cloud, allTasks)
+
+	arn := fi.ValueOf(allTasks["tg1"].(*TargetGroup).ARN)
+	described, err := c.DescribeTargetGroups(ctx, &elbv2.DescribeTargetGroupsInput{TargetGroupArns: []string{arn}})
+	if err != nil {
+		t.Fatalf("error describing target group: %v", err)
+	}
+	if ipAddressType := described.TargetGroups[0].IpAddressType; ipAddressType != elbv2types.TargetGroupIpAddressTypeEnumIpv6 {
+		t.Errorf("expected IP address type %q, got %q", elbv2types.TargetGroupIpAddressTypeEnumIpv6, ipAddressType)
+	}
+
+	checkNLBNoChanges(t, ctx, cloud, buildTasks())
+}
+
+func TestTargetGroupIPv6HTTPSHealthCheck(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func() map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		vpc1 := allTasks["vpc1"].(*VPC)
+		vpc1.AmazonIPv6 = fi.PtrTo(true)
+		allTasks["AmazonIPv6"] = &VPCAmazonIPv6CIDRBlock{
+			Name:      s("AmazonIPv6"),
+			Lifecycle: fi.LifecycleSync,
+			VPC:       vpc1,
+			Shared:    fi.PtrTo(false),
+		}
+
+		// The health of the targets is checked over HTTPS on their IPv6 addresses
+		allTasks["tg1"] = &TargetGroup{
+			Name:                s("tg1"),
+			Lifecycle:           fi.LifecycleSync,
+			VPC:                 vpc1,
+			Tags:                map[string]string{"Name": "tg1"},
+			Protocol:            elbv2types.ProtocolEnumTcp,
+			Port:                fi.PtrTo(int32(443)),
+			TargetType:          elbv2types.TargetTypeEnumIp,
+			IPAddressType:       elbv2types.TargetGroupIpAddressTypeEnumIpv6,
+			Interval:            fi.PtrTo(int32(10)),
+			HealthyThreshold:    fi.PtrTo(int32(2)),
+			UnhealthyThreshold:  fi.PtrTo(int32(2)),
+			HealthCheckProtocol: elbv2types.ProtocolEnumHttps,
+			HealthCheckPort:     s("3990"),
+			HealthCheckPath:     s("/healthz"),
+		}
+		return allTasks
+	}
+
+	allTasks := buildTasks()
+	runNLBTasks(t, cloud, allTasks)
+
+	response, err := c.DescribeTargetGroups(ctx, &elbv2.DescribeTargetGroupsInput{
+		TargetGroupArns: []string{fi.ValueOf(allTasks["tg1"].(*TargetGroup).ARN)},
+	})
+	if err != nil {
+		t.Fatalf("error describing target group: %v", err)
+	}
+	tg := response.TargetGroups[0]
+	if tg.IpAddressType != elbv2types.TargetGroupIpAddressTypeEnumIpv6 {
+		t.Errorf("expected IP address type %q, got %q", elbv2types.TargetGroupIpAddressTypeEnumIpv6, tg.IpAddressType)
+	}
+	if tg.HealthCheckProtocol != elbv2types.ProtocolEnumHttps || fi.ValueOf(tg.HealthCheckPort) != "3990" || fi.ValueOf(tg.HealthCheckPath) != "/healthz" {
+		t.Errorf("expected HTTPS health checks of /healthz on port 3990, got %s port %q path %q", tg.HealthCheckProtocol, fi.ValueOf(tg.HealthCheckPort), fi.ValueOf(tg.HealthCheckPath))
+	}
+	if tg.Matcher == nil || fi.ValueOf(tg.Matcher.HttpCode) != defaultHTTPHealthCheckMatcher {
+		t.Errorf("expected the default HTTP matcher %q, got %+v", defaultHTTPHealthCheckMatcher, tg.Matcher)
+	}
+
+	checkNLBNoChanges(t, ctx, cloud, buildTasks())
+}
+
+func TestTargetGroupIPv6Terraform(t *testing.T) {
+	cases := []*renderTest{
+		{
+			Resource: &TargetGroup{
+				Name:               s("tg1"),
+				VPC:                &VPC{Name: s("vpc1"), ID: s("vpc-1234")},
+				Tags:               map[string]string{"Name": "tg1"},
+				Protocol:           elbv2types.ProtocolEnumTcp,
+				Port:               fi.PtrTo(int32(443)),
+				TargetType:         elbv2types.TargetTypeEnumIp,
+				IPAddressType:      elbv2types.TargetGroupIpAddressTypeEnumIpv6,
+				Interval:           fi.PtrTo(int32(10)),
+				HealthyThreshold:   fi.PtrTo(int32(2)),
+				UnhealthyThreshold: fi.PtrTo(int32(2)),
+			},
+			Expected: `provider "aws" {
+  region = "eu-west-2"
+}
+
+resource "aws_lb_target_group" "tg1" {
+  connection_termination = ""
+  deregistration_delay   = ""
+  health_check {
+    healthy_threshold   = 2
+    interval            = 10
+    protocol            = "TCP"
+    unhealthy_threshold = 2
+  }
+  ip_address_type = "ipv6"
+  name            = "tg1"
+  port            = 443
+  protocol        = "TCP"
+  tags = {
+    "Name" = "tg1"
+  }
+  target_type = "ip"
+  vpc_id      = aws_vpc.vpc1.id
+}
+
+terraform {
+  required_version = ">= 0.15.0"
+  required_providers {
+    aws = {
+      "source"  = "hashicorp/aws"
+      "version" = ">= 5.0.0"
+    }
+  }
+}
+`,
+		},
+	}
+
+	doRenderTests(t, "RenderTerraform", cases)
+}
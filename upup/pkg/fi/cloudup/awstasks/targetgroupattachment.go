@@ -0,0 +1,261 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awstasks
+
+import (
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"k8s.io/klog/v2"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+	"k8s.io/kops/upup/pkg/fi/cloudup/terraform"
+	"k8s.io/kops/upup/pkg/fi/cloudup/terraformWriter"
+)
+
+// TargetGroupAttachment registers a fixed set of IP targets with a target group,
+// e.g. to send API traffic to control plane nodes outside of the cluster's autoscaling groups.
+// Any other target registered with the target group is deregistered.
+// +kops:fitask
+type TargetGroupAttachment struct {
+	Name      *string
+	Lifecycle fi.Lifecycle
+
+	TargetGroup *TargetGroup
+	// Targets are the IP targets of the target group, sorted by IP and port.
+	Targets []TargetGroupTarget
+}
+
+// TargetGroupTarget is an IP target registered with a target group.
+type TargetGroupTarget struct {
+	IP   string
+	Port int32
+	// AvailabilityZone is the zone of the target, or "all" for an IP address outside of the VPC.
+	// If not set, AWS uses the zone of the subnet of the IP address.
+	AvailabilityZone string
+}
+
+var _ fi.CloudupHasDependencies = &TargetGroupAttachment{}
+
+func (e *TargetGroupAttachment) GetDependencies(tasks map[string]fi.CloudupTask) []fi.CloudupTask {
+	return []fi.CloudupTask{e.TargetGroup}
+}
+
+var _ fi.CompareWithID = &TargetGroupAttachment{}
+
+func (e *TargetGroupAttachment) CompareWithID() *string {
+	return e.Name
+}
+
+func (e *TargetGroupAttachment) Normalize(c *fi.CloudupContext) error {
+	sortTargetGroupTargets(e.Targets)
+	return nil
+}
+
+func (e *TargetGroupAttachment) Find(c *fi.CloudupContext) (*TargetGroupAttachment, error) {
+	ctx := c.Context()
+	cloud := awsup.GetCloud(c)
+
+	if e.TargetGroup == nil || e.TargetGroup.ARN == nil {
+		klog.V(2).Infof("target group of %q not yet known", fi.ValueOf(e.Name))
+		return nil, nil
+	}
+
+	response, err := cloud.ELBV2().DescribeTargetHealth(ctx, &elbv2.DescribeTargetHealthInput{
+		TargetGroupArn: e.TargetGroup.ARN,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describing targets of target group %q: %w", fi.ValueOf(e.TargetGroup.ARN), err)
+	}
+
+	desiredZones := make(map[targetGroupTargetKey]string)
+	for _, target := range e.Targets {
+		desiredZones[target.key()] = target.AvailabilityZone
+	}
+
+	actual := &TargetGroupAttachment{
+		Name:        e.Name,
+		Lifecycle:   e.Lifecycle,
+		TargetGroup: e.TargetGroup,
+	}
+	for _, description := range response.TargetHealthDescriptions {
+		if description.Target == nil {
+			continue
+		}
+		// Targets that are draining are already being deregistered
+		if description.TargetHealth != nil && description.TargetHealth.State == elbv2types.TargetHealthStateEnumDraining {
+			continue
+		}
+		target := TargetGroupTarget{
+			IP:   aws.ToString(description.Target.Id),
+			Port: aws.ToInt32(description.Target.Port),
+		}
+		// AWS reports the zone it picked for targets registered without one, which is not a change
+		if zone, found := desiredZones[target.key()]; !found || zone != "" {
+			target.AvailabilityZone = aws.ToString(description.Target.AvailabilityZone)
+		}
+		actual.Targets = append(actual.Targets, target)
+	}
+	sortTargetGroupTargets(actual.Targets)
+
+	return actual, nil
+}
+
+func (e *TargetGroupAttachment) Run(c *fi.CloudupContext) error {
+	return fi.CloudupDefaultDeltaRunMethod(e, c)
+}
+
+func (_ *TargetGroupAttachment) CheckChanges(a, e, changes *TargetGroupAttachment) error {
+	if e.TargetGroup == nil {
+		return fi.RequiredField("TargetGroup")
+	}
+	if targetType := e.TargetGroup.TargetType; targetType != "" && targetType != elbv2types.TargetTypeEnumIp {
+		return fmt.Errorf("target group attachment %q can only register targets with a target group of type %q, not %q", fi.ValueOf(e.Name), elbv2types.TargetTypeEnumIp, targetType)
+	}
+
+	seen := make(map[targetGroupTargetKey]bool)
+	for _, target := range e.Targets {
+		if net.ParseIP(target.IP) == nil {
+			return fmt.Errorf("target group attachment %q has invalid target IP %q", fi.ValueOf(e.Name), target.IP)
+		}
+		if target.Port < 1 || target.Port > 65535 {
+			return fmt.Errorf("target group attachment %q has invalid port %d for target %q", fi.ValueOf(e.Name), target.Port, target.IP)
+		}
+		if seen[target.key()] {
+			return fmt.Errorf("target group attachment %q has duplicate target %s:%d", fi.ValueOf(e.Name), target.IP, target.Port)
+		}
+		seen[target.key()] = true
+	}
+	return nil
+}
+
+func (_ *TargetGroupAttachment) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *TargetGroupAttachment) error {
+	ctx := t.Context()
+
+	var actualTargets []TargetGroupTarget
+	if a != nil {
+		actualTargets = a.Targets
+	}
+	// A target that moves to another zone is deregistered and registered again
+	toDeregister := subtractTargetGroupTargets(actualTargets, e.Targets)
+	toRegister := subtractTargetGroupTargets(e.Targets, actualTargets)
+
+	if len(toDeregister) != 0 {
+		klog.V(2).Infof("deregistering %d targets from target group %q", len(toDeregister), fi.ValueOf(e.TargetGroup.Name))
+		request := &elbv2.DeregisterTargetsInput{
+			TargetGroupArn: e.TargetGroup.ARN,
+		}
+		for _, target := range toDeregister {
+			request.Targets = append(request.Targets, elbv2types.TargetDescription{
+				Id:   aws.String(target.IP),
+				Port: aws.Int32(target.Port),
+			})
+		}
+		if _, err := t.Cloud.ELBV2().DeregisterTargets(ctx, request); err != nil {
+			return fmt.Errorf("deregistering targets from target group %q: %w", fi.ValueOf(e.TargetGroup.Name), err)
+		}
+	}
+
+	if len(toRegister) != 0 {
+		klog.V(2).Infof("registering %d targets with target group %q", len(toRegister), fi.ValueOf(e.TargetGroup.Name))
+		request := &elbv2.RegisterTargetsInput{
+			TargetGroupArn: e.TargetGroup.ARN,
+		}
+		for _, target := range toRegister {
+			description := elbv2types.TargetDescription{
+				Id:   aws.String(target.IP),
+				Port: aws.Int32(target.Port),
+			}
+			if target.AvailabilityZone != "" {
+				description.AvailabilityZone = aws.String(target.AvailabilityZone)
+			}
+			request.Targets = append(request.Targets, description)
+		}
+		if _, err := t.Cloud.ELBV2().RegisterTargets(ctx, request); err != nil {
+			return fmt.Errorf("registering targets with target group %q: %w", fi.ValueOf(e.TargetGroup.Name), err)
+		}
+	}
+
+	return nil
+}
+
+type terraformTargetGroupAttachment struct {
+	TargetGroupARN   *terraformWriter.Literal `cty:"target_group_arn"`
+	TargetID         *string                  `cty:"target_id"`
+	Port             *int32                   `cty:"port"`
+	AvailabilityZone *string                  `cty:"availability_zone"`
+}
+
+func (_ *TargetGroupAttachment) RenderTerraform(t *terraform.TerraformTarget, a, e, changes *TargetGroupAttachment) error {
+	for _, target := range e.Targets {
+		tf := &terraformTargetGroupAttachment{
+			TargetGroupARN: e.TargetGroup.TerraformLink(),
+			TargetID:       fi.PtrTo(target.IP),
+			Port:           fi.PtrTo(target.Port),
+		}
+		if target.AvailabilityZone != "" {
+			tf.AvailabilityZone = fi.PtrTo(target.AvailabilityZone)
+		}
+		// Named after the target rather than its position, so that removing a target doesn't replace the others
+		name := fmt.Sprintf("%s-%s-%d", fi.ValueOf(e.Name), target.IP, target.Port)
+		if err := t.RenderResource("aws_lb_target_group_attachment", name, tf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// targetGroupTargetKey identifies a target of a target group.
+type targetGroupTargetKey struct {
+	IP   string
+	Port int32
+}
+
+func (t *TargetGroupTarget) key() targetGroupTargetKey {
+	return targetGroupTargetKey{IP: t.IP, Port: t.Port}
+}
+
+func sortTargetGroupTargets(targets []TargetGroupTarget) {
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].IP != targets[j].IP {
+			return targets[i].IP < targets[j].IP
+		}
+		return targets[i].Port < targets[j].Port
+	})
+}
+
+// subtractTargetGroupTargets returns the targets of l that are not in r.
+func subtractTargetGroupTargets(l, r []TargetGroupTarget) []TargetGroupTarget {
+	var targets []TargetGroupTarget
+	for _, target := range l {
+		found := false
+		for _, other := range r {
+			if target == other {
+				found = true
+				break
+			}
+		}
+		if !found {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
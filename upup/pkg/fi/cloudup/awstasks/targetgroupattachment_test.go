@@ -0,0 +1,203 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awstasks
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"k8s.io/kops/cloudmock/aws/mockec2"
+	"k8s.io/kops/cloudmock/aws/mockelbv2"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+func TestTargetGroupAttachment(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	c := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(targets ...TargetGroupTarget) map[string]fi.CloudupTask {
+		allTasks := buildNLBTasks()
+		nlb1 := allTasks["nlb1"].(*NetworkLoadBalancer)
+
+		tg1 := &TargetGroup{
+			Name:               s("tg1"),
+			Lifecycle:          fi.LifecycleSync,
+			VPC:                nlb1.VPC,
+			Tags:               map[string]string{"Name": "tg1"},
+			Protocol:           elbv2types.ProtocolEnumTcp,
+			Port:               fi.PtrTo(int32(443)),
+			TargetType:         elbv2types.TargetTypeEnumIp,
+			Interval:           fi.PtrTo(int32(10)),
+			HealthyThreshold:   fi.PtrTo(int32(2)),
+			UnhealthyThreshold: fi.PtrTo(int32(2)),
+		}
+		allTasks["tg1"] = tg1
+		allTasks["attachment1"] = &TargetGroupAttachment{
+			Name:        s("attachment1"),
+			Lifecycle:   fi.LifecycleSync,
+			TargetGroup: tg1,
+			Targets:     targets,
+		}
+		return allTasks
+	}
+
+	registeredTargets := func(t *testing.T, targetGroupARN *string) []elbv2types.TargetDescription {
+		response, err := c.DescribeTargetHealth(ctx, &elbv2.DescribeTargetHealthInput{TargetGroupArn: targetGroupARN})
+		if err != nil {
+			t.Fatalf("error describing targets: %v", err)
+		}
+		var targets []elbv2types.TargetDescription
+		for _, description := range response.TargetHealthDescriptions {
+			targets = append(targets, *description.Target)
+		}
+		return targets
+	}
+
+	grid := []struct {
+		name     string
+		targets  []TargetGroupTarget
+		expected []elbv2types.TargetDescription
+	}{
+		{
+			name: "register",
+			targets: []TargetGroupTarget{
+				{IP: "10.0.0.2", Port: 443},
+				{IP: "10.0.0.1", Port: 443},
+			},
+			expected: []elbv2types.TargetDescription{
+				{Id: aws.String("10.0.0.1"), Port: aws.Int32(443)},
+				{Id: aws.String("10.0.0.2"), Port: aws.Int32(443)},
+			},
+		},
+		{
+			name: "replace",
+			targets: []TargetGroupTarget{
+				{IP: "10.0.0.2", Port: 443},
+				{IP: "192.168.0.1", Port: 6443, AvailabilityZone: "all"},
+			},
+			expected: []elbv2types.TargetDescription{
+				{Id: aws.String("10.0.0.2"), Port: aws.Int32(443)},
+				{Id: aws.String("192.168.0.1"), Port: aws.Int32(6443), AvailabilityZone: aws.String("all")},
+			},
+		},
+		{
+			name: "deregister all",
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			allTasks := buildTasks(g.targets...)
+			runNLBTasks(t, cloud, allTasks)
+
+			actual := registeredTargets(t, allTasks["tg1"].(*TargetGroup).ARN)
+			if !reflect.DeepEqual(actual, g.expected) {
+				t.Errorf("unexpected targets:\nexpected %+v\ngot      %+v", g.expected, actual)
+			}
+
+			allTasks = buildTasks(g.targets...)
+			checkNLBNoChanges(t, ctx, cloud, allTasks)
+		})
+	}
+}
+
+func TestTargetGroupAttachmentCheckChanges(t *testing.T) {
+	grid := []struct {
+		name        string
+		targetType  elbv2types.TargetTypeEnum
+		targets     []TargetGroupTarget
+		expectError bool
+	}{
+		{name: "ipv4", targetType: elbv2types.TargetTypeEnumIp, targets: []TargetGroupTarget{{IP: "10.0.0.1", Port: 443}}},
+		{name: "ipv6", targetType: elbv2types.TargetTypeEnumIp, targets: []TargetGroupTarget{{IP: "2001:db8::1", Port: 443}}},
+		{name: "same ip on two ports", targetType: elbv2types.TargetTypeEnumIp, targets: []TargetGroupTarget{{IP: "10.0.0.1", Port: 443}, {IP: "10.0.0.1", Port: 6443}}},
+		{name: "instance target group", targetType: elbv2types.TargetTypeEnumInstance, targets: []TargetGroupTarget{{IP: "10.0.0.1", Port: 443}}, expectError: true},
+		{name: "hostname", targetType: elbv2types.TargetTypeEnumIp, targets: []TargetGroupTarget{{IP: "api.example.com", Port: 443}}, expectError: true},
+		{name: "missing port", targetType: elbv2types.TargetTypeEnumIp, targets: []TargetGroupTarget{{IP: "10.0.0.1"}}, expectError: true},
+		{name: "duplicate", targetType: elbv2types.TargetTypeEnumIp, targets: []TargetGroupTarget{{IP: "10.0.0.1", Port: 443}, {IP: "10.0.0.1", Port: 443, AvailabilityZone: "all"}}, expectError: true},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			attachment := &TargetGroupAttachment{
+				Name:        s("attachment"),
+				TargetGroup: &TargetGroup{Name: s("tg"), TargetType: g.targetType},
+				Targets:     g.targets,
+			}
+			err := (&TargetGroupAttachment{}).CheckChanges(nil, attachment, attachment)
+			if g.expectError && err == nil {
+				t.Errorf("expected error, got none")
+			}
+			if !g.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestTargetGroupAttachmentTerraform(t *testing.T) {
+	cases := []*renderTest{
+		{
+			Resource: &TargetGroupAttachment{
+				Name:        s("api"),
+				TargetGroup: &TargetGroup{Name: s("tcp-api")},
+				Targets: []TargetGroupTarget{
+					{IP: "10.0.0.1", Port: 443},
+					{IP: "192.168.0.1", Port: 6443, AvailabilityZone: "all"},
+				},
+			},
+			Expected: `provider "aws" {
+  region = "eu-west-2"
+}
+
+resource "aws_lb_target_group_attachment" "api-10-0-0-1-443" {
+  port             = 443
+  target_group_arn = aws_lb_target_group.tcp-api.id
+  target_id        = "10.0.0.1"
+}
+
+resource "aws_lb_target_group_attachment" "api-192-168-0-1-6443" {
+  availability_zone = "all"
+  port              = 6443
+  target_group_arn  = aws_lb_target_group.tcp-api.id
+  target_id         = "192.168.0.1"
+}
+
+terraform {
+  required_version = ">= 0.15.0"
+  required_providers {
+    aws = {
+      "source"  = "hashicorp/aws"
+      "version" = ">= 5.0.0"
+    }
+  }
+}
+`,
+		},
+	}
+
+	doRenderTests(t, "RenderTerraform", cases)
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awsup
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/aws/smithy-go/middleware"
+)
+
+// APICallCounter counts the AWS API calls made with a context returned by WithAPICallCounter.
+// Retries of a call are not counted separately.
+type APICallCounter struct {
+	calls atomic.Int64
+}
+
+// Calls returns the number of AWS API calls counted so far.
+func (c *APICallCounter) Calls() int64 {
+	return c.calls.Load()
+}
+
+type apiCallCounterKey struct{}
+
+// WithAPICallCounter returns a context that counts the AWS API calls made with it in counter.
+func WithAPICallCounter(ctx context.Context, counter *APICallCounter) context.Context {
+	return context.WithValue(ctx, apiCallCounterKey{}, counter)
+}
+
+// CountAPICall records an AWS API call against the counter of ctx, if any.
+func CountAPICall(ctx context.Context) {
+	if counter, ok := ctx.Value(apiCallCounterKey{}).(*APICallCounter); ok {
+		counter.calls.Add(1)
+	}
+}
+
+// addAPICallCounter adds a middleware counting the calls of the AWS clients, once per operation.
+func addAPICallCounter(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("KopsAPICallCounter", func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+		CountAPICall(ctx)
+		return next.HandleInitialize(ctx, in)
+	}), middleware.Before)
+}
@@ -0,0 +1,36 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awsup
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAPICallCounter(t *testing.T) {
+	// Calls without a counter are not recorded anywhere
+	CountAPICall(context.TODO())
+
+	counter := &APICallCounter{}
+	ctx := WithAPICallCounter(context.TODO(), counter)
+	CountAPICall(ctx)
+	CountAPICall(ctx)
+
+	if got := counter.Calls(); got != 2 {
+		t.Errorf("expected 2 calls, got %d", got)
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package awsup
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -27,14 +28,31 @@ import (
 
 type AWSAPITarget struct {
 	Cloud AWSCloud
+
+	// ctx is the context of the update, so that cancellation and deadlines reach the AWS API calls made when rendering.
+	ctx context.Context
 }
 
 var _ fi.CloudupTarget = &AWSAPITarget{}
 
-func NewAWSAPITarget(cloud AWSCloud) *AWSAPITarget {
+func NewAWSAPITarget(ctx context.Context, cloud AWSCloud) *AWSAPITarget {
 	return &AWSAPITarget{
 		Cloud: cloud,
+		ctx:   ctx,
+	}
+}
+
+// Context returns the context to use for AWS API calls made when rendering.
+func (t *AWSAPITarget) Context() context.Context {
+	if t.ctx == nil {
+		return context.TODO()
 	}
+	return t.ctx
+}
+
+// WithContext returns a copy of the target that uses ctx for AWS API calls.
+func (t *AWSAPITarget) WithContext(ctx context.Context) *AWSAPITarget {
+	return NewAWSAPITarget(ctx, t.Cloud)
 }
 
 func (t *AWSAPITarget) DefaultCheckExisting() bool {
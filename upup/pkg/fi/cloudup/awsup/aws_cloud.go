@@ -59,6 +59,7 @@ import (
 	"k8s.io/kops/pkg/featureflag"
 	identity_aws "k8s.io/kops/pkg/nodeidentity/aws"
 	"k8s.io/kops/pkg/resources/spotinst"
+	"k8s.io/kops/pkg/wellknownports"
 	"k8s.io/kops/upup/pkg/fi"
 	"k8s.io/kops/util/pkg/awsinterfaces"
 )
@@ -316,6 +317,7 @@ func NewAWSCloud(region string, tags map[string]string) (AWSCloud, error) {
 			return c, fmt.Errorf("failed to load default aws config: %w", err)
 		}
 
+		cfg.APIOptions = append(cfg.APIOptions, addAPICallCounter)
 		c.config = cfg
 
 		c.ec2 = ec2.NewFromConfig(cfg)
@@ -2156,46 +2158,151 @@ func findVPCInfo(c AWSCloud, vpcID string) (*fi.VPCInfo, error) {
 	return vpcInfo, nil
 }
 
-func (c *awsCloudImplementation) GetApiIngressStatus(cluster *kops.Cluster) ([]fi.ApiIngressStatus, error) {
-	return getApiIngressStatus(c, cluster)
+func (c *awsCloudImplementation) GetApiIngressStatus(ctx context.Context, cluster *kops.Cluster) ([]fi.ApiIngressStatus, error) {
+	return getApiIngressStatus(ctx, c, cluster)
 }
 
-func getApiIngressStatus(c AWSCloud, cluster *kops.Cluster) ([]fi.ApiIngressStatus, error) {
+func getApiIngressStatus(ctx context.Context, c AWSCloud, cluster *kops.Cluster) ([]fi.ApiIngressStatus, error) {
 	var ingresses []fi.ApiIngressStatus
-	if lbDnsName, err := findDNSName(c, cluster); err != nil {
+	if ingress, err := findAPIIngress(ctx, c, cluster); err != nil {
 		return nil, fmt.Errorf("error finding aws DNSName: %v", err)
-	} else if lbDnsName != "" {
-		ingresses = append(ingresses, fi.ApiIngressStatus{Hostname: lbDnsName})
+	} else if ingress != nil {
+		ingresses = append(ingresses, *ingress)
 	}
 
-	return ingresses, nil
+	return fi.UniqueApiIngressStatus(ingresses), nil
 }
 
-func findDNSName(cloud AWSCloud, cluster *kops.Cluster) (string, error) {
-	ctx := context.TODO()
+// GetApiIngressStatusForScheme returns the API ingress points served by load balancers with the given scheme
+// (internal or internet-facing).
+func GetApiIngressStatusForScheme(ctx context.Context, c AWSCloud, cluster *kops.Cluster, scheme elbv2types.LoadBalancerSchemeEnum) ([]fi.ApiIngressStatus, error) {
+	ingresses, err := c.GetApiIngressStatus(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	return filterApiIngressStatusByScheme(ingresses, scheme), nil
+}
+
+func filterApiIngressStatusByScheme(ingresses []fi.ApiIngressStatus, scheme elbv2types.LoadBalancerSchemeEnum) []fi.ApiIngressStatus {
+	internal := scheme == elbv2types.LoadBalancerSchemeEnumInternal
 
+	var filtered []fi.ApiIngressStatus
+	for _, ingress := range ingresses {
+		if ingress.InternalEndpoint == internal {
+			filtered = append(filtered, ingress)
+		}
+	}
+	return filtered
+}
+
+// findAPIIngress returns the ingress point of the API load balancer, if it exists.
+func findAPIIngress(ctx context.Context, cloud AWSCloud, cluster *kops.Cluster) (*fi.ApiIngressStatus, error) {
 	name := "api." + cluster.Name
 	if cluster.Spec.API.LoadBalancer == nil {
-		return "", nil
+		return nil, nil
 	}
 	if cluster.Spec.API.LoadBalancer.Class == kops.LoadBalancerClassClassic {
 		if lb, err := cloud.FindELBByNameTag(name); err != nil {
-			return "", fmt.Errorf("error looking for AWS ELB: %v", err)
-		} else if lb != nil {
-			return aws.ToString(lb.DNSName), nil
+			return nil, fmt.Errorf("error looking for AWS ELB: %v", err)
+		} else if lb != nil && aws.ToString(lb.DNSName) != "" {
+			ingress := &fi.ApiIngressStatus{
+				Hostname:         aws.ToString(lb.DNSName),
+				InternalEndpoint: aws.ToString(lb.Scheme) == string(elbv2types.LoadBalancerSchemeEnumInternal),
+				LoadBalancerName: aws.ToString(lb.LoadBalancerName),
+			}
+			// As for NLBs, the lowest of the ports forwarding to the API servers is the API port
+			for _, listener := range lb.ListenerDescriptions {
+				if listener.Listener == nil || aws.ToInt32(listener.Listener.InstancePort) != int32(wellknownports.KubeAPIServer) {
+					continue
+				}
+				if ingress.Port == 0 || listener.Listener.LoadBalancerPort < ingress.Port {
+					ingress.Port = listener.Listener.LoadBalancerPort
+				}
+			}
+			return ingress, nil
 		}
 	} else if cluster.Spec.API.LoadBalancer.Class == kops.LoadBalancerClassNetwork {
 		allLoadBalancers, err := ListELBV2LoadBalancers(ctx, cloud)
 		if err != nil {
-			return "", fmt.Errorf("looking for AWS NLB: %w", err)
+			return nil, fmt.Errorf("looking for AWS NLB: %w", err)
 		}
 
 		latest := FindLatestELBV2ByNameTag(allLoadBalancers, name)
-		if latest != nil {
-			return aws.ToString(latest.LoadBalancer.DNSName), nil
+		if latest != nil && aws.ToString(latest.LoadBalancer.DNSName) != "" {
+			port, err := findAPIListenerPort(ctx, cloud, latest.ARN())
+			if err != nil {
+				return nil, err
+			}
+			// The DNS name of a dualstack NLB is itself the dualstack name, resolving to both A and AAAA records
+			return &fi.ApiIngressStatus{
+				Hostname:         aws.ToString(latest.LoadBalancer.DNSName),
+				InternalEndpoint: latest.LoadBalancer.Scheme == elbv2types.LoadBalancerSchemeEnumInternal,
+				IPFamilies:       elbv2IPFamilies(latest.LoadBalancer.IpAddressType, latest.LoadBalancer.Scheme),
+				Port:             port,
+				LoadBalancerName: aws.ToString(latest.LoadBalancer.LoadBalancerName),
+				LoadBalancerARN:  latest.ARN(),
+			}, nil
 		}
 	}
-	return "", nil
+	return nil, nil
+}
+
+// findAPIListenerPort returns the lowest port of the listeners of the NLB that forward to the API servers,
+// or 0 if there is none.
+func findAPIListenerPort(ctx context.Context, cloud AWSCloud, loadBalancerArn string) (int32, error) {
+	listeners, err := ListELBV2Listeners(ctx, cloud, loadBalancerArn)
+	if err != nil {
+		return 0, err
+	}
+
+	targetGroupArns := sets.NewString()
+	for _, listener := range listeners {
+		targetGroupArns.Insert(forwardTargetGroupARNs(listener.DefaultActions)...)
+	}
+	if targetGroupArns.Len() == 0 {
+		return 0, nil
+	}
+	response, err := cloud.ELBV2().DescribeTargetGroups(ctx, &elbv2.DescribeTargetGroupsInput{
+		TargetGroupArns: targetGroupArns.List(),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("describing target groups of load balancer %q: %w", loadBalancerArn, err)
+	}
+	apiTargetGroupArns := sets.NewString()
+	for _, targetGroup := range response.TargetGroups {
+		if aws.ToInt32(targetGroup.Port) == int32(wellknownports.KubeAPIServer) {
+			apiTargetGroupArns.Insert(aws.ToString(targetGroup.TargetGroupArn))
+		}
+	}
+
+	var port int32
+	for _, listener := range listeners {
+		if !apiTargetGroupArns.HasAny(forwardTargetGroupARNs(listener.DefaultActions)...) {
+			continue
+		}
+		if port == 0 || aws.ToInt32(listener.Port) < port {
+			port = aws.ToInt32(listener.Port)
+		}
+	}
+	return port, nil
+}
+
+// elbv2IPFamilies returns the address families served by a load balancer with the given IP address type and scheme.
+// An internet-facing load balancer without public IPv4 addresses only serves IPv6 clients.
+func elbv2IPFamilies(ipAddressType elbv2types.IpAddressType, scheme elbv2types.LoadBalancerSchemeEnum) []string {
+	switch ipAddressType {
+	case elbv2types.IpAddressTypeIpv4:
+		return []string{"ipv4"}
+	case elbv2types.IpAddressTypeDualstack:
+		return []string{"ipv4", "ipv6"}
+	case elbv2types.IpAddressTypeDualstackWithoutPublicIpv4:
+		if scheme == elbv2types.LoadBalancerSchemeEnumInternetFacing {
+			return []string{"ipv6"}
+		}
+		return []string{"ipv4", "ipv6"}
+	default:
+		return nil
+	}
 }
 
 // DefaultInstanceType determines an instance type for the specified cluster & instance group
@@ -0,0 +1,327 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awsup
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"k8s.io/kops/cloudmock/aws/mockelbv2"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/upup/pkg/fi"
+)
+
+func TestFilterApiIngressStatusByScheme(t *testing.T) {
+	ingresses := []fi.ApiIngressStatus{
+		{Hostname: "public-1.example.com"},
+		{Hostname: "internal-1.example.com", InternalEndpoint: true},
+		{IP: "192.0.2.1"},
+		{IP: "10.0.0.1", InternalEndpoint: true},
+	}
+
+	internal := filterApiIngressStatusByScheme(ingresses, elbv2types.LoadBalancerSchemeEnumInternal)
+	expectedInternal := []fi.ApiIngressStatus{
+		{Hostname: "internal-1.example.com", InternalEndpoint: true},
+		{IP: "10.0.0.1", InternalEndpoint: true},
+	}
+	if !reflect.DeepEqual(internal, expectedInternal) {
+		t.Errorf("unexpected internal ingresses: expected %v, got %v", expectedInternal, internal)
+	}
+
+	public := filterApiIngressStatusByScheme(ingresses, elbv2types.LoadBalancerSchemeEnumInternetFacing)
+	expectedPublic := []fi.ApiIngressStatus{
+		{Hostname: "public-1.example.com"},
+		{IP: "192.0.2.1"},
+	}
+	if !reflect.DeepEqual(public, expectedPublic) {
+		t.Errorf("unexpected public ingresses: expected %v, got %v", expectedPublic, public)
+	}
+}
+
+func TestGetApiIngressStatusForScheme(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := BuildMockAWSCloud("us-test-1", "a")
+	elbv2Client := &mockelbv2.MockELBV2{}
+	cloud.MockELBV2 = elbv2Client
+
+	// An internal API load balancer, and an internet-facing one for some other cluster
+	arns := make(map[string]string)
+	for name, scheme := range map[string]elbv2types.LoadBalancerSchemeEnum{
+		"api.internal.example.com": elbv2types.LoadBalancerSchemeEnumInternal,
+		"api.public.example.com":   elbv2types.LoadBalancerSchemeEnumInternetFacing,
+	} {
+		lb, err := elbv2Client.CreateLoadBalancer(ctx, &elbv2.CreateLoadBalancerInput{
+			Name:   aws.String(name),
+			Scheme: scheme,
+			Type:   elbv2types.LoadBalancerTypeEnumNetwork,
+			Tags:   []elbv2types.Tag{{Key: aws.String("Name"), Value: aws.String(name)}},
+		})
+		if err != nil {
+			t.Fatalf("error creating load balancer: %v", err)
+		}
+		arns[name] = aws.ToString(lb.LoadBalancers[0].LoadBalancerArn)
+	}
+
+	grid := []struct {
+		cluster  string
+		scheme   elbv2types.LoadBalancerSchemeEnum
+		expected []fi.ApiIngressStatus
+	}{
+		{
+			cluster: "internal.example.com",
+			scheme:  elbv2types.LoadBalancerSchemeEnumInternal,
+			expected: []fi.ApiIngressStatus{
+				{Hostname: "api.internal.example.com.amazonaws.com", InternalEndpoint: true, LoadBalancerName: "api.internal.example.com", LoadBalancerARN: arns["api.internal.example.com"]},
+			},
+		},
+		{
+			cluster: "internal.example.com",
+			scheme:  elbv2types.LoadBalancerSchemeEnumInternetFacing,
+		},
+		{
+			cluster: "public.example.com",
+			scheme:  elbv2types.LoadBalancerSchemeEnumInternetFacing,
+			expected: []fi.ApiIngressStatus{
+				{Hostname: "api.public.example.com.amazonaws.com", LoadBalancerName: "api.public.example.com", LoadBalancerARN: arns["api.public.example.com"]},
+			},
+		},
+		{
+			cluster: "public.example.com",
+			scheme:  elbv2types.LoadBalancerSchemeEnumInternal,
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.cluster+"-"+string(g.scheme), func(t *testing.T) {
+			cluster := &kops.Cluster{}
+			cluster.Name = g.cluster
+			cluster.Spec.API.LoadBalancer = &kops.LoadBalancerAccessSpec{
+				Class: kops.LoadBalancerClassNetwork,
+			}
+
+			actual, err := GetApiIngressStatusForScheme(ctx, cloud, cluster, g.scheme)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(actual, g.expected) {
+				t.Errorf("unexpected ingresses: expected %v, got %v", g.expected, actual)
+			}
+		})
+	}
+}
+
+func TestGetApiIngressStatusIPFamilies(t *testing.T) {
+	ctx := context.TODO()
+
+	grid := []struct {
+		name          string
+		ipAddressType elbv2types.IpAddressType
+		scheme        elbv2types.LoadBalancerSchemeEnum
+		expected      []string
+	}{
+		{
+			name:          "ipv4",
+			ipAddressType: elbv2types.IpAddressTypeIpv4,
+			scheme:        elbv2types.LoadBalancerSchemeEnumInternetFacing,
+			expected:      []string{"ipv4"},
+		},
+		{
+			name:          "ipv6",
+			ipAddressType: elbv2types.IpAddressTypeDualstackWithoutPublicIpv4,
+			scheme:        elbv2types.LoadBalancerSchemeEnumInternetFacing,
+			expected:      []string{"ipv6"},
+		},
+		{
+			name:          "dualstack",
+			ipAddressType: elbv2types.IpAddressTypeDualstack,
+			scheme:        elbv2types.LoadBalancerSchemeEnumInternetFacing,
+			expected:      []string{"ipv4", "ipv6"},
+		},
+		{
+			name:          "internal dualstack without public ipv4",
+			ipAddressType: elbv2types.IpAddressTypeDualstackWithoutPublicIpv4,
+			scheme:        elbv2types.LoadBalancerSchemeEnumInternal,
+			expected:      []string{"ipv4", "ipv6"},
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			cloud := BuildMockAWSCloud("us-test-1", "a")
+			elbv2Client := &mockelbv2.MockELBV2{}
+			cloud.MockELBV2 = elbv2Client
+
+			lb, err := elbv2Client.CreateLoadBalancer(ctx, &elbv2.CreateLoadBalancerInput{
+				Name:          aws.String("api.example.com"),
+				Scheme:        g.scheme,
+				Type:          elbv2types.LoadBalancerTypeEnumNetwork,
+				IpAddressType: g.ipAddressType,
+				Tags:          []elbv2types.Tag{{Key: aws.String("Name"), Value: aws.String("api.example.com")}},
+			})
+			if err != nil {
+				t.Fatalf("error creating load balancer: %v", err)
+			}
+
+			cluster := &kops.Cluster{}
+			cluster.Name = "example.com"
+			cluster.Spec.API.LoadBalancer = &kops.LoadBalancerAccessSpec{
+				Class: kops.LoadBalancerClassNetwork,
+			}
+
+			actual, err := cloud.GetApiIngressStatus(ctx, cluster)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expected := []fi.ApiIngressStatus{
+				{
+					Hostname:         "api.example.com.amazonaws.com",
+					InternalEndpoint: g.scheme == elbv2types.LoadBalancerSchemeEnumInternal,
+					IPFamilies:       g.expected,
+					LoadBalancerName: "api.example.com",
+					LoadBalancerARN:  aws.ToString(lb.LoadBalancers[0].LoadBalancerArn),
+				},
+			}
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("unexpected ingresses: expected %v, got %v", expected, actual)
+			}
+		})
+	}
+}
+
+func TestGetApiIngressStatusPort(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := BuildMockAWSCloud("us-test-1", "a")
+	elbv2Client := &mockelbv2.MockELBV2{}
+	cloud.MockELBV2 = elbv2Client
+
+	lb, err := elbv2Client.CreateLoadBalancer(ctx, &elbv2.CreateLoadBalancerInput{
+		Name:   aws.String("api.example.com"),
+		Scheme: elbv2types.LoadBalancerSchemeEnumInternetFacing,
+		Type:   elbv2types.LoadBalancerTypeEnumNetwork,
+		Tags:   []elbv2types.Tag{{Key: aws.String("Name"), Value: aws.String("api.example.com")}},
+	})
+	if err != nil {
+		t.Fatalf("error creating load balancer: %v", err)
+	}
+
+	targetGroupArns := make(map[int32]*string)
+	for _, port := range []int32{443, 3988} {
+		tg, err := elbv2Client.CreateTargetGroup(ctx, &elbv2.CreateTargetGroupInput{
+			Name:     aws.String(fmt.Sprintf("tcp-%d", port)),
+			Port:     aws.Int32(port),
+			Protocol: elbv2types.ProtocolEnumTcp,
+		})
+		if err != nil {
+			t.Fatalf("error creating target group: %v", err)
+		}
+		targetGroupArns[port] = tg.TargetGroups[0].TargetGroupArn
+	}
+
+	// The API is served on 6443 and 8443, kops-controller on the lower port 3988
+	for listenerPort, targetPort := range map[int32]int32{3988: 3988, 6443: 443, 8443: 443} {
+		if _, err := elbv2Client.CreateListener(ctx, &elbv2.CreateListenerInput{
+			LoadBalancerArn: lb.LoadBalancers[0].LoadBalancerArn,
+			Port:            aws.Int32(listenerPort),
+			Protocol:        elbv2types.ProtocolEnumTcp,
+			DefaultActions:  []elbv2types.Action{{Type: elbv2types.ActionTypeEnumForward, TargetGroupArn: targetGroupArns[targetPort]}},
+		}); err != nil {
+			t.Fatalf("error creating listener: %v", err)
+		}
+	}
+
+	cluster := &kops.Cluster{}
+	cluster.Name = "example.com"
+	cluster.Spec.API.LoadBalancer = &kops.LoadBalancerAccessSpec{
+		Class: kops.LoadBalancerClassNetwork,
+	}
+
+	actual, err := cloud.GetApiIngressStatus(ctx, cluster)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []fi.ApiIngressStatus{
+		{Hostname: "api.example.com.amazonaws.com", Port: 6443, LoadBalancerName: "api.example.com", LoadBalancerARN: aws.ToString(lb.LoadBalancers[0].LoadBalancerArn)},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected ingresses: expected %v, got %v", expected, actual)
+	}
+}
+
+func TestGetApiIngressStatusAuxiliaryListener(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := BuildMockAWSCloud("us-test-1", "a")
+	elbv2Client := &mockelbv2.MockELBV2{}
+	cloud.MockELBV2 = elbv2Client
+
+	lb, err := elbv2Client.CreateLoadBalancer(ctx, &elbv2.CreateLoadBalancerInput{
+		Name:   aws.String("api.example.com"),
+		Scheme: elbv2types.LoadBalancerSchemeEnumInternetFacing,
+		Type:   elbv2types.LoadBalancerTypeEnumNetwork,
+		Tags:   []elbv2types.Tag{{Key: aws.String("Name"), Value: aws.String("api.example.com")}},
+	})
+	if err != nil {
+		t.Fatalf("error creating load balancer: %v", err)
+	}
+
+	targetGroupArns := make(map[int32]*string)
+	for _, port := range []int32{443, 8443} {
+		tg, err := elbv2Client.CreateTargetGroup(ctx, &elbv2.CreateTargetGroupInput{
+			Name:     aws.String(fmt.Sprintf("tcp-%d", port)),
+			Port:     aws.Int32(port),
+			Protocol: elbv2types.ProtocolEnumTcp,
+		})
+		if err != nil {
+			t.Fatalf("error creating target group: %v", err)
+		}
+		targetGroupArns[port] = tg.TargetGroups[0].TargetGroupArn
+	}
+
+	// The API is served on 443, an auxiliary service on 8443; the listeners are created in reverse order
+	for _, listenerPort := range []int32{8443, 443} {
+		if _, err := elbv2Client.CreateListener(ctx, &elbv2.CreateListenerInput{
+			LoadBalancerArn: lb.LoadBalancers[0].LoadBalancerArn,
+			Port:            aws.Int32(listenerPort),
+			Protocol:        elbv2types.ProtocolEnumTcp,
+			DefaultActions:  []elbv2types.Action{{Type: elbv2types.ActionTypeEnumForward, TargetGroupArn: targetGroupArns[listenerPort]}},
+		}); err != nil {
+			t.Fatalf("error creating listener: %v", err)
+		}
+	}
+
+	cluster := &kops.Cluster{}
+	cluster.Name = "example.com"
+	cluster.Spec.API.LoadBalancer = &kops.LoadBalancerAccessSpec{
+		Class: kops.LoadBalancerClassNetwork,
+	}
+
+	actual, err := cloud.GetApiIngressStatus(ctx, cluster)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []fi.ApiIngressStatus{
+		{Hostname: "api.example.com.amazonaws.com", Port: 443, LoadBalancerName: "api.example.com", LoadBalancerARN: aws.ToString(lb.LoadBalancers[0].LoadBalancerArn)},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected ingresses: expected %v, got %v", expected, actual)
+	}
+}
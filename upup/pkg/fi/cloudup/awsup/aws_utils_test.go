@@ -141,3 +141,31 @@ func Test_GetResourceName32(t *testing.T) {
 		}
 	}
 }
+
+func TestNameForExternalTargetGroup(t *testing.T) {
+	grid := []struct {
+		arn         string
+		expected    string
+		expectError bool
+	}{
+		{arn: "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/my-targets/73e2d6bc24d8a067", expected: "my-targets"},
+		{arn: "arn:aws-us-gov:elasticloadbalancing:us-gov-west-1:123456789012:targetgroup/gov-targets/73e2d6bc24d8a067", expected: "gov-targets"},
+		{arn: "arn:aws-cn:elasticloadbalancing:cn-north-1:123456789012:targetgroup/cn-targets/73e2d6bc24d8a067", expected: "cn-targets"},
+		{arn: "arn:aws-cn:elasticloadbalancing:cn-north-1:123456789012:loadbalancer/net/api/73e2d6bc24d8a067", expectError: true},
+		{arn: "my-targets", expectError: true},
+	}
+	for _, g := range grid {
+		actual, err := NameForExternalTargetGroup(g.arn)
+		if g.expectError {
+			if err == nil {
+				t.Errorf("expected error for %q, got name %q", g.arn, actual)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %q: %v", g.arn, err)
+		} else if actual != g.expected {
+			t.Errorf("expected name %q for %q, got %q", g.expected, g.arn, actual)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awsup
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"k8s.io/kops/pkg/apis/kops"
+)
+
+// LoadBalancerHealthReport correlates the listeners of a load balancer with their target groups and the health of their targets.
+type LoadBalancerHealthReport struct {
+	Name      string
+	ARN       string
+	Listeners []ListenerHealthReport
+}
+
+// ListenerHealthReport is a listener of a load balancer and the target groups it forwards to.
+type ListenerHealthReport struct {
+	ARN          string
+	Port         int32
+	Protocol     string
+	TargetGroups []TargetGroupHealthReport
+}
+
+// TargetGroupHealthReport is a target group and the health of its registered targets.
+type TargetGroupHealthReport struct {
+	Name    string
+	ARN     string
+	Targets []TargetHealthReport
+}
+
+// TargetHealthReport is the health of a target registered with a target group.
+type TargetHealthReport struct {
+	ID   string
+	Port int32
+	// State is the ELBv2 target health state, e.g. healthy, unhealthy or draining.
+	State string
+	// Reason and Description explain why the target is not healthy.
+	Reason      string
+	Description string
+}
+
+// Healthy returns true if every listener forwards to at least one target, and all the targets are healthy.
+func (r *LoadBalancerHealthReport) Healthy() bool {
+	for _, listener := range r.Listeners {
+		targets := 0
+		for _, targetGroup := range listener.TargetGroups {
+			for _, target := range targetGroup.Targets {
+				if target.State != string(elbv2types.TargetHealthStateEnumHealthy) {
+					return false
+				}
+				targets++
+			}
+		}
+		if targets == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// GetTargetGroupHealth returns the health of the targets registered with the target group, sorted by target ID.
+// DescribeTargetHealth is not paginated, a single call returns all the targets of the target group.
+func GetTargetGroupHealth(ctx context.Context, cloud AWSCloud, targetGroupArn string) ([]TargetHealthReport, error) {
+	response, err := cloud.ELBV2().DescribeTargetHealth(ctx, &elbv2.DescribeTargetHealthInput{
+		TargetGroupArn: aws.String(targetGroupArn),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describing health of target group %q: %w", targetGroupArn, err)
+	}
+
+	var targets []TargetHealthReport
+	for _, description := range response.TargetHealthDescriptions {
+		if description.Target == nil {
+			continue
+		}
+		target := TargetHealthReport{
+			ID:   aws.ToString(description.Target.Id),
+			Port: aws.ToInt32(description.Target.Port),
+		}
+		if description.TargetHealth != nil {
+			target.State = string(description.TargetHealth.State)
+			target.Reason = string(description.TargetHealth.Reason)
+			target.Description = aws.ToString(description.TargetHealth.Description)
+		}
+		targets = append(targets, target)
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].ID < targets[j].ID
+	})
+	return targets, nil
+}
+
+// targetGroupHealthyBackoff is the backoff between the polls of WaitForTargetGroupHealthy.
+// Health checks take at least a few intervals to pass, so there is no point in polling more often.
+var targetGroupHealthyBackoff = wait.Backoff{
+	Duration: 2 * time.Second,
+	Factor:   2,
+	Jitter:   0.1,
+	Cap:      30 * time.Second,
+}
+
+// WaitForTargetGroupHealthy polls the health of the targets of the target group, with exponential backoff,
+// until at least minHealthy targets are healthy. It fails once timeout has elapsed, listing the targets that are
+// not healthy and why, or as soon as ctx is cancelled.
+func WaitForTargetGroupHealthy(ctx context.Context, cloud AWSCloud, targetGroupArn string, minHealthy int, timeout time.Duration) error {
+	klog.V(2).Infof("Waiting up to %v for %d healthy targets in target group %q", timeout, minHealthy, targetGroupArn)
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	backoff := targetGroupHealthyBackoff
+	backoff.Steps = math.MaxInt32
+
+	var targets []TargetHealthReport
+	for {
+		var err error
+		targets, err = GetTargetGroupHealth(waitCtx, cloud, targetGroupArn)
+		if err != nil && waitCtx.Err() == nil {
+			return err
+		}
+
+		healthy := 0
+		for _, target := range targets {
+			if target.State == string(elbv2types.TargetHealthStateEnumHealthy) {
+				healthy++
+			}
+		}
+		if err == nil && healthy >= minHealthy {
+			return nil
+		}
+
+		select {
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return fmt.Errorf("waiting for target group %q to become healthy: %w", targetGroupArn, ctx.Err())
+			}
+			return fmt.Errorf("timed out after %v waiting for target group %q to have %d healthy targets, found %d; %s",
+				timeout, targetGroupArn, minHealthy, healthy, describeUnhealthyTargets(targets))
+		case <-time.After(backoff.Step()):
+		}
+	}
+}
+
+// describeUnhealthyTargets lists the targets that are not healthy, with their state and the reason, for error messages.
+func describeUnhealthyTargets(targets []TargetHealthReport) string {
+	var unhealthy []string
+	for _, target := range targets {
+		if target.State == string(elbv2types.TargetHealthStateEnumHealthy) {
+			continue
+		}
+		s := fmt.Sprintf("%s:%d is %s", target.ID, target.Port, target.State)
+		if target.Reason != "" {
+			s += " (" + target.Reason
+			if target.Description != "" {
+				s += ": " + target.Description
+			}
+			s += ")"
+		}
+		unhealthy = append(unhealthy, s)
+	}
+	if len(targets) == 0 {
+		return "no targets are registered"
+	}
+	if len(unhealthy) == 0 {
+		return fmt.Sprintf("all %d registered targets are healthy", len(targets))
+	}
+	return "unhealthy targets: " + strings.Join(unhealthy, ", ")
+}
+
+// BuildAPILoadBalancerHealthReport reports the health of the API Network Load Balancer of the cluster,
+// returning nil if the cluster does not use one or it does not exist.
+func BuildAPILoadBalancerHealthReport(ctx context.Context, cloud AWSCloud, cluster *kops.Cluster) (*LoadBalancerHealthReport, error) {
+	if cluster.Spec.API.LoadBalancer == nil || cluster.Spec.API.LoadBalancer.Class != kops.LoadBalancerClassNetwork {
+		return nil, nil
+	}
+
+	klog.V(2).Infof("Querying AWS for API load balancer health")
+	allLoadBalancers, err := ListELBV2LoadBalancers(ctx, cloud)
+	if err != nil {
+		return nil, fmt.Errorf("looking for AWS NLB: %w", err)
+	}
+	latest := FindLatestELBV2ByNameTag(allLoadBalancers, "api."+cluster.Name)
+	if latest == nil {
+		return nil, nil
+	}
+
+	return BuildELBV2HealthReport(ctx, cloud, latest)
+}
+
+// BuildELBV2HealthReport reports the health of the targets of each listener of the load balancer.
+func BuildELBV2HealthReport(ctx context.Context, cloud AWSCloud, loadBalancer *LoadBalancerInfo) (*LoadBalancerHealthReport, error) {
+	listeners, err := ListELBV2Listeners(ctx, cloud, loadBalancer.ARN())
+	if err != nil {
+		return nil, err
+	}
+
+	targetGroups, err := ListELBV2TargetGroups(ctx, cloud, "", "")
+	if err != nil {
+		return nil, err
+	}
+	targetGroupNames := make(map[string]string)
+	for _, targetGroup := range targetGroups {
+		targetGroupNames[targetGroup.ARN] = aws.ToString(targetGroup.TargetGroup.TargetGroupName)
+	}
+
+	report := &LoadBalancerHealthReport{
+		Name: loadBalancer.NameTag(),
+		ARN:  loadBalancer.ARN(),
+	}
+	// Target groups may be shared between listeners
+	targetGroupReports := make(map[string]TargetGroupHealthReport)
+	for _, listener := range listeners {
+		listenerReport := ListenerHealthReport{
+			ARN:      aws.ToString(listener.ListenerArn),
+			Port:     aws.ToInt32(listener.Port),
+			Protocol: string(listener.Protocol),
+		}
+		for _, targetGroupArn := range forwardTargetGroupARNs(listener.DefaultActions) {
+			targetGroupReport, found := targetGroupReports[targetGroupArn]
+			if !found {
+				targetGroupReport = TargetGroupHealthReport{
+					Name: targetGroupNames[targetGroupArn],
+					ARN:  targetGroupArn,
+				}
+				targets, err := GetTargetGroupHealth(ctx, cloud, targetGroupArn)
+				if err != nil {
+					return nil, err
+				}
+				targetGroupReport.Targets = targets
+				targetGroupReports[targetGroupArn] = targetGroupReport
+			}
+			listenerReport.TargetGroups = append(listenerReport.TargetGroups, targetGroupReport)
+		}
+		report.Listeners = append(report.Listeners, listenerReport)
+	}
+	sort.Slice(report.Listeners, func(i, j int) bool {
+		return report.Listeners[i].Port < report.Listeners[j].Port
+	})
+
+	return report, nil
+}
+
+// forwardTargetGroupARNs returns the target groups of the forward actions among the actions.
+func forwardTargetGroupARNs(actions []elbv2types.Action) []string {
+	var arns []string
+	for _, action := range actions {
+		if action.Type != elbv2types.ActionTypeEnumForward {
+			continue
+		}
+		if action.ForwardConfig != nil && len(action.ForwardConfig.TargetGroups) != 0 {
+			for _, tuple := range action.ForwardConfig.TargetGroups {
+				arns = append(arns, aws.ToString(tuple.TargetGroupArn))
+			}
+		} else if action.TargetGroupArn != nil {
+			arns = append(arns, aws.ToString(action.TargetGroupArn))
+		}
+	}
+	return arns
+}
@@ -0,0 +1,327 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awsup
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/kops/cloudmock/aws/mockelbv2"
+	"k8s.io/kops/pkg/apis/kops"
+)
+
+func TestBuildAPILoadBalancerHealthReport(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := BuildMockAWSCloud("us-test-1", "a")
+	c := &mockelbv2.MockELBV2{}
+	cloud.MockELBV2 = c
+
+	lb, err := c.CreateLoadBalancer(ctx, &elbv2.CreateLoadBalancerInput{
+		Name: aws.String("api-example-com"),
+		Type: elbv2types.LoadBalancerTypeEnumNetwork,
+		Tags: []elbv2types.Tag{{Key: aws.String("Name"), Value: aws.String("api.example.com")}},
+	})
+	if err != nil {
+		t.Fatalf("error creating load balancer: %v", err)
+	}
+	lbARN := aws.ToString(lb.LoadBalancers[0].LoadBalancerArn)
+
+	targetGroupARNs := make(map[string]string)
+	for name, targets := range map[string][]string{
+		"tcp-api":   {"i-2", "i-1"},
+		"tls-api":   {"i-1"},
+		"kops-ctrl": {"i-1"},
+	} {
+		tg, err := c.CreateTargetGroup(ctx, &elbv2.CreateTargetGroupInput{
+			Name:     aws.String(name),
+			Port:     aws.Int32(443),
+			Protocol: elbv2types.ProtocolEnumTcp,
+		})
+		if err != nil {
+			t.Fatalf("error creating target group: %v", err)
+		}
+		arn := aws.ToString(tg.TargetGroups[0].TargetGroupArn)
+		targetGroupARNs[name] = arn
+		for _, id := range targets {
+			if _, err := c.RegisterTargets(ctx, &elbv2.RegisterTargetsInput{
+				TargetGroupArn: aws.String(arn),
+				Targets:        []elbv2types.TargetDescription{{Id: aws.String(id), Port: aws.Int32(443)}},
+			}); err != nil {
+				t.Fatalf("error registering target: %v", err)
+			}
+		}
+	}
+	if err := c.SetTargetHealth(targetGroupARNs["tcp-api"], "i-2", elbv2types.TargetHealth{
+		State:       elbv2types.TargetHealthStateEnumUnhealthy,
+		Reason:      elbv2types.TargetHealthReasonEnumFailedHealthChecks,
+		Description: aws.String("Health checks failed"),
+	}); err != nil {
+		t.Fatalf("error setting target health: %v", err)
+	}
+
+	for port, targetGroups := range map[int32][]string{
+		443:  {"tcp-api"},
+		8443: {"tls-api", "kops-ctrl"},
+	} {
+		action := elbv2types.Action{Type: elbv2types.ActionTypeEnumForward}
+		if len(targetGroups) == 1 {
+			action.TargetGroupArn = aws.String(targetGroupARNs[targetGroups[0]])
+		} else {
+			action.ForwardConfig = &elbv2types.ForwardActionConfig{}
+			for _, name := range targetGroups {
+				action.ForwardConfig.TargetGroups = append(action.ForwardConfig.TargetGroups, elbv2types.TargetGroupTuple{
+					TargetGroupArn: aws.String(targetGroupARNs[name]),
+					Weight:         aws.Int32(50),
+				})
+			}
+		}
+		if _, err := c.CreateListener(ctx, &elbv2.CreateListenerInput{
+			LoadBalancerArn: aws.String(lbARN),
+			Port:            aws.Int32(port),
+			Protocol:        elbv2types.ProtocolEnumTcp,
+			DefaultActions:  []elbv2types.Action{action},
+		}); err != nil {
+			t.Fatalf("error creating listener: %v", err)
+		}
+	}
+
+	cluster := &kops.Cluster{}
+	cluster.Name = "example.com"
+	cluster.Spec.API.LoadBalancer = &kops.LoadBalancerAccessSpec{
+		Class: kops.LoadBalancerClassNetwork,
+	}
+
+	report, err := BuildAPILoadBalancerHealthReport(ctx, cloud, cluster)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report == nil {
+		t.Fatalf("expected a health report")
+	}
+
+	healthy := func(id string) TargetHealthReport {
+		return TargetHealthReport{ID: id, Port: 443, State: "healthy"}
+	}
+	expected := []ListenerHealthReport{
+		{
+			Port:     443,
+			Protocol: "TCP",
+			TargetGroups: []TargetGroupHealthReport{
+				{
+					Name: "tcp-api",
+					ARN:  targetGroupARNs["tcp-api"],
+					Targets: []TargetHealthReport{
+						healthy("i-1"),
+						{ID: "i-2", Port: 443, State: "unhealthy", Reason: "Target.FailedHealthChecks", Description: "Health checks failed"},
+					},
+				},
+			},
+		},
+		{
+			Port:     8443,
+			Protocol: "TCP",
+			TargetGroups: []TargetGroupHealthReport{
+				{Name: "tls-api", ARN: targetGroupARNs["tls-api"], Targets: []TargetHealthReport{healthy("i-1")}},
+				{Name: "kops-ctrl", ARN: targetGroupARNs["kops-ctrl"], Targets: []TargetHealthReport{healthy("i-1")}},
+			},
+		},
+	}
+	if report.Name != "api.example.com" || report.ARN != lbARN {
+		t.Errorf("unexpected load balancer %q %q", report.Name, report.ARN)
+	}
+	for i := range report.Listeners {
+		// Listener ARNs are generated by the mock
+		report.Listeners[i].ARN = ""
+	}
+	if !reflect.DeepEqual(report.Listeners, expected) {
+		t.Errorf("unexpected listeners:\nexpected %+v\ngot      %+v", expected, report.Listeners)
+	}
+	if report.Healthy() {
+		t.Errorf("expected report with an unhealthy target not to be healthy")
+	}
+
+	if err := c.SetTargetHealth(targetGroupARNs["tcp-api"], "i-2", elbv2types.TargetHealth{State: elbv2types.TargetHealthStateEnumHealthy}); err != nil {
+		t.Fatalf("error setting target health: %v", err)
+	}
+	report, err = BuildAPILoadBalancerHealthReport(ctx, cloud, cluster)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !report.Healthy() {
+		t.Errorf("expected report to be healthy once all targets are healthy: %+v", report)
+	}
+}
+
+func TestGetTargetGroupHealth(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := BuildMockAWSCloud("us-test-1", "a")
+	c := &mockelbv2.MockELBV2{}
+	cloud.MockELBV2 = c
+
+	tg, err := c.CreateTargetGroup(ctx, &elbv2.CreateTargetGroupInput{
+		Name:     aws.String("tcp-api"),
+		Port:     aws.Int32(443),
+		Protocol: elbv2types.ProtocolEnumTcp,
+	})
+	if err != nil {
+		t.Fatalf("error creating target group: %v", err)
+	}
+	arn := aws.ToString(tg.TargetGroups[0].TargetGroupArn)
+	for _, id := range []string{"i-3", "i-1", "i-2"} {
+		if _, err := c.RegisterTargets(ctx, &elbv2.RegisterTargetsInput{
+			TargetGroupArn: aws.String(arn),
+			Targets:        []elbv2types.TargetDescription{{Id: aws.String(id), Port: aws.Int32(443)}},
+		}); err != nil {
+			t.Fatalf("error registering target: %v", err)
+		}
+	}
+	if err := c.SetTargetHealth(arn, "i-2", elbv2types.TargetHealth{
+		State:       elbv2types.TargetHealthStateEnumDraining,
+		Reason:      elbv2types.TargetHealthReasonEnumDeregistrationInProgress,
+		Description: aws.String("Target deregistration is in progress"),
+	}); err != nil {
+		t.Fatalf("error setting target health: %v", err)
+	}
+	if err := c.SetTargetHealth(arn, "i-3", elbv2types.TargetHealth{
+		State:  elbv2types.TargetHealthStateEnumUnhealthy,
+		Reason: elbv2types.TargetHealthReasonEnumTimeout,
+	}); err != nil {
+		t.Fatalf("error setting target health: %v", err)
+	}
+
+	targets, err := GetTargetGroupHealth(ctx, cloud, arn)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []TargetHealthReport{
+		{ID: "i-1", Port: 443, State: "healthy"},
+		{ID: "i-2", Port: 443, State: "draining", Reason: "Target.DeregistrationInProgress", Description: "Target deregistration is in progress"},
+		{ID: "i-3", Port: 443, State: "unhealthy", Reason: "Target.Timeout"},
+	}
+	if !reflect.DeepEqual(targets, expected) {
+		t.Errorf("unexpected targets:\nexpected %+v\ngot      %+v", expected, targets)
+	}
+
+	if _, err := GetTargetGroupHealth(ctx, cloud, "arn:aws-test:elasticloadbalancing:us-test-1:000000000000:targetgroup/missing/0"); err == nil {
+		t.Errorf("expected error for a missing target group")
+	}
+}
+
+// healingELBV2 makes all the targets of a target group healthy after a number of DescribeTargetHealth calls.
+type healingELBV2 struct {
+	*mockelbv2.MockELBV2
+
+	healAfter           int
+	describeHealthCalls int
+	unhealthyTargetIDs  []string
+}
+
+func (m *healingELBV2) DescribeTargetHealth(ctx context.Context, request *elbv2.DescribeTargetHealthInput, optFns ...func(*elbv2.Options)) (*elbv2.DescribeTargetHealthOutput, error) {
+	m.describeHealthCalls++
+	if m.describeHealthCalls == m.healAfter {
+		for _, id := range m.unhealthyTargetIDs {
+			if err := m.SetTargetHealth(aws.ToString(request.TargetGroupArn), id, elbv2types.TargetHealth{State: elbv2types.TargetHealthStateEnumHealthy}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return m.MockELBV2.DescribeTargetHealth(ctx, request, optFns...)
+}
+
+func TestWaitForTargetGroupHealthy(t *testing.T) {
+	defer func(backoff wait.Backoff) {
+		targetGroupHealthyBackoff = backoff
+	}(targetGroupHealthyBackoff)
+	targetGroupHealthyBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 2, Cap: 10 * time.Millisecond}
+
+	ctx := context.TODO()
+
+	buildCloud := func(t *testing.T, healAfter int) (*MockAWSCloud, *healingELBV2, string) {
+		cloud := BuildMockAWSCloud("us-test-1", "a")
+		c := &healingELBV2{MockELBV2: &mockelbv2.MockELBV2{}, healAfter: healAfter, unhealthyTargetIDs: []string{"i-1", "i-2"}}
+		cloud.MockELBV2 = c
+
+		tg, err := c.CreateTargetGroup(ctx, &elbv2.CreateTargetGroupInput{
+			Name:     aws.String("tcp-api"),
+			Port:     aws.Int32(443),
+			Protocol: elbv2types.ProtocolEnumTcp,
+		})
+		if err != nil {
+			t.Fatalf("error creating target group: %v", err)
+		}
+		arn := aws.ToString(tg.TargetGroups[0].TargetGroupArn)
+		for _, id := range []string{"i-1", "i-2"} {
+			if _, err := c.RegisterTargets(ctx, &elbv2.RegisterTargetsInput{
+				TargetGroupArn: aws.String(arn),
+				Targets:        []elbv2types.TargetDescription{{Id: aws.String(id), Port: aws.Int32(443)}},
+			}); err != nil {
+				t.Fatalf("error registering target: %v", err)
+			}
+			if err := c.SetTargetHealth(arn, id, elbv2types.TargetHealth{
+				State:       elbv2types.TargetHealthStateEnumInitial,
+				Reason:      elbv2types.TargetHealthReasonEnumRegistrationInProgress,
+				Description: aws.String("Target registration is in progress"),
+			}); err != nil {
+				t.Fatalf("error setting target health: %v", err)
+			}
+		}
+		return cloud, c, arn
+	}
+
+	t.Run("eventually healthy", func(t *testing.T) {
+		cloud, c, arn := buildCloud(t, 3)
+		if err := WaitForTargetGroupHealthy(ctx, cloud, arn, 2, time.Minute); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if c.describeHealthCalls != 3 {
+			t.Errorf("expected 3 polls, got %d", c.describeHealthCalls)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		cloud, _, arn := buildCloud(t, 0)
+		err := WaitForTargetGroupHealthy(ctx, cloud, arn, 1, 50*time.Millisecond)
+		if err == nil {
+			t.Fatalf("expected timeout error")
+		}
+		for _, s := range []string{"timed out", "i-1:443 is initial (Elb.RegistrationInProgress: Target registration is in progress)", "i-2:443"} {
+			if !strings.Contains(err.Error(), s) {
+				t.Errorf("expected error to contain %q, got %v", s, err)
+			}
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		cloud, _, arn := buildCloud(t, 0)
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		err := WaitForTargetGroupHealthy(ctx, cloud, arn, 1, time.Minute)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected cancellation error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,225 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awsup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"k8s.io/klog/v2"
+)
+
+// ELBV2ListenerSpec is the desired configuration of a listener of a load balancer, forwarding to a single target group.
+type ELBV2ListenerSpec struct {
+	Port     int32
+	Protocol elbv2types.ProtocolEnum
+	// CertificateARN is the default certificate of a TLS listener.
+	CertificateARN string
+	// SSLPolicy is the security policy of a TLS listener; if empty, the policy of an existing listener is kept.
+	SSLPolicy      string
+	TargetGroupARN string
+	// Tags are applied to the listener when it is created, in addition to the cluster tags.
+	Tags map[string]string
+}
+
+// ELBV2ListenerModification is a change to an existing listener, applied in place.
+type ELBV2ListenerModification struct {
+	ListenerARN string
+	Spec        ELBV2ListenerSpec
+}
+
+// ELBV2ListenerPlan holds the changes that bring the listeners of a load balancer to the desired set.
+type ELBV2ListenerPlan struct {
+	LoadBalancerARN string
+
+	// Creates are the desired listeners on ports without a listener.
+	Creates []ELBV2ListenerSpec
+	// Modifies are the listeners whose configuration differs from the desired listener on the same port.
+	Modifies []ELBV2ListenerModification
+	// Deletes are the ARNs of the listeners on ports without a desired listener.
+	// Only listeners owned by the cluster and not protected are deleted.
+	Deletes []string
+}
+
+// IsEmpty returns true if the listeners are already in the desired state.
+func (p *ELBV2ListenerPlan) IsEmpty() bool {
+	return len(p.Creates) == 0 && len(p.Modifies) == 0 && len(p.Deletes) == 0
+}
+
+// PlanELBV2Listeners compares the listeners of the load balancer with the desired listeners, describing the listeners once.
+// Each port must have at most one desired listener.
+func PlanELBV2Listeners(ctx context.Context, cloud AWSCloud, loadBalancerArn string, desired []ELBV2ListenerSpec) (*ELBV2ListenerPlan, error) {
+	desiredByPort := make(map[int32]ELBV2ListenerSpec)
+	for _, spec := range desired {
+		if _, found := desiredByPort[spec.Port]; found {
+			return nil, fmt.Errorf("several listeners are desired on port %d of load balancer %q", spec.Port, loadBalancerArn)
+		}
+		desiredByPort[spec.Port] = spec
+	}
+
+	listeners, err := ListELBV2ListenersWithTags(ctx, cloud, loadBalancerArn)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &ELBV2ListenerPlan{LoadBalancerARN: loadBalancerArn}
+	existingPorts := make(map[int32]bool)
+	for _, listener := range listeners {
+		port := aws.ToInt32(listener.Listener.Port)
+		spec, found := desiredByPort[port]
+		if !found || existingPorts[port] {
+			if !MatchesElbV2Tags(cloud.Tags(), listener.Tags) {
+				klog.V(2).Infof("not deleting listener %q of load balancer %q, which is not owned by the cluster", listener.ARN(), loadBalancerArn)
+				continue
+			}
+			if _, protected := FindELBV2Tag(listener.Tags, KopsProtectedTag); protected {
+				klog.Warningf("not deleting protected listener %q of load balancer %q; delete it manually", listener.ARN(), loadBalancerArn)
+				continue
+			}
+			plan.Deletes = append(plan.Deletes, listener.ARN())
+			continue
+		}
+		existingPorts[port] = true
+		if !elbv2ListenerMatchesSpec(listener.Listener, spec) {
+			plan.Modifies = append(plan.Modifies, ELBV2ListenerModification{ListenerARN: listener.ARN(), Spec: spec})
+		}
+	}
+	for _, spec := range desired {
+		if !existingPorts[spec.Port] {
+			plan.Creates = append(plan.Creates, spec)
+		}
+	}
+
+	sort.Strings(plan.Deletes)
+	sort.Slice(plan.Modifies, func(i, j int) bool { return plan.Modifies[i].Spec.Port < plan.Modifies[j].Spec.Port })
+	sort.Slice(plan.Creates, func(i, j int) bool { return plan.Creates[i].Port < plan.Creates[j].Port })
+	return plan, nil
+}
+
+// elbv2ListenerMatchesSpec returns true if the listener is configured as the spec.
+func elbv2ListenerMatchesSpec(listener elbv2types.Listener, spec ELBV2ListenerSpec) bool {
+	if listener.Protocol != spec.Protocol {
+		return false
+	}
+	// DescribeListeners only returns the default certificate
+	certificateARN := ""
+	if len(listener.Certificates) != 0 {
+		certificateARN = aws.ToString(listener.Certificates[0].CertificateArn)
+	}
+	if certificateARN != spec.CertificateARN {
+		return false
+	}
+	if spec.SSLPolicy != "" && aws.ToString(listener.SslPolicy) != spec.SSLPolicy {
+		return false
+	}
+	if len(listener.DefaultActions) != 1 || listener.DefaultActions[0].Type != elbv2types.ActionTypeEnumForward {
+		return false
+	}
+	action := listener.DefaultActions[0]
+	targetGroupARN := aws.ToString(action.TargetGroupArn)
+	if targetGroupARN == "" && action.ForwardConfig != nil && len(action.ForwardConfig.TargetGroups) == 1 {
+		targetGroupARN = aws.ToString(action.ForwardConfig.TargetGroups[0].TargetGroupArn)
+	}
+	return targetGroupARN == spec.TargetGroupARN
+}
+
+// Apply executes the plan: the listeners are modified first, then deleted, so that their ports are free,
+// and finally created.
+func (p *ELBV2ListenerPlan) Apply(ctx context.Context, cloud AWSCloud) error {
+	for _, modify := range p.Modifies {
+		spec := modify.Spec
+		klog.V(2).Infof("Updating listener %q on port %d of load balancer %q", modify.ListenerARN, spec.Port, p.LoadBalancerARN)
+		request := &elbv2.ModifyListenerInput{
+			ListenerArn:    aws.String(modify.ListenerARN),
+			Protocol:       spec.Protocol,
+			DefaultActions: spec.defaultActions(),
+		}
+		if spec.CertificateARN != "" {
+			request.Certificates = []elbv2types.Certificate{{CertificateArn: aws.String(spec.CertificateARN)}}
+		}
+		if spec.SSLPolicy != "" {
+			request.SslPolicy = aws.String(spec.SSLPolicy)
+		}
+		if _, err := cloud.ELBV2().ModifyListener(ctx, request); err != nil {
+			return fmt.Errorf("updating listener %q on port %d of load balancer %q: %w", modify.ListenerARN, spec.Port, p.LoadBalancerARN, err)
+		}
+	}
+
+	for _, listenerArn := range p.Deletes {
+		klog.V(2).Infof("Deleting listener %q of load balancer %q", listenerArn, p.LoadBalancerARN)
+		if _, err := cloud.ELBV2().DeleteListener(ctx, &elbv2.DeleteListenerInput{
+			ListenerArn: aws.String(listenerArn),
+		}); err != nil {
+			return fmt.Errorf("deleting listener %q of load balancer %q: %w", listenerArn, p.LoadBalancerARN, err)
+		}
+	}
+
+	for _, spec := range p.Creates {
+		klog.V(2).Infof("Creating listener on port %d of load balancer %q", spec.Port, p.LoadBalancerARN)
+		tags := make(map[string]string)
+		for k, v := range spec.Tags {
+			tags[k] = v
+		}
+		for k, v := range cloud.Tags() {
+			tags[k] = v
+		}
+		request := &elbv2.CreateListenerInput{
+			LoadBalancerArn: aws.String(p.LoadBalancerARN),
+			Port:            aws.Int32(spec.Port),
+			Protocol:        spec.Protocol,
+			DefaultActions:  spec.defaultActions(),
+			Tags:            ELBv2Tags(tags),
+		}
+		if spec.CertificateARN != "" {
+			request.Certificates = []elbv2types.Certificate{{CertificateArn: aws.String(spec.CertificateARN)}}
+		}
+		if spec.SSLPolicy != "" {
+			request.SslPolicy = aws.String(spec.SSLPolicy)
+		}
+		if _, err := cloud.ELBV2().CreateListener(ctx, request); err != nil {
+			return fmt.Errorf("creating listener on port %d of load balancer %q: %w", spec.Port, p.LoadBalancerARN, err)
+		}
+	}
+	return nil
+}
+
+func (s *ELBV2ListenerSpec) defaultActions() []elbv2types.Action {
+	return []elbv2types.Action{{
+		Type:           elbv2types.ActionTypeEnumForward,
+		TargetGroupArn: aws.String(s.TargetGroupARN),
+	}}
+}
+
+// ReconcileELBV2Listeners brings the listeners of the load balancer to the desired set in one pass,
+// creating, updating and deleting listeners as needed, and returns the changes that were applied.
+func ReconcileELBV2Listeners(ctx context.Context, cloud AWSCloud, loadBalancerArn string, desired []ELBV2ListenerSpec) (*ELBV2ListenerPlan, error) {
+	plan, err := PlanELBV2Listeners(ctx, cloud, loadBalancerArn, desired)
+	if err != nil {
+		return nil, err
+	}
+	if plan.IsEmpty() {
+		return plan, nil
+	}
+	if err := plan.Apply(ctx, cloud); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awsup
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"k8s.io/kops/cloudmock/aws/mockelbv2"
+)
+
+// describeListenersCountingELBV2 counts the DescribeListeners calls.
+type describeListenersCountingELBV2 struct {
+	*mockelbv2.MockELBV2
+
+	describeListenersCalls int
+}
+
+func (m *describeListenersCountingELBV2) DescribeListeners(ctx context.Context, request *elbv2.DescribeListenersInput, optFns ...func(*elbv2.Options)) (*elbv2.DescribeListenersOutput, error) {
+	m.describeListenersCalls++
+	return m.MockELBV2.DescribeListeners(ctx, request, optFns...)
+}
+
+func TestReconcileELBV2Listeners(t *testing.T) {
+	ctx := context.TODO()
+
+	apiCertificateARN := "arn:aws-test:acm:us-test-1:123456789012:certificate/api"
+	otherCertificateARN := "arn:aws-test:acm:us-test-1:123456789012:certificate/other"
+	clusterTags := map[string]string{"KubernetesCluster": "example.com"}
+
+	// The existing listeners: 443 (TLS), 8443 (TCP), 9443 (TCP, protected) and 3000 (TCP, not owned by the cluster)
+	existing := []ELBV2ListenerSpec{
+		{Port: 443, Protocol: elbv2types.ProtocolEnumTls, CertificateARN: apiCertificateARN, SSLPolicy: "ELBSecurityPolicy-TLS13-1-2-2021-06", TargetGroupARN: "tg-tls", Tags: clusterTags},
+		{Port: 8443, Protocol: elbv2types.ProtocolEnumTcp, TargetGroupARN: "tg-tcp", Tags: clusterTags},
+		{Port: 9443, Protocol: elbv2types.ProtocolEnumTcp, TargetGroupARN: "tg-tcp", Tags: map[string]string{"KubernetesCluster": "example.com", KopsProtectedTag: "true"}},
+		{Port: 3000, Protocol: elbv2types.ProtocolEnumTcp, TargetGroupARN: "tg-other"},
+	}
+	unchanged := existing[:2]
+
+	grid := []struct {
+		name            string
+		desired         []ELBV2ListenerSpec
+		expectedCreates []int32
+		expectedModify  []int32
+		expectedDeletes []int32
+		expectedError   string
+		// expectedPorts are the ports of the listeners after reconciling
+		expectedPorts []int32
+	}{
+		{
+			name:          "unchanged",
+			desired:       unchanged,
+			expectedPorts: []int32{443, 3000, 8443, 9443},
+		},
+		{
+			name:            "add one",
+			desired:         append(append([]ELBV2ListenerSpec{}, unchanged...), ELBV2ListenerSpec{Port: 3988, Protocol: elbv2types.ProtocolEnumTcp, TargetGroupARN: "tg-kops-controller"}),
+			expectedCreates: []int32{3988},
+			expectedPorts:   []int32{443, 3000, 3988, 8443, 9443},
+		},
+		{
+			name:            "remove one",
+			desired:         unchanged[:1],
+			expectedDeletes: []int32{8443},
+			expectedPorts:   []int32{443, 3000, 9443},
+		},
+		{
+			name: "modify one",
+			desired: []ELBV2ListenerSpec{
+				{Port: 443, Protocol: elbv2types.ProtocolEnumTls, CertificateARN: otherCertificateARN, SSLPolicy: "ELBSecurityPolicy-TLS13-1-3-2021-06", TargetGroupARN: "tg-tls"},
+				unchanged[1],
+			},
+			expectedModify: []int32{443},
+			expectedPorts:  []int32{443, 3000, 8443, 9443},
+		},
+		{
+			name: "duplicate port",
+			desired: []ELBV2ListenerSpec{
+				unchanged[0],
+				{Port: 443, Protocol: elbv2types.ProtocolEnumTcp, TargetGroupARN: "tg-tcp"},
+			},
+			expectedError: "several listeners are desired on port 443",
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			mockCloud := BuildMockAWSCloud("us-test-1", "a")
+			c := &describeListenersCountingELBV2{MockELBV2: &mockelbv2.MockELBV2{}}
+			mockCloud.MockELBV2 = c
+			cloud := mockCloud.WithTags(clusterTags)
+
+			lb, err := c.CreateLoadBalancer(ctx, &elbv2.CreateLoadBalancerInput{
+				Name: aws.String("api-example-com"),
+				Type: elbv2types.LoadBalancerTypeEnumNetwork,
+			})
+			if err != nil {
+				t.Fatalf("error creating load balancer: %v", err)
+			}
+			lbARN := aws.ToString(lb.LoadBalancers[0].LoadBalancerArn)
+			portByARN := make(map[string]int32)
+			for _, spec := range existing {
+				request := &elbv2.CreateListenerInput{
+					LoadBalancerArn: aws.String(lbARN),
+					Port:            aws.Int32(spec.Port),
+					Protocol:        spec.Protocol,
+					DefaultActions:  spec.defaultActions(),
+					Tags:            ELBv2Tags(spec.Tags),
+				}
+				if spec.CertificateARN != "" {
+					request.Certificates = []elbv2types.Certificate{{CertificateArn: aws.String(spec.CertificateARN)}}
+					request.SslPolicy = aws.String(spec.SSLPolicy)
+				}
+				response, err := c.CreateListener(ctx, request)
+				if err != nil {
+					t.Fatalf("error creating listener: %v", err)
+				}
+				portByARN[aws.ToString(response.Listeners[0].ListenerArn)] = spec.Port
+			}
+			c.describeListenersCalls = 0
+
+			plan, err := ReconcileELBV2Listeners(ctx, cloud, lbARN, g.desired)
+			if g.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), g.expectedError) {
+					t.Fatalf("expected error containing %q, got %v", g.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if c.describeListenersCalls != 1 {
+				t.Errorf("expected the listeners to be described once, got %d DescribeListeners calls", c.describeListenersCalls)
+			}
+
+			var creates, modifies, deletes []int32
+			for _, spec := range plan.Creates {
+				creates = append(creates, spec.Port)
+			}
+			for _, modify := range plan.Modifies {
+				modifies = append(modifies, modify.Spec.Port)
+			}
+			for _, arn := range plan.Deletes {
+				deletes = append(deletes, portByARN[arn])
+			}
+			if !reflect.DeepEqual(creates, g.expectedCreates) || !reflect.DeepEqual(modifies, g.expectedModify) || !reflect.DeepEqual(deletes, g.expectedDeletes) {
+				t.Errorf("expected creates %v, modifies %v and deletes %v, got %v, %v and %v", g.expectedCreates, g.expectedModify, g.expectedDeletes, creates, modifies, deletes)
+			}
+
+			listeners, err := ListELBV2Listeners(ctx, cloud, lbARN)
+			if err != nil {
+				t.Fatalf("error listing listeners: %v", err)
+			}
+			var ports []int32
+			for _, listener := range listeners {
+				ports = append(ports, aws.ToInt32(listener.Port))
+			}
+			sort.Slice(ports, func(i, j int) bool { return ports[i] < ports[j] })
+			if !reflect.DeepEqual(ports, g.expectedPorts) {
+				t.Errorf("expected listeners on ports %v, got %v", g.expectedPorts, ports)
+			}
+
+			// Reconciling again is a no-op
+			plan, err = PlanELBV2Listeners(ctx, cloud, lbARN, g.desired)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !plan.IsEmpty() {
+				t.Errorf("expected no changes after reconciling, got %+v", plan)
+			}
+		})
+	}
+}
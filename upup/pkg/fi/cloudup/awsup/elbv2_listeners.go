@@ -0,0 +1,524 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awsup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"k8s.io/klog/v2"
+)
+
+// ErrCertificateNotFound is returned by ValidateListenerCertificate when the certificate does not exist.
+var ErrCertificateNotFound = errors.New("certificate not found")
+
+// tlsVersions lists the TLS protocol versions reported by ELBV2 security policies, from oldest to newest.
+var tlsVersions = []string{"SSLv3", "TLSv1", "TLSv1.1", "TLSv1.2", "TLSv1.3"}
+
+// sslPolicyNameTLSVersion matches the minimum TLS version in the names of the predefined security policies,
+// e.g. ELBSecurityPolicy-TLS13-1-2-2021-06 or ELBSecurityPolicy-FS-1-2-Res-2020-10.
+var sslPolicyNameTLSVersion = regexp.MustCompile(`-(?:TLS13|TLS|FS)-1-([1-3])(?:-|$)`)
+
+// DefaultNetworkLoadBalancerSSLPolicy is the security policy of TLS listeners that do not set a policy,
+// instead of the oldest policy that AWS would otherwise apply.
+const DefaultNetworkLoadBalancerSSLPolicy = "ELBSecurityPolicy-TLS13-1-2-2021-06"
+
+// NetworkLoadBalancerSSLPolicies lists the names of the predefined security policies that can be used by TLS listeners
+// of network load balancers. Not every policy is available in every region; see ListELBV2SSLPolicies.
+var NetworkLoadBalancerSSLPolicies = []string{
+	"ELBSecurityPolicy-TLS13-1-3-2021-06",
+	"ELBSecurityPolicy-TLS13-1-2-2021-06",
+	"ELBSecurityPolicy-TLS13-1-2-Res-2021-06",
+	"ELBSecurityPolicy-TLS13-1-2-Ext1-2021-06",
+	"ELBSecurityPolicy-TLS13-1-2-Ext2-2021-06",
+	"ELBSecurityPolicy-TLS13-1-1-2021-06",
+	"ELBSecurityPolicy-TLS13-1-0-2021-06",
+	"ELBSecurityPolicy-TLS13-1-3-FIPS-2023-04",
+	"ELBSecurityPolicy-TLS13-1-2-FIPS-2023-04",
+	"ELBSecurityPolicy-TLS13-1-2-Res-FIPS-2023-04",
+	"ELBSecurityPolicy-TLS13-1-2-Ext0-FIPS-2023-04",
+	"ELBSecurityPolicy-TLS13-1-2-Ext1-FIPS-2023-04",
+	"ELBSecurityPolicy-TLS13-1-2-Ext2-FIPS-2023-04",
+	"ELBSecurityPolicy-TLS13-1-1-FIPS-2023-04",
+	"ELBSecurityPolicy-TLS13-1-0-FIPS-2023-04",
+	"ELBSecurityPolicy-FS-1-2-Res-2020-10",
+	"ELBSecurityPolicy-FS-1-2-Res-2019-08",
+	"ELBSecurityPolicy-FS-1-2-2019-08",
+	"ELBSecurityPolicy-FS-1-1-2019-08",
+	"ELBSecurityPolicy-FS-2018-06",
+	"ELBSecurityPolicy-TLS-1-2-Ext-2018-06",
+	"ELBSecurityPolicy-TLS-1-2-2017-01",
+	"ELBSecurityPolicy-TLS-1-1-2017-01",
+	"ELBSecurityPolicy-TLS-1-0-2015-04",
+	"ELBSecurityPolicy-2016-08",
+	"ELBSecurityPolicy-2015-05",
+}
+
+// ListELBV2Listeners returns all the listeners of the load balancer with the given ARN.
+func ListELBV2Listeners(ctx context.Context, cloud AWSCloud, loadBalancerArn string) ([]elbv2types.Listener, error) {
+	klog.V(2).Infof("Listing listeners for load balancer %q", loadBalancerArn)
+
+	request := &elbv2.DescribeListenersInput{
+		LoadBalancerArn: &loadBalancerArn,
+	}
+
+	var listeners []elbv2types.Listener
+	paginator := elbv2.NewDescribeListenersPaginator(cloud.ELBV2(), request)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing listeners for load balancer %q: %w", loadBalancerArn, err)
+		}
+		listeners = append(listeners, page.Listeners...)
+	}
+	return listeners, nil
+}
+
+// ListELBV2ListenerCertificates returns all the certificates of the listener, including the default certificate.
+func ListELBV2ListenerCertificates(ctx context.Context, cloud AWSCloud, listenerArn string) ([]elbv2types.Certificate, error) {
+	request := &elbv2.DescribeListenerCertificatesInput{
+		ListenerArn: aws.String(listenerArn),
+	}
+
+	var certificates []elbv2types.Certificate
+	for {
+		response, err := cloud.ELBV2().DescribeListenerCertificates(ctx, request)
+		if err != nil {
+			return nil, fmt.Errorf("describing certificates of listener %q: %w", listenerArn, err)
+		}
+		certificates = append(certificates, response.Certificates...)
+		if aws.ToString(response.NextMarker) == "" {
+			return certificates, nil
+		}
+		request.Marker = response.NextMarker
+	}
+}
+
+// FindELBV2SSLPolicyMinimumTLSVersion returns the lowest TLS protocol version accepted by the named security policy.
+func FindELBV2SSLPolicyMinimumTLSVersion(ctx context.Context, cloud AWSCloud, policyName string) (string, error) {
+	response, err := cloud.ELBV2().DescribeSSLPolicies(ctx, &elbv2.DescribeSSLPoliciesInput{
+		Names: []string{policyName},
+	})
+	if err != nil {
+		return "", fmt.Errorf("describing SSL policy %q: %w", policyName, err)
+	}
+	if len(response.SslPolicies) != 1 {
+		return "", fmt.Errorf("found %d SSL policies named %q, expected 1", len(response.SslPolicies), policyName)
+	}
+	return MinimumTLSVersion(response.SslPolicies[0].SslProtocols), nil
+}
+
+// ListELBV2SSLPolicies returns the security policies for network load balancers that are available in the region.
+func ListELBV2SSLPolicies(ctx context.Context, cloud AWSCloud) ([]elbv2types.SslPolicy, error) {
+	request := &elbv2.DescribeSSLPoliciesInput{
+		LoadBalancerType: elbv2types.LoadBalancerTypeEnumNetwork,
+	}
+
+	var policies []elbv2types.SslPolicy
+	for {
+		response, err := cloud.ELBV2().DescribeSSLPolicies(ctx, request)
+		if err != nil {
+			return nil, fmt.Errorf("listing SSL policies: %w", err)
+		}
+		policies = append(policies, response.SslPolicies...)
+		if aws.ToString(response.NextMarker) == "" {
+			return policies, nil
+		}
+		request.Marker = response.NextMarker
+	}
+}
+
+// SSLPolicyNameMinimumTLSVersion infers the minimum TLS version of a predefined security policy from its name,
+// for policies that cannot be described because they are not available in the region. It returns "" if unknown.
+func SSLPolicyNameMinimumTLSVersion(name string) string {
+	if match := sslPolicyNameTLSVersion.FindStringSubmatch(name); match != nil {
+		return "TLSv1." + match[1]
+	}
+	if strings.HasPrefix(name, "ELBSecurityPolicy-20") {
+		// The original policies, e.g. ELBSecurityPolicy-2016-08, accept TLSv1
+		return "TLSv1"
+	}
+	return ""
+}
+
+// ClosestELBV2SSLPolicies returns the names of the available policies that are closest to the requested policy, best first.
+// Policies accepting an older TLS version than the requested policy are never considered equivalent.
+// Otherwise policies are ranked by how close their minimum TLS version is, and then by the similarity of their names.
+func ClosestELBV2SSLPolicies(requested string, available []elbv2types.SslPolicy) []string {
+	requestedVersion := tlsVersionIndex(SSLPolicyNameMinimumTLSVersion(requested))
+
+	type candidate struct {
+		name     string
+		distance int
+		prefix   int
+	}
+	var candidates []candidate
+	for _, policy := range available {
+		name := aws.ToString(policy.Name)
+		version := tlsVersionIndex(MinimumTLSVersion(policy.SslProtocols))
+		distance := 0
+		if requestedVersion >= 0 {
+			if version < requestedVersion {
+				continue
+			}
+			distance = version - requestedVersion
+		}
+		prefix := 0
+		for prefix < len(name) && prefix < len(requested) && name[prefix] == requested[prefix] {
+			prefix++
+		}
+		candidates = append(candidates, candidate{name: name, distance: distance, prefix: prefix})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		if candidates[i].prefix != candidates[j].prefix {
+			return candidates[i].prefix > candidates[j].prefix
+		}
+		return candidates[i].name < candidates[j].name
+	})
+
+	var names []string
+	for _, c := range candidates {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+// ClosestNetworkLoadBalancerSSLPolicy returns the name in NetworkLoadBalancerSSLPolicies with the smallest edit distance
+// to the given name, to suggest a correction for a mistyped policy.
+func ClosestNetworkLoadBalancerSSLPolicy(name string) string {
+	closest := ""
+	closestDistance := -1
+	for _, policy := range NetworkLoadBalancerSSLPolicies {
+		if distance := editDistance(name, policy); closestDistance < 0 || distance < closestDistance {
+			closest = policy
+			closestDistance = distance
+		}
+	}
+	return closest
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// tlsVersionIndex returns the position of the version in tlsVersions, or -1 if it is not recognized.
+func tlsVersionIndex(version string) int {
+	for i, v := range tlsVersions {
+		if v == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// MinimumTLSVersion returns the oldest of the given TLS protocol versions, or "" if none are recognized.
+func MinimumTLSVersion(protocols []string) string {
+	for _, version := range tlsVersions {
+		for _, protocol := range protocols {
+			if protocol == version {
+				return version
+			}
+		}
+	}
+	return ""
+}
+
+// ListenerInfo is a listener of an ELBV2 load balancer, with its tags.
+type ListenerInfo struct {
+	Listener elbv2types.Listener
+	Tags     []elbv2types.Tag
+}
+
+// ARN returns the ARN of the listener.
+func (i *ListenerInfo) ARN() string {
+	return aws.ToString(i.Listener.ListenerArn)
+}
+
+// ListELBV2ListenersWithTags returns all the listeners of the load balancer with the given ARN, along with their tags.
+func ListELBV2ListenersWithTags(ctx context.Context, cloud AWSCloud, loadBalancerArn string) ([]*ListenerInfo, error) {
+	listeners, err := ListELBV2Listeners(ctx, cloud, loadBalancerArn)
+	if err != nil {
+		return nil, err
+	}
+
+	byARN := make(map[string]*ListenerInfo)
+	var results []*ListenerInfo
+	for _, listener := range listeners {
+		info := &ListenerInfo{Listener: listener}
+		byARN[info.ARN()] = info
+		results = append(results, info)
+	}
+
+	var arns []string
+	for _, listener := range listeners {
+		arns = append(arns, aws.ToString(listener.ListenerArn))
+	}
+	tagDescriptions, err := describeELBV2TagsInBatches(ctx, cloud, arns)
+	if err != nil {
+		return nil, fmt.Errorf("describing tags for listeners of load balancer %q: %w", loadBalancerArn, err)
+	}
+	for _, tagDescription := range tagDescriptions {
+		if info := byARN[aws.ToString(tagDescription.ResourceArn)]; info != nil {
+			info.Tags = append(info.Tags, tagDescription.Tags...)
+		}
+	}
+	return results, nil
+}
+
+// FindProtectedELBV2Listeners returns the ARNs of the listeners of the load balancer that are tagged with KopsProtectedTag.
+func FindProtectedELBV2Listeners(ctx context.Context, cloud AWSCloud, loadBalancerArn string) ([]string, error) {
+	listeners, err := ListELBV2ListenersWithTags(ctx, cloud, loadBalancerArn)
+	if err != nil {
+		return nil, err
+	}
+
+	var protected []string
+	for _, listener := range listeners {
+		if _, found := FindELBV2Tag(listener.Tags, KopsProtectedTag); found {
+			protected = append(protected, listener.ARN())
+		}
+	}
+	return protected, nil
+}
+
+// RotateELBV2ListenerCertificate replaces the default certificate of all the listeners of the load balancer
+// that use oldCertificateARN with newCertificateARN, in order of port.
+// The rotation is all or nothing: if a listener cannot be updated, the listeners already updated are rolled back,
+// so that listeners sharing a certificate are never left on different certificates.
+// The rotated listeners are tagged with the time of the rotation, and we then wait for settleTime, so that
+// clients resuming TLS sessions established with the old certificate have fallen back to full handshakes
+// before any further change.
+// If policyChange is set, the SSL policy of that listener is changed in the same request as its certificate,
+// so that the listener never serves the new certificate with the old policy, or the old certificate with the new policy.
+func RotateELBV2ListenerCertificate(ctx context.Context, cloud AWSCloud, loadBalancerArn string, oldCertificateARN string, newCertificateARN string, policyChange *ELBV2ListenerSSLPolicyChange, settleTime time.Duration) error {
+	listeners, err := ListELBV2Listeners(ctx, cloud, loadBalancerArn)
+	if err != nil {
+		return err
+	}
+	sort.Slice(listeners, func(i, j int) bool {
+		return aws.ToInt32(listeners[i].Port) < aws.ToInt32(listeners[j].Port)
+	})
+
+	var rotated []string
+	for _, listener := range listeners {
+		if len(listener.Certificates) == 0 || aws.ToString(listener.Certificates[0].CertificateArn) != oldCertificateARN {
+			continue
+		}
+		listenerArn := aws.ToString(listener.ListenerArn)
+		klog.V(2).Infof("Rotating certificate of listener %q from %q to %q", listenerArn, oldCertificateARN, newCertificateARN)
+		if err := setELBV2ListenerCertificate(ctx, cloud, listenerArn, newCertificateARN, policyChange.newPolicy(listenerArn)); err != nil {
+			for _, rotatedArn := range rotated {
+				if rollbackErr := setELBV2ListenerCertificate(ctx, cloud, rotatedArn, oldCertificateARN, policyChange.oldPolicy(rotatedArn)); rollbackErr != nil {
+					klog.Warningf("failed to roll back certificate of listener %q to %q: %v", rotatedArn, oldCertificateARN, rollbackErr)
+				}
+			}
+			return err
+		}
+		rotated = append(rotated, listenerArn)
+	}
+	if len(rotated) == 0 {
+		return nil
+	}
+
+	if _, err := cloud.ELBV2().AddTags(ctx, &elbv2.AddTagsInput{
+		ResourceArns: rotated,
+		Tags:         ELBv2Tags(map[string]string{KopsCertificateRotatedTag: time.Now().UTC().Format(time.RFC3339)}),
+	}); err != nil {
+		klog.Warningf("failed to record certificate rotation on listeners %v: %v", rotated, err)
+	}
+
+	if settleTime > 0 {
+		klog.Infof("Waiting %v for TLS sessions to settle after rotating the certificate of %d listeners", settleTime, len(rotated))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(settleTime):
+		}
+	}
+	return nil
+}
+
+// ELBV2ListenerSSLPolicyChange is a change of the SSL policy of a listener,
+// applied together with the rotation of its certificate.
+type ELBV2ListenerSSLPolicyChange struct {
+	ListenerARN string
+	OldPolicy   string
+	NewPolicy   string
+}
+
+// newPolicy returns the SSL policy to set on the listener together with the new certificate, or "" to leave it unchanged.
+func (c *ELBV2ListenerSSLPolicyChange) newPolicy(listenerArn string) string {
+	if c == nil || c.ListenerARN != listenerArn {
+		return ""
+	}
+	return c.NewPolicy
+}
+
+// oldPolicy returns the SSL policy to restore on the listener together with the old certificate, or "" to leave it unchanged.
+func (c *ELBV2ListenerSSLPolicyChange) oldPolicy(listenerArn string) string {
+	if c == nil || c.ListenerARN != listenerArn {
+		return ""
+	}
+	return c.OldPolicy
+}
+
+// setELBV2ListenerCertificate sets the default certificate of the listener, and its SSL policy if sslPolicy is not empty.
+func setELBV2ListenerCertificate(ctx context.Context, cloud AWSCloud, listenerArn string, certificateARN string, sslPolicy string) error {
+	request := &elbv2.ModifyListenerInput{
+		ListenerArn:  aws.String(listenerArn),
+		Certificates: []elbv2types.Certificate{{CertificateArn: aws.String(certificateARN)}},
+	}
+	if sslPolicy != "" {
+		request.SslPolicy = aws.String(sslPolicy)
+	}
+	if _, err := cloud.ELBV2().ModifyListener(ctx, request); err != nil {
+		return fmt.Errorf("setting certificate of listener %q to %q: %w", listenerArn, certificateARN, err)
+	}
+	return nil
+}
+
+// regionPartitions are the partitions of regions, by region prefix; other regions are in the aws partition.
+var regionPartitions = map[string]string{
+	"cn-":      "aws-cn",
+	"us-gov-":  "aws-us-gov",
+	"us-iso-":  "aws-iso",
+	"us-isob-": "aws-iso-b",
+}
+
+// regionPartition returns the partition of the region, e.g. aws-cn for cn-north-1.
+func regionPartition(region string) string {
+	for prefix, partition := range regionPartitions {
+		if strings.HasPrefix(region, prefix) {
+			return partition
+		}
+	}
+	return "aws"
+}
+
+// isKnownPartition returns true if the partition is one of the regionPartitions, or the aws partition.
+func isKnownPartition(partition string) bool {
+	if partition == "aws" {
+		return true
+	}
+	for _, p := range regionPartitions {
+		if p == partition {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateListenerCertificate checks that the certificate can be used by a listener of a load balancer in the region of the cloud.
+// The certificate must be in the partition of the region, unless kops does not know the partition. IAM server certificates are global, and are looked up by name;
+// ACM certificates must be in the region of the load balancer.
+// ACM certificates are not looked up, as kops does not use the ACM API; CreateListener reports them as CertificateNotFound.
+func ValidateListenerCertificate(ctx context.Context, cloud AWSCloud, certificateARN string) error {
+	parsed, err := arn.Parse(certificateARN)
+	if err != nil {
+		return fmt.Errorf("certificate %q is not a valid ARN: %w", certificateARN, err)
+	}
+	if partition := regionPartition(cloud.Region()); isKnownPartition(parsed.Partition) && parsed.Partition != partition {
+		return fmt.Errorf("certificate %q is in partition %q, but load balancers in region %q can only use certificates in partition %q", certificateARN, parsed.Partition, cloud.Region(), partition)
+	}
+	switch parsed.Service {
+	case "acm":
+		if parsed.Region != cloud.Region() {
+			return fmt.Errorf("certificate %q is in region %q, but ACM certificates can only be used by load balancers in the same region (%q)", certificateARN, parsed.Region, cloud.Region())
+		}
+		return nil
+
+	case "iam":
+		if _, err := getIAMServerCertificate(ctx, cloud, certificateARN, parsed); err != nil {
+			return err
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("certificate %q must be an ACM certificate or an IAM server certificate", certificateARN)
+	}
+}
+
+// FindListenerCertificateNotAfter returns when the certificate of a listener expires, in RFC3339 format.
+// ACM renews certificates in place, keeping their ARN, so the expiration is how a renewal can be noticed.
+// It is only known for IAM server certificates; "" is returned for ACM certificates, as kops does not use the ACM API.
+func FindListenerCertificateNotAfter(ctx context.Context, cloud AWSCloud, certificateARN string) (string, error) {
+	parsed, err := arn.Parse(certificateARN)
+	if err != nil {
+		return "", fmt.Errorf("certificate %q is not a valid ARN: %w", certificateARN, err)
+	}
+	if parsed.Service != "iam" {
+		return "", nil
+	}
+	certificate, err := getIAMServerCertificate(ctx, cloud, certificateARN, parsed)
+	if err != nil {
+		return "", err
+	}
+	if certificate.ServerCertificateMetadata == nil || certificate.ServerCertificateMetadata.Expiration == nil {
+		return "", nil
+	}
+	return certificate.ServerCertificateMetadata.Expiration.UTC().Format(time.RFC3339), nil
+}
+
+// getIAMServerCertificate looks up an IAM server certificate by the name in its ARN.
+func getIAMServerCertificate(ctx context.Context, cloud AWSCloud, certificateARN string, parsed arn.ARN) (*iamtypes.ServerCertificate, error) {
+	path, found := strings.CutPrefix(parsed.Resource, "server-certificate/")
+	if !found {
+		return nil, fmt.Errorf("certificate %q is not an IAM server certificate", certificateARN)
+	}
+	// The name is the last component of the path of the certificate
+	name := path[strings.LastIndex(path, "/")+1:]
+	response, err := cloud.IAM().GetServerCertificate(ctx, &iam.GetServerCertificateInput{ServerCertificateName: aws.String(name)})
+	if err != nil {
+		if AWSErrorCode(err) == "NoSuchEntity" {
+			return nil, fmt.Errorf("IAM server certificate %q: %w", certificateARN, ErrCertificateNotFound)
+		}
+		return nil, fmt.Errorf("getting IAM server certificate %q: %w", name, err)
+	}
+	return response.ServerCertificate, nil
+}
@@ -0,0 +1,315 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awsup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"k8s.io/kops/cloudmock/aws/mockelbv2"
+	"k8s.io/kops/cloudmock/aws/mockiam"
+)
+
+func TestSSLPolicyNameMinimumTLSVersion(t *testing.T) {
+	grid := map[string]string{
+		"ELBSecurityPolicy-2016-08":                "TLSv1",
+		"ELBSecurityPolicy-TLS-1-1-2017-01":        "TLSv1.1",
+		"ELBSecurityPolicy-TLS-1-2-Ext-2018-06":    "TLSv1.2",
+		"ELBSecurityPolicy-FS-1-2-Res-2020-10":     "TLSv1.2",
+		"ELBSecurityPolicy-TLS13-1-2-2021-06":      "TLSv1.2",
+		"ELBSecurityPolicy-TLS13-1-3-2021-06":      "TLSv1.3",
+		"ELBSecurityPolicy-TLS13-1-3-FIPS-2023-04": "TLSv1.3",
+		"ELBSecurityPolicy-FS-2018-06":             "",
+		"my-custom-policy":                         "",
+	}
+	for name, expected := range grid {
+		if actual := SSLPolicyNameMinimumTLSVersion(name); actual != expected {
+			t.Errorf("unexpected minimum TLS version for %q: expected %q, got %q", name, expected, actual)
+		}
+	}
+}
+
+func TestClosestELBV2SSLPolicies(t *testing.T) {
+	available := []elbv2types.SslPolicy{
+		{Name: aws.String("ELBSecurityPolicy-2016-08"), SslProtocols: []string{"TLSv1", "TLSv1.1", "TLSv1.2"}},
+		{Name: aws.String("ELBSecurityPolicy-TLS-1-2-2017-01"), SslProtocols: []string{"TLSv1.2"}},
+		{Name: aws.String("ELBSecurityPolicy-TLS13-1-2-2021-06"), SslProtocols: []string{"TLSv1.2", "TLSv1.3"}},
+		{Name: aws.String("ELBSecurityPolicy-TLS13-1-3-2021-06"), SslProtocols: []string{"TLSv1.3"}},
+	}
+
+	grid := []struct {
+		requested string
+		expected  []string
+	}{
+		{
+			requested: "ELBSecurityPolicy-TLS13-1-2-Res-2021-06",
+			expected: []string{
+				"ELBSecurityPolicy-TLS13-1-2-2021-06",
+				"ELBSecurityPolicy-TLS-1-2-2017-01",
+				"ELBSecurityPolicy-TLS13-1-3-2021-06",
+			},
+		},
+		{
+			requested: "ELBSecurityPolicy-TLS13-1-3-FIPS-2023-04",
+			expected: []string{
+				"ELBSecurityPolicy-TLS13-1-3-2021-06",
+			},
+		},
+		{
+			requested: "ELBSecurityPolicy-TLS-1-1-2017-01",
+			expected: []string{
+				"ELBSecurityPolicy-TLS-1-2-2017-01",
+				"ELBSecurityPolicy-TLS13-1-2-2021-06",
+				"ELBSecurityPolicy-TLS13-1-3-2021-06",
+			},
+		},
+	}
+	for _, g := range grid {
+		actual := ClosestELBV2SSLPolicies(g.requested, available)
+		if !reflect.DeepEqual(actual, g.expected) {
+			t.Errorf("unexpected closest policies for %q: expected %v, got %v", g.requested, g.expected, actual)
+		}
+	}
+}
+
+func TestClosestNetworkLoadBalancerSSLPolicy(t *testing.T) {
+	grid := map[string]string{
+		"ELBSecurityPolicy-TLS13-1-2-2021-07":  "ELBSecurityPolicy-TLS13-1-2-2021-06",
+		"ELBSecurityPolicy-TLS13-1-3-2021-06":  "ELBSecurityPolicy-TLS13-1-3-2021-06",
+		"ELBSecurityPolicy-TLS-1-2-2017-1":     "ELBSecurityPolicy-TLS-1-2-2017-01",
+		"elbsecuritypolicy-FS-1-2-Res-2020-10": "ELBSecurityPolicy-FS-1-2-Res-2020-10",
+	}
+	for name, expected := range grid {
+		if actual := ClosestNetworkLoadBalancerSSLPolicy(name); actual != expected {
+			t.Errorf("unexpected closest policy for %q: expected %q, got %q", name, expected, actual)
+		}
+	}
+}
+
+// failingModifyListenerELBV2 fails to modify the listener on a given port.
+type failingModifyListenerELBV2 struct {
+	*mockelbv2.MockELBV2
+
+	failingListenerArn string
+}
+
+func (m *failingModifyListenerELBV2) ModifyListener(ctx context.Context, request *elbv2.ModifyListenerInput, optFns ...func(*elbv2.Options)) (*elbv2.ModifyListenerOutput, error) {
+	if aws.ToString(request.ListenerArn) == m.failingListenerArn {
+		return nil, fmt.Errorf("throttled")
+	}
+	return m.MockELBV2.ModifyListener(ctx, request, optFns...)
+}
+
+func TestRotateELBV2ListenerCertificateRollsBack(t *testing.T) {
+	ctx := context.TODO()
+
+	oldCertificateARN := "arn:aws:acm:us-east-1:000000000000:certificate/old"
+	newCertificateARN := "arn:aws:acm:us-east-1:000000000000:certificate/new"
+
+	cloud := BuildMockAWSCloud("us-east-1", "abc")
+	c := &failingModifyListenerELBV2{MockELBV2: &mockelbv2.MockELBV2{}}
+	cloud.MockELBV2 = c
+
+	lb, err := c.CreateLoadBalancer(ctx, &elbv2.CreateLoadBalancerInput{
+		Name: aws.String("nlb1"),
+		Type: elbv2types.LoadBalancerTypeEnumNetwork,
+	})
+	if err != nil {
+		t.Fatalf("error creating load balancer: %v", err)
+	}
+	loadBalancerArn := aws.ToString(lb.LoadBalancers[0].LoadBalancerArn)
+
+	var listenerArns []string
+	for _, port := range []int32{443, 8443, 9443} {
+		listener, err := c.CreateListener(ctx, &elbv2.CreateListenerInput{
+			LoadBalancerArn: aws.String(loadBalancerArn),
+			Port:            aws.Int32(port),
+			Protocol:        elbv2types.ProtocolEnumTls,
+			Certificates:    []elbv2types.Certificate{{CertificateArn: aws.String(oldCertificateARN)}},
+			DefaultActions:  []elbv2types.Action{{Type: elbv2types.ActionTypeEnumForward, TargetGroupArn: aws.String("tg")}},
+		})
+		if err != nil {
+			t.Fatalf("error creating listener: %v", err)
+		}
+		listenerArns = append(listenerArns, aws.ToString(listener.Listeners[0].ListenerArn))
+	}
+	// The last listener cannot be updated
+	c.failingListenerArn = listenerArns[2]
+
+	if err := RotateELBV2ListenerCertificate(ctx, cloud, loadBalancerArn, oldCertificateARN, newCertificateARN, nil, 0); err == nil {
+		t.Fatalf("expected an error rotating the certificate")
+	}
+
+	listeners, err := c.DescribeListeners(ctx, &elbv2.DescribeListenersInput{ListenerArns: listenerArns})
+	if err != nil {
+		t.Fatalf("error describing listeners: %v", err)
+	}
+	for _, listener := range listeners.Listeners {
+		if certificateARN := aws.ToString(listener.Certificates[0].CertificateArn); certificateARN != oldCertificateARN {
+			t.Errorf("expected listener on port %d to be rolled back to certificate %q, got %q", aws.ToInt32(listener.Port), oldCertificateARN, certificateARN)
+		}
+	}
+}
+
+func TestRotateELBV2ListenerCertificateSettles(t *testing.T) {
+	ctx := context.TODO()
+
+	oldCertificateARN := "arn:aws:acm:us-east-1:000000000000:certificate/old"
+	newCertificateARN := "arn:aws:acm:us-east-1:000000000000:certificate/new"
+	settleTime := 100 * time.Millisecond
+
+	cloud := BuildMockAWSCloud("us-east-1", "abc")
+	c := &mockelbv2.MockELBV2{}
+	cloud.MockELBV2 = c
+
+	lb, err := c.CreateLoadBalancer(ctx, &elbv2.CreateLoadBalancerInput{
+		Name: aws.String("nlb1"),
+		Type: elbv2types.LoadBalancerTypeEnumNetwork,
+	})
+	if err != nil {
+		t.Fatalf("error creating load balancer: %v", err)
+	}
+	loadBalancerArn := aws.ToString(lb.LoadBalancers[0].LoadBalancerArn)
+
+	listener, err := c.CreateListener(ctx, &elbv2.CreateListenerInput{
+		LoadBalancerArn: aws.String(loadBalancerArn),
+		Port:            aws.Int32(443),
+		Protocol:        elbv2types.ProtocolEnumTls,
+		Certificates:    []elbv2types.Certificate{{CertificateArn: aws.String(oldCertificateARN)}},
+		DefaultActions:  []elbv2types.Action{{Type: elbv2types.ActionTypeEnumForward, TargetGroupArn: aws.String("tg")}},
+	})
+	if err != nil {
+		t.Fatalf("error creating listener: %v", err)
+	}
+	listenerArn := aws.ToString(listener.Listeners[0].ListenerArn)
+
+	start := time.Now()
+	if err := RotateELBV2ListenerCertificate(ctx, cloud, loadBalancerArn, oldCertificateARN, newCertificateARN, nil, settleTime); err != nil {
+		t.Fatalf("unexpected error rotating the certificate: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < settleTime {
+		t.Errorf("expected to wait at least %v after rotating the certificate, waited %v", settleTime, elapsed)
+	}
+
+	tags, err := c.DescribeTags(ctx, &elbv2.DescribeTagsInput{ResourceArns: []string{listenerArn}})
+	if err != nil {
+		t.Fatalf("error describing tags: %v", err)
+	}
+	if _, found := FindELBV2Tag(tags.TagDescriptions[0].Tags, KopsCertificateRotatedTag); !found {
+		t.Errorf("expected the listener to be tagged with %q", KopsCertificateRotatedTag)
+	}
+
+	// Nothing is left to rotate, so we don't wait again
+	start = time.Now()
+	if err := RotateELBV2ListenerCertificate(ctx, cloud, loadBalancerArn, oldCertificateARN, newCertificateARN, nil, time.Minute); err != nil {
+		t.Fatalf("unexpected error rotating the certificate: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= time.Minute {
+		t.Errorf("expected not to wait when no listener was rotated, waited %v", elapsed)
+	}
+}
+
+func TestValidateListenerCertificate(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := BuildMockAWSCloud("us-east-1", "abc")
+	cloud.MockIAM = &mockiam.MockIAM{
+		ServerCertificates: map[string]*iamtypes.ServerCertificate{
+			"api": {},
+		},
+	}
+
+	grid := []struct {
+		name           string
+		certificateARN string
+		expectNotFound bool
+		expectError    bool
+	}{
+		{name: "acm", certificateARN: "arn:aws:acm:us-east-1:000000000000:certificate/1"},
+		{name: "acm in another region", certificateARN: "arn:aws:acm:eu-west-1:000000000000:certificate/1", expectError: true},
+		{name: "iam", certificateARN: "arn:aws:iam::000000000000:server-certificate/api"},
+		{name: "iam with path", certificateARN: "arn:aws:iam::000000000000:server-certificate/cloudfront/api"},
+		{name: "iam missing", certificateARN: "arn:aws:iam::000000000000:server-certificate/deleted", expectNotFound: true, expectError: true},
+		{name: "iam role", certificateARN: "arn:aws:iam::000000000000:role/api", expectError: true},
+		{name: "other service", certificateARN: "arn:aws:s3:::bucket", expectError: true},
+		{name: "not an arn", certificateARN: "api", expectError: true},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			err := ValidateListenerCertificate(ctx, cloud, g.certificateARN)
+			if g.expectError && err == nil {
+				t.Fatalf("expected error, got none")
+			}
+			if !g.expectError && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if notFound := errors.Is(err, ErrCertificateNotFound); notFound != g.expectNotFound {
+				t.Errorf("expected not found %v, got error %v", g.expectNotFound, err)
+			}
+		})
+	}
+}
+
+func TestValidateListenerCertificatePartitions(t *testing.T) {
+	ctx := context.TODO()
+
+	grid := []struct {
+		name           string
+		region         string
+		certificateARN string
+		expectError    bool
+	}{
+		{name: "govcloud acm", region: "us-gov-west-1", certificateARN: "arn:aws-us-gov:acm:us-gov-west-1:000000000000:certificate/1"},
+		{name: "govcloud acm in another region", region: "us-gov-west-1", certificateARN: "arn:aws-us-gov:acm:us-gov-east-1:000000000000:certificate/1", expectError: true},
+		{name: "govcloud iam", region: "us-gov-west-1", certificateARN: "arn:aws-us-gov:iam::000000000000:server-certificate/api"},
+		{name: "china acm", region: "cn-north-1", certificateARN: "arn:aws-cn:acm:cn-north-1:000000000000:certificate/1"},
+		{name: "china acm in another region", region: "cn-north-1", certificateARN: "arn:aws-cn:acm:cn-northwest-1:000000000000:certificate/1", expectError: true},
+		{name: "china iam", region: "cn-north-1", certificateARN: "arn:aws-cn:iam::000000000000:server-certificate/cloudfront/api"},
+		{name: "china iam role", region: "cn-north-1", certificateARN: "arn:aws-cn:iam::000000000000:role/api", expectError: true},
+		{name: "commercial acm from china", region: "cn-north-1", certificateARN: "arn:aws:acm:us-east-1:000000000000:certificate/1", expectError: true},
+		{name: "commercial iam from china", region: "cn-north-1", certificateARN: "arn:aws:iam::000000000000:server-certificate/api", expectError: true},
+		{name: "govcloud iam from commercial", region: "us-east-1", certificateARN: "arn:aws-us-gov:iam::000000000000:server-certificate/api", expectError: true},
+		{name: "iso acm", region: "us-iso-east-1", certificateARN: "arn:aws-iso:acm:us-iso-east-1:000000000000:certificate/1"},
+		{name: "iso-b acm", region: "us-isob-east-1", certificateARN: "arn:aws-iso-b:acm:us-isob-east-1:000000000000:certificate/1"},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			cloud := BuildMockAWSCloud(g.region, "a")
+			cloud.MockIAM = &mockiam.MockIAM{
+				ServerCertificates: map[string]*iamtypes.ServerCertificate{
+					"api": {},
+				},
+			}
+
+			err := ValidateListenerCertificate(ctx, cloud, g.certificateARN)
+			if g.expectError && err == nil {
+				t.Fatalf("expected error, got none")
+			}
+			if !g.expectError && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -19,10 +19,13 @@ package awsup
 import (
 	"context"
 	"fmt"
+	"math"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
 	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog/v2"
 )
 
@@ -54,6 +57,26 @@ func (i *LoadBalancerInfo) GetTag(key string) (string, bool) {
 }
 
 func ListELBV2LoadBalancers(ctx context.Context, cloud AWSCloud) ([]*LoadBalancerInfo, error) {
+	loadBalancers, err := ListAllELBV2LoadBalancers(ctx, cloud)
+	if err != nil {
+		return nil, err
+	}
+
+	cloudTags := cloud.Tags()
+
+	var results []*LoadBalancerInfo
+	for _, v := range loadBalancers {
+		if !MatchesElbV2Tags(cloudTags, v.Tags) {
+			continue
+		}
+		results = append(results, v)
+	}
+	return results, nil
+}
+
+// ListAllELBV2LoadBalancers returns all the ELBV2 load balancers in the region with their tags,
+// whether or not they are tagged for the cluster.
+func ListAllELBV2LoadBalancers(ctx context.Context, cloud AWSCloud) ([]*LoadBalancerInfo, error) {
 	// TODO: Any way around this?
 	klog.V(2).Infof("Listing all NLBs for ListELBV2LoadBalancers")
 
@@ -101,19 +124,28 @@ func ListELBV2LoadBalancers(ctx context.Context, cloud AWSCloud) ([]*LoadBalance
 		}
 	}
 
-	cloudTags := cloud.Tags()
-
 	var results []*LoadBalancerInfo
 	for _, v := range byARN {
-		if !MatchesElbV2Tags(cloudTags, v.Tags) {
-			continue
-		}
 		results = append(results, v)
 	}
 	return results, nil
 }
 
+// MatchesElbV2Tags returns true if the actual tags include all the tags.
 func MatchesElbV2Tags(tags map[string]string, actual []elbv2types.Tag) bool {
+	return MatchesElbV2TagsExcluding(tags, nil, actual)
+}
+
+// MatchesElbV2TagsExcluding returns true if the actual tags include all the tags, and none of the excluded tags.
+// An excluded tag with an empty value is matched by any value.
+func MatchesElbV2TagsExcluding(tags map[string]string, exclude map[string]string, actual []elbv2types.Tag) bool {
+	for _, a := range actual {
+		v, found := exclude[aws.ToString(a.Key)]
+		if found && (v == "" || v == aws.ToString(a.Value)) {
+			return false
+		}
+	}
+
 	for k, v := range tags {
 		found := false
 		for _, a := range actual {
@@ -130,3 +162,86 @@ func MatchesElbV2Tags(tags map[string]string, actual []elbv2types.Tag) bool {
 	}
 	return true
 }
+
+// DisableELBV2DeletionProtection turns off the deletion protection of the load balancer, if it is enabled,
+// so that kops can delete a load balancer it owns.
+func DisableELBV2DeletionProtection(ctx context.Context, cloud AWSCloud, loadBalancerArn string) error {
+	response, err := cloud.ELBV2().DescribeLoadBalancerAttributes(ctx, &elbv2.DescribeLoadBalancerAttributesInput{
+		LoadBalancerArn: aws.String(loadBalancerArn),
+	})
+	if err != nil {
+		return fmt.Errorf("describing attributes of load balancer %q: %w", loadBalancerArn, err)
+	}
+
+	protected := false
+	for _, attribute := range response.Attributes {
+		if aws.ToString(attribute.Key) == "deletion_protection.enabled" && aws.ToString(attribute.Value) == "true" {
+			protected = true
+		}
+	}
+	if !protected {
+		return nil
+	}
+
+	klog.V(2).Infof("disabling deletion protection of load balancer %q", loadBalancerArn)
+	if _, err := cloud.ELBV2().ModifyLoadBalancerAttributes(ctx, &elbv2.ModifyLoadBalancerAttributesInput{
+		LoadBalancerArn: aws.String(loadBalancerArn),
+		Attributes: []elbv2types.LoadBalancerAttribute{
+			{Key: aws.String("deletion_protection.enabled"), Value: aws.String("false")},
+		},
+	}); err != nil {
+		return fmt.Errorf("disabling deletion protection of load balancer %q: %w", loadBalancerArn, err)
+	}
+	return nil
+}
+
+// loadBalancerActiveBackoff is the backoff between the polls of WaitForLoadBalancerActive.
+// Network load balancers typically take a few minutes to provision.
+var loadBalancerActiveBackoff = wait.Backoff{
+	Duration: 2 * time.Second,
+	Factor:   2,
+	Jitter:   0.1,
+	Cap:      15 * time.Second,
+}
+
+// WaitForLoadBalancerActive polls the state of the load balancer, with exponential backoff, until it is active.
+// It fails as soon as the load balancer fails to provision or ctx is cancelled, or once timeout has elapsed.
+func WaitForLoadBalancerActive(ctx context.Context, cloud AWSCloud, loadBalancerArn string, timeout time.Duration) error {
+	klog.V(2).Infof("Waiting up to %v for load balancer %q to be active", timeout, loadBalancerArn)
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	backoff := loadBalancerActiveBackoff
+	backoff.Steps = math.MaxInt32
+
+	var state elbv2types.LoadBalancerStateEnum
+	for {
+		response, err := cloud.ELBV2().DescribeLoadBalancers(waitCtx, &elbv2.DescribeLoadBalancersInput{
+			LoadBalancerArns: []string{loadBalancerArn},
+		})
+		// A load balancer that was just created may not be visible yet
+		if err != nil && waitCtx.Err() == nil && AWSErrorCode(err) != "LoadBalancerNotFound" {
+			return fmt.Errorf("describing load balancer %q: %w", loadBalancerArn, err)
+		}
+		if err == nil && len(response.LoadBalancers) > 0 && response.LoadBalancers[0].State != nil {
+			lbState := response.LoadBalancers[0].State
+			state = lbState.Code
+			switch state {
+			case elbv2types.LoadBalancerStateEnumActive:
+				return nil
+			case elbv2types.LoadBalancerStateEnumFailed:
+				return fmt.Errorf("load balancer %q failed to provision: %s", loadBalancerArn, aws.ToString(lbState.Reason))
+			}
+		}
+
+		select {
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return fmt.Errorf("waiting for load balancer %q to be active: %w", loadBalancerArn, ctx.Err())
+			}
+			return fmt.Errorf("timed out after %v waiting for load balancer %q to be active, state is %q", timeout, loadBalancerArn, state)
+		case <-time.After(backoff.Step()):
+		}
+	}
+}
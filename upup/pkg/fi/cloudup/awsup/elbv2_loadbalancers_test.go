@@ -0,0 +1,115 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awsup
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/kops/cloudmock/aws/mockelbv2"
+)
+
+// provisioningELBV2 reports load balancers in the given state until a number of DescribeLoadBalancers calls.
+type provisioningELBV2 struct {
+	*mockelbv2.MockELBV2
+
+	state         elbv2types.LoadBalancerStateEnum
+	activeAfter   int
+	describeCalls int
+}
+
+func (m *provisioningELBV2) DescribeLoadBalancers(ctx context.Context, request *elbv2.DescribeLoadBalancersInput, optFns ...func(*elbv2.Options)) (*elbv2.DescribeLoadBalancersOutput, error) {
+	m.describeCalls++
+	response, err := m.MockELBV2.DescribeLoadBalancers(ctx, request, optFns...)
+	if err != nil || (m.activeAfter > 0 && m.describeCalls >= m.activeAfter) {
+		return response, err
+	}
+	for i := range response.LoadBalancers {
+		response.LoadBalancers[i].State = &elbv2types.LoadBalancerState{Code: m.state, Reason: aws.String("Insufficient capacity")}
+	}
+	return response, nil
+}
+
+func TestWaitForLoadBalancerActive(t *testing.T) {
+	defer func(backoff wait.Backoff) {
+		loadBalancerActiveBackoff = backoff
+	}(loadBalancerActiveBackoff)
+	loadBalancerActiveBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 2, Cap: 10 * time.Millisecond}
+
+	ctx := context.TODO()
+
+	buildCloud := func(t *testing.T, state elbv2types.LoadBalancerStateEnum, activeAfter int) (*MockAWSCloud, *provisioningELBV2, string) {
+		cloud := BuildMockAWSCloud("us-test-1", "a")
+		c := &provisioningELBV2{MockELBV2: &mockelbv2.MockELBV2{}, state: state, activeAfter: activeAfter}
+		cloud.MockELBV2 = c
+
+		lb, err := c.CreateLoadBalancer(ctx, &elbv2.CreateLoadBalancerInput{
+			Name: aws.String("api-example-com"),
+			Type: elbv2types.LoadBalancerTypeEnumNetwork,
+		})
+		if err != nil {
+			t.Fatalf("error creating load balancer: %v", err)
+		}
+		return cloud, c, aws.ToString(lb.LoadBalancers[0].LoadBalancerArn)
+	}
+
+	t.Run("provisioning then active", func(t *testing.T) {
+		cloud, c, arn := buildCloud(t, elbv2types.LoadBalancerStateEnumProvisioning, 3)
+		if err := WaitForLoadBalancerActive(ctx, cloud, arn, time.Minute); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if c.describeCalls != 3 {
+			t.Errorf("expected 3 polls, got %d", c.describeCalls)
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		cloud, c, arn := buildCloud(t, elbv2types.LoadBalancerStateEnumFailed, 0)
+		err := WaitForLoadBalancerActive(ctx, cloud, arn, time.Minute)
+		if err == nil || !strings.Contains(err.Error(), "Insufficient capacity") {
+			t.Errorf("expected the error to give the reason of the failure, got %v", err)
+		}
+		if c.describeCalls != 1 {
+			t.Errorf("expected a failed load balancer not to be polled again, got %d polls", c.describeCalls)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		cloud, _, arn := buildCloud(t, elbv2types.LoadBalancerStateEnumProvisioning, 0)
+		err := WaitForLoadBalancerActive(ctx, cloud, arn, 50*time.Millisecond)
+		if err == nil || !strings.Contains(err.Error(), `timed out`) || !strings.Contains(err.Error(), `"provisioning"`) {
+			t.Errorf("expected a timeout error giving the state, got %v", err)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		cloud, _, arn := buildCloud(t, elbv2types.LoadBalancerStateEnumProvisioning, 0)
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		err := WaitForLoadBalancerActive(ctx, cloud, arn, time.Minute)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected cancellation error, got %v", err)
+		}
+	})
+}
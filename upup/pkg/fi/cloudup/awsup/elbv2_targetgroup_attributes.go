@@ -0,0 +1,164 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awsup
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+)
+
+// The keys of the target group attributes.
+// https://docs.aws.amazon.com/elasticloadbalancing/latest/network/load-balancer-target-groups.html#target-group-attributes
+const (
+	TargetGroupAttributeDeregistrationDelayConnectionTerminationEnabled = "deregistration_delay.connection_termination.enabled"
+	TargetGroupAttributeDeregistrationDelayTimeoutSeconds               = "deregistration_delay.timeout_seconds"
+	TargetGroupAttributeProxyProtocolV2Enabled                          = "proxy_protocol_v2.enabled"
+	TargetGroupAttributeStickinessEnabled                               = "stickiness.enabled"
+	TargetGroupAttributeStickinessType                                  = "stickiness.type"
+	TargetGroupAttributeLoadBalancingCrossZoneEnabled                   = "load_balancing.cross_zone.enabled"
+	TargetGroupAttributePreserveClientIPEnabled                         = "preserve_client_ip.enabled"
+	TargetGroupAttributeUnhealthyConnectionTerminationEnabled           = "target_health_state.unhealthy.connection_termination.enabled"
+	TargetGroupAttributeUnhealthyDrainingIntervalSeconds                = "target_health_state.unhealthy.draining_interval_seconds"
+)
+
+// TargetGroupAttributes are the attributes of a target group, with a field for each attribute that kops manages.
+// Unset fields are not part of the attributes.
+type TargetGroupAttributes struct {
+	DeregistrationDelayConnectionTermination *bool
+	DeregistrationDelayTimeoutSeconds        *int
+	ProxyProtocolV2                          *bool
+	StickinessEnabled                        *bool
+	StickinessType                           *string
+	// CrossZoneLoadBalancing is "true", "false" or "use_load_balancer_configuration".
+	CrossZoneLoadBalancing           *string
+	PreserveClientIP                 *bool
+	UnhealthyConnectionTermination   *bool
+	UnhealthyDrainingIntervalSeconds *int
+
+	// Other holds the attributes without a field, by key.
+	Other map[string]string
+}
+
+// Map returns the attributes by key.
+// A field takes precedence over the same key in Other.
+func (a *TargetGroupAttributes) Map() map[string]string {
+	attributes := make(map[string]string)
+	for k, v := range a.Other {
+		attributes[k] = v
+	}
+	setBool := func(key string, value *bool) {
+		if value != nil {
+			attributes[key] = strconv.FormatBool(*value)
+		}
+	}
+	setInt := func(key string, value *int) {
+		if value != nil {
+			attributes[key] = strconv.Itoa(*value)
+		}
+	}
+	setString := func(key string, value *string) {
+		if value != nil {
+			attributes[key] = *value
+		}
+	}
+	setBool(TargetGroupAttributeDeregistrationDelayConnectionTerminationEnabled, a.DeregistrationDelayConnectionTermination)
+	setInt(TargetGroupAttributeDeregistrationDelayTimeoutSeconds, a.DeregistrationDelayTimeoutSeconds)
+	setBool(TargetGroupAttributeProxyProtocolV2Enabled, a.ProxyProtocolV2)
+	setBool(TargetGroupAttributeStickinessEnabled, a.StickinessEnabled)
+	setString(TargetGroupAttributeStickinessType, a.StickinessType)
+	setString(TargetGroupAttributeLoadBalancingCrossZoneEnabled, a.CrossZoneLoadBalancing)
+	setBool(TargetGroupAttributePreserveClientIPEnabled, a.PreserveClientIP)
+	setBool(TargetGroupAttributeUnhealthyConnectionTerminationEnabled, a.UnhealthyConnectionTermination)
+	setInt(TargetGroupAttributeUnhealthyDrainingIntervalSeconds, a.UnhealthyDrainingIntervalSeconds)
+	return attributes
+}
+
+// Marshal returns the attributes as expected by the ELBV2 API, in order of key.
+func (a *TargetGroupAttributes) Marshal() []elbv2types.TargetGroupAttribute {
+	return MarshalTargetGroupAttributes(a.Map())
+}
+
+// MarshalTargetGroupAttributes returns the attributes by key as expected by the ELBV2 API, in order of key.
+func MarshalTargetGroupAttributes(attributes map[string]string) []elbv2types.TargetGroupAttribute {
+	keys := make([]string, 0, len(attributes))
+	for k := range attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	marshaled := make([]elbv2types.TargetGroupAttribute, 0, len(keys))
+	for _, k := range keys {
+		marshaled = append(marshaled, elbv2types.TargetGroupAttribute{
+			Key:   aws.String(k),
+			Value: aws.String(attributes[k]),
+		})
+	}
+	return marshaled
+}
+
+// UnmarshalTargetGroupAttributes parses the attributes returned by the ELBV2 API.
+// Values that cannot be parsed are left unset, and attributes without a field are kept in Other.
+func UnmarshalTargetGroupAttributes(attributes []elbv2types.TargetGroupAttribute) *TargetGroupAttributes {
+	parsed := &TargetGroupAttributes{}
+	parseBool := func(value string) *bool {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil
+		}
+		return &b
+	}
+	parseInt := func(value string) *int {
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return nil
+		}
+		return &i
+	}
+	for _, attr := range attributes {
+		key := aws.ToString(attr.Key)
+		value := aws.ToString(attr.Value)
+		switch key {
+		case TargetGroupAttributeDeregistrationDelayConnectionTerminationEnabled:
+			parsed.DeregistrationDelayConnectionTermination = parseBool(value)
+		case TargetGroupAttributeDeregistrationDelayTimeoutSeconds:
+			parsed.DeregistrationDelayTimeoutSeconds = parseInt(value)
+		case TargetGroupAttributeProxyProtocolV2Enabled:
+			parsed.ProxyProtocolV2 = parseBool(value)
+		case TargetGroupAttributeStickinessEnabled:
+			parsed.StickinessEnabled = parseBool(value)
+		case TargetGroupAttributeStickinessType:
+			parsed.StickinessType = aws.String(value)
+		case TargetGroupAttributeLoadBalancingCrossZoneEnabled:
+			parsed.CrossZoneLoadBalancing = aws.String(value)
+		case TargetGroupAttributePreserveClientIPEnabled:
+			parsed.PreserveClientIP = parseBool(value)
+		case TargetGroupAttributeUnhealthyConnectionTerminationEnabled:
+			parsed.UnhealthyConnectionTermination = parseBool(value)
+		case TargetGroupAttributeUnhealthyDrainingIntervalSeconds:
+			parsed.UnhealthyDrainingIntervalSeconds = parseInt(value)
+		default:
+			if parsed.Other == nil {
+				parsed.Other = make(map[string]string)
+			}
+			parsed.Other[key] = value
+		}
+	}
+	return parsed
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awsup
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+)
+
+func TestTargetGroupAttributesRoundTrip(t *testing.T) {
+	grid := []struct {
+		name       string
+		attributes *TargetGroupAttributes
+	}{
+		{
+			name:       "empty",
+			attributes: &TargetGroupAttributes{},
+		},
+		{
+			name: "all fields",
+			attributes: &TargetGroupAttributes{
+				DeregistrationDelayConnectionTermination: aws.Bool(true),
+				DeregistrationDelayTimeoutSeconds:        aws.Int(30),
+				ProxyProtocolV2:                          aws.Bool(false),
+				StickinessEnabled:                        aws.Bool(true),
+				StickinessType:                           aws.String("source_ip"),
+				CrossZoneLoadBalancing:                   aws.String("use_load_balancer_configuration"),
+				PreserveClientIP:                         aws.Bool(true),
+				UnhealthyConnectionTermination:           aws.Bool(false),
+				UnhealthyDrainingIntervalSeconds:         aws.Int(300),
+			},
+		},
+		{
+			name: "other attributes",
+			attributes: &TargetGroupAttributes{
+				DeregistrationDelayTimeoutSeconds: aws.Int(0),
+				Other: map[string]string{
+					"target_failover.on_deregistration": "rebalance",
+				},
+			},
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			marshaled := g.attributes.Marshal()
+			for i := 1; i < len(marshaled); i++ {
+				if aws.ToString(marshaled[i-1].Key) >= aws.ToString(marshaled[i].Key) {
+					t.Errorf("expected attributes in order of key, got %q before %q", aws.ToString(marshaled[i-1].Key), aws.ToString(marshaled[i].Key))
+				}
+			}
+			if actual := UnmarshalTargetGroupAttributes(marshaled); !reflect.DeepEqual(actual, g.attributes) {
+				t.Errorf("unexpected round trip: expected %+v, got %+v", g.attributes, actual)
+			}
+		})
+	}
+}
+
+func TestMarshalTargetGroupAttributesKeys(t *testing.T) {
+	attributes := &TargetGroupAttributes{
+		DeregistrationDelayTimeoutSeconds: aws.Int(30),
+		StickinessEnabled:                 aws.Bool(true),
+		// A field takes precedence over the same key in Other
+		Other: map[string]string{
+			TargetGroupAttributeDeregistrationDelayTimeoutSeconds: "300",
+		},
+	}
+	expected := []elbv2types.TargetGroupAttribute{
+		{Key: aws.String("deregistration_delay.timeout_seconds"), Value: aws.String("30")},
+		{Key: aws.String("stickiness.enabled"), Value: aws.String("true")},
+	}
+	if actual := attributes.Marshal(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestUnmarshalTargetGroupAttributesInvalidValues(t *testing.T) {
+	actual := UnmarshalTargetGroupAttributes([]elbv2types.TargetGroupAttribute{
+		{Key: aws.String(TargetGroupAttributeProxyProtocolV2Enabled), Value: aws.String("maybe")},
+		{Key: aws.String(TargetGroupAttributeDeregistrationDelayTimeoutSeconds), Value: aws.String("")},
+	})
+	if !reflect.DeepEqual(actual, &TargetGroupAttributes{}) {
+		t.Errorf("expected invalid values to be left unset, got %+v", actual)
+	}
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awsup
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+)
+
+// DiffTargetGroupInfo returns the differences between the desired and the actual configuration of a target group,
+// one line per field, e.g. `HealthyThresholdCount: expected "3", got "2"`, in order.
+// The health check, the port, protocol, target type and VPC, and the tags are compared;
+// fields that are not set in desired are not compared, and tags with the aws: prefix are ignored.
+func DiffTargetGroupInfo(desired, actual *TargetGroupInfo) []string {
+	var diffs []string
+	for _, field := range targetGroupDiffFields {
+		expected := field.value(&desired.TargetGroup)
+		if expected == "" {
+			continue
+		}
+		if got := field.value(&actual.TargetGroup); got != expected {
+			diffs = append(diffs, fmt.Sprintf("%s: expected %q, got %q", field.name, expected, got))
+		}
+	}
+	diffs = append(diffs, diffStringMaps("tag", desired.TagsMap(), actual.TagsMap(), func(key string) bool {
+		return strings.HasPrefix(key, "aws:")
+	})...)
+	return diffs
+}
+
+// DiffTargetGroupAttributes returns the differences between the desired and the actual attributes of a target group,
+// one line per attribute, e.g. `attribute "stickiness.enabled": expected "true", got "false"`, in order of key.
+// Attributes that are not set in desired are not compared, as AWS returns every attribute with its default value.
+func DiffTargetGroupAttributes(desired, actual *TargetGroupAttributes) []string {
+	return diffStringMaps("attribute", desired.Map(), actual.Map(), func(key string) bool {
+		return true
+	})
+}
+
+// diffStringMaps returns the differences between the expected and the actual values, in order of key.
+// Keys that are only in actual are reported, unless ignoreExtra returns true for them.
+func diffStringMaps(kind string, expected, actual map[string]string, ignoreExtra func(key string) bool) []string {
+	var keys []string
+	for k := range expected {
+		keys = append(keys, k)
+	}
+	for k := range actual {
+		if _, found := expected[k]; !found && !ignoreExtra(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var diffs []string
+	for _, k := range keys {
+		expectedValue, expectedFound := expected[k]
+		actualValue, actualFound := actual[k]
+		switch {
+		case !actualFound:
+			diffs = append(diffs, fmt.Sprintf("%s %q: expected %q, not set", kind, k, expectedValue))
+		case !expectedFound:
+			diffs = append(diffs, fmt.Sprintf("%s %q: not expected, got %q", kind, k, actualValue))
+		case expectedValue != actualValue:
+			diffs = append(diffs, fmt.Sprintf("%s %q: expected %q, got %q", kind, k, expectedValue, actualValue))
+		}
+	}
+	return diffs
+}
+
+// targetGroupDiffFields are the fields of a target group compared by DiffTargetGroupInfo, formatted as strings.
+var targetGroupDiffFields = []struct {
+	name  string
+	value func(tg *elbv2types.TargetGroup) string
+}{
+	{"Port", func(tg *elbv2types.TargetGroup) string { return formatInt32(tg.Port) }},
+	{"Protocol", func(tg *elbv2types.TargetGroup) string { return string(tg.Protocol) }},
+	{"ProtocolVersion", func(tg *elbv2types.TargetGroup) string { return aws.ToString(tg.ProtocolVersion) }},
+	{"TargetType", func(tg *elbv2types.TargetGroup) string { return string(tg.TargetType) }},
+	{"IpAddressType", func(tg *elbv2types.TargetGroup) string { return string(tg.IpAddressType) }},
+	{"VpcId", func(tg *elbv2types.TargetGroup) string { return aws.ToString(tg.VpcId) }},
+	{"HealthCheckEnabled", func(tg *elbv2types.TargetGroup) string {
+		if tg.HealthCheckEnabled == nil {
+			return ""
+		}
+		return strconv.FormatBool(*tg.HealthCheckEnabled)
+	}},
+	{"HealthCheckProtocol", func(tg *elbv2types.TargetGroup) string { return string(tg.HealthCheckProtocol) }},
+	{"HealthCheckPort", func(tg *elbv2types.TargetGroup) string { return aws.ToString(tg.HealthCheckPort) }},
+	{"HealthCheckPath", func(tg *elbv2types.TargetGroup) string { return aws.ToString(tg.HealthCheckPath) }},
+	{"HealthCheckIntervalSeconds", func(tg *elbv2types.TargetGroup) string { return formatInt32(tg.HealthCheckIntervalSeconds) }},
+	{"HealthCheckTimeoutSeconds", func(tg *elbv2types.TargetGroup) string { return formatInt32(tg.HealthCheckTimeoutSeconds) }},
+	{"HealthyThresholdCount", func(tg *elbv2types.TargetGroup) string { return formatInt32(tg.HealthyThresholdCount) }},
+	{"UnhealthyThresholdCount", func(tg *elbv2types.TargetGroup) string { return formatInt32(tg.UnhealthyThresholdCount) }},
+	{"Matcher.HttpCode", func(tg *elbv2types.TargetGroup) string {
+		if tg.Matcher == nil {
+			return ""
+		}
+		return aws.ToString(tg.Matcher.HttpCode)
+	}},
+	{"Matcher.GrpcCode", func(tg *elbv2types.TargetGroup) string {
+		if tg.Matcher == nil {
+			return ""
+		}
+		return aws.ToString(tg.Matcher.GrpcCode)
+	}},
+}
+
+func formatInt32(v *int32) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatInt(int64(*v), 10)
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awsup
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+)
+
+func TestDiffTargetGroupInfo(t *testing.T) {
+	desired := &TargetGroupInfo{
+		TargetGroup: elbv2types.TargetGroup{
+			Port:                  aws.Int32(443),
+			Protocol:              elbv2types.ProtocolEnumTcp,
+			HealthyThresholdCount: aws.Int32(3),
+		},
+		Tags: ELBv2Tags(map[string]string{"Name": "tcp-api", "team": "platform"}),
+	}
+
+	grid := []struct {
+		name     string
+		actual   *TargetGroupInfo
+		expected []string
+	}{
+		{
+			name: "unchanged",
+			actual: &TargetGroupInfo{
+				TargetGroup: elbv2types.TargetGroup{
+					Port:                    aws.Int32(443),
+					Protocol:                elbv2types.ProtocolEnumTcp,
+					HealthyThresholdCount:   aws.Int32(3),
+					UnhealthyThresholdCount: aws.Int32(3),
+				},
+				Tags: ELBv2Tags(map[string]string{"Name": "tcp-api", "team": "platform", "aws:cloudformation:stack-name": "stack"}),
+			},
+		},
+		{
+			name: "health check threshold",
+			actual: &TargetGroupInfo{
+				TargetGroup: elbv2types.TargetGroup{
+					Port:                  aws.Int32(443),
+					Protocol:              elbv2types.ProtocolEnumTcp,
+					HealthyThresholdCount: aws.Int32(2),
+				},
+				Tags: ELBv2Tags(map[string]string{"Name": "tcp-api", "team": "platform"}),
+			},
+			expected: []string{`HealthyThresholdCount: expected "3", got "2"`},
+		},
+		{
+			name: "tags",
+			actual: &TargetGroupInfo{
+				TargetGroup: elbv2types.TargetGroup{
+					Port:                  aws.Int32(443),
+					Protocol:              elbv2types.ProtocolEnumTcp,
+					HealthyThresholdCount: aws.Int32(3),
+				},
+				Tags: ELBv2Tags(map[string]string{"team": "network", "owner": "someone"}),
+			},
+			expected: []string{
+				`tag "Name": expected "tcp-api", not set`,
+				`tag "owner": not expected, got "someone"`,
+				`tag "team": expected "platform", got "network"`,
+			},
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			if actual := DiffTargetGroupInfo(desired, g.actual); !reflect.DeepEqual(actual, g.expected) {
+				t.Errorf("expected differences %q, got %q", g.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDiffTargetGroupAttributes(t *testing.T) {
+	desired := &TargetGroupAttributes{
+		DeregistrationDelayTimeoutSeconds: aws.Int(30),
+		StickinessEnabled:                 aws.Bool(true),
+	}
+	actual := &TargetGroupAttributes{
+		DeregistrationDelayTimeoutSeconds: aws.Int(300),
+		ProxyProtocolV2:                   aws.Bool(false),
+	}
+	expected := []string{
+		`attribute "deregistration_delay.timeout_seconds": expected "30", got "300"`,
+		`attribute "stickiness.enabled": expected "true", not set`,
+	}
+	if diffs := DiffTargetGroupAttributes(desired, actual); !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("expected differences %q, got %q", expected, diffs)
+	}
+}
@@ -19,10 +19,12 @@ package awsup
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
 	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"golang.org/x/sync/errgroup"
 	"k8s.io/klog/v2"
 )
 
@@ -32,6 +34,10 @@ type TargetGroupInfo struct {
 
 	// ARN holds the arn (amazon id) of the target group.
 	ARN string
+
+	// MatchesClusterTags is set if the target group has the tags of the cluster and none of the excluded tags.
+	// ListELBV2TargetGroups only returns target groups that don't match with IncludingNonMatchingTargetGroups.
+	MatchesClusterTags bool
 }
 
 // NameTag returns the value of the tag with the key "Name".
@@ -50,14 +56,133 @@ func (i *TargetGroupInfo) GetTag(key string) (string, bool) {
 	return "", false
 }
 
-func ListELBV2TargetGroups(ctx context.Context, cloud AWSCloud) ([]*TargetGroupInfo, error) {
-	klog.V(2).Infof("Listing all target groups")
+// GetTagFold returns the value of the tag with the given key, ignoring case.
+// If several tags match, a tag with exactly the given key is preferred, and then the first matching tag.
+func (i *TargetGroupInfo) GetTagFold(key string) (string, bool) {
+	if value, found := i.GetTag(key); found {
+		return value, true
+	}
+	for _, tag := range i.Tags {
+		if strings.EqualFold(aws.ToString(tag.Key), key) {
+			return aws.ToString(tag.Value), true
+		}
+	}
+	return "", false
+}
+
+// TagsMap returns the tags of the target group as a map.
+func (i *TargetGroupInfo) TagsMap() map[string]string {
+	tags := make(map[string]string, len(i.Tags))
+	for _, tag := range i.Tags {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tags
+}
+
+// HealthCheckProtocol returns the protocol of the health checks, or "" if it is not set.
+func (i *TargetGroupInfo) HealthCheckProtocol() string {
+	return string(i.TargetGroup.HealthCheckProtocol)
+}
+
+// HealthCheckPort returns the port of the health checks, either a port number or "traffic-port", or "" if it is not set.
+func (i *TargetGroupInfo) HealthCheckPort() string {
+	return aws.ToString(i.TargetGroup.HealthCheckPort)
+}
+
+// HealthCheckPath returns the destination of HTTP/HTTPS health checks, or "" if it is not set.
+func (i *TargetGroupInfo) HealthCheckPath() string {
+	return aws.ToString(i.TargetGroup.HealthCheckPath)
+}
+
+// VpcID returns the ID of the VPC of the target group, or "" if it is not set.
+func (i *TargetGroupInfo) VpcID() string {
+	return aws.ToString(i.TargetGroup.VpcId)
+}
+
+// HealthyThresholdCount returns the number of consecutive successful health checks before a target is healthy, or 0 if it is not set.
+func (i *TargetGroupInfo) HealthyThresholdCount() int32 {
+	return aws.ToInt32(i.TargetGroup.HealthyThresholdCount)
+}
+
+// ListELBV2TargetGroupsOption changes which target groups are returned by ListELBV2TargetGroups.
+type ListELBV2TargetGroupsOption func(*listELBV2TargetGroupsOptions)
+
+type listELBV2TargetGroupsOptions struct {
+	excludeTags        map[string]string
+	includeNonMatching bool
+	skipTags           bool
+	pageSize           int32
+}
+
+// maxDescribeTargetGroupsPageSize is the largest page size AWS accepts for DescribeTargetGroups,
+// and the default page size of ListELBV2TargetGroups, for the fewest API calls.
+const maxDescribeTargetGroupsPageSize = 400
+
+// ExcludingTargetGroupsTagged skips the target groups that carry any of the tags, even if they have the cluster tags,
+// e.g. target groups of the cluster that are managed for another purpose. An empty value matches any value of the tag.
+func ExcludingTargetGroupsTagged(tags map[string]string) ListELBV2TargetGroupsOption {
+	return func(options *listELBV2TargetGroupsOptions) {
+		options.excludeTags = tags
+	}
+}
+
+// IncludingNonMatchingTargetGroups returns all the listed target groups instead of only those of the cluster,
+// with MatchesClusterTags reporting whether each one matches, e.g. to audit target groups with stale cluster tags.
+func IncludingNonMatchingTargetGroups() ListELBV2TargetGroupsOption {
+	return func(options *listELBV2TargetGroupsOptions) {
+		options.includeNonMatching = true
+	}
+}
+
+// WithoutTargetGroupTags skips the DescribeTags calls, e.g. on accounts where they are rate limited,
+// for callers that filter the target groups themselves. The target groups are returned without tags,
+// and as they cannot be matched against the cluster tags, all the listed target groups are returned
+// with MatchesClusterTags unset.
+func WithoutTargetGroupTags() ListELBV2TargetGroupsOption {
+	return func(options *listELBV2TargetGroupsOptions) {
+		options.skipTags = true
+	}
+}
+
+// WithTargetGroupsPageSize sets the page size of the DescribeTargetGroups requests, between 1 and 400.
+// The tags are described in batches regardless of the page size.
+func WithTargetGroupsPageSize(pageSize int32) ListELBV2TargetGroupsOption {
+	return func(options *listELBV2TargetGroupsOptions) {
+		options.pageSize = pageSize
+	}
+}
+
+// ListELBV2TargetGroups returns the target groups of the cluster, restricted to those in the given VPC unless vpcID is empty,
+// and to those attached to the given load balancer unless loadBalancerARN is empty.
+func ListELBV2TargetGroups(ctx context.Context, cloud AWSCloud, vpcID string, loadBalancerARN string, opts ...ListELBV2TargetGroupsOption) ([]*TargetGroupInfo, error) {
+	options := listELBV2TargetGroupsOptions{
+		pageSize: maxDescribeTargetGroupsPageSize,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.pageSize < 1 || options.pageSize > maxDescribeTargetGroupsPageSize {
+		return nil, fmt.Errorf("invalid page size %d for listing target groups, must be between 1 and %d", options.pageSize, maxDescribeTargetGroupsPageSize)
+	}
+
+	if loadBalancerARN != "" {
+		klog.V(2).Infof("Listing all target groups of load balancer %q", loadBalancerARN)
+	} else if vpcID != "" {
+		klog.V(2).Infof("Listing all target groups in VPC %q", vpcID)
+	} else {
+		klog.V(2).Infof("Listing all target groups")
+	}
 
-	request := &elbv2.DescribeTargetGroupsInput{}
-	// ELBV2 DescribeTags has a limit of 20 names, so we set the page size here to 20 also
-	request.PageSize = aws.Int32(20)
+	request := &elbv2.DescribeTargetGroupsInput{
+		PageSize: aws.Int32(options.pageSize),
+	}
+	if loadBalancerARN != "" {
+		// AWS only returns the target groups attached to the load balancer, so we don't fetch unrelated groups
+		request.LoadBalancerArn = aws.String(loadBalancerARN)
+	}
 
 	byARN := make(map[string]*TargetGroupInfo)
+	var arns []string
 
 	paginator := elbv2.NewDescribeTargetGroupsPaginator(cloud.ELBV2(), request)
 	for paginator.HasMorePages() {
@@ -69,40 +194,145 @@ func ListELBV2TargetGroups(ctx context.Context, cloud AWSCloud) ([]*TargetGroupI
 			break
 		}
 
-		tagRequest := &elbv2.DescribeTagsInput{}
-
 		for _, tg := range page.TargetGroups {
+			// Filtering before fetching the tags saves DescribeTags calls when several clusters share an account
+			if vpcID != "" && aws.ToString(tg.VpcId) != vpcID {
+				continue
+			}
 			arn := aws.ToString(tg.TargetGroupArn)
+			if byARN[arn] == nil {
+				arns = append(arns, arn)
+			}
 			byARN[arn] = &TargetGroupInfo{TargetGroup: tg, ARN: arn}
-
-			tagRequest.ResourceArns = append(tagRequest.ResourceArns, aws.ToString(tg.TargetGroupArn))
 		}
+	}
 
-		tagResponse, err := cloud.ELBV2().DescribeTags(ctx, tagRequest)
-		if err != nil {
-			return nil, fmt.Errorf("listing ELB TargetGroup tags: %w", err)
+	if options.skipTags {
+		results := make([]*TargetGroupInfo, 0, len(arns))
+		for _, arn := range arns {
+			results = append(results, byARN[arn])
 		}
+		return results, nil
+	}
 
-		for _, t := range tagResponse.TagDescriptions {
-			arn := aws.ToString(t.ResourceArn)
-
-			info := byARN[arn]
-			if info == nil {
-				klog.Fatalf("found tag for load balancer we didn't ask for %q", arn)
-			}
+	tagDescriptions, err := describeELBV2TagsInBatches(ctx, cloud, arns)
+	if err != nil {
+		return nil, fmt.Errorf("listing ELB TargetGroup tags: %w", err)
+	}
+	for _, t := range tagDescriptions {
+		arn := aws.ToString(t.ResourceArn)
 
-			info.Tags = append(info.Tags, t.Tags...)
+		info := byARN[arn]
+		if info == nil {
+			// This is not expected, but is not fatal either
+			klog.Warningf("ignoring tags for target group we didn't ask for %q", arn)
+			continue
 		}
+
+		info.Tags = append(info.Tags, t.Tags...)
 	}
 
 	cloudTags := cloud.Tags()
 
+	// Returned in the order they were listed
 	var results []*TargetGroupInfo
-	for _, v := range byARN {
-		if !MatchesElbV2Tags(cloudTags, v.Tags) {
+	for _, arn := range arns {
+		v := byARN[arn]
+		v.MatchesClusterTags = MatchesElbV2TagsExcluding(cloudTags, options.excludeTags, v.Tags)
+		if !v.MatchesClusterTags && !options.includeNonMatching {
 			continue
 		}
 		results = append(results, v)
 	}
 	return results, nil
 }
+
+// ListELBV2TargetGroupsByName returns the target groups of the cluster listed by ListELBV2TargetGroups, keyed by their Name tag.
+// Target groups without a Name tag are skipped, and several target groups with the same Name tag are an error,
+// rather than silently picking one of them.
+func ListELBV2TargetGroupsByName(ctx context.Context, cloud AWSCloud, vpcID string, loadBalancerARN string, opts ...ListELBV2TargetGroupsOption) (map[string]*TargetGroupInfo, error) {
+	targetGroups, err := ListELBV2TargetGroups(ctx, cloud, vpcID, loadBalancerARN, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*TargetGroupInfo, len(targetGroups))
+	for _, targetGroup := range targetGroups {
+		name := targetGroup.NameTag()
+		if name == "" {
+			continue
+		}
+		if existing := byName[name]; existing != nil {
+			return nil, fmt.Errorf("found several target groups with Name tag %q: %q and %q", name, existing.ARN, targetGroup.ARN)
+		}
+		byName[name] = targetGroup
+	}
+	return byName, nil
+}
+
+// describeELBV2TagsMaxResources is the maximum number of resources in a single ELBV2 DescribeTags request.
+const describeELBV2TagsMaxResources = 20
+
+// describeELBV2TagsConcurrency is the maximum number of concurrent DescribeTags requests,
+// kept low to stay well within the ELBV2 API rate limits.
+const describeELBV2TagsConcurrency = 4
+
+// describeELBV2TagsInBatches returns the tags of the ELBV2 resources with the given ARNs,
+// splitting the ARNs into as many DescribeTags requests as needed, which are issued concurrently.
+// The tag descriptions are returned in the order of the batches.
+func describeELBV2TagsInBatches(ctx context.Context, cloud AWSCloud, arns []string) ([]elbv2types.TagDescription, error) {
+	var batches [][]string
+	for i := 0; i < len(arns); i += describeELBV2TagsMaxResources {
+		batches = append(batches, arns[i:min(i+describeELBV2TagsMaxResources, len(arns))])
+	}
+
+	results := make([][]elbv2types.TagDescription, len(batches))
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.SetLimit(describeELBV2TagsConcurrency)
+	for i, batch := range batches {
+		eg.Go(func() error {
+			response, err := cloud.ELBV2().DescribeTags(ctx, &elbv2.DescribeTagsInput{ResourceArns: batch})
+			if err != nil {
+				return err
+			}
+			results[i] = response.TagDescriptions
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	var tagDescriptions []elbv2types.TagDescription
+	for _, result := range results {
+		tagDescriptions = append(tagDescriptions, result...)
+	}
+	return tagDescriptions, nil
+}
+
+// FindTargetGroupByNameTag returns the target group of the cluster with the given Name tag and revision, if any.
+// An empty revision matches target groups without a revision tag, and an empty vpcID matches target groups in any VPC.
+func FindTargetGroupByNameTag(ctx context.Context, cloud AWSCloud, name string, revision string, vpcID string) (*TargetGroupInfo, error) {
+	targetGroups, err := ListELBV2TargetGroups(ctx, cloud, vpcID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []*TargetGroupInfo
+	for _, targetGroup := range targetGroups {
+		if targetGroup.NameTag() != name {
+			continue
+		}
+		if tag, _ := targetGroup.GetTag(KopsResourceRevisionTag); tag != revision {
+			continue
+		}
+		matches = append(matches, targetGroup)
+	}
+	if len(matches) > 1 {
+		return nil, fmt.Errorf("found %d target groups with Name tag %q and revision %q, expected 1", len(matches), name, revision)
+	}
+	if len(matches) == 0 {
+		return nil, nil
+	}
+	return matches[0], nil
+}
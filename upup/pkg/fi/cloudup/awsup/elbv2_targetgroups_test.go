@@ -0,0 +1,821 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awsup
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"k8s.io/kops/cloudmock/aws/mockelbv2"
+)
+
+// orphanTagsELBV2 also returns tags for a target group that was not requested,
+// as happens when a target group is deleted and recreated while listing.
+type orphanTagsELBV2 struct {
+	*mockelbv2.MockELBV2
+}
+
+func (m *orphanTagsELBV2) DescribeTags(ctx context.Context, request *elbv2.DescribeTagsInput, optFns ...func(*elbv2.Options)) (*elbv2.DescribeTagsOutput, error) {
+	response, err := m.MockELBV2.DescribeTags(ctx, request, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	response.TagDescriptions = append(response.TagDescriptions, elbv2types.TagDescription{
+		ResourceArn: aws.String("arn:aws-test:elasticloadbalancing:us-test-1:000000000000:targetgroup/deleted/0"),
+		Tags:        []elbv2types.Tag{{Key: aws.String("Name"), Value: aws.String("deleted")}},
+	})
+	return response, nil
+}
+
+func TestListELBV2TargetGroupsIgnoresUnrequestedTags(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := BuildMockAWSCloud("us-test-1", "a")
+	c := &orphanTagsELBV2{MockELBV2: &mockelbv2.MockELBV2{}}
+	cloud.MockELBV2 = c
+
+	tg, err := c.CreateTargetGroup(ctx, &elbv2.CreateTargetGroupInput{
+		Name:     aws.String("tcp-api"),
+		Port:     aws.Int32(443),
+		Protocol: elbv2types.ProtocolEnumTcp,
+		Tags:     []elbv2types.Tag{{Key: aws.String("Name"), Value: aws.String("tcp-api")}},
+	})
+	if err != nil {
+		t.Fatalf("error creating target group: %v", err)
+	}
+
+	targetGroups, err := ListELBV2TargetGroups(ctx, cloud, "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(targetGroups) != 1 {
+		t.Fatalf("expected 1 target group, got %d", len(targetGroups))
+	}
+	if targetGroups[0].ARN != aws.ToString(tg.TargetGroups[0].TargetGroupArn) {
+		t.Errorf("unexpected target group %q", targetGroups[0].ARN)
+	}
+	if name := targetGroups[0].NameTag(); name != "tcp-api" {
+		t.Errorf("expected Name tag %q, got %q", "tcp-api", name)
+	}
+}
+
+// countingTagsELBV2 records the DescribeTags requests, and how many were in flight at the same time.
+type countingTagsELBV2 struct {
+	*mockelbv2.MockELBV2
+
+	// delay slows down DescribeTags, so that concurrent requests overlap
+	delay time.Duration
+
+	mutex                sync.Mutex
+	describeTagsRequests []*elbv2.DescribeTagsInput
+	inFlight             int
+	maxInFlight          int
+	listedARNs           []string
+	pageSizes            []int32
+}
+
+func (m *countingTagsELBV2) DescribeTags(ctx context.Context, request *elbv2.DescribeTagsInput, optFns ...func(*elbv2.Options)) (*elbv2.DescribeTagsOutput, error) {
+	m.mutex.Lock()
+	m.describeTagsRequests = append(m.describeTagsRequests, request)
+	m.inFlight++
+	m.maxInFlight = max(m.maxInFlight, m.inFlight)
+	m.mutex.Unlock()
+
+	defer func() {
+		m.mutex.Lock()
+		m.inFlight--
+		m.mutex.Unlock()
+	}()
+
+	time.Sleep(m.delay)
+	return m.MockELBV2.DescribeTags(ctx, request, optFns...)
+}
+
+func (m *countingTagsELBV2) DescribeTargetGroups(ctx context.Context, request *elbv2.DescribeTargetGroupsInput, optFns ...func(*elbv2.Options)) (*elbv2.DescribeTargetGroupsOutput, error) {
+	m.pageSizes = append(m.pageSizes, aws.ToInt32(request.PageSize))
+	response, err := m.MockELBV2.DescribeTargetGroups(ctx, request, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	for _, tg := range response.TargetGroups {
+		m.listedARNs = append(m.listedARNs, aws.ToString(tg.TargetGroupArn))
+	}
+	return response, nil
+}
+
+func TestListELBV2TargetGroupsBatchesTags(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := BuildMockAWSCloud("us-test-1", "a")
+	c := &countingTagsELBV2{MockELBV2: &mockelbv2.MockELBV2{}}
+	cloud.MockELBV2 = c
+
+	nameByARN := make(map[string]string)
+	for i := 0; i < 45; i++ {
+		name := fmt.Sprintf("tcp-api-%d", i)
+		tg, err := c.CreateTargetGroup(ctx, &elbv2.CreateTargetGroupInput{
+			Name:     aws.String(name),
+			Port:     aws.Int32(443),
+			Protocol: elbv2types.ProtocolEnumTcp,
+			Tags:     []elbv2types.Tag{{Key: aws.String("Name"), Value: aws.String(name)}},
+		})
+		if err != nil {
+			t.Fatalf("error creating target group: %v", err)
+		}
+		nameByARN[aws.ToString(tg.TargetGroups[0].TargetGroupArn)] = name
+	}
+
+	targetGroups, err := ListELBV2TargetGroups(ctx, cloud, "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(c.describeTagsRequests) != 3 {
+		t.Errorf("expected 3 DescribeTags requests, got %d", len(c.describeTagsRequests))
+	}
+	for _, request := range c.describeTagsRequests {
+		if len(request.ResourceArns) > 20 {
+			t.Errorf("expected at most 20 resources per DescribeTags request, got %d", len(request.ResourceArns))
+		}
+	}
+	if len(targetGroups) != 45 {
+		t.Fatalf("expected 45 target groups, got %d", len(targetGroups))
+	}
+	for _, targetGroup := range targetGroups {
+		if name := targetGroup.NameTag(); name != nameByARN[targetGroup.ARN] {
+			t.Errorf("expected Name tag %q for %q, got %q", nameByARN[targetGroup.ARN], targetGroup.ARN, name)
+		}
+	}
+}
+
+func TestListELBV2TargetGroupsConcurrentTags(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := BuildMockAWSCloud("us-test-1", "a")
+	c := &countingTagsELBV2{MockELBV2: &mockelbv2.MockELBV2{}, delay: 10 * time.Millisecond}
+	cloud.MockELBV2 = c
+
+	nameByARN := make(map[string]string)
+	for i := 0; i < 200; i++ {
+		name := fmt.Sprintf("tcp-api-%d", i)
+		tg, err := c.CreateTargetGroup(ctx, &elbv2.CreateTargetGroupInput{
+			Name:     aws.String(name),
+			Port:     aws.Int32(443),
+			Protocol: elbv2types.ProtocolEnumTcp,
+			Tags:     []elbv2types.Tag{{Key: aws.String("Name"), Value: aws.String(name)}},
+		})
+		if err != nil {
+			t.Fatalf("error creating target group: %v", err)
+		}
+		nameByARN[aws.ToString(tg.TargetGroups[0].TargetGroupArn)] = name
+	}
+
+	targetGroups, err := ListELBV2TargetGroups(ctx, cloud, "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(c.describeTagsRequests) != 10 {
+		t.Errorf("expected 10 DescribeTags requests, got %d", len(c.describeTagsRequests))
+	}
+	if c.maxInFlight > describeELBV2TagsConcurrency {
+		t.Errorf("expected at most %d concurrent DescribeTags requests, got %d", describeELBV2TagsConcurrency, c.maxInFlight)
+	}
+
+	var actualARNs []string
+	for _, targetGroup := range targetGroups {
+		actualARNs = append(actualARNs, targetGroup.ARN)
+		if name := targetGroup.NameTag(); name != nameByARN[targetGroup.ARN] {
+			t.Errorf("expected Name tag %q for %q, got %q", nameByARN[targetGroup.ARN], targetGroup.ARN, name)
+		}
+	}
+	if !reflect.DeepEqual(actualARNs, c.listedARNs) {
+		t.Errorf("expected target groups in the order they were listed")
+	}
+}
+
+func BenchmarkListELBV2TargetGroups(b *testing.B) {
+	ctx := context.TODO()
+
+	cloud := BuildMockAWSCloud("us-test-1", "a")
+	c := &countingTagsELBV2{MockELBV2: &mockelbv2.MockELBV2{}, delay: time.Millisecond}
+	cloud.MockELBV2 = c
+
+	for i := 0; i < 1000; i++ {
+		name := fmt.Sprintf("tcp-api-%d", i)
+		if _, err := c.CreateTargetGroup(ctx, &elbv2.CreateTargetGroupInput{
+			Name:     aws.String(name),
+			Port:     aws.Int32(443),
+			Protocol: elbv2types.ProtocolEnumTcp,
+			Tags:     []elbv2types.Tag{{Key: aws.String("Name"), Value: aws.String(name)}},
+		}); err != nil {
+			b.Fatalf("error creating target group: %v", err)
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ListELBV2TargetGroups(ctx, cloud, "", ""); err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+func TestListELBV2TargetGroupsByVPC(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := BuildMockAWSCloud("us-test-1", "a")
+	c := &mockelbv2.MockELBV2{}
+	cloud.MockELBV2 = c
+
+	arnByVPC := make(map[string]string)
+	for i, vpcID := range []string{"vpc-1", "vpc-2"} {
+		tg, err := c.CreateTargetGroup(ctx, &elbv2.CreateTargetGroupInput{
+			Name:     aws.String(fmt.Sprintf("tcp-api-%d", i)),
+			Port:     aws.Int32(443),
+			Protocol: elbv2types.ProtocolEnumTcp,
+			VpcId:    aws.String(vpcID),
+			Tags:     []elbv2types.Tag{{Key: aws.String("Name"), Value: aws.String("tcp-api")}},
+		})
+		if err != nil {
+			t.Fatalf("error creating target group: %v", err)
+		}
+		arnByVPC[vpcID] = aws.ToString(tg.TargetGroups[0].TargetGroupArn)
+	}
+
+	all, err := ListELBV2TargetGroups(ctx, cloud, "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(all) != 2 {
+		t.Errorf("expected 2 target groups without a VPC filter, got %d", len(all))
+	}
+
+	for _, vpcID := range []string{"vpc-1", "vpc-2"} {
+		targetGroups, err := ListELBV2TargetGroups(ctx, cloud, vpcID, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(targetGroups) != 1 {
+			t.Fatalf("expected 1 target group in %q, got %d", vpcID, len(targetGroups))
+		}
+		if targetGroups[0].ARN != arnByVPC[vpcID] {
+			t.Errorf("expected target group %q in %q, got %q", arnByVPC[vpcID], vpcID, targetGroups[0].ARN)
+		}
+		if name := targetGroups[0].NameTag(); name != "tcp-api" {
+			t.Errorf("expected Name tag %q, got %q", "tcp-api", name)
+		}
+		if actual := targetGroups[0].VpcID(); actual != vpcID {
+			t.Errorf("expected VPC %q, got %q", vpcID, actual)
+		}
+
+		found, err := FindTargetGroupByNameTag(ctx, cloud, "tcp-api", "", vpcID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if found == nil || found.ARN != arnByVPC[vpcID] {
+			t.Errorf("expected to find target group %q in %q, got %+v", arnByVPC[vpcID], vpcID, found)
+		}
+	}
+
+	none, err := ListELBV2TargetGroups(ctx, cloud, "vpc-3", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(none) != 0 {
+		t.Errorf("expected no target groups in an unrelated VPC, got %d", len(none))
+	}
+}
+
+func TestListELBV2TargetGroupsByLoadBalancer(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := BuildMockAWSCloud("us-test-1", "a")
+	c := &countingTagsELBV2{MockELBV2: &mockelbv2.MockELBV2{}}
+	cloud.MockELBV2 = c
+
+	lb, err := c.CreateLoadBalancer(ctx, &elbv2.CreateLoadBalancerInput{
+		Name: aws.String("api"),
+		Type: elbv2types.LoadBalancerTypeEnumNetwork,
+	})
+	if err != nil {
+		t.Fatalf("error creating load balancer: %v", err)
+	}
+	lbARN := aws.ToString(lb.LoadBalancers[0].LoadBalancerArn)
+
+	var arns []string
+	for i := 0; i < 2; i++ {
+		tg, err := c.CreateTargetGroup(ctx, &elbv2.CreateTargetGroupInput{
+			Name:     aws.String(fmt.Sprintf("tcp-api-%d", i)),
+			Port:     aws.Int32(443),
+			Protocol: elbv2types.ProtocolEnumTcp,
+			VpcId:    aws.String("vpc-1"),
+			Tags:     []elbv2types.Tag{{Key: aws.String("Name"), Value: aws.String("tcp-api")}},
+		})
+		if err != nil {
+			t.Fatalf("error creating target group: %v", err)
+		}
+		arns = append(arns, aws.ToString(tg.TargetGroups[0].TargetGroupArn))
+	}
+
+	// Only the first target group is attached to the load balancer
+	if _, err := c.CreateListener(ctx, &elbv2.CreateListenerInput{
+		LoadBalancerArn: aws.String(lbARN),
+		Port:            aws.Int32(443),
+		Protocol:        elbv2types.ProtocolEnumTcp,
+		DefaultActions:  []elbv2types.Action{{Type: elbv2types.ActionTypeEnumForward, TargetGroupArn: aws.String(arns[0])}},
+	}); err != nil {
+		t.Fatalf("error creating listener: %v", err)
+	}
+
+	targetGroups, err := ListELBV2TargetGroups(ctx, cloud, "", lbARN)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(targetGroups) != 1 {
+		t.Fatalf("expected 1 target group of the load balancer, got %d", len(targetGroups))
+	}
+	if targetGroups[0].ARN != arns[0] {
+		t.Errorf("expected target group %q, got %q", arns[0], targetGroups[0].ARN)
+	}
+	if vpcID := targetGroups[0].VpcID(); vpcID != "vpc-1" {
+		t.Errorf("expected VPC %q, got %q", "vpc-1", vpcID)
+	}
+	if !reflect.DeepEqual(c.listedARNs, []string{arns[0]}) {
+		t.Errorf("expected only the target group of the load balancer to be described, got %v", c.listedARNs)
+	}
+
+	other, err := ListELBV2TargetGroups(ctx, cloud, "", strings.Replace(lbARN, "/api/", "/other/", 1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(other) != 0 {
+		t.Errorf("expected no target groups of an unrelated load balancer, got %d", len(other))
+	}
+}
+
+func TestListELBV2TargetGroupsByName(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := BuildMockAWSCloud("us-test-1", "a")
+	c := &mockelbv2.MockELBV2{}
+	cloud.MockELBV2 = c
+
+	createTargetGroup := func(name string, nameTag string) string {
+		request := &elbv2.CreateTargetGroupInput{
+			Name:     aws.String(name),
+			Port:     aws.Int32(443),
+			Protocol: elbv2types.ProtocolEnumTcp,
+		}
+		if nameTag != "" {
+			request.Tags = []elbv2types.Tag{{Key: aws.String("Name"), Value: aws.String(nameTag)}}
+		}
+		tg, err := c.CreateTargetGroup(ctx, request)
+		if err != nil {
+			t.Fatalf("error creating target group: %v", err)
+		}
+		return aws.ToString(tg.TargetGroups[0].TargetGroupArn)
+	}
+
+	tcpARN := createTargetGroup("tcp-api", "tcp-api")
+	tlsARN := createTargetGroup("tls-api", "tls-api")
+	createTargetGroup("untagged", "")
+
+	byName, err := ListELBV2TargetGroupsByName(ctx, cloud, "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	actual := make(map[string]string)
+	for name, targetGroup := range byName {
+		actual[name] = targetGroup.ARN
+	}
+	expected := map[string]string{"tcp-api": tcpARN, "tls-api": tlsARN}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected target groups %v, got %v", expected, actual)
+	}
+
+	createTargetGroup("tcp-api-copy", "tcp-api")
+	if _, err := ListELBV2TargetGroupsByName(ctx, cloud, "", ""); err == nil {
+		t.Errorf("expected an error for duplicate Name tags")
+	} else if !strings.Contains(err.Error(), `"tcp-api"`) {
+		t.Errorf("expected the error to name the duplicate Name tag, got %v", err)
+	}
+}
+
+func TestListELBV2TargetGroupsExcludingTags(t *testing.T) {
+	ctx := context.TODO()
+
+	mockCloud := BuildMockAWSCloud("us-test-1", "a")
+	c := &mockelbv2.MockELBV2{}
+	mockCloud.MockELBV2 = c
+	cloud := mockCloud.WithTags(map[string]string{"KubernetesCluster": "example.com"})
+
+	createTargetGroup := func(name string, tags map[string]string) string {
+		request := &elbv2.CreateTargetGroupInput{
+			Name:     aws.String(name),
+			Port:     aws.Int32(443),
+			Protocol: elbv2types.ProtocolEnumTcp,
+			Tags:     []elbv2types.Tag{{Key: aws.String("KubernetesCluster"), Value: aws.String("example.com")}},
+		}
+		for k, v := range tags {
+			request.Tags = append(request.Tags, elbv2types.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+		tg, err := c.CreateTargetGroup(ctx, request)
+		if err != nil {
+			t.Fatalf("error creating target group: %v", err)
+		}
+		return aws.ToString(tg.TargetGroups[0].TargetGroupArn)
+	}
+
+	apiARN := createTargetGroup("api", map[string]string{"purpose": "api"})
+	ingressARN := createTargetGroup("ingress", map[string]string{"purpose": "ingress"})
+	backupARN := createTargetGroup("backup", map[string]string{"purpose": "api", "backup": "true"})
+
+	grid := []struct {
+		name     string
+		opts     []ListELBV2TargetGroupsOption
+		expected []string
+	}{
+		{
+			name:     "no exclusions",
+			expected: []string{apiARN, ingressARN, backupARN},
+		},
+		{
+			name:     "excluded value",
+			opts:     []ListELBV2TargetGroupsOption{ExcludingTargetGroupsTagged(map[string]string{"purpose": "ingress"})},
+			expected: []string{apiARN, backupARN},
+		},
+		{
+			name:     "excluded key with any value",
+			opts:     []ListELBV2TargetGroupsOption{ExcludingTargetGroupsTagged(map[string]string{"backup": ""})},
+			expected: []string{apiARN, ingressARN},
+		},
+		{
+			name:     "exclusion not present",
+			opts:     []ListELBV2TargetGroupsOption{ExcludingTargetGroupsTagged(map[string]string{"purpose": "other"})},
+			expected: []string{apiARN, ingressARN, backupARN},
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			targetGroups, err := ListELBV2TargetGroups(ctx, cloud, "", "", g.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var actual []string
+			for _, targetGroup := range targetGroups {
+				actual = append(actual, targetGroup.ARN)
+			}
+			sort.Strings(actual)
+			sort.Strings(g.expected)
+			if !reflect.DeepEqual(actual, g.expected) {
+				t.Errorf("expected target groups %v, got %v", g.expected, actual)
+			}
+		})
+	}
+}
+
+func TestListELBV2TargetGroupsIncludingNonMatching(t *testing.T) {
+	ctx := context.TODO()
+
+	mockCloud := BuildMockAWSCloud("us-test-1", "a")
+	c := &mockelbv2.MockELBV2{}
+	mockCloud.MockELBV2 = c
+	cloud := mockCloud.WithTags(map[string]string{"KubernetesCluster": "example.com"})
+
+	createTargetGroup := func(name string, tags map[string]string) string {
+		request := &elbv2.CreateTargetGroupInput{
+			Name:     aws.String(name),
+			Port:     aws.Int32(443),
+			Protocol: elbv2types.ProtocolEnumTcp,
+		}
+		for k, v := range tags {
+			request.Tags = append(request.Tags, elbv2types.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+		tg, err := c.CreateTargetGroup(ctx, request)
+		if err != nil {
+			t.Fatalf("error creating target group: %v", err)
+		}
+		return aws.ToString(tg.TargetGroups[0].TargetGroupArn)
+	}
+
+	apiARN := createTargetGroup("api", map[string]string{"KubernetesCluster": "example.com"})
+	staleARN := createTargetGroup("stale", map[string]string{"KubernetesCluster": "old.example.com"})
+	untaggedARN := createTargetGroup("untagged", nil)
+
+	grid := []struct {
+		name     string
+		opts     []ListELBV2TargetGroupsOption
+		expected map[string]bool
+	}{
+		{
+			name:     "filtered by default",
+			expected: map[string]bool{apiARN: true},
+		},
+		{
+			name:     "including non-matching",
+			opts:     []ListELBV2TargetGroupsOption{IncludingNonMatchingTargetGroups()},
+			expected: map[string]bool{apiARN: true, staleARN: false, untaggedARN: false},
+		},
+		{
+			name:     "including non-matching with exclusions",
+			opts:     []ListELBV2TargetGroupsOption{IncludingNonMatchingTargetGroups(), ExcludingTargetGroupsTagged(map[string]string{"KubernetesCluster": ""})},
+			expected: map[string]bool{apiARN: false, staleARN: false, untaggedARN: false},
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			targetGroups, err := ListELBV2TargetGroups(ctx, cloud, "", "", g.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			actual := make(map[string]bool)
+			for _, targetGroup := range targetGroups {
+				actual[targetGroup.ARN] = targetGroup.MatchesClusterTags
+			}
+			if !reflect.DeepEqual(actual, g.expected) {
+				t.Errorf("expected target groups %v, got %v", g.expected, actual)
+			}
+		})
+	}
+}
+
+func TestTargetGroupInfoHealthCheck(t *testing.T) {
+	grid := []struct {
+		name                  string
+		targetGroup           elbv2types.TargetGroup
+		healthCheckProtocol   string
+		healthCheckPort       string
+		healthCheckPath       string
+		healthyThresholdCount int32
+	}{
+		{
+			name: "unset",
+		},
+		{
+			name: "tcp",
+			targetGroup: elbv2types.TargetGroup{
+				HealthCheckProtocol:   elbv2types.ProtocolEnumTcp,
+				HealthCheckPort:       aws.String("traffic-port"),
+				HealthyThresholdCount: aws.Int32(2),
+			},
+			healthCheckProtocol:   "TCP",
+			healthCheckPort:       "traffic-port",
+			healthyThresholdCount: 2,
+		},
+		{
+			name: "https",
+			targetGroup: elbv2types.TargetGroup{
+				HealthCheckProtocol:   elbv2types.ProtocolEnumHttps,
+				HealthCheckPort:       aws.String("8443"),
+				HealthCheckPath:       aws.String("/healthz"),
+				HealthyThresholdCount: aws.Int32(3),
+			},
+			healthCheckProtocol:   "HTTPS",
+			healthCheckPort:       "8443",
+			healthCheckPath:       "/healthz",
+			healthyThresholdCount: 3,
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			info := &TargetGroupInfo{TargetGroup: g.targetGroup}
+			if actual := info.HealthCheckProtocol(); actual != g.healthCheckProtocol {
+				t.Errorf("expected HealthCheckProtocol %q, got %q", g.healthCheckProtocol, actual)
+			}
+			if actual := info.HealthCheckPort(); actual != g.healthCheckPort {
+				t.Errorf("expected HealthCheckPort %q, got %q", g.healthCheckPort, actual)
+			}
+			if actual := info.HealthCheckPath(); actual != g.healthCheckPath {
+				t.Errorf("expected HealthCheckPath %q, got %q", g.healthCheckPath, actual)
+			}
+			if actual := info.HealthyThresholdCount(); actual != g.healthyThresholdCount {
+				t.Errorf("expected HealthyThresholdCount %d, got %d", g.healthyThresholdCount, actual)
+			}
+		})
+	}
+}
+
+func TestFindTargetGroupByNameTag(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := BuildMockAWSCloud("us-test-1", "a")
+	c := &mockelbv2.MockELBV2{}
+	cloud.MockELBV2 = c
+
+	arnByName := make(map[string]string)
+	for i, nameTag := range []string{"tcp-api", "tls-api", "tls-api"} {
+		tg, err := c.CreateTargetGroup(ctx, &elbv2.CreateTargetGroupInput{
+			Name:     aws.String(fmt.Sprintf("target-group-%d", i)),
+			Port:     aws.Int32(443),
+			Protocol: elbv2types.ProtocolEnumTcp,
+			Tags:     []elbv2types.Tag{{Key: aws.String("Name"), Value: aws.String(nameTag)}},
+		})
+		if err != nil {
+			t.Fatalf("error creating target group: %v", err)
+		}
+		arnByName[nameTag] = aws.ToString(tg.TargetGroups[0].TargetGroupArn)
+	}
+
+	grid := []struct {
+		name        string
+		expectedARN string
+		expectError bool
+	}{
+		{name: "kops-controller"},
+		{name: "tcp-api", expectedARN: arnByName["tcp-api"]},
+		{name: "tls-api", expectError: true},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			found, err := FindTargetGroupByNameTag(ctx, cloud, g.name, "", "")
+			if g.expectError {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", found)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if g.expectedARN == "" {
+				if found != nil {
+					t.Errorf("expected no target group, got %q", found.ARN)
+				}
+				return
+			}
+			if found == nil || found.ARN != g.expectedARN {
+				t.Errorf("expected target group %q, got %+v", g.expectedARN, found)
+			}
+		})
+	}
+}
+
+func TestTargetGroupInfoTags(t *testing.T) {
+	info := &TargetGroupInfo{
+		Tags: []elbv2types.Tag{
+			{Key: aws.String("name"), Value: aws.String("lower")},
+			{Key: aws.String("Name"), Value: aws.String("exact")},
+			{Key: aws.String("COST-CENTER"), Value: aws.String("1234")},
+		},
+	}
+
+	grid := []struct {
+		key           string
+		expectedValue string
+		expectedFound bool
+	}{
+		{key: "Name", expectedValue: "exact", expectedFound: true},
+		{key: "NAME", expectedValue: "lower", expectedFound: true},
+		{key: "cost-center", expectedValue: "1234", expectedFound: true},
+		{key: "team"},
+	}
+	for _, g := range grid {
+		value, found := info.GetTagFold(g.key)
+		if value != g.expectedValue || found != g.expectedFound {
+			t.Errorf("GetTagFold(%q): expected (%q, %v), got (%q, %v)", g.key, g.expectedValue, g.expectedFound, value, found)
+		}
+	}
+
+	if _, found := info.GetTag("cost-center"); found {
+		t.Errorf("expected GetTag to remain case-sensitive")
+	}
+
+	expected := map[string]string{"name": "lower", "Name": "exact", "COST-CENTER": "1234"}
+	if actual := info.TagsMap(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("TagsMap: expected %v, got %v", expected, actual)
+	}
+	if actual := (&TargetGroupInfo{}).TagsMap(); len(actual) != 0 {
+		t.Errorf("TagsMap: expected no tags, got %v", actual)
+	}
+}
+
+func TestListELBV2TargetGroupsWithoutTags(t *testing.T) {
+	ctx := context.TODO()
+
+	mockCloud := BuildMockAWSCloud("us-test-1", "a")
+	c := &countingTagsELBV2{MockELBV2: &mockelbv2.MockELBV2{}}
+	mockCloud.MockELBV2 = c
+	cloud := mockCloud.WithTags(map[string]string{"KubernetesCluster": "example.com"})
+
+	var expected []string
+	for _, name := range []string{"api", "other"} {
+		tg, err := c.CreateTargetGroup(ctx, &elbv2.CreateTargetGroupInput{
+			Name:     aws.String(name),
+			Port:     aws.Int32(443),
+			Protocol: elbv2types.ProtocolEnumTcp,
+			Tags:     []elbv2types.Tag{{Key: aws.String("KubernetesCluster"), Value: aws.String(name + ".example.com")}},
+		})
+		if err != nil {
+			t.Fatalf("error creating target group: %v", err)
+		}
+		expected = append(expected, aws.ToString(tg.TargetGroups[0].TargetGroupArn))
+	}
+
+	targetGroups, err := ListELBV2TargetGroups(ctx, cloud, "", "", WithoutTargetGroupTags())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(c.describeTagsRequests) != 0 {
+		t.Errorf("expected no DescribeTags calls, got %d", len(c.describeTagsRequests))
+	}
+	var actual []string
+	for _, targetGroup := range targetGroups {
+		actual = append(actual, targetGroup.ARN)
+		if len(targetGroup.Tags) != 0 || targetGroup.MatchesClusterTags {
+			t.Errorf("expected target group %q without tags, got tags %v and MatchesClusterTags %v", targetGroup.ARN, targetGroup.Tags, targetGroup.MatchesClusterTags)
+		}
+	}
+	sort.Strings(actual)
+	sort.Strings(expected)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected target groups %v, got %v", expected, actual)
+	}
+
+	// The tags are described by default
+	if _, err := ListELBV2TargetGroups(ctx, cloud, "", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(c.describeTagsRequests) == 0 {
+		t.Errorf("expected DescribeTags calls by default")
+	}
+}
+
+func TestListELBV2TargetGroupsPageSize(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := BuildMockAWSCloud("us-test-1", "a")
+
+	grid := []struct {
+		name             string
+		opts             []ListELBV2TargetGroupsOption
+		expectedPageSize int32
+		expectedError    string
+	}{
+		{
+			name:             "default",
+			expectedPageSize: 400,
+		},
+		{
+			name:             "configured",
+			opts:             []ListELBV2TargetGroupsOption{WithTargetGroupsPageSize(50)},
+			expectedPageSize: 50,
+		},
+		{
+			name:          "zero",
+			opts:          []ListELBV2TargetGroupsOption{WithTargetGroupsPageSize(0)},
+			expectedError: "invalid page size 0",
+		},
+		{
+			name:          "too large",
+			opts:          []ListELBV2TargetGroupsOption{WithTargetGroupsPageSize(401)},
+			expectedError: "invalid page size 401",
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			c := &countingTagsELBV2{MockELBV2: &mockelbv2.MockELBV2{}}
+			cloud.MockELBV2 = c
+
+			_, err := ListELBV2TargetGroups(ctx, cloud, "", "", g.opts...)
+			if g.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), g.expectedError) {
+					t.Fatalf("expected error containing %q, got %v", g.expectedError, err)
+				}
+				if len(c.pageSizes) != 0 {
+					t.Errorf("expected no DescribeTargetGroups calls, got %d", len(c.pageSizes))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if expected := []int32{g.expectedPageSize}; !reflect.DeepEqual(c.pageSizes, expected) {
+				t.Errorf("expected page sizes %v, got %v", expected, c.pageSizes)
+			}
+		})
+	}
+}
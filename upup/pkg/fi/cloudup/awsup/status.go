@@ -18,44 +18,74 @@ package awsup
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
 	"k8s.io/klog/v2"
 	"k8s.io/kops/pkg/apis/kops"
 	"k8s.io/kops/protokube/pkg/etcd"
 	"k8s.io/kops/upup/pkg/fi"
 )
 
-// FindClusterStatus discovers the status of the cluster, by looking for the tagged etcd volumes
-func (c *awsCloudImplementation) FindClusterStatus(cluster *kops.Cluster) (*kops.ClusterStatus, error) {
-	etcdStatus, err := findEtcdStatus(c, cluster)
+// FindClusterStatus discovers the status of the cluster, by looking for the tagged etcd volumes and the API load balancer.
+// Failures to discover parts of the status, e.g. the health of a target group, are reported as warnings in the status.
+func (c *awsCloudImplementation) FindClusterStatus(ctx context.Context, cluster *kops.Cluster) (*kops.ClusterStatus, error) {
+	status, err := findClusterStatus(ctx, c, cluster)
 	if err != nil {
 		return nil, err
 	}
-	status := &kops.ClusterStatus{
-		EtcdClusters: etcdStatus,
-	}
 	klog.V(2).Infof("Cluster status (from cloud): %v", fi.DebugAsJsonString(status))
 	return status, nil
 }
 
-// FindClusterStatus discovers the status of the cluster, by looking for the tagged etcd volumes
-func (c *MockAWSCloud) FindClusterStatus(cluster *kops.Cluster) (*kops.ClusterStatus, error) {
-	etcdStatus, err := findEtcdStatus(c, cluster)
+// FindClusterStatus discovers the status of the cluster, by looking for the tagged etcd volumes and the API load balancer
+func (c *MockAWSCloud) FindClusterStatus(ctx context.Context, cluster *kops.Cluster) (*kops.ClusterStatus, error) {
+	return findClusterStatus(ctx, c, cluster)
+}
+
+func findClusterStatus(ctx context.Context, c AWSCloud, cluster *kops.Cluster) (*kops.ClusterStatus, error) {
+	warnings := &statusWarnings{ctx: ctx}
+	etcdStatus, etcdMembersByInstance, err := findEtcdStatus(ctx, c, cluster, warnings)
+	if err != nil {
+		return nil, err
+	}
+	loadBalancerStatus, err := findAPILoadBalancerStatus(ctx, c, cluster, etcdMembersByInstance, warnings)
 	if err != nil {
 		return nil, err
 	}
 	return &kops.ClusterStatus{
-		EtcdClusters: etcdStatus,
+		EtcdClusters:  etcdStatus,
+		LoadBalancers: loadBalancerStatus,
+		Warnings:      warnings.warnings,
 	}, nil
 }
 
-// findEtcdStatus discovers the status of etcd, by looking for the tagged etcd volumes
-func findEtcdStatus(c AWSCloud, cluster *kops.Cluster) ([]kops.EtcdClusterStatus, error) {
+// statusWarnings collects the failures to discover parts of the cluster status, which are reported in the status
+// rather than failing FindClusterStatus.
+type statusWarnings struct {
+	ctx      context.Context
+	warnings []string
+}
+
+// add records the error as a warning, unless the context is done, in which case the context error is returned.
+func (w *statusWarnings) add(err error) error {
+	if ctxErr := w.ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	klog.Warningf("%v", err)
+	w.warnings = append(w.warnings, err.Error())
+	return nil
+}
+
+// findEtcdStatus discovers the status of etcd, by looking for the tagged etcd volumes.
+// It also returns the etcd members (as <etcd cluster>/<member>) whose volumes are attached to each instance.
+func findEtcdStatus(ctx context.Context, c AWSCloud, cluster *kops.Cluster, warnings *statusWarnings) ([]kops.EtcdClusterStatus, map[string][]string, error) {
 	klog.V(2).Infof("Querying AWS for etcd volumes")
 	statusMap := make(map[string]*kops.EtcdClusterStatus)
 
@@ -70,14 +100,16 @@ func findEtcdStatus(c AWSCloud, cluster *kops.Cluster) ([]kops.EtcdClusterStatus
 	klog.V(2).Infof("Listing EC2 Volumes")
 	paginator := ec2.NewDescribeVolumesPaginator(c.EC2(), request)
 	for paginator.HasMorePages() {
-		page, err := paginator.NextPage(context.TODO())
+		page, err := paginator.NextPage(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("error describing volumes: %v", err)
+			return nil, nil, fmt.Errorf("error describing volumes: %w", err)
 		}
 		volumes = append(volumes, page.Volumes...)
 	}
 
 	var err error
+	etcdMembersByInstance := make(map[string][]string)
+	etcdClusterSizes := make(map[string]int)
 	for _, volume := range volumes {
 		volumeID := aws.ToString(volume.VolumeId)
 
@@ -92,7 +124,7 @@ func findEtcdStatus(c AWSCloud, cluster *kops.Cluster) ([]kops.EtcdClusterStatus
 				etcdClusterName = strings.TrimPrefix(k, TagNameEtcdClusterPrefix)
 				etcdClusterSpec, err = etcd.ParseEtcdClusterSpec(etcdClusterName, v)
 				if err != nil {
-					return nil, fmt.Errorf("error parsing etcd cluster tag %q on volume %q: %v", v, volumeID, err)
+					return nil, nil, fmt.Errorf("error parsing etcd cluster tag %q on volume %q: %w", v, volumeID, err)
 				}
 			} else if k == TagNameRolePrefix+TagRoleMaster || k == TagNameRolePrefix+TagRoleControlPlane {
 				master = true
@@ -110,16 +142,287 @@ func findEtcdStatus(c AWSCloud, cluster *kops.Cluster) ([]kops.EtcdClusterStatus
 			statusMap[etcdClusterName] = status
 		}
 
+		etcdClusterSizes[etcdClusterName] = len(etcdClusterSpec.NodeNames)
+
 		memberName := etcdClusterSpec.NodeName
-		status.Members = append(status.Members, &kops.EtcdMemberStatus{
-			Name:     memberName,
-			VolumeID: aws.ToString(volume.VolumeId),
-		})
+		member := &kops.EtcdMemberStatus{
+			Name:             memberName,
+			VolumeID:         aws.ToString(volume.VolumeId),
+			AvailabilityZone: aws.ToString(volume.AvailabilityZone),
+		}
+		status.Members = append(status.Members, member)
+		for _, attachment := range volume.Attachments {
+			if instanceID := aws.ToString(attachment.InstanceId); instanceID != "" {
+				// EBS volumes attach to a single instance
+				member.InstanceID = instanceID
+				etcdMembersByInstance[instanceID] = append(etcdMembersByInstance[instanceID], etcdClusterName+"/"+memberName)
+			}
+		}
+	}
+
+	runningInstances, err := findRunningInstances(ctx, c, etcdMembersByInstance)
+	if err != nil {
+		// The health of the members is best-effort
+		if err := warnings.add(fmt.Errorf("unable to determine the health of etcd members: %w", err)); err != nil {
+			return nil, nil, err
+		}
 	}
 
 	var status []kops.EtcdClusterStatus
 	for _, v := range statusMap {
+		if err == nil {
+			setEtcdClusterHealth(v, etcdClusterSizes[v.Name], runningInstances)
+		}
 		status = append(status, *v)
 	}
+	return status, etcdMembersByInstance, nil
+}
+
+// findRunningInstances returns which of the instances are running.
+func findRunningInstances(ctx context.Context, c AWSCloud, instances map[string][]string) (map[string]bool, error) {
+	running := make(map[string]bool)
+	if len(instances) == 0 {
+		return running, nil
+	}
+	request := &ec2.DescribeInstancesInput{}
+	for instanceID := range instances {
+		request.InstanceIds = append(request.InstanceIds, instanceID)
+	}
+	sort.Strings(request.InstanceIds)
+	paginator := ec2.NewDescribeInstancesPaginator(c.EC2(), request)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error describing instances: %w", err)
+		}
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				if instance.State != nil && instance.State.Name == ec2types.InstanceStateNameRunning {
+					running[aws.ToString(instance.InstanceId)] = true
+				}
+			}
+		}
+	}
+	return running, nil
+}
+
+// setEtcdClusterHealth marks the members whose volume is attached to a running instance as healthy,
+// and reports whether a majority of the size members of the cluster are healthy.
+// This only detects members that cannot be running; it does not query etcd itself.
+func setEtcdClusterHealth(status *kops.EtcdClusterStatus, size int, runningInstances map[string]bool) {
+	healthy := 0
+	for _, member := range status.Members {
+		member.Healthy = fi.PtrTo(member.InstanceID != "" && runningInstances[member.InstanceID])
+		if *member.Healthy {
+			healthy++
+		}
+	}
+	if size < len(status.Members) {
+		size = len(status.Members)
+	}
+	status.Quorum = fi.PtrTo(healthy > size/2)
+}
+
+// findAPILoadBalancerStatus discovers the status of the API network load balancer, including the effective TLS configuration of its listeners
+// and the targets registered with its target groups.
+// The load balancer itself must be found, but failures to describe its listeners, their security policies
+// or the health of its target groups are recorded as warnings, and the corresponding parts of the status are left out.
+func findAPILoadBalancerStatus(ctx context.Context, c AWSCloud, cluster *kops.Cluster, etcdMembersByInstance map[string][]string, warnings *statusWarnings) ([]kops.LoadBalancerStatus, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if cluster.Spec.API.LoadBalancer == nil || cluster.Spec.API.LoadBalancer.Class != kops.LoadBalancerClassNetwork {
+		return nil, nil
+	}
+
+	klog.V(2).Infof("Querying AWS for API load balancer")
+	allLoadBalancers, err := ListELBV2LoadBalancers(ctx, c)
+	if err != nil {
+		return nil, fmt.Errorf("looking for AWS NLB: %w", err)
+	}
+	latest := FindLatestELBV2ByNameTag(allLoadBalancers, "api."+cluster.Name)
+	if latest == nil {
+		return nil, nil
+	}
+
+	listeners, err := ListELBV2ListenersWithTags(ctx, c, latest.ARN())
+	if err != nil {
+		if err := warnings.add(fmt.Errorf("describing the listeners of load balancer %q: %w", latest.ARN(), err)); err != nil {
+			return nil, err
+		}
+	}
+
+	status := kops.LoadBalancerStatus{
+		Name:    latest.NameTag(),
+		ARN:     latest.ARN(),
+		DNSName: aws.ToString(latest.LoadBalancer.DNSName),
+
+		CanonicalHostedZoneID: aws.ToString(latest.LoadBalancer.CanonicalHostedZoneId),
+	}
+	if latest.LoadBalancer.State != nil {
+		status.State = string(latest.LoadBalancer.State.Code)
+	}
+	status.AvailabilityZones, status.SubnetIDs = loadBalancerZones(latest.LoadBalancer.AvailabilityZones)
+	// The effective settings, which may differ from the cluster spec until the next update
+	status.IPAddressType = string(latest.LoadBalancer.IpAddressType)
+	status.SecurityGroups = latest.LoadBalancer.SecurityGroups
+	minimumTLSVersions := make(map[string]string)
+	certificateNotAfters := make(map[string]string)
+	// TODO: Report listener attributes (e.g. tcp.idle_timeout.seconds) once the vendored
+	// elasticloadbalancingv2 SDK (v1.34.0) is updated to a version with DescribeListenerAttributes.
+	for _, info := range listeners {
+		listener := info.Listener
+		listenerStatus := kops.ListenerStatus{
+			ARNs:      []string{aws.ToString(listener.ListenerArn)},
+			Port:      aws.ToInt32(listener.Port),
+			Protocol:  string(listener.Protocol),
+			SSLPolicy: aws.ToString(listener.SslPolicy),
+		}
+		if len(listener.Certificates) > 0 {
+			// DescribeListeners only returns the default certificate
+			listenerStatus.CertificateARN = aws.ToString(listener.Certificates[0].CertificateArn)
+		}
+		if listenerStatus.CertificateARN != "" {
+			notAfter, found := certificateNotAfters[listenerStatus.CertificateARN]
+			if !found {
+				notAfter, err = FindListenerCertificateNotAfter(ctx, c, listenerStatus.CertificateARN)
+				if err != nil {
+					if err := warnings.add(fmt.Errorf("finding the expiration of certificate %q: %w", listenerStatus.CertificateARN, err)); err != nil {
+						return nil, err
+					}
+				}
+				certificateNotAfters[listenerStatus.CertificateARN] = notAfter
+			}
+			listenerStatus.CertificateNotAfter = notAfter
+		}
+		listenerStatus.CertificateRotatedAt, _ = FindELBV2Tag(info.Tags, KopsCertificateRotatedTag)
+		listenerStatus.CorrelationID, _ = FindELBV2Tag(info.Tags, KopsListenerCorrelationIDTag)
+		if listenerStatus.SSLPolicy != "" {
+			minimumTLSVersion, found := minimumTLSVersions[listenerStatus.SSLPolicy]
+			if !found {
+				minimumTLSVersion, err = FindELBV2SSLPolicyMinimumTLSVersion(ctx, c, listenerStatus.SSLPolicy)
+				if err != nil {
+					if err := warnings.add(fmt.Errorf("describing SSL policy %q: %w", listenerStatus.SSLPolicy, err)); err != nil {
+						return nil, err
+					}
+				}
+				minimumTLSVersions[listenerStatus.SSLPolicy] = minimumTLSVersion
+			}
+			listenerStatus.MinimumTLSVersion = minimumTLSVersion
+		}
+		status.Listeners = append(status.Listeners, listenerStatus)
+	}
+	sort.Slice(status.Listeners, func(i, j int) bool {
+		return status.Listeners[i].Port < status.Listeners[j].Port
+	})
+	status.Listeners = coalesceTCPUDPListenerStatus(status.Listeners)
+
+	// Target groups may be shared between listeners
+	targetGroupARNs := make(map[string]bool)
+	for _, info := range listeners {
+		for _, targetGroupArn := range forwardTargetGroupARNs(info.Listener.DefaultActions) {
+			if targetGroupARNs[targetGroupArn] {
+				continue
+			}
+			targetGroupARNs[targetGroupArn] = true
+
+			targetGroupStatus, err := findTargetGroupStatus(ctx, c, targetGroupArn, etcdMembersByInstance)
+			if err != nil {
+				if err := warnings.add(err); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if targetGroupStatus != nil {
+				status.TargetGroups = append(status.TargetGroups, *targetGroupStatus)
+			}
+		}
+	}
+	sort.Slice(status.TargetGroups, func(i, j int) bool {
+		return status.TargetGroups[i].Name < status.TargetGroups[j].Name
+	})
+
+	return []kops.LoadBalancerStatus{status}, nil
+}
+
+// coalesceTCPUDPListenerStatus merges a TCP and a UDP listener on the same port into a single TCP_UDP listener,
+// which is how they are configured, rather than reporting them as duplicate listeners. The listeners must be sorted by port.
+func coalesceTCPUDPListenerStatus(listeners []kops.ListenerStatus) []kops.ListenerStatus {
+	var coalesced []kops.ListenerStatus
+	for i := 0; i < len(listeners); i++ {
+		listener := listeners[i]
+		if i+1 < len(listeners) && listeners[i+1].Port == listener.Port {
+			protocols := map[string]bool{listener.Protocol: true, listeners[i+1].Protocol: true}
+			if protocols[string(elbv2types.ProtocolEnumTcp)] && protocols[string(elbv2types.ProtocolEnumUdp)] {
+				listener.Protocol = string(elbv2types.ProtocolEnumTcpUdp)
+				listener.ARNs = append(append([]string(nil), listener.ARNs...), listeners[i+1].ARNs...)
+				i++
+			}
+		}
+		coalesced = append(coalesced, listener)
+	}
+	return coalesced
+}
+
+// loadBalancerZones returns the availability zones of a load balancer, sorted by name, and the ID of the subnet attached in each zone.
+func loadBalancerZones(zones []elbv2types.AvailabilityZone) ([]string, []string) {
+	zones = append([]elbv2types.AvailabilityZone(nil), zones...)
+	sort.SliceStable(zones, func(i, j int) bool {
+		return aws.ToString(zones[i].ZoneName) < aws.ToString(zones[j].ZoneName)
+	})
+	var zoneNames, subnetIDs []string
+	for _, zone := range zones {
+		zoneNames = append(zoneNames, aws.ToString(zone.ZoneName))
+		subnetIDs = append(subnetIDs, aws.ToString(zone.SubnetId))
+	}
+	return zoneNames, subnetIDs
+}
+
+// findTargetGroupStatus discovers the targets registered with the target group and their health,
+// returning nil if the target group no longer exists.
+func findTargetGroupStatus(ctx context.Context, c AWSCloud, targetGroupArn string, etcdMembersByInstance map[string][]string) (*kops.TargetGroupStatus, error) {
+	targets, err := GetTargetGroupHealth(ctx, c, targetGroupArn)
+	if err != nil {
+		var nfe *elbv2types.TargetGroupNotFoundException
+		if errors.As(err, &nfe) {
+			klog.Warningf("target group %q not found", targetGroupArn)
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	status := &kops.TargetGroupStatus{
+		Name: targetGroupNameFromARN(targetGroupArn),
+		ARN:  targetGroupArn,
+	}
+	for _, target := range targets {
+		targetStatus := kops.TargetStatus{
+			ID:    target.ID,
+			State: target.State,
+		}
+		targetStatus.EtcdMembers = etcdMembersByInstance[targetStatus.ID]
+		sort.Strings(targetStatus.EtcdMembers)
+		status.Targets = append(status.Targets, targetStatus)
+
+		switch elbv2types.TargetHealthStateEnum(target.State) {
+		case elbv2types.TargetHealthStateEnumHealthy:
+			status.HealthyTargets++
+		case elbv2types.TargetHealthStateEnumUnhealthy, elbv2types.TargetHealthStateEnumUnavailable:
+			status.UnhealthyTargets++
+		case elbv2types.TargetHealthStateEnumDraining:
+			status.DrainingTargets++
+		}
+	}
 	return status, nil
 }
+
+// targetGroupNameFromARN returns the name of a target group from its ARN (arn:...:targetgroup/<name>/<id>),
+// falling back to the ARN itself if it is not of the expected form.
+func targetGroupNameFromARN(targetGroupArn string) string {
+	_, resource, found := strings.Cut(targetGroupArn, ":targetgroup/")
+	if !found {
+		return targetGroupArn
+	}
+	name, _, _ := strings.Cut(resource, "/")
+	return name
+}
@@ -0,0 +1,593 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awsup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"k8s.io/kops/cloudmock/aws/mockec2"
+	"k8s.io/kops/cloudmock/aws/mockelbv2"
+	"k8s.io/kops/cloudmock/aws/mockiam"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/upup/pkg/fi"
+)
+
+func TestMinimumTLSVersion(t *testing.T) {
+	grid := []struct {
+		protocols []string
+		expected  string
+	}{
+		{protocols: []string{"TLSv1.2", "TLSv1.3"}, expected: "TLSv1.2"},
+		{protocols: []string{"TLSv1.3", "TLSv1.1", "TLSv1.2"}, expected: "TLSv1.1"},
+		{protocols: []string{"TLSv1.3"}, expected: "TLSv1.3"},
+		{protocols: []string{"QUIC"}, expected: ""},
+		{protocols: nil, expected: ""},
+	}
+	for _, g := range grid {
+		actual := MinimumTLSVersion(g.protocols)
+		if actual != g.expected {
+			t.Errorf("unexpected minimum TLS version for %v: expected %q, got %q", g.protocols, g.expected, actual)
+		}
+	}
+}
+
+func TestFindAPILoadBalancerStatus(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := BuildMockAWSCloud("us-test-1", "ab")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	elbv2Client := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = elbv2Client
+
+	vpc, err := ec2Client.CreateVpc(ctx, &ec2.CreateVpcInput{CidrBlock: aws.String("172.20.0.0/16")})
+	if err != nil {
+		t.Fatalf("error creating VPC: %v", err)
+	}
+	subnetIDs := make(map[string]string)
+	for i, zone := range []string{"us-test-1b", "us-test-1a"} {
+		subnet, err := ec2Client.CreateSubnet(ctx, &ec2.CreateSubnetInput{
+			VpcId:            vpc.Vpc.VpcId,
+			AvailabilityZone: aws.String(zone),
+			CidrBlock:        aws.String(fmt.Sprintf("172.20.%d.0/24", i)),
+		})
+		if err != nil {
+			t.Fatalf("error creating subnet: %v", err)
+		}
+		subnetIDs[zone] = aws.ToString(subnet.Subnet.SubnetId)
+	}
+
+	lb, err := elbv2Client.CreateLoadBalancer(ctx, &elbv2.CreateLoadBalancerInput{
+		Name:           aws.String("api-example-com"),
+		Scheme:         elbv2types.LoadBalancerSchemeEnumInternetFacing,
+		Type:           elbv2types.LoadBalancerTypeEnumNetwork,
+		Subnets:        []string{subnetIDs["us-test-1b"], subnetIDs["us-test-1a"]},
+		IpAddressType:  elbv2types.IpAddressTypeDualstack,
+		SecurityGroups: []string{"sg-api"},
+		Tags:           []elbv2types.Tag{{Key: aws.String("Name"), Value: aws.String("api.example.com")}},
+	})
+	if err != nil {
+		t.Fatalf("error creating load balancer: %v", err)
+	}
+	lbARN := lb.LoadBalancers[0].LoadBalancerArn
+
+	listenerARNs := make(map[int32]string)
+	for _, request := range []*elbv2.CreateListenerInput{
+		{
+			Port:      aws.Int32(8443),
+			Protocol:  elbv2types.ProtocolEnumTls,
+			SslPolicy: aws.String("ELBSecurityPolicy-TLS13-1-2-2021-06"),
+		},
+		{
+			Port:      aws.Int32(443),
+			Protocol:  elbv2types.ProtocolEnumTls,
+			SslPolicy: aws.String("ELBSecurityPolicy-TLS13-1-3-2021-06"),
+			Certificates: []elbv2types.Certificate{
+				{CertificateArn: aws.String("arn:aws-test:acm:us-test-1:123456789012:certificate/api")},
+			},
+			Tags: []elbv2types.Tag{{Key: aws.String(KopsListenerCorrelationIDTag), Value: aws.String("api.example.com-443")}},
+		},
+		{
+			Port:     aws.Int32(3988),
+			Protocol: elbv2types.ProtocolEnumTcp,
+		},
+	} {
+		request.LoadBalancerArn = lbARN
+		request.DefaultActions = []elbv2types.Action{{Type: elbv2types.ActionTypeEnumForward, TargetGroupArn: aws.String("tg")}}
+		response, err := elbv2Client.CreateListener(ctx, request)
+		if err != nil {
+			t.Fatalf("error creating listener: %v", err)
+		}
+		listenerARNs[aws.ToInt32(request.Port)] = aws.ToString(response.Listeners[0].ListenerArn)
+	}
+
+	cluster := &kops.Cluster{}
+	cluster.Name = "example.com"
+	cluster.Spec.API.LoadBalancer = &kops.LoadBalancerAccessSpec{
+		Class: kops.LoadBalancerClassNetwork,
+	}
+
+	actual, err := findAPILoadBalancerStatus(ctx, cloud, cluster, nil, &statusWarnings{ctx: ctx})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []kops.LoadBalancerStatus{
+		{
+			Name:                  "api.example.com",
+			ARN:                   aws.ToString(lbARN),
+			DNSName:               "api-example-com.amazonaws.com",
+			CanonicalHostedZoneID: "HZ123456",
+			State:                 "active",
+			AvailabilityZones:     []string{"us-test-1a", "us-test-1b"},
+			SubnetIDs:             []string{subnetIDs["us-test-1a"], subnetIDs["us-test-1b"]},
+			IPAddressType:         "dualstack",
+			SecurityGroups:        []string{"sg-api"},
+			Listeners: []kops.ListenerStatus{
+				{ARNs: []string{listenerARNs[443]}, Port: 443, Protocol: "TLS", SSLPolicy: "ELBSecurityPolicy-TLS13-1-3-2021-06", MinimumTLSVersion: "TLSv1.3", CertificateARN: "arn:aws-test:acm:us-test-1:123456789012:certificate/api", CorrelationID: "api.example.com-443"},
+				{ARNs: []string{listenerARNs[3988]}, Port: 3988, Protocol: "TCP"},
+				{ARNs: []string{listenerARNs[8443]}, Port: 8443, Protocol: "TLS", SSLPolicy: "ELBSecurityPolicy-TLS13-1-2-2021-06", MinimumTLSVersion: "TLSv1.2"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected status: expected %+v, got %+v", expected, actual)
+	}
+
+	cluster.Spec.API.LoadBalancer.Class = kops.LoadBalancerClassClassic
+	actual, err = findAPILoadBalancerStatus(ctx, cloud, cluster, nil, &statusWarnings{ctx: ctx})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual != nil {
+		t.Errorf("expected no status for classic load balancer, got %+v", actual)
+	}
+}
+
+func TestFindAPILoadBalancerStatusCertificateNotAfter(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := BuildMockAWSCloud("us-test-1", "ab")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	elbv2Client := &mockelbv2.MockELBV2{EC2: ec2Client}
+	cloud.MockELBV2 = elbv2Client
+	certificate := &iamtypes.ServerCertificate{
+		ServerCertificateMetadata: &iamtypes.ServerCertificateMetadata{
+			Expiration: aws.Time(time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)),
+		},
+	}
+	cloud.MockIAM = &mockiam.MockIAM{
+		ServerCertificates: map[string]*iamtypes.ServerCertificate{
+			"api": certificate,
+		},
+	}
+
+	lb, err := elbv2Client.CreateLoadBalancer(ctx, &elbv2.CreateLoadBalancerInput{
+		Name: aws.String("api-example-com"),
+		Type: elbv2types.LoadBalancerTypeEnumNetwork,
+		Tags: []elbv2types.Tag{{Key: aws.String("Name"), Value: aws.String("api.example.com")}},
+	})
+	if err != nil {
+		t.Fatalf("error creating load balancer: %v", err)
+	}
+	certificateARN := "arn:aws-test:iam::123456789012:server-certificate/api"
+	listener, err := elbv2Client.CreateListener(ctx, &elbv2.CreateListenerInput{
+		LoadBalancerArn: lb.LoadBalancers[0].LoadBalancerArn,
+		Port:            aws.Int32(443),
+		Protocol:        elbv2types.ProtocolEnumTls,
+		SslPolicy:       aws.String("ELBSecurityPolicy-TLS13-1-2-2021-06"),
+		Certificates:    []elbv2types.Certificate{{CertificateArn: aws.String(certificateARN)}},
+		DefaultActions:  []elbv2types.Action{{Type: elbv2types.ActionTypeEnumForward, TargetGroupArn: aws.String("tg")}},
+	})
+	if err != nil {
+		t.Fatalf("error creating listener: %v", err)
+	}
+	listenerARN := aws.ToString(listener.Listeners[0].ListenerArn)
+
+	cluster := &kops.Cluster{}
+	cluster.Name = "example.com"
+	cluster.Spec.API.LoadBalancer = &kops.LoadBalancerAccessSpec{
+		Class: kops.LoadBalancerClassNetwork,
+	}
+
+	findListenerStatus := func() kops.ListenerStatus {
+		t.Helper()
+		warnings := &statusWarnings{ctx: ctx}
+		status, err := findAPILoadBalancerStatus(ctx, cloud, cluster, nil, warnings)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(warnings.warnings) != 0 {
+			t.Errorf("unexpected warnings: %v", warnings.warnings)
+		}
+		if len(status) != 1 || len(status[0].Listeners) != 1 {
+			t.Fatalf("expected a single load balancer with a single listener, got %+v", status)
+		}
+		return status[0].Listeners[0]
+	}
+
+	listenerStatus := findListenerStatus()
+	if listenerStatus.CertificateNotAfter != "2026-11-01T00:00:00Z" {
+		t.Errorf("expected the certificate to expire at 2026-11-01T00:00:00Z, got %q", listenerStatus.CertificateNotAfter)
+	}
+
+	// A certificate renewed in place keeps its ARN, so the listener is unchanged but its expiration is updated
+	certificate.ServerCertificateMetadata.Expiration = aws.Time(time.Date(2027, 11, 1, 0, 0, 0, 0, time.UTC))
+	listenerStatus = findListenerStatus()
+	if listenerStatus.CertificateARN != certificateARN || listenerStatus.ARNs[0] != listenerARN {
+		t.Errorf("expected listener %q with certificate %q, got %+v", listenerARN, certificateARN, listenerStatus)
+	}
+	if listenerStatus.CertificateNotAfter != "2027-11-01T00:00:00Z" {
+		t.Errorf("expected the renewed certificate to expire at 2027-11-01T00:00:00Z, got %q", listenerStatus.CertificateNotAfter)
+	}
+}
+
+func TestFindEtcdStatus(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := BuildMockAWSCloud("us-test-1", "ab")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+
+	// Member "a" is attached to instance i-a; member "b" is not attached
+	for _, member := range []string{"a", "b"} {
+		volume, err := ec2Client.CreateVolume(ctx, &ec2.CreateVolumeInput{
+			AvailabilityZone: aws.String("us-test-1" + member),
+			TagSpecifications: []ec2types.TagSpecification{{
+				ResourceType: ec2types.ResourceTypeVolume,
+				Tags: []ec2types.Tag{
+					{Key: aws.String(TagNameEtcdClusterPrefix + "main"), Value: aws.String(member + "/a,b")},
+					{Key: aws.String(TagNameRolePrefix + TagRoleControlPlane), Value: aws.String("1")},
+				},
+			}},
+		})
+		if err != nil {
+			t.Fatalf("error creating volume: %v", err)
+		}
+		if member == "a" {
+			ec2Client.Volumes[aws.ToString(volume.VolumeId)].Attachments = []ec2types.VolumeAttachment{{InstanceId: aws.String("i-a")}}
+		}
+	}
+
+	ec2Client.Instances = map[string]*ec2types.Instance{
+		"i-a": {InstanceId: aws.String("i-a"), State: &ec2types.InstanceState{Name: ec2types.InstanceStateNameRunning}},
+	}
+
+	status, _, err := findEtcdStatus(ctx, cloud, &kops.Cluster{}, &statusWarnings{ctx: ctx})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(status) != 1 || len(status[0].Members) != 2 {
+		t.Fatalf("expected one etcd cluster with two members, got %+v", status)
+	}
+	members := status[0].Members
+	sort.Slice(members, func(i, j int) bool { return members[i].Name < members[j].Name })
+	for i, expected := range []kops.EtcdMemberStatus{
+		{Name: "a", InstanceID: "i-a", AvailabilityZone: "us-test-1a", Healthy: fi.PtrTo(true)},
+		{Name: "b", AvailabilityZone: "us-test-1b", Healthy: fi.PtrTo(false)},
+	} {
+		actual := *members[i]
+		actual.VolumeID = ""
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("unexpected member status: expected %+v, got %+v", expected, actual)
+		}
+	}
+	// One of the two members is healthy, which is not a majority
+	if !reflect.DeepEqual(status[0].Quorum, fi.PtrTo(false)) {
+		t.Errorf("expected quorum to be lost, got %v", status[0].Quorum)
+	}
+}
+
+func TestSetEtcdClusterHealth(t *testing.T) {
+	running := map[string]bool{"i-a": true, "i-b": true}
+	grid := []struct {
+		name            string
+		size            int
+		instances       []string
+		expectedHealthy []bool
+		expectedQuorum  bool
+	}{
+		{
+			name:            "healthy",
+			size:            3,
+			instances:       []string{"i-a", "i-b", "i-c"},
+			expectedHealthy: []bool{true, true, false},
+			expectedQuorum:  true,
+		},
+		{
+			name:            "quorum lost",
+			size:            3,
+			instances:       []string{"i-a", "", "i-c"},
+			expectedHealthy: []bool{true, false, false},
+			expectedQuorum:  false,
+		},
+		{
+			name:            "missing volumes count against quorum",
+			size:            5,
+			instances:       []string{"i-a", "i-b"},
+			expectedHealthy: []bool{true, true},
+			expectedQuorum:  false,
+		},
+		{
+			name:            "single member",
+			size:            1,
+			instances:       []string{"i-a"},
+			expectedHealthy: []bool{true},
+			expectedQuorum:  true,
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			status := &kops.EtcdClusterStatus{Name: "main"}
+			for i, instanceID := range g.instances {
+				status.Members = append(status.Members, &kops.EtcdMemberStatus{Name: fmt.Sprintf("m%d", i), InstanceID: instanceID})
+			}
+			setEtcdClusterHealth(status, g.size, running)
+			for i, member := range status.Members {
+				if member.Healthy == nil || *member.Healthy != g.expectedHealthy[i] {
+					t.Errorf("member %q: expected healthy %v, got %v", member.Name, g.expectedHealthy[i], member.Healthy)
+				}
+			}
+			if status.Quorum == nil || *status.Quorum != g.expectedQuorum {
+				t.Errorf("expected quorum %v, got %v", g.expectedQuorum, status.Quorum)
+			}
+		})
+	}
+}
+
+func TestFindClusterStatusAPITargets(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := BuildMockAWSCloud("us-test-1", "a")
+	ec2Client := &mockec2.MockEC2{}
+	cloud.MockEC2 = ec2Client
+	elbv2Client := &mockelbv2.MockELBV2{}
+	cloud.MockELBV2 = elbv2Client
+
+	// The etcd volumes of control plane member "a" are attached to instance i-a
+	for _, etcdCluster := range []string{"main", "events"} {
+		volume, err := ec2Client.CreateVolume(ctx, &ec2.CreateVolumeInput{
+			AvailabilityZone: aws.String("us-test-1a"),
+			TagSpecifications: []ec2types.TagSpecification{{
+				ResourceType: ec2types.ResourceTypeVolume,
+				Tags: []ec2types.Tag{
+					{Key: aws.String(TagNameEtcdClusterPrefix + etcdCluster), Value: aws.String("a/a")},
+					{Key: aws.String(TagNameRolePrefix + TagRoleControlPlane), Value: aws.String("1")},
+				},
+			}},
+		})
+		if err != nil {
+			t.Fatalf("error creating volume: %v", err)
+		}
+		ec2Client.Volumes[aws.ToString(volume.VolumeId)].Attachments = []ec2types.VolumeAttachment{{InstanceId: aws.String("i-a")}}
+	}
+
+	lb, err := elbv2Client.CreateLoadBalancer(ctx, &elbv2.CreateLoadBalancerInput{
+		Name:   aws.String("api-example-com"),
+		Scheme: elbv2types.LoadBalancerSchemeEnumInternetFacing,
+		Type:   elbv2types.LoadBalancerTypeEnumNetwork,
+		Tags:   []elbv2types.Tag{{Key: aws.String("Name"), Value: aws.String("api.example.com")}},
+	})
+	if err != nil {
+		t.Fatalf("error creating load balancer: %v", err)
+	}
+	tg, err := elbv2Client.CreateTargetGroup(ctx, &elbv2.CreateTargetGroupInput{
+		Name:     aws.String("tcp-example-com"),
+		Port:     aws.Int32(443),
+		Protocol: elbv2types.ProtocolEnumTcp,
+	})
+	if err != nil {
+		t.Fatalf("error creating target group: %v", err)
+	}
+	tgARN := tg.TargetGroups[0].TargetGroupArn
+	if _, err := elbv2Client.RegisterTargets(ctx, &elbv2.RegisterTargetsInput{
+		TargetGroupArn: tgARN,
+		Targets:        []elbv2types.TargetDescription{{Id: aws.String("i-b")}, {Id: aws.String("i-a")}, {Id: aws.String("i-c")}},
+	}); err != nil {
+		t.Fatalf("error registering targets: %v", err)
+	}
+	if err := elbv2Client.SetTargetHealth(aws.ToString(tgARN), "i-b", elbv2types.TargetHealth{State: elbv2types.TargetHealthStateEnumUnhealthy}); err != nil {
+		t.Fatalf("error setting target health: %v", err)
+	}
+	if err := elbv2Client.SetTargetHealth(aws.ToString(tgARN), "i-c", elbv2types.TargetHealth{State: elbv2types.TargetHealthStateEnumDraining}); err != nil {
+		t.Fatalf("error setting target health: %v", err)
+	}
+	if _, err := elbv2Client.CreateListener(ctx, &elbv2.CreateListenerInput{
+		LoadBalancerArn: lb.LoadBalancers[0].LoadBalancerArn,
+		Port:            aws.Int32(443),
+		Protocol:        elbv2types.ProtocolEnumTcp,
+		DefaultActions:  []elbv2types.Action{{Type: elbv2types.ActionTypeEnumForward, TargetGroupArn: tgARN}},
+	}); err != nil {
+		t.Fatalf("error creating listener: %v", err)
+	}
+
+	cluster := &kops.Cluster{}
+	cluster.Name = "example.com"
+	cluster.Spec.API.LoadBalancer = &kops.LoadBalancerAccessSpec{
+		Class: kops.LoadBalancerClassNetwork,
+	}
+
+	status, err := cloud.FindClusterStatus(ctx, cluster)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(status.LoadBalancers) != 1 {
+		t.Fatalf("expected 1 load balancer status, got %+v", status.LoadBalancers)
+	}
+	expected := []kops.TargetGroupStatus{
+		{
+			Name:             "tcp-example-com",
+			ARN:              aws.ToString(tgARN),
+			HealthyTargets:   1,
+			UnhealthyTargets: 1,
+			DrainingTargets:  1,
+			Targets: []kops.TargetStatus{
+				{ID: "i-a", State: "healthy", EtcdMembers: []string{"events/a", "main/a"}},
+				{ID: "i-b", State: "unhealthy"},
+				{ID: "i-c", State: "draining"},
+			},
+		},
+	}
+	if actual := status.LoadBalancers[0].TargetGroups; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected target group status: expected %+v, got %+v", expected, actual)
+	}
+}
+
+// throttledTargetHealthELBV2 fails DescribeTargetHealth for one target group.
+type throttledTargetHealthELBV2 struct {
+	*mockelbv2.MockELBV2
+
+	throttledTargetGroupARN string
+}
+
+func (m *throttledTargetHealthELBV2) DescribeTargetHealth(ctx context.Context, request *elbv2.DescribeTargetHealthInput, optFns ...func(*elbv2.Options)) (*elbv2.DescribeTargetHealthOutput, error) {
+	if aws.ToString(request.TargetGroupArn) == m.throttledTargetGroupARN {
+		return nil, fmt.Errorf("Throttling: Rate exceeded")
+	}
+	return m.MockELBV2.DescribeTargetHealth(ctx, request, optFns...)
+}
+
+func TestFindClusterStatusPartial(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := BuildMockAWSCloud("us-test-1", "a")
+	cloud.MockEC2 = &mockec2.MockEC2{}
+	elbv2Client := &throttledTargetHealthELBV2{MockELBV2: &mockelbv2.MockELBV2{}}
+	cloud.MockELBV2 = elbv2Client
+
+	lb, err := elbv2Client.CreateLoadBalancer(ctx, &elbv2.CreateLoadBalancerInput{
+		Name: aws.String("api-example-com"),
+		Type: elbv2types.LoadBalancerTypeEnumNetwork,
+		Tags: []elbv2types.Tag{{Key: aws.String("Name"), Value: aws.String("api.example.com")}},
+	})
+	if err != nil {
+		t.Fatalf("error creating load balancer: %v", err)
+	}
+	targetGroupARNs := make(map[string]string)
+	for _, name := range []string{"tcp-example-com", "kops-controller-example-com"} {
+		tg, err := elbv2Client.CreateTargetGroup(ctx, &elbv2.CreateTargetGroupInput{
+			Name:     aws.String(name),
+			Port:     aws.Int32(443),
+			Protocol: elbv2types.ProtocolEnumTcp,
+		})
+		if err != nil {
+			t.Fatalf("error creating target group: %v", err)
+		}
+		targetGroupARNs[name] = aws.ToString(tg.TargetGroups[0].TargetGroupArn)
+	}
+	elbv2Client.throttledTargetGroupARN = targetGroupARNs["kops-controller-example-com"]
+	for port, name := range map[int32]string{443: "tcp-example-com", 3988: "kops-controller-example-com"} {
+		if _, err := elbv2Client.CreateListener(ctx, &elbv2.CreateListenerInput{
+			LoadBalancerArn: lb.LoadBalancers[0].LoadBalancerArn,
+			Port:            aws.Int32(port),
+			Protocol:        elbv2types.ProtocolEnumTcp,
+			DefaultActions:  []elbv2types.Action{{Type: elbv2types.ActionTypeEnumForward, TargetGroupArn: aws.String(targetGroupARNs[name])}},
+		}); err != nil {
+			t.Fatalf("error creating listener: %v", err)
+		}
+	}
+
+	cluster := &kops.Cluster{}
+	cluster.Name = "example.com"
+	cluster.Spec.API.LoadBalancer = &kops.LoadBalancerAccessSpec{
+		Class: kops.LoadBalancerClassNetwork,
+	}
+
+	status, err := cloud.FindClusterStatus(ctx, cluster)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(status.LoadBalancers) != 1 {
+		t.Fatalf("expected 1 load balancer status, got %+v", status.LoadBalancers)
+	}
+	if ports := len(status.LoadBalancers[0].Listeners); ports != 2 {
+		t.Errorf("expected the status of 2 listeners, got %+v", status.LoadBalancers[0].Listeners)
+	}
+	var names []string
+	for _, targetGroup := range status.LoadBalancers[0].TargetGroups {
+		names = append(names, targetGroup.Name)
+	}
+	if expected := []string{"tcp-example-com"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected the status of target groups %v, got %v", expected, names)
+	}
+	if len(status.Warnings) != 1 || !strings.Contains(status.Warnings[0], "Throttling: Rate exceeded") || !strings.Contains(status.Warnings[0], targetGroupARNs["kops-controller-example-com"]) {
+		t.Errorf("expected a warning for the throttled target group, got %q", status.Warnings)
+	}
+}
+
+// cancellingEC2 cancels the context during DescribeVolumes, and fails like the SDK once the context is done.
+type cancellingEC2 struct {
+	*mockec2.MockEC2
+
+	cancel context.CancelFunc
+}
+
+func (m *cancellingEC2) DescribeVolumes(ctx context.Context, request *ec2.DescribeVolumesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error) {
+	m.cancel()
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestFindClusterStatusCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cloud := BuildMockAWSCloud("us-test-1", "a")
+	cloud.MockEC2 = &cancellingEC2{MockEC2: &mockec2.MockEC2{}, cancel: cancel}
+	cloud.MockELBV2 = &mockelbv2.MockELBV2{}
+
+	cluster := &kops.Cluster{}
+	cluster.Name = "example.com"
+
+	status, err := cloud.FindClusterStatus(ctx, cluster)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got status %+v and error %v", status, err)
+	}
+}
+
+func TestCoalesceTCPUDPListenerStatus(t *testing.T) {
+	listeners := []kops.ListenerStatus{
+		{ARNs: []string{"listener/udp-53"}, Port: 53, Protocol: "UDP"},
+		{ARNs: []string{"listener/tcp-53"}, Port: 53, Protocol: "TCP"},
+		{Port: 443, Protocol: "TLS", SSLPolicy: "ELBSecurityPolicy-TLS13-1-2-2021-06", MinimumTLSVersion: "TLSv1.2"},
+		{Port: 3988, Protocol: "TCP"},
+		{Port: 8053, Protocol: "UDP"},
+	}
+	expected := []kops.ListenerStatus{
+		{ARNs: []string{"listener/udp-53", "listener/tcp-53"}, Port: 53, Protocol: "TCP_UDP"},
+		{Port: 443, Protocol: "TLS", SSLPolicy: "ELBSecurityPolicy-TLS13-1-2-2021-06", MinimumTLSVersion: "TLSv1.2"},
+		{Port: 3988, Protocol: "TCP"},
+		{Port: 8053, Protocol: "UDP"},
+	}
+	actual := coalesceTCPUDPListenerStatus(listeners)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected listeners: expected %+v, got %+v", expected, actual)
+	}
+}
@@ -22,3 +22,15 @@ package awsup
 // it also happens for ELBs, when we cannot have two ELBs pointing at the same target group
 // and thus must create a second.
 const KopsResourceRevisionTag = "kops.k8s.io/revision"
+
+// KopsProtectedTag marks a resource that kops must never delete, even when a change would require recreating it
+// or when the cluster is deleted. Protected resources must be deleted manually.
+const KopsProtectedTag = "kops.k8s.io/protected"
+
+// KopsCertificateRotatedTag records when kops last rotated the certificate of a listener, in RFC3339 format.
+// TLS sessions established with the previous certificate cannot be resumed, so clients fall back to full handshakes for a while.
+const KopsCertificateRotatedTag = "kops.k8s.io/certificate-rotated"
+
+// KopsListenerCorrelationIDTag holds a stable identifier of a listener managed by kops. Unlike the ARN, it survives the
+// listener being recreated, so log pipelines can join access log records, which carry the listener, to the kops listener.
+const KopsListenerCorrelationIDTag = "kops.k8s.io/listener-correlation-id"
@@ -240,7 +240,7 @@ func (c *azureCloudImplementation) AddClusterTags(tags map[string]*string) {
 	}
 }
 
-func (c *azureCloudImplementation) GetApiIngressStatus(cluster *kops.Cluster) ([]fi.ApiIngressStatus, error) {
+func (c *azureCloudImplementation) GetApiIngressStatus(ctx context.Context, cluster *kops.Cluster) ([]fi.ApiIngressStatus, error) {
 	var ingresses []fi.ApiIngressStatus
 	rg := cluster.AzureResourceGroupName()
 
@@ -31,7 +31,7 @@ import (
 )
 
 // FindClusterStatus discovers the status of the cluster by looking for the tagged etcd volume.
-func (c *azureCloudImplementation) FindClusterStatus(cluster *kops.Cluster) (*kops.ClusterStatus, error) {
+func (c *azureCloudImplementation) FindClusterStatus(ctx context.Context, cluster *kops.Cluster) (*kops.ClusterStatus, error) {
 	klog.V(2).Infof("Listing Azure managed disks.")
 	disks, err := c.Disk().List(context.TODO(), cluster.AzureResourceGroupName())
 	if err != nil {
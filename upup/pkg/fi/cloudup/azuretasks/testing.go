@@ -179,12 +179,12 @@ func (c *MockAzureCloud) AddClusterTags(tags map[string]*string) {
 }
 
 // FindClusterStatus discovers the status of the cluster, by looking for the tagged etcd volumes
-func (c *MockAzureCloud) FindClusterStatus(cluster *kops.Cluster) (*kops.ClusterStatus, error) {
+func (c *MockAzureCloud) FindClusterStatus(ctx context.Context, cluster *kops.Cluster) (*kops.ClusterStatus, error) {
 	return &kops.ClusterStatus{}, nil
 }
 
 // GetApiIngressStatus returns the status of API ingress.
-func (c *MockAzureCloud) GetApiIngressStatus(cluster *kops.Cluster) ([]fi.ApiIngressStatus, error) {
+func (c *MockAzureCloud) GetApiIngressStatus(ctx context.Context, cluster *kops.Cluster) ([]fi.ApiIngressStatus, error) {
 	return nil, nil
 }
 
@@ -71,7 +71,7 @@ type DOCloud interface {
 	DomainService() godo.DomainsService
 	ActionsService() godo.ActionsService
 	VPCsService() godo.VPCsService
-	FindClusterStatus(cluster *kops.Cluster) (*kops.ClusterStatus, error)
+	FindClusterStatus(ctx context.Context, cluster *kops.Cluster) (*kops.ClusterStatus, error)
 	GetAllLoadBalancers() ([]godo.LoadBalancer, error)
 	GetAllDropletsByTag(tag string) ([]godo.Droplet, error)
 	GetAllVolumesByRegion() ([]godo.Volume, error)
@@ -284,7 +284,7 @@ func (c *doCloudImplementation) GetVPCUUID(networkCIDR string, vpcName string) (
 	}
 }
 
-func (c *doCloudImplementation) GetApiIngressStatus(cluster *kops.Cluster) ([]fi.ApiIngressStatus, error) {
+func (c *doCloudImplementation) GetApiIngressStatus(ctx context.Context, cluster *kops.Cluster) ([]fi.ApiIngressStatus, error) {
 	var ingresses []fi.ApiIngressStatus
 	done, err := vfs.RetryWithBackoff(readBackoff, func() (bool, error) {
 		// Note that this must match Digital Ocean's lb name
@@ -322,7 +322,7 @@ func (c *doCloudImplementation) GetApiIngressStatus(cluster *kops.Cluster) ([]fi
 }
 
 // FindClusterStatus discovers the status of the cluster, by looking for the tagged etcd volumes
-func (c *doCloudImplementation) FindClusterStatus(cluster *kops.Cluster) (*kops.ClusterStatus, error) {
+func (c *doCloudImplementation) FindClusterStatus(ctx context.Context, cluster *kops.Cluster) (*kops.ClusterStatus, error) {
 	etcdStatus, err := findEtcdStatus(c, cluster)
 	if err != nil {
 		return nil, err
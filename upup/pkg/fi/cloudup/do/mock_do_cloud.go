@@ -17,6 +17,7 @@ limitations under the License.
 package do
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -83,11 +84,11 @@ func (c *doCloudMockImplementation) GetCloudGroups(cluster *kops.Cluster, instan
 }
 
 // FindClusterStatus discovers the status of the cluster, by inspecting the cloud objects
-func (c *doCloudMockImplementation) FindClusterStatus(cluster *kops.Cluster) (*kops.ClusterStatus, error) {
+func (c *doCloudMockImplementation) FindClusterStatus(ctx context.Context, cluster *kops.Cluster) (*kops.ClusterStatus, error) {
 	return nil, errors.New("not tested")
 }
 
-func (c *doCloudMockImplementation) GetApiIngressStatus(cluster *kops.Cluster) ([]fi.ApiIngressStatus, error) {
+func (c *doCloudMockImplementation) GetApiIngressStatus(ctx context.Context, cluster *kops.Cluster) ([]fi.ApiIngressStatus, error) {
 	return nil, errors.New("not tested")
 }
 
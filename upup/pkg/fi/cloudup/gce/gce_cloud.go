@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -312,9 +313,7 @@ func (c *gceCloudImplementation) WaitForOp(op *compute.Operation) error {
 	return WaitForOp(c.compute.srv, op)
 }
 
-func (c *gceCloudImplementation) GetApiIngressStatus(cluster *kops.Cluster) ([]fi.ApiIngressStatus, error) {
-	// TODO: Add context to GetApiIngressStatus
-
+func (c *gceCloudImplementation) GetApiIngressStatus(ctx context.Context, cluster *kops.Cluster) ([]fi.ApiIngressStatus, error) {
 	var ingresses []fi.ApiIngressStatus
 
 	klog.V(2).Infof("Querying GCE to find forwardingRules for API")
@@ -326,7 +325,7 @@ func (c *gceCloudImplementation) GetApiIngressStatus(cluster *kops.Cluster) ([]f
 		project = c.Project()
 	}
 
-	forwardingRules, err := c.compute.ForwardingRules().List(context.Background(), project, c.region)
+	forwardingRules, err := c.compute.ForwardingRules().List(ctx, project, c.region)
 	if err != nil {
 		if !IsNotFound(err) {
 			forwardingRules = nil
@@ -363,10 +362,26 @@ func (c *gceCloudImplementation) GetApiIngressStatus(cluster *kops.Cluster) ([]f
 		ingresses = append(ingresses, fi.ApiIngressStatus{
 			InternalEndpoint: internalEndpoint,
 			IP:               forwardingRule.IPAddress,
+			Port:             forwardingRulePort(forwardingRule),
 		})
 	}
 
-	return ingresses, nil
+	return fi.UniqueApiIngressStatus(ingresses), nil
+}
+
+// forwardingRulePort returns the first port a forwarding rule accepts traffic on, or 0 if it is not known.
+// External forwarding rules set a port range (e.g. "443-443"), internal ones a list of ports.
+func forwardingRulePort(forwardingRule *compute.ForwardingRule) int32 {
+	port := forwardingRule.PortRange
+	if len(forwardingRule.Ports) != 0 {
+		port = forwardingRule.Ports[0]
+	}
+	port, _, _ = strings.Cut(port, "-")
+	n, err := strconv.ParseInt(port, 10, 32)
+	if err != nil {
+		return 0
+	}
+	return int32(n)
 }
 
 // FindInstanceTemplates finds all instance templates that are associated with the current cluster
@@ -46,7 +46,7 @@ func (c *gceCloudImplementation) allZones() ([]string, error) {
 }
 
 // FindClusterStatus discovers the status of the cluster, by inspecting the cloud objects
-func (c *gceCloudImplementation) FindClusterStatus(cluster *kops.Cluster) (*kops.ClusterStatus, error) {
+func (c *gceCloudImplementation) FindClusterStatus(ctx context.Context, cluster *kops.Cluster) (*kops.ClusterStatus, error) {
 	etcdClusters, err := c.findEtcdStatus(cluster)
 	if err != nil {
 		return nil, err
@@ -423,11 +423,11 @@ func (c *hetznerCloudImplementation) FindVPCInfo(id string) (*fi.VPCInfo, error)
 }
 
 // FindClusterStatus was used before etcd-manager to check the etcd cluster status and prevent unsupported changes.
-func (c *hetznerCloudImplementation) FindClusterStatus(cluster *kops.Cluster) (*kops.ClusterStatus, error) {
+func (c *hetznerCloudImplementation) FindClusterStatus(ctx context.Context, cluster *kops.Cluster) (*kops.ClusterStatus, error) {
 	return nil, nil
 }
 
-func (c *hetznerCloudImplementation) GetApiIngressStatus(cluster *kops.Cluster) ([]fi.ApiIngressStatus, error) {
+func (c *hetznerCloudImplementation) GetApiIngressStatus(ctx context.Context, cluster *kops.Cluster) ([]fi.ApiIngressStatus, error) {
 	lbName := "api." + cluster.Name
 
 	client := c.LoadBalancerClient()
@@ -17,6 +17,7 @@ limitations under the License.
 package openstack
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"net/http"
@@ -56,6 +57,7 @@ import (
 	"k8s.io/kops/dnsprovider/pkg/dnsprovider/providers/openstack/designate"
 	"k8s.io/kops/pkg/apis/kops"
 	"k8s.io/kops/pkg/cloudinstances"
+	"k8s.io/kops/pkg/wellknownports"
 	"k8s.io/kops/upup/pkg/fi"
 	"k8s.io/kops/util/pkg/vfs"
 )
@@ -738,16 +740,23 @@ type Address struct {
 	Addr   string
 }
 
-func (c *openstackCloud) GetApiIngressStatus(cluster *kops.Cluster) ([]fi.ApiIngressStatus, error) {
+func (c *openstackCloud) GetApiIngressStatus(ctx context.Context, cluster *kops.Cluster) ([]fi.ApiIngressStatus, error) {
 	return getApiIngressStatus(c, cluster)
 }
 
 func getApiIngressStatus(c OpenstackCloud, cluster *kops.Cluster) ([]fi.ApiIngressStatus, error) {
+	var ingresses []fi.ApiIngressStatus
+	var err error
 	if cluster.Spec.CloudProvider.Openstack.Loadbalancer != nil {
-		return getLoadBalancerIngressStatus(c, cluster)
+		ingresses, err = getLoadBalancerIngressStatus(c, cluster)
 	} else {
-		return getIPIngressStatus(c, cluster)
+		ingresses, err = getIPIngressStatus(c, cluster)
+	}
+	if err != nil {
+		return ingresses, err
 	}
+	// A floating IP may be reported for several ports of the same server
+	return fi.UniqueApiIngressStatus(ingresses), nil
 }
 
 func getLoadBalancerIngressStatus(c OpenstackCloud, cluster *kops.Cluster) ([]fi.ApiIngressStatus, error) {
@@ -774,8 +783,10 @@ func getLoadBalancerIngressStatus(c OpenstackCloud, cluster *kops.Cluster) ([]fi
 		}
 		for _, fip := range fips {
 			if fip.PortID == lb.VipPortID {
+				// The API listener of the load balancer listens on the API server port
 				ingresses = append(ingresses, fi.ApiIngressStatus{
-					IP: fip.FloatingIP,
+					IP:   fip.FloatingIP,
+					Port: int32(wellknownports.KubeAPIServer),
 				})
 			}
 		}
@@ -800,7 +811,8 @@ func getIPIngressStatus(c OpenstackCloud, cluster *kops.Cluster) (ingresses []fi
 					address, err := GetServerFixedIP(&instance, ifName)
 					if err == nil {
 						ingresses = append(ingresses, fi.ApiIngressStatus{
-							IP: address,
+							IP:   address,
+							Port: int32(wellknownports.KubeAPIServer),
 						})
 					} else {
 						ips, err := c.ListServerFloatingIPs(instance.ID)
@@ -809,7 +821,8 @@ func getIPIngressStatus(c OpenstackCloud, cluster *kops.Cluster) (ingresses []fi
 						}
 						for _, ip := range ips {
 							ingresses = append(ingresses, fi.ApiIngressStatus{
-								IP: fi.ValueOf(ip),
+								IP:   fi.ValueOf(ip),
+								Port: int32(wellknownports.KubeAPIServer),
 							})
 						}
 					}
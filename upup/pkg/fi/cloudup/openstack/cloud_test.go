@@ -17,6 +17,7 @@ limitations under the License.
 package openstack
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -150,7 +151,8 @@ func Test_OpenstackCloud_GetApiIngressStatus(t *testing.T) {
 			},
 			expectedAPIIngress: []fi.ApiIngressStatus{
 				{
-					IP: "8.8.8.8",
+					IP:   "8.8.8.8",
+					Port: 443,
 				},
 			},
 		},
@@ -199,7 +201,7 @@ func Test_OpenstackCloud_GetApiIngressStatus(t *testing.T) {
 				},
 			},
 			expectedAPIIngress: []fi.ApiIngressStatus{
-				{IP: "8.8.8.8"},
+				{IP: "8.8.8.8", Port: 443},
 			},
 		},
 		{
@@ -277,14 +279,14 @@ func Test_OpenstackCloud_GetApiIngressStatus(t *testing.T) {
 				},
 			},
 			expectedAPIIngress: []fi.ApiIngressStatus{
-				{IP: "1.2.3.4"},
-				{IP: "2.3.4.5"},
-				{IP: "3.4.5.6"},
-				{IP: "4.5.6.7"},
-				{IP: "10.20.30.40"},
-				{IP: "20.30.40.50"},
-				{IP: "30.40.50.60"},
-				{IP: "40.50.60.70"},
+				{IP: "1.2.3.4", Port: 443},
+				{IP: "2.3.4.5", Port: 443},
+				{IP: "3.4.5.6", Port: 443},
+				{IP: "4.5.6.7", Port: 443},
+				{IP: "10.20.30.40", Port: 443},
+				{IP: "20.30.40.50", Port: 443},
+				{IP: "30.40.50.60", Port: 443},
+				{IP: "40.50.60.70", Port: 443},
 			},
 		},
 		{
@@ -350,9 +352,9 @@ func Test_OpenstackCloud_GetApiIngressStatus(t *testing.T) {
 			},
 			cloudFloatingEnabled: true,
 			expectedAPIIngress: []fi.ApiIngressStatus{
-				{IP: "1.2.3.4"},
-				{IP: "4.5.6.7"},
-				{IP: "40.50.60.70"},
+				{IP: "1.2.3.4", Port: 443},
+				{IP: "4.5.6.7", Port: 443},
+				{IP: "40.50.60.70", Port: 443},
 			},
 		},
 	}
@@ -449,7 +451,7 @@ func Test_OpenstackCloud_GetApiIngressStatus(t *testing.T) {
 				neutronClient:   serviceClient(testServer.URL),
 			}
 
-			ingress, err := cloud.GetApiIngressStatus(testCase.cluster)
+			ingress, err := cloud.GetApiIngressStatus(context.TODO(), testCase.cluster)
 
 			compareErrors(t, testCase.expectedError, err)
 
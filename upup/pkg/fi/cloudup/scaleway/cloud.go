@@ -17,6 +17,7 @@ limitations under the License.
 package scaleway
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -70,9 +71,9 @@ type ScwCloud interface {
 	DeleteInstance(i *cloudinstances.CloudInstance) error
 	DeregisterInstance(instance *cloudinstances.CloudInstance) error
 	DetachInstance(instance *cloudinstances.CloudInstance) error
-	FindClusterStatus(cluster *kops.Cluster) (*kops.ClusterStatus, error)
+	FindClusterStatus(ctx context.Context, cluster *kops.Cluster) (*kops.ClusterStatus, error)
 	FindVPCInfo(id string) (*fi.VPCInfo, error)
-	GetApiIngressStatus(cluster *kops.Cluster) ([]fi.ApiIngressStatus, error)
+	GetApiIngressStatus(ctx context.Context, cluster *kops.Cluster) ([]fi.ApiIngressStatus, error)
 	GetCloudGroups(cluster *kops.Cluster, instancegroups []*kops.InstanceGroup, warnUnmatched bool, nodes []v1.Node) (map[string]*cloudinstances.CloudInstanceGroup, error)
 
 	GetClusterDNSRecords(clusterName string) ([]*domain.Record, error)
@@ -301,7 +302,7 @@ func (s *scwCloudImplementation) DetachInstance(i *cloudinstances.CloudInstance)
 }
 
 // FindClusterStatus was used before etcd-manager to check the etcd cluster status and prevent unsupported changes.
-func (s *scwCloudImplementation) FindClusterStatus(cluster *kops.Cluster) (*kops.ClusterStatus, error) {
+func (s *scwCloudImplementation) FindClusterStatus(ctx context.Context, cluster *kops.Cluster) (*kops.ClusterStatus, error) {
 	klog.V(8).Info("Scaleway FindClusterStatus is not implemented")
 	return nil, nil
 }
@@ -312,7 +313,7 @@ func (s *scwCloudImplementation) FindVPCInfo(id string) (*fi.VPCInfo, error) {
 	return nil, fmt.Errorf("FindVPCInfo is not implemented yet for Scaleway")
 }
 
-func (s *scwCloudImplementation) GetApiIngressStatus(cluster *kops.Cluster) ([]fi.ApiIngressStatus, error) {
+func (s *scwCloudImplementation) GetApiIngressStatus(ctx context.Context, cluster *kops.Cluster) ([]fi.ApiIngressStatus, error) {
 	var ingresses []fi.ApiIngressStatus
 	name := "api." + cluster.Name
 
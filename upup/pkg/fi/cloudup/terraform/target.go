@@ -34,6 +34,10 @@ type TerraformTarget struct {
 
 	ClusterName string
 
+	// EmitImportBlocks adds import blocks for the existing cloud resources of the tasks that support it,
+	// so that terraform adopts them rather than planning to create them; import blocks require terraform 1.5.
+	EmitImportBlocks bool
+
 	outDir string
 	// extra config to add to the provider block
 	clusterSpecTarget *kops.TargetSpec
@@ -63,6 +67,14 @@ func (t *TerraformTarget) AddFileResource(resourceType string, resourceName stri
 	return t.AddFileBytes(resourceType, resourceName, key, d, base64)
 }
 
+// AddImport adds an import block for an existing cloud resource, if EmitImportBlocks is set.
+func (t *TerraformTarget) AddImport(resourceType string, resourceName string, id string) {
+	if !t.EmitImportBlocks {
+		return
+	}
+	t.RenderImport(resourceType, resourceName, id)
+}
+
 func (t *TerraformTarget) DefaultCheckExisting() bool {
 	return false
 }
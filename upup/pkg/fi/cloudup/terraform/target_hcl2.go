@@ -46,6 +46,13 @@ func (t *TerraformTarget) finishHCL2() error {
 
 	t.writeResources(buf, resourcesByType)
 
+	imports, err := t.GetImports()
+	if err != nil {
+		return err
+	}
+
+	writeImports(buf, imports)
+
 	dataSourcesByType, err := t.GetDataSourcesByType()
 	if err != nil {
 		return err
@@ -53,7 +60,7 @@ func (t *TerraformTarget) finishHCL2() error {
 
 	t.writeDataSources(buf, dataSourcesByType)
 
-	t.writeTerraform(buf)
+	t.writeTerraform(buf, len(imports) != 0)
 
 	t.Files["kubernetes.tf"] = buf.Bytes()
 
@@ -186,6 +193,22 @@ func (t *TerraformTarget) writeResources(buf *bytes.Buffer, resourcesByType map[
 	}
 }
 
+// writeImports creates an import block for each existing cloud resource
+// Example:
+//
+//	import {
+//	  to = aws_lb_listener.api-example-com-443
+//	  id = "arn:aws:elasticloadbalancing:us-east-1:123456789012:listener/net/api/1/2"
+//	}
+func writeImports(buf *bytes.Buffer, imports []terraformWriter.ImportBlock) {
+	for _, imp := range imports {
+		buf.WriteString("import {\n")
+		fmt.Fprintf(buf, "  to = %s\n", imp.To)
+		fmt.Fprintf(buf, "  id = %q\n", imp.ID)
+		buf.WriteString("}\n\n")
+	}
+}
+
 func (t *TerraformTarget) writeDataSources(buf *bytes.Buffer, dataSourcesByType map[string]map[string]interface{}) {
 	dataSourceTypes := make([]string, 0, len(dataSourcesByType))
 	for dataSourceType := range dataSourcesByType {
@@ -207,9 +230,14 @@ func (t *TerraformTarget) writeDataSources(buf *bytes.Buffer, dataSourcesByType
 	}
 }
 
-func (t *TerraformTarget) writeTerraform(buf *bytes.Buffer) {
+func (t *TerraformTarget) writeTerraform(buf *bytes.Buffer, hasImports bool) {
 	buf.WriteString("terraform {\n")
-	buf.WriteString("  required_version = \">= 0.15.0\"\n")
+	if hasImports {
+		// Import blocks were added in terraform 1.5
+		buf.WriteString("  required_version = \">= 1.5.0\"\n")
+	} else {
+		buf.WriteString("  required_version = \">= 0.15.0\"\n")
+	}
 	buf.WriteString("  required_providers {\n")
 
 	providers := make(map[string]bool)
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"path"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -36,6 +37,8 @@ type TerraformWriter struct {
 	resources []*terraformResource
 	// outputs is a list of our TF output variables
 	outputs map[string]*terraformOutputVariable
+	// imports is a list of TF import blocks for existing cloud resources
+	imports []*terraformImport
 
 	// Providers is a list of TF Providers we need for writing files
 	Providers map[string]*TerraformProvider
@@ -61,6 +64,18 @@ type terraformResource struct {
 	Item         interface{}
 }
 
+type terraformImport struct {
+	ResourceType string
+	ResourceName string
+	ID           string
+}
+
+// ImportBlock is an import block, adopting the cloud resource with ID as the resource at address To.
+type ImportBlock struct {
+	To string
+	ID string
+}
+
 type terraformOutputVariable struct {
 	Key        string
 	Value      *Literal
@@ -165,6 +180,21 @@ func (t *TerraformWriter) RenderResource(resourceType string, resourceName strin
 	return nil
 }
 
+// RenderImport adds an import block, so that terraform adopts the existing cloud resource with the given ID
+// as the resource of the given type and name, rather than creating it.
+func (t *TerraformWriter) RenderImport(resourceType string, resourceName string, id string) {
+	imp := &terraformImport{
+		ResourceType: resourceType,
+		ResourceName: resourceName,
+		ID:           id,
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.imports = append(t.imports, imp)
+}
+
 func (t *TerraformWriter) AddOutputVariable(key string, literal *Literal) error {
 	v := &terraformOutputVariable{
 		Key:   key,
@@ -245,6 +275,22 @@ func (t *TerraformWriter) GetResourcesByType() (map[string]map[string]interface{
 	return resourcesByType, nil
 }
 
+// GetImports returns the import blocks, sorted by resource address.
+func (t *TerraformWriter) GetImports() ([]ImportBlock, error) {
+	var imports []ImportBlock
+	seen := make(map[string]bool)
+	for _, imp := range t.imports {
+		to := imp.ResourceType + "." + sanitizeName(imp.ResourceName)
+		if seen[to] {
+			return nil, fmt.Errorf("duplicate import found: %s", to)
+		}
+		seen[to] = true
+		imports = append(imports, ImportBlock{To: to, ID: imp.ID})
+	}
+	sort.Slice(imports, func(i, j int) bool { return imports[i].To < imports[j].To })
+	return imports, nil
+}
+
 func (t *TerraformWriter) GetOutputs() (map[string]OutputValue, error) {
 	values := map[string]OutputValue{}
 	for _, v := range t.outputs {
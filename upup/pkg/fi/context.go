@@ -70,6 +70,13 @@ func (c *Context[T]) Context() context.Context {
 	return c.ctx
 }
 
+// WithContext returns a shallow copy of the context that uses ctx, for example to carry values for the duration of a single task.
+func (c *Context[T]) WithContext(ctx context.Context) *Context[T] {
+	scoped := *c
+	scoped.ctx = ctx
+	return &scoped
+}
+
 // Warning holds the details of a warning encountered during validation/creation
 type Warning[T SubContext] struct {
 	Task    Task[T]
@@ -0,0 +1,136 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fi
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/kops/pkg/apis/kops"
+)
+
+// CachingStatusStore wraps a StatusStore, reusing the status of a cluster for a short time,
+// so that loops such as cluster validation do not query the cloud on every iteration.
+// Errors are not cached. It is safe for concurrent use.
+type CachingStatusStore struct {
+	inner StatusStore
+	ttl   time.Duration
+
+	// now is overridden by tests
+	now func() time.Time
+
+	mutex         sync.Mutex
+	clusterStatus map[string]cachedClusterStatus
+	ingressStatus map[string]cachedApiIngressStatus
+
+	// generations is bumped by Invalidate, so that a status fetched before an invalidation is not cached
+	generations map[string]uint64
+}
+
+type cachedClusterStatus struct {
+	status  *kops.ClusterStatus
+	expires time.Time
+}
+
+type cachedApiIngressStatus struct {
+	ingresses []ApiIngressStatus
+	expires   time.Time
+}
+
+var _ StatusStore = &CachingStatusStore{}
+
+// NewCachingStatusStore builds a CachingStatusStore, which reuses the status of each cluster (by name) for ttl.
+func NewCachingStatusStore(inner StatusStore, ttl time.Duration) *CachingStatusStore {
+	return &CachingStatusStore{
+		inner:         inner,
+		ttl:           ttl,
+		now:           time.Now,
+		clusterStatus: make(map[string]cachedClusterStatus),
+		ingressStatus: make(map[string]cachedApiIngressStatus),
+		generations:   make(map[string]uint64),
+	}
+}
+
+// FindClusterStatus implements StatusStore; callers may modify the returned status.
+func (s *CachingStatusStore) FindClusterStatus(ctx context.Context, cluster *kops.Cluster) (*kops.ClusterStatus, error) {
+	s.mutex.Lock()
+	cached, found := s.clusterStatus[cluster.Name]
+	generation := s.generations[cluster.Name]
+	s.mutex.Unlock()
+	if found && s.now().Before(cached.expires) {
+		return cached.status.DeepCopy(), nil
+	}
+
+	status, err := s.inner.FindClusterStatus(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	if s.generations[cluster.Name] == generation {
+		s.clusterStatus[cluster.Name] = cachedClusterStatus{status: status.DeepCopy(), expires: s.now().Add(s.ttl)}
+	}
+	s.mutex.Unlock()
+	return status, nil
+}
+
+// GetApiIngressStatus implements StatusStore; callers may modify the returned ingress points.
+func (s *CachingStatusStore) GetApiIngressStatus(ctx context.Context, cluster *kops.Cluster) ([]ApiIngressStatus, error) {
+	s.mutex.Lock()
+	cached, found := s.ingressStatus[cluster.Name]
+	generation := s.generations[cluster.Name]
+	s.mutex.Unlock()
+	if found && s.now().Before(cached.expires) {
+		return copyApiIngressStatus(cached.ingresses), nil
+	}
+
+	ingresses, err := s.inner.GetApiIngressStatus(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	if s.generations[cluster.Name] == generation {
+		s.ingressStatus[cluster.Name] = cachedApiIngressStatus{ingresses: copyApiIngressStatus(ingresses), expires: s.now().Add(s.ttl)}
+	}
+	s.mutex.Unlock()
+	return ingresses, nil
+}
+
+// Invalidate discards the cached status of the cluster, e.g. after changing its cloud resources.
+// Lookups that are in progress return their result, but do not cache it.
+func (s *CachingStatusStore) Invalidate(clusterName string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.generations[clusterName]++
+	delete(s.clusterStatus, clusterName)
+	delete(s.ingressStatus, clusterName)
+}
+
+func copyApiIngressStatus(ingresses []ApiIngressStatus) []ApiIngressStatus {
+	if ingresses == nil {
+		return nil
+	}
+	copied := make([]ApiIngressStatus, len(ingresses))
+	for i, ingress := range ingresses {
+		ingress.IPFamilies = append([]string(nil), ingress.IPFamilies...)
+		copied[i] = ingress
+	}
+	return copied
+}
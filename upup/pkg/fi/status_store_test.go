@@ -0,0 +1,245 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/kops/pkg/apis/kops"
+)
+
+// countingStatusStore counts the calls of each method, returning err if set.
+type countingStatusStore struct {
+	clusterStatusCalls atomic.Int32
+	ingressStatusCalls atomic.Int32
+	err                error
+}
+
+func (s *countingStatusStore) FindClusterStatus(ctx context.Context, cluster *kops.Cluster) (*kops.ClusterStatus, error) {
+	s.clusterStatusCalls.Add(1)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &kops.ClusterStatus{EtcdClusters: []kops.EtcdClusterStatus{{Name: cluster.Name}}}, nil
+}
+
+func (s *countingStatusStore) GetApiIngressStatus(ctx context.Context, cluster *kops.Cluster) ([]ApiIngressStatus, error) {
+	s.ingressStatusCalls.Add(1)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return []ApiIngressStatus{{Hostname: "api." + cluster.Name}}, nil
+}
+
+func TestCachingStatusStoreTTL(t *testing.T) {
+	ctx := context.TODO()
+
+	inner := &countingStatusStore{}
+	store := NewCachingStatusStore(inner, time.Minute)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	clusterA := &kops.Cluster{}
+	clusterA.Name = "a.example.com"
+	clusterB := &kops.Cluster{}
+	clusterB.Name = "b.example.com"
+
+	lookup := func(cluster *kops.Cluster) {
+		status, err := store.FindClusterStatus(ctx, cluster)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if status.EtcdClusters[0].Name != cluster.Name {
+			t.Errorf("expected status of %q, got %+v", cluster.Name, status)
+		}
+		// Modifying the returned status must not affect the cache
+		status.EtcdClusters[0].Name = "modified"
+
+		ingresses, err := store.GetApiIngressStatus(ctx, cluster)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(ingresses) != 1 || ingresses[0].Hostname != "api."+cluster.Name {
+			t.Errorf("expected ingress of %q, got %+v", cluster.Name, ingresses)
+		}
+		ingresses[0].Hostname = "modified"
+	}
+	expectCalls := func(expected int32) {
+		t.Helper()
+		if actual := inner.clusterStatusCalls.Load(); actual != expected {
+			t.Errorf("expected %d FindClusterStatus calls, got %d", expected, actual)
+		}
+		if actual := inner.ingressStatusCalls.Load(); actual != expected {
+			t.Errorf("expected %d GetApiIngressStatus calls, got %d", expected, actual)
+		}
+	}
+
+	lookup(clusterA)
+	lookup(clusterA)
+	expectCalls(1)
+
+	// Each cluster is cached separately
+	lookup(clusterB)
+	expectCalls(2)
+
+	now = now.Add(59 * time.Second)
+	lookup(clusterA)
+	expectCalls(2)
+
+	now = now.Add(time.Second)
+	lookup(clusterA)
+	expectCalls(3)
+
+	store.Invalidate(clusterA.Name)
+	lookup(clusterA)
+	expectCalls(4)
+}
+
+func TestCachingStatusStoreErrors(t *testing.T) {
+	ctx := context.TODO()
+
+	inner := &countingStatusStore{err: errors.New("throttled")}
+	store := NewCachingStatusStore(inner, time.Minute)
+	cluster := &kops.Cluster{}
+	cluster.Name = "example.com"
+
+	for i := 0; i < 2; i++ {
+		if _, err := store.FindClusterStatus(ctx, cluster); err == nil {
+			t.Errorf("expected error")
+		}
+		if _, err := store.GetApiIngressStatus(ctx, cluster); err == nil {
+			t.Errorf("expected error")
+		}
+	}
+	if inner.clusterStatusCalls.Load() != 2 || inner.ingressStatusCalls.Load() != 2 {
+		t.Errorf("expected errors not to be cached, got %d and %d calls", inner.clusterStatusCalls.Load(), inner.ingressStatusCalls.Load())
+	}
+}
+
+func TestCachingStatusStoreConcurrent(t *testing.T) {
+	ctx := context.TODO()
+
+	inner := &countingStatusStore{}
+	store := NewCachingStatusStore(inner, time.Hour)
+	cluster := &kops.Cluster{}
+	cluster.Name = "example.com"
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%5 == 0 {
+				store.Invalidate(cluster.Name)
+			}
+			if _, err := store.FindClusterStatus(ctx, cluster); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if _, err := store.GetApiIngressStatus(ctx, cluster); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Once the goroutines are done, the status is cached
+	calls := inner.clusterStatusCalls.Load()
+	if _, err := store.FindClusterStatus(ctx, cluster); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if inner.clusterStatusCalls.Load() != calls {
+		t.Errorf("expected cached status after concurrent lookups")
+	}
+}
+
+// blockingStatusStore blocks each call until release is closed, after signalling started.
+type blockingStatusStore struct {
+	countingStatusStore
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStatusStore) FindClusterStatus(ctx context.Context, cluster *kops.Cluster) (*kops.ClusterStatus, error) {
+	s.started <- struct{}{}
+	<-s.release
+	return s.countingStatusStore.FindClusterStatus(ctx, cluster)
+}
+
+func (s *blockingStatusStore) GetApiIngressStatus(ctx context.Context, cluster *kops.Cluster) ([]ApiIngressStatus, error) {
+	s.started <- struct{}{}
+	<-s.release
+	return s.countingStatusStore.GetApiIngressStatus(ctx, cluster)
+}
+
+func TestCachingStatusStoreInvalidateDuringLookup(t *testing.T) {
+	ctx := context.TODO()
+
+	inner := &blockingStatusStore{started: make(chan struct{}), release: make(chan struct{})}
+	store := NewCachingStatusStore(inner, time.Hour)
+	cluster := &kops.Cluster{}
+	cluster.Name = "example.com"
+
+	errs := make(chan error, 2)
+	go func() {
+		_, err := store.FindClusterStatus(ctx, cluster)
+		errs <- err
+	}()
+	go func() {
+		_, err := store.GetApiIngressStatus(ctx, cluster)
+		errs <- err
+	}()
+
+	// Invalidate while both lookups are blocked in the inner store
+	<-inner.started
+	<-inner.started
+	store.Invalidate(cluster.Name)
+	close(inner.release)
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// The results fetched before the invalidation must not have been cached
+	go func() {
+		for range 2 {
+			<-inner.started
+		}
+	}()
+	if _, err := store.FindClusterStatus(ctx, cluster); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := store.GetApiIngressStatus(ctx, cluster); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if inner.clusterStatusCalls.Load() != 2 || inner.ingressStatusCalls.Load() != 2 {
+		t.Errorf("expected lookups after the invalidation to call the inner store, got %d and %d calls", inner.clusterStatusCalls.Load(), inner.ingressStatusCalls.Load())
+	}
+
+	// Lookups that were not interrupted by an invalidation are cached
+	if _, err := store.FindClusterStatus(ctx, cluster); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if inner.clusterStatusCalls.Load() != 2 {
+		t.Errorf("expected cached status, got %d calls", inner.clusterStatusCalls.Load())
+	}
+}